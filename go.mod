@@ -54,6 +54,7 @@ require (
 	golang.org/x/net v0.28.0
 	golang.org/x/term v0.23.0
 	golang.org/x/text v0.17.0
+	golang.org/x/time v0.5.0
 	gopkg.in/vansante/go-ffprobe.v2 v2.2.0
 	gorm.io/gorm v1.25.10
 )
@@ -206,7 +207,6 @@ require (
 	golang.org/x/oauth2 v0.18.0 // indirect
 	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/sys v0.24.0 // indirect
-	golang.org/x/time v0.5.0 // indirect
 	golang.org/x/tools v0.21.1-0.20240508182429-e35e4ccd0d2d // indirect
 	google.golang.org/api v0.171.0 // indirect
 	google.golang.org/appengine v1.6.8 // indirect
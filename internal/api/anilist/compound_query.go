@@ -5,26 +5,95 @@ import (
 	"github.com/goccy/go-json"
 	"seanime/internal/util"
 	"strconv"
+	"strings"
 )
 
+// FetchBaseAnimeMap fetches the anime with the given AniList IDs.
+// The returned map is keyed by AniList ID.
 func FetchBaseAnimeMap(ids []int) (ret map[int]*BaseAnime, err error) {
+	return fetchCompoundMap[BaseAnime]("Media(id: %d, type: ANIME)", "baseAnime", BaseAnimeByIDDocument, ids)
+}
 
-	query := fmt.Sprintf(CompoundBaseAnimeDocument, newCompoundQuery(ids))
+// FetchBaseAnimeMapByMalIDs fetches the anime with the given MAL IDs.
+// The returned map is keyed by MAL ID, IDs that do not exist on AniList are omitted.
+func FetchBaseAnimeMapByMalIDs(malIds []int) (ret map[int]*BaseAnime, err error) {
+	return fetchCompoundMap[BaseAnime]("Media(idMal: %d, type: ANIME)", "baseAnime", BaseAnimeByMalIDDocument, malIds)
+}
 
-	requestBody, err := json.Marshal(map[string]interface{}{
-		"query":     query,
-		"variables": nil,
-	})
-	if err != nil {
-		return nil, err
+// FetchBaseMangaMap fetches the manga with the given AniList IDs.
+// The returned map is keyed by AniList ID.
+func FetchBaseMangaMap(ids []int) (ret map[int]*BaseManga, err error) {
+	return fetchCompoundMap[BaseManga]("Media(id: %d, type: MANGA)", "baseManga", BaseMangaByIDDocument, ids)
+}
+
+// FetchBaseMangaMapByMalIDs fetches the manga with the given MAL IDs.
+// The returned map is keyed by MAL ID, IDs that do not exist on AniList are omitted.
+func FetchBaseMangaMapByMalIDs(malIds []int) (ret map[int]*BaseManga, err error) {
+	return fetchCompoundMap[BaseManga]("Media(idMal: %d, type: MANGA)", "baseManga", BaseMangaByIDDocument, malIds)
+}
+
+// FetchCompleteAnimeMap fetches the anime with the given AniList IDs, including their relations.
+// The returned map is keyed by AniList ID.
+func FetchCompleteAnimeMap(ids []int) (ret map[int]*CompleteAnime, err error) {
+	return fetchCompoundMap[CompleteAnime]("Media(id: %d, type: ANIME)", "completeAnime", CompleteAnimeByIDDocument, ids)
+}
+
+// compoundQueryChunkSize is the number of aliased fields sent in a single compound query.
+// AniList rejects queries that are too complex, so larger ID lists are split into multiple requests.
+const compoundQueryChunkSize = 25
+
+// fetchCompoundMap builds a compound query where each ID is aliased, e.g. "t21: Media(id: 21) { ...baseAnime }".
+// The fragments are taken from the generated document so that the result can be unmarshalled into the generated types.
+// IDs that cannot be fetched (e.g. a MAL ID that does not exist on AniList) are omitted instead of failing the whole query.
+func fetchCompoundMap[T any](field string, fragmentName string, document string, ids []int) (ret map[int]*T, err error) {
+	ret = make(map[int]*T)
+
+	fragmentIdx := strings.Index(document, "fragment ")
+	if fragmentIdx == -1 {
+		return nil, fmt.Errorf("anilist: no fragment found in document")
 	}
+	fragments := document[fragmentIdx:]
 
-	data, err := customQuery(requestBody, util.NewLogger())
-	if err != nil {
-		return nil, err
+	logger := util.NewLogger()
+
+	for start := 0; start < len(ids); start += compoundQueryChunkSize {
+		end := min(start+compoundQueryChunkSize, len(ids))
+
+		var fields strings.Builder
+		for _, id := range ids[start:end] {
+			_, _ = fmt.Fprintf(&fields, "\n\tt%d: %s {\n\t\t...%s\n\t}", id, fmt.Sprintf(field, id), fragmentName)
+		}
+
+		query := fmt.Sprintf("query CompoundQuery {%s\n}\n%s", fields.String(), fragments)
+
+		requestBody, err := json.Marshal(map[string]interface{}{
+			"query":     query,
+			"variables": nil,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := customQueryPartial(requestBody, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := parseCompoundData[T](data)
+		if err != nil {
+			return nil, err
+		}
+		for id, v := range res {
+			ret[id] = v
+		}
 	}
 
-	var res map[string]*BaseAnime
+	return ret, nil
+}
+
+// parseCompoundData returns the aliased fields of a compound query keyed by ID, null fields are omitted.
+func parseCompoundData[T any](data interface{}) (map[int]*T, error) {
+	var res map[string]*T
 
 	dataB, err := json.Marshal(data)
 	if err != nil {
@@ -36,8 +105,11 @@ func FetchBaseAnimeMap(ids []int) (ret map[int]*BaseAnime, err error) {
 		return nil, err
 	}
 
-	ret = make(map[int]*BaseAnime)
+	ret := make(map[int]*T, len(res))
 	for k, v := range res {
+		if v == nil {
+			continue
+		}
 		id, err := strconv.Atoi(k[1:])
 		if err != nil {
 			return nil, err
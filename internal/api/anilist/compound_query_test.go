@@ -93,3 +93,36 @@ fragment baseAnime on Media {
 		episode
 	}
 }`
+
+func TestCompoundQuery_PartialErrors(t *testing.T) {
+	// One of the aliased fields does not exist on AniList
+	var res interface{}
+	err := json.Unmarshal([]byte(`{
+		"errors": [{"message": "Not Found.", "status": 404, "locations": [{"line": 5, "column": 2}], "path": ["t99999999"]}],
+		"data": {
+			"t21": {"id": 21, "idMal": 21, "title": {"romaji": "One Piece"}},
+			"t99999999": null
+		}
+	}`), &res)
+	require.NoError(t, err)
+
+	logger := util.NewLogger()
+
+	// The whole query fails by default
+	_, err = parseQueryResponse(res, false, logger)
+	require.Error(t, err)
+
+	data, err := parseQueryResponse(res, true, logger)
+	require.NoError(t, err)
+
+	ret, err := parseCompoundData[BaseAnime](data)
+	require.NoError(t, err)
+	require.Len(t, ret, 1)
+	require.Equal(t, 21, ret[21].ID)
+
+	// Errors without data are still returned
+	err = json.Unmarshal([]byte(`{"errors": [{"message": "Too many requests"}], "data": null}`), &res)
+	require.NoError(t, err)
+	_, err = parseQueryResponse(res, true, logger)
+	require.EqualError(t, err, "Too many requests")
+}
@@ -14,6 +14,16 @@ import (
 )
 
 func customQuery(body []byte, logger *zerolog.Logger, token ...string) (data interface{}, err error) {
+	return doCustomQuery(body, logger, false, token...)
+}
+
+// customQueryPartial is like customQuery but it returns the data even if some fields failed.
+// It is used by compound queries, where an error in one aliased field should not discard the others.
+func customQueryPartial(body []byte, logger *zerolog.Logger, token ...string) (data interface{}, err error) {
+	return doCustomQuery(body, logger, true, token...)
+}
+
+func doCustomQuery(body []byte, logger *zerolog.Logger, allowPartial bool, token ...string) (data interface{}, err error) {
 
 	var rlRemainingStr string
 
@@ -111,18 +121,32 @@ func customQuery(body []byte, logger *zerolog.Logger, token ...string) (data int
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	var ok bool
+	return parseQueryResponse(res, allowPartial, logger)
+}
 
-	reqErrors, ok := res.(map[string]interface{})["errors"].([]interface{})
+// parseQueryResponse returns the data of a GraphQL response.
+// If allowPartial is true and the response has data, the errors are logged and the data is returned,
+// the fields that failed are null.
+func parseQueryResponse(res interface{}, allowPartial bool, logger *zerolog.Logger) (data interface{}, err error) {
+	resMap, ok := res.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("failed to parse response")
+	}
+
+	reqErrors, ok := resMap["errors"].([]interface{})
 
 	if ok && len(reqErrors) > 0 {
 		firstError, foundErr := reqErrors[0].(map[string]interface{})
 		if foundErr {
-			return nil, errors.New(firstError["message"].(string))
+			message, _ := firstError["message"].(string)
+			if dataMap, hasData := resMap["data"].(map[string]interface{}); !allowPartial || !hasData || len(dataMap) == 0 {
+				return nil, errors.New(message)
+			}
+			logger.Debug().Int("errors", len(reqErrors)).Str("first", message).Msg("anilist: Partial response")
 		}
 	}
 
-	data, ok = res.(map[string]interface{})["data"]
+	data, ok = resMap["data"]
 	if !ok {
 		return nil, errors.New("failed to parse data")
 	}
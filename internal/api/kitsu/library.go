@@ -0,0 +1,247 @@
+package kitsu
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const libraryPageLimit = 500
+
+type libraryEntriesResponse struct {
+	Data     []*LibraryEntry     `json:"data"`
+	Included []*includedResource `json:"included"`
+	Links    struct {
+		Next string `json:"next"`
+	} `json:"links"`
+}
+
+// GetLibraryEntries returns all the library entries of the user for the given kind.
+// The MyAnimeList and AniList IDs of each entry are resolved from Kitsu's mappings when available.
+func (w *Wrapper) GetLibraryEntries(kind MediaKind) ([]*LibraryEntry, error) {
+	w.logger.Debug().Str("kind", string(kind)).Msg("kitsu: Getting library entries")
+
+	if w.UserID == "" {
+		return nil, errors.New("kitsu: user id is empty")
+	}
+
+	query := url.Values{}
+	query.Set("filter[userId]", w.UserID)
+	query.Set("filter[kind]", string(kind))
+	query.Set("include", string(kind)+".mappings")
+	query.Set("fields["+string(kind)+"]", "mappings")
+	query.Set("fields[mappings]", "externalSite,externalId")
+	query.Set("page[limit]", strconv.Itoa(libraryPageLimit))
+
+	reqUrl := fmt.Sprintf("%s/library-entries?%s", w.baseUrl, query.Encode())
+
+	ret := make([]*LibraryEntry, 0)
+	for reqUrl != "" {
+		var data libraryEntriesResponse
+		err := w.doRequest("GET", reqUrl, nil, &data)
+		if err != nil {
+			w.logger.Error().Err(err).Msg("kitsu: Failed to get library entries")
+			return nil, err
+		}
+
+		resolveLibraryEntries(kind, data.Data, data.Included)
+		ret = append(ret, data.Data...)
+
+		reqUrl = data.Links.Next
+	}
+
+	w.logger.Info().Str("kind", string(kind)).Int("count", len(ret)).Msg("kitsu: Fetched library entries")
+
+	return ret, nil
+}
+
+// GetLibraryEntry returns the user's library entry for the given Kitsu media ID.
+// Returns ErrNotFound if the media is not in the user's library.
+func (w *Wrapper) GetLibraryEntry(kind MediaKind, mediaID string) (*LibraryEntry, error) {
+	query := url.Values{}
+	query.Set("filter[userId]", w.UserID)
+	query.Set("filter["+string(kind)+"Id]", mediaID)
+
+	var data libraryEntriesResponse
+	err := w.doRequest("GET", fmt.Sprintf("%s/library-entries?%s", w.baseUrl, query.Encode()), nil, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data.Data) == 0 {
+		return nil, ErrNotFound
+	}
+
+	resolveLibraryEntries(kind, data.Data, data.Included)
+
+	return data.Data[0], nil
+}
+
+// CreateLibraryEntry adds the media to the user's library.
+func (w *Wrapper) CreateLibraryEntry(kind MediaKind, mediaID string, attributes *LibraryEntryAttributes) (*LibraryEntry, error) {
+	w.logger.Debug().Str("mediaId", mediaID).Msg("kitsu: Creating library entry")
+
+	body := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type":       "libraryEntries",
+			"attributes": attributes,
+			"relationships": map[string]interface{}{
+				string(kind): relationship{Data: &resourceIdentifier{ID: mediaID, Type: string(kind)}},
+				"user":       relationship{Data: &resourceIdentifier{ID: w.UserID, Type: "users"}},
+			},
+		},
+	}
+
+	var data struct {
+		Data *LibraryEntry `json:"data"`
+	}
+	err := w.doRequest("POST", fmt.Sprintf("%s/library-entries", w.baseUrl), body, &data)
+	if err != nil {
+		w.logger.Error().Err(err).Str("mediaId", mediaID).Msg("kitsu: Failed to create library entry")
+		return nil, err
+	}
+
+	return data.Data, nil
+}
+
+// UpdateLibraryEntry updates an existing library entry.
+// Only the non-nil attributes are sent.
+func (w *Wrapper) UpdateLibraryEntry(entryID string, attributes *LibraryEntryAttributes) error {
+	w.logger.Debug().Str("entryId", entryID).Msg("kitsu: Updating library entry")
+
+	body := map[string]interface{}{
+		"data": map[string]interface{}{
+			"id":         entryID,
+			"type":       "libraryEntries",
+			"attributes": attributes,
+		},
+	}
+
+	err := w.doRequest("PATCH", fmt.Sprintf("%s/library-entries/%s", w.baseUrl, entryID), body, nil)
+	if err != nil {
+		w.logger.Error().Err(err).Str("entryId", entryID).Msg("kitsu: Failed to update library entry")
+		return err
+	}
+
+	return nil
+}
+
+// UpsertLibraryEntry updates the library entry of the media or creates it if it does not exist.
+func (w *Wrapper) UpsertLibraryEntry(kind MediaKind, mediaID string, attributes *LibraryEntryAttributes) error {
+	entry, err := w.GetLibraryEntry(kind, mediaID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	if entry == nil {
+		if attributes.Status == "" {
+			attributes.Status = LibraryEntryStatusCurrent
+		}
+		_, err = w.CreateLibraryEntry(kind, mediaID, attributes)
+		return err
+	}
+
+	return w.UpdateLibraryEntry(entry.ID, attributes)
+}
+
+// DeleteLibraryEntry removes the media from the user's library.
+func (w *Wrapper) DeleteLibraryEntry(kind MediaKind, mediaID string) error {
+	w.logger.Debug().Str("mediaId", mediaID).Msg("kitsu: Deleting library entry")
+
+	entry, err := w.GetLibraryEntry(kind, mediaID)
+	if err != nil {
+		return err
+	}
+
+	err = w.doRequest("DELETE", fmt.Sprintf("%s/library-entries/%s", w.baseUrl, entry.ID), nil, nil)
+	if err != nil {
+		w.logger.Error().Err(err).Str("mediaId", mediaID).Msg("kitsu: Failed to delete library entry")
+		return err
+	}
+
+	w.logger.Info().Str("mediaId", mediaID).Msg("kitsu: Deleted library entry")
+
+	return nil
+}
+
+// GetMediaIDByExternalID returns the Kitsu ID of the media mapped to the given external ID.
+// e.g. GetMediaIDByExternalID(ExternalSiteAnilistAnime, 21)
+func (w *Wrapper) GetMediaIDByExternalID(externalSite string, externalID int) (string, error) {
+	query := url.Values{}
+	query.Set("filter[externalSite]", externalSite)
+	query.Set("filter[externalId]", strconv.Itoa(externalID))
+	query.Set("include", "item")
+
+	var data struct {
+		Data []*struct {
+			ID            string `json:"id"`
+			Relationships struct {
+				Item relationship `json:"item"`
+			} `json:"relationships"`
+		} `json:"data"`
+	}
+	err := w.doRequest("GET", fmt.Sprintf("%s/mappings?%s", w.baseUrl, query.Encode()), nil, &data)
+	if err != nil {
+		return "", err
+	}
+
+	for _, mapping := range data.Data {
+		if mapping.Relationships.Item.Data != nil && mapping.Relationships.Item.Data.ID != "" {
+			return mapping.Relationships.Item.Data.ID, nil
+		}
+	}
+
+	return "", ErrNotFound
+}
+
+// resolveLibraryEntries sets the media, MyAnimeList and AniList IDs of the entries from the included resources.
+func resolveLibraryEntries(kind MediaKind, entries []*LibraryEntry, included []*includedResource) {
+	mappings := make(map[string]*includedResource)
+	media := make(map[string]*includedResource)
+	for _, res := range included {
+		switch res.Type {
+		case "mappings":
+			mappings[res.ID] = res
+		case string(kind):
+			media[res.ID] = res
+		}
+	}
+
+	malSite, anilistSite := ExternalSiteMyAnimeListAnime, ExternalSiteAnilistAnime
+	if kind == MediaKindManga {
+		malSite, anilistSite = ExternalSiteMyAnimeListManga, ExternalSiteAnilistManga
+	}
+
+	for _, entry := range entries {
+		rel := entry.Relationships.Anime
+		if kind == MediaKindManga {
+			rel = entry.Relationships.Manga
+		}
+		if rel.Data == nil {
+			continue
+		}
+		entry.MediaID = rel.Data.ID
+
+		m, ok := media[entry.MediaID]
+		if !ok {
+			continue
+		}
+		for _, ri := range m.Relationships.Mappings.Data {
+			mapping, ok := mappings[ri.ID]
+			if !ok {
+				continue
+			}
+			id, err := strconv.Atoi(mapping.Attributes.ExternalID)
+			if err != nil {
+				continue
+			}
+			switch mapping.Attributes.ExternalSite {
+			case malSite:
+				entry.MalID = id
+			case anilistSite:
+				entry.AnilistID = id
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package kitsu
+
+type (
+	MediaKind          string
+	LibraryEntryStatus string
+)
+
+const (
+	MediaKindAnime MediaKind = "anime"
+	MediaKindManga MediaKind = "manga"
+
+	LibraryEntryStatusCurrent   LibraryEntryStatus = "current"
+	LibraryEntryStatusPlanned   LibraryEntryStatus = "planned"
+	LibraryEntryStatusCompleted LibraryEntryStatus = "completed"
+	LibraryEntryStatusOnHold    LibraryEntryStatus = "on_hold"
+	LibraryEntryStatusDropped   LibraryEntryStatus = "dropped"
+)
+
+const (
+	ExternalSiteMyAnimeListAnime = "myanimelist/anime"
+	ExternalSiteMyAnimeListManga = "myanimelist/manga"
+	ExternalSiteAnilistAnime     = "anilist/anime"
+	ExternalSiteAnilistManga     = "anilist/manga"
+)
+
+type (
+	// resourceIdentifier is a JSON:API resource linkage
+	resourceIdentifier struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}
+
+	relationship struct {
+		Data *resourceIdentifier `json:"data,omitempty"`
+	}
+
+	relationshipMany struct {
+		Data []*resourceIdentifier `json:"data,omitempty"`
+	}
+
+	// includedResource is a resource from the "included" array of a JSON:API document.
+	// Only the fields used by the wrapper are decoded.
+	includedResource struct {
+		ID         string `json:"id"`
+		Type       string `json:"type"`
+		Attributes struct {
+			ExternalSite string `json:"externalSite"`
+			ExternalID   string `json:"externalId"`
+		} `json:"attributes"`
+		Relationships struct {
+			Mappings relationshipMany `json:"mappings"`
+		} `json:"relationships"`
+	}
+
+	LibraryEntryAttributes struct {
+		Status         LibraryEntryStatus `json:"status,omitempty"`
+		Progress       *int               `json:"progress,omitempty"`
+		RatingTwenty   *int               `json:"ratingTwenty,omitempty"`
+		Reconsuming    *bool              `json:"reconsuming,omitempty"`
+		ReconsumeCount *int               `json:"reconsumeCount,omitempty"`
+		StartedAt      *string            `json:"startedAt,omitempty"`
+		FinishedAt     *string            `json:"finishedAt,omitempty"`
+		Notes          *string            `json:"notes,omitempty"`
+		Private        *bool              `json:"private,omitempty"`
+	}
+
+	LibraryEntry struct {
+		ID            string                  `json:"id"`
+		Attributes    *LibraryEntryAttributes `json:"attributes"`
+		Relationships struct {
+			Anime relationship `json:"anime"`
+			Manga relationship `json:"manga"`
+		} `json:"relationships"`
+		// MediaID is the Kitsu ID of the anime or manga
+		MediaID string `json:"-"`
+		// MalID is the MyAnimeList ID of the media, 0 if Kitsu has no mapping
+		MalID int `json:"-"`
+		// AnilistID is the AniList ID of the media, 0 if Kitsu has no mapping
+		AnilistID int `json:"-"`
+	}
+
+	User struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Name string `json:"name"`
+			Slug string `json:"slug"`
+		} `json:"attributes"`
+	}
+)
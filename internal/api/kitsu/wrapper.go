@@ -0,0 +1,217 @@
+package kitsu
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/goccy/go-json"
+	"github.com/rs/zerolog"
+	"io"
+	"net/http"
+	"net/url"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"strings"
+	"time"
+)
+
+const (
+	ApiBaseURL   string = "https://kitsu.app/api/edge"
+	OAuthBaseURL string = "https://kitsu.app/api/oauth"
+	// jsonApiContentType is the content type required by Kitsu's JSON:API endpoints
+	jsonApiContentType string = "application/vnd.api+json"
+)
+
+var ErrNotFound = errors.New("kitsu: not found")
+
+type (
+	Wrapper struct {
+		AccessToken string
+		UserID      string
+		baseUrl     string
+		client      *http.Client
+		logger      *zerolog.Logger
+	}
+)
+
+func NewWrapper(accessToken string, userID string, logger *zerolog.Logger) *Wrapper {
+	return &Wrapper{
+		AccessToken: accessToken,
+		UserID:      userID,
+		baseUrl:     ApiBaseURL,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		logger:      logger,
+	}
+}
+
+func (w *Wrapper) doRequest(method, uri string, body interface{}, data interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, uri, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", jsonApiContentType)
+	req.Header.Add("Content-Type", jsonApiContentType)
+	if w.AccessToken != "" {
+		req.Header.Add("Authorization", "Bearer "+w.AccessToken)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	if !((resp.StatusCode >= 200) && (resp.StatusCode <= 299)) {
+		return fmt.Errorf("invalid response status %s", resp.Status)
+	}
+
+	if data == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(data); err != nil {
+		return err
+	}
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type authResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+}
+
+func requestToken(form url.Values) (*authResponse, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	req, err := http.NewRequest("POST", OAuthBaseURL+"/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kitsu: Failed to authenticate %s", res.Status)
+	}
+
+	ret := authResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+
+	if ret.AccessToken == "" {
+		return nil, errors.New("kitsu: No access token returned")
+	}
+
+	return &ret, nil
+}
+
+// Login authenticates the user with their Kitsu credentials and returns the account info.
+// The password is only used to obtain the tokens, it is never stored.
+func Login(username string, password string, logger *zerolog.Logger) (*models.Kitsu, error) {
+	form := url.Values{}
+	form.Set("grant_type", "password")
+	form.Set("username", username)
+	form.Set("password", password)
+
+	ret, err := requestToken(form)
+	if err != nil {
+		logger.Error().Err(err).Msg("kitsu: Failed to log in")
+		return nil, err
+	}
+
+	w := NewWrapper(ret.AccessToken, "", logger)
+	user, err := w.GetViewer()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Kitsu{
+		BaseModel: models.BaseModel{
+			ID:        1,
+			UpdatedAt: time.Now(),
+		},
+		UserID:         user.ID,
+		Username:       user.Attributes.Name,
+		AccessToken:    ret.AccessToken,
+		RefreshToken:   ret.RefreshToken,
+		TokenExpiresAt: time.Now().Add(time.Duration(ret.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// GetViewer returns the user associated with the access token.
+func (w *Wrapper) GetViewer() (*User, error) {
+	type response struct {
+		Data []*User `json:"data"`
+	}
+
+	var data response
+	err := w.doRequest("GET", fmt.Sprintf("%s/users?filter[self]=true", w.baseUrl), nil, &data)
+	if err != nil {
+		w.logger.Error().Err(err).Msg("kitsu: Failed to get viewer")
+		return nil, err
+	}
+
+	if len(data.Data) == 0 {
+		return nil, errors.New("kitsu: Could not find user")
+	}
+
+	return data.Data[0], nil
+}
+
+func VerifyKitsuAuth(kitsuInfo *models.Kitsu, db *db.Database, logger *zerolog.Logger) (*models.Kitsu, error) {
+
+	// Token has not expired
+	if kitsuInfo.TokenExpiresAt.After(time.Now()) {
+		return kitsuInfo, nil
+	}
+
+	// Token is expired, refresh it
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", kitsuInfo.RefreshToken)
+
+	ret, err := requestToken(form)
+	if err != nil {
+		logger.Error().Err(err).Msg("kitsu: Failed to refresh token")
+		return kitsuInfo, err
+	}
+
+	updatedKitsuInfo := *kitsuInfo
+	updatedKitsuInfo.UpdatedAt = time.Now()
+	updatedKitsuInfo.AccessToken = ret.AccessToken
+	updatedKitsuInfo.RefreshToken = ret.RefreshToken
+	updatedKitsuInfo.TokenExpiresAt = time.Now().Add(time.Duration(ret.ExpiresIn) * time.Second)
+
+	_, err = db.UpsertKitsuInfo(&updatedKitsuInfo)
+	if err != nil {
+		logger.Error().Err(err).Msg("kitsu: Failed to save updated Kitsu info")
+		return kitsuInfo, err
+	}
+
+	logger.Info().Msg("kitsu: Refreshed token")
+
+	return &updatedKitsuInfo, nil
+}
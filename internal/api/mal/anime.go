@@ -52,6 +52,8 @@ type (
 			NumEpisodesWatched int             `json:"num_episodes_watched"`
 			Score              int             `json:"score"`
 			UpdatedAt          string          `json:"updated_at"`
+			StartDate          string          `json:"start_date,omitempty"`
+			FinishDate         string          `json:"finish_date,omitempty"`
 		} `json:"list_status"`
 	}
 )
@@ -83,19 +85,28 @@ func (w *Wrapper) GetAnimeCollection() ([]*AnimeListEntry, error) {
 	reqUrl := fmt.Sprintf("%s/users/@me/animelist?fields=list_status&limit=1000", ApiBaseURL)
 
 	type response struct {
-		Data []*AnimeListEntry `json:"data"`
+		Data   []*AnimeListEntry `json:"data"`
+		Paging struct {
+			Next string `json:"next"`
+		} `json:"paging"`
 	}
 
-	var data response
-	err := w.doQuery("GET", reqUrl, nil, "application/json", &data)
-	if err != nil {
-		w.logger.Error().Err(err).Msg("mal: Failed to get anime collection")
-		return nil, err
+	ret := make([]*AnimeListEntry, 0)
+	// Follow the pagination links until all entries are fetched
+	for reqUrl != "" {
+		var data response
+		err := w.doQuery("GET", reqUrl, nil, "application/json", &data)
+		if err != nil {
+			w.logger.Error().Err(err).Msg("mal: Failed to get anime collection")
+			return nil, err
+		}
+		ret = append(ret, data.Data...)
+		reqUrl = data.Paging.Next
 	}
 
 	w.logger.Info().Msg("mal: Fetched anime collection")
 
-	return data.Data, nil
+	return ret, nil
 }
 
 type AnimeListProgressParams struct {
@@ -138,6 +149,8 @@ type AnimeListStatusParams struct {
 	IsRewatching       *bool
 	NumEpisodesWatched *int
 	Score              *int
+	StartDate          *string // YYYY-MM-DD
+	FinishDate         *string // YYYY-MM-DD
 }
 
 func (w *Wrapper) UpdateAnimeListStatus(opts *AnimeListStatusParams, mId int) error {
@@ -159,6 +172,12 @@ func (w *Wrapper) UpdateAnimeListStatus(opts *AnimeListStatusParams, mId int) er
 	if opts.Score != nil {
 		urlData.Set("score", fmt.Sprintf("%d", *opts.Score))
 	}
+	if opts.StartDate != nil {
+		urlData.Set("start_date", *opts.StartDate)
+	}
+	if opts.FinishDate != nil {
+		urlData.Set("finish_date", *opts.FinishDate)
+	}
 	encodedData := urlData.Encode()
 
 	err := w.doMutation("PATCH", reqUrl, encodedData)
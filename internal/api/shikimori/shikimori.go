@@ -0,0 +1,190 @@
+package shikimori
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/goccy/go-json"
+	"github.com/rs/zerolog"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Shikimori uses MyAnimeList IDs for its anime and manga.
+
+const (
+	ApiBaseURL string = "https://shikimori.one/api"
+	userAgent  string = "Seanime"
+)
+
+type (
+	TargetType     string
+	UserRateStatus string
+
+	Wrapper struct {
+		AccessToken string
+		baseUrl     string
+		client      *http.Client
+		logger      *zerolog.Logger
+		userID      int
+	}
+
+	User struct {
+		ID       int    `json:"id"`
+		Nickname string `json:"nickname"`
+	}
+
+	UserRate struct {
+		ID         int            `json:"id,omitempty"`
+		UserID     int            `json:"user_id,omitempty"`
+		TargetID   int            `json:"target_id,omitempty"`
+		TargetType TargetType     `json:"target_type,omitempty"`
+		Status     UserRateStatus `json:"status,omitempty"`
+		Score      *int           `json:"score,omitempty"`
+		Episodes   *int           `json:"episodes,omitempty"`
+		Chapters   *int           `json:"chapters,omitempty"`
+		Rewatches  *int           `json:"rewatches,omitempty"`
+	}
+)
+
+const (
+	TargetTypeAnime TargetType = "Anime"
+	TargetTypeManga TargetType = "Manga"
+)
+
+const (
+	UserRateStatusPlanned    UserRateStatus = "planned"
+	UserRateStatusWatching   UserRateStatus = "watching"
+	UserRateStatusRewatching UserRateStatus = "rewatching"
+	UserRateStatusCompleted  UserRateStatus = "completed"
+	UserRateStatusOnHold     UserRateStatus = "on_hold"
+	UserRateStatusDropped    UserRateStatus = "dropped"
+)
+
+func NewWrapper(accessToken string, logger *zerolog.Logger) *Wrapper {
+	return &Wrapper{
+		AccessToken: accessToken,
+		baseUrl:     ApiBaseURL,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		logger:      logger,
+	}
+}
+
+func (w *Wrapper) doRequest(method, uri string, body interface{}, data interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, w.baseUrl+uri, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("User-Agent", userAgent)
+	req.Header.Add("Authorization", "Bearer "+w.AccessToken)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !((resp.StatusCode >= 200) && (resp.StatusCode <= 299)) {
+		return fmt.Errorf("invalid response status %s", resp.Status)
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(data)
+}
+
+// GetViewer returns the user the access token belongs to.
+func (w *Wrapper) GetViewer() (*User, error) {
+	var ret User
+	if err := w.doRequest("GET", "/users/whoami", nil, &ret); err != nil {
+		return nil, err
+	}
+	if ret.ID == 0 {
+		return nil, fmt.Errorf("shikimori: Invalid access token")
+	}
+	return &ret, nil
+}
+
+func (w *Wrapper) getUserID() (int, error) {
+	if w.userID != 0 {
+		return w.userID, nil
+	}
+	user, err := w.GetViewer()
+	if err != nil {
+		return 0, err
+	}
+	w.userID = user.ID
+	return w.userID, nil
+}
+
+// GetUserRate returns the user's rate for the given media.
+// Returns nil if the media is not in the user's list.
+func (w *Wrapper) GetUserRate(targetType TargetType, malId int) (*UserRate, error) {
+	userId, err := w.getUserID()
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("user_id", strconv.Itoa(userId))
+	q.Set("target_id", strconv.Itoa(malId))
+	q.Set("target_type", string(targetType))
+
+	var ret []*UserRate
+	if err := w.doRequest("GET", "/v2/user_rates?"+q.Encode(), nil, &ret); err != nil {
+		return nil, err
+	}
+	if len(ret) == 0 {
+		return nil, nil
+	}
+	return ret[0], nil
+}
+
+// UpsertUserRate creates or updates the user's rate for the given media.
+// Only the non-empty fields of rate are sent.
+func (w *Wrapper) UpsertUserRate(targetType TargetType, malId int, rate *UserRate) error {
+	existing, err := w.GetUserRate(targetType, malId)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		rate.ID = 0
+		return w.doRequest("PATCH", fmt.Sprintf("/v2/user_rates/%d", existing.ID), map[string]interface{}{"user_rate": rate}, nil)
+	}
+
+	rate.UserID = w.userID
+	rate.TargetID = malId
+	rate.TargetType = targetType
+	if rate.Status == "" {
+		rate.Status = UserRateStatusWatching
+	}
+	return w.doRequest("POST", "/v2/user_rates", map[string]interface{}{"user_rate": rate}, nil)
+}
+
+// DeleteUserRate removes the media from the user's list.
+func (w *Wrapper) DeleteUserRate(targetType TargetType, malId int) error {
+	existing, err := w.GetUserRate(targetType, malId)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+
+	return w.doRequest("DELETE", fmt.Sprintf("/v2/user_rates/%d", existing.ID), nil, nil)
+}
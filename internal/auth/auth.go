@@ -0,0 +1,356 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ScopeFull = "full" // Full access
+	ScopeRead = "read" // Only safe methods (GET, HEAD)
+
+	KindSession = "session" // Created when logging in with the password
+	KindToken   = "token"   // Minted by the user for other devices
+
+	SessionCookieName = "Seanime-Session"
+	TokenQueryParam   = "token"
+
+	SessionDuration = 30 * 24 * time.Hour
+
+	minPasswordLength = 6
+	lastUsedThrottle  = time.Minute
+)
+
+var (
+	ErrInvalidPassword  = errors.New("auth: Invalid password")
+	ErrPasswordTooShort = errors.New("auth: Password is too short")
+	ErrInvalidScope     = errors.New("auth: Invalid scope")
+)
+
+// TooManyAttemptsError is returned by Manager.Login and LoginLimiter.Check when the client is locked out after too many failed attempts.
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("auth: Too many failed attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+type (
+	// Manager handles the server password, sessions and tokens.
+	// When no password is set, authentication is disabled and every request is allowed.
+	Manager struct {
+		db           *db.Database
+		logger       *zerolog.Logger
+		passwordHash string
+		// streamToken is a read-only token kept in memory.
+		// It is appended to the URLs that are opened by external media players since they cannot send cookies.
+		// Since it ends up in the history and logs of the players, it is only accepted by the media-serving paths.
+		streamToken  string
+		loginLimiter *LoginLimiter
+		mu           sync.RWMutex
+	}
+
+	NewManagerOptions struct {
+		Database *db.Database
+		Logger   *zerolog.Logger
+	}
+)
+
+func NewManager(opts *NewManagerOptions) *Manager {
+	m := &Manager{
+		db:           opts.Database,
+		logger:       opts.Logger,
+		streamToken:  generateToken(),
+		loginLimiter: NewLoginLimiter(),
+	}
+
+	serverAuth, err := opts.Database.GetServerAuth()
+	if err != nil {
+		m.logger.Error().Err(err).Msg("auth: Failed to get server password")
+	} else {
+		m.passwordHash = serverAuth.PasswordHash
+	}
+
+	_ = opts.Database.DeleteExpiredAuthTokens()
+
+	if m.IsEnabled() {
+		m.logger.Info().Msg("auth: Password authentication enabled")
+	}
+
+	return m
+}
+
+// IsEnabled returns true if a server password is set.
+func (m *Manager) IsEnabled() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.passwordHash != ""
+}
+
+func (m *Manager) checkPassword(password string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.passwordHash == "" {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(m.passwordHash), []byte(password)) == nil
+}
+
+// SetPassword sets, changes or removes the server password.
+// If a password is already set, currentPassword must match it.
+// Removing the password (newPassword == "") disables authentication.
+// All sessions are revoked, minted tokens are kept.
+func (m *Manager) SetPassword(currentPassword string, newPassword string) error {
+	if !m.checkPassword(currentPassword) {
+		return ErrInvalidPassword
+	}
+
+	hash := ""
+	if newPassword != "" {
+		if len(newPassword) < minPasswordLength {
+			return ErrPasswordTooShort
+		}
+		b, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		hash = string(b)
+	}
+
+	if err := m.db.UpsertServerAuth(&models.ServerAuth{PasswordHash: hash}); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.passwordHash = hash
+	m.mu.Unlock()
+
+	_ = m.db.DeleteAuthTokensByKind(KindSession)
+
+	if hash == "" {
+		m.logger.Info().Msg("auth: Password authentication disabled")
+	} else {
+		m.logger.Info().Msg("auth: Server password updated")
+	}
+
+	return nil
+}
+
+// Login verifies the password and creates a new session.
+// client identifies the origin of the request (e.g. the IP address), it is used to lock out clients after too many failed attempts.
+// A *TooManyAttemptsError is returned while the client is locked out.
+func (m *Manager) Login(password string, name string, client string) (token string, expiresAt time.Time, err error) {
+	if !m.IsEnabled() {
+		return "", time.Time{}, errors.New("auth: No password set")
+	}
+	if err := m.loginLimiter.Check(client); err != nil {
+		return "", time.Time{}, err
+	}
+	if !m.checkPassword(password) {
+		m.loginLimiter.Fail(client)
+		m.logger.Warn().Str("client", client).Msg("auth: Failed login attempt")
+		return "", time.Time{}, ErrInvalidPassword
+	}
+	m.loginLimiter.Reset(client)
+
+	token, t, err := m.createToken(name, KindSession, ScopeFull, SessionDuration)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, *t.ExpiresAt, nil
+}
+
+// Logout revokes the session.
+func (m *Manager) Logout(token string) error {
+	if token == "" {
+		return nil
+	}
+	return m.db.DeleteAuthTokenByHash(hashToken(token))
+}
+
+// CreateToken mints a token for another device.
+// If expiresIn is 0, the token never expires.
+// The token is only returned once, only its hash is stored.
+func (m *Manager) CreateToken(name string, scope string, expiresIn time.Duration) (string, *models.AuthToken, error) {
+	if scope != ScopeFull && scope != ScopeRead {
+		return "", nil, ErrInvalidScope
+	}
+	return m.createToken(name, KindToken, scope, expiresIn)
+}
+
+func (m *Manager) createToken(name string, kind string, scope string, expiresIn time.Duration) (string, *models.AuthToken, error) {
+	token := generateToken()
+	t := &models.AuthToken{
+		Name:      name,
+		Kind:      kind,
+		Scope:     scope,
+		TokenHash: hashToken(token),
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		t.ExpiresAt = &expiresAt
+	}
+
+	if err := m.db.InsertAuthToken(t); err != nil {
+		return "", nil, err
+	}
+	return token, t, nil
+}
+
+// GetTokens returns the tokens minted by the user.
+func (m *Manager) GetTokens() ([]*models.AuthToken, error) {
+	return m.db.GetAuthTokensByKind(KindToken)
+}
+
+// RevokeToken revokes a session or a token.
+func (m *Manager) RevokeToken(id uint) error {
+	return m.db.DeleteAuthToken(id)
+}
+
+// StreamToken returns the in-memory read-only token.
+func (m *Manager) StreamToken() string {
+	return m.streamToken
+}
+
+// isStreamToken returns true if the token is the stream token.
+func (m *Manager) isStreamToken(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(m.streamToken)) == 1
+}
+
+// Validate returns the scope of the token.
+// Returns false if the token is invalid or expired.
+// The stream token is not accepted, see ValidateStream.
+func (m *Manager) Validate(token string) (scope string, ok bool) {
+	t, ok := m.validate(token)
+	if !ok {
+		return "", false
+	}
+	return t.Scope, true
+}
+
+func (m *Manager) validate(token string) (*models.AuthToken, bool) {
+	if token == "" {
+		return nil, false
+	}
+
+	t, err := m.db.GetAuthTokenByHash(hashToken(token))
+	if err != nil {
+		return nil, false
+	}
+
+	now := time.Now()
+	if t.ExpiresAt != nil && t.ExpiresAt.Before(now) {
+		_ = m.db.DeleteAuthToken(t.ID)
+		return nil, false
+	}
+
+	if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) > lastUsedThrottle {
+		_ = m.db.UpdateAuthTokenLastUsedAt(t.ID, now)
+	}
+
+	return t, true
+}
+
+// extendSession extends a session that is used after half of SessionDuration, so that clients that are used regularly stay logged in.
+// It returns the new expiration time, false if the token is not a session or was not extended.
+func (m *Manager) extendSession(t *models.AuthToken) (time.Time, bool) {
+	if t.Kind != KindSession || t.ExpiresAt == nil || time.Until(*t.ExpiresAt) > SessionDuration/2 {
+		return time.Time{}, false
+	}
+
+	expiresAt := time.Now().Add(SessionDuration)
+	if err := m.db.UpdateAuthTokenExpiresAt(t.ID, expiresAt); err != nil {
+		m.logger.Warn().Err(err).Msg("auth: Failed to extend session")
+		return time.Time{}, false
+	}
+	return expiresAt, true
+}
+
+// IsAllowed returns true if a request with the given method is allowed for the scope.
+func IsAllowed(scope string, method string) bool {
+	switch scope {
+	case ScopeFull:
+		return true
+	case ScopeRead:
+		return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+	}
+	return false
+}
+
+// ValidateStream returns the scope of a token sent to a media-serving path.
+// Unlike Validate, the stream token is accepted.
+func (m *Manager) ValidateStream(token string) (scope string, ok bool) {
+	if m.isStreamToken(token) {
+		return ScopeRead, true
+	}
+	return m.Validate(token)
+}
+
+// ValidateRequest checks the token of a net/http request.
+// Always returns true if authentication is disabled.
+func (m *Manager) ValidateRequest(r *http.Request) bool {
+	if !m.IsEnabled() {
+		return true
+	}
+
+	scope, ok := m.Validate(tokenFromRequest(r))
+	return ok && IsAllowed(scope, r.Method)
+}
+
+// ValidateStreamRequest checks the token of a net/http request made to a streaming server.
+// Unlike ValidateRequest, the stream token is accepted.
+// Always returns true if authentication is disabled.
+func (m *Manager) ValidateStreamRequest(r *http.Request) bool {
+	if !m.IsEnabled() {
+		return true
+	}
+
+	scope, ok := m.ValidateStream(tokenFromRequest(r))
+	return ok && IsAllowed(scope, r.Method)
+}
+
+// tokenFromRequest returns the token sent with a net/http request, see TokenFromCtx.
+func tokenFromRequest(r *http.Request) string {
+	if token := ExtractBearerToken(r.Header.Get("Authorization")); token != "" {
+		return token
+	}
+	if token := r.URL.Query().Get(TokenQueryParam); token != "" {
+		return token
+	}
+	if c, err := r.Cookie(SessionCookieName); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// ExtractBearerToken returns the token from an "Authorization: Bearer <token>" header value.
+func ExtractBearerToken(header string) string {
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+func generateToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,293 @@
+package auth
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"seanime/internal/database/db"
+	"seanime/internal/util"
+	"testing"
+	"time"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Setenv("TEST_ENV", "true")
+	logger := util.NewLogger()
+	database, err := db.NewDatabase(t.TempDir(), "test", logger)
+	require.NoError(t, err)
+
+	return NewManager(&NewManagerOptions{
+		Database: database,
+		Logger:   logger,
+	})
+}
+
+func TestPasswordAndSessions(t *testing.T) {
+	m := newTestManager(t)
+	assert.False(t, m.IsEnabled())
+
+	require.NoError(t, m.SetPassword("", "hunter22"))
+	assert.True(t, m.IsEnabled())
+
+	_, _, err := m.Login("wrong", "test", "127.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+
+	token, expiresAt, err := m.Login("hunter22", "test", "127.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, expiresAt.After(time.Now()))
+
+	scope, ok := m.Validate(token)
+	assert.True(t, ok)
+	assert.Equal(t, ScopeFull, scope)
+
+	// Changing the password requires the current one and revokes the sessions
+	assert.ErrorIs(t, m.SetPassword("wrong", "hunter33"), ErrInvalidPassword)
+	require.NoError(t, m.SetPassword("hunter22", "hunter33"))
+	_, ok = m.Validate(token)
+	assert.False(t, ok)
+
+	// Removing the password disables authentication
+	require.NoError(t, m.SetPassword("hunter33", ""))
+	assert.False(t, m.IsEnabled())
+}
+
+func TestScopedTokens(t *testing.T) {
+	m := newTestManager(t)
+	require.NoError(t, m.SetPassword("", "hunter22"))
+
+	token, _, err := m.CreateToken("TV", ScopeRead, 0)
+	require.NoError(t, err)
+
+	_, _, err = m.CreateToken("TV", "admin", 0)
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	get, _ := http.NewRequest(http.MethodGet, "/api/v1/status?token="+token, nil)
+	assert.True(t, m.ValidateRequest(get))
+
+	post, _ := http.NewRequest(http.MethodPost, "/api/v1/settings", nil)
+	post.Header.Set("Authorization", "Bearer "+token)
+	assert.False(t, m.ValidateRequest(post))
+
+	// Expired token
+	expired, _, err := m.CreateToken("Old", ScopeFull, time.Nanosecond)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, ok := m.Validate(expired)
+	assert.False(t, ok)
+
+	// Revoked token
+	tokens, err := m.GetTokens()
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.NoError(t, m.RevokeToken(tokens[0].ID))
+	_, ok = m.Validate(token)
+	assert.False(t, ok)
+}
+
+func TestIsProtected(t *testing.T) {
+	assert.True(t, isProtected("/api/v1/settings"))
+	assert.True(t, isProtected("/events"))
+	assert.True(t, isProtected("/manga-downloads/foo/1.jpg"))
+	assert.False(t, isProtected("/api/v1/server-auth/login"))
+	assert.False(t, isProtected("/index.html"))
+	assert.False(t, isProtected("/assetsfoo"))
+
+	// Fiber matches routes case-insensitively and ignores trailing slashes
+	assert.True(t, isProtected("/API/v1/settings"))
+	assert.True(t, isProtected("/Events"))
+	assert.True(t, isProtected("/api/V1/settings/"))
+	assert.False(t, isProtected("/API/v1/Server-Auth/Login/"))
+}
+
+func TestFiberMiddlewareMixedCase(t *testing.T) {
+	m := newTestManager(t)
+	require.NoError(t, m.SetPassword("", "hunter22"))
+
+	app := fiber.New()
+	app.Use(m.FiberMiddleware())
+	app.Get("/api/v1/settings", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	app.Get("/events", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	for _, path := range []string{"/api/v1/settings", "/API/v1/settings", "/Api/V1/Settings/", "/events", "/EVENTS"} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode, path)
+	}
+
+	token, _, err := m.Login("hunter22", "test", "127.0.0.1")
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/API/v1/settings", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func TestSessionExtension(t *testing.T) {
+	m := newTestManager(t)
+	require.NoError(t, m.SetPassword("", "hunter22"))
+
+	app := fiber.New()
+	app.Use(m.FiberMiddleware())
+	app.Get("/api/v1/settings", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	token, _, err := m.Login("hunter22", "test", "127.0.0.1")
+	require.NoError(t, err)
+
+	request := func() *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/settings", nil)
+		req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: token})
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		return resp
+	}
+
+	// A new session is not extended
+	assert.Empty(t, request().Header.Get(fiber.HeaderSetCookie))
+
+	// A session used after half of its duration is extended and the cookie is sent again
+	session, err := m.db.GetAuthTokenByHash(hashToken(token))
+	require.NoError(t, err)
+	require.NoError(t, m.db.UpdateAuthTokenExpiresAt(session.ID, time.Now().Add(24*time.Hour)))
+
+	assert.Contains(t, request().Header.Get(fiber.HeaderSetCookie), SessionCookieName+"="+token)
+	session, err = m.db.GetAuthTokenByHash(hashToken(token))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(SessionDuration), *session.ExpiresAt, time.Minute)
+
+	// Minted tokens are never extended
+	minted, _, err := m.CreateToken("TV", ScopeFull, 24*time.Hour)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/settings", nil)
+	req.Header.Set("Authorization", "Bearer "+minted)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Header.Get(fiber.HeaderSetCookie))
+	tokens, err := m.GetTokens()
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), *tokens[0].ExpiresAt, time.Minute)
+}
+
+func TestStreamToken(t *testing.T) {
+	m := newTestManager(t)
+	require.NoError(t, m.SetPassword("", "hunter22"))
+
+	app := fiber.New()
+	app.Use(m.FiberMiddleware())
+	for _, path := range []string{"/api/v1/settings", "/api/v1/mediastream/settings", "/api/v1/mediastream/file/*", "/manga-downloads/*"} {
+		app.Get(path, func(c *fiber.Ctx) error {
+			return c.SendString("ok")
+		})
+	}
+	app.Post("/api/v1/mediastream/file/*", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	// The stream token is only accepted by the media-serving paths
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, "/api/v1/settings", fiber.StatusUnauthorized},
+		{http.MethodGet, "/API/v1/Settings/", fiber.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/mediastream/settings", fiber.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/mediastream/file/abc", fiber.StatusOK},
+		{http.MethodGet, "/API/v1/MediaStream/file/abc", fiber.StatusOK},
+		{http.MethodGet, "/manga-downloads/foo/1.jpg", fiber.StatusOK},
+		{http.MethodPost, "/api/v1/mediastream/file/abc", fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(tt.method, tt.path+"?token="+m.StreamToken(), nil))
+		require.NoError(t, err)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.method+" "+tt.path)
+	}
+
+	get, _ := http.NewRequest(http.MethodGet, "/stream/foo.mkv?token="+m.StreamToken(), nil)
+	assert.False(t, m.ValidateRequest(get))
+	assert.True(t, m.ValidateStreamRequest(get))
+}
+
+func TestReadTokenRoutes(t *testing.T) {
+	m := newTestManager(t)
+	require.NoError(t, m.SetPassword("", "hunter22"))
+
+	token, _, err := m.CreateToken("TV", ScopeRead, 0)
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(m.FiberMiddleware())
+	for _, path := range []string{"/api/v1/settings", "/api/v1/debrid/settings", "/api/v1/library/collection", "/api/v1/mediastream/file/*"} {
+		app.Get(path, func(c *fiber.Ctx) error {
+			return c.SendString("ok")
+		})
+	}
+
+	// Read-only tokens cannot read the settings, which contain secrets
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/api/v1/settings", fiber.StatusForbidden},
+		{"/API/v1/Debrid/Settings/", fiber.StatusForbidden},
+		{"/api/v1/library/collection", fiber.StatusOK},
+		{"/api/v1/mediastream/file/abc", fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.path)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	m := newTestManager(t)
+	require.NoError(t, m.SetPassword("", "hunter22"))
+
+	now := time.Now()
+	m.loginLimiter.now = func() time.Time { return now }
+
+	for i := 0; i < maxFailedLogins; i++ {
+		_, _, err := m.Login("wrong", "test", "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidPassword)
+	}
+
+	// Locked out, even with the right password
+	_, _, err := m.Login("hunter22", "test", "10.0.0.1")
+	var tooManyAttempts *TooManyAttemptsError
+	require.ErrorAs(t, err, &tooManyAttempts)
+	assert.Equal(t, baseLoginLockout, tooManyAttempts.RetryAfter)
+
+	// Other clients are not affected
+	_, _, err = m.Login("hunter22", "test", "10.0.0.2")
+	assert.NoError(t, err)
+
+	// The lockout doubles with each failed attempt
+	now = now.Add(baseLoginLockout)
+	_, _, err = m.Login("wrong", "test", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+	_, _, err = m.Login("hunter22", "test", "10.0.0.1")
+	require.ErrorAs(t, err, &tooManyAttempts)
+	assert.Equal(t, 2*baseLoginLockout, tooManyAttempts.RetryAfter)
+
+	// A successful login resets the counter
+	now = now.Add(2 * baseLoginLockout)
+	_, _, err = m.Login("hunter22", "test", "10.0.0.1")
+	require.NoError(t, err)
+	_, _, err = m.Login("wrong", "test", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+	_, _, err = m.Login("hunter22", "test", "10.0.0.1")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginLimiter slows down password, PIN and secret guessing.
+// After maxFailedLogins failed attempts from the same client, the client is locked out.
+// The lockout duration doubles with each additional failed attempt, up to maxLoginLockout.
+// A successful login resets the counter.
+
+const (
+	maxFailedLogins  = 5
+	baseLoginLockout = 30 * time.Second
+	maxLoginLockout  = 15 * time.Minute
+	// Failed attempts older than this are forgotten
+	failedLoginWindow = time.Hour
+)
+
+type (
+	LoginLimiter struct {
+		mu      sync.Mutex
+		clients map[string]*loginAttempts
+		now     func() time.Time // Replaced in tests
+	}
+
+	loginAttempts struct {
+		failed      int
+		lastFailed  time.Time
+		lockedUntil time.Time
+	}
+)
+
+func NewLoginLimiter() *LoginLimiter {
+	return &LoginLimiter{
+		clients: make(map[string]*loginAttempts),
+		now:     time.Now,
+	}
+}
+
+// Check returns a *TooManyAttemptsError if the client is locked out.
+func (l *LoginLimiter) Check(client string) error {
+	if retryAfter := l.RetryAfter(client); retryAfter > 0 {
+		return &TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RetryAfter returns how long the client has to wait before trying again, 0 if it is not locked out.
+func (l *LoginLimiter) RetryAfter(client string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	a, ok := l.clients[client]
+	if !ok {
+		return 0
+	}
+	now := l.now()
+	if now.Before(a.lockedUntil) {
+		return a.lockedUntil.Sub(now)
+	}
+	return 0
+}
+
+// Fail records a failed attempt.
+func (l *LoginLimiter) Fail(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.cleanup(now)
+
+	a, ok := l.clients[client]
+	if !ok {
+		a = &loginAttempts{}
+		l.clients[client] = a
+	}
+	a.failed++
+	a.lastFailed = now
+
+	if a.failed >= maxFailedLogins {
+		lockout := baseLoginLockout << min(a.failed-maxFailedLogins, 10)
+		a.lockedUntil = now.Add(min(lockout, maxLoginLockout))
+	}
+}
+
+// Reset forgets the failed attempts of the client.
+func (l *LoginLimiter) Reset(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.clients, client)
+}
+
+// cleanup removes the clients that are no longer locked out and have not failed recently.
+// The mutex must be held.
+func (l *LoginLimiter) cleanup(now time.Time) {
+	for client, a := range l.clients {
+		if now.After(a.lockedUntil) && now.Sub(a.lastFailed) > failedLoginWindow {
+			delete(l.clients, client)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"strings"
+	"time"
+)
+
+// protectedPrefixes are the paths that require authentication.
+// The web interface itself is public so that the login page can be displayed.
+var protectedPrefixes = []string{
+	"/api",
+	"/events",
+	"/assets",
+	"/manga-downloads",
+	"/offline-assets",
+}
+
+// publicPaths are the API routes that can be accessed without authentication.
+// The continuity peer sync is authenticated with its own secret, see continuity.HandlePeerSync.
+var publicPaths = []string{
+	"/api/v1/server-auth/login",
+	"/api/v1/server-auth/status",
+	"/api/v1/continuity/sync",
+}
+
+// streamPrefixes are the media-serving paths that accept the stream token.
+// These are opened by external media players, see Manager.StreamToken.
+var streamPrefixes = []string{
+	"/api/v1/mediastream/transcode",
+	"/api/v1/mediastream/subs",
+	"/api/v1/mediastream/att",
+	"/api/v1/mediastream/direct",
+	"/api/v1/mediastream/file",
+	"/assets",
+	"/manga-downloads",
+	"/offline-assets",
+}
+
+// readPrefixes are the paths that read-only tokens can access: the media-serving paths and the read-only library routes.
+// Other routes, e.g. the settings, can return secrets such as API keys and passwords.
+var readPrefixes = append([]string{
+	"/api/v1/mediastream/trickplay",
+	"/api/v1/library/collection",
+	"/api/v1/library/anime-entry",
+	"/api/v1/library/missing-episodes",
+	"/api/v1/anilist/collection",
+	"/api/v1/anilist/media-details",
+	"/api/v1/manga/collection",
+	"/api/v1/manga/entry",
+	"/api/v1/manga/downloaded-chapters",
+	"/api/v1/manga/local-library",
+	"/api/v1/manga/local-page",
+	"/api/v1/torrentstream/episodes",
+	"/api/v1/continuity/item",
+	"/api/v1/continuity/history",
+	"/api/v1/playlists",
+	"/api/v1/playlist/episodes",
+}, streamPrefixes...)
+
+// normalizePath returns the path the way Fiber matches routes: lowercase and without trailing slashes.
+func normalizePath(path string) string {
+	path = strings.ToLower(path)
+	if len(path) > 1 {
+		path = strings.TrimRight(path, "/")
+	}
+	return path
+}
+
+// isStreamPath returns true if the stream token is accepted for the path.
+func isStreamPath(path string) bool {
+	path = normalizePath(path)
+	for _, p := range streamPrefixes {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isReadPath returns true if read-only tokens can access the path.
+func isReadPath(path string) bool {
+	path = normalizePath(path)
+	for _, p := range readPrefixes {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isProtected returns true if the path requires authentication.
+// The path is normalized the same way Fiber matches routes: case-insensitively and without trailing slashes,
+// e.g. "/API/v1/status/" is handled by "/api/v1/status".
+func isProtected(path string) bool {
+	path = normalizePath(path)
+	for _, p := range publicPaths {
+		if path == p {
+			return false
+		}
+	}
+	for _, p := range protectedPrefixes {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenFromCtx returns the token sent with the request.
+// The token is read from the Authorization header, the "token" query parameter or the session cookie, in that order.
+func TokenFromCtx(c *fiber.Ctx) string {
+	if token := ExtractBearerToken(c.Get(fiber.HeaderAuthorization)); token != "" {
+		return token
+	}
+	if token := c.Query(TokenQueryParam); token != "" {
+		return token
+	}
+	return c.Cookies(SessionCookieName)
+}
+
+// SetSessionCookie sets the session cookie sent by the web interface.
+func SetSessionCookie(c *fiber.Ctx, token string, expiresAt time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// FiberMiddleware enforces authentication on the protected paths.
+// It should be registered before any other handler.
+// The scope of the request is stored in the "authScope" local.
+// Sessions are extended while they are used, the session cookie is then sent again with the new expiration time.
+// The web interface displays the login page when a request returns a 401 status.
+func (m *Manager) FiberMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !m.IsEnabled() || c.Method() == fiber.MethodOptions || !isProtected(c.Path()) {
+			return c.Next()
+		}
+
+		token := TokenFromCtx(c)
+
+		var scope string
+		var ok bool
+		if isStreamPath(c.Path()) && m.isStreamToken(token) {
+			scope, ok = ScopeRead, true
+		} else if t, valid := m.validate(token); valid {
+			scope, ok = t.Scope, true
+			if expiresAt, extended := m.extendSession(t); extended && c.Cookies(SessionCookieName) == token {
+				SetSessionCookie(c, token, expiresAt)
+			}
+		}
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		}
+
+		if !IsAllowed(scope, c.Method()) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "this token is read-only"})
+		}
+		if scope == ScopeRead && !isReadPath(c.Path()) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "this token can only access media and library routes"})
+		}
+
+		c.Locals("authScope", scope)
+
+		return c.Next()
+	}
+}
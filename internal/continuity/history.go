@@ -62,41 +62,74 @@ type (
 
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+// GetWatchHistory returns the watch history of the server profile.
 func (m *Manager) GetWatchHistory() WatchHistory {
+	m.mu.RLock()
+	bucket := *m.watchHistoryFileCacheBucket
+	m.mu.RUnlock()
+	return m.getWatchHistoryIn(bucket)
+}
+
+// GetProfileWatchHistory returns the watch history of the profile.
+func (m *Manager) GetProfileWatchHistory(profileID uint) WatchHistory {
+	return m.getWatchHistoryIn(newWatchHistoryBucket(profileID))
+}
+
+func (m *Manager) getWatchHistoryIn(bucket filecache.Bucket) WatchHistory {
 	defer util.HandlePanicInModuleThen("continuity/GetWatchHistory", func() {})
 
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	items, err := filecache.GetAll[*WatchHistoryItem](m.fileCacher, *m.watchHistoryFileCacheBucket)
+	ret, err := m.getAllWatchHistory(bucket)
 	if err != nil {
 		m.logger.Error().Err(err).Msg("continuity: Failed to get watch history")
 		return nil
 	}
 
-	ret := make(WatchHistory)
-	for _, item := range items {
-		ret[item.MediaId] = item
-	}
-
 	return ret
 }
 
+// GetWatchHistoryItem returns the item of the server profile.
 func (m *Manager) GetWatchHistoryItem(mediaId int) *WatchHistoryItemResponse {
+	m.mu.RLock()
+	bucket := *m.watchHistoryFileCacheBucket
+	m.mu.RUnlock()
+	return m.getWatchHistoryItemIn(bucket, mediaId)
+}
+
+// GetProfileWatchHistoryItem returns the item of the profile.
+func (m *Manager) GetProfileWatchHistoryItem(profileID uint, mediaId int) *WatchHistoryItemResponse {
+	return m.getWatchHistoryItemIn(newWatchHistoryBucket(profileID), mediaId)
+}
+
+func (m *Manager) getWatchHistoryItemIn(bucket filecache.Bucket, mediaId int) *WatchHistoryItemResponse {
 	defer util.HandlePanicInModuleThen("continuity/GetWatchHistoryItem", func() {})
 
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	i, found := m.getWatchHistory(mediaId)
+	i, found := m.getWatchHistory(bucket, mediaId)
 	return &WatchHistoryItemResponse{
 		Item:  i,
 		Found: found,
 	}
 }
 
-// UpdateWatchHistoryItem updates the WatchHistoryItem in the file cache.
+// UpdateWatchHistoryItem updates the WatchHistoryItem of the server profile in the file cache.
 func (m *Manager) UpdateWatchHistoryItem(opts *UpdateWatchHistoryItemOptions) (err error) {
+	m.mu.RLock()
+	bucket := *m.watchHistoryFileCacheBucket
+	m.mu.RUnlock()
+	return m.updateWatchHistoryItemIn(bucket, opts)
+}
+
+// UpdateProfileWatchHistoryItem updates the WatchHistoryItem of the profile in the file cache.
+func (m *Manager) UpdateProfileWatchHistoryItem(profileID uint, opts *UpdateWatchHistoryItemOptions) (err error) {
+	return m.updateWatchHistoryItemIn(newWatchHistoryBucket(profileID), opts)
+}
+
+func (m *Manager) updateWatchHistoryItemIn(bucket filecache.Bucket, opts *UpdateWatchHistoryItemOptions) (err error) {
 	defer util.HandlePanicInModuleWithError("continuity/UpdateWatchHistoryItem", &err)
 
 	m.mu.Lock()
@@ -105,7 +138,7 @@ func (m *Manager) UpdateWatchHistoryItem(opts *UpdateWatchHistoryItemOptions) (e
 	added := false
 
 	// Get the current history
-	i, found := m.getWatchHistory(opts.MediaId)
+	i, found := m.getWatchHistory(bucket, opts.MediaId)
 	if !found {
 		added = true
 		i = &WatchHistoryItem{
@@ -127,16 +160,18 @@ func (m *Manager) UpdateWatchHistoryItem(opts *UpdateWatchHistoryItemOptions) (e
 	}
 
 	// Save the i
-	err = m.fileCacher.Set(*m.watchHistoryFileCacheBucket, strconv.Itoa(opts.MediaId), i)
+	err = m.fileCacher.Set(bucket, strconv.Itoa(opts.MediaId), i)
 	if err != nil {
 		return fmt.Errorf("continuity: Failed to save watch history item: %w", err)
 	}
 
 	// If the item was added, check if we need to remove the oldest item
 	if added {
-		_ = m.trimWatchHistoryItems()
+		_ = m.trimWatchHistoryItems(bucket)
 	}
 
+	m.triggerPeerSync()
+
 	return nil
 }
 
@@ -171,7 +206,7 @@ func (m *Manager) GetExternalPlayerEpisodeWatchHistoryItem(path string, isStream
 			return
 		}
 
-		i, found := m.getWatchHistory(mediaId)
+		i, found := m.getWatchHistory(*m.watchHistoryFileCacheBucket, mediaId)
 		if !found || i.EpisodeNumber != episode {
 			return
 		}
@@ -210,7 +245,7 @@ func (m *Manager) GetExternalPlayerEpisodeWatchHistoryItem(path string, isStream
 			return
 		}
 
-		i, found := m.getWatchHistory(lf.MediaId)
+		i, found := m.getWatchHistory(*m.watchHistoryFileCacheBucket, lf.MediaId)
 		if !found || i.EpisodeNumber != lf.GetEpisodeNumber() {
 			return
 		}
@@ -246,7 +281,7 @@ func (m *Manager) UpdateExternalPlayerEpisodeWatchHistoryItem(currentTime, durat
 	}
 
 	// Get the current history
-	i, found := m.getWatchHistory(opts.MediaId)
+	i, found := m.getWatchHistory(*m.watchHistoryFileCacheBucket, opts.MediaId)
 	if !found {
 		added = true
 		i = &WatchHistoryItem{
@@ -272,41 +307,60 @@ func (m *Manager) UpdateExternalPlayerEpisodeWatchHistoryItem(currentTime, durat
 
 	// If the item was added, check if we need to remove the oldest item
 	if added {
-		_ = m.trimWatchHistoryItems()
+		_ = m.trimWatchHistoryItems(*m.watchHistoryFileCacheBucket)
 	}
 
+	m.triggerPeerSync()
+
 	return
 }
 
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
-func (m *Manager) getWatchHistory(mediaId int) (ret *WatchHistoryItem, exists bool) {
-	exists, _ = m.fileCacher.Get(*m.watchHistoryFileCacheBucket, strconv.Itoa(mediaId), &ret)
+func (m *Manager) getWatchHistory(bucket filecache.Bucket, mediaId int) (ret *WatchHistoryItem, exists bool) {
+	exists, _ = m.fileCacher.Get(bucket, strconv.Itoa(mediaId), &ret)
 	return
 }
 
-// removes the oldest WatchHistoryItem from the file cache.
-func (m *Manager) trimWatchHistoryItems() error {
+// getAllWatchHistory returns the watch history stored in the bucket.
+func (m *Manager) getAllWatchHistory(bucket filecache.Bucket) (WatchHistory, error) {
+	items, err := filecache.GetAll[*WatchHistoryItem](m.fileCacher, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(WatchHistory)
+	for _, item := range items {
+		ret[item.MediaId] = item
+	}
+
+	return ret, nil
+}
+
+// removes the oldest WatchHistoryItem from the file cache until there are at most MaxWatchHistoryItems.
+// More than one item can be over the limit after merging the history of a peer.
+func (m *Manager) trimWatchHistoryItems(bucket filecache.Bucket) error {
 	defer util.HandlePanicInModuleThen("continuity/TrimWatchHistoryItems", func() {})
 
 	// Get all the items
-	items, err := filecache.GetAll[*WatchHistoryItem](m.fileCacher, *m.watchHistoryFileCacheBucket)
+	items, err := filecache.GetAll[*WatchHistoryItem](m.fileCacher, bucket)
 	if err != nil {
 		return fmt.Errorf("continuity: Failed to get watch history items: %w", err)
 	}
 
-	// If there are too many items, remove the oldest one
-	if len(items) > MaxWatchHistoryItems {
+	// If there are too many items, remove the oldest ones
+	for len(items) > MaxWatchHistoryItems {
 		var oldestKey string
 		for key := range items {
 			if oldestKey == "" || items[key].TimeUpdated.Before(items[oldestKey].TimeUpdated) {
 				oldestKey = key
 			}
 		}
-		err = m.fileCacher.Delete(*m.watchHistoryFileCacheBucket, oldestKey)
+		err = m.fileCacher.Delete(bucket, oldestKey)
 		if err != nil {
 			return fmt.Errorf("continuity: Failed to remove oldest watch history item: %w", err)
 		}
+		delete(items, oldestKey)
 	}
 
 	return nil
@@ -1,8 +1,11 @@
 package continuity
 
 import (
+	"context"
+	"fmt"
 	"github.com/rs/zerolog"
 	"github.com/samber/mo"
+	"seanime/internal/auth"
 	"seanime/internal/database/db"
 	"seanime/internal/util/filecache"
 	"sync"
@@ -27,6 +30,11 @@ type (
 		logger   *zerolog.Logger
 		settings *Settings
 		mu       sync.RWMutex
+
+		peerSyncCh      chan struct{}
+		peerSyncCancel  context.CancelFunc
+		lastPeerSync    time.Time
+		peerSyncLimiter *auth.LoginLimiter // Locks out clients that send too many invalid secrets
 	}
 
 	// ExternalPlayerEpisodeDetails is used to store the episode details when using an external player.
@@ -39,6 +47,13 @@ type (
 
 	Settings struct {
 		WatchContinuityEnabled bool
+		// Sync the watch history with another instance, see sync.go
+		PeerSyncEnabled bool
+		PeerSyncUrl     string // e.g. http://192.168.1.10:43211
+		PeerSyncSecret  string // Shared by both instances
+		// PeerSyncProfileID is the profile whose watch history is synced, the one that configured the sync.
+		// The server profile is not used since it can be changed.
+		PeerSyncProfileID uint
 	}
 
 	Kind string
@@ -65,6 +80,8 @@ func NewManager(opts *NewManagerOptions) *Manager {
 			WatchContinuityEnabled: false,
 		},
 		externalPlayerEpisodeDetails: mo.None[*ExternalPlayerEpisodeDetails](),
+		peerSyncCh:                   make(chan struct{}, 1),
+		peerSyncLimiter:              auth.NewLoginLimiter(),
 	}
 
 	ret.logger.Info().Msg("continuity: Initialized manager")
@@ -73,6 +90,7 @@ func NewManager(opts *NewManagerOptions) *Manager {
 }
 
 // SetSettings should be called after initializing the Manager.
+// The peer sync is restarted with the new settings.
 func (m *Manager) SetSettings(settings *Settings) {
 	if m == nil || settings == nil {
 		return
@@ -81,6 +99,7 @@ func (m *Manager) SetSettings(settings *Settings) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.settings = settings
+	m.startPeerSyncLoop()
 }
 
 // GetSettings returns the current settings.
@@ -94,6 +113,40 @@ func (m *Manager) GetSettings() *Settings {
 	return m.settings
 }
 
+// SetProfile switches the watch history used by the background modules (e.g. the external player tracking) to the one of the server profile.
+func (m *Manager) SetProfile(profileID uint) {
+	if m == nil {
+		return
+	}
+
+	bucket := newWatchHistoryBucket(profileID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watchHistoryFileCacheBucket = &bucket
+}
+
+// DeleteProfileHistory deletes the watch history of the profile.
+func (m *Manager) DeleteProfileHistory(profileID uint) error {
+	if m == nil {
+		return nil
+	}
+	return m.fileCacher.Remove(watchHistoryBucketName(profileID))
+}
+
+func newWatchHistoryBucket(profileID uint) filecache.Bucket {
+	return filecache.NewBucket(watchHistoryBucketName(profileID), time.Hour*24*99999)
+}
+
+// watchHistoryBucketName returns the name of the watch history bucket of the profile.
+// The default profile keeps the original bucket so that existing history is preserved.
+func watchHistoryBucketName(profileID uint) string {
+	if profileID == db.DefaultProfileID {
+		return WatchHistoryBucketName
+	}
+	return fmt.Sprintf("%s_%d", WatchHistoryBucketName, profileID)
+}
+
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 func (m *Manager) SetExternalPlayerEpisodeDetails(details *ExternalPlayerEpisodeDetails) {
@@ -0,0 +1,270 @@
+package continuity
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"seanime/internal/database/db"
+	"seanime/internal/util"
+	"seanime/internal/util/filecache"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Peer sync
+//
+// Two instances can exchange their watch history so that playback is resumed where it was left off on the other one.
+// The instance that initiates the sync sends its history to the peer, the peer merges it and returns its own history, which is merged in turn.
+// Conflicts are resolved by keeping the most recently updated item.
+// Only the watch history of the profile that configured the sync is synced, see Settings.PeerSyncProfileID.
+// Both instances must be configured with the same secret.
+
+const (
+	PeerSyncSecretHeader = "X-Seanime-Sync-Secret"
+	PeerSyncPath         = "/api/v1/continuity/sync"
+
+	peerSyncInterval    = time.Minute
+	peerSyncMinInterval = 15 * time.Second // Minimum delay between two syncs triggered by updates
+	peerSyncTimeout     = 15 * time.Second
+)
+
+var (
+	ErrPeerSyncDisabled     = errors.New("continuity: Peer sync is disabled")
+	ErrPeerSyncUnauthorized = errors.New("continuity: Invalid peer sync secret")
+)
+
+type (
+	PeerSyncRequest struct {
+		History WatchHistory `json:"history"`
+	}
+
+	peerSyncResponse struct {
+		Data  WatchHistory `json:"data"`
+		Error string       `json:"error,omitempty"`
+	}
+)
+
+func (s *Settings) isPeerSyncEnabled() bool {
+	return s.WatchContinuityEnabled && s.PeerSyncEnabled && s.PeerSyncSecret != ""
+}
+
+// peerSyncBucket returns the watch history bucket of the profile that is synced.
+func (s *Settings) peerSyncBucket() filecache.Bucket {
+	profileID := s.PeerSyncProfileID
+	if profileID == 0 {
+		profileID = db.DefaultProfileID
+	}
+	return newWatchHistoryBucket(profileID)
+}
+
+// getPeerSyncHistory returns the watch history of the profile that is synced.
+func (m *Manager) getPeerSyncHistory() (WatchHistory, error) {
+	bucket := m.GetSettings().peerSyncBucket()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getAllWatchHistory(bucket)
+}
+
+// MergeWatchHistory merges the watch history of a peer into the one of the synced profile and returns the number of updated items.
+// Items are only replaced by more recent ones.
+func (m *Manager) MergeWatchHistory(history WatchHistory) (updated int, err error) {
+	defer util.HandlePanicInModuleWithError("continuity/MergeWatchHistory", &err)
+
+	bucket := m.GetSettings().peerSyncBucket()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, item := range history {
+		if item == nil || item.MediaId <= 0 {
+			continue
+		}
+
+		local, found := m.getWatchHistory(bucket, item.MediaId)
+		if found && !item.TimeUpdated.After(local.TimeUpdated) {
+			continue
+		}
+
+		merged := *item
+		// File paths are specific to each instance, the local path is only kept if it is the file of the same episode
+		if found && local.EpisodeNumber == item.EpisodeNumber && local.Kind == item.Kind {
+			merged.Filepath = local.Filepath
+		} else if item.Kind != OnlinestreamKind {
+			merged.Filepath = ""
+		}
+		if found && local.TimeAdded.Before(merged.TimeAdded) {
+			merged.TimeAdded = local.TimeAdded
+		}
+
+		err = m.fileCacher.Set(bucket, strconv.Itoa(merged.MediaId), &merged)
+		if err != nil {
+			return updated, fmt.Errorf("continuity: Failed to save watch history item: %w", err)
+		}
+		updated++
+	}
+
+	if updated > 0 {
+		_ = m.trimWatchHistoryItems(bucket)
+	}
+
+	return updated, nil
+}
+
+// HandlePeerSync is called when a peer sends its watch history.
+// The history is merged and the history of the synced profile is returned.
+// client identifies the origin of the request (e.g. the IP address), it is locked out after too many invalid secrets,
+// a *auth.TooManyAttemptsError is returned while it is locked out.
+func (m *Manager) HandlePeerSync(secret string, client string, history WatchHistory) (WatchHistory, error) {
+	if m == nil {
+		return nil, ErrPeerSyncDisabled
+	}
+
+	settings := m.GetSettings()
+	if !settings.isPeerSyncEnabled() {
+		return nil, ErrPeerSyncDisabled
+	}
+	if err := m.peerSyncLimiter.Check(client); err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(settings.PeerSyncSecret)) != 1 {
+		m.peerSyncLimiter.Fail(client)
+		m.logger.Warn().Str("client", client).Msg("continuity: Invalid peer sync secret")
+		return nil, ErrPeerSyncUnauthorized
+	}
+	m.peerSyncLimiter.Reset(client)
+
+	updated, err := m.MergeWatchHistory(history)
+	if err != nil {
+		return nil, err
+	}
+	if updated > 0 {
+		m.logger.Debug().Int("updated", updated).Msg("continuity: Merged watch history from peer")
+	}
+
+	return m.getPeerSyncHistory()
+}
+
+// SyncWithPeer sends the watch history to the peer and merges the peer's history.
+func (m *Manager) SyncWithPeer(ctx context.Context) (err error) {
+	defer util.HandlePanicInModuleWithError("continuity/SyncWithPeer", &err)
+
+	settings := m.GetSettings()
+	if !settings.isPeerSyncEnabled() || settings.PeerSyncUrl == "" {
+		return ErrPeerSyncDisabled
+	}
+
+	m.mu.Lock()
+	m.lastPeerSync = time.Now()
+	m.mu.Unlock()
+
+	endpoint, err := url.JoinPath(strings.TrimSpace(settings.PeerSyncUrl), PeerSyncPath)
+	if err != nil {
+		return fmt.Errorf("continuity: Invalid peer URL: %w", err)
+	}
+
+	history, err := m.getPeerSyncHistory()
+	if err != nil {
+		return fmt.Errorf("continuity: Failed to get watch history: %w", err)
+	}
+
+	body, err := json.Marshal(&PeerSyncRequest{History: history})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, peerSyncTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(PeerSyncSecretHeader, settings.PeerSyncSecret)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("continuity: Failed to reach peer: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var data peerSyncResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil && resp.StatusCode == http.StatusOK {
+		return fmt.Errorf("continuity: Invalid response from peer: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("continuity: Peer responded with status %d: %s", resp.StatusCode, data.Error)
+	}
+
+	updated, err := m.MergeWatchHistory(data.Data)
+	if err != nil {
+		return err
+	}
+
+	if updated > 0 {
+		m.logger.Debug().Int("updated", updated).Msg("continuity: Merged watch history from peer")
+	}
+
+	return nil
+}
+
+// triggerPeerSync requests a sync after the watch history was updated.
+func (m *Manager) triggerPeerSync() {
+	select {
+	case m.peerSyncCh <- struct{}{}:
+	default:
+	}
+}
+
+// startPeerSyncLoop restarts the loop that periodically syncs the watch history with the peer.
+// It should be called with the lock held.
+func (m *Manager) startPeerSyncLoop() {
+	if m.peerSyncCancel != nil {
+		m.peerSyncCancel()
+		m.peerSyncCancel = nil
+	}
+
+	if !m.settings.isPeerSyncEnabled() || m.settings.PeerSyncUrl == "" {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.peerSyncCancel = cancel
+
+	m.logger.Info().Str("peer", m.settings.PeerSyncUrl).Msg("continuity: Peer sync enabled")
+
+	go func() {
+		defer util.HandlePanicInModuleThen("continuity/peerSyncLoop", func() {})
+
+		ticker := time.NewTicker(peerSyncInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-m.peerSyncCh:
+				m.mu.RLock()
+				lastSync := m.lastPeerSync
+				m.mu.RUnlock()
+				// The next tick syncs the update
+				if time.Since(lastSync) < peerSyncMinInterval {
+					continue
+				}
+			}
+
+			if err := m.SyncWithPeer(ctx); err != nil && ctx.Err() == nil {
+				m.logger.Warn().Err(err).Msg("continuity: Failed to sync watch history with peer")
+			}
+		}
+	}()
+}
@@ -0,0 +1,192 @@
+package continuity
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"seanime/internal/auth"
+	"testing"
+	"time"
+)
+
+func TestMergeWatchHistory(t *testing.T) {
+	manager := GetMockManager(t, nil)
+
+	now := time.Now()
+
+	err := manager.UpdateWatchHistoryItem(&UpdateWatchHistoryItemOptions{
+		Kind:          MediastreamKind,
+		Filepath:      "/local/show/ep1.mkv",
+		MediaId:       1,
+		EpisodeNumber: 1,
+		CurrentTime:   10,
+		Duration:      100,
+	})
+	require.NoError(t, err)
+
+	err = manager.UpdateWatchHistoryItem(&UpdateWatchHistoryItemOptions{
+		Kind:          MediastreamKind,
+		MediaId:       2,
+		EpisodeNumber: 5,
+		CurrentTime:   50,
+		Duration:      100,
+	})
+	require.NoError(t, err)
+
+	err = manager.UpdateWatchHistoryItem(&UpdateWatchHistoryItemOptions{
+		Kind:          MediastreamKind,
+		Filepath:      "/local/movie.mkv",
+		MediaId:       5,
+		EpisodeNumber: 1,
+		CurrentTime:   10,
+		Duration:      100,
+	})
+	require.NoError(t, err)
+
+	updated, err := manager.MergeWatchHistory(WatchHistory{
+		// More recent, replaces the local item
+		1: {Kind: ExternalPlayerKind, Filepath: "D:/show/ep2.mkv", MediaId: 1, EpisodeNumber: 2, CurrentTime: 30, Duration: 100, TimeUpdated: now.Add(time.Hour)},
+		// Older, ignored
+		2: {Kind: MediastreamKind, MediaId: 2, EpisodeNumber: 4, CurrentTime: 20, Duration: 100, TimeUpdated: now.Add(-time.Hour)},
+		// New item
+		3: {Kind: OnlinestreamKind, MediaId: 3, EpisodeNumber: 1, CurrentTime: 5, Duration: 100, TimeUpdated: now},
+		// Invalid
+		4: {MediaId: 0},
+		// More recent, same episode
+		5: {Kind: MediastreamKind, Filepath: "D:/movie.mkv", MediaId: 5, EpisodeNumber: 1, CurrentTime: 60, Duration: 100, TimeUpdated: now.Add(time.Hour)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, updated)
+
+	history := manager.GetWatchHistory()
+	require.Len(t, history, 4)
+
+	assert.Equal(t, 2, history[1].EpisodeNumber)
+	assert.Equal(t, 30., history[1].CurrentTime)
+	// The local path is the file of another episode
+	assert.Empty(t, history[1].Filepath)
+
+	assert.Equal(t, 60., history[5].CurrentTime)
+	// The local path is kept
+	assert.Equal(t, "/local/movie.mkv", history[5].Filepath)
+
+	assert.Equal(t, 5, history[2].EpisodeNumber)
+	assert.Equal(t, 1, history[3].EpisodeNumber)
+}
+
+func TestMergeWatchHistory_Profile(t *testing.T) {
+	manager := GetMockManager(t, nil)
+	manager.SetSettings(&Settings{
+		WatchContinuityEnabled: true,
+		PeerSyncEnabled:        true,
+		PeerSyncSecret:         "secret",
+		PeerSyncProfileID:      2,
+	})
+
+	// Another profile is active
+	manager.SetProfile(3)
+
+	history, err := manager.HandlePeerSync("secret", "127.0.0.1", WatchHistory{
+		1: {Kind: MediastreamKind, MediaId: 1, EpisodeNumber: 2, CurrentTime: 30, Duration: 100, TimeUpdated: time.Now()},
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+
+	// The history is merged into the profile that configured the sync
+	assert.Empty(t, manager.GetWatchHistory())
+	manager.SetProfile(2)
+	require.Len(t, manager.GetWatchHistory(), 1)
+	assert.Equal(t, 2, manager.GetWatchHistory()[1].EpisodeNumber)
+}
+
+func TestMergeWatchHistory_Trim(t *testing.T) {
+	manager := GetMockManager(t, nil)
+
+	history := make(WatchHistory)
+	for i := 1; i <= MaxWatchHistoryItems+5; i++ {
+		history[i] = &WatchHistoryItem{MediaId: i, EpisodeNumber: 1, TimeUpdated: time.Now().Add(time.Duration(i) * time.Minute)}
+	}
+
+	updated, err := manager.MergeWatchHistory(history)
+	require.NoError(t, err)
+	assert.Equal(t, MaxWatchHistoryItems+5, updated)
+
+	merged := manager.GetWatchHistory()
+	require.Len(t, merged, MaxWatchHistoryItems)
+	// The oldest items are removed
+	for i := 1; i <= 5; i++ {
+		assert.NotContains(t, merged, i)
+	}
+}
+
+func TestSyncWithPeer(t *testing.T) {
+	settings := func(url string) *Settings {
+		return &Settings{
+			WatchContinuityEnabled: true,
+			PeerSyncEnabled:        true,
+			PeerSyncUrl:            url,
+			PeerSyncSecret:         "secret",
+		}
+	}
+
+	remote := GetMockManager(t, nil)
+	remote.SetSettings(settings(""))
+	require.NoError(t, remote.UpdateWatchHistoryItem(&UpdateWatchHistoryItemOptions{Kind: MediastreamKind, MediaId: 1, EpisodeNumber: 3, CurrentTime: 42, Duration: 100}))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, PeerSyncPath, r.URL.Path)
+
+		var b PeerSyncRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&b))
+
+		history, err := remote.HandlePeerSync(r.Header.Get(PeerSyncSecretHeader), r.RemoteAddr, b.History)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": history})
+	}))
+	defer server.Close()
+
+	local := GetMockManager(t, nil)
+	local.SetSettings(settings(server.URL))
+	require.NoError(t, local.UpdateWatchHistoryItem(&UpdateWatchHistoryItemOptions{Kind: MediastreamKind, MediaId: 2, EpisodeNumber: 7, CurrentTime: 10, Duration: 100}))
+
+	require.NoError(t, local.SyncWithPeer(context.Background()))
+
+	// Both instances have both items
+	for _, m := range []*Manager{local, remote} {
+		history := m.GetWatchHistory()
+		require.Len(t, history, 2)
+		assert.Equal(t, 3, history[1].EpisodeNumber)
+		assert.Equal(t, 42., history[1].CurrentTime)
+		assert.Equal(t, 7, history[2].EpisodeNumber)
+	}
+
+	// Wrong secret
+	s := settings(server.URL)
+	s.PeerSyncSecret = "wrong"
+	local.SetSettings(s)
+	assert.Error(t, local.SyncWithPeer(context.Background()))
+
+	_, err := remote.HandlePeerSync("wrong", "10.0.0.1", nil)
+	assert.ErrorIs(t, err, ErrPeerSyncUnauthorized)
+
+	// The client is locked out after too many invalid secrets, even with the right secret
+	for i := 0; i < 10; i++ {
+		_, _ = remote.HandlePeerSync("wrong", "10.0.0.1", nil)
+	}
+	_, err = remote.HandlePeerSync("secret", "10.0.0.1", nil)
+	var tooManyAttempts *auth.TooManyAttemptsError
+	assert.ErrorAs(t, err, &tooManyAttempts)
+	_, err = remote.HandlePeerSync("secret", "10.0.0.2", nil)
+	assert.NoError(t, err)
+
+	// Disabled
+	local.SetSettings(&Settings{WatchContinuityEnabled: true})
+	assert.ErrorIs(t, local.SyncWithPeer(context.Background()), ErrPeerSyncDisabled)
+}
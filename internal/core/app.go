@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"seanime/internal/api/anilist"
 	"seanime/internal/api/metadata"
+	"seanime/internal/auth"
 	"seanime/internal/constants"
 	"seanime/internal/continuity"
 	"seanime/internal/database/db"
@@ -20,6 +21,8 @@ import (
 	"seanime/internal/library/autodownloader"
 	"seanime/internal/library/autoscanner"
 	"seanime/internal/library/fillermanager"
+	"seanime/internal/library/nfo"
+	"seanime/internal/library/organizer"
 	"seanime/internal/library/playbackmanager"
 	"seanime/internal/library/scanner"
 	"seanime/internal/manga"
@@ -30,7 +33,10 @@ import (
 	"seanime/internal/mediastream"
 	"seanime/internal/onlinestream"
 	"seanime/internal/platforms/anilist_platform"
+	"seanime/internal/platforms/fanout_platform"
+	"seanime/internal/platforms/kitsu_platform"
 	"seanime/internal/platforms/local_platform"
+	"seanime/internal/platforms/mal_platform"
 	"seanime/internal/platforms/platform"
 	sync2 "seanime/internal/sync"
 	"seanime/internal/torrent_clients/torrent_client"
@@ -46,6 +52,7 @@ type (
 	App struct {
 		Config                        *Config
 		Database                      *db.Database
+		AuthManager                   *auth.Manager
 		Logger                        *zerolog.Logger
 		TorrentClientRepository       *torrent_client.Repository
 		TorrentRepository             *torrent.Repository
@@ -54,8 +61,11 @@ type (
 		AnilistClient                 anilist.AnilistClient
 		AnilistPlatform               platform.Platform
 		LocalPlatform                 platform.Platform
+		FanoutPlatform                *fanout_platform.FanoutPlatform
 		SyncManager                   sync2.Manager
 		FillerManager                 *fillermanager.FillerManager
+		Organizer                     *organizer.Organizer
+		NfoExporter                   *nfo.Exporter
 		WSEventManager                *events.WSEventManager
 		AutoDownloader                *autodownloader.AutoDownloader
 		ExtensionRepository           *extension_repo.Repository
@@ -86,6 +96,7 @@ type (
 			Mediastream   *models.MediastreamSettings
 			Torrentstream *models.TorrentstreamSettings
 			Debrid        *models.DebridSettings
+			TrackerSync   *models.TrackerSyncSettings
 		} // Struct for other settings sent to client
 		SelfUpdater        *updater.SelfUpdater
 		TotalLibrarySize   uint64 // Initialized in modules.go
@@ -96,7 +107,13 @@ type (
 		rawMangaCollection *anilist.MangaCollection // (retains custom lists)
 		account            *models.Account
 		previousVersion    string
+		trackingPlatform   string // "anilist", "mal" or "kitsu"
 		moduleMu           sync.Mutex
+		profileSessions    map[string]uint // Profile sessions of the clients, key: session token
+		profileSessionMu   sync.RWMutex
+		profileScopes      map[uint]*ProfileScope // AniList platforms of the profiles other than the server profile
+		profileScopeMu     sync.Mutex
+		profilePinLimiter  *auth.LoginLimiter // Locks out clients that enter too many invalid PINs
 	}
 )
 
@@ -145,10 +162,19 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 		}
 	}
 
+	// Auth Manager
+	authManager := auth.NewManager(&auth.NewManagerOptions{
+		Database: database,
+		Logger:   logger,
+	})
+
 	database.TrimLocalFileEntries()     // ran in goroutine
 	database.TrimScanSummaryEntries()   // ran in goroutine
 	database.TrimTorrentstreamHistory() // ran in goroutine
 
+	// Load the server profile, the account is read from it
+	initActiveProfile(database, logger)
+
 	// Get token from stored account or return empty string
 	anilistToken := database.GetAnilistToken()
 
@@ -179,11 +205,32 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 		ServerURI:      cfg.GetServerURI(),
 		WsEventManager: wsEventManager,
 		DownloadDir:    cfg.Manga.DownloadDir,
+		CacheDir:       cfg.Cache.Dir,
 		Database:       database,
 	})
 
 	anilistPlatform := anilist_platform.NewAnilistPlatform(anilistCW, logger)
 
+	malPlatform := mal_platform.NewMalPlatform(anilistCW, database, logger)
+	kitsuPlatform := kitsu_platform.NewKitsuPlatform(anilistCW, database, logger)
+
+	// The tracking platform is the one used to fetch and update the user's lists
+	// Changing it requires a restart
+	trackingPlatform := anilistPlatform
+	trackingPlatformName := "anilist"
+	if settings, err := database.GetSettings(); err == nil && settings.Library != nil {
+		switch settings.Library.TrackingPlatform {
+		case "mal":
+			trackingPlatformName = "mal"
+			trackingPlatform = malPlatform
+			logger.Info().Msg("app: Using MyAnimeList as the tracking platform")
+		case "kitsu":
+			trackingPlatformName = "kitsu"
+			trackingPlatform = kitsuPlatform
+			logger.Info().Msg("app: Using Kitsu as the tracking platform")
+		}
+	}
+
 	// Platforms
 	syncManager, err := sync2.NewManager(&sync2.NewManagerOptions{
 		LocalDir:         cfg.Offline.Dir,
@@ -194,7 +241,7 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 		Database:         database,
 		WSEventManager:   wsEventManager,
 		IsOffline:        cfg.Server.Offline,
-		AnilistPlatform:  anilistPlatform,
+		AnilistPlatform:  trackingPlatform,
 	})
 	if err != nil {
 		logger.Fatal().Err(err).Msgf("app: Failed to initialize sync manager")
@@ -209,12 +256,25 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 		logger.Fatal().Err(err).Msgf("app: Failed to initialize local platform")
 	}
 
-	activePlatform := anilistPlatform
+	activePlatform := trackingPlatform
 	// If offline mode is enabled, use the local platform
 	if cfg.Server.Offline {
 		activePlatform = localPlatform
 	}
 
+	// Mirror progress, status and score changes to the other trackers
+	fanoutPlatform := fanout_platform.NewFanoutPlatform(&fanout_platform.NewFanoutPlatformOptions{
+		Platform:        activePlatform,
+		PrimaryName:     trackingPlatformName,
+		AnilistPlatform: anilistPlatform,
+		MalPlatform:     malPlatform,
+		KitsuPlatform:   kitsuPlatform,
+		AnilistClient:   anilistCW,
+		Database:        database,
+		Logger:          logger,
+	})
+	activePlatform = fanoutPlatform
+
 	// Online Stream
 	onlinestreamRepository := onlinestream.NewRepository(&onlinestream.NewRepositoryOptions{
 		Logger:           logger,
@@ -237,9 +297,11 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 	app := &App{
 		Config:                        cfg,
 		Database:                      database,
+		AuthManager:                   authManager,
 		AnilistClient:                 anilistCW,
 		AnilistPlatform:               activePlatform,
 		LocalPlatform:                 localPlatform,
+		FanoutPlatform:                fanoutPlatform,
 		SyncManager:                   syncManager,
 		WSEventManager:                wsEventManager,
 		Logger:                        logger,
@@ -253,6 +315,8 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 		ExtensionPlaygroundRepository: extensionPlaygroundRepository,
 		TorrentRepository:             nil, // Initialized in App.initModulesOnce
 		FillerManager:                 nil, // Initialized in App.initModulesOnce
+		Organizer:                     nil, // Initialized in App.initModulesOnce
+		NfoExporter:                   nil, // Initialized in App.initModulesOnce
 		MangaDownloader:               nil, // Initialized in App.initModulesOnce
 		PlaybackManager:               nil, // Initialized in App.initModulesOnce
 		AutoDownloader:                nil, // Initialized in App.initModulesOnce
@@ -265,14 +329,17 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 		MediaPlayerRepository:         nil, // Initialized in App.InitOrRefreshModules
 		DiscordPresence:               nil, // Initialized in App.InitOrRefreshModules
 		previousVersion:               previousVersion,
+		trackingPlatform:              trackingPlatformName,
 		FeatureFlags:                  NewFeatureFlags(cfg, logger),
 		SecondarySettings: struct {
 			Mediastream   *models.MediastreamSettings
 			Torrentstream *models.TorrentstreamSettings
 			Debrid        *models.DebridSettings
+			TrackerSync   *models.TrackerSyncSettings
 		}{Mediastream: nil, Torrentstream: nil},
-		SelfUpdater: selfupdater,
-		moduleMu:    sync.Mutex{},
+		SelfUpdater:       selfupdater,
+		moduleMu:          sync.Mutex{},
+		profilePinLimiter: auth.NewLoginLimiter(),
 	}
 
 	// Perform necessary migrations if the version has changed
@@ -303,12 +370,20 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 	// Initialize debrid settings
 	app.InitOrRefreshDebridSettings()
 
+	// Initialize tracker sync settings
+	app.InitOrRefreshTrackerSyncSettings()
+
 	// Perform actions that need to be done after the app has been initialized
 	app.performActionsOnce()
 
 	return app
 }
 
+// GetTrackingPlatform returns the name of the platform used to track progress.
+func (a *App) GetTrackingPlatform() string {
+	return a.trackingPlatform
+}
+
 func (a *App) IsOffline() bool {
 	if a.Config == nil {
 		return false
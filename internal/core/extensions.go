@@ -99,6 +99,18 @@ func (a *App) LoadBuiltInExtensions() {
 		Icon:        "https://raw.githubusercontent.com/5rahim/hibike/main/icons/manganato.png",
 	}, manga_providers.NewManganato(a.Logger))
 
+	a.ExtensionRepository.LoadBuiltInMangaProviderExtension(extension.Extension{
+		ID:          manga_providers.LocalMangaProvider,
+		Name:        "Local",
+		Version:     "",
+		ManifestURI: "builtin",
+		Language:    extension.LanguageGo,
+		Type:        extension.TypeMangaProvider,
+		Author:      "Seanime",
+		Description: "Reads CBZ/CBR archives and image folders from the local manga library.",
+		Lang:        "multi",
+	}, a.MangaRepository.GetLocalProvider())
+
 	//
 	// Built-in online stream providers
 	//
@@ -22,6 +22,9 @@ func NewFiberApp(app *App, webFS *embed.FS) *fiber.App {
 		DisableStartupMessage: true,
 	})
 
+	// Enforce authentication on the API, websocket and static files if a password is set
+	fiberApp.Use(app.AuthManager.FiberMiddleware())
+
 	//
 	// Serve the embedded web interface
 	//
@@ -11,15 +11,20 @@ import (
 	"seanime/internal/library/autodownloader"
 	"seanime/internal/library/autoscanner"
 	"seanime/internal/library/fillermanager"
+	"seanime/internal/library/nfo"
+	"seanime/internal/library/organizer"
 	"seanime/internal/library/playbackmanager"
 	"seanime/internal/manga"
+	"seanime/internal/manga/imagecache"
 	"seanime/internal/mediaplayers/mediaplayer"
 	"seanime/internal/mediaplayers/mpchc"
 	"seanime/internal/mediaplayers/mpv"
 	"seanime/internal/mediaplayers/vlc"
 	"seanime/internal/mediastream"
 	"seanime/internal/notifier"
+	"seanime/internal/torrent_clients/deluge"
 	"seanime/internal/torrent_clients/qbittorrent"
+	"seanime/internal/torrent_clients/rtorrent"
 	"seanime/internal/torrent_clients/torrent_client"
 	"seanime/internal/torrent_clients/transmission"
 	"seanime/internal/torrents/torrent"
@@ -54,6 +59,27 @@ func (a *App) initModulesOnce() {
 		Logger: a.Logger,
 	})
 
+	// +---------------------+
+	// |      Organizer      |
+	// +---------------------+
+
+	a.Organizer = organizer.NewOrganizer(&organizer.NewOrganizerOptions{
+		Database: a.Database,
+		Logger:   a.Logger,
+		Platform: a.AnilistPlatform,
+	})
+
+	// +---------------------+
+	// |     NFO Export      |
+	// +---------------------+
+
+	a.NfoExporter = nfo.NewExporter(&nfo.NewExporterOptions{
+		Database:         a.Database,
+		Logger:           a.Logger,
+		Platform:         a.AnilistPlatform,
+		MetadataProvider: a.MetadataProvider,
+	})
+
 	// +---------------------+
 	// |     Continuity      |
 	// +---------------------+
@@ -63,6 +89,7 @@ func (a *App) initModulesOnce() {
 		Logger:     a.Logger,
 		Database:   a.Database,
 	})
+	a.ContinuityManager.SetProfile(a.Database.GetProfileID())
 
 	// +---------------------+
 	// |   Playback Manager  |
@@ -117,6 +144,7 @@ func (a *App) initModulesOnce() {
 		WSEventManager:          a.WSEventManager,
 		MetadataProvider:        a.MetadataProvider,
 		DebridClientRepository:  a.DebridClientRepository,
+		CacheDir:                a.Config.Cache.Dir,
 	})
 
 	if !a.IsOffline() {
@@ -135,6 +163,7 @@ func (a *App) initModulesOnce() {
 		WSEventManager:   a.WSEventManager,
 		Enabled:          false, // Will be set in InitOrRefreshModules
 		AutoDownloader:   a.AutoDownloader,
+		NfoExporter:      a.NfoExporter,
 		MetadataProvider: a.MetadataProvider,
 		LogsDir:          a.Config.Logs.Dir,
 	})
@@ -142,6 +171,9 @@ func (a *App) initModulesOnce() {
 	// This is run in a goroutine
 	a.AutoScanner.Start()
 
+	// Files downloaded from the debrid service are scanned once they are placed in the library
+	a.AutoDownloader.SetLibraryScanner(a.AutoScanner)
+
 	// +---------------------+
 	// |  Manga Downloader   |
 	// +---------------------+
@@ -167,6 +199,7 @@ func (a *App) initModulesOnce() {
 		Logger:         a.Logger,
 		WSEventManager: a.WSEventManager,
 		FileCacher:     a.FileCacher,
+		Database:       a.Database,
 	})
 
 	a.AddCleanupFunction(func() {
@@ -187,8 +220,12 @@ func (a *App) initModulesOnce() {
 		PlaybackManager:    a.PlaybackManager,
 		WSEventManager:     a.WSEventManager,
 		Database:           a.Database,
+		AuthManager:        a.AuthManager,
 	})
 
+	// Streamed episodes added to the library are scanned once they are fully downloaded
+	a.TorrentstreamRepository.SetLibraryScanner(a.AutoScanner)
+
 }
 
 // InitOrRefreshModules will initialize or refresh modules that depend on settings.
@@ -218,6 +255,9 @@ func (a *App) InitOrRefreshModules() {
 		return
 	}
 
+	// Apply the overrides of the server profile
+	settings = a.GetProfileSettings(a.Database.GetProfileID(), settings)
+
 	a.Settings = settings // Store settings instance in app
 	if settings != nil && settings.Library != nil {
 		a.LibraryDir = settings.Library.LibraryPath
@@ -239,6 +279,7 @@ func (a *App) InitOrRefreshModules() {
 	if settings.Library != nil && a.AutoScanner != nil {
 
 		a.AutoScanner.SetEnabled(settings.Library.AutoScan)
+		a.AutoScanner.SetIncremental(settings.Library.IncrementalScan)
 
 		// Torrent Repository
 		a.TorrentRepository.SetSettings(&torrent.RepositorySettings{
@@ -319,6 +360,32 @@ func (a *App) InitOrRefreshModules() {
 			a.Logger.Error().Err(err).Msg("app: Failed to initialize transmission client")
 		}
 
+		// Init Deluge
+		delugeClient := deluge.New(&deluge.NewDelugeOptions{
+			Logger:   a.Logger,
+			Password: settings.Torrent.DelugePassword,
+			Host:     settings.Torrent.DelugeHost,
+			Port:     settings.Torrent.DelugePort,
+			Path:     settings.Torrent.DelugePath,
+		})
+		go func() {
+			if settings.Torrent.Default == torrent_client.DelugeClient {
+				err := delugeClient.Login()
+				if err != nil {
+					a.Logger.Error().Err(err).Msg("app: Failed to login to Deluge")
+				} else {
+					a.Logger.Info().Msg("app: Logged in to Deluge")
+				}
+			}
+		}()
+		// Init rTorrent
+		rtorrentClient := rtorrent.New(&rtorrent.NewRTorrentOptions{
+			Logger:   a.Logger,
+			URL:      settings.Torrent.RTorrentURL,
+			Username: settings.Torrent.RTorrentUsername,
+			Password: settings.Torrent.RTorrentPassword,
+		})
+
 		if a.TorrentClientRepository != nil {
 			a.TorrentClientRepository.Shutdown()
 		}
@@ -328,6 +395,8 @@ func (a *App) InitOrRefreshModules() {
 			Logger:            a.Logger,
 			QbittorrentClient: qbit,
 			Transmission:      trans,
+			Deluge:            delugeClient,
+			RTorrent:          rtorrentClient,
 			TorrentRepository: a.TorrentRepository,
 			Provider:          settings.Torrent.Default,
 			MetadataProvider:  a.MetadataProvider,
@@ -369,6 +438,29 @@ func (a *App) InitOrRefreshModules() {
 		a.DiscordPresence.SetSettings(settings.Discord)
 	}
 
+	// +---------------------+
+	// |     Local Manga     |
+	// +---------------------+
+
+	if settings.Manga != nil {
+		a.MangaRepository.SetLocalLibraryDirs(settings.Manga.LocalLibraryPaths)
+	}
+
+	// +---------------------+
+	// |    Manga Images     |
+	// +---------------------+
+
+	if settings.Manga != nil {
+		a.MangaRepository.SetImageSettings(&manga_imagecache.Settings{
+			CacheSizeLimit:  int64(settings.Manga.ImageCacheSizeLimit) * 1024 * 1024,
+			OptimizeImages:  settings.Manga.OptimizeImages,
+			MaxWidth:        settings.Manga.ImageMaxWidth,
+			Quality:         settings.Manga.ImageQuality,
+			Format:          settings.Manga.ImageFormat,
+			SplitTallImages: settings.Manga.SplitTallImages,
+		})
+	}
+
 	// +---------------------+
 	// |     Continuity      |
 	// +---------------------+
@@ -376,6 +468,10 @@ func (a *App) InitOrRefreshModules() {
 	if settings.Library != nil {
 		a.ContinuityManager.SetSettings(&continuity.Settings{
 			WatchContinuityEnabled: settings.Library.EnableWatchContinuity,
+			PeerSyncEnabled:        settings.Library.ContinuityPeerSyncEnabled,
+			PeerSyncUrl:            settings.Library.ContinuityPeerSyncUrl,
+			PeerSyncSecret:         settings.Library.ContinuityPeerSyncSecret,
+			PeerSyncProfileID:      settings.Library.ContinuityPeerSyncProfileID,
 		})
 	}
 
@@ -412,6 +508,9 @@ func (a *App) InitOrRefreshMediastreamSettings() {
 
 	a.MediastreamRepository.InitializeModules(settings, a.Config.Cache.Dir, a.Config.Cache.TranscodeDir)
 
+	// Manga pages are encoded as WebP and AVIF with the same FFmpeg binary
+	a.MangaRepository.GetImagePipeline().SetFfmpegPath(settings.FfmpegPath)
+
 	// Cleanup cache
 	go func() {
 		if settings.TranscodeEnabled {
@@ -504,11 +603,41 @@ func (a *App) InitOrRefreshDebridSettings() {
 	}
 }
 
+func (a *App) InitOrRefreshTrackerSyncSettings() {
+
+	settings, found := a.Database.GetTrackerSyncSettings()
+	if !found {
+
+		var err error
+		settings, err = a.Database.UpsertTrackerSyncSettings(&models.TrackerSyncSettings{
+			BaseModel: models.BaseModel{
+				ID: 1,
+			},
+			Enabled:       false,
+			EnableLedger:  true,
+			RetryInterval: 5,
+		})
+		if err != nil {
+			a.Logger.Error().Err(err).Msg("app: Failed to initialize tracker sync module")
+			return
+		}
+	}
+
+	a.SecondarySettings.TrackerSync = settings
+
+	a.FanoutPlatform.SetSettings(settings)
+}
+
 // InitOrRefreshAnilistData will initialize the Anilist anime collection and the account.
 // This function should be called after App.Database is initialized and after settings are updated.
 func (a *App) InitOrRefreshAnilistData() {
 	a.Logger.Debug().Msg("app: Fetching Anilist data")
 
+	// MAL and Kitsu do not depend on the AniList account
+	if a.trackingPlatform == "mal" || a.trackingPlatform == "kitsu" {
+		a.refreshTrackingPlatformCollections()
+	}
+
 	acc, err := a.Database.GetAccount()
 	if err != nil {
 		return
@@ -525,23 +654,42 @@ func (a *App) InitOrRefreshAnilistData() {
 	a.account = acc
 	a.Logger.Info().Msg("app: Authenticated to AniList as " + acc.Username)
 
-	_, err = a.RefreshAnimeCollection()
+	if a.trackingPlatform == "anilist" || a.trackingPlatform == "" {
+		_, err = a.RefreshAnimeCollection()
+		if err != nil {
+			a.Logger.Error().Err(err).Msg("app: Failed to fetch Anilist anime collection")
+			return
+		}
+
+		_, err = a.RefreshMangaCollection()
+		if err != nil {
+			a.Logger.Error().Err(err).Msg("app: Failed to fetch Anilist manga collection")
+			return
+		}
+	}
+
+	go func(username string) {
+		a.DiscordPresence.SetUsername(username)
+	}(a.account.Username)
+
+	a.Logger.Info().Msg("app: Fetched Anilist data")
+}
+
+// refreshTrackingPlatformCollections fetches the collections from MAL or Kitsu.
+func (a *App) refreshTrackingPlatformCollections() {
+	_, err := a.RefreshAnimeCollection()
 	if err != nil {
-		a.Logger.Error().Err(err).Msg("app: Failed to fetch Anilist anime collection")
+		a.Logger.Error().Err(err).Msgf("app: Failed to fetch %s anime collection", a.trackingPlatform)
 		return
 	}
 
 	_, err = a.RefreshMangaCollection()
 	if err != nil {
-		a.Logger.Error().Err(err).Msg("app: Failed to fetch Anilist manga collection")
+		a.Logger.Error().Err(err).Msgf("app: Failed to fetch %s manga collection", a.trackingPlatform)
 		return
 	}
 
-	go func(username string) {
-		a.DiscordPresence.SetUsername(username)
-	}(a.account.Username)
-
-	a.Logger.Info().Msg("app: Fetched Anilist data")
+	a.Logger.Info().Msgf("app: Fetched %s data", a.trackingPlatform)
 }
 
 func (a *App) performActionsOnce() {
@@ -567,6 +715,11 @@ func (a *App) performActionsOnce() {
 				a.AutoScanner.RunNow()
 				a.Logger.Info().Msg("app: Refreshed library")
 			}()
+		} else if a.Settings.Library.AutoScan && a.Settings.Library.IncrementalScan {
+			go func() {
+				a.Logger.Debug().Msg("app: Scanning library changes")
+				a.AutoScanner.ScanChanges()
+			}()
 		}
 
 		if a.Settings.Library.OpenTorrentClientOnStart && a.TorrentClientRepository != nil {
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+)
+
+// initActiveProfile loads the server profile, the one that was the server profile when the app was closed.
+// It should be called before the AniList client is created.
+func initActiveProfile(database *db.Database, logger *zerolog.Logger) {
+	profile, err := database.EnsureDefaultProfile()
+	if err != nil {
+		logger.Error().Err(err).Msg("app: Failed to initialize profiles")
+		return
+	}
+
+	database.SetProfileID(profile.ID)
+	logger.Debug().Str("name", profile.Name).Msg("app: Server profile loaded")
+}
+
+// SwitchProfile makes the profile the server profile.
+// The background modules (auto downloader, playback manager, sync, Discord RPC) act on behalf of the server profile,
+// its account, watch history and Discord settings are loaded and the modules are refreshed.
+// Clients keep using the profile they selected.
+func (a *App) SwitchProfile(id uint) error {
+	profile, err := a.Database.GetProfile(id)
+	if err != nil {
+		return err
+	}
+
+	if err := a.Database.SetActiveProfile(profile.ID); err != nil {
+		return err
+	}
+
+	a.Database.SetProfileID(profile.ID)
+	a.ContinuityManager.SetProfile(profile.ID)
+	// The new server profile uses the platform of the app, the previous one gets its own
+	a.deleteProfileScopes()
+
+	// Load the AniList account of the profile
+	a.account = nil
+	token := ""
+	if acc, err := a.Database.GetAccount(); err == nil {
+		token = acc.Token
+	}
+	if token == "" {
+		a.AnilistPlatform.SetUsername("")
+	}
+	a.UpdateAnilistClientToken(token)
+
+	a.InitOrRefreshModules()
+	if !a.IsOffline() {
+		a.InitOrRefreshAnilistData()
+	}
+
+	a.Logger.Info().Str("name", profile.Name).Msg("app: Switched server profile")
+
+	return nil
+}
+
+// GetProfileSettings returns the settings with the overrides of the profile applied.
+// The returned settings should not be saved.
+func (a *App) GetProfileSettings(profileID uint, settings *models.Settings) *models.Settings {
+	if settings == nil {
+		return nil
+	}
+
+	profile, err := a.Database.GetProfile(profileID)
+	if err != nil || profile.Discord == nil {
+		return settings
+	}
+
+	ret := *settings
+	ret.Discord = profile.Discord
+	return &ret
+}
+
+// SaveProfileDiscordSettings stores the Discord settings in the profile.
+// They are used by the Discord RPC when the profile is the server profile.
+func (a *App) SaveProfileDiscordSettings(profileID uint, discord *models.DiscordSettings) error {
+	profile, err := a.Database.GetProfile(profileID)
+	if err != nil {
+		return err
+	}
+
+	profile.Discord = discord
+	return a.Database.UpdateProfile(profile)
+}
+
+// SetProfilePin sets or removes the PIN of the profile.
+// The PIN is required to select the profile.
+func SetProfilePin(profile *models.Profile, pin string) error {
+	if pin == "" {
+		profile.PinHash = ""
+		return nil
+	}
+	if len(pin) < 4 {
+		return errors.New("PIN should be at least 4 characters long")
+	}
+	b, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	profile.PinHash = string(b)
+	return nil
+}
+
+// CheckProfilePin returns true if the profile has no PIN or if the PIN matches.
+func CheckProfilePin(profile *models.Profile, pin string) bool {
+	if profile.PinHash == "" {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(profile.PinHash), []byte(pin)) == nil
+}
+
+var ErrInvalidProfilePin = errors.New("invalid PIN")
+
+// VerifyProfilePin checks the PIN entered by the client.
+// client identifies the origin of the request (e.g. the IP address), it is locked out after too many failed attempts
+// so that short PINs cannot be guessed, a *auth.TooManyAttemptsError is returned while it is locked out.
+func (a *App) VerifyProfilePin(profile *models.Profile, pin string, client string) error {
+	if profile.PinHash == "" {
+		return nil
+	}
+	if err := a.profilePinLimiter.Check(client); err != nil {
+		return err
+	}
+	if !CheckProfilePin(profile, pin) {
+		a.profilePinLimiter.Fail(client)
+		a.Logger.Warn().Str("client", client).Str("profile", profile.Name).Msg("app: Invalid profile PIN")
+		return ErrInvalidProfilePin
+	}
+	a.profilePinLimiter.Reset(client)
+	return nil
+}
+
+// CreateProfileSession returns a session token for the profile.
+// The client sends it back to prove that it selected the profile (and entered its PIN).
+// Sessions are kept in memory, clients have to select their profile again after a restart.
+func (a *App) CreateProfileSession(profileID uint) string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	a.profileSessionMu.Lock()
+	defer a.profileSessionMu.Unlock()
+	if a.profileSessions == nil {
+		a.profileSessions = make(map[string]uint)
+	}
+	a.profileSessions[token] = profileID
+	return token
+}
+
+// GetProfileSession returns the profile of the session.
+func (a *App) GetProfileSession(token string) (uint, bool) {
+	a.profileSessionMu.RLock()
+	defer a.profileSessionMu.RUnlock()
+	id, ok := a.profileSessions[token]
+	return id, ok
+}
+
+// DeleteProfileSessions revokes the sessions of the profile, e.g. when its PIN changes.
+func (a *App) DeleteProfileSessions(profileID uint) {
+	a.profileSessionMu.Lock()
+	defer a.profileSessionMu.Unlock()
+	for token, id := range a.profileSessions {
+		if id == profileID {
+			delete(a.profileSessions, token)
+		}
+	}
+}
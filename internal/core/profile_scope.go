@@ -0,0 +1,142 @@
+package core
+
+import (
+	"seanime/internal/api/anilist"
+	"seanime/internal/database/models"
+	"seanime/internal/platforms/anilist_platform"
+	"seanime/internal/platforms/platform"
+)
+
+// ProfileScope gives access to the AniList account and collections of a profile.
+// Each client selects its profile and its requests are served with the scope of that profile,
+// so clients using different profiles can use the server at the same time.
+//
+// The server profile uses the platform of the app, the one used by the background modules
+// (auto downloader, playback manager, sync, Discord RPC) and the additional trackers.
+// Other profiles use their own AniList platform.
+type ProfileScope struct {
+	ID              uint
+	AnilistClient   anilist.AnilistClient
+	AnilistPlatform platform.Platform
+	app             *App
+}
+
+// GetProfileScope returns the scope of the profile.
+// The AniList platform of a profile other than the server profile is created the first time it is used.
+func (a *App) GetProfileScope(id uint) *ProfileScope {
+	// Offline, the collections are the local ones, they are shared by all profiles
+	if id == a.Database.GetProfileID() || a.IsOffline() {
+		return &ProfileScope{
+			ID:              id,
+			AnilistClient:   a.AnilistClient,
+			AnilistPlatform: a.AnilistPlatform,
+			app:             a,
+		}
+	}
+
+	a.profileScopeMu.Lock()
+	defer a.profileScopeMu.Unlock()
+
+	if scope, ok := a.profileScopes[id]; ok {
+		return scope
+	}
+
+	client := anilist.NewAnilistClient(a.Database.GetProfileAnilistToken(id))
+	anilistPlatform := anilist_platform.NewAnilistPlatform(client, a.Logger)
+	if acc, err := a.Database.GetProfileAccount(id); err == nil {
+		anilistPlatform.SetUsername(acc.Username)
+	}
+
+	scope := &ProfileScope{
+		ID:              id,
+		AnilistClient:   client,
+		AnilistPlatform: anilistPlatform,
+		app:             a,
+	}
+	if a.profileScopes == nil {
+		a.profileScopes = make(map[uint]*ProfileScope)
+	}
+	a.profileScopes[id] = scope
+	return scope
+}
+
+// DeleteProfileScope drops the cached scope of the profile, e.g. when it logs in or out of AniList.
+func (a *App) DeleteProfileScope(id uint) {
+	a.profileScopeMu.Lock()
+	defer a.profileScopeMu.Unlock()
+	delete(a.profileScopes, id)
+}
+
+// deleteProfileScopes drops all the cached scopes, e.g. when the server profile changes.
+func (a *App) deleteProfileScopes() {
+	a.profileScopeMu.Lock()
+	defer a.profileScopeMu.Unlock()
+	a.profileScopes = nil
+}
+
+// IsServerProfile returns true if the profile is the server profile.
+func (s *ProfileScope) IsServerProfile() bool {
+	return s.ID == s.app.Database.GetProfileID()
+}
+
+// GetAccount returns the AniList account of the profile.
+func (s *ProfileScope) GetAccount() (*models.Account, error) {
+	return s.app.Database.GetProfileAccount(s.ID)
+}
+
+// UpdateAnilistClientToken returns an AniList client using the token, e.g. to check the token when the profile logs in.
+// The client of the server profile replaces the client of the app, see App.UpdateAnilistClientToken.
+// The client of another profile is only used once the account is saved, see ReloadAccount.
+func (s *ProfileScope) UpdateAnilistClientToken(token string) anilist.AnilistClient {
+	if s.IsServerProfile() {
+		s.app.UpdateAnilistClientToken(token)
+		return s.app.AnilistClient
+	}
+	return anilist.NewAnilistClient(token)
+}
+
+// ReloadAccount should be called after the account of a profile other than the server profile is saved.
+// The scope is re-created with the new account the next time it is used.
+func (s *ProfileScope) ReloadAccount() {
+	if s.IsServerProfile() {
+		return
+	}
+	s.app.DeleteProfileScope(s.ID)
+}
+
+// GetAnimeCollection returns the anime collection of the profile, see App.GetAnimeCollection.
+func (s *ProfileScope) GetAnimeCollection(bypassCache bool) (*anilist.AnimeCollection, error) {
+	return s.AnilistPlatform.GetAnimeCollection(bypassCache)
+}
+
+// GetRawAnimeCollection returns the anime collection of the profile including custom lists.
+func (s *ProfileScope) GetRawAnimeCollection(bypassCache bool) (*anilist.AnimeCollection, error) {
+	return s.AnilistPlatform.GetRawAnimeCollection(bypassCache)
+}
+
+// RefreshAnimeCollection queries AniList for the anime collection of the profile.
+// The collection of the server profile is also passed to the background modules.
+func (s *ProfileScope) RefreshAnimeCollection() (*anilist.AnimeCollection, error) {
+	if s.IsServerProfile() {
+		return s.app.RefreshAnimeCollection()
+	}
+	return s.AnilistPlatform.RefreshAnimeCollection()
+}
+
+// GetMangaCollection returns the manga collection of the profile.
+func (s *ProfileScope) GetMangaCollection(bypassCache bool) (*anilist.MangaCollection, error) {
+	return s.AnilistPlatform.GetMangaCollection(bypassCache)
+}
+
+// GetRawMangaCollection returns the manga collection of the profile including custom lists.
+func (s *ProfileScope) GetRawMangaCollection(bypassCache bool) (*anilist.MangaCollection, error) {
+	return s.AnilistPlatform.GetRawMangaCollection(bypassCache)
+}
+
+// RefreshMangaCollection queries AniList for the manga collection of the profile.
+func (s *ProfileScope) RefreshMangaCollection() (*anilist.MangaCollection, error) {
+	if s.IsServerProfile() {
+		return s.app.RefreshMangaCollection()
+	}
+	return s.AnilistPlatform.RefreshMangaCollection()
+}
@@ -52,9 +52,9 @@ func (a *App) initLibraryWatcher(paths []string) {
 
 	// Start watching
 	a.Watcher.StartWatching(
-		func() {
+		func(event *scanner.FileEvent) {
 			// Notify the auto scanner when a file action occurs
-			a.AutoScanner.Notify()
+			a.AutoScanner.NotifyFileEvent(event)
 		})
 
 }
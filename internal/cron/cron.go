@@ -21,6 +21,7 @@ func RunJobs(app *core.App) {
 		refreshAnilistTicker := time.NewTicker(10 * time.Minute)
 		refreshLocalDataTicker := time.NewTicker(31 * time.Minute)
 		refetchReleaseTicker := time.NewTicker(1 * time.Hour)
+		mangaAutoDownloaderTicker := time.NewTicker(30 * time.Minute)
 
 		go func() {
 			for {
@@ -31,6 +32,8 @@ func RunJobs(app *core.App) {
 					SyncLocalDataJob(ctx)
 				case <-refetchReleaseTicker.C:
 					app.Updater.ShouldRefetchReleases()
+				case <-mangaAutoDownloaderTicker.C:
+					RunMangaAutoDownloaderJob(ctx)
 				}
 			}
 		}()
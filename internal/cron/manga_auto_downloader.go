@@ -0,0 +1,23 @@
+package cron
+
+func RunMangaAutoDownloaderJob(c *JobCtx) {
+	defer func() {
+		if r := recover(); r != nil {
+		}
+	}()
+
+	if c.App.Settings == nil || c.App.Settings.Library == nil || !c.App.Settings.Library.EnableManga {
+		return
+	}
+
+	if c.App.MangaDownloader == nil {
+		return
+	}
+
+	mangaCollection, err := c.App.GetMangaCollection(false)
+	if err != nil {
+		return
+	}
+
+	_, _ = c.App.MangaDownloader.RunAutoDownloader(mangaCollection)
+}
@@ -4,11 +4,24 @@ import (
 	"errors"
 	"gorm.io/gorm/clause"
 	"seanime/internal/database/models"
+	"sync"
 )
 
-var accountCache *models.Account
+var (
+	accountCache   = make(map[uint]*models.Account) // key: profile ID
+	accountCacheMu sync.RWMutex
+)
 
+// UpsertAccount saves the account of the server profile.
 func (db *Database) UpsertAccount(acc *models.Account) (*models.Account, error) {
+	return db.UpsertProfileAccount(db.GetProfileID(), acc)
+}
+
+// UpsertProfileAccount saves the account of the profile.
+// Accounts are keyed by profile ID, the ID of acc is overwritten.
+func (db *Database) UpsertProfileAccount(profileID uint, acc *models.Account) (*models.Account, error) {
+	acc.ID = profileID
+
 	err := db.gormdb.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "id"}},
 		UpdateAll: true,
@@ -19,19 +32,30 @@ func (db *Database) UpsertAccount(acc *models.Account) (*models.Account, error)
 		return nil, err
 	}
 
-	accountCache = acc
+	accountCacheMu.Lock()
+	accountCache[profileID] = acc
+	accountCacheMu.Unlock()
 
 	return acc, nil
 }
 
+// GetAccount returns the account of the server profile.
 func (db *Database) GetAccount() (*models.Account, error) {
+	return db.GetProfileAccount(db.GetProfileID())
+}
+
+// GetProfileAccount returns the account of the profile.
+func (db *Database) GetProfileAccount(profileID uint) (*models.Account, error) {
 
-	if accountCache != nil {
-		return accountCache, nil
+	accountCacheMu.RLock()
+	cached, ok := accountCache[profileID]
+	accountCacheMu.RUnlock()
+	if ok {
+		return cached, nil
 	}
 
 	var acc models.Account
-	err := db.gormdb.Last(&acc).Error
+	err := db.gormdb.Where("id = ?", profileID).First(&acc).Error
 	if err != nil {
 		return nil, err
 	}
@@ -39,14 +63,21 @@ func (db *Database) GetAccount() (*models.Account, error) {
 		return nil, errors.New("account does not exist")
 	}
 
-	accountCache = &acc
+	accountCacheMu.Lock()
+	accountCache[profileID] = &acc
+	accountCacheMu.Unlock()
 
 	return &acc, err
 }
 
-// GetAnilistToken retrieves the AniList token from the account or returns an empty string
+// GetAnilistToken retrieves the AniList token from the account of the server profile or returns an empty string
 func (db *Database) GetAnilistToken() string {
-	acc, err := db.GetAccount()
+	return db.GetProfileAnilistToken(db.GetProfileID())
+}
+
+// GetProfileAnilistToken retrieves the AniList token from the account of the profile or returns an empty string
+func (db *Database) GetProfileAnilistToken(profileID uint) string {
+	acc, err := db.GetProfileAccount(profileID)
 	if err != nil {
 		return ""
 	}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"seanime/internal/database/models"
+	"time"
+)
+
+func (db *Database) GetServerAuth() (*models.ServerAuth, error) {
+	var res models.ServerAuth
+	err := db.gormdb.First(&res, 1).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.ServerAuth{BaseModel: models.BaseModel{ID: 1}}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (db *Database) UpsertServerAuth(auth *models.ServerAuth) error {
+	auth.ID = 1
+	return db.gormdb.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		UpdateAll: true,
+	}).Create(auth).Error
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (db *Database) InsertAuthToken(token *models.AuthToken) error {
+	return db.gormdb.Create(token).Error
+}
+
+func (db *Database) GetAuthTokenByHash(hash string) (*models.AuthToken, error) {
+	var res models.AuthToken
+	err := db.gormdb.Where("token_hash = ?", hash).First(&res).Error
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (db *Database) GetAuthTokensByKind(kind string) ([]*models.AuthToken, error) {
+	var res []*models.AuthToken
+	err := db.gormdb.Where("kind = ?", kind).Order("id asc").Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (db *Database) UpdateAuthTokenLastUsedAt(id uint, t time.Time) error {
+	return db.gormdb.Model(&models.AuthToken{}).Where("id = ?", id).Update("last_used_at", t).Error
+}
+
+func (db *Database) UpdateAuthTokenExpiresAt(id uint, t time.Time) error {
+	return db.gormdb.Model(&models.AuthToken{}).Where("id = ?", id).Update("expires_at", t).Error
+}
+
+func (db *Database) DeleteAuthToken(id uint) error {
+	return db.gormdb.Delete(&models.AuthToken{}, id).Error
+}
+
+func (db *Database) DeleteAuthTokenByHash(hash string) error {
+	return db.gormdb.Where("token_hash = ?", hash).Delete(&models.AuthToken{}).Error
+}
+
+func (db *Database) DeleteAuthTokensByKind(kind string) error {
+	return db.gormdb.Where("kind = ?", kind).Delete(&models.AuthToken{}).Error
+}
+
+// DeleteExpiredAuthTokens deletes sessions and tokens that have expired.
+func (db *Database) DeleteExpiredAuthTokens() error {
+	return db.gormdb.Where("expires_at IS NOT NULL AND expires_at < ?", time.Now()).Delete(&models.AuthToken{}).Error
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"seanime/internal/database/models"
+)
+
+func (db *Database) GetAutoDownloaderDebridDownloads() ([]*models.AutoDownloaderDebridDownload, error) {
+	var res []*models.AutoDownloaderDebridDownload
+	err := db.gormdb.Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (db *Database) GetAutoDownloaderDebridDownload(id uint) (*models.AutoDownloaderDebridDownload, error) {
+	var res models.AutoDownloaderDebridDownload
+	err := db.gormdb.First(&res, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// GetPendingAutoDownloaderDebridDownloads returns the downloads that are queued or were interrupted.
+// Paused downloads are not returned.
+func (db *Database) GetPendingAutoDownloaderDebridDownloads() ([]*models.AutoDownloaderDebridDownload, error) {
+	var res []*models.AutoDownloaderDebridDownload
+	err := db.gormdb.Where("status IN ?", []string{"queued", "downloading"}).Order("id asc").Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (db *Database) InsertAutoDownloaderDebridDownload(item *models.AutoDownloaderDebridDownload) error {
+	return db.gormdb.Create(item).Error
+}
+
+func (db *Database) SaveAutoDownloaderDebridDownload(item *models.AutoDownloaderDebridDownload) error {
+	return db.gormdb.Save(item).Error
+}
+
+func (db *Database) DeleteAutoDownloaderDebridDownload(id uint) error {
+	return db.gormdb.Delete(&models.AutoDownloaderDebridDownload{}, id).Error
+}
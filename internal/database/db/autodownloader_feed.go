@@ -0,0 +1,38 @@
+package db
+
+import (
+	"seanime/internal/database/models"
+)
+
+func (db *Database) GetAutoDownloaderFeeds() ([]*models.AutoDownloaderFeed, error) {
+	var res []*models.AutoDownloaderFeed
+	err := db.gormdb.Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (db *Database) GetAutoDownloaderFeed(id uint) (*models.AutoDownloaderFeed, error) {
+	var res models.AutoDownloaderFeed
+	err := db.gormdb.First(&res, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (db *Database) InsertAutoDownloaderFeed(feed *models.AutoDownloaderFeed) error {
+	return db.gormdb.Create(feed).Error
+}
+
+func (db *Database) UpdateAutoDownloaderFeed(feed *models.AutoDownloaderFeed) error {
+	// Save all the fields so that they can be cleared
+	return db.gormdb.Save(feed).Error
+}
+
+func (db *Database) DeleteAutoDownloaderFeed(id uint) error {
+	return db.gormdb.Delete(&models.AutoDownloaderFeed{}, id).Error
+}
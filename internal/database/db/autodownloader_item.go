@@ -2,6 +2,7 @@ package db
 
 import (
 	"seanime/internal/database/models"
+	"time"
 )
 
 func (db *Database) GetAutoDownloaderItems() ([]*models.AutoDownloaderItem, error) {
@@ -47,8 +48,11 @@ func (db *Database) DeleteAutoDownloaderItem(id uint) error {
 }
 
 // DeleteDownloadedAutoDownloaderItems will delete all the downloaded queued items from the database.
-func (db *Database) DeleteDownloadedAutoDownloaderItems() error {
-	return db.gormdb.Where("downloaded = ?", true).Delete(&models.AutoDownloaderItem{}).Error
+// Upgrades that still have to replace a file are kept, unless they were created before upgradesExpiredBefore.
+func (db *Database) DeleteDownloadedAutoDownloaderItems(upgradesExpiredBefore time.Time) error {
+	return db.gormdb.
+		Where("downloaded = ? AND (replaces_path IS NULL OR replaces_path = '' OR created_at < ?)", true, upgradesExpiredBefore).
+		Delete(&models.AutoDownloaderItem{}).Error
 }
 
 func (db *Database) UpdateAutoDownloaderItem(id uint, item *models.AutoDownloaderItem) error {
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"seanime/internal/database/models"
+	"sync"
 	"time"
 )
 
@@ -18,6 +19,8 @@ type Database struct {
 	gormdb           *gorm.DB
 	Logger           *zerolog.Logger
 	CurrMediaFillers mo.Option[map[int]*MediaFillerItem]
+	profileID        uint // ID of the server profile
+	profileMu        sync.RWMutex
 }
 
 func (db *Database) Gorm() *gorm.DB {
@@ -64,6 +67,7 @@ func NewDatabase(appDataDir, dbName string, logger *zerolog.Logger) (*Database,
 		gormdb:           db,
 		Logger:           logger,
 		CurrMediaFillers: mo.None[map[int]*MediaFillerItem](),
+		profileID:        DefaultProfileID,
 	}, nil
 }
 
@@ -74,6 +78,7 @@ func migrateTables(db *gorm.DB) error {
 		&models.Settings{},
 		&models.Account{},
 		&models.Mal{},
+		&models.Kitsu{},
 		&models.ScanSummary{},
 		&models.AutoDownloaderRule{},
 		&models.AutoDownloaderItem{},
@@ -89,6 +94,19 @@ func migrateTables(db *gorm.DB) error {
 		&models.OnlinestreamMapping{},
 		&models.DebridSettings{},
 		&models.DebridTorrentItem{},
+		&models.TrackerSyncSettings{},
+		&models.TrackerSyncQueueItem{},
+		&models.TrackerLedgerEntry{},
+		&models.ServerAuth{},
+		&models.AuthToken{},
+		&models.Profile{},
+		&models.OrganizerSettings{},
+		&models.OrganizerLog{},
+		&models.NfoSettings{},
+		&models.AutoDownloaderFeed{},
+		&models.AutoDownloaderDebridDownload{},
+		&models.MediastreamPreTranscodeJob{},
+		&models.MangaAutoDownloaderRule{},
 		//&models.MangaChapterContainer{},
 	)
 	if err != nil {
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"seanime/internal/database/models"
+)
+
+func (db *Database) GetKitsuInfo() (*models.Kitsu, error) {
+	// Get the first entry
+	var res models.Kitsu
+	err := db.gormdb.First(&res, 1).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("Kitsu not connected")
+	} else if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (db *Database) UpsertKitsuInfo(info *models.Kitsu) (*models.Kitsu, error) {
+	err := db.gormdb.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		UpdateAll: true,
+	}).Create(info).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (db *Database) InsertKitsuInfo(info *models.Kitsu) (*models.Kitsu, error) {
+	err := db.gormdb.Create(info).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (db *Database) DeleteKitsuInfo() error {
+	err := db.gormdb.Delete(&models.Kitsu{}, 1).Error
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
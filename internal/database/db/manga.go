@@ -29,6 +29,16 @@ func (db *Database) GetMangaMapping(provider string, mediaId int) (*models.Manga
 	return &res, true
 }
 
+// GetMangaMappings returns all the mappings of a provider.
+func (db *Database) GetMangaMappings(provider string) ([]*models.MangaMapping, error) {
+	var res []*models.MangaMapping
+	err := db.gormdb.Where("provider = ?", provider).Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (db *Database) InsertMangaMapping(provider string, mediaId int, mangaId string) error {
 	mapping := models.MangaMapping{
 		Provider: provider,
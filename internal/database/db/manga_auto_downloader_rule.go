@@ -0,0 +1,48 @@
+package db
+
+import (
+	"seanime/internal/database/models"
+)
+
+func (db *Database) GetMangaAutoDownloaderRules() ([]*models.MangaAutoDownloaderRule, error) {
+	var res []*models.MangaAutoDownloaderRule
+	err := db.gormdb.Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (db *Database) GetMangaAutoDownloaderRule(id uint) (*models.MangaAutoDownloaderRule, error) {
+	var res models.MangaAutoDownloaderRule
+	err := db.gormdb.First(&res, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (db *Database) GetMangaAutoDownloaderRuleByMediaId(mediaId int) (*models.MangaAutoDownloaderRule, bool) {
+	var res models.MangaAutoDownloaderRule
+	err := db.gormdb.Where("media_id = ?", mediaId).First(&res).Error
+	if err != nil {
+		return nil, false
+	}
+
+	return &res, true
+}
+
+func (db *Database) InsertMangaAutoDownloaderRule(rule *models.MangaAutoDownloaderRule) error {
+	return db.gormdb.Create(rule).Error
+}
+
+func (db *Database) UpdateMangaAutoDownloaderRule(rule *models.MangaAutoDownloaderRule) error {
+	// Save all the fields so that they can be cleared
+	return db.gormdb.Save(rule).Error
+}
+
+func (db *Database) DeleteMangaAutoDownloaderRule(id uint) error {
+	return db.gormdb.Delete(&models.MangaAutoDownloaderRule{}, id).Error
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"seanime/internal/database/models"
+)
+
+func (db *Database) GetMediastreamPreTranscodeJobs() ([]*models.MediastreamPreTranscodeJob, error) {
+	var res []*models.MediastreamPreTranscodeJob
+	err := db.gormdb.Order("id asc").Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetPendingMediastreamPreTranscodeJobs returns the jobs that are queued or were interrupted.
+func (db *Database) GetPendingMediastreamPreTranscodeJobs() ([]*models.MediastreamPreTranscodeJob, error) {
+	var res []*models.MediastreamPreTranscodeJob
+	err := db.gormdb.Where("status IN ?", []string{"queued", "running"}).Order("id asc").Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (db *Database) GetMediastreamPreTranscodeJob(id uint) (*models.MediastreamPreTranscodeJob, error) {
+	var res models.MediastreamPreTranscodeJob
+	err := db.gormdb.First(&res, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (db *Database) InsertMediastreamPreTranscodeJob(job *models.MediastreamPreTranscodeJob) error {
+	return db.gormdb.Create(job).Error
+}
+
+func (db *Database) SaveMediastreamPreTranscodeJob(job *models.MediastreamPreTranscodeJob) error {
+	return db.gormdb.Save(job).Error
+}
+
+// DeleteFinishedMediastreamPreTranscodeJobs removes the jobs that are completed, failed or canceled.
+func (db *Database) DeleteFinishedMediastreamPreTranscodeJobs() error {
+	return db.gormdb.Where("status IN ?", []string{"completed", "failed", "canceled"}).Delete(&models.MediastreamPreTranscodeJob{}).Error
+}
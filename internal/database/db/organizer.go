@@ -0,0 +1,33 @@
+package db
+
+import (
+	"seanime/internal/database/models"
+	"time"
+)
+
+func (db *Database) InsertOrganizerLog(log *models.OrganizerLog) error {
+	return db.gormdb.Create(log).Error
+}
+
+func (db *Database) GetOrganizerLog(id uint) (*models.OrganizerLog, error) {
+	var res models.OrganizerLog
+	err := db.gormdb.First(&res, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetOrganizerLogs returns the most recent organizer runs.
+func (db *Database) GetOrganizerLogs(limit int) ([]*models.OrganizerLog, error) {
+	var res []*models.OrganizerLog
+	err := db.gormdb.Order("id desc").Limit(limit).Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (db *Database) SetOrganizerLogUndone(id uint) error {
+	return db.gormdb.Model(&models.OrganizerLog{}).Where("id = ?", id).Update("undone_at", time.Now()).Error
+}
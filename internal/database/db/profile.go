@@ -0,0 +1,130 @@
+package db
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"seanime/internal/database/models"
+)
+
+// DefaultProfileID is the ID of the profile created on first run.
+// It owns the account, theme and playlists created before profiles existed.
+const DefaultProfileID uint = 1
+
+// GetProfileID returns the ID of the server profile.
+// The server profile is the one the background modules (auto downloader, playback manager, sync, Discord RPC) act on behalf of.
+// Requests made by clients are resolved to the profile they selected, see the *Profile* methods.
+func (db *Database) GetProfileID() uint {
+	db.profileMu.RLock()
+	defer db.profileMu.RUnlock()
+	return db.profileID
+}
+
+// SetProfileID sets the server profile.
+// The account and theme returned by GetAccount and GetTheme are those of the server profile.
+func (db *Database) SetProfileID(id uint) {
+	db.profileMu.Lock()
+	defer db.profileMu.Unlock()
+	db.profileID = id
+}
+
+// EnsureDefaultProfile creates the default profile if there are no profiles
+// and assigns the playlists created before profiles existed to it.
+// Returns the server profile.
+func (db *Database) EnsureDefaultProfile() (*models.Profile, error) {
+	var count int64
+	if err := db.gormdb.Model(&models.Profile{}).Count(&count).Error; err != nil {
+		return nil, err
+	}
+
+	if count == 0 {
+		err := db.gormdb.Create(&models.Profile{
+			BaseModel: models.BaseModel{ID: DefaultProfileID},
+			Name:      "Default",
+			IsActive:  true,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_ = db.gormdb.Model(&models.PlaylistEntry{}).
+		Where("profile_id IS NULL OR profile_id = 0").
+		Update("profile_id", DefaultProfileID).Error
+
+	return db.GetActiveProfile()
+}
+
+func (db *Database) GetProfiles() ([]*models.Profile, error) {
+	var res []*models.Profile
+	err := db.gormdb.Order("id asc").Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (db *Database) GetProfile(id uint) (*models.Profile, error) {
+	var res models.Profile
+	err := db.gormdb.First(&res, id).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("profile not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetActiveProfile returns the server profile, falling back to the first profile.
+func (db *Database) GetActiveProfile() (*models.Profile, error) {
+	var res models.Profile
+	err := db.gormdb.Where("is_active = ?", true).First(&res).Error
+	if err == nil {
+		return &res, nil
+	}
+	err = db.gormdb.Order("id asc").First(&res).Error
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (db *Database) InsertProfile(profile *models.Profile) error {
+	return db.gormdb.Create(profile).Error
+}
+
+func (db *Database) UpdateProfile(profile *models.Profile) error {
+	return db.gormdb.Save(profile).Error
+}
+
+// SetActiveProfile marks the profile as the server profile, it is loaded on the next start.
+func (db *Database) SetActiveProfile(id uint) error {
+	return db.gormdb.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Profile{}).Where("id <> ?", id).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Profile{}).Where("id = ?", id).Update("is_active", true).Error
+	})
+}
+
+// DeleteProfile deletes the profile along with its account, theme and playlists.
+func (db *Database) DeleteProfile(id uint) error {
+	accountCacheMu.Lock()
+	delete(accountCache, id)
+	accountCacheMu.Unlock()
+	themeCacheMu.Lock()
+	delete(themeCache, id)
+	themeCacheMu.Unlock()
+
+	return db.gormdb.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.Account{}, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Theme{}, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("profile_id = ?", id).Delete(&models.PlaylistEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Profile{}, id).Error
+	})
+}
@@ -178,3 +178,108 @@ func (db *Database) GetDebridSettings() (*models.DebridSettings, bool) {
 }
 
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var CurrentTrackerSyncSettings *models.TrackerSyncSettings
+
+func (db *Database) UpsertTrackerSyncSettings(settings *models.TrackerSyncSettings) (*models.TrackerSyncSettings, error) {
+	err := db.gormdb.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		UpdateAll: true,
+	}).Create(settings).Error
+
+	if err != nil {
+		db.Logger.Error().Err(err).Msg("db: Failed to save tracker sync settings in the database")
+		return nil, err
+	}
+
+	CurrentTrackerSyncSettings = settings
+
+	db.Logger.Debug().Msg("db: Tracker sync settings saved")
+	return settings, nil
+}
+
+func (db *Database) GetTrackerSyncSettings() (*models.TrackerSyncSettings, bool) {
+
+	if CurrentTrackerSyncSettings != nil {
+		return CurrentTrackerSyncSettings, true
+	}
+
+	var settings models.TrackerSyncSettings
+	err := db.gormdb.Where("id = ?", 1).First(&settings).Error
+	if err != nil {
+		return nil, false
+	}
+	return &settings, true
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var CurrentOrganizerSettings *models.OrganizerSettings
+
+func (db *Database) UpsertOrganizerSettings(settings *models.OrganizerSettings) (*models.OrganizerSettings, error) {
+	err := db.gormdb.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		UpdateAll: true,
+	}).Create(settings).Error
+
+	if err != nil {
+		db.Logger.Error().Err(err).Msg("db: Failed to save organizer settings in the database")
+		return nil, err
+	}
+
+	CurrentOrganizerSettings = settings
+
+	db.Logger.Debug().Msg("db: Organizer settings saved")
+	return settings, nil
+}
+
+func (db *Database) GetOrganizerSettings() (*models.OrganizerSettings, bool) {
+
+	if CurrentOrganizerSettings != nil {
+		return CurrentOrganizerSettings, true
+	}
+
+	var settings models.OrganizerSettings
+	err := db.gormdb.Where("id = ?", 1).First(&settings).Error
+	if err != nil {
+		return nil, false
+	}
+	return &settings, true
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var CurrentNfoSettings *models.NfoSettings
+
+func (db *Database) UpsertNfoSettings(settings *models.NfoSettings) (*models.NfoSettings, error) {
+	err := db.gormdb.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		UpdateAll: true,
+	}).Create(settings).Error
+
+	if err != nil {
+		db.Logger.Error().Err(err).Msg("db: Failed to save NFO settings in the database")
+		return nil, err
+	}
+
+	CurrentNfoSettings = settings
+
+	db.Logger.Debug().Msg("db: NFO settings saved")
+	return settings, nil
+}
+
+func (db *Database) GetNfoSettings() (*models.NfoSettings, bool) {
+
+	if CurrentNfoSettings != nil {
+		return CurrentNfoSettings, true
+	}
+
+	var settings models.NfoSettings
+	err := db.gormdb.Where("id = ?", 1).First(&settings).Error
+	if err != nil {
+		return nil, false
+	}
+	return &settings, true
+}
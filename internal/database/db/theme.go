@@ -3,30 +3,52 @@ package db
 import (
 	"gorm.io/gorm/clause"
 	"seanime/internal/database/models"
+	"sync"
 )
 
-var themeCache *models.Theme
+var (
+	themeCache   = make(map[uint]*models.Theme) // key: profile ID
+	themeCacheMu sync.RWMutex
+)
 
+// GetTheme returns the theme settings of the server profile.
 func (db *Database) GetTheme() (*models.Theme, error) {
+	return db.GetProfileTheme(db.GetProfileID())
+}
+
+// GetProfileTheme returns the theme settings of the profile.
+func (db *Database) GetProfileTheme(profileID uint) (*models.Theme, error) {
 
-	if themeCache != nil {
-		return themeCache, nil
+	themeCacheMu.RLock()
+	cached, ok := themeCache[profileID]
+	themeCacheMu.RUnlock()
+	if ok {
+		return cached, nil
 	}
 
 	var theme models.Theme
-	err := db.gormdb.Where("id = ?", 1).Find(&theme).Error
+	err := db.gormdb.Where("id = ?", profileID).Find(&theme).Error
 
 	if err != nil {
 		return nil, err
 	}
 
-	themeCache = &theme
+	themeCacheMu.Lock()
+	themeCache[profileID] = &theme
+	themeCacheMu.Unlock()
 
 	return &theme, nil
 }
 
-// UpsertTheme updates the theme settings.
+// UpsertTheme updates the theme settings of the server profile.
 func (db *Database) UpsertTheme(settings *models.Theme) (*models.Theme, error) {
+	return db.UpsertProfileTheme(db.GetProfileID(), settings)
+}
+
+// UpsertProfileTheme updates the theme settings of the profile.
+// Themes are keyed by profile ID, the ID of settings is overwritten.
+func (db *Database) UpsertProfileTheme(profileID uint, settings *models.Theme) (*models.Theme, error) {
+	settings.ID = profileID
 
 	err := db.gormdb.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "id"}},
@@ -40,7 +62,9 @@ func (db *Database) UpsertTheme(settings *models.Theme) (*models.Theme, error) {
 
 	db.Logger.Debug().Msg("db: Theme saved")
 
-	themeCache = settings
+	themeCacheMu.Lock()
+	themeCache[profileID] = settings
+	themeCacheMu.Unlock()
 
 	return settings, nil
 
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"seanime/internal/database/models"
+)
+
+func (db *Database) GetTrackerSyncQueueItems() ([]*models.TrackerSyncQueueItem, error) {
+	var res []*models.TrackerSyncQueueItem
+	err := db.gormdb.Order("id asc").Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (db *Database) InsertTrackerSyncQueueItem(item *models.TrackerSyncQueueItem) error {
+	return db.gormdb.Create(item).Error
+}
+
+func (db *Database) UpdateTrackerSyncQueueItem(item *models.TrackerSyncQueueItem) error {
+	return db.gormdb.Save(item).Error
+}
+
+func (db *Database) DeleteTrackerSyncQueueItem(id uint) error {
+	return db.gormdb.Delete(&models.TrackerSyncQueueItem{}, id).Error
+}
+
+// DeleteTrackerSyncQueueItemsByMedia deletes the queued items of a tracker for the given media and action.
+// This is used to drop outdated changes when a newer one is queued.
+func (db *Database) DeleteTrackerSyncQueueItemsByMedia(tracker string, mediaId int, action string) error {
+	return db.gormdb.Where("tracker = ? AND media_id = ? AND action = ?", tracker, mediaId, action).Delete(&models.TrackerSyncQueueItem{}).Error
+}
+
+func (db *Database) ClearTrackerSyncQueue() error {
+	return db.gormdb.Where("1 = 1").Delete(&models.TrackerSyncQueueItem{}).Error
+}
+
+// HasTrackerSyncQueueItems returns true if changes for the given media are waiting to be written to the tracker.
+func (db *Database) HasTrackerSyncQueueItems(tracker string, mediaId int) bool {
+	var count int64
+	err := db.gormdb.Model(&models.TrackerSyncQueueItem{}).Where("tracker = ? AND media_id = ?", tracker, mediaId).Count(&count).Error
+	if err != nil {
+		return false
+	}
+	return count > 0
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (db *Database) InsertTrackerLedgerEntry(entry *models.TrackerLedgerEntry) error {
+	return db.gormdb.Create(entry).Error
+}
+
+// GetTrackerLedgerEntries returns the most recent ledger entries, optionally filtered by media.
+func (db *Database) GetTrackerLedgerEntries(mediaId int, limit int) ([]*models.TrackerLedgerEntry, error) {
+	var res []*models.TrackerLedgerEntry
+	q := db.gormdb.Order("id desc")
+	if mediaId > 0 {
+		q = q.Where("media_id = ?", mediaId)
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	err := q.Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
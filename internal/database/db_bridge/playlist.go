@@ -7,9 +7,10 @@ import (
 	"seanime/internal/library/anime"
 )
 
-func GetPlaylists(db *db.Database) ([]*anime.Playlist, error) {
+// GetPlaylists returns the playlists of the profile.
+func GetPlaylists(db *db.Database, profileID uint) ([]*anime.Playlist, error) {
 	var res []*models.PlaylistEntry
-	err := db.Gorm().Find(&res).Error
+	err := db.Gorm().Where("profile_id = ?", profileID).Find(&res).Error
 	if err != nil {
 		return nil, err
 	}
@@ -21,30 +22,35 @@ func GetPlaylists(db *db.Database) ([]*anime.Playlist, error) {
 			playlist := anime.NewPlaylist(p.Name)
 			playlist.SetLocalFiles(localFiles)
 			playlist.DbId = p.ID
+			playlist.ProfileID = p.ProfileID
 			playlists = append(playlists, playlist)
 		}
 	}
 	return playlists, nil
 }
 
-func SavePlaylist(db *db.Database, playlist *anime.Playlist) error {
+// SavePlaylist saves a new playlist for the profile.
+func SavePlaylist(db *db.Database, profileID uint, playlist *anime.Playlist) error {
 	data, err := json.Marshal(playlist.LocalFiles)
 	if err != nil {
 		return err
 	}
 	playlistEntry := &models.PlaylistEntry{
-		Name:  playlist.Name,
-		Value: data,
+		Name:      playlist.Name,
+		Value:     data,
+		ProfileID: profileID,
 	}
 
 	return db.Gorm().Save(playlistEntry).Error
 }
 
-func DeletePlaylist(db *db.Database, id uint) error {
-	return db.Gorm().Where("id = ?", id).Delete(&models.PlaylistEntry{}).Error
+// DeletePlaylist deletes the playlist if it belongs to the profile.
+func DeletePlaylist(db *db.Database, profileID uint, id uint) error {
+	return db.Gorm().Where("id = ? AND profile_id = ?", id, profileID).Delete(&models.PlaylistEntry{}).Error
 }
 
-func UpdatePlaylist(db *db.Database, playlist *anime.Playlist) error {
+// UpdatePlaylist updates the playlist if it belongs to the profile.
+func UpdatePlaylist(db *db.Database, profileID uint, playlist *anime.Playlist) error {
 	data, err := json.Marshal(playlist.LocalFiles)
 	if err != nil {
 		return err
@@ -52,7 +58,7 @@ func UpdatePlaylist(db *db.Database, playlist *anime.Playlist) error {
 
 	// Get the playlist entry
 	playlistEntry := &models.PlaylistEntry{}
-	if err := db.Gorm().Where("id = ?", playlist.DbId).First(playlistEntry).Error; err != nil {
+	if err := db.Gorm().Where("id = ? AND profile_id = ?", playlist.DbId, profileID).First(playlistEntry).Error; err != nil {
 		return err
 	}
 
@@ -63,9 +69,10 @@ func UpdatePlaylist(db *db.Database, playlist *anime.Playlist) error {
 	return db.Gorm().Save(playlistEntry).Error
 }
 
-func GetPlaylist(db *db.Database, id uint) (*anime.Playlist, error) {
+// GetPlaylist returns the playlist if it belongs to the profile.
+func GetPlaylist(db *db.Database, profileID uint, id uint) (*anime.Playlist, error) {
 	playlistEntry := &models.PlaylistEntry{}
-	if err := db.Gorm().Where("id = ?", id).First(playlistEntry).Error; err != nil {
+	if err := db.Gorm().Where("id = ? AND profile_id = ?", id, profileID).First(playlistEntry).Error; err != nil {
 		return nil, err
 	}
 
@@ -77,6 +84,7 @@ func GetPlaylist(db *db.Database, id uint) (*anime.Playlist, error) {
 	playlist := anime.NewPlaylist(playlistEntry.Name)
 	playlist.SetLocalFiles(localFiles)
 	playlist.DbId = playlistEntry.ID
+	playlist.ProfileID = playlistEntry.ProfileID
 
 	return playlist, nil
 }
@@ -78,6 +78,18 @@ type LibrarySettings struct {
 	EnableWatchContinuity    bool         `gorm:"column:enable_watch_continuity" json:"enableWatchContinuity"`
 	LibraryPaths             LibraryPaths `gorm:"column:library_paths;type:text" json:"libraryPaths"`
 	AutoSyncOfflineLocalData bool         `gorm:"column:auto_sync_offline_local_data" json:"autoSyncOfflineLocalData"`
+	// v2.3+
+	// TrackingPlatform is the platform used to track progress, "anilist" (default), "mal" or "kitsu"
+	TrackingPlatform string `gorm:"column:tracking_platform" json:"trackingPlatform"`
+	// IncrementalScan makes the auto scanner only scan the files reported by the watcher
+	// and the files that changed while the app was not running
+	IncrementalScan bool `gorm:"column:incremental_scan" json:"incrementalScan"`
+	// Sync the watch history with another instance
+	ContinuityPeerSyncEnabled bool   `gorm:"column:continuity_peer_sync_enabled" json:"continuityPeerSyncEnabled"`
+	ContinuityPeerSyncUrl     string `gorm:"column:continuity_peer_sync_url" json:"continuityPeerSyncUrl"`
+	ContinuityPeerSyncSecret  string `gorm:"column:continuity_peer_sync_secret" json:"continuityPeerSyncSecret"`
+	// ContinuityPeerSyncProfileID is the profile that configured the sync, its watch history is the one that is synced
+	ContinuityPeerSyncProfileID uint `gorm:"column:continuity_peer_sync_profile_id" json:"continuityPeerSyncProfileId"`
 }
 
 func (o *LibrarySettings) GetLibraryPaths() (ret []string) {
@@ -108,6 +120,16 @@ func (o LibraryPaths) Value() (driver.Value, error) {
 
 type MangaSettings struct {
 	DefaultProvider string `gorm:"column:default_manga_provider" json:"defaultMangaProvider"`
+	// Directories of CBZ/CBR archives and image folders, each sub-directory is a series
+	LocalLibraryPaths LibraryPaths `gorm:"column:manga_local_library_paths;type:text" json:"localLibraryPaths"`
+	// ImageCacheSizeLimit is the size limit of the page image cache in MB, the default limit is used if 0
+	ImageCacheSizeLimit int `gorm:"column:manga_image_cache_size_limit" json:"imageCacheSizeLimit"`
+	// OptimizeImages downscales pages wider than ImageMaxWidth and re-encodes them with ImageQuality
+	OptimizeImages  bool   `gorm:"column:manga_optimize_images" json:"optimizeImages"`
+	ImageMaxWidth   int    `gorm:"column:manga_image_max_width" json:"imageMaxWidth"`
+	ImageQuality    int    `gorm:"column:manga_image_quality" json:"imageQuality"`
+	ImageFormat     string `gorm:"column:manga_image_format" json:"imageFormat"`          // "jpeg" (default), "webp" or "avif", WebP and AVIF are encoded with FFmpeg
+	SplitTallImages bool   `gorm:"column:manga_split_tall_images" json:"splitTallImages"` // Split webtoon strips into tiles when downloading
 }
 
 type MediaPlayerSettings struct {
@@ -139,6 +161,15 @@ type TorrentSettings struct {
 	ShowActiveTorrentCount bool `gorm:"column:show_active_torrent_count" json:"showActiveTorrentCount"`
 	// v2.2+
 	HideTorrentList bool `gorm:"column:hide_torrent_list" json:"hideTorrentList"`
+	// Deluge Web UI (JSON-RPC)
+	DelugePath     string `gorm:"column:deluge_path" json:"delugePath"`
+	DelugeHost     string `gorm:"column:deluge_host" json:"delugeHost"`
+	DelugePort     int    `gorm:"column:deluge_port" json:"delugePort"`
+	DelugePassword string `gorm:"column:deluge_password" json:"delugePassword"`
+	// rTorrent XML-RPC endpoint, e.g. http://127.0.0.1:8080/RPC2 or the ruTorrent httprpc plugin
+	RTorrentURL      string `gorm:"column:rtorrent_url" json:"rtorrentUrl"`
+	RTorrentUsername string `gorm:"column:rtorrent_username" json:"rtorrentUsername"`
+	RTorrentPassword string `gorm:"column:rtorrent_password" json:"rtorrentPassword"`
 }
 
 type ListSyncSettings struct {
@@ -173,6 +204,19 @@ type Mal struct {
 	TokenExpiresAt time.Time `gorm:"column:token_expires_at" json:"tokenExpiresAt"`
 }
 
+// +---------------------+
+// |        Kitsu        |
+// +---------------------+
+
+type Kitsu struct {
+	BaseModel
+	UserID         string    `gorm:"column:user_id" json:"userId"`
+	Username       string    `gorm:"column:username" json:"username"`
+	AccessToken    string    `gorm:"column:access_token" json:"accessToken"`
+	RefreshToken   string    `gorm:"column:refresh_token" json:"refreshToken"`
+	TokenExpiresAt time.Time `gorm:"column:token_expires_at" json:"tokenExpiresAt"`
+}
+
 // +---------------------+
 // |    Scan Summary     |
 // +---------------------+
@@ -201,6 +245,40 @@ type AutoDownloaderItem struct {
 	Magnet      string `gorm:"column:magnet" json:"magnet"`
 	TorrentName string `gorm:"column:torrent_name" json:"torrentName"`
 	Downloaded  bool   `gorm:"column:downloaded" json:"downloaded"`
+	// IsUpgrade is true if the torrent is a better release of an episode that was already downloaded
+	IsUpgrade bool `gorm:"column:is_upgrade" json:"isUpgrade"`
+	// ReplacesPath is the file that is deleted once the upgrade is scanned
+	ReplacesPath string `gorm:"column:replaces_path" json:"replacesPath"`
+}
+
+// AutoDownloaderDebridDownload is a torrent added to the debrid service by the Auto Downloader.
+// The Auto Downloader downloads the files once the torrent is ready and places them in the rule's destination.
+type AutoDownloaderDebridDownload struct {
+	BaseModel
+	ItemID        uint   `gorm:"column:item_id" json:"itemId"` // AutoDownloaderItem
+	MediaID       int    `gorm:"column:media_id" json:"mediaId"`
+	Episode       int    `gorm:"column:episode" json:"episode"`
+	Provider      string `gorm:"column:provider" json:"provider"`
+	TorrentItemID string `gorm:"column:torrent_item_id" json:"torrentItemId"`
+	TorrentName   string `gorm:"column:torrent_name" json:"torrentName"`
+	Destination   string `gorm:"column:destination" json:"destination"`
+	// Status is "paused", "queued", "downloading", "completed" or "failed"
+	Status string `gorm:"column:status" json:"status"`
+	// Attempts is the number of failed download attempts
+	Attempts int    `gorm:"column:attempts" json:"attempts"`
+	Error    string `gorm:"column:error" json:"error"`
+}
+
+// AutoDownloaderFeed is an RSS or Atom feed used by the Auto Downloader as a source, in addition to the torrent provider.
+type AutoDownloaderFeed struct {
+	BaseModel
+	Name    string `gorm:"column:name" json:"name"`
+	URL     string `gorm:"column:url" json:"url"`
+	Enabled bool   `gorm:"column:enabled" json:"enabled"`
+	// Cookies is sent as the Cookie header, e.g. "uid=1234; pass=abcd"
+	Cookies string `gorm:"column:cookies" json:"cookies"`
+	// Headers are sent with each request, one "Name: Value" per line
+	Headers string `gorm:"column:headers" json:"headers"`
 }
 
 type AutoDownloaderSettings struct {
@@ -262,8 +340,9 @@ type Theme struct {
 
 type PlaylistEntry struct {
 	BaseModel
-	Name  string `gorm:"column:name" json:"name"`
-	Value []byte `gorm:"column:value" json:"value"`
+	Name      string `gorm:"column:name" json:"name"`
+	Value     []byte `gorm:"column:value" json:"value"`
+	ProfileID uint   `gorm:"column:profile_id;index" json:"profileId"`
 }
 
 // +------------------------+
@@ -297,10 +376,31 @@ type MediastreamSettings struct {
 	PreTranscodeLibraryDir        string `gorm:"column:pre_transcode_library_dir" json:"preTranscodeLibraryDir"`
 	FfmpegPath                    string `gorm:"column:ffmpeg_path" json:"ffmpegPath"`
 	FfprobePath                   string `gorm:"column:ffprobe_path" json:"ffprobePath"`
+	TrickplayEnabled              bool   `gorm:"column:trickplay_enabled" json:"trickplayEnabled"`
+	TrickplayInterval             int    `gorm:"column:trickplay_interval" json:"trickplayInterval"`               // Seconds between two thumbnails
+	TrickplayMaxCacheSize         int    `gorm:"column:trickplay_max_cache_size" json:"trickplayMaxCacheSize"`     // MB, 0 means no limit
+	PreTranscodeQuality           string `gorm:"column:pre_transcode_quality" json:"preTranscodeQuality"`          // Default quality of the pre-transcode jobs
+	PreTranscodeWindowStart       string `gorm:"column:pre_transcode_window_start" json:"preTranscodeWindowStart"` // "HH:MM", jobs run at any time if empty
+	PreTranscodeWindowEnd         string `gorm:"column:pre_transcode_window_end" json:"preTranscodeWindowEnd"`     // "HH:MM"
+	PreTranscodeMaxThreads        int    `gorm:"column:pre_transcode_max_threads" json:"preTranscodeMaxThreads"`   // 0 means no limit
 
 	//TranscodeTempDir              string `gorm:"column:transcode_temp_dir" json:"transcodeTempDir"` // DEPRECATED
 }
 
+// MediastreamPreTranscodeJob is a file queued to be pre-transcoded by the optimizer.
+type MediastreamPreTranscodeJob struct {
+	BaseModel
+	Path    string `gorm:"column:path" json:"path"`
+	Hash    string `gorm:"column:hash" json:"hash"`
+	MediaID int    `gorm:"column:media_id" json:"mediaId"`
+	Quality string `gorm:"column:quality" json:"quality"`
+	// Status is "queued", "running", "completed", "failed" or "canceled"
+	Status     string  `gorm:"column:status" json:"status"`
+	Progress   float64 `gorm:"column:progress" json:"progress"` // 0 to 1
+	OutputPath string  `gorm:"column:output_path" json:"outputPath"`
+	Error      string  `gorm:"column:error" json:"error"`
+}
+
 // +---------------------+
 // |    TorrentStream    |
 // +---------------------+
@@ -319,6 +419,13 @@ type TorrentstreamSettings struct {
 	StreamingServerPort int    `gorm:"column:streaming_server_port" json:"streamingServerPort"`
 	//FallbackToTorrentStreamingView bool   `gorm:"column:fallback_to_torrent_streaming_view" json:"fallbackToTorrentStreamingView"`
 	IncludeInLibrary bool `gorm:"column:include_in_library" json:"includeInLibrary"`
+	// Seeding policy, 0 means no limit
+	SeedRatioLimit  float64 `gorm:"column:seed_ratio_limit" json:"seedRatioLimit"`
+	SeedTimeLimit   int     `gorm:"column:seed_time_limit" json:"seedTimeLimit"`     // Minutes
+	UploadRateLimit int     `gorm:"column:upload_rate_limit" json:"uploadRateLimit"` // KB/s
+	// MaxCacheSize is the maximum size of the download directory in MB.
+	// Completed torrents are kept until the size is reached, 0 disables the cache.
+	MaxCacheSize int `gorm:"column:max_cache_size" json:"maxCacheSize"`
 }
 
 type TorrentstreamHistory struct {
@@ -351,6 +458,22 @@ type MangaMapping struct {
 	MangaID  string `gorm:"column:manga_id" json:"mangaId"` // ID from search result, used to fetch chapters
 }
 
+// MangaAutoDownloaderRule is used to queue new chapters of a manga automatically.
+type MangaAutoDownloaderRule struct {
+	BaseModel
+	MediaID  int    `gorm:"column:media_id;uniqueIndex" json:"mediaId"`
+	Enabled  bool   `gorm:"column:enabled" json:"enabled"`
+	Provider string `gorm:"column:provider" json:"provider"`
+	// Scanlators is a comma-separated list of scanlators in order of preference, e.g. "Group A, Group B"
+	// When a chapter is released by several scanlators, the first one in the list is used.
+	Scanlators string `gorm:"column:scanlators" json:"scanlators"`
+	// OnlyAfterProgress queues only the chapters after the user's progress
+	OnlyAfterProgress bool `gorm:"column:only_after_progress" json:"onlyAfterProgress"`
+	// LastQueuedChapter is the highest chapter number queued by the rule.
+	// Chapters up to this number are not queued again, even if they are deleted.
+	LastQueuedChapter float64 `gorm:"column:last_queued_chapter" json:"lastQueuedChapter"`
+}
+
 type MangaChapterContainer struct {
 	BaseModel
 	Provider  string `gorm:"column:provider" json:"provider"`
@@ -392,3 +515,129 @@ type DebridTorrentItem struct {
 	Provider      string `gorm:"column:provider" json:"provider"`
 	MediaId       int    `gorm:"column:media_id" json:"mediaId"`
 }
+
+// +---------------------+
+// |    Tracker sync     |
+// +---------------------+
+
+// TrackerSyncSettings defines the additional trackers progress, status and score changes are mirrored to.
+type TrackerSyncSettings struct {
+	BaseModel
+	Enabled         bool `gorm:"column:enabled" json:"enabled"`
+	MirrorAnilist   bool `gorm:"column:mirror_anilist" json:"mirrorAnilist"`
+	MirrorMal       bool `gorm:"column:mirror_mal" json:"mirrorMal"`
+	MirrorKitsu     bool `gorm:"column:mirror_kitsu" json:"mirrorKitsu"`
+	MirrorShikimori bool `gorm:"column:mirror_shikimori" json:"mirrorShikimori"`
+	// ShikimoriAccessToken is a personal access token generated on shikimori.one
+	ShikimoriAccessToken string `gorm:"column:shikimori_access_token" json:"shikimoriAccessToken"`
+	// EnableLedger records every change in a local-only ledger
+	EnableLedger bool `gorm:"column:enable_ledger" json:"enableLedger"`
+	// RetryInterval is the interval in minutes between retries of failed writes
+	RetryInterval int `gorm:"column:retry_interval" json:"retryInterval"`
+}
+
+// TrackerSyncQueueItem is a change that could not be written to a tracker and will be retried.
+type TrackerSyncQueueItem struct {
+	BaseModel
+	Tracker       string    `gorm:"column:tracker" json:"tracker"`
+	Action        string    `gorm:"column:action" json:"action"`
+	MediaID       int       `gorm:"column:media_id" json:"mediaId"`
+	Change        []byte    `gorm:"column:change" json:"change"` // JSON-encoded change
+	Attempts      int       `gorm:"column:attempts" json:"attempts"`
+	LastError     string    `gorm:"column:last_error" json:"lastError"`
+	NextAttemptAt time.Time `gorm:"column:next_attempt_at" json:"nextAttemptAt"`
+}
+
+// TrackerLedgerEntry is a change recorded in the local-only ledger.
+type TrackerLedgerEntry struct {
+	BaseModel
+	Action   string `gorm:"column:action" json:"action"`
+	MediaID  int    `gorm:"column:media_id" json:"mediaId"`
+	Status   string `gorm:"column:status" json:"status"`
+	ScoreRaw *int   `gorm:"column:score_raw" json:"scoreRaw"`
+	Progress *int   `gorm:"column:progress" json:"progress"`
+}
+
+// +---------------------+
+// |        Auth         |
+// +---------------------+
+
+// ServerAuth holds the server password.
+// Authentication is disabled when no password is set.
+type ServerAuth struct {
+	BaseModel
+	PasswordHash string `gorm:"column:password_hash" json:"-"`
+}
+
+// AuthToken is either a browser session or a token minted for another device.
+// Only the SHA-256 hash of the token is stored.
+type AuthToken struct {
+	BaseModel
+	Name       string     `gorm:"column:name" json:"name"`
+	Kind       string     `gorm:"column:kind" json:"kind"`   // "session" or "token"
+	Scope      string     `gorm:"column:scope" json:"scope"` // "full" or "read"
+	TokenHash  string     `gorm:"column:token_hash;uniqueIndex" json:"-"`
+	ExpiresAt  *time.Time `gorm:"column:expires_at" json:"expiresAt"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"lastUsedAt"`
+}
+
+// +---------------------+
+// |       Profile       |
+// +---------------------+
+
+// Profile is a user of the server.
+// The AniList account and the theme of a profile are stored in the rows whose ID is the profile ID.
+// The library (local files) is shared between all profiles.
+type Profile struct {
+	BaseModel
+	Name     string `gorm:"column:name" json:"name"`
+	Avatar   string `gorm:"column:avatar" json:"avatar"`
+	PinHash  string `gorm:"column:pin_hash" json:"-"`
+	IsActive bool   `gorm:"column:is_active" json:"isActive"` // Server profile, see db.Database.GetProfileID
+	// Discord overrides the global Discord settings when the profile is the server profile
+	Discord *DiscordSettings `gorm:"embedded;embeddedPrefix:discord_" json:"discord"`
+}
+
+// +---------------------+
+// |      Organizer      |
+// +---------------------+
+
+// OrganizerSettings defines how matched files are renamed and moved.
+type OrganizerSettings struct {
+	BaseModel
+	// Template is the path of the organized file relative to the destination directory
+	// e.g. "{romaji}/Season {season}/{romaji} - S{season:02}E{episode:02} [{group}][{resolution}].{ext}"
+	Template string `gorm:"column:template" json:"template"`
+	// Mode is "move", "hardlink" or "symlink"
+	Mode string `gorm:"column:mode" json:"mode"`
+	// DestinationDir is the directory the files are organized into, defaults to the library path.
+	// It must be inside a library when moving files and outside the library when creating links.
+	DestinationDir string `gorm:"column:destination_dir" json:"destinationDir"`
+	// DeleteEmptyDirs deletes the source directories left empty after moving files
+	DeleteEmptyDirs bool `gorm:"column:delete_empty_dirs" json:"deleteEmptyDirs"`
+}
+
+// OrganizerLog records the operations of an organizer run so that it can be undone.
+type OrganizerLog struct {
+	BaseModel
+	Mode       string     `gorm:"column:mode" json:"mode"`
+	Operations []byte     `gorm:"column:operations" json:"operations"` // JSON-encoded []*organizer.Operation
+	UndoneAt   *time.Time `gorm:"column:undone_at" json:"undoneAt"`
+}
+
+// +---------------------+
+// |     NFO Export      |
+// +---------------------+
+
+// NfoSettings defines how the NFO files and artwork of the matched library are exported for Kodi, Jellyfin and Plex.
+type NfoSettings struct {
+	BaseModel
+	// Enabled exports the files after each scan
+	Enabled bool `gorm:"column:enabled" json:"enabled"`
+	// Mode is "alongside" (next to the media files) or "mirror" (in a directory of symlinks with clean names)
+	Mode string `gorm:"column:mode" json:"mode"`
+	// MirrorDir is the directory of symlinks, required in "mirror" mode
+	MirrorDir string `gorm:"column:mirror_dir" json:"mirrorDir"`
+	// DownloadArtwork downloads the posters, fanart and episode thumbnails
+	DownloadArtwork bool `gorm:"column:download_artwork" json:"downloadArtwork"`
+}
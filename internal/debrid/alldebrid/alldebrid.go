@@ -0,0 +1,586 @@
+package alldebrid
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"github.com/rs/zerolog"
+	"github.com/samber/mo"
+	"net/http"
+	"net/url"
+	"seanime/internal/debrid/debrid"
+	"seanime/internal/util"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	AllDebrid struct {
+		baseUrl      string
+		apiKey       mo.Option[string]
+		client       *http.Client
+		logger       *zerolog.Logger
+		pollInterval time.Duration
+	}
+
+	Response struct {
+		Status string          `json:"status"`
+		Data   json.RawMessage `json:"data"`
+		Error  *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	Link struct {
+		Link     string `json:"link"`
+		Filename string `json:"filename"`
+		Size     int64  `json:"size"`
+	}
+
+	Magnet struct {
+		ID             int     `json:"id"`
+		Filename       string  `json:"filename"`
+		Size           int64   `json:"size"`
+		Hash           string  `json:"hash"`
+		Status         string  `json:"status"`
+		StatusCode     int     `json:"statusCode"`
+		Downloaded     int64   `json:"downloaded"`
+		Seeders        int     `json:"seeders"`
+		DownloadSpeed  int64   `json:"downloadSpeed"`
+		UploadDate     int64   `json:"uploadDate"`
+		CompletionDate int64   `json:"completionDate"`
+		Links          []*Link `json:"links"` // Only present when the magnet is ready
+	}
+
+	UploadedMagnet struct {
+		Magnet string `json:"magnet"`
+		Hash   string `json:"hash"`
+		Name   string `json:"name"`
+		Size   int64  `json:"size"`
+		Ready  bool   `json:"ready"`
+		ID     int    `json:"id"`
+		Error  *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	InstantMagnet struct {
+		Magnet  string `json:"magnet"`
+		Hash    string `json:"hash"`
+		Instant bool   `json:"instant"`
+		Files   []struct {
+			Name string `json:"n"`
+			Size int64  `json:"s"`
+		} `json:"files"`
+	}
+)
+
+// Magnet status codes
+const (
+	StatusCodeInQueue     = 0
+	StatusCodeDownloading = 1
+	StatusCodeCompressing = 2
+	StatusCodeUploading   = 3
+	StatusCodeReady       = 4
+	// Status codes above 4 are errors
+)
+
+const agent = "seanime"
+
+func NewAllDebrid(logger *zerolog.Logger) debrid.Provider {
+	return &AllDebrid{
+		baseUrl:      "https://api.alldebrid.com/v4",
+		apiKey:       mo.None[string](),
+		client:       &http.Client{},
+		logger:       logger,
+		pollInterval: 4 * time.Second,
+	}
+}
+
+func (t *AllDebrid) GetSettings() debrid.Settings {
+	return debrid.Settings{
+		ID:   "alldebrid",
+		Name: "AllDebrid",
+	}
+}
+
+// doQuery sends the request and returns the data of the response.
+func (t *AllDebrid) doQuery(method, endpoint string, params url.Values) (json.RawMessage, error) {
+	apiKey, found := t.apiKey.Get()
+	if !found {
+		return nil, debrid.ErrNotAuthenticated
+	}
+
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("agent", agent)
+
+	var req *http.Request
+	var err error
+	if method == http.MethodPost {
+		req, err = http.NewRequest(method, t.baseUrl+endpoint, strings.NewReader(params.Encode()))
+		if err == nil {
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		req, err = http.NewRequest(method, t.baseUrl+endpoint+"?"+params.Encode(), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "Bearer "+apiKey)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var ret Response
+
+	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil {
+		t.logger.Error().Err(err).Msg("alldebrid: Failed to decode response")
+		return nil, err
+	}
+
+	if ret.Status != "success" {
+		if ret.Error != nil {
+			if strings.HasPrefix(ret.Error.Code, "AUTH_") {
+				return nil, fmt.Errorf("%w: %s", debrid.ErrNotAuthenticated, ret.Error.Message)
+			}
+			return nil, fmt.Errorf("request failed: %s (%s)", ret.Error.Message, ret.Error.Code)
+		}
+		return nil, fmt.Errorf("request failed")
+	}
+
+	return ret.Data, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (t *AllDebrid) Authenticate(apiKey string) error {
+	t.apiKey = mo.Some(apiKey)
+	return nil
+}
+
+func (t *AllDebrid) GetInstantAvailability(hashes []string) map[string]debrid.TorrentItemInstantAvailability {
+
+	t.logger.Trace().Strs("hashes", hashes).Msg("alldebrid: Checking instant availability")
+
+	availability := make(map[string]debrid.TorrentItemInstantAvailability)
+
+	for i := 0; i < len(hashes); i += 100 {
+		end := min(i+100, len(hashes))
+
+		data, err := t.doQuery(http.MethodPost, "/magnet/instant", url.Values{"magnets[]": hashes[i:end]})
+		if err != nil {
+			t.logger.Debug().Err(err).Msg("alldebrid: Instant availability is unavailable")
+			return availability
+		}
+
+		var d struct {
+			Magnets []*InstantMagnet `json:"magnets"`
+		}
+		if err := json.Unmarshal(data, &d); err != nil {
+			return availability
+		}
+
+		for _, magnet := range d.Magnets {
+			if !magnet.Instant {
+				continue
+			}
+
+			cachedFiles := make(map[string]*debrid.CachedFile)
+			for idx, file := range magnet.Files {
+				cachedFiles[strconv.Itoa(idx)] = &debrid.CachedFile{
+					Name: file.Name,
+					Size: file.Size,
+				}
+			}
+
+			availability[strings.ToLower(magnet.Hash)] = debrid.TorrentItemInstantAvailability{
+				CachedFiles: cachedFiles,
+			}
+		}
+	}
+
+	return availability
+}
+
+// AddTorrent uploads the magnet link. AllDebrid always downloads every file of the torrent.
+func (t *AllDebrid) AddTorrent(opts debrid.AddTorrentOptions) (string, error) {
+
+	// Check if the torrent is already added
+	if opts.InfoHash != "" {
+		magnets, err := t.getMagnets()
+		if err == nil {
+			for _, magnet := range magnets {
+				if strings.EqualFold(magnet.Hash, opts.InfoHash) {
+					return strconv.Itoa(magnet.ID), nil
+				}
+			}
+		}
+	}
+
+	t.logger.Trace().Str("magnetLink", opts.MagnetLink).Msg("alldebrid: Adding torrent")
+
+	magnet, err := t.uploadMagnet(opts.MagnetLink)
+	if err != nil {
+		return "", fmt.Errorf("alldebrid: Failed to add torrent: %w", err)
+	}
+
+	t.logger.Debug().Str("torrentId", strconv.Itoa(magnet.ID)).Str("torrentName", magnet.Name).Str("torrentHash", magnet.Hash).Msg("alldebrid: Torrent added")
+
+	return strconv.Itoa(magnet.ID), nil
+}
+
+func (t *AllDebrid) uploadMagnet(magnetLink string) (*UploadedMagnet, error) {
+	data, err := t.doQuery(http.MethodPost, "/magnet/upload", url.Values{"magnets[]": {magnetLink}})
+	if err != nil {
+		return nil, err
+	}
+
+	var d struct {
+		Magnets []*UploadedMagnet `json:"magnets"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+
+	if len(d.Magnets) == 0 {
+		return nil, fmt.Errorf("no magnet uploaded")
+	}
+	if d.Magnets[0].Error != nil {
+		return nil, fmt.Errorf("%s (%s)", d.Magnets[0].Error.Message, d.Magnets[0].Error.Code)
+	}
+
+	return d.Magnets[0], nil
+}
+
+// GetTorrentStreamUrl blocks until the torrent is downloaded and returns the unlocked link of the file.
+func (t *AllDebrid) GetTorrentStreamUrl(ctx context.Context, opts debrid.StreamTorrentOptions, itemCh chan debrid.TorrentItem) (streamUrl string, err error) {
+
+	t.logger.Trace().Str("torrentId", opts.ID).Str("fileId", opts.FileId).Msg("alldebrid: Retrieving stream link")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(t.pollInterval):
+			magnet, _err := t.getMagnet(opts.ID)
+			if _err != nil {
+				t.logger.Error().Err(_err).Msg("alldebrid: Failed to get torrent")
+				return "", fmt.Errorf("alldebrid: Failed to get torrent: %w", _err)
+			}
+
+			itemCh <- *toDebridTorrent(magnet)
+
+			if magnet.StatusCode > StatusCodeReady {
+				return "", fmt.Errorf("alldebrid: Torrent failed: %s", magnet.Status)
+			}
+
+			if magnet.StatusCode == StatusCodeReady {
+				downloadUrl, _err := t.getFileDownloadUrl(magnet, opts.FileId)
+				if _err != nil {
+					t.logger.Error().Err(_err).Msg("alldebrid: Failed to get download URL")
+					return "", fmt.Errorf("alldebrid: Failed to get download URL: %w", _err)
+				}
+				return downloadUrl, nil
+			}
+		}
+	}
+}
+
+// GetTorrentDownloadUrls returns the unlocked link of the file, or of every file.
+func (t *AllDebrid) GetTorrentDownloadUrls(opts debrid.DownloadTorrentOptions) (downloadUrls []string, err error) {
+
+	t.logger.Trace().Str("torrentId", opts.ID).Msg("alldebrid: Retrieving download link")
+
+	magnet, err := t.getMagnet(opts.ID)
+	if err != nil {
+		return nil, fmt.Errorf("alldebrid: Failed to get download URL: %w", err)
+	}
+
+	if magnet.StatusCode != StatusCodeReady {
+		return nil, fmt.Errorf("alldebrid: Failed to get download URL, torrent is not ready")
+	}
+
+	if opts.FileId != "" {
+		downloadUrl, err := t.getFileDownloadUrl(magnet, opts.FileId)
+		if err != nil {
+			return nil, fmt.Errorf("alldebrid: Failed to get download URL: %w", err)
+		}
+		return []string{downloadUrl}, nil
+	}
+
+	downloadUrls = make([]string, 0, len(magnet.Links))
+	for _, link := range magnet.Links {
+		unlocked, err := t.unlockLink(link.Link)
+		if err != nil {
+			return nil, fmt.Errorf("alldebrid: Failed to get download URL: %w", err)
+		}
+		downloadUrls = append(downloadUrls, unlocked)
+	}
+
+	if len(downloadUrls) == 0 {
+		return nil, fmt.Errorf("alldebrid: Failed to get download URL, no links found")
+	}
+
+	t.logger.Debug().Int("count", len(downloadUrls)).Msg("alldebrid: Download links retrieved")
+
+	return downloadUrls, nil
+}
+
+// getFileDownloadUrl returns the unlocked link of a file, the file ID is the index of its link.
+func (t *AllDebrid) getFileDownloadUrl(magnet *Magnet, fileId string) (string, error) {
+	if len(magnet.Links) == 0 {
+		return "", fmt.Errorf("no links found")
+	}
+
+	idx := 0
+	if fileId != "" {
+		var err error
+		idx, err = strconv.Atoi(fileId)
+		if err != nil || idx < 0 || idx >= len(magnet.Links) {
+			return "", fmt.Errorf("file not found")
+		}
+	}
+
+	downloadUrl, err := t.unlockLink(magnet.Links[idx].Link)
+	if err != nil {
+		return "", err
+	}
+
+	t.logger.Debug().Str("downloadUrl", downloadUrl).Msg("alldebrid: Download link retrieved")
+
+	return downloadUrl, nil
+}
+
+func (t *AllDebrid) unlockLink(link string) (string, error) {
+	data, err := t.doQuery(http.MethodGet, "/link/unlock", url.Values{"link": {link}})
+	if err != nil {
+		return "", err
+	}
+
+	var d struct {
+		Link     string `json:"link"`
+		Filename string `json:"filename"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return "", err
+	}
+
+	if d.Link == "" {
+		return "", fmt.Errorf("link could not be unlocked")
+	}
+
+	return d.Link, nil
+}
+
+func (t *AllDebrid) GetTorrent(id string) (ret *debrid.TorrentItem, err error) {
+	magnet, err := t.getMagnet(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ret = toDebridTorrent(magnet)
+
+	return ret, nil
+}
+
+func (t *AllDebrid) getMagnet(id string) (ret *Magnet, err error) {
+
+	data, err := t.doQuery(http.MethodGet, "/magnet/status", url.Values{"id": {id}})
+	if err != nil {
+		return nil, fmt.Errorf("alldebrid: Failed to get torrent: %w", err)
+	}
+
+	var d struct {
+		Magnets *Magnet `json:"magnets"`
+	}
+	err = json.Unmarshal(data, &d)
+	if err != nil || d.Magnets == nil {
+		return nil, fmt.Errorf("alldebrid: Failed to parse torrent: %w", err)
+	}
+
+	return d.Magnets, nil
+}
+
+// GetTorrentInfo uploads the magnet link to retrieve the torrent's files.
+// The files are only known once the torrent is ready, so it fails for torrents that are not cached.
+// The returned ID is set when the torrent was added by this call so that the caller can delete it.
+func (t *AllDebrid) GetTorrentInfo(opts debrid.GetTorrentInfoOptions) (ret *debrid.TorrentInfo, err error) {
+
+	if opts.MagnetLink == "" {
+		return nil, fmt.Errorf("alldebrid: Magnet link is required to retrieve torrent info")
+	}
+
+	var id string
+	added := false
+
+	if opts.InfoHash != "" {
+		magnets, err := t.getMagnets()
+		if err == nil {
+			for _, magnet := range magnets {
+				if strings.EqualFold(magnet.Hash, opts.InfoHash) {
+					id = strconv.Itoa(magnet.ID)
+					break
+				}
+			}
+		}
+	}
+
+	if id == "" {
+		uploaded, err := t.uploadMagnet(opts.MagnetLink)
+		if err != nil {
+			return nil, fmt.Errorf("alldebrid: Failed to get torrent info: %w", err)
+		}
+		id = strconv.Itoa(uploaded.ID)
+		added = true
+	}
+
+	magnet, err := t.getMagnet(id)
+	if err == nil && magnet.StatusCode != StatusCodeReady {
+		err = fmt.Errorf("torrent is not cached")
+	}
+	if err != nil {
+		if added {
+			_ = t.DeleteTorrent(id)
+		}
+		return nil, fmt.Errorf("alldebrid: Failed to get torrent info: %w", err)
+	}
+
+	ret = toDebridTorrentInfo(magnet)
+	if added {
+		ret.ID = &id
+	}
+
+	return ret, nil
+}
+
+func (t *AllDebrid) GetTorrents() (ret []*debrid.TorrentItem, err error) {
+
+	magnets, err := t.getMagnets()
+	if err != nil {
+		return nil, fmt.Errorf("alldebrid: Failed to get torrents: %w", err)
+	}
+
+	for _, m := range magnets {
+		ret = append(ret, toDebridTorrent(m))
+	}
+
+	slices.SortFunc(ret, func(i, j *debrid.TorrentItem) int {
+		return cmp.Compare(j.AddedAt, i.AddedAt)
+	})
+
+	return ret, nil
+}
+
+func (t *AllDebrid) getMagnets() (ret []*Magnet, err error) {
+
+	data, err := t.doQuery(http.MethodGet, "/magnet/status", nil)
+	if err != nil {
+		return nil, fmt.Errorf("alldebrid: Failed to get torrents: %w", err)
+	}
+
+	var d struct {
+		Magnets []*Magnet `json:"magnets"`
+	}
+	err = json.Unmarshal(data, &d)
+	if err != nil {
+		t.logger.Error().Err(err).Msg("alldebrid: Failed to parse torrents")
+		return nil, fmt.Errorf("alldebrid: Failed to parse torrents: %w", err)
+	}
+
+	return d.Magnets, nil
+}
+
+func (t *AllDebrid) DeleteTorrent(id string) error {
+
+	_, err := t.doQuery(http.MethodGet, "/magnet/delete", url.Values{"id": {id}})
+	if err != nil {
+		return fmt.Errorf("alldebrid: Failed to delete torrent: %w", err)
+	}
+
+	return nil
+}
+
+func toDebridTorrent(m *Magnet) (ret *debrid.TorrentItem) {
+
+	completionPercentage := 0
+	if m.StatusCode == StatusCodeReady {
+		completionPercentage = 100
+	} else if m.Size > 0 {
+		completionPercentage = int(m.Downloaded * 100 / m.Size)
+	}
+
+	eta := "-"
+	if m.StatusCode == StatusCodeDownloading && m.DownloadSpeed > 0 {
+		eta = util.FormatETA(int((m.Size - m.Downloaded) / m.DownloadSpeed))
+	}
+
+	ret = &debrid.TorrentItem{
+		ID:                   strconv.Itoa(m.ID),
+		Name:                 m.Filename,
+		Hash:                 strings.ToLower(m.Hash),
+		Size:                 m.Size,
+		FormattedSize:        humanize.Bytes(uint64(m.Size)),
+		CompletionPercentage: completionPercentage,
+		ETA:                  eta,
+		Status:               toDebridTorrentStatus(m.StatusCode),
+		AddedAt:              time.Unix(m.UploadDate, 0).UTC().Format(time.RFC3339),
+		Speed:                util.ToHumanReadableSpeed(int(m.DownloadSpeed)),
+		Seeders:              m.Seeders,
+		IsReady:              m.StatusCode == StatusCodeReady,
+	}
+
+	return
+}
+
+func toDebridTorrentInfo(m *Magnet) (ret *debrid.TorrentInfo) {
+
+	files := make([]*debrid.TorrentItemFile, 0, len(m.Links))
+	for idx, l := range m.Links {
+		// e.g. "/Big Buck Bunny/Big Buck Bunny.mp4", or "/Big Buck Bunny.mp4" for single-file torrents
+		p := fmt.Sprintf("/%s/%s", m.Filename, l.Filename)
+		if l.Filename == m.Filename {
+			p = "/" + l.Filename
+		}
+		files = append(files, &debrid.TorrentItemFile{
+			ID:    strconv.Itoa(idx), // The index of the link is used to retrieve the file
+			Index: idx,
+			Name:  l.Filename, // e.g. "Big Buck Bunny.mp4"
+			Path:  p,
+			Size:  l.Size,
+		})
+	}
+
+	ret = &debrid.TorrentInfo{
+		Name:  m.Filename,
+		Hash:  strings.ToLower(m.Hash),
+		Size:  m.Size,
+		Files: files,
+	}
+
+	return
+}
+
+func toDebridTorrentStatus(statusCode int) debrid.TorrentItemStatus {
+	switch statusCode {
+	case StatusCodeInQueue:
+		return debrid.TorrentItemStatusPaused
+	case StatusCodeDownloading, StatusCodeCompressing, StatusCodeUploading:
+		return debrid.TorrentItemStatusDownloading
+	case StatusCodeReady:
+		return debrid.TorrentItemStatusCompleted
+	default:
+		return debrid.TorrentItemStatusError
+	}
+}
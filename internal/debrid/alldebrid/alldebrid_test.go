@@ -0,0 +1,185 @@
+package alldebrid
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"path"
+	"seanime/internal/debrid/debrid"
+	"seanime/internal/test_utils"
+	"seanime/internal/util"
+	"testing"
+	"time"
+)
+
+// routeFixture returns the recorded response of an AllDebrid API request.
+func routeFixture(r *http.Request) (int, string) {
+	if r.Header.Get("Authorization") != "Bearer token" || r.Form.Get("agent") != "seanime" {
+		return http.StatusOK, "auth_bad_apikey.json"
+	}
+
+	switch r.URL.Path {
+	case "/magnet/status":
+		if id := r.Form.Get("id"); id != "" {
+			return http.StatusOK, "magnet_status_" + id + ".json"
+		}
+		return http.StatusOK, "magnet_status_list.json"
+	case "/magnet/upload":
+		return http.StatusOK, "magnet_upload.json"
+	case "/magnet/delete":
+		return http.StatusOK, "magnet_delete.json"
+	case "/magnet/instant":
+		return http.StatusOK, "magnet_instant.json"
+	case "/link/unlock":
+		return http.StatusOK, "link_unlock_" + path.Base(r.Form.Get("link")) + ".json"
+	default:
+		return http.StatusNotFound, ""
+	}
+}
+
+func newTestAllDebrid(t *testing.T) (*AllDebrid, *test_utils.FixtureServer) {
+	fixtures := test_utils.NewFixtureServer(t, "testdata", routeFixture)
+
+	ad := NewAllDebrid(util.NewLogger()).(*AllDebrid)
+	ad.baseUrl = fixtures.URL
+	ad.pollInterval = 10 * time.Millisecond
+	require.NoError(t, ad.Authenticate("token"))
+
+	return ad, fixtures
+}
+
+func TestAllDebrid_GetTorrents(t *testing.T) {
+	ad, _ := newTestAllDebrid(t)
+
+	torrents, err := ad.GetTorrents()
+	require.NoError(t, err)
+	require.Len(t, torrents, 2)
+
+	// Sorted by date added, most recent first
+	assert.Equal(t, "102", torrents[0].ID)
+	assert.Equal(t, debrid.TorrentItemStatusDownloading, torrents[0].Status)
+	assert.Equal(t, 50, torrents[0].CompletionPercentage)
+	assert.Equal(t, "2024-11-03T08:00:00Z", torrents[0].AddedAt)
+	assert.False(t, torrents[0].IsReady)
+
+	assert.Equal(t, "101", torrents[1].ID)
+	assert.Equal(t, debrid.TorrentItemStatusCompleted, torrents[1].Status)
+	assert.True(t, torrents[1].IsReady)
+
+	// Wrong API key
+	require.NoError(t, ad.Authenticate("wrong"))
+	_, err = ad.GetTorrents()
+	assert.ErrorIs(t, err, debrid.ErrNotAuthenticated)
+}
+
+func TestAllDebrid_AddTorrent(t *testing.T) {
+	ad, fixtures := newTestAllDebrid(t)
+
+	// Already added
+	id, err := ad.AddTorrent(debrid.AddTorrentOptions{
+		MagnetLink: "magnet:?xt=urn:btih:6a9759bffd5c0af65319979fb7832189f4f3c35d",
+		InfoHash:   "6A9759BFFD5C0AF65319979FB7832189F4F3C35D",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "101", id)
+	assert.False(t, fixtures.Called("", "/magnet/upload"))
+
+	id, err = ad.AddTorrent(debrid.AddTorrentOptions{
+		MagnetLink: "magnet:?xt=urn:btih:fedcba9876543210fedcba9876543210fedcba98",
+		InfoHash:   "fedcba9876543210fedcba9876543210fedcba98",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "103", id)
+}
+
+func TestAllDebrid_GetTorrentInfo(t *testing.T) {
+	ad, fixtures := newTestAllDebrid(t)
+
+	info, err := ad.GetTorrentInfo(debrid.GetTorrentInfoOptions{
+		MagnetLink: "magnet:?xt=urn:btih:fedcba9876543210fedcba9876543210fedcba98",
+		InfoHash:   "fedcba9876543210fedcba9876543210fedcba98",
+	})
+	require.NoError(t, err)
+
+	// The torrent was added to retrieve its files
+	require.NotNil(t, info.ID)
+	assert.Equal(t, "103", *info.ID)
+	require.Len(t, info.Files, 1)
+	assert.Equal(t, "0", info.Files[0].ID)
+	assert.Equal(t, "/[SubsPlease] Dungeon Meshi - 01 (1080p) [9E0C2C5B].mkv", info.Files[0].Path)
+
+	// Already added
+	info, err = ad.GetTorrentInfo(debrid.GetTorrentInfoOptions{
+		MagnetLink: "magnet:?xt=urn:btih:6a9759bffd5c0af65319979fb7832189f4f3c35d",
+		InfoHash:   "6a9759bffd5c0af65319979fb7832189f4f3c35d",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, info.ID)
+	require.Len(t, info.Files, 2)
+	assert.Equal(t, "/[SubsPlease] Sousou no Frieren (01-28) (1080p) [Batch]/[SubsPlease] Sousou no Frieren - 02 (1080p) [E5A85899].mkv", info.Files[1].Path)
+
+	// Not ready, the torrent was already in the account so it is kept
+	_, err = ad.GetTorrentInfo(debrid.GetTorrentInfoOptions{
+		MagnetLink: "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567",
+		InfoHash:   "0123456789abcdef0123456789abcdef01234567",
+	})
+	assert.Error(t, err)
+	assert.False(t, fixtures.Called("", "/magnet/delete", "id", "102"))
+}
+
+func TestAllDebrid_GetTorrentDownloadUrls(t *testing.T) {
+	ad, _ := newTestAllDebrid(t)
+
+	downloadUrls, err := ad.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{ID: "101", FileId: "1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://debrid.alldebrid.com/dl/UNLOCKED2/episode.mkv"}, downloadUrls)
+
+	_, err = ad.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{ID: "101", FileId: "2"})
+	assert.Error(t, err)
+
+	// All files
+	downloadUrls, err = ad.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{ID: "101"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"https://debrid.alldebrid.com/dl/UNLOCKED1/episode.mkv",
+		"https://debrid.alldebrid.com/dl/UNLOCKED2/episode.mkv",
+	}, downloadUrls)
+
+	// Not ready
+	_, err = ad.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{ID: "102"})
+	assert.Error(t, err)
+}
+
+func TestAllDebrid_GetTorrentStreamUrl(t *testing.T) {
+	ad, _ := newTestAllDebrid(t)
+
+	itemCh := make(chan debrid.TorrentItem, 10)
+	streamUrl, err := ad.GetTorrentStreamUrl(context.Background(), debrid.StreamTorrentOptions{ID: "101", FileId: "0"}, itemCh)
+	require.NoError(t, err)
+	assert.Equal(t, "https://debrid.alldebrid.com/dl/UNLOCKED1/episode.mkv", streamUrl)
+
+	item := <-itemCh
+	assert.Equal(t, 100, item.CompletionPercentage)
+
+	// Cancelled while waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ad.GetTorrentStreamUrl(ctx, debrid.StreamTorrentOptions{ID: "102", FileId: "0"}, itemCh)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestAllDebrid_GetInstantAvailability(t *testing.T) {
+	ad, _ := newTestAllDebrid(t)
+
+	availability := ad.GetInstantAvailability([]string{
+		"6a9759bffd5c0af65319979fb7832189f4f3c35d",
+		"0123456789abcdef0123456789abcdef01234567",
+	})
+	require.Len(t, availability, 1)
+
+	cached, ok := availability["6a9759bffd5c0af65319979fb7832189f4f3c35d"]
+	require.True(t, ok)
+	require.Len(t, cached.CachedFiles, 2)
+	assert.Equal(t, "[SubsPlease] Sousou no Frieren - 02 (1080p) [E5A85899].mkv", cached.CachedFiles["1"].Name)
+}
@@ -8,7 +8,9 @@ import (
 	"io"
 	"mime"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -94,7 +96,8 @@ func (r *Repository) downloadTorrentItem(tId string, torrentName string, destina
 	}
 
 	// Get the download URL
-	downloadUrl, err := provider.GetTorrentDownloadUrl(debrid.DownloadTorrentOptions{
+	// Providers that cannot bundle the files of a torrent return one URL per file
+	downloadUrls, err := provider.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{
 		ID: tId,
 	})
 	if err != nil {
@@ -110,13 +113,6 @@ func (r *Repository) downloadTorrentItem(tId string, torrentName string, destina
 			r.ctxMap.Delete(tId)
 		}()
 
-		// Create a cancellable HTTP request
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadUrl, nil)
-		if err != nil {
-			r.logger.Err(err).Msg("debrid: Failed to create request")
-			return
-		}
-
 		// Download the files to a temporary folder
 		tmpDirPath, err := os.MkdirTemp("", "torrent-")
 		if err != nil {
@@ -126,180 +122,186 @@ func (r *Repository) downloadTorrentItem(tId string, torrentName string, destina
 		}
 		defer os.RemoveAll(tmpDirPath) // Clean up temp folder on exit
 
-		// Execute the request
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			r.logger.Err(err).Msg("debrid: Failed to execute request")
-			r.wsEventManager.SendEvent(events.ErrorToast, fmt.Sprintf("debrid: Failed to execute download request: %v", err))
-			return
-		}
-		defer resp.Body.Close()
-
-		// e.g. "my-torrent.zip", "downloaded_torrent"
-		filename := "downloaded_torrent"
-		ext := ""
-
-		// Try to get the file name from the Content-Disposition header
-		hFilename, err := getFilenameFromHeaders(downloadUrl)
-		if err == nil {
-			filename = hFilename
-		}
-
-		if ct := resp.Header.Get("Content-Type"); ct != "" {
-			mediaType, _, err := mime.ParseMediaType(ct)
-			if err == nil {
-				switch mediaType {
-				case "application/zip":
-					ext = ".zip"
-				case "application/x-rar-compressed":
-					ext = ".rar"
-				default:
+		for _, u := range downloadUrls {
+			err = r.downloadFile(ctx, tId, u, tmpDirPath, destination)
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					r.logger.Debug().Msg("debrid: Download cancelled")
+				} else {
+					r.logger.Err(err).Msg("debrid: Download failed")
+					r.wsEventManager.SendEvent(events.ErrorToast, fmt.Sprintf("debrid: Download failed / %v", err))
 				}
+				r.sendDownloadCancelledEvent(tId)
+				return
 			}
 		}
 
-		if filename == "downloaded_torrent" && ext != "" {
-			filename = fmt.Sprintf("%s%s", filename, ext)
-		}
+		r.sendDownloadCompletedEvent(tId)
+		notifier.GlobalNotifier.Notify(notifier.Debrid, fmt.Sprintf("Downloaded %q", torrentName))
+	}(ctx)
 
-		r.logger.Debug().Str("filename", filename).Str("ext", ext).Msg("debrid: Downloading torrent")
+	// Send a starting event
+	r.wsEventManager.SendEvent(events.DebridDownloadProgress, map[string]interface{}{
+		"status":     "downloading",
+		"itemID":     tId,
+		"totalBytes": "0 B",
+		"totalSize":  "-",
+		"speed":      "",
+	})
 
-		// Create a file in the temporary folder to store the download
-		// e.g. "/tmp/torrent-123456789/my-torrent.zip"
-		tmpDownloadedFilePath := filepath.Join(tmpDirPath, filename)
-		file, err := os.Create(tmpDownloadedFilePath)
-		if err != nil {
-			r.logger.Err(err).Msg("debrid: Failed to create temp file")
-			r.wsEventManager.SendEvent(events.ErrorToast, fmt.Sprintf("debrid: Failed to create temp file: %v", err))
-			return
-		}
+	return nil
+}
 
-		totalSize := resp.ContentLength
-		speed := 0
+// downloadFile downloads the file to the temporary folder, then extracts or moves it to the destination.
+func (r *Repository) downloadFile(ctx context.Context, tId string, downloadUrl string, tmpDirPath string, destination string) error {
 
-		lastSent := time.Now()
+	// Create a cancellable HTTP request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadUrl, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
 
-		// Copy response body to the temporary file
-		buffer := make([]byte, 32*1024)
-		var totalBytes int64
-		var lastBytes int64
-		for {
-			n, err := resp.Body.Read(buffer)
-			if n > 0 {
-				_, writeErr := file.Write(buffer[:n])
-				if writeErr != nil {
-					_ = file.Close()
-					r.logger.Err(writeErr).Msg("debrid: Failed to write to temp file")
-					r.wsEventManager.SendEvent(events.ErrorToast, fmt.Sprintf("debrid: Download failed / Failed to write to temp file: %v", writeErr))
-					r.sendDownloadCancelledEvent(tId)
-					return
-				}
-				totalBytes += int64(n)
-				if totalSize > 0 {
-					speed = int((totalBytes - lastBytes) / 1024) // KB/s
-					lastBytes = totalBytes
-				}
+	// Execute the request
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute download request: %w", err)
+	}
+	defer resp.Body.Close()
 
-				if time.Since(lastSent) > time.Second*2 {
-					// Notify progress
-					r.wsEventManager.SendEvent(events.DebridDownloadProgress, map[string]interface{}{
-						"status":     "downloading",
-						"itemID":     tId,
-						"totalBytes": humanize.Bytes(uint64(totalBytes)),
-						"totalSize":  humanize.Bytes(uint64(totalSize)),
-						"speed":      speed,
-					})
-					lastSent = time.Now()
-				}
-			}
-			if err != nil {
-				if err == io.EOF {
-					break
-				}
-				if errors.Is(err, context.Canceled) {
-					_ = file.Close()
-					r.logger.Debug().Msg("debrid: Download cancelled")
-					r.sendDownloadCancelledEvent(tId)
-					return
-				}
-				_ = file.Close()
-				r.logger.Err(err).Msg("debrid: Failed to read from response body")
-				r.wsEventManager.SendEvent(events.ErrorToast, fmt.Sprintf("debrid: Download failed / Failed to read from response body: %v", err))
-				r.sendDownloadCancelledEvent(tId)
-				return
+	// e.g. "my-torrent.zip", "downloaded_torrent"
+	filename := "downloaded_torrent"
+	ext := ""
+
+	// Try to get the file name from the Content-Disposition header, or from the URL
+	hFilename, err := getFilenameFromHeaders(downloadUrl)
+	if err == nil {
+		filename = hFilename
+	} else if uFilename := getFilenameFromUrl(downloadUrl); uFilename != "" {
+		filename = uFilename
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err == nil {
+			switch mediaType {
+			case "application/zip":
+				ext = ".zip"
+			case "application/x-rar-compressed":
+				ext = ".rar"
+			default:
 			}
 		}
+	}
 
-		_ = file.Close()
+	if filename == "downloaded_torrent" && ext != "" {
+		filename = fmt.Sprintf("%s%s", filename, ext)
+	}
 
-		r.wsEventManager.SendEvent(events.DebridDownloadProgress, map[string]interface{}{
-			"status":     "downloading",
-			"itemID":     tId,
-			"totalBytes": "Extracting...",
-			"totalSize":  humanize.Bytes(uint64(totalSize)),
-			"speed":      "",
-		})
+	r.logger.Debug().Str("filename", filename).Str("ext", ext).Msg("debrid: Downloading torrent")
 
-		switch runtime.GOOS {
-		case "windows":
-			time.Sleep(time.Second * 1)
-		}
+	// Create a file in the temporary folder to store the download
+	// e.g. "/tmp/torrent-123456789/my-torrent.zip"
+	tmpDownloadedFilePath := filepath.Join(tmpDirPath, filename)
+	file, err := os.Create(tmpDownloadedFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
 
-		// Extract the downloaded file
-		var extractedDir string
-		switch ext {
-		case ".zip":
-			extractedDir, err = unzipFile(tmpDownloadedFilePath, tmpDirPath)
-		case ".rar":
-			extractedDir, err = unrarFile(tmpDownloadedFilePath, tmpDirPath)
-		default:
-			// Move the file directly to the destination
-			err = moveFolderOrFileTo(tmpDownloadedFilePath, destination)
-			if err != nil {
-				r.logger.Err(err).Msg("debrid: Failed to move downloaded file")
-				r.wsEventManager.SendEvent(events.ErrorToast, fmt.Sprintf("debrid: Failed to move downloaded file: %v", err))
-				r.sendDownloadCancelledEvent(tId)
-				return
+	totalSize := resp.ContentLength
+	speed := 0
+
+	lastSent := time.Now()
+
+	// Copy response body to the temporary file
+	buffer := make([]byte, 32*1024)
+	var totalBytes int64
+	var lastBytes int64
+	for {
+		n, err := resp.Body.Read(buffer)
+		if n > 0 {
+			_, writeErr := file.Write(buffer[:n])
+			if writeErr != nil {
+				_ = file.Close()
+				return fmt.Errorf("failed to write to temp file: %w", writeErr)
+			}
+			totalBytes += int64(n)
+			if totalSize > 0 {
+				speed = int((totalBytes - lastBytes) / 1024) // KB/s
+				lastBytes = totalBytes
 			}
-			return // Exit early
-		}
-		if err != nil {
-			r.logger.Err(err).Msg("debrid: Failed to extract downloaded file")
-			r.wsEventManager.SendEvent(events.ErrorToast, fmt.Sprintf("debrid: Failed to extract downloaded file: %v", err))
-			r.sendDownloadCancelledEvent(tId)
-			return
-		}
 
-		// Delete the downloaded file
-		err = os.Remove(tmpDownloadedFilePath)
-		if err != nil {
-			r.logger.Err(err).Msg("debrid: Failed to delete downloaded file")
-			// Do not stop here, continue with the extracted files
+			if time.Since(lastSent) > time.Second*2 {
+				// Notify progress
+				r.wsEventManager.SendEvent(events.DebridDownloadProgress, map[string]interface{}{
+					"status":     "downloading",
+					"itemID":     tId,
+					"totalBytes": humanize.Bytes(uint64(totalBytes)),
+					"totalSize":  humanize.Bytes(uint64(totalSize)),
+					"speed":      speed,
+				})
+				lastSent = time.Now()
+			}
 		}
-
-		// Move the extracted files to the destination
-		err = moveContentsTo(extractedDir, destination)
 		if err != nil {
-			r.logger.Err(err).Msg("debrid: Failed to move downloaded files")
-			r.wsEventManager.SendEvent(events.ErrorToast, fmt.Sprintf("debrid: Failed to move downloaded files: %v", err))
-			r.sendDownloadCancelledEvent(tId)
-			return
+			if err == io.EOF {
+				break
+			}
+			_ = file.Close()
+			if errors.Is(err, context.Canceled) {
+				return err
+			}
+			return fmt.Errorf("failed to read from response body: %w", err)
 		}
+	}
 
-		r.sendDownloadCompletedEvent(tId)
-		notifier.GlobalNotifier.Notify(notifier.Debrid, fmt.Sprintf("Downloaded %q", torrentName))
-	}(ctx)
+	_ = file.Close()
 
-	// Send a starting event
 	r.wsEventManager.SendEvent(events.DebridDownloadProgress, map[string]interface{}{
 		"status":     "downloading",
 		"itemID":     tId,
-		"totalBytes": "0 B",
-		"totalSize":  "-",
+		"totalBytes": "Extracting...",
+		"totalSize":  humanize.Bytes(uint64(totalSize)),
 		"speed":      "",
 	})
 
+	switch runtime.GOOS {
+	case "windows":
+		time.Sleep(time.Second * 1)
+	}
+
+	// Extract the downloaded file
+	var extractedDir string
+	switch ext {
+	case ".zip":
+		extractedDir, err = unzipFile(tmpDownloadedFilePath, tmpDirPath)
+	case ".rar":
+		extractedDir, err = unrarFile(tmpDownloadedFilePath, tmpDirPath)
+	default:
+		// Move the file directly to the destination
+		err = moveFolderOrFileTo(tmpDownloadedFilePath, destination)
+		if err != nil {
+			return fmt.Errorf("failed to move downloaded file: %w", err)
+		}
+		return nil // Exit early
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract downloaded file: %w", err)
+	}
+
+	// Delete the downloaded file
+	err = os.Remove(tmpDownloadedFilePath)
+	if err != nil {
+		r.logger.Err(err).Msg("debrid: Failed to delete downloaded file")
+		// Do not stop here, continue with the extracted files
+	}
+
+	// Move the extracted files to the destination
+	err = moveContentsTo(extractedDir, destination)
+	if err != nil {
+		return fmt.Errorf("failed to move downloaded files: %w", err)
+	}
+
 	return nil
 }
 
@@ -338,3 +340,16 @@ func getFilenameFromHeaders(url string) (string, error) {
 	}
 	return "", fmt.Errorf("filename not found in Content-Disposition header")
 }
+
+// getFilenameFromUrl returns the last segment of the URL's path if it looks like a file name.
+func getFilenameFromUrl(downloadUrl string) string {
+	u, err := url.Parse(downloadUrl)
+	if err != nil {
+		return ""
+	}
+	filename := path.Base(u.Path)
+	if path.Ext(filename) == "" {
+		return ""
+	}
+	return filename
+}
@@ -10,7 +10,10 @@ import (
 	"seanime/internal/api/metadata"
 	"seanime/internal/database/db"
 	"seanime/internal/database/models"
+	"seanime/internal/debrid/alldebrid"
 	"seanime/internal/debrid/debrid"
+	"seanime/internal/debrid/premiumize"
+	"seanime/internal/debrid/realdebrid"
 	"seanime/internal/debrid/torbox"
 	"seanime/internal/events"
 	"seanime/internal/library/playbackmanager"
@@ -105,6 +108,12 @@ func (r *Repository) InitializeProvider(settings *models.DebridSettings) error {
 	switch settings.Provider {
 	case "torbox":
 		r.provider = mo.Some(torbox.NewTorBox(r.logger))
+	case "realdebrid":
+		r.provider = mo.Some(realdebrid.NewRealDebrid(r.logger))
+	case "alldebrid":
+		r.provider = mo.Some(alldebrid.NewAllDebrid(r.logger))
+	case "premiumize":
+		r.provider = mo.Some(premiumize.NewPremiumize(r.logger))
 	default:
 		r.provider = mo.None[debrid.Provider]()
 	}
@@ -137,10 +137,11 @@ func (s *StreamManager) startStream(opts *StartStreamOptions) (err error) {
 
 	// Add the torrent to the debrid service
 	// For Torbox, this will automatically start downloading the torrent
-	// For Real Debrid, this will just add the torrent to the user's account
+	// For Real Debrid, this will add the torrent and only select the file to stream
 	torrentItemId, err := provider.AddTorrent(debrid.AddTorrentOptions{
-		MagnetLink: selectedTorrent.MagnetLink,
-		InfoHash:   selectedTorrent.InfoHash,
+		MagnetLink:   selectedTorrent.MagnetLink,
+		InfoHash:     selectedTorrent.InfoHash,
+		SelectFileId: fileId,
 	})
 	if err != nil {
 		s.repository.wsEventManager.SendEvent(events.DebridStreamState, StreamState{
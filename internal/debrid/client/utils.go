@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // Unzips a file to the destination
@@ -125,6 +126,18 @@ func unrarFile(src, dest string) (string, error) {
 	return extractedDir, nil
 }
 
+// ExtractArchive extracts a zip or rar archive to a new folder in the destination and returns that folder.
+func ExtractArchive(src, dest string) (string, error) {
+	switch strings.ToLower(filepath.Ext(src)) {
+	case ".zip":
+		return unzipFile(src, dest)
+	case ".rar":
+		return unrarFile(src, dest)
+	default:
+		return "", fmt.Errorf("unsupported archive: %s", filepath.Base(src))
+	}
+}
+
 // Moves a folder or file to the destination
 //
 //	Example:
@@ -198,3 +198,18 @@ func TestMoveContentsTo(t *testing.T) {
 		})
 	}
 }
+
+func TestGetFilenameFromUrl(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://download.real-debrid.com/d/ABC/%5BSubsPlease%5D%20Frieren%20-%2001.mkv", "[SubsPlease] Frieren - 01.mkv"},
+		{"https://cdn.premiumize.me/zip/FOLDER1.zip?token=abc", "FOLDER1.zip"},
+		{"https://api.torbox.app/v1/api/torrents/requestdl", ""},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, getFilenameFromUrl(tt.url))
+	}
+}
@@ -18,8 +18,9 @@ type (
 		AddTorrent(opts AddTorrentOptions) (string, error)
 		// GetTorrentStreamUrl returns the stream URL for the torrent file. It should block until the stream URL is available.
 		GetTorrentStreamUrl(ctx context.Context, opts StreamTorrentOptions, itemCh chan TorrentItem) (streamUrl string, err error)
-		// GetTorrentDownloadUrl returns the download URL for the torrent. It should return an error if the torrent is not ready.
-		GetTorrentDownloadUrl(opts DownloadTorrentOptions) (downloadUrl string, err error)
+		// GetTorrentDownloadUrls returns the download URLs for the torrent. It should return an error if the torrent is not ready.
+		// Providers that cannot bundle the files of a torrent into a single archive return one URL per file.
+		GetTorrentDownloadUrls(opts DownloadTorrentOptions) (downloadUrls []string, err error)
 		// GetInstantAvailability returns a map where the key is the torrent's info hash
 		GetInstantAvailability(hashes []string) map[string]TorrentItemInstantAvailability
 		GetTorrent(id string) (*TorrentItem, error)
@@ -29,8 +30,9 @@ type (
 	}
 
 	AddTorrentOptions struct {
-		MagnetLink string `json:"magnetLink"`
-		InfoHash   string `json:"infoHash"`
+		MagnetLink   string `json:"magnetLink"`
+		InfoHash     string `json:"infoHash"`
+		SelectFileId string `json:"selectFileId"` // ID of the only file to download, all files are downloaded if empty (ignored by providers that do not support file selection)
 	}
 
 	StreamTorrentOptions struct {
@@ -0,0 +1,485 @@
+package premiumize
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog"
+	"github.com/samber/mo"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"seanime/internal/debrid/debrid"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	Premiumize struct {
+		baseUrl      string
+		apiKey       mo.Option[string]
+		client       *http.Client
+		logger       *zerolog.Logger
+		pollInterval time.Duration
+	}
+
+	Response struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+
+	Transfer struct {
+		ID       string  `json:"id"`
+		Name     string  `json:"name"`
+		Message  string  `json:"message"`
+		Status   string  `json:"status"`
+		Progress float64 `json:"progress"` // 0 to 1
+		Src      string  `json:"src"`      // Magnet link of the transfer
+		FolderID string  `json:"folder_id"`
+		FileID   string  `json:"file_id"`
+	}
+
+	// Content is a file of a cached torrent, returned by the direct download endpoint.
+	Content struct {
+		Path       string `json:"path"` // e.g. "Big Buck Bunny/Big Buck Bunny.mp4"
+		Size       int64  `json:"size"`
+		Link       string `json:"link"`
+		StreamLink string `json:"stream_link"`
+	}
+)
+
+const (
+	StatusWaiting  = "waiting"
+	StatusQueued   = "queued"
+	StatusRunning  = "running"
+	StatusFinished = "finished"
+	StatusSeeding  = "seeding"
+	StatusDeleted  = "deleted"
+	StatusBanned   = "banned"
+	StatusError    = "error"
+	StatusTimeout  = "timeout"
+)
+
+var infoHashRegex = regexp.MustCompile(`(?i)urn:btih:([a-z0-9]+)`)
+
+func NewPremiumize(logger *zerolog.Logger) debrid.Provider {
+	return &Premiumize{
+		baseUrl:      "https://www.premiumize.me/api",
+		apiKey:       mo.None[string](),
+		client:       &http.Client{},
+		logger:       logger,
+		pollInterval: 4 * time.Second,
+	}
+}
+
+func (t *Premiumize) GetSettings() debrid.Settings {
+	return debrid.Settings{
+		ID:   "premiumize",
+		Name: "Premiumize",
+	}
+}
+
+// doQuery sends the request and decodes the response into ret.
+func (t *Premiumize) doQuery(method, endpoint string, params url.Values, ret interface{}) error {
+	apiKey, found := t.apiKey.Get()
+	if !found {
+		return debrid.ErrNotAuthenticated
+	}
+
+	if params == nil {
+		params = url.Values{}
+	}
+
+	var req *http.Request
+	var err error
+	if method == http.MethodPost {
+		req, err = http.NewRequest(method, t.baseUrl+endpoint+"?apikey="+url.QueryEscape(apiKey), strings.NewReader(params.Encode()))
+		if err == nil {
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		params.Set("apikey", apiKey)
+		req, err = http.NewRequest(method, t.baseUrl+endpoint+"?"+params.Encode(), nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return debrid.ErrNotAuthenticated
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		t.logger.Error().Err(err).Msg("premiumize: Failed to decode response")
+		return err
+	}
+
+	var r Response
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return err
+	}
+
+	if r.Status != "success" {
+		if strings.Contains(strings.ToLower(r.Message), "not logged in") {
+			return fmt.Errorf("%w: %s", debrid.ErrNotAuthenticated, r.Message)
+		}
+		return fmt.Errorf("request failed: %s", r.Message)
+	}
+
+	if ret != nil {
+		return json.Unmarshal(raw, ret)
+	}
+
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (t *Premiumize) Authenticate(apiKey string) error {
+	t.apiKey = mo.Some(apiKey)
+	return nil
+}
+
+// GetInstantAvailability checks the cache of Premiumize.
+// Premiumize only reports whether the torrent is cached, so the files are not listed.
+func (t *Premiumize) GetInstantAvailability(hashes []string) map[string]debrid.TorrentItemInstantAvailability {
+
+	t.logger.Trace().Strs("hashes", hashes).Msg("premiumize: Checking instant availability")
+
+	availability := make(map[string]debrid.TorrentItemInstantAvailability)
+
+	for i := 0; i < len(hashes); i += 100 {
+		end := min(i+100, len(hashes))
+		batch := hashes[i:end]
+
+		var d struct {
+			Response []bool `json:"response"`
+		}
+		err := t.doQuery(http.MethodGet, "/cache/check", url.Values{"items[]": batch}, &d)
+		if err != nil {
+			t.logger.Debug().Err(err).Msg("premiumize: Instant availability is unavailable")
+			return availability
+		}
+
+		for idx, cached := range d.Response {
+			if !cached || idx >= len(batch) {
+				continue
+			}
+			availability[strings.ToLower(batch[idx])] = debrid.TorrentItemInstantAvailability{
+				CachedFiles: make(map[string]*debrid.CachedFile),
+			}
+		}
+	}
+
+	return availability
+}
+
+// AddTorrent creates a transfer for the magnet link. Premiumize always downloads every file of the torrent.
+func (t *Premiumize) AddTorrent(opts debrid.AddTorrentOptions) (string, error) {
+
+	// Check if the torrent is already added
+	if opts.InfoHash != "" {
+		transfers, err := t.getTransfers()
+		if err == nil {
+			for _, transfer := range transfers {
+				if strings.EqualFold(getInfoHash(transfer.Src), opts.InfoHash) {
+					return transfer.ID, nil
+				}
+			}
+		}
+	}
+
+	t.logger.Trace().Str("magnetLink", opts.MagnetLink).Msg("premiumize: Adding torrent")
+
+	var d struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	err := t.doQuery(http.MethodPost, "/transfer/create", url.Values{"src": {opts.MagnetLink}}, &d)
+	if err != nil {
+		return "", fmt.Errorf("premiumize: Failed to add torrent: %w", err)
+	}
+
+	t.logger.Debug().Str("torrentId", d.ID).Str("torrentName", d.Name).Msg("premiumize: Torrent added")
+
+	return d.ID, nil
+}
+
+// GetTorrentStreamUrl blocks until the transfer is finished and returns the direct link of the file.
+func (t *Premiumize) GetTorrentStreamUrl(ctx context.Context, opts debrid.StreamTorrentOptions, itemCh chan debrid.TorrentItem) (streamUrl string, err error) {
+
+	t.logger.Trace().Str("torrentId", opts.ID).Str("fileId", opts.FileId).Msg("premiumize: Retrieving stream link")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(t.pollInterval):
+			transfer, _err := t.getTransfer(opts.ID)
+			if _err != nil {
+				t.logger.Error().Err(_err).Msg("premiumize: Failed to get torrent")
+				return "", fmt.Errorf("premiumize: Failed to get torrent: %w", _err)
+			}
+
+			itemCh <- *toDebridTorrent(transfer)
+
+			switch transfer.Status {
+			case StatusError, StatusBanned, StatusTimeout, StatusDeleted:
+				return "", fmt.Errorf("premiumize: Torrent failed: %s", transfer.Message)
+			case StatusFinished, StatusSeeding:
+				downloadUrl, _err := t.getFileDownloadUrl(transfer, opts.FileId)
+				if _err != nil {
+					t.logger.Error().Err(_err).Msg("premiumize: Failed to get download URL")
+					return "", fmt.Errorf("premiumize: Failed to get download URL: %w", _err)
+				}
+				return downloadUrl, nil
+			}
+		}
+	}
+}
+
+// GetTorrentDownloadUrls returns the direct link of the file.
+// When downloading a torrent with multiple files, the link of a zip archive of the transfer's folder is returned.
+func (t *Premiumize) GetTorrentDownloadUrls(opts debrid.DownloadTorrentOptions) (downloadUrls []string, err error) {
+
+	t.logger.Trace().Str("torrentId", opts.ID).Msg("premiumize: Retrieving download link")
+
+	transfer, err := t.getTransfer(opts.ID)
+	if err != nil {
+		return nil, fmt.Errorf("premiumize: Failed to get download URL: %w", err)
+	}
+
+	if transfer.Status != StatusFinished && transfer.Status != StatusSeeding {
+		return nil, fmt.Errorf("premiumize: Failed to get download URL, torrent is not ready")
+	}
+
+	if opts.FileId == "" && transfer.FolderID != "" {
+		var d struct {
+			Location string `json:"location"`
+		}
+		err = t.doQuery(http.MethodPost, "/zip/generate", url.Values{"folders[]": {transfer.FolderID}}, &d)
+		if err != nil {
+			return nil, fmt.Errorf("premiumize: Failed to get download URL: %w", err)
+		}
+
+		t.logger.Debug().Str("downloadUrl", d.Location).Msg("premiumize: Download link retrieved")
+
+		return []string{d.Location}, nil
+	}
+
+	downloadUrl, err := t.getFileDownloadUrl(transfer, opts.FileId)
+	if err != nil {
+		return nil, fmt.Errorf("premiumize: Failed to get download URL: %w", err)
+	}
+
+	return []string{downloadUrl}, nil
+}
+
+// getFileDownloadUrl returns the direct link of a file, the file ID is the index of the file in the direct download content.
+func (t *Premiumize) getFileDownloadUrl(transfer *Transfer, fileId string) (string, error) {
+	content, err := t.getDirectDownloadContent(transfer.Src)
+	if err != nil {
+		return "", err
+	}
+
+	if len(content) == 0 {
+		return "", fmt.Errorf("no files found")
+	}
+
+	idx := 0
+	if fileId != "" {
+		idx, err = strconv.Atoi(fileId)
+		if err != nil || idx < 0 || idx >= len(content) {
+			return "", fmt.Errorf("file not found")
+		}
+	}
+
+	t.logger.Debug().Str("downloadUrl", content[idx].Link).Msg("premiumize: Download link retrieved")
+
+	return content[idx].Link, nil
+}
+
+// getDirectDownloadContent returns the files of a cached torrent.
+func (t *Premiumize) getDirectDownloadContent(magnetLink string) ([]*Content, error) {
+	var d struct {
+		Content []*Content `json:"content"`
+	}
+	err := t.doQuery(http.MethodPost, "/transfer/directdl", url.Values{"src": {magnetLink}}, &d)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Content, nil
+}
+
+func (t *Premiumize) GetTorrent(id string) (ret *debrid.TorrentItem, err error) {
+	transfer, err := t.getTransfer(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ret = toDebridTorrent(transfer)
+
+	return ret, nil
+}
+
+// getTransfer returns the transfer from the list of transfers, Premiumize has no endpoint for a single transfer.
+func (t *Premiumize) getTransfer(id string) (*Transfer, error) {
+	transfers, err := t.getTransfers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, transfer := range transfers {
+		if transfer.ID == id {
+			return transfer, nil
+		}
+	}
+
+	return nil, fmt.Errorf("premiumize: Torrent not found")
+}
+
+// GetTorrentInfo returns the files of a cached torrent without adding it to the user's account.
+func (t *Premiumize) GetTorrentInfo(opts debrid.GetTorrentInfoOptions) (ret *debrid.TorrentInfo, err error) {
+
+	magnetLink := opts.MagnetLink
+	if magnetLink == "" && opts.InfoHash != "" {
+		magnetLink = "magnet:?xt=urn:btih:" + opts.InfoHash
+	}
+
+	if magnetLink == "" {
+		return nil, fmt.Errorf("premiumize: Magnet link is required to retrieve torrent info")
+	}
+
+	content, err := t.getDirectDownloadContent(magnetLink)
+	if err != nil {
+		return nil, fmt.Errorf("premiumize: Failed to get torrent info: %w", err)
+	}
+
+	if len(content) == 0 {
+		return nil, fmt.Errorf("premiumize: Failed to get torrent info, torrent is not cached")
+	}
+
+	ret = toDebridTorrentInfo(content)
+	ret.Hash = strings.ToLower(getInfoHash(magnetLink))
+
+	return ret, nil
+}
+
+func (t *Premiumize) GetTorrents() (ret []*debrid.TorrentItem, err error) {
+
+	transfers, err := t.getTransfers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range transfers {
+		ret = append(ret, toDebridTorrent(t))
+	}
+
+	return ret, nil
+}
+
+func (t *Premiumize) getTransfers() (ret []*Transfer, err error) {
+
+	var d struct {
+		Transfers []*Transfer `json:"transfers"`
+	}
+	err = t.doQuery(http.MethodGet, "/transfer/list", nil, &d)
+	if err != nil {
+		return nil, fmt.Errorf("premiumize: Failed to get torrents: %w", err)
+	}
+
+	return d.Transfers, nil
+}
+
+func (t *Premiumize) DeleteTorrent(id string) error {
+
+	err := t.doQuery(http.MethodPost, "/transfer/delete", url.Values{"id": {id}}, nil)
+	if err != nil {
+		return fmt.Errorf("premiumize: Failed to delete torrent: %w", err)
+	}
+
+	return nil
+}
+
+func getInfoHash(magnetLink string) string {
+	matches := infoHashRegex.FindStringSubmatch(magnetLink)
+	if len(matches) < 2 {
+		return ""
+	}
+	return strings.ToLower(matches[1])
+}
+
+func toDebridTorrent(t *Transfer) (ret *debrid.TorrentItem) {
+
+	completionPercentage := int(t.Progress * 100)
+	if t.Status == StatusFinished || t.Status == StatusSeeding {
+		completionPercentage = 100
+	}
+
+	ret = &debrid.TorrentItem{
+		ID:                   t.ID,
+		Name:                 t.Name,
+		Hash:                 getInfoHash(t.Src),
+		FormattedSize:        "-", // Premiumize does not return the size of transfers
+		CompletionPercentage: completionPercentage,
+		ETA:                  "-",
+		Status:               toDebridTorrentStatus(t.Status),
+		IsReady:              t.Status == StatusFinished || t.Status == StatusSeeding,
+	}
+
+	return
+}
+
+func toDebridTorrentInfo(content []*Content) (ret *debrid.TorrentInfo) {
+
+	ret = &debrid.TorrentInfo{
+		Files: make([]*debrid.TorrentItemFile, 0, len(content)),
+	}
+
+	for idx, c := range content {
+		ret.Files = append(ret.Files, &debrid.TorrentItemFile{
+			ID:    strconv.Itoa(idx), // The index of the file is used to retrieve its link
+			Index: idx,
+			Name:  path.Base(c.Path), // e.g. "Big Buck Bunny.mp4"
+			Path:  "/" + c.Path,      // e.g. "/Big Buck Bunny/Big Buck Bunny.mp4"
+			Size:  c.Size,
+		})
+		ret.Size += c.Size
+	}
+
+	// The name of the torrent is the root folder, or the file for single-file torrents
+	if len(content) > 0 {
+		ret.Name = strings.Split(content[0].Path, "/")[0]
+	}
+
+	return
+}
+
+func toDebridTorrentStatus(status string) debrid.TorrentItemStatus {
+	switch status {
+	case StatusRunning:
+		return debrid.TorrentItemStatusDownloading
+	case StatusFinished:
+		return debrid.TorrentItemStatusCompleted
+	case StatusSeeding:
+		return debrid.TorrentItemStatusSeeding
+	case StatusWaiting, StatusQueued:
+		return debrid.TorrentItemStatusPaused
+	case StatusError, StatusBanned, StatusTimeout, StatusDeleted:
+		return debrid.TorrentItemStatusError
+	default:
+		return debrid.TorrentItemStatusOther
+	}
+}
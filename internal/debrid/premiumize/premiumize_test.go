@@ -0,0 +1,173 @@
+package premiumize
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"seanime/internal/debrid/debrid"
+	"seanime/internal/test_utils"
+	"seanime/internal/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+// routeFixture returns the recorded response of a Premiumize API request.
+func routeFixture(r *http.Request) (int, string) {
+	if r.URL.Query().Get("apikey") != "token" {
+		return http.StatusOK, "not_logged_in.json"
+	}
+
+	switch r.URL.Path {
+	case "/transfer/list":
+		return http.StatusOK, "transfer_list.json"
+	case "/transfer/create":
+		return http.StatusOK, "transfer_create.json"
+	case "/transfer/delete":
+		return http.StatusOK, "success.json"
+	case "/transfer/directdl":
+		if strings.Contains(r.PostForm.Get("src"), "6a9759bffd5c0af65319979fb7832189f4f3c35d") {
+			return http.StatusOK, "transfer_directdl.json"
+		}
+		return http.StatusOK, "transfer_directdl_uncached.json"
+	case "/cache/check":
+		return http.StatusOK, "cache_check.json"
+	case "/zip/generate":
+		return http.StatusOK, "zip_generate.json"
+	default:
+		return http.StatusNotFound, ""
+	}
+}
+
+func newTestPremiumize(t *testing.T) (*Premiumize, *test_utils.FixtureServer) {
+	fixtures := test_utils.NewFixtureServer(t, "testdata", routeFixture)
+
+	pm := NewPremiumize(util.NewLogger()).(*Premiumize)
+	pm.baseUrl = fixtures.URL
+	pm.pollInterval = 10 * time.Millisecond
+	require.NoError(t, pm.Authenticate("token"))
+
+	return pm, fixtures
+}
+
+func TestPremiumize_GetTorrents(t *testing.T) {
+	pm, _ := newTestPremiumize(t)
+
+	torrents, err := pm.GetTorrents()
+	require.NoError(t, err)
+	require.Len(t, torrents, 2)
+
+	assert.Equal(t, "TRANSFER1", torrents[0].ID)
+	assert.Equal(t, "6a9759bffd5c0af65319979fb7832189f4f3c35d", torrents[0].Hash)
+	assert.Equal(t, debrid.TorrentItemStatusCompleted, torrents[0].Status)
+	assert.True(t, torrents[0].IsReady)
+
+	assert.Equal(t, "0123456789abcdef0123456789abcdef01234567", torrents[1].Hash)
+	assert.Equal(t, debrid.TorrentItemStatusDownloading, torrents[1].Status)
+	assert.Equal(t, 42, torrents[1].CompletionPercentage)
+	assert.False(t, torrents[1].IsReady)
+
+	// Wrong API key
+	require.NoError(t, pm.Authenticate("wrong"))
+	_, err = pm.GetTorrents()
+	assert.ErrorIs(t, err, debrid.ErrNotAuthenticated)
+}
+
+func TestPremiumize_AddTorrent(t *testing.T) {
+	pm, fixtures := newTestPremiumize(t)
+
+	// Already added
+	id, err := pm.AddTorrent(debrid.AddTorrentOptions{
+		MagnetLink: "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567",
+		InfoHash:   "0123456789abcdef0123456789abcdef01234567",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "TRANSFER2", id)
+	assert.False(t, fixtures.Called("", "/transfer/create"))
+
+	id, err = pm.AddTorrent(debrid.AddTorrentOptions{
+		MagnetLink: "magnet:?xt=urn:btih:fedcba9876543210fedcba9876543210fedcba98",
+		InfoHash:   "fedcba9876543210fedcba9876543210fedcba98",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "TRANSFER3", id)
+}
+
+func TestPremiumize_GetTorrentInfo(t *testing.T) {
+	pm, fixtures := newTestPremiumize(t)
+
+	info, err := pm.GetTorrentInfo(debrid.GetTorrentInfoOptions{
+		MagnetLink: "magnet:?xt=urn:btih:6a9759bffd5c0af65319979fb7832189f4f3c35d",
+		InfoHash:   "6a9759bffd5c0af65319979fb7832189f4f3c35d",
+	})
+	require.NoError(t, err)
+
+	// The torrent is not added to the account
+	assert.Nil(t, info.ID)
+	assert.False(t, fixtures.Called("", "/transfer/create"))
+
+	assert.Equal(t, "[SubsPlease] Sousou no Frieren (01-28) (1080p) [Batch]", info.Name)
+	assert.Equal(t, int64(2900000000), info.Size)
+	require.Len(t, info.Files, 2)
+	assert.Equal(t, "1", info.Files[1].ID)
+	assert.Equal(t, "[SubsPlease] Sousou no Frieren - 02 (1080p) [E5A85899].mkv", info.Files[1].Name)
+	assert.Equal(t, "/[SubsPlease] Sousou no Frieren (01-28) (1080p) [Batch]/[SubsPlease] Sousou no Frieren - 02 (1080p) [E5A85899].mkv", info.Files[1].Path)
+
+	// Not cached
+	_, err = pm.GetTorrentInfo(debrid.GetTorrentInfoOptions{
+		InfoHash: "fedcba9876543210fedcba9876543210fedcba98",
+	})
+	assert.Error(t, err)
+}
+
+func TestPremiumize_GetTorrentDownloadUrls(t *testing.T) {
+	pm, _ := newTestPremiumize(t)
+
+	downloadUrls, err := pm.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{ID: "TRANSFER1", FileId: "1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://cdn.premiumize.me/dl/FILE2/episode.mkv"}, downloadUrls)
+
+	_, err = pm.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{ID: "TRANSFER1", FileId: "2"})
+	assert.Error(t, err)
+
+	// All files are zipped
+	downloadUrls, err = pm.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{ID: "TRANSFER1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://cdn.premiumize.me/zip/FOLDER1.zip"}, downloadUrls)
+
+	// Not ready
+	_, err = pm.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{ID: "TRANSFER2"})
+	assert.Error(t, err)
+}
+
+func TestPremiumize_GetTorrentStreamUrl(t *testing.T) {
+	pm, _ := newTestPremiumize(t)
+
+	itemCh := make(chan debrid.TorrentItem, 10)
+	streamUrl, err := pm.GetTorrentStreamUrl(context.Background(), debrid.StreamTorrentOptions{ID: "TRANSFER1", FileId: "0"}, itemCh)
+	require.NoError(t, err)
+	assert.Equal(t, "https://cdn.premiumize.me/dl/FILE1/episode.mkv", streamUrl)
+
+	item := <-itemCh
+	assert.Equal(t, 100, item.CompletionPercentage)
+
+	// Cancelled while waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = pm.GetTorrentStreamUrl(ctx, debrid.StreamTorrentOptions{ID: "TRANSFER2", FileId: "0"}, itemCh)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPremiumize_GetInstantAvailability(t *testing.T) {
+	pm, _ := newTestPremiumize(t)
+
+	availability := pm.GetInstantAvailability([]string{
+		"6A9759BFFD5C0AF65319979FB7832189F4F3C35D",
+		"0123456789abcdef0123456789abcdef01234567",
+	})
+	require.Len(t, availability, 1)
+
+	_, ok := availability["6a9759bffd5c0af65319979fb7832189f4f3c35d"]
+	assert.True(t, ok)
+}
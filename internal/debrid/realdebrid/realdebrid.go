@@ -0,0 +1,653 @@
+package realdebrid
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"github.com/rs/zerolog"
+	"github.com/samber/mo"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"seanime/internal/debrid/debrid"
+	"seanime/internal/util"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	RealDebrid struct {
+		baseUrl      string
+		apiKey       mo.Option[string]
+		client       *http.Client
+		logger       *zerolog.Logger
+		pollInterval time.Duration
+	}
+
+	ErrorResponse struct {
+		Error     string `json:"error"`
+		ErrorCode int    `json:"error_code"`
+	}
+
+	File struct {
+		ID       int    `json:"id"`
+		Path     string `json:"path"` // e.g. "/Big Buck Bunny/Big Buck Bunny.mp4"
+		Bytes    int64  `json:"bytes"`
+		Selected int    `json:"selected"`
+	}
+
+	Torrent struct {
+		ID       string   `json:"id"`
+		Filename string   `json:"filename"`
+		Hash     string   `json:"hash"`
+		Bytes    int64    `json:"bytes"`
+		Host     string   `json:"host"`
+		Split    int      `json:"split"`
+		Progress float64  `json:"progress"` // 0 to 100
+		Status   string   `json:"status"`
+		Added    string   `json:"added"`
+		Files    []*File  `json:"files"` // Only present in the torrent info
+		Links    []string `json:"links"` // One link per selected file, or a single link if the files were archived
+		Ended    string   `json:"ended"`
+		Speed    int64    `json:"speed"`
+		Seeders  int      `json:"seeders"`
+	}
+
+	UnrestrictedLink struct {
+		ID         string `json:"id"`
+		Filename   string `json:"filename"`
+		MimeType   string `json:"mimeType"`
+		Filesize   int64  `json:"filesize"`
+		Link       string `json:"link"`
+		Host       string `json:"host"`
+		Download   string `json:"download"`
+		Streamable int    `json:"streamable"`
+	}
+
+	// InstantAvailabilityVariant maps file IDs to the cached files of one variant of the torrent.
+	InstantAvailabilityVariant map[string]struct {
+		Filename string `json:"filename"`
+		Filesize int64  `json:"filesize"`
+	}
+)
+
+const (
+	StatusMagnetError           = "magnet_error"
+	StatusMagnetConversion      = "magnet_conversion"
+	StatusWaitingFilesSelection = "waiting_files_selection"
+	StatusQueued                = "queued"
+	StatusDownloading           = "downloading"
+	StatusDownloaded            = "downloaded"
+	StatusError                 = "error"
+	StatusVirus                 = "virus"
+	StatusCompressing           = "compressing"
+	StatusUploading             = "uploading"
+	StatusDead                  = "dead"
+)
+
+func NewRealDebrid(logger *zerolog.Logger) debrid.Provider {
+	return &RealDebrid{
+		baseUrl:      "https://api.real-debrid.com/rest/1.0",
+		apiKey:       mo.None[string](),
+		client:       &http.Client{},
+		logger:       logger,
+		pollInterval: 4 * time.Second,
+	}
+}
+
+func (t *RealDebrid) GetSettings() debrid.Settings {
+	return debrid.Settings{
+		ID:   "realdebrid",
+		Name: "RealDebrid",
+	}
+}
+
+// doQuery sends the request and returns the response body.
+// Real-Debrid responds with an error object and a non-2xx status code when the request fails.
+func (t *RealDebrid) doQuery(method, uri string, body io.Reader, contentType string) ([]byte, error) {
+	apiKey, found := t.apiKey.Get()
+	if !found {
+		return nil, debrid.ErrNotAuthenticated
+	}
+
+	req, err := http.NewRequest(method, uri, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Add("Content-Type", contentType)
+	}
+	req.Header.Add("Authorization", "Bearer "+apiKey)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, debrid.ErrNotAuthenticated
+	}
+
+	if resp.StatusCode >= 400 {
+		var errResp ErrorResponse
+		if err := json.Unmarshal(data, &errResp); err == nil && errResp.Error != "" {
+			return nil, fmt.Errorf("request failed: %s (code %d)", errResp.Error, errResp.ErrorCode)
+		}
+		return nil, fmt.Errorf("request failed: %s", resp.Status)
+	}
+
+	return data, nil
+}
+
+func (t *RealDebrid) doForm(method, uri string, form url.Values) ([]byte, error) {
+	return t.doQuery(method, uri, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded")
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (t *RealDebrid) Authenticate(apiKey string) error {
+	t.apiKey = mo.Some(apiKey)
+	return nil
+}
+
+// GetInstantAvailability checks the cache of Real-Debrid.
+// Real-Debrid has disabled this endpoint for most accounts, in which case the map is empty.
+func (t *RealDebrid) GetInstantAvailability(hashes []string) map[string]debrid.TorrentItemInstantAvailability {
+
+	t.logger.Trace().Strs("hashes", hashes).Msg("realdebrid: Checking instant availability")
+
+	availability := make(map[string]debrid.TorrentItemInstantAvailability)
+
+	for i := 0; i < len(hashes); i += 100 {
+		end := min(i+100, len(hashes))
+
+		data, err := t.doQuery("GET", t.baseUrl+"/torrents/instantAvailability/"+strings.Join(hashes[i:end], "/"), nil, "")
+		if err != nil {
+			t.logger.Debug().Err(err).Msg("realdebrid: Instant availability is unavailable")
+			return availability
+		}
+
+		// e.g. {"hash": {"rd": [{"1": {"filename": "...", "filesize": 123}}]}}
+		// Torrents that are not cached are either missing, empty arrays or empty objects
+		var resp map[string]json.RawMessage
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return availability
+		}
+
+		for hash, raw := range resp {
+			var hosts map[string][]InstantAvailabilityVariant
+			if err := json.Unmarshal(raw, &hosts); err != nil {
+				continue
+			}
+
+			cachedFiles := make(map[string]*debrid.CachedFile)
+			for _, variant := range hosts["rd"] {
+				for fileId, file := range variant {
+					cachedFiles[fileId] = &debrid.CachedFile{
+						Name: file.Filename,
+						Size: file.Filesize,
+					}
+				}
+			}
+
+			if len(cachedFiles) == 0 {
+				continue
+			}
+
+			availability[strings.ToLower(hash)] = debrid.TorrentItemInstantAvailability{
+				CachedFiles: cachedFiles,
+			}
+		}
+	}
+
+	return availability
+}
+
+// AddTorrent adds the magnet link to the user's account and selects the files to download.
+// Real-Debrid does not start downloading a torrent until its files are selected.
+func (t *RealDebrid) AddTorrent(opts debrid.AddTorrentOptions) (string, error) {
+
+	// Check if the torrent is already added
+	if opts.InfoHash != "" {
+		torrents, err := t.getTorrents()
+		if err == nil {
+			for _, torrent := range torrents {
+				if !strings.EqualFold(torrent.Hash, opts.InfoHash) {
+					continue
+				}
+				if torrent.Status == StatusWaitingFilesSelection {
+					if err := t.selectFiles(torrent.ID, opts.SelectFileId); err != nil {
+						return "", fmt.Errorf("realdebrid: Failed to add torrent: %w", err)
+					}
+					return torrent.ID, nil
+				}
+				// The files of a torrent cannot be selected again once they are,
+				// e.g. a batch added to stream another episode, so the magnet link is added again to select the requested file
+				if info, err := t.getTorrent(torrent.ID); err == nil && isFileSelected(info.Files, opts.SelectFileId) {
+					return torrent.ID, nil
+				}
+			}
+		}
+	}
+
+	t.logger.Trace().Str("magnetLink", opts.MagnetLink).Msg("realdebrid: Adding torrent")
+
+	id, err := t.addMagnet(opts.MagnetLink)
+	if err != nil {
+		return "", fmt.Errorf("realdebrid: Failed to add torrent: %w", err)
+	}
+
+	torrent, err := t.awaitFiles(id)
+	if err != nil {
+		return "", fmt.Errorf("realdebrid: Failed to add torrent: %w", err)
+	}
+
+	if torrent.Status == StatusWaitingFilesSelection {
+		if err := t.selectFiles(id, opts.SelectFileId); err != nil {
+			return "", fmt.Errorf("realdebrid: Failed to add torrent: %w", err)
+		}
+	}
+
+	t.logger.Debug().Str("torrentId", id).Str("torrentName", torrent.Filename).Str("torrentHash", torrent.Hash).Msg("realdebrid: Torrent added")
+
+	return id, nil
+}
+
+func (t *RealDebrid) addMagnet(magnetLink string) (string, error) {
+	data, err := t.doForm("POST", t.baseUrl+"/torrents/addMagnet", url.Values{"magnet": {magnetLink}})
+	if err != nil {
+		return "", err
+	}
+
+	var d struct {
+		ID  string `json:"id"`
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return "", err
+	}
+
+	return d.ID, nil
+}
+
+// selectFiles starts the download of the given file, or of all files if fileId is empty.
+func (t *RealDebrid) selectFiles(id string, fileId string) error {
+	files := "all"
+	if fileId != "" {
+		files = fileId
+	}
+
+	_, err := t.doForm("POST", t.baseUrl+"/torrents/selectFiles/"+id, url.Values{"files": {files}})
+	return err
+}
+
+// awaitFiles waits for Real-Debrid to retrieve the metadata of a newly added magnet link.
+func (t *RealDebrid) awaitFiles(id string) (*Torrent, error) {
+	for i := 0; ; i++ {
+		torrent, err := t.getTorrent(id)
+		if err != nil {
+			return nil, err
+		}
+
+		switch torrent.Status {
+		case StatusMagnetError, StatusError, StatusVirus, StatusDead:
+			return nil, fmt.Errorf("torrent status is %q", torrent.Status)
+		case StatusMagnetConversion:
+			if i >= 15 {
+				return nil, fmt.Errorf("timed out waiting for the torrent metadata")
+			}
+			time.Sleep(t.pollInterval / 2)
+		default:
+			return torrent, nil
+		}
+	}
+}
+
+// GetTorrentStreamUrl blocks until the selected file is downloaded and returns its unrestricted link.
+func (t *RealDebrid) GetTorrentStreamUrl(ctx context.Context, opts debrid.StreamTorrentOptions, itemCh chan debrid.TorrentItem) (streamUrl string, err error) {
+
+	t.logger.Trace().Str("torrentId", opts.ID).Str("fileId", opts.FileId).Msg("realdebrid: Retrieving stream link")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(t.pollInterval):
+			torrent, _err := t.getTorrent(opts.ID)
+			if _err != nil {
+				t.logger.Error().Err(_err).Msg("realdebrid: Failed to get torrent")
+				return "", fmt.Errorf("realdebrid: Failed to get torrent: %w", _err)
+			}
+
+			itemCh <- *toDebridTorrent(torrent)
+
+			switch torrent.Status {
+			case StatusMagnetError, StatusError, StatusVirus, StatusDead:
+				return "", fmt.Errorf("realdebrid: Torrent failed with status %q", torrent.Status)
+			case StatusWaitingFilesSelection:
+				if _err := t.selectFiles(opts.ID, opts.FileId); _err != nil {
+					return "", fmt.Errorf("realdebrid: Failed to select files: %w", _err)
+				}
+			case StatusDownloaded:
+				downloadUrl, _err := t.getFileDownloadUrl(torrent, opts.FileId)
+				if _err != nil {
+					t.logger.Error().Err(_err).Msg("realdebrid: Failed to get download URL")
+					return "", fmt.Errorf("realdebrid: Failed to get download URL: %w", _err)
+				}
+				return downloadUrl, nil
+			}
+		}
+	}
+}
+
+// GetTorrentDownloadUrls returns the unrestricted link of the file, or of every selected file.
+func (t *RealDebrid) GetTorrentDownloadUrls(opts debrid.DownloadTorrentOptions) (downloadUrls []string, err error) {
+
+	t.logger.Trace().Str("torrentId", opts.ID).Msg("realdebrid: Retrieving download link")
+
+	torrent, err := t.getTorrent(opts.ID)
+	if err != nil {
+		return nil, fmt.Errorf("realdebrid: Failed to get download URL: %w", err)
+	}
+
+	if torrent.Status != StatusDownloaded {
+		return nil, fmt.Errorf("realdebrid: Failed to get download URL, torrent is not ready")
+	}
+
+	if opts.FileId != "" {
+		downloadUrl, err := t.getFileDownloadUrl(torrent, opts.FileId)
+		if err != nil {
+			return nil, fmt.Errorf("realdebrid: Failed to get download URL: %w", err)
+		}
+		return []string{downloadUrl}, nil
+	}
+
+	downloadUrls = make([]string, 0, len(torrent.Links))
+	for _, link := range torrent.Links {
+		unrestricted, err := t.unrestrictLink(link)
+		if err != nil {
+			return nil, fmt.Errorf("realdebrid: Failed to get download URL: %w", err)
+		}
+		downloadUrls = append(downloadUrls, unrestricted.Download)
+	}
+
+	if len(downloadUrls) == 0 {
+		return nil, fmt.Errorf("realdebrid: Failed to get download URL, no links found")
+	}
+
+	t.logger.Debug().Int("count", len(downloadUrls)).Msg("realdebrid: Download links retrieved")
+
+	return downloadUrls, nil
+}
+
+// getFileDownloadUrl returns the unrestricted link of a file.
+// Links are ordered like the selected files, unless Real-Debrid archived the files into a single link.
+func (t *RealDebrid) getFileDownloadUrl(torrent *Torrent, fileId string) (string, error) {
+	if len(torrent.Links) == 0 {
+		return "", fmt.Errorf("no links found")
+	}
+
+	link := torrent.Links[0]
+	if fileId != "" {
+		// The file must be selected, even if there is a single link, otherwise another file would be returned
+		selectedFiles := getSelectedFiles(torrent.Files)
+		idx := slices.IndexFunc(selectedFiles, func(f *File) bool {
+			return strconv.Itoa(f.ID) == fileId
+		})
+		switch {
+		case idx == -1:
+			return "", fmt.Errorf("file not selected")
+		case idx < len(torrent.Links):
+			link = torrent.Links[idx]
+		case len(torrent.Links) > 1:
+			return "", fmt.Errorf("file not found")
+		}
+	}
+
+	unrestricted, err := t.unrestrictLink(link)
+	if err != nil {
+		return "", err
+	}
+
+	t.logger.Debug().Str("downloadUrl", unrestricted.Download).Msg("realdebrid: Download link retrieved")
+
+	return unrestricted.Download, nil
+}
+
+// isFileSelected returns true if the file is selected.
+// Always returns true if fileId is empty since no specific file is requested.
+func isFileSelected(files []*File, fileId string) bool {
+	if fileId == "" {
+		return true
+	}
+	return slices.ContainsFunc(files, func(f *File) bool {
+		return strconv.Itoa(f.ID) == fileId && f.Selected == 1
+	})
+}
+
+// getSelectedFiles returns the selected files ordered like the links of the torrent.
+func getSelectedFiles(files []*File) []*File {
+	ret := make([]*File, 0, len(files))
+	for _, f := range files {
+		if f.Selected == 1 {
+			ret = append(ret, f)
+		}
+	}
+	slices.SortFunc(ret, func(i, j *File) int {
+		return cmp.Compare(i.ID, j.ID)
+	})
+	return ret
+}
+
+func (t *RealDebrid) unrestrictLink(link string) (*UnrestrictedLink, error) {
+	data, err := t.doForm("POST", t.baseUrl+"/unrestrict/link", url.Values{"link": {link}})
+	if err != nil {
+		return nil, err
+	}
+
+	var ret UnrestrictedLink
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+func (t *RealDebrid) GetTorrent(id string) (ret *debrid.TorrentItem, err error) {
+	torrent, err := t.getTorrent(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ret = toDebridTorrent(torrent)
+
+	return ret, nil
+}
+
+func (t *RealDebrid) getTorrent(id string) (ret *Torrent, err error) {
+
+	data, err := t.doQuery("GET", t.baseUrl+"/torrents/info/"+id, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("realdebrid: Failed to get torrent: %w", err)
+	}
+
+	err = json.Unmarshal(data, &ret)
+	if err != nil {
+		return nil, fmt.Errorf("realdebrid: Failed to parse torrent: %w", err)
+	}
+
+	return ret, nil
+}
+
+// GetTorrentInfo adds the magnet link to the user's account to retrieve the torrent's files.
+// The returned ID is set when the torrent was added by this call so that the caller can delete it.
+func (t *RealDebrid) GetTorrentInfo(opts debrid.GetTorrentInfoOptions) (ret *debrid.TorrentInfo, err error) {
+
+	if opts.MagnetLink == "" {
+		return nil, fmt.Errorf("realdebrid: Magnet link is required to retrieve torrent info")
+	}
+
+	var id string
+	added := false
+
+	if opts.InfoHash != "" {
+		torrents, err := t.getTorrents()
+		if err == nil {
+			for _, torrent := range torrents {
+				if strings.EqualFold(torrent.Hash, opts.InfoHash) {
+					id = torrent.ID
+					break
+				}
+			}
+		}
+	}
+
+	if id == "" {
+		id, err = t.addMagnet(opts.MagnetLink)
+		if err != nil {
+			return nil, fmt.Errorf("realdebrid: Failed to get torrent info: %w", err)
+		}
+		added = true
+	}
+
+	torrent, err := t.awaitFiles(id)
+	if err != nil {
+		if added {
+			_ = t.DeleteTorrent(id)
+		}
+		return nil, fmt.Errorf("realdebrid: Failed to get torrent info: %w", err)
+	}
+
+	ret = toDebridTorrentInfo(torrent)
+	if added {
+		ret.ID = &id
+	}
+
+	return ret, nil
+}
+
+func (t *RealDebrid) GetTorrents() (ret []*debrid.TorrentItem, err error) {
+
+	torrents, err := t.getTorrents()
+	if err != nil {
+		return nil, fmt.Errorf("realdebrid: Failed to get torrents: %w", err)
+	}
+
+	for _, t := range torrents {
+		ret = append(ret, toDebridTorrent(t))
+	}
+
+	slices.SortFunc(ret, func(i, j *debrid.TorrentItem) int {
+		return cmp.Compare(j.AddedAt, i.AddedAt)
+	})
+
+	return ret, nil
+}
+
+func (t *RealDebrid) getTorrents() (ret []*Torrent, err error) {
+
+	data, err := t.doQuery("GET", t.baseUrl+"/torrents?limit=500", nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("realdebrid: Failed to get torrents: %w", err)
+	}
+
+	// An empty list is returned with a 204 status code
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	err = json.Unmarshal(data, &ret)
+	if err != nil {
+		t.logger.Error().Err(err).Msg("realdebrid: Failed to parse torrents")
+		return nil, fmt.Errorf("realdebrid: Failed to parse torrents: %w", err)
+	}
+
+	return ret, nil
+}
+
+func (t *RealDebrid) DeleteTorrent(id string) error {
+
+	_, err := t.doQuery("DELETE", t.baseUrl+"/torrents/delete/"+id, nil, "")
+	if err != nil {
+		return fmt.Errorf("realdebrid: Failed to delete torrent: %w", err)
+	}
+
+	return nil
+}
+
+func toDebridTorrent(t *Torrent) (ret *debrid.TorrentItem) {
+
+	addedAt, _ := time.Parse(time.RFC3339Nano, t.Added)
+
+	eta := "-"
+	if t.Status == StatusDownloading && t.Speed > 0 {
+		remaining := int64(float64(t.Bytes) * (100 - t.Progress) / 100)
+		eta = util.FormatETA(int(remaining / t.Speed))
+	}
+
+	ret = &debrid.TorrentItem{
+		ID:                   t.ID,
+		Name:                 t.Filename,
+		Hash:                 strings.ToLower(t.Hash),
+		Size:                 t.Bytes,
+		FormattedSize:        humanize.Bytes(uint64(t.Bytes)),
+		CompletionPercentage: int(t.Progress),
+		ETA:                  eta,
+		Status:               toDebridTorrentStatus(t.Status),
+		AddedAt:              addedAt.Format(time.RFC3339),
+		Speed:                util.ToHumanReadableSpeed(int(t.Speed)),
+		Seeders:              t.Seeders,
+		IsReady:              t.Status == StatusDownloaded,
+	}
+
+	return
+}
+
+func toDebridTorrentInfo(t *Torrent) (ret *debrid.TorrentInfo) {
+
+	files := make([]*debrid.TorrentItemFile, 0, len(t.Files))
+	for idx, f := range t.Files {
+		files = append(files, &debrid.TorrentItemFile{
+			ID:    strconv.Itoa(f.ID), // Real-Debrid file IDs are used for file selection
+			Index: idx,
+			Name:  path.Base(f.Path), // e.g. "Big Buck Bunny.mp4"
+			Path:  f.Path,            // e.g. "/Big Buck Bunny/Big Buck Bunny.mp4"
+			Size:  f.Bytes,
+		})
+	}
+
+	ret = &debrid.TorrentInfo{
+		Name:  t.Filename,
+		Hash:  strings.ToLower(t.Hash),
+		Size:  t.Bytes,
+		Files: files,
+	}
+
+	return
+}
+
+func toDebridTorrentStatus(status string) debrid.TorrentItemStatus {
+	switch status {
+	case StatusDownloading, StatusCompressing, StatusUploading, StatusMagnetConversion:
+		return debrid.TorrentItemStatusDownloading
+	case StatusDownloaded:
+		return debrid.TorrentItemStatusCompleted
+	case StatusMagnetError, StatusError, StatusVirus, StatusDead:
+		return debrid.TorrentItemStatusError
+	case StatusQueued, StatusWaitingFilesSelection:
+		return debrid.TorrentItemStatusPaused
+	default:
+		return debrid.TorrentItemStatusOther
+	}
+}
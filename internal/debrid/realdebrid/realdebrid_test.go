@@ -0,0 +1,211 @@
+package realdebrid
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"path"
+	"seanime/internal/debrid/debrid"
+	"seanime/internal/test_utils"
+	"seanime/internal/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+// routeFixture returns the recorded response of a Real-Debrid API request.
+func routeFixture(r *http.Request) (int, string) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		return http.StatusUnauthorized, "bad_token.json"
+	}
+
+	switch p := r.URL.Path; {
+	case p == "/torrents":
+		return http.StatusOK, "torrents.json"
+	case p == "/torrents/addMagnet":
+		return http.StatusCreated, "add_magnet.json"
+	case strings.HasPrefix(p, "/torrents/info/"):
+		return http.StatusOK, "torrent_info_" + path.Base(p) + ".json"
+	case strings.HasPrefix(p, "/torrents/selectFiles/"), strings.HasPrefix(p, "/torrents/delete/"):
+		return http.StatusNoContent, ""
+	case strings.HasPrefix(p, "/torrents/instantAvailability/"):
+		return http.StatusOK, "instant_availability.json"
+	case p == "/unrestrict/link":
+		return http.StatusOK, "unrestrict_" + path.Base(r.PostForm.Get("link")) + ".json"
+	default:
+		return http.StatusNotFound, ""
+	}
+}
+
+func newTestRealDebrid(t *testing.T) (*RealDebrid, *test_utils.FixtureServer) {
+	fixtures := test_utils.NewFixtureServer(t, "testdata", routeFixture)
+
+	rd := NewRealDebrid(util.NewLogger()).(*RealDebrid)
+	rd.baseUrl = fixtures.URL
+	rd.pollInterval = 10 * time.Millisecond
+	require.NoError(t, rd.Authenticate("token"))
+
+	return rd, fixtures
+}
+
+func TestRealDebrid_GetTorrents(t *testing.T) {
+	rd, _ := newTestRealDebrid(t)
+
+	torrents, err := rd.GetTorrents()
+	require.NoError(t, err)
+	require.Len(t, torrents, 2)
+
+	// Sorted by date added, most recent first
+	assert.Equal(t, "TORRENT2", torrents[0].ID)
+	assert.Equal(t, debrid.TorrentItemStatusDownloading, torrents[0].Status)
+	assert.Equal(t, 42, torrents[0].CompletionPercentage)
+	assert.False(t, torrents[0].IsReady)
+
+	assert.Equal(t, "TORRENT1", torrents[1].ID)
+	assert.Equal(t, debrid.TorrentItemStatusCompleted, torrents[1].Status)
+	assert.True(t, torrents[1].IsReady)
+
+	// Wrong API key
+	require.NoError(t, rd.Authenticate("wrong"))
+	_, err = rd.GetTorrents()
+	assert.ErrorIs(t, err, debrid.ErrNotAuthenticated)
+}
+
+func TestRealDebrid_AddTorrent(t *testing.T) {
+	rd, fixtures := newTestRealDebrid(t)
+
+	// Already added
+	id, err := rd.AddTorrent(debrid.AddTorrentOptions{
+		MagnetLink: "magnet:?xt=urn:btih:6a9759bffd5c0af65319979fb7832189f4f3c35d",
+		InfoHash:   "6A9759BFFD5C0AF65319979FB7832189F4F3C35D",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "TORRENT1", id)
+	assert.False(t, fixtures.Called(http.MethodPost, "/torrents/addMagnet"))
+
+	// New torrent, only the selected file is downloaded
+	id, err = rd.AddTorrent(debrid.AddTorrentOptions{
+		MagnetLink:   "magnet:?xt=urn:btih:fedcba9876543210fedcba9876543210fedcba98",
+		InfoHash:     "fedcba9876543210fedcba9876543210fedcba98",
+		SelectFileId: "2",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "NEWTORRENT", id)
+	assert.Equal(t, "magnet:?xt=urn:btih:fedcba9876543210fedcba9876543210fedcba98", fixtures.Form("/torrents/addMagnet", "magnet"))
+	assert.Equal(t, "2", fixtures.Form("/torrents/selectFiles/NEWTORRENT", "files"))
+}
+
+func TestRealDebrid_AddTorrent_OtherFileSelected(t *testing.T) {
+	rd, fixtures := newTestRealDebrid(t)
+
+	// The batch was added to stream episode 1 and 3, the requested file is selected
+	id, err := rd.AddTorrent(debrid.AddTorrentOptions{
+		MagnetLink:   "magnet:?xt=urn:btih:6a9759bffd5c0af65319979fb7832189f4f3c35d",
+		InfoHash:     "6a9759bffd5c0af65319979fb7832189f4f3c35d",
+		SelectFileId: "3",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "TORRENT1", id)
+	assert.False(t, fixtures.Called(http.MethodPost, "/torrents/addMagnet"))
+
+	// Episode 2 is not selected, the magnet link is added again to select it
+	id, err = rd.AddTorrent(debrid.AddTorrentOptions{
+		MagnetLink:   "magnet:?xt=urn:btih:6a9759bffd5c0af65319979fb7832189f4f3c35d",
+		InfoHash:     "6a9759bffd5c0af65319979fb7832189f4f3c35d",
+		SelectFileId: "2",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "NEWTORRENT", id)
+	assert.Equal(t, "magnet:?xt=urn:btih:6a9759bffd5c0af65319979fb7832189f4f3c35d", fixtures.Form("/torrents/addMagnet", "magnet"))
+	assert.Equal(t, "2", fixtures.Form("/torrents/selectFiles/NEWTORRENT", "files"))
+	assert.False(t, fixtures.Called(http.MethodPost, "/torrents/selectFiles/TORRENT1"))
+}
+
+func TestRealDebrid_GetTorrentInfo(t *testing.T) {
+	rd, fixtures := newTestRealDebrid(t)
+
+	info, err := rd.GetTorrentInfo(debrid.GetTorrentInfoOptions{
+		MagnetLink: "magnet:?xt=urn:btih:fedcba9876543210fedcba9876543210fedcba98",
+		InfoHash:   "fedcba9876543210fedcba9876543210fedcba98",
+	})
+	require.NoError(t, err)
+
+	// The torrent was added to retrieve its files
+	require.NotNil(t, info.ID)
+	assert.Equal(t, "NEWTORRENT", *info.ID)
+	require.Len(t, info.Files, 2)
+	assert.Equal(t, "2", info.Files[1].ID)
+	assert.Equal(t, 1, info.Files[1].Index)
+	assert.Equal(t, "[SubsPlease] Dungeon Meshi - 02 (1080p) [D6A1A2D4].mkv", info.Files[1].Name)
+	assert.Equal(t, "/[SubsPlease] Dungeon Meshi (01-24) (1080p) [Batch]/[SubsPlease] Dungeon Meshi - 02 (1080p) [D6A1A2D4].mkv", info.Files[1].Path)
+
+	// Files are not selected yet
+	assert.False(t, fixtures.Called(http.MethodPost, "/torrents/selectFiles/NEWTORRENT"))
+}
+
+func TestRealDebrid_GetTorrentDownloadUrls(t *testing.T) {
+	rd, _ := newTestRealDebrid(t)
+
+	// The links follow the order of the selected files, file 2 is not selected
+	downloadUrls, err := rd.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{ID: "TORRENT1", FileId: "3"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://download.real-debrid.com/d/UNRESTRICTED2/Frieren.mkv"}, downloadUrls)
+
+	_, err = rd.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{ID: "TORRENT1", FileId: "2"})
+	assert.Error(t, err)
+
+	// All files
+	downloadUrls, err = rd.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{ID: "TORRENT1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"https://download.real-debrid.com/d/UNRESTRICTED1/Frieren.mkv",
+		"https://download.real-debrid.com/d/UNRESTRICTED2/Frieren.mkv",
+	}, downloadUrls)
+
+	// Not ready
+	_, err = rd.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{ID: "NEWTORRENT"})
+	assert.Error(t, err)
+}
+
+func TestRealDebrid_GetTorrentStreamUrl(t *testing.T) {
+	rd, _ := newTestRealDebrid(t)
+
+	itemCh := make(chan debrid.TorrentItem, 10)
+	streamUrl, err := rd.GetTorrentStreamUrl(context.Background(), debrid.StreamTorrentOptions{ID: "TORRENT1", FileId: "1"}, itemCh)
+	require.NoError(t, err)
+	assert.Equal(t, "https://download.real-debrid.com/d/UNRESTRICTED1/Frieren.mkv", streamUrl)
+
+	item := <-itemCh
+	assert.Equal(t, 100, item.CompletionPercentage)
+
+	// A single link is only returned for the selected file
+	streamUrl, err = rd.GetTorrentStreamUrl(context.Background(), debrid.StreamTorrentOptions{ID: "TORRENT3", FileId: "1"}, itemCh)
+	require.NoError(t, err)
+	assert.Equal(t, "https://download.real-debrid.com/d/UNRESTRICTED1/Frieren.mkv", streamUrl)
+
+	_, err = rd.GetTorrentStreamUrl(context.Background(), debrid.StreamTorrentOptions{ID: "TORRENT3", FileId: "2"}, itemCh)
+	assert.Error(t, err)
+
+	// Cancelled while waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = rd.GetTorrentStreamUrl(ctx, debrid.StreamTorrentOptions{ID: "NEWTORRENT", FileId: "1"}, itemCh)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRealDebrid_GetInstantAvailability(t *testing.T) {
+	rd, _ := newTestRealDebrid(t)
+
+	availability := rd.GetInstantAvailability([]string{
+		"6a9759bffd5c0af65319979fb7832189f4f3c35d",
+		"0123456789abcdef0123456789abcdef01234567",
+	})
+	require.Len(t, availability, 1)
+
+	cached, ok := availability["6a9759bffd5c0af65319979fb7832189f4f3c35d"]
+	require.True(t, ok)
+	require.Len(t, cached.CachedFiles, 2)
+	assert.Equal(t, "[SubsPlease] Sousou no Frieren - 02 (1080p) [E5A85899].mkv", cached.CachedFiles["2"].Name)
+	assert.Equal(t, int64(1450000000), cached.CachedFiles["2"].Size)
+}
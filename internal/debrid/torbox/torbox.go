@@ -308,6 +308,15 @@ func (t *TorBox) GetTorrentStreamUrl(ctx context.Context, opts debrid.StreamTorr
 	return
 }
 
+// GetTorrentDownloadUrls returns the download URL of the file, or of a zip archive of the torrent.
+func (t *TorBox) GetTorrentDownloadUrls(opts debrid.DownloadTorrentOptions) (downloadUrls []string, err error) {
+	downloadUrl, err := t.GetTorrentDownloadUrl(opts)
+	if err != nil {
+		return nil, err
+	}
+	return []string{downloadUrl}, nil
+}
+
 func (t *TorBox) GetTorrentDownloadUrl(opts debrid.DownloadTorrentOptions) (downloadUrl string, err error) {
 
 	t.logger.Trace().Str("torrentId", opts.ID).Msg("torbox: Retrieving download link")
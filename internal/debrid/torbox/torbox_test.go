@@ -35,12 +35,12 @@ func TestTorBox_GetTorrents(t *testing.T) {
 
 	fmt.Println("=== Download link ===")
 
-	downloadUrl, err := tb.GetTorrentDownloadUrl(debrid.DownloadTorrentOptions{
+	downloadUrls, err := tb.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{
 		ID: strconv.Itoa(98926),
 	})
 	require.NoError(t, err)
 
-	fmt.Println(downloadUrl)
+	fmt.Println(downloadUrls)
 }
 
 func TestTorBox_AddTorrent(t *testing.T) {
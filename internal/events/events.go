@@ -7,6 +7,7 @@ const (
 	RefreshedAnilistMangaCollection = "refreshed-anilist-manga-collection" // The manga collection has been refreshed
 	LibraryWatcherFileAdded         = "library-watcher-file-added"         // A new file has been added to the library
 	LibraryWatcherFileRemoved       = "library-watcher-file-removed"       // A file has been removed from the library
+	LibraryOrganized                = "library-organized"                  // Files have been organized or an organizer run has been undone
 	AutoDownloaderItemAdded         = "auto-downloader-item-added"         // An item has been added to the auto downloader queue
 
 	AutoScanStarted   = "auto-scan-started"   // The auto scan has started
@@ -30,9 +31,15 @@ const (
 
 	RefreshedMangaDownloadData  = "refreshed-manga-download-data"
 	ChapterDownloadQueueUpdated = "chapter-download-queue-updated"
+	MangaExportUpdated          = "manga-export-updated"         // The state of a manga export job changed
+	MangaAutoDownloaderQueued   = "manga-auto-downloader-queued" // New chapters were queued by the manga auto downloader
 	OfflineSnapshotCreated      = "offline-snapshot-created"
 
 	MediastreamShutdownStream = "mediastream-shutdown-stream"
+	MediastreamTrickplayReady = "mediastream-trickplay-ready"
+
+	MediastreamPreTranscodeProgress     = "mediastream-pre-transcode-progress"
+	MediastreamPreTranscodeQueueUpdated = "mediastream-pre-transcode-queue-updated"
 
 	ExtensionsReloaded = "extensions-reloaded"
 
@@ -45,4 +52,6 @@ const (
 	DebridDownloadProgress = "debrid-download-progress"
 
 	DebridStreamState = "debrid-stream-state"
+
+	ServerProfileChanged = "server-profile-changed" // The background modules now act on behalf of another profile
 )
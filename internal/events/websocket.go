@@ -57,6 +57,18 @@ func (m *WSEventManager) RemoveConn(id string) {
 	}
 }
 
+// CloseAll closes all the connections.
+// Clients will have to reconnect, this is used to force re-authentication.
+func (m *WSEventManager) CloseAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, conn := range m.Conns {
+		_ = conn.Conn.Close()
+	}
+	m.Conns = make([]*WSConn, 0)
+}
+
 // SendEvent sends a websocket event to the client.
 func (m *WSEventManager) SendEvent(t string, payload interface{}) {
 	m.mu.Lock()
@@ -22,15 +22,17 @@ func HandleGetAnimeCollection(c *RouteCtx) error {
 
 	bypassCache := c.Fiber.Method() == "POST"
 
+	profile := c.Profile()
+
 	// Get the user's anilist collection
-	animeCollection, err := c.App.GetAnimeCollection(bypassCache)
+	animeCollection, err := profile.GetAnimeCollection(bypassCache)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
 
 	go func() {
 		if c.App.Settings != nil && c.App.Settings.Library.EnableManga {
-			_, _ = c.App.GetMangaCollection(bypassCache)
+			_, _ = profile.GetMangaCollection(bypassCache)
 			if bypassCache {
 				c.App.WSEventManager.SendEvent(events.RefreshedAnilistMangaCollection, nil)
 			}
@@ -51,7 +53,7 @@ func HandleGetRawAnimeCollection(c *RouteCtx) error {
 	bypassCache := c.Fiber.Method() == "POST"
 
 	// Get the user's anilist collection
-	animeCollection, err := c.App.GetRawAnimeCollection(bypassCache)
+	animeCollection, err := c.Profile().GetRawAnimeCollection(bypassCache)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -84,7 +86,7 @@ func HandleEditAnilistListEntry(c *RouteCtx) error {
 		return c.RespondWithError(err)
 	}
 
-	err := c.App.AnilistPlatform.UpdateEntry(
+	err := c.Profile().AnilistPlatform.UpdateEntry(
 		*p.MediaId,
 		p.Status,
 		p.Score,
@@ -98,12 +100,12 @@ func HandleEditAnilistListEntry(c *RouteCtx) error {
 
 	switch p.Type {
 	case "anime":
-		_, _ = c.App.RefreshAnimeCollection()
+		_, _ = c.Profile().RefreshAnimeCollection()
 	case "manga":
-		_, _ = c.App.RefreshMangaCollection()
+		_, _ = c.Profile().RefreshMangaCollection()
 	default:
-		_, _ = c.App.RefreshAnimeCollection()
-		_, _ = c.App.RefreshMangaCollection()
+		_, _ = c.Profile().RefreshAnimeCollection()
+		_, _ = c.Profile().RefreshMangaCollection()
 	}
 
 	return c.RespondWithData(true)
@@ -132,7 +134,7 @@ func HandleGetAnilistAnimeDetails(c *RouteCtx) error {
 	if details, ok := detailsCache.Get(mId); ok {
 		return c.RespondWithData(details)
 	}
-	details, err := c.App.AnilistPlatform.GetAnimeDetails(mId)
+	details, err := c.Profile().AnilistPlatform.GetAnimeDetails(mId)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -162,7 +164,7 @@ func HandleGetAnilistStudioDetails(c *RouteCtx) error {
 	if details, ok := studioDetailsMap.Get(mId); ok {
 		return c.RespondWithData(details)
 	}
-	details, err := c.App.AnilistPlatform.GetStudioDetails(mId)
+	details, err := c.Profile().AnilistPlatform.GetStudioDetails(mId)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -202,46 +204,17 @@ func HandleDeleteAnilistListEntry(c *RouteCtx) error {
 		return c.RespondWithError(errors.New("missing parameters"))
 	}
 
-	var listEntryID int
-
-	switch *p.Type {
-	case "anime":
-		// Get the list entry ID
-		animeCollection, err := c.App.GetAnimeCollection(false)
-		if err != nil {
-			return c.RespondWithError(err)
-		}
-
-		listEntry, found := animeCollection.GetListEntryFromAnimeId(*p.MediaId)
-		if !found {
-			return c.RespondWithError(errors.New("list entry not found"))
-		}
-		listEntryID = listEntry.ID
-	case "manga":
-		// Get the list entry ID
-		mangaCollection, err := c.App.GetMangaCollection(false)
-		if err != nil {
-			return c.RespondWithError(err)
-		}
-
-		listEntry, found := mangaCollection.GetListEntryFromMangaId(*p.MediaId)
-		if !found {
-			return c.RespondWithError(errors.New("list entry not found"))
-		}
-		listEntryID = listEntry.ID
-	}
-
-	// Delete the list entry
-	err := c.App.AnilistPlatform.DeleteEntry(listEntryID)
+	// Delete the list entry, the platform resolves its own list entry ID
+	err := c.Profile().AnilistPlatform.DeleteEntry(*p.MediaId)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
 
 	switch *p.Type {
 	case "anime":
-		_, _ = c.App.RefreshAnimeCollection()
+		_, _ = c.Profile().RefreshAnimeCollection()
 	case "manga":
-		_, _ = c.App.RefreshMangaCollection()
+		_, _ = c.Profile().RefreshMangaCollection()
 	}
 
 	return c.RespondWithData(true)
@@ -409,7 +382,7 @@ func HandleAnilistListMissedSequels(c *RouteCtx) error {
 	}
 
 	// Get complete anime collection
-	animeCollection, err := c.App.AnilistPlatform.GetAnimeCollectionWithRelations()
+	animeCollection, err := c.Profile().AnilistPlatform.GetAnimeCollectionWithRelations()
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -445,7 +418,7 @@ func HandleGetAniListStats(c *RouteCtx) error {
 
 	ret, err := anilist.GetStats(
 		c.Fiber.Context(),
-		c.App.AnilistClient,
+		c.Profile().AnilistClient,
 	)
 	if err != nil {
 		return c.RespondWithError(err)
@@ -44,7 +44,7 @@ func HandleGetAnimeEntry(c *RouteCtx) error {
 	}
 
 	// Get the user's anilist collection
-	animeCollection, err := c.App.GetAnimeCollection(false)
+	animeCollection, err := c.Profile().GetAnimeCollection(false)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -58,7 +58,7 @@ func HandleGetAnimeEntry(c *RouteCtx) error {
 		MediaId:          mId,
 		LocalFiles:       lfs,
 		AnimeCollection:  animeCollection,
-		Platform:         c.App.AnilistPlatform,
+		Platform:         c.Profile().AnilistPlatform,
 		MetadataProvider: c.App.MetadataProvider,
 	})
 	if err != nil {
@@ -297,7 +297,7 @@ func HandleAnimeEntryManualMatch(c *RouteCtx) error {
 		return c.RespondWithError(err)
 	}
 
-	animeCollectionWithRelations, err := c.App.AnilistPlatform.GetAnimeCollectionWithRelations()
+	animeCollectionWithRelations, err := c.Profile().AnilistPlatform.GetAnimeCollectionWithRelations()
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -328,7 +328,7 @@ func HandleAnimeEntryManualMatch(c *RouteCtx) error {
 	})
 
 	// Get the media
-	media, err := c.App.AnilistPlatform.GetAnime(b.MediaId)
+	media, err := c.Profile().AnilistPlatform.GetAnime(b.MediaId)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -349,7 +349,7 @@ func HandleAnimeEntryManualMatch(c *RouteCtx) error {
 	fh := scanner.FileHydrator{
 		LocalFiles:         selectedLfs,
 		CompleteAnimeCache: anilist.NewCompleteAnimeCache(),
-		Platform:           c.App.AnilistPlatform,
+		Platform:           c.Profile().AnilistPlatform,
 		MetadataProvider:   c.App.MetadataProvider,
 		AnilistRateLimiter: limiter.NewAnilistLimiter(),
 		Logger:             c.App.Logger,
@@ -407,7 +407,7 @@ func HandleGetMissingEpisodes(c *RouteCtx) error {
 	// Get the user's anilist collection
 	// Do not bypass the cache, since this handler might be called multiple times, and we don't want to spam the API
 	// A cron job will refresh the cache every 10 minutes
-	animeCollection, err := c.App.GetAnimeCollection(false)
+	animeCollection, err := c.Profile().GetAnimeCollection(false)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -520,7 +520,7 @@ func HandleUpdateAnimeEntryProgress(c *RouteCtx) error {
 	}
 
 	// Update the progress on AniList
-	err := c.App.AnilistPlatform.UpdateEntryProgress(
+	err := c.Profile().AnilistPlatform.UpdateEntryProgress(
 		b.MediaId,
 		b.EpisodeNumber,
 		&b.TotalEpisodes,
@@ -529,7 +529,7 @@ func HandleUpdateAnimeEntryProgress(c *RouteCtx) error {
 		return c.RespondWithError(err)
 	}
 
-	_, _ = c.App.RefreshAnimeCollection() // Refresh the AniList collection
+	_, _ = c.Profile().RefreshAnimeCollection() // Refresh the AniList collection
 
 	return c.RespondWithData(true)
 }
@@ -14,7 +14,8 @@ import (
 //	@summary logs in the user by saving the JWT token in the database.
 //	@desc This is called when the JWT token is obtained from AniList after logging in with redirection on the client.
 //	@desc It also fetches the Viewer data from AniList and saves it in the database.
-//	@desc It creates a new handlers.Status and refreshes App modules.
+//	@desc The account is the one of the profile selected by the client.
+//	@desc It creates a new handlers.Status and refreshes App modules if the profile is the server profile.
 //	@route /api/v1/auth/login [POST]
 //	@returns handlers.Status
 func HandleLogin(c *RouteCtx) error {
@@ -29,11 +30,13 @@ func HandleLogin(c *RouteCtx) error {
 		return c.Fiber.JSON(NewErrorResponse(err))
 	}
 
+	profile := c.Profile()
+
 	// Set a new AniList client by passing to JWT token
-	c.App.UpdateAnilistClientToken(b.Token)
+	anilistClient := profile.UpdateAnilistClientToken(b.Token)
 
 	// Get viewer data from AniList
-	getViewer, err := c.App.AnilistClient.GetViewer(context.Background())
+	getViewer, err := anilistClient.GetViewer(context.Background())
 	if err != nil {
 		c.App.Logger.Error().Msg("Could not authenticate to AniList")
 		return c.RespondWithError(err)
@@ -50,9 +53,9 @@ func HandleLogin(c *RouteCtx) error {
 	}
 
 	// Save account data in database
-	_, err = c.App.Database.UpsertAccount(&models.Account{
+	_, err = c.App.Database.UpsertProfileAccount(profile.ID, &models.Account{
 		BaseModel: models.BaseModel{
-			ID:        1,
+			ID:        profile.ID,
 			UpdatedAt: time.Now(),
 		},
 		Username: getViewer.Viewer.Name,
@@ -69,6 +72,12 @@ func HandleLogin(c *RouteCtx) error {
 	// Create a new status
 	status := NewStatus(c)
 
+	// Only the server profile's account is used by the modules
+	if !profile.IsServerProfile() {
+		profile.ReloadAccount()
+		return c.RespondWithData(status)
+	}
+
 	c.App.InitOrRefreshAnilistData()
 
 	c.App.InitOrRefreshModules()
@@ -89,14 +98,17 @@ func HandleLogin(c *RouteCtx) error {
 //
 //	@summary logs out the user by removing JWT token from the database.
 //	@desc It removes JWT token and Viewer data from the database.
-//	@desc It creates a new handlers.Status and refreshes App modules.
+//	@desc The account is the one of the profile selected by the client.
+//	@desc It creates a new handlers.Status and refreshes App modules if the profile is the server profile.
 //	@route /api/v1/auth/logout [POST]
 //	@returns handlers.Status
 func HandleLogout(c *RouteCtx) error {
 
-	_, err := c.App.Database.UpsertAccount(&models.Account{
+	profile := c.Profile()
+
+	_, err := c.App.Database.UpsertProfileAccount(profile.ID, &models.Account{
 		BaseModel: models.BaseModel{
-			ID:        1,
+			ID:        profile.ID,
 			UpdatedAt: time.Now(),
 		},
 		Username: "",
@@ -112,6 +124,11 @@ func HandleLogout(c *RouteCtx) error {
 
 	status := NewStatus(c)
 
+	if !profile.IsServerProfile() {
+		profile.ReloadAccount()
+		return c.RespondWithData(status)
+	}
+
 	c.App.InitOrRefreshModules()
 
 	c.App.InitOrRefreshAnilistData()
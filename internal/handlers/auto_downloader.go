@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"errors"
+	"net/url"
 	"path/filepath"
 	"seanime/internal/database/db_bridge"
+	"seanime/internal/database/models"
 	"seanime/internal/library/anime"
+	"seanime/internal/library/autodownloader"
 	"strconv"
 )
 
@@ -22,6 +25,51 @@ func HandleRunAutoDownloader(c *RouteCtx) error {
 	return c.RespondWithData(true)
 }
 
+// HandleSimulateAutoDownloaderRule
+//
+//	@summary runs a rule against torrents without downloading anything.
+//	@desc The rule can be a saved rule (ruleId) or a draft (rule).
+//	@desc If torrentNames is empty, the latest torrents from the provider and feeds are used.
+//	@desc It returns the verdict of each check for each torrent, and the torrents that would be downloaded.
+//	@desc Nothing is added to the queue or the torrent client.
+//	@route /api/v1/auto-downloader/simulate [POST]
+//	@returns autodownloader.SimulationResult
+func HandleSimulateAutoDownloaderRule(c *RouteCtx) error {
+
+	type body struct {
+		RuleID       uint                      `json:"ruleId"`
+		Rule         *anime.AutoDownloaderRule `json:"rule"`
+		TorrentNames []string                  `json:"torrentNames"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	rule := b.Rule
+	if b.RuleID != 0 {
+		var err error
+		rule, err = db_bridge.GetAutoDownloaderRule(c.App.Database, b.RuleID)
+		if err != nil {
+			return c.RespondWithError(err)
+		}
+	}
+	if rule == nil || rule.MediaId == 0 {
+		return c.RespondWithError(errors.New("a rule is required"))
+	}
+
+	ret, err := c.App.AutoDownloader.Simulate(&autodownloader.SimulationOptions{
+		Rule:         rule,
+		TorrentNames: b.TorrentNames,
+	})
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(ret)
+}
+
 // HandleGetAutoDownloaderRule
 //
 //	@summary returns the rule with the given DB id.
@@ -95,6 +143,7 @@ func HandleCreateAutoDownloaderRule(c *RouteCtx) error {
 		EpisodeType         anime.AutoDownloaderRuleEpisodeType         `json:"episodeType"`
 		EpisodeNumbers      []int                                       `json:"episodeNumbers,omitempty"`
 		Destination         string                                      `json:"destination"`
+		QualityProfile      *anime.AutoDownloaderQualityProfile         `json:"qualityProfile,omitempty"`
 	}
 
 	var b body
@@ -122,6 +171,7 @@ func HandleCreateAutoDownloaderRule(c *RouteCtx) error {
 		EpisodeNumbers:      b.EpisodeNumbers,
 		Destination:         b.Destination,
 		AdditionalTerms:     b.AdditionalTerms,
+		QualityProfile:      b.QualityProfile,
 	}
 
 	if err := db_bridge.InsertAutoDownloaderRule(c.App.Database, rule); err != nil {
@@ -229,3 +279,178 @@ func HandleDeleteAutoDownloaderItem(c *RouteCtx) error {
 
 	return c.RespondWithData(true)
 }
+
+// HandleGetAutoDownloaderDebridDownloads
+//
+//	@summary returns the downloads of torrents added to the debrid service.
+//	@desc The AutoDownloader downloads the files once the torrent is ready and places them in the rule's destination.
+//	@route /api/v1/auto-downloader/debrid-downloads [GET]
+//	@returns []models.AutoDownloaderDebridDownload
+func HandleGetAutoDownloaderDebridDownloads(c *RouteCtx) error {
+	downloads, err := c.App.Database.GetAutoDownloaderDebridDownloads()
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(downloads)
+}
+
+// HandleStartAutoDownloaderDebridDownload
+//
+//	@summary starts a paused debrid download.
+//	@desc Downloads are paused when "Download automatically" is disabled.
+//	@desc The files are downloaded once the torrent is ready on the debrid service.
+//	@route /api/v1/auto-downloader/debrid-download/start [POST]
+//	@returns bool
+func HandleStartAutoDownloaderDebridDownload(c *RouteCtx) error {
+
+	type body struct {
+		ID uint `json:"id"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if err := c.App.AutoDownloader.StartDebridDownload(b.ID); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(true)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// HandleGetAutoDownloaderFeeds
+//
+//	@summary returns all RSS and Atom feeds.
+//	@desc Enabled feeds are used by the AutoDownloader as sources, in addition to the torrent provider.
+//	@route /api/v1/auto-downloader/feeds [GET]
+//	@returns []models.AutoDownloaderFeed
+func HandleGetAutoDownloaderFeeds(c *RouteCtx) error {
+	feeds, err := c.App.Database.GetAutoDownloaderFeeds()
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(feeds)
+}
+
+// HandleCreateAutoDownloaderFeed
+//
+//	@summary creates a new feed.
+//	@desc It returns the created feed.
+//	@route /api/v1/auto-downloader/feed [POST]
+//	@returns models.AutoDownloaderFeed
+func HandleCreateAutoDownloaderFeed(c *RouteCtx) error {
+
+	var b models.AutoDownloaderFeed
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if err := validateAutoDownloaderFeed(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	b.ID = 0
+	if err := c.App.Database.InsertAutoDownloaderFeed(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(&b)
+}
+
+// HandleUpdateAutoDownloaderFeed
+//
+//	@summary updates a feed.
+//	@desc It returns the updated feed.
+//	@route /api/v1/auto-downloader/feed [PATCH]
+//	@returns models.AutoDownloaderFeed
+func HandleUpdateAutoDownloaderFeed(c *RouteCtx) error {
+
+	var b models.AutoDownloaderFeed
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if b.ID == 0 {
+		return c.RespondWithError(errors.New("invalid id"))
+	}
+
+	if err := validateAutoDownloaderFeed(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	existing, err := c.App.Database.GetAutoDownloaderFeed(b.ID)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+	b.CreatedAt = existing.CreatedAt
+
+	if err := c.App.Database.UpdateAutoDownloaderFeed(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(&b)
+}
+
+// HandleDeleteAutoDownloaderFeed
+//
+//	@summary deletes a feed.
+//	@desc It returns 'true' if the feed was deleted.
+//	@route /api/v1/auto-downloader/feed/{id} [DELETE]
+//	@param id - int - true - "The DB id of the feed"
+//	@returns bool
+func HandleDeleteAutoDownloaderFeed(c *RouteCtx) error {
+	id, err := strconv.Atoi(c.Fiber.Params("id"))
+	if err != nil {
+		return c.RespondWithError(errors.New("invalid id"))
+	}
+
+	if err := c.App.Database.DeleteAutoDownloaderFeed(uint(id)); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(true)
+}
+
+// HandlePreviewAutoDownloaderFeed
+//
+//	@summary fetches a feed and returns its torrents.
+//	@desc The feed does not need to be saved, this is used to check the URL, cookies and headers.
+//	@route /api/v1/auto-downloader/feed/preview [POST]
+//	@returns []hibiketorrent.AnimeTorrent
+func HandlePreviewAutoDownloaderFeed(c *RouteCtx) error {
+
+	var b models.AutoDownloaderFeed
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if err := validateAutoDownloaderFeed(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	torrents, err := c.App.AutoDownloader.PreviewFeed(&b)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(torrents)
+}
+
+func validateAutoDownloaderFeed(feed *models.AutoDownloaderFeed) error {
+	if feed.URL == "" {
+		return errors.New("url is required")
+	}
+	u, err := url.Parse(feed.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("url must be an http or https url")
+	}
+	if feed.Name == "" {
+		feed.Name = u.Host
+	}
+	return nil
+}
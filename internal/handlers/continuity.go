@@ -1,6 +1,12 @@
 package handlers
 
-import "seanime/internal/continuity"
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"seanime/internal/auth"
+	"seanime/internal/continuity"
+	"strconv"
+)
 
 // HandleUpdateContinuityWatchHistoryItem
 //
@@ -19,7 +25,7 @@ func HandleUpdateContinuityWatchHistoryItem(c *RouteCtx) error {
 		return c.RespondWithError(err)
 	}
 
-	err := c.App.ContinuityManager.UpdateWatchHistoryItem(&b.Options)
+	err := c.App.ContinuityManager.UpdateProfileWatchHistoryItem(c.ProfileID(), &b.Options)
 	if err != nil {
 		// Ignore the error
 		return c.RespondWithData(false)
@@ -48,7 +54,7 @@ func HandleGetContinuityWatchHistoryItem(c *RouteCtx) error {
 		})
 	}
 
-	resp := c.App.ContinuityManager.GetWatchHistoryItem(id)
+	resp := c.App.ContinuityManager.GetProfileWatchHistoryItem(c.ProfileID(), id)
 	return c.RespondWithData(resp)
 }
 
@@ -64,6 +70,53 @@ func HandleGetContinuityWatchHistory(c *RouteCtx) error {
 		return c.RespondWithData(ret)
 	}
 
-	resp := c.App.ContinuityManager.GetWatchHistory()
+	resp := c.App.ContinuityManager.GetProfileWatchHistory(c.ProfileID())
 	return c.RespondWithData(resp)
 }
+
+// HandleContinuityPeerSync
+//
+//	@summary merges the watch history sent by another instance.
+//	@desc This endpoint is called by the peer instance, it is authenticated with the shared secret sent in the X-Seanime-Sync-Secret header.
+//	@desc Clients are locked out for an increasing duration after too many invalid secrets, a 429 status is returned.
+//	@desc The items are merged by keeping the most recently updated ones, the merged watch history is returned.
+//	@route /api/v1/continuity/sync [POST]
+//	@returns continuity.WatchHistory
+func HandleContinuityPeerSync(c *RouteCtx) error {
+	var b continuity.PeerSyncRequest
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	history, err := c.App.ContinuityManager.HandlePeerSync(c.Fiber.Get(continuity.PeerSyncSecretHeader), c.Fiber.IP(), b.History)
+	if err != nil {
+		if errors.Is(err, continuity.ErrPeerSyncUnauthorized) {
+			return c.Fiber.Status(fiber.StatusUnauthorized).JSON(NewErrorResponse(err))
+		}
+		var tooManyAttempts *auth.TooManyAttemptsError
+		if errors.As(err, &tooManyAttempts) {
+			c.Fiber.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(tooManyAttempts.RetryAfter.Seconds())+1))
+			return c.Fiber.Status(fiber.StatusTooManyRequests).JSON(NewErrorResponse(err))
+		}
+		if errors.Is(err, continuity.ErrPeerSyncDisabled) {
+			return c.Fiber.Status(fiber.StatusForbidden).JSON(NewErrorResponse(err))
+		}
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(history)
+}
+
+// HandleContinuitySyncWithPeer
+//
+//	@summary syncs the watch history with the peer instance.
+//	@desc The watch history is also synced periodically and after each update when the peer sync is enabled.
+//	@route /api/v1/continuity/sync/now [POST]
+//	@returns bool
+func HandleContinuitySyncWithPeer(c *RouteCtx) error {
+	if err := c.App.ContinuityManager.SyncWithPeer(c.Fiber.Context()); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(true)
+}
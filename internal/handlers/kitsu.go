@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"seanime/internal/api/kitsu"
+)
+
+// HandleKitsuAuth
+//
+//	@summary logs the user in to Kitsu.
+//	@desc Kitsu uses the password grant, the credentials are not stored, only the access and refresh tokens are.
+//	@desc It will save the info in the database, effectively logging the user in.
+//	@desc The client should re-fetch the server status after this.
+//	@route /api/v1/kitsu/auth [POST]
+//	@returns models.Kitsu
+func HandleKitsuAuth(c *RouteCtx) error {
+
+	type body struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	b := new(body)
+	if err := c.Fiber.BodyParser(b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if b.Username == "" || b.Password == "" {
+		return c.RespondWithError(errors.New("username and password are required"))
+	}
+
+	kitsuInfo, err := kitsu.Login(b.Username, b.Password, c.App.Logger)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	kitsuInfo, err = c.App.Database.UpsertKitsuInfo(kitsuInfo)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	// Fetch the collections if Kitsu is the tracking platform
+	if c.App.GetTrackingPlatform() == "kitsu" {
+		go c.App.InitOrRefreshAnilistData()
+	}
+
+	kitsuInfo.AccessToken = "HIDDEN"
+	kitsuInfo.RefreshToken = "HIDDEN"
+
+	return c.RespondWithData(kitsuInfo)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// HandleKitsuLogout
+//
+//	@summary logs the user out of Kitsu.
+//	@desc This will delete the Kitsu info from the database, effectively logging the user out.
+//	@desc The client should re-fetch the server status after this.
+//	@route /api/v1/kitsu/logout [POST]
+//	@returns bool
+func HandleKitsuLogout(c *RouteCtx) error {
+
+	err := c.App.Database.DeleteKitsuInfo()
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(true)
+}
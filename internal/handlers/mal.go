@@ -87,6 +87,11 @@ func HandleMALAuth(c *RouteCtx) error {
 		return c.RespondWithError(err)
 	}
 
+	// Fetch the collections if MAL is the tracking platform
+	if c.App.GetTrackingPlatform() == "mal" {
+		go c.App.InitOrRefreshAnilistData()
+	}
+
 	return c.RespondWithData(ret)
 }
 
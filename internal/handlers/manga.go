@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
 	"seanime/internal/api/anilist"
 	"seanime/internal/manga"
+	util2 "seanime/internal/util/proxies"
 	"seanime/internal/util/result"
 	"time"
 )
@@ -30,7 +33,7 @@ func HandleGetAnilistMangaCollection(c *RouteCtx) error {
 		return c.RespondWithError(err)
 	}
 
-	collection, err := c.App.GetMangaCollection(b.BypassCache)
+	collection, err := c.Profile().GetMangaCollection(b.BypassCache)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -48,7 +51,7 @@ func HandleGetRawAnilistMangaCollection(c *RouteCtx) error {
 	bypassCache := c.Fiber.Method() == "POST"
 
 	// Get the user's anilist collection
-	mangaCollection, err := c.App.GetRawMangaCollection(bypassCache)
+	mangaCollection, err := c.Profile().GetRawMangaCollection(bypassCache)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -66,14 +69,14 @@ func HandleGetRawAnilistMangaCollection(c *RouteCtx) error {
 //	@returns manga.Collection
 func HandleGetMangaCollection(c *RouteCtx) error {
 
-	animeCollection, err := c.App.GetMangaCollection(false)
+	animeCollection, err := c.Profile().GetMangaCollection(false)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
 
 	collection, err := manga.NewCollection(&manga.NewCollectionOptions{
 		MangaCollection: animeCollection,
-		Platform:        c.App.AnilistPlatform,
+		Platform:        c.Profile().AnilistPlatform,
 	})
 	if err != nil {
 		return c.RespondWithError(err)
@@ -96,7 +99,7 @@ func HandleGetMangaEntry(c *RouteCtx) error {
 		return c.RespondWithError(err)
 	}
 
-	animeCollection, err := c.App.GetMangaCollection(false)
+	animeCollection, err := c.Profile().GetMangaCollection(false)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -105,7 +108,7 @@ func HandleGetMangaEntry(c *RouteCtx) error {
 		MediaId:         id,
 		Logger:          c.App.Logger,
 		FileCacher:      c.App.FileCacher,
-		Platform:        c.App.AnilistPlatform,
+		Platform:        c.Profile().AnilistPlatform,
 		MangaCollection: animeCollection,
 	})
 	if err != nil {
@@ -137,7 +140,7 @@ func HandleGetMangaEntryDetails(c *RouteCtx) error {
 		return c.RespondWithData(detailsMedia)
 	}
 
-	details, err := c.App.AnilistPlatform.GetMangaDetails(id)
+	details, err := c.Profile().AnilistPlatform.GetMangaDetails(id)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -197,7 +200,7 @@ func HandleGetMangaEntryChapters(c *RouteCtx) error {
 	baseManga, found := baseMangaCache.Get(b.MediaId)
 	if !found {
 		var err error
-		baseManga, err = c.App.AnilistPlatform.GetManga(b.MediaId)
+		baseManga, err = c.Profile().AnilistPlatform.GetManga(b.MediaId)
 		if err != nil {
 			return c.RespondWithError(err)
 		}
@@ -252,6 +255,200 @@ func HandleGetMangaEntryPages(c *RouteCtx) error {
 	return c.RespondWithData(container)
 }
 
+// HandleGetMangaEntryMergedChapters
+//
+//	@summary returns the chapters of several providers merged into one list.
+//	@desc Chapters are deduplicated by chapter number, the chapter of the first provider in 'providers' that has it is used.
+//	@desc The chapters of the other providers are returned as alternatives.
+//	@desc Providers that fail are listed in 'errors', an error is returned only if all providers fail.
+//	@route /api/v1/manga/merged-chapters [POST]
+//	@returns manga.MergedChapterContainer
+func HandleGetMangaEntryMergedChapters(c *RouteCtx) error {
+
+	type body struct {
+		MediaId   int      `json:"mediaId"`
+		Providers []string `json:"providers"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	baseManga, err := getBaseManga(c, b.MediaId)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	container, err := c.App.MangaRepository.GetMergedChapterContainer(&manga.GetMergedChapterContainerOptions{
+		MediaId:   b.MediaId,
+		Providers: b.Providers,
+		Titles:    baseManga.GetAllTitles(),
+		Year:      baseManga.GetStartYearSafe(),
+	})
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(container)
+}
+
+// HandleGetMangaEntryMergedPages
+//
+//	@summary returns the pages of a chapter from a merged chapter list.
+//	@desc The requested chapter is tried first. If its pages cannot be fetched, the chapters with the same number
+//	@desc from the other providers are tried in order of preference.
+//	@desc The 'provider' of the returned page container is the provider the pages were fetched from.
+//	@route /api/v1/manga/merged-pages [POST]
+//	@returns manga.PageContainer
+func HandleGetMangaEntryMergedPages(c *RouteCtx) error {
+
+	type body struct {
+		MediaId    int      `json:"mediaId"`
+		Providers  []string `json:"providers"`
+		Provider   string   `json:"provider"`
+		ChapterId  string   `json:"chapterId"`
+		DoublePage bool     `json:"doublePage"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	opts := &manga.GetMergedPageContainerOptions{
+		GetMergedChapterContainerOptions: manga.GetMergedChapterContainerOptions{
+			MediaId:   b.MediaId,
+			Providers: b.Providers,
+		},
+		Provider:   b.Provider,
+		ChapterId:  b.ChapterId,
+		DoublePage: b.DoublePage,
+		IsOffline:  c.App.IsOffline(),
+	}
+
+	if !opts.IsOffline {
+		baseManga, err := getBaseManga(c, b.MediaId)
+		if err != nil {
+			return c.RespondWithError(err)
+		}
+		opts.Titles = baseManga.GetAllTitles()
+		opts.Year = baseManga.GetStartYearSafe()
+	}
+
+	container, err := c.App.MangaRepository.GetMergedPageContainer(opts)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(container)
+}
+
+// getBaseManga returns the media from the cache or from AniList.
+func getBaseManga(c *RouteCtx, mediaId int) (*anilist.BaseManga, error) {
+	if baseManga, found := baseMangaCache.Get(mediaId); found {
+		return baseManga, nil
+	}
+
+	baseManga, err := c.Profile().AnilistPlatform.GetManga(mediaId)
+	if err != nil {
+		return nil, err
+	}
+	baseMangaCache.SetT(mediaId, baseManga, 24*time.Hour)
+
+	return baseManga, nil
+}
+
+// HandleGetLocalMangaLibrary
+//
+//	@summary returns the series of the local manga library.
+//	@desc Each series is matched to an entry of the user's manga collection, 'mediaId' is 0 if no entry matched.
+//	@desc The chapters of a series are returned by the 'local-manga' provider through the chapters endpoint.
+//	@route /api/v1/manga/local-library [GET]
+//	@returns []manga.LocalMangaSeries
+func HandleGetLocalMangaLibrary(c *RouteCtx) error {
+	mangaCollection, err := c.Profile().GetMangaCollection(false)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(c.App.MangaRepository.GetLocalMangaLibrary(mangaCollection))
+}
+
+// HandleGetLocalMangaPage
+//
+//	@summary returns a page of a chapter from the local manga library.
+//	@desc The page URLs returned by the 'local-manga' provider point to this endpoint.
+//	@route /api/v1/manga/local-page/{chapterId}/{index} [GET]
+//	@param chapterId - string - true - "Chapter ID"
+//	@param index - int - true - "Page index"
+//	@returns bytes
+func HandleGetLocalMangaPage(c *RouteCtx) error {
+	index, err := c.Fiber.ParamsInt("index")
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	data, contentType, err := c.App.MangaRepository.ReadLocalPage(c.Fiber.Params("chapterId"), index)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	c.Fiber.Set(fiber.HeaderContentType, contentType)
+	c.Fiber.Set(fiber.HeaderCacheControl, "private, max-age=86400")
+	return c.Fiber.Send(data)
+}
+
+// HandleGetMangaPageImage
+//
+//	@summary returns a page image through the image cache.
+//	@desc The image is fetched from the provider on the first request and served from the disk cache afterward.
+//	@desc It is downscaled and re-encoded if image optimization is enabled in the manga settings.
+//	@desc 'key' is one of the 'pageKeys' of a page container, only the pages returned by the providers can be requested.
+//	@route /api/v1/manga/image [GET]
+//	@returns bytes
+func HandleGetMangaPageImage(c *RouteCtx) error {
+	key := c.Fiber.Query("key")
+	if key == "" {
+		return c.RespondWithError(errors.New("no key provided"))
+	}
+
+	data, err := c.App.MangaRepository.GetPageImageByKey(key)
+	if err != nil {
+		if errors.Is(err, manga.ErrPageKeyNotFound) {
+			return c.Fiber.Status(fiber.StatusNotFound).JSON(NewErrorResponse(err))
+		}
+		return c.RespondWithError(err)
+	}
+
+	c.Fiber.Set(fiber.HeaderContentType, util2.DetectImageContentType(data))
+	c.Fiber.Set(fiber.HeaderCacheControl, "private, max-age=86400")
+	return c.Fiber.Send(data)
+}
+
+// HandleGetMangaImageCacheStats
+//
+//	@summary returns the size and usage of the page image cache.
+//	@route /api/v1/manga/image-cache [GET]
+//	@returns manga_imagecache.Stats
+func HandleGetMangaImageCacheStats(c *RouteCtx) error {
+	return c.RespondWithData(c.App.MangaRepository.GetImageCacheStats())
+}
+
+// HandlePurgeMangaImageCache
+//
+//	@summary removes all the cached page images.
+//	@desc Downloaded chapters are not affected.
+//	@route /api/v1/manga/image-cache [DELETE]
+//	@returns bool
+func HandlePurgeMangaImageCache(c *RouteCtx) error {
+	if err := c.App.MangaRepository.PurgeImageCache(); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(true)
+}
+
 // HandleGetMangaEntryDownloadedChapters
 //
 //	@summary returns all download chapters for a manga entry,
@@ -265,7 +462,7 @@ func HandleGetMangaEntryDownloadedChapters(c *RouteCtx) error {
 		return c.RespondWithError(err)
 	}
 
-	mangaCollection, err := c.App.GetMangaCollection(false)
+	mangaCollection, err := c.Profile().GetMangaCollection(false)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -384,7 +581,7 @@ func HandleUpdateMangaProgress(c *RouteCtx) error {
 	}
 
 	// Update the progress on AniList
-	err := c.App.AnilistPlatform.UpdateEntryProgress(
+	err := c.Profile().AnilistPlatform.UpdateEntryProgress(
 		b.MediaId,
 		b.ChapterNumber,
 		&b.TotalChapters,
@@ -393,7 +590,7 @@ func HandleUpdateMangaProgress(c *RouteCtx) error {
 		return c.RespondWithError(err)
 	}
 
-	_, _ = c.App.RefreshMangaCollection() // Refresh the AniList collection
+	_, _ = c.Profile().RefreshMangaCollection() // Refresh the AniList collection
 
 	return c.RespondWithData(true)
 }
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"seanime/internal/database/models"
+	"strconv"
+)
+
+// HandleGetMangaAutoDownloaderRules
+//
+//	@summary returns all manga auto downloader rules.
+//	@route /api/v1/manga/auto-downloader/rules [GET]
+//	@returns []models.MangaAutoDownloaderRule
+func HandleGetMangaAutoDownloaderRules(c *RouteCtx) error {
+	rules, err := c.App.Database.GetMangaAutoDownloaderRules()
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(rules)
+}
+
+// HandleCreateMangaAutoDownloaderRule
+//
+//	@summary creates a new manga auto downloader rule.
+//	@desc A manga can only have one rule.
+//	@desc It returns the created rule.
+//	@route /api/v1/manga/auto-downloader/rule [POST]
+//	@returns models.MangaAutoDownloaderRule
+func HandleCreateMangaAutoDownloaderRule(c *RouteCtx) error {
+
+	var b models.MangaAutoDownloaderRule
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if err := validateMangaAutoDownloaderRule(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if _, found := c.App.Database.GetMangaAutoDownloaderRuleByMediaId(b.MediaID); found {
+		return c.RespondWithError(errors.New("a rule already exists for this manga"))
+	}
+
+	b.ID = 0
+	if err := c.App.Database.InsertMangaAutoDownloaderRule(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(&b)
+}
+
+// HandleUpdateMangaAutoDownloaderRule
+//
+//	@summary updates a manga auto downloader rule.
+//	@desc It returns the updated rule.
+//	@route /api/v1/manga/auto-downloader/rule [PATCH]
+//	@returns models.MangaAutoDownloaderRule
+func HandleUpdateMangaAutoDownloaderRule(c *RouteCtx) error {
+
+	var b models.MangaAutoDownloaderRule
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if b.ID == 0 {
+		return c.RespondWithError(errors.New("invalid id"))
+	}
+
+	if err := validateMangaAutoDownloaderRule(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	existing, err := c.App.Database.GetMangaAutoDownloaderRule(b.ID)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+	if existing.MediaID != b.MediaID {
+		return c.RespondWithError(errors.New("the manga of a rule cannot be changed"))
+	}
+	b.CreatedAt = existing.CreatedAt
+
+	if err := c.App.Database.UpdateMangaAutoDownloaderRule(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(&b)
+}
+
+// HandleDeleteMangaAutoDownloaderRule
+//
+//	@summary deletes a manga auto downloader rule.
+//	@desc It returns 'true' if the rule was deleted.
+//	@route /api/v1/manga/auto-downloader/rule/{id} [DELETE]
+//	@param id - int - true - "The DB id of the rule"
+//	@returns bool
+func HandleDeleteMangaAutoDownloaderRule(c *RouteCtx) error {
+	id, err := strconv.Atoi(c.Fiber.Params("id"))
+	if err != nil {
+		return c.RespondWithError(errors.New("invalid id"))
+	}
+
+	if err := c.App.Database.DeleteMangaAutoDownloaderRule(uint(id)); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(true)
+}
+
+// HandleRunMangaAutoDownloader
+//
+//	@summary checks the manga auto downloader rules and queues new chapters.
+//	@desc This is run periodically, it can be called to check for new chapters immediately.
+//	@desc It returns the chapters that were added to the download queue.
+//	@route /api/v1/manga/auto-downloader/run [POST]
+//	@returns []manga.AutoDownloaderQueuedChapters
+func HandleRunMangaAutoDownloader(c *RouteCtx) error {
+	mangaCollection, err := c.Profile().GetMangaCollection(false)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	queued, err := c.App.MangaDownloader.RunAutoDownloader(mangaCollection)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(queued)
+}
+
+func validateMangaAutoDownloaderRule(rule *models.MangaAutoDownloaderRule) error {
+	if rule.MediaID == 0 {
+		return errors.New("media id is required")
+	}
+	if rule.Provider == "" {
+		return errors.New("provider is required")
+	}
+	if rule.LastQueuedChapter < 0 {
+		rule.LastQueuedChapter = 0
+	}
+	return nil
+}
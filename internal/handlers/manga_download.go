@@ -4,6 +4,7 @@ import (
 	"seanime/internal/events"
 	"seanime/internal/manga"
 	"seanime/internal/manga/downloader"
+	"seanime/internal/manga/exporter"
 	"time"
 )
 
@@ -192,7 +193,7 @@ func HandleDeleteMangaDownloadedChapters(c *RouteCtx) error {
 //	@returns []manga.DownloadListItem
 func HandleGetMangaDownloadsList(c *RouteCtx) error {
 
-	mangaCollection, err := c.App.GetMangaCollection(false)
+	mangaCollection, err := c.Profile().GetMangaCollection(false)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -206,3 +207,66 @@ func HandleGetMangaDownloadsList(c *RouteCtx) error {
 
 	return c.RespondWithData(res)
 }
+
+// HandleExportMangaChapters
+//
+//	@summary exports downloaded chapters as CBZ or EPUB files.
+//	@desc This adds an export job to the queue. If no chapter IDs are provided, all downloaded chapters of the provider are exported.
+//	@desc If 'groupByVolume' is true, chapters with a known volume are packaged together, other chapters are exported individually.
+//	@desc The exported files are served under '/manga-downloads/exports'.
+//	@route /api/v1/manga/export [POST]
+//	@returns manga.ExportJob
+func HandleExportMangaChapters(c *RouteCtx) error {
+
+	type body struct {
+		MediaId       int                   `json:"mediaId"`
+		Provider      string                `json:"provider"`
+		ChapterIds    []string              `json:"chapterIds"`
+		Format        manga_exporter.Format `json:"format"`
+		GroupByVolume bool                  `json:"groupByVolume"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	baseManga, err := getBaseManga(c, b.MediaId)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	job, err := c.App.MangaDownloader.ExportChapters(&manga.ExportChaptersOptions{
+		MediaId:       b.MediaId,
+		Provider:      b.Provider,
+		ChapterIds:    b.ChapterIds,
+		Format:        b.Format,
+		GroupByVolume: b.GroupByVolume,
+		Media:         baseManga,
+	})
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(job)
+}
+
+// HandleGetMangaExportJobs
+//
+//	@summary returns the export jobs, most recent first.
+//	@route /api/v1/manga/exports [GET]
+//	@returns []manga.ExportJob
+func HandleGetMangaExportJobs(c *RouteCtx) error {
+	return c.RespondWithData(c.App.MangaDownloader.GetExportJobs())
+}
+
+// HandleClearFinishedMangaExportJobs
+//
+//	@summary removes completed and failed export jobs from the list.
+//	@desc The exported files are not deleted.
+//	@route /api/v1/manga/exports [DELETE]
+//	@returns bool
+func HandleClearFinishedMangaExportJobs(c *RouteCtx) error {
+	c.App.MangaDownloader.ClearFinishedExportJobs()
+	return c.RespondWithData(true)
+}
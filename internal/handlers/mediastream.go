@@ -3,8 +3,10 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"seanime/internal/database/db_bridge"
 	"seanime/internal/database/models"
 	"seanime/internal/mediastream"
+	"seanime/internal/mediastream/optimizer"
 )
 
 // HandleGetMediastreamSettings
@@ -133,6 +135,166 @@ func HandleMediastreamGetAttachments(c *RouteCtx) error {
 	return c.App.MediastreamRepository.ServeFiberExtractedAttachments(c.Fiber)
 }
 
+//
+// Trickplay
+//
+
+func HandleMediastreamGetTrickplay(c *RouteCtx) error {
+	return c.App.MediastreamRepository.ServeFiberTrickplay(c.Fiber)
+}
+
+// HandleGetMediastreamTrickplayStatus
+//
+//	@summary returns the state of the trickplay generation.
+//	@desc This returns the file being processed, the number of pending files and the size of the generated files.
+//	@returns mediastream.TrickplayStatus
+//	@route /api/v1/mediastream/trickplay/status [GET]
+func HandleGetMediastreamTrickplayStatus(c *RouteCtx) error {
+	return c.RespondWithData(c.App.MediastreamRepository.GetTrickplayStatus())
+}
+
+// HandleGenerateMediastreamLibraryTrickplay
+//
+//	@summary generates the trickplay files for the whole library.
+//	@desc This queues all local files for trickplay generation in the background.
+//	@desc Files that already have trickplay files are skipped.
+//	@desc An events.MediastreamTrickplayReady event is sent each time a file is processed.
+//	@returns bool
+//	@route /api/v1/mediastream/trickplay/generate-library [POST]
+func HandleGenerateMediastreamLibraryTrickplay(c *RouteCtx) error {
+	lfs, _, err := db_bridge.GetLocalFiles(c.App.Database)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	paths := make([]string, 0, len(lfs))
+	for _, lf := range lfs {
+		paths = append(paths, lf.GetPath())
+	}
+
+	if err := c.App.MediastreamRepository.QueueTrickplay(paths, false); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(true)
+}
+
+// HandleCancelMediastreamTrickplay
+//
+//	@summary cancels the trickplay generation.
+//	@desc This empties the queue and stops the generation of the current file.
+//	@returns bool
+//	@route /api/v1/mediastream/trickplay/cancel [POST]
+func HandleCancelMediastreamTrickplay(c *RouteCtx) error {
+	c.App.MediastreamRepository.CancelTrickplay()
+	return c.RespondWithData(true)
+}
+
+// HandleQueueMediastreamPreTranscode
+//
+//	@summary queues files to be pre-transcoded.
+//	@desc This queues the local files of a series, or of the whole library if 'all' is true.
+//	@desc Files that are already queued or pre-transcoded in the same quality are skipped.
+//	@desc Jobs only run within the time window set in the settings and are resumed after a restart.
+//	@returns int
+//	@route /api/v1/mediastream/pre-transcode [POST]
+func HandleQueueMediastreamPreTranscode(c *RouteCtx) error {
+
+	type body struct {
+		MediaId int               `json:"mediaId"` // The media ID of the series to pre-transcode.
+		All     bool              `json:"all"`     // Pre-transcode the whole library.
+		Quality optimizer.Quality `json:"quality"` // Uses the default quality if empty.
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if b.MediaId == 0 && !b.All {
+		return c.RespondWithError(errors.New("no media specified"))
+	}
+
+	lfs, _, err := db_bridge.GetLocalFiles(c.App.Database)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	files := make([]*optimizer.QueueJobOptions, 0, len(lfs))
+	for _, lf := range lfs {
+		if !b.All && lf.MediaId != b.MediaId {
+			continue
+		}
+		files = append(files, &optimizer.QueueJobOptions{
+			Path:    lf.GetPath(),
+			MediaID: lf.MediaId,
+		})
+	}
+
+	added, err := c.App.MediastreamRepository.QueuePreTranscodeJobs(&mediastream.QueuePreTranscodeJobsOptions{
+		Files:   files,
+		Quality: b.Quality,
+	})
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(added)
+}
+
+// HandleGetMediastreamPreTranscodeJobs
+//
+//	@summary returns the pre-transcode jobs.
+//	@desc The progress of the running job is also sent through the events.MediastreamPreTranscodeProgress event.
+//	@returns []models.MediastreamPreTranscodeJob
+//	@route /api/v1/mediastream/pre-transcode/jobs [GET]
+func HandleGetMediastreamPreTranscodeJobs(c *RouteCtx) error {
+	jobs, err := c.App.MediastreamRepository.GetPreTranscodeJobs()
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(jobs)
+}
+
+// HandleCancelMediastreamPreTranscodeJob
+//
+//	@summary cancels a pre-transcode job.
+//	@desc This stops the job if it is running.
+//	@returns bool
+//	@route /api/v1/mediastream/pre-transcode/cancel [POST]
+func HandleCancelMediastreamPreTranscodeJob(c *RouteCtx) error {
+
+	type body struct {
+		ID uint `json:"id"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if err := c.App.MediastreamRepository.CancelPreTranscodeJob(b.ID); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(true)
+}
+
+// HandleClearMediastreamPreTranscodeJobs
+//
+//	@summary removes the finished pre-transcode jobs.
+//	@desc This removes the completed, failed and canceled jobs. The pre-transcoded files are kept.
+//	@returns bool
+//	@route /api/v1/mediastream/pre-transcode/jobs [DELETE]
+func HandleClearMediastreamPreTranscodeJobs(c *RouteCtx) error {
+	if err := c.App.MediastreamRepository.ClearFinishedPreTranscodeJobs(); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(true)
+}
+
 //
 // Direct
 //
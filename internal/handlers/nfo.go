@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"seanime/internal/database/models"
+	"seanime/internal/library/nfo"
+)
+
+// HandleGetNfoSettings
+//
+//	@summary returns the NFO export settings.
+//	@returns models.NfoSettings
+//	@route /api/v1/library/nfo/settings [GET]
+func HandleGetNfoSettings(c *RouteCtx) error {
+	settings, found := c.App.Database.GetNfoSettings()
+	if !found {
+		// Return the defaults
+		return c.RespondWithData(&models.NfoSettings{
+			BaseModel:       models.BaseModel{ID: 1},
+			Mode:            nfo.ModeAlongside,
+			DownloadArtwork: true,
+		})
+	}
+
+	return c.RespondWithData(settings)
+}
+
+// HandleSaveNfoSettings
+//
+//	@summary saves the NFO export settings.
+//	@desc If enabled, the NFO files and artwork are exported after each scan.
+//	@returns models.NfoSettings
+//	@route /api/v1/library/nfo/settings [PATCH]
+func HandleSaveNfoSettings(c *RouteCtx) error {
+
+	type body struct {
+		Settings models.NfoSettings `json:"settings"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	switch b.Settings.Mode {
+	case nfo.ModeAlongside:
+	case nfo.ModeMirror:
+		if err := c.App.NfoExporter.ValidateMirrorDir(b.Settings.MirrorDir); err != nil {
+			return c.RespondWithError(err)
+		}
+	default:
+		return c.RespondWithError(nfo.ErrInvalidMode)
+	}
+
+	b.Settings.ID = 1
+
+	settings, err := c.App.Database.UpsertNfoSettings(&b.Settings)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(settings)
+}
+
+// HandleExportNfo
+//
+//	@summary exports the NFO files and artwork of the matched library.
+//	@desc The saved settings are used, whether the automatic export is enabled or not.
+//	@desc NFO files that were not created by Seanime are never modified.
+//	@returns nfo.ExportResult
+//	@route /api/v1/library/nfo/export [POST]
+func HandleExportNfo(c *RouteCtx) error {
+	settings, found := c.App.Database.GetNfoSettings()
+	if !found {
+		settings = &models.NfoSettings{
+			Mode:            nfo.ModeAlongside,
+			DownloadArtwork: true,
+		}
+	}
+
+	res, err := c.App.NfoExporter.Export(settings)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(res)
+}
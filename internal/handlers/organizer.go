@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"errors"
+	"seanime/internal/database/models"
+	"seanime/internal/events"
+	"seanime/internal/library/organizer"
+)
+
+// HandleGetOrganizerSettings
+//
+//	@summary returns the library organizer settings.
+//	@returns models.OrganizerSettings
+//	@route /api/v1/library/organizer/settings [GET]
+func HandleGetOrganizerSettings(c *RouteCtx) error {
+	settings, found := c.App.Database.GetOrganizerSettings()
+	if !found {
+		// Return the defaults
+		return c.RespondWithData(&models.OrganizerSettings{
+			BaseModel: models.BaseModel{ID: 1},
+			Template:  organizer.DefaultTemplate,
+			Mode:      organizer.ModeMove,
+		})
+	}
+
+	return c.RespondWithData(settings)
+}
+
+// HandleSaveOrganizerSettings
+//
+//	@summary saves the library organizer settings.
+//	@desc The template is validated before being saved.
+//	@returns models.OrganizerSettings
+//	@route /api/v1/library/organizer/settings [PATCH]
+func HandleSaveOrganizerSettings(c *RouteCtx) error {
+
+	type body struct {
+		Settings models.OrganizerSettings `json:"settings"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if _, err := organizer.ParseTemplate(b.Settings.Template); err != nil {
+		return c.RespondWithError(err)
+	}
+	switch b.Settings.Mode {
+	case organizer.ModeMove, organizer.ModeHardlink, organizer.ModeSymlink:
+	default:
+		return c.RespondWithError(organizer.ErrInvalidMode)
+	}
+
+	b.Settings.ID = 1
+
+	settings, err := c.App.Database.UpsertOrganizerSettings(&b.Settings)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(settings)
+}
+
+// HandleGetOrganizerPlan
+//
+//	@summary returns what the organizer would do, without modifying anything.
+//	@desc If settings are sent, they are used instead of the saved settings so that a template can be previewed.
+//	@returns organizer.Plan
+//	@route /api/v1/library/organizer/plan [POST]
+func HandleGetOrganizerPlan(c *RouteCtx) error {
+
+	type body struct {
+		Settings *models.OrganizerSettings `json:"settings"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	opts, err := getOrganizerPlanOptions(c, b.Settings)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	plan, err := c.App.Organizer.Plan(opts)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(plan)
+}
+
+// HandleRunOrganizer
+//
+//	@summary organizes the library using the saved settings.
+//	@desc The plan is computed again and only the files whose status is "ready" are organized.
+//	@desc If any operation fails, all changes are reverted.
+//	@desc The local files of moved files are updated, no rescan is needed.
+//	@desc Links are created outside the library and the local files keep pointing to the original files.
+//	@returns organizer.Plan
+//	@route /api/v1/library/organizer/run [POST]
+func HandleRunOrganizer(c *RouteCtx) error {
+
+	opts, err := getOrganizerPlanOptions(c, nil)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	plan, _, err := c.App.Organizer.Execute(opts)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	c.App.WSEventManager.SendEvent(events.LibraryOrganized, nil)
+
+	// The NFO files follow the media files
+	go c.App.NfoExporter.ExportAfterScan()
+
+	return c.RespondWithData(plan)
+}
+
+// HandleGetOrganizerLogs
+//
+//	@summary returns the most recent organizer runs.
+//	@returns []models.OrganizerLog
+//	@route /api/v1/library/organizer/logs [GET]
+func HandleGetOrganizerLogs(c *RouteCtx) error {
+	logs, err := c.App.Database.GetOrganizerLogs(20)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(logs)
+}
+
+// HandleUndoOrganizerRun
+//
+//	@summary reverts an organizer run.
+//	@desc Moved files are moved back, links are removed and the local files are restored.
+//	@returns bool
+//	@route /api/v1/library/organizer/undo [POST]
+func HandleUndoOrganizerRun(c *RouteCtx) error {
+
+	type body struct {
+		ID uint `json:"id"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if err := c.App.Organizer.Undo(b.ID); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	c.App.WSEventManager.SendEvent(events.LibraryOrganized, nil)
+
+	// The NFO files follow the media files
+	go c.App.NfoExporter.ExportAfterScan()
+
+	return c.RespondWithData(true)
+}
+
+func getOrganizerPlanOptions(c *RouteCtx, settings *models.OrganizerSettings) (*organizer.PlanOptions, error) {
+	if settings == nil {
+		var found bool
+		settings, found = c.App.Database.GetOrganizerSettings()
+		if !found {
+			return nil, errors.New("organizer settings not found, save them first")
+		}
+	}
+
+	libraryPath := ""
+	libraryPaths := make([]string, 0)
+	if c.App.Settings != nil && c.App.Settings.Library != nil {
+		libraryPath = c.App.Settings.Library.LibraryPath
+		libraryPaths = c.App.Settings.Library.GetLibraryPaths()
+	}
+
+	animeCollection, _ := c.App.GetAnimeCollection(false)
+
+	return &organizer.PlanOptions{
+		Settings:        settings,
+		LibraryPath:     libraryPath,
+		LibraryPaths:    libraryPaths,
+		AnimeCollection: animeCollection,
+	}, nil
+}
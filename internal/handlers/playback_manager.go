@@ -138,7 +138,7 @@ func HandlePlaybackStartPlaylist(c *RouteCtx) error {
 	}
 
 	// Get playlist
-	playlist, err := db_bridge.GetPlaylist(c.App.Database, b.DbId)
+	playlist, err := db_bridge.GetPlaylist(c.App.Database, c.ProfileID(), b.DbId)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
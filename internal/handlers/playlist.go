@@ -49,7 +49,7 @@ func HandleCreatePlaylist(c *RouteCtx) error {
 	playlist.SetLocalFiles(lfs)
 
 	// Save the playlist
-	if err := db_bridge.SavePlaylist(c.App.Database, playlist); err != nil {
+	if err := db_bridge.SavePlaylist(c.App.Database, c.ProfileID(), playlist); err != nil {
 		return c.RespondWithError(err)
 	}
 
@@ -63,7 +63,7 @@ func HandleCreatePlaylist(c *RouteCtx) error {
 //	@returns []anime.Playlist
 func HandleGetPlaylists(c *RouteCtx) error {
 
-	playlists, err := db_bridge.GetPlaylists(c.App.Database)
+	playlists, err := db_bridge.GetPlaylists(c.App.Database, c.ProfileID())
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -116,7 +116,7 @@ func HandleUpdatePlaylist(c *RouteCtx) error {
 	playlist.SetLocalFiles(lfs)
 
 	// Save the playlist
-	if err := db_bridge.UpdatePlaylist(c.App.Database, playlist); err != nil {
+	if err := db_bridge.UpdatePlaylist(c.App.Database, c.ProfileID(), playlist); err != nil {
 		return c.RespondWithError(err)
 	}
 
@@ -140,7 +140,7 @@ func HandleDeletePlaylist(c *RouteCtx) error {
 
 	}
 
-	if err := db_bridge.DeletePlaylist(c.App.Database, b.DbId); err != nil {
+	if err := db_bridge.DeletePlaylist(c.App.Database, c.ProfileID(), b.DbId); err != nil {
 		return c.RespondWithError(err)
 	}
 
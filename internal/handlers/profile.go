@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/samber/lo"
+	"seanime/internal/auth"
+	"seanime/internal/continuity"
+	"seanime/internal/core"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/events"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ProfileCookieName = "Seanime-Profile"
+	ProfileHeaderName = "X-Seanime-Profile"
+)
+
+type Profile struct {
+	*models.Profile
+	// HasPin is true if a PIN is required to select the profile
+	HasPin bool `json:"hasPin"`
+}
+
+func newProfile(p *models.Profile) *Profile {
+	return &Profile{
+		Profile: p,
+		HasPin:  p.PinHash != "",
+	}
+}
+
+// profileLocalsKey is the key of the profile ID resolved by profileMiddleware in the request locals.
+const profileLocalsKey = "Seanime-Profile-Id"
+
+// profileMiddleware resolves the profile of the client that made the request, see RouteCtx.Profile.
+// The client sends the session token it received when selecting a profile.
+// Clients that never selected a profile use the server profile, unless it has a PIN since it could be bypassed
+// by not sending a session. They are then asked to select their profile.
+func profileMiddleware(app *core.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !strings.HasPrefix(strings.ToLower(c.Path()), "/api/") {
+			return c.Next()
+		}
+
+		id := resolveProfile(app, c)
+		c.Locals(profileLocalsKey, id)
+
+		if id == 0 && isProfileIsolated(c.Path()) {
+			return c.Status(fiber.StatusConflict).JSON(NewErrorResponse(errors.New("select your profile")))
+		}
+
+		return c.Next()
+	}
+}
+
+// resolveProfile returns the profile of the client, 0 if the client has to select a profile.
+func resolveProfile(app *core.App, c *fiber.Ctx) uint {
+	token := c.Get(ProfileHeaderName)
+	if token == "" {
+		token = c.Cookies(ProfileCookieName)
+	}
+	if token != "" {
+		// The session is revoked when the PIN changes or when the server restarts
+		id, _ := app.GetProfileSession(token)
+		return id
+	}
+
+	serverProfile, err := app.Database.GetProfile(app.Database.GetProfileID())
+	if err != nil || serverProfile.PinHash != "" {
+		return 0
+	}
+	return serverProfile.ID
+}
+
+// ProfileID returns the profile of the client that made the request.
+// It is 0 on the routes that can be used without selecting a profile if the client has not selected one.
+func (c *RouteCtx) ProfileID() uint {
+	id, _ := c.Fiber.Locals(profileLocalsKey).(uint)
+	return id
+}
+
+// Profile returns the scope of the profile of the client that made the request.
+// It should be used instead of the app's AniList platform and collections to read and update the lists of the client.
+func (c *RouteCtx) Profile() *core.ProfileScope {
+	return c.App.GetProfileScope(c.ProfileID())
+}
+
+// profileExemptPaths are the API routes that can be used without selecting a profile.
+var profileExemptPaths = []string{
+	"/api/v1/profiles",
+	"/api/v1/server-auth",
+	"/api/v1/status",
+}
+
+// isProfileIsolated returns true if the path requires the client to have a profile.
+// The path is normalized the same way Fiber matches routes: case-insensitively and without trailing slashes,
+// e.g. "/API/v1/settings/" is handled by "/api/v1/settings".
+func isProfileIsolated(path string) bool {
+	path = strings.ToLower(path)
+	if len(path) > 1 {
+		path = strings.TrimRight(path, "/")
+	}
+	// The continuity peer sync is made by another instance, it is bound to the profile that configured it
+	if !strings.HasPrefix(path, "/api/v1/") || path == continuity.PeerSyncPath {
+		return false
+	}
+	for _, p := range profileExemptPaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// HandleGetProfiles
+//
+//	@summary returns all profiles.
+//	@route /api/v1/profiles [GET]
+//	@returns []handlers.Profile
+func HandleGetProfiles(c *RouteCtx) error {
+	profiles, err := c.App.Database.GetProfiles()
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(lo.Map(profiles, func(p *models.Profile, _ int) *Profile {
+		return newProfile(p)
+	}))
+}
+
+// HandleCreateProfile
+//
+//	@summary creates a new profile.
+//	@desc The profile starts without an AniList account, watch history, playlists or theme.
+//	@desc The scanned library is shared by all profiles.
+//	@route /api/v1/profiles [POST]
+//	@returns handlers.Profile
+func HandleCreateProfile(c *RouteCtx) error {
+
+	type body struct {
+		Name   string `json:"name"`
+		Avatar string `json:"avatar"`
+		Pin    string `json:"pin"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if strings.TrimSpace(b.Name) == "" {
+		return c.RespondWithError(errors.New("name is required"))
+	}
+
+	profile := &models.Profile{
+		Name:   strings.TrimSpace(b.Name),
+		Avatar: b.Avatar,
+	}
+	if err := core.SetProfilePin(profile, b.Pin); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if err := c.App.Database.InsertProfile(profile); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(newProfile(profile))
+}
+
+// HandleUpdateProfile
+//
+//	@summary updates the name, avatar or PIN of a profile.
+//	@desc The current PIN is required to change the PIN, an empty PIN removes it.
+//	@route /api/v1/profiles [PATCH]
+//	@returns handlers.Profile
+func HandleUpdateProfile(c *RouteCtx) error {
+
+	type body struct {
+		ID         uint    `json:"id"`
+		Name       *string `json:"name"`
+		Avatar     *string `json:"avatar"`
+		CurrentPin string  `json:"currentPin"`
+		Pin        *string `json:"pin"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	profile, err := c.App.Database.GetProfile(b.ID)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if b.Name != nil {
+		if strings.TrimSpace(*b.Name) == "" {
+			return c.RespondWithError(errors.New("name is required"))
+		}
+		profile.Name = strings.TrimSpace(*b.Name)
+	}
+	if b.Avatar != nil {
+		profile.Avatar = *b.Avatar
+	}
+	if b.Pin != nil {
+		if err := c.App.VerifyProfilePin(profile, b.CurrentPin, c.Fiber.IP()); err != nil {
+			return respondWithPinError(c, err)
+		}
+		if err := core.SetProfilePin(profile, *b.Pin); err != nil {
+			return c.RespondWithError(err)
+		}
+	}
+
+	if err := c.App.Database.UpdateProfile(profile); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if b.Pin != nil {
+		// The clients have to enter the new PIN
+		c.App.DeleteProfileSessions(profile.ID)
+	}
+
+	return c.RespondWithData(newProfile(profile))
+}
+
+// HandleDeleteProfile
+//
+//	@summary deletes a profile along with its account, theme, playlists and watch history.
+//	@desc The default profile and the server profile cannot be deleted.
+//	@route /api/v1/profiles [DELETE]
+//	@returns bool
+func HandleDeleteProfile(c *RouteCtx) error {
+
+	type body struct {
+		ID  uint   `json:"id"`
+		Pin string `json:"pin"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if b.ID == db.DefaultProfileID {
+		return c.RespondWithError(errors.New("the default profile cannot be deleted"))
+	}
+	if b.ID == c.App.Database.GetProfileID() {
+		return c.RespondWithError(errors.New("the server profile cannot be deleted"))
+	}
+
+	profile, err := c.App.Database.GetProfile(b.ID)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if err := c.App.VerifyProfilePin(profile, b.Pin, c.Fiber.IP()); err != nil {
+		return respondWithPinError(c, err)
+	}
+
+	if err := c.App.Database.DeleteProfile(profile.ID); err != nil {
+		return c.RespondWithError(err)
+	}
+	c.App.DeleteProfileSessions(profile.ID)
+	c.App.DeleteProfileScope(profile.ID)
+
+	if err := c.App.ContinuityManager.DeleteProfileHistory(profile.ID); err != nil {
+		c.App.Logger.Warn().Err(err).Msg("app: Failed to delete watch history of profile")
+	}
+
+	return c.RespondWithData(true)
+}
+
+// HandleSelectProfile
+//
+//	@summary selects the profile used by the client.
+//	@desc The PIN is required if the profile has one.
+//	@desc Clients are locked out for an increasing duration after too many invalid PINs, a 429 status is returned.
+//	@desc The profile session cookie is set, the requests of the client are then served with the account, watch history, playlists, theme and Discord settings of the profile.
+//	@desc The session token is also returned in the X-Seanime-Profile header for clients that do not keep cookies, they should send it back in the same header.
+//	@desc Other clients are not affected. A 409 status is returned to clients that have to select their profile.
+//	@desc The client should re-fetch the server status and its data after this.
+//	@route /api/v1/profiles/select [POST]
+//	@returns handlers.Status
+func HandleSelectProfile(c *RouteCtx) error {
+
+	type body struct {
+		ID  uint   `json:"id"`
+		Pin string `json:"pin"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	profile, err := c.App.Database.GetProfile(b.ID)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if err := c.App.VerifyProfilePin(profile, b.Pin, c.Fiber.IP()); err != nil {
+		return respondWithPinError(c, err)
+	}
+
+	token := c.App.CreateProfileSession(profile.ID)
+	c.Fiber.Cookie(&fiber.Cookie{
+		Name:     ProfileCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().Add(365 * 24 * time.Hour),
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	c.Fiber.Set(ProfileHeaderName, token)
+
+	// The status is the one of the selected profile
+	c.Fiber.Locals(profileLocalsKey, profile.ID)
+
+	return c.RespondWithData(NewStatus(c))
+}
+
+// HandleSetServerProfile
+//
+//	@summary makes a profile the server profile.
+//	@desc The background modules (auto downloader, playback manager, sync, Discord RPC) act on behalf of the server profile.
+//	@desc Clients that did not select a profile use the server profile, unless it has a PIN.
+//	@desc The PIN is required if the profile has one.
+//	@route /api/v1/profiles/server [POST]
+//	@returns handlers.Status
+func HandleSetServerProfile(c *RouteCtx) error {
+
+	type body struct {
+		ID  uint   `json:"id"`
+		Pin string `json:"pin"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	profile, err := c.App.Database.GetProfile(b.ID)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if err := c.App.VerifyProfilePin(profile, b.Pin, c.Fiber.IP()); err != nil {
+		return respondWithPinError(c, err)
+	}
+
+	if profile.ID != c.App.Database.GetProfileID() {
+		if err := c.App.SwitchProfile(profile.ID); err != nil {
+			return c.RespondWithError(err)
+		}
+		c.App.WSEventManager.SendEvent(events.ServerProfileChanged, profile.ID)
+	}
+
+	return c.RespondWithData(NewStatus(c))
+}
+
+// respondWithPinError responds with a 401 status if the PIN is invalid
+// and with a 429 status if the client is locked out after too many invalid PINs.
+func respondWithPinError(c *RouteCtx, err error) error {
+	var tooManyAttempts *auth.TooManyAttemptsError
+	if errors.As(err, &tooManyAttempts) {
+		c.Fiber.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(tooManyAttempts.RetryAfter.Seconds())+1))
+		return c.Fiber.Status(fiber.StatusTooManyRequests).JSON(NewErrorResponse(err))
+	}
+	if errors.Is(err, core.ErrInvalidProfilePin) {
+		return c.Fiber.Status(fiber.StatusUnauthorized).JSON(NewErrorResponse(err))
+	}
+	return c.RespondWithError(err)
+}
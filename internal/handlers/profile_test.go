@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"seanime/internal/core"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/util"
+	"strconv"
+	"testing"
+)
+
+func TestIsProfileIsolated(t *testing.T) {
+	assert.True(t, isProfileIsolated("/api/v1/settings"))
+	assert.True(t, isProfileIsolated("/api/v1/library/collection"))
+	assert.True(t, isProfileIsolated("/api/v1/statusfoo"))
+	assert.True(t, isProfileIsolated("/api/v1/profilesfoo"))
+	assert.False(t, isProfileIsolated("/api/v1/profiles"))
+	assert.False(t, isProfileIsolated("/api/v1/profiles/1/select"))
+	assert.False(t, isProfileIsolated("/api/v1/server-auth/login"))
+	assert.False(t, isProfileIsolated("/api/v1/status"))
+	assert.False(t, isProfileIsolated("/api/v1/continuity/sync"))
+	assert.True(t, isProfileIsolated("/api/v1/continuity/sync/now"))
+	assert.False(t, isProfileIsolated("/events"))
+	assert.False(t, isProfileIsolated("/index.html"))
+
+	// Fiber matches routes case-insensitively and ignores trailing slashes
+	assert.True(t, isProfileIsolated("/API/v1/settings"))
+	assert.True(t, isProfileIsolated("/Api/v1/library/collection"))
+	assert.True(t, isProfileIsolated("/api/V1/settings/"))
+	assert.False(t, isProfileIsolated("/API/v1/Profiles/"))
+	assert.False(t, isProfileIsolated("/api/v1/STATUS/"))
+}
+
+func TestProfileMiddleware(t *testing.T) {
+	t.Setenv("TEST_ENV", "true")
+	logger := util.NewLogger()
+	database, err := db.NewDatabase(t.TempDir(), "test", logger)
+	require.NoError(t, err)
+	_, err = database.EnsureDefaultProfile()
+	require.NoError(t, err)
+	database.SetProfileID(db.DefaultProfileID)
+
+	other := &models.Profile{Name: "Other"}
+	require.NoError(t, database.InsertProfile(other))
+
+	app := &core.App{Database: database, Logger: logger}
+
+	fiberApp := fiber.New()
+	fiberApp.Use(profileMiddleware(app))
+	profileIDHandler := makeHandler(app, func(c *RouteCtx) error {
+		return c.Fiber.SendString(strconv.Itoa(int(c.ProfileID())))
+	})
+	fiberApp.Get("/api/v1/library/collection", profileIDHandler)
+	fiberApp.Get("/api/v1/status", profileIDHandler)
+
+	get := func(path string, session string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if session != "" {
+			req.Header.Set(ProfileHeaderName, session)
+		}
+		res, err := fiberApp.Test(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(body)
+	}
+
+	// Clients that did not select a profile use the server profile
+	status, body := get("/api/v1/library/collection", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "1", body)
+
+	// Clients that selected different profiles are served at the same time
+	otherSession := app.CreateProfileSession(other.ID)
+	defaultSession := app.CreateProfileSession(db.DefaultProfileID)
+	_, body = get("/api/v1/library/collection", otherSession)
+	assert.Equal(t, strconv.Itoa(int(other.ID)), body)
+	_, body = get("/api/v1/library/collection", defaultSession)
+	assert.Equal(t, "1", body)
+	_, body = get("/api/v1/library/collection", otherSession)
+	assert.Equal(t, strconv.Itoa(int(other.ID)), body)
+	assert.Equal(t, db.DefaultProfileID, database.GetProfileID())
+
+	// Revoked sessions have to select their profile again
+	app.DeleteProfileSessions(other.ID)
+	status, _ = get("/api/v1/library/collection", otherSession)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// A PIN on another profile does not affect the clients that did not select a profile
+	require.NoError(t, core.SetProfilePin(other, "1234"))
+	require.NoError(t, database.UpdateProfile(other))
+	status, body = get("/api/v1/library/collection", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "1", body)
+
+	// If the server profile has a PIN, they have to select their profile
+	serverProfile, err := database.GetProfile(db.DefaultProfileID)
+	require.NoError(t, err)
+	require.NoError(t, core.SetProfilePin(serverProfile, "1234"))
+	require.NoError(t, database.UpdateProfile(serverProfile))
+	status, _ = get("/api/v1/library/collection", "")
+	assert.Equal(t, http.StatusConflict, status)
+	status, body = get("/api/v1/status", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "0", body)
+	_, body = get("/api/v1/library/collection", defaultSession)
+	assert.Equal(t, "1", body)
+}
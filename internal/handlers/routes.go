@@ -19,8 +19,9 @@ import (
 func InitRoutes(app *core.App, fiberApp *fiber.App) {
 
 	fiberApp.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, X-Seanime-Profile, X-Seanime-Sync-Secret",
+		ExposeHeaders: "X-Seanime-Profile",
 	}))
 
 	// Set up a custom logger for fiber.
@@ -33,6 +34,7 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 			"/icons",
 			"/events",
 			"/api/v1/image-proxy",
+			"/api/v1/manga/image",
 			"/api/v1/mediastream/transcode/",
 			"/api/v1/torrent-client/list",
 		},
@@ -70,6 +72,8 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 		return c.Next()
 	})
 
+	fiberApp.Use(profileMiddleware(app))
+
 	api := fiberApp.Group("/api")
 	v1 := api.Group("/v1")
 
@@ -109,7 +113,12 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 	v1.Get("/internal/docs", makeHandler(app, HandleGetDocs))
 
 	// Image Proxy
-	imageProxy := &util2.ImageProxy{}
+	// Reader pages go through the manga image cache
+	imageProxy := &util2.ImageProxy{
+		GetImageFunc: func(url string, headers map[string]string) ([]byte, error) {
+			return app.MangaRepository.GetPageImage(url, headers)
+		},
+	}
 	v1.Get("/image-proxy", imageProxy.ProxyImage)
 
 	v1.Get("/proxy", util2.Proxy)
@@ -126,6 +135,25 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 	v1.Post("/auth/login", makeHandler(app, HandleLogin))
 	v1.Post("/auth/logout", makeHandler(app, HandleLogout))
 
+	// Server auth
+	v1ServerAuth := v1.Group("/server-auth")
+	v1ServerAuth.Get("/status", makeHandler(app, HandleGetServerAuthStatus))
+	v1ServerAuth.Post("/login", makeHandler(app, HandleServerAuthLogin))
+	v1ServerAuth.Post("/logout", makeHandler(app, HandleServerAuthLogout))
+	v1ServerAuth.Post("/password", makeHandler(app, HandleSetServerPassword))
+	v1ServerAuth.Get("/tokens", makeHandler(app, HandleGetAuthTokens))
+	v1ServerAuth.Post("/tokens", makeHandler(app, HandleCreateAuthToken))
+	v1ServerAuth.Delete("/tokens", makeHandler(app, HandleRevokeAuthToken))
+
+	// Profiles
+	v1Profiles := v1.Group("/profiles")
+	v1Profiles.Get("", makeHandler(app, HandleGetProfiles))
+	v1Profiles.Post("", makeHandler(app, HandleCreateProfile))
+	v1Profiles.Patch("", makeHandler(app, HandleUpdateProfile))
+	v1Profiles.Delete("", makeHandler(app, HandleDeleteProfile))
+	v1Profiles.Post("/select", makeHandler(app, HandleSelectProfile))
+	v1Profiles.Post("/server", makeHandler(app, HandleSetServerProfile))
+
 	// Settings
 	v1.Get("/settings", makeHandler(app, HandleGetSettings))
 	v1.Patch("/settings", makeHandler(app, HandleSaveSettings))
@@ -134,6 +162,7 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 
 	// Auto Downloader
 	v1.Post("/auto-downloader/run", makeHandler(app, HandleRunAutoDownloader))
+	v1.Post("/auto-downloader/simulate", makeHandler(app, HandleSimulateAutoDownloaderRule))
 	v1.Get("/auto-downloader/rule/:id", makeHandler(app, HandleGetAutoDownloaderRule))
 	v1.Get("/auto-downloader/rule/anime/:id", makeHandler(app, HandleGetAutoDownloaderRulesByAnime))
 	v1.Get("/auto-downloader/rules", makeHandler(app, HandleGetAutoDownloaderRules))
@@ -143,6 +172,14 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 
 	v1.Get("/auto-downloader/items", makeHandler(app, HandleGetAutoDownloaderItems))
 	v1.Delete("/auto-downloader/item", makeHandler(app, HandleDeleteAutoDownloaderItem))
+	v1.Get("/auto-downloader/debrid-downloads", makeHandler(app, HandleGetAutoDownloaderDebridDownloads))
+	v1.Post("/auto-downloader/debrid-download/start", makeHandler(app, HandleStartAutoDownloaderDebridDownload))
+
+	v1.Get("/auto-downloader/feeds", makeHandler(app, HandleGetAutoDownloaderFeeds))
+	v1.Post("/auto-downloader/feed", makeHandler(app, HandleCreateAutoDownloaderFeed))
+	v1.Patch("/auto-downloader/feed", makeHandler(app, HandleUpdateAutoDownloaderFeed))
+	v1.Delete("/auto-downloader/feed/:id", makeHandler(app, HandleDeleteAutoDownloaderFeed))
+	v1.Post("/auto-downloader/feed/preview", makeHandler(app, HandlePreviewAutoDownloaderFeed))
 
 	// Other
 	v1.Post("/test-dump", makeHandler(app, HandleTestDump))
@@ -189,6 +226,14 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 
 	v1.Post("/mal/logout", makeHandler(app, HandleMALLogout))
 
+	//
+	// Kitsu
+	//
+
+	v1.Post("/kitsu/auth", makeHandler(app, HandleKitsuAuth))
+
+	v1.Post("/kitsu/logout", makeHandler(app, HandleKitsuLogout))
+
 	//
 	// Library
 	//
@@ -224,6 +269,17 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 
 	v1Library.Post("/unknown-media", makeHandler(app, HandleAddUnknownMedia))
 
+	v1Library.Get("/organizer/settings", makeHandler(app, HandleGetOrganizerSettings))
+	v1Library.Patch("/organizer/settings", makeHandler(app, HandleSaveOrganizerSettings))
+	v1Library.Post("/organizer/plan", makeHandler(app, HandleGetOrganizerPlan))
+	v1Library.Post("/organizer/run", makeHandler(app, HandleRunOrganizer))
+	v1Library.Get("/organizer/logs", makeHandler(app, HandleGetOrganizerLogs))
+	v1Library.Post("/organizer/undo", makeHandler(app, HandleUndoOrganizerRun))
+
+	v1Library.Get("/nfo/settings", makeHandler(app, HandleGetNfoSettings))
+	v1Library.Patch("/nfo/settings", makeHandler(app, HandleSaveNfoSettings))
+	v1Library.Post("/nfo/export", makeHandler(app, HandleExportNfo))
+
 	//
 	// Torrent / Torrent Client
 	//
@@ -320,6 +376,8 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 	v1Manga.Delete("/entry/cache", makeHandler(app, HandleEmptyMangaEntryCache))
 	v1Manga.Post("/chapters", makeHandler(app, HandleGetMangaEntryChapters))
 	v1Manga.Post("/pages", makeHandler(app, HandleGetMangaEntryPages))
+	v1Manga.Post("/merged-chapters", makeHandler(app, HandleGetMangaEntryMergedChapters))
+	v1Manga.Post("/merged-pages", makeHandler(app, HandleGetMangaEntryMergedPages))
 	v1Manga.Post("/update-progress", makeHandler(app, HandleUpdateMangaProgress))
 
 	v1Manga.Get("/downloaded-chapters/:id", makeHandler(app, HandleGetMangaEntryDownloadedChapters))
@@ -332,6 +390,22 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 	v1Manga.Post("/download-queue/stop", makeHandler(app, HandleStopMangaDownloadQueue))
 	v1Manga.Delete("/download-queue", makeHandler(app, HandleClearAllChapterDownloadQueue))
 	v1Manga.Post("/download-queue/reset-errored", makeHandler(app, HandleResetErroredChapterDownloadQueue))
+	v1Manga.Post("/export", makeHandler(app, HandleExportMangaChapters))
+	v1Manga.Get("/exports", makeHandler(app, HandleGetMangaExportJobs))
+	v1Manga.Delete("/exports", makeHandler(app, HandleClearFinishedMangaExportJobs))
+
+	v1Manga.Get("/auto-downloader/rules", makeHandler(app, HandleGetMangaAutoDownloaderRules))
+	v1Manga.Post("/auto-downloader/rule", makeHandler(app, HandleCreateMangaAutoDownloaderRule))
+	v1Manga.Patch("/auto-downloader/rule", makeHandler(app, HandleUpdateMangaAutoDownloaderRule))
+	v1Manga.Delete("/auto-downloader/rule/:id", makeHandler(app, HandleDeleteMangaAutoDownloaderRule))
+	v1Manga.Post("/auto-downloader/run", makeHandler(app, HandleRunMangaAutoDownloader))
+
+	v1Manga.Get("/local-library", makeHandler(app, HandleGetLocalMangaLibrary))
+	v1Manga.Get("/local-page/:chapterId/:index", makeHandler(app, HandleGetLocalMangaPage))
+
+	v1Manga.Get("/image", makeHandler(app, HandleGetMangaPageImage))
+	v1Manga.Get("/image-cache", makeHandler(app, HandleGetMangaImageCacheStats))
+	v1Manga.Delete("/image-cache", makeHandler(app, HandlePurgeMangaImageCache))
 
 	v1Manga.Post("/search", makeHandler(app, HandleMangaManualSearch))
 	v1Manga.Post("/manual-mapping", makeHandler(app, HandleMangaManualMapping))
@@ -368,6 +442,14 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 	v1.Get("/mediastream/transcode/*", makeHandler(app, HandleMediastreamTranscode))
 	v1.Get("/mediastream/subs/*", makeHandler(app, HandleMediastreamGetSubtitles))
 	v1.Get("/mediastream/att/*", makeHandler(app, HandleMediastreamGetAttachments))
+	v1.Get("/mediastream/trickplay/status", makeHandler(app, HandleGetMediastreamTrickplayStatus))
+	v1.Post("/mediastream/trickplay/generate-library", makeHandler(app, HandleGenerateMediastreamLibraryTrickplay))
+	v1.Post("/mediastream/trickplay/cancel", makeHandler(app, HandleCancelMediastreamTrickplay))
+	v1.Get("/mediastream/trickplay/:hash/*", makeHandler(app, HandleMediastreamGetTrickplay))
+	v1.Post("/mediastream/pre-transcode", makeHandler(app, HandleQueueMediastreamPreTranscode))
+	v1.Get("/mediastream/pre-transcode/jobs", makeHandler(app, HandleGetMediastreamPreTranscodeJobs))
+	v1.Post("/mediastream/pre-transcode/cancel", makeHandler(app, HandleCancelMediastreamPreTranscodeJob))
+	v1.Delete("/mediastream/pre-transcode/jobs", makeHandler(app, HandleClearMediastreamPreTranscodeJobs))
 	v1.Get("/mediastream/direct", makeHandler(app, HandleMediastreamDirectPlay))
 	v1.Get("/mediastream/file/*", makeHandler(app, HandleMediastreamFile))
 
@@ -382,6 +464,7 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 	v1.Post("/torrentstream/drop", makeHandler(app, HandleTorrentstreamDropTorrent))
 	v1.Post("/torrentstream/torrent-file-previews", makeHandler(app, HandleGetTorrentstreamTorrentFilePreviews))
 	v1.Post("/torrentstream/batch-history", makeHandler(app, HandleGetTorrentstreamBatchHistory))
+	v1.Get("/torrentstream/cache", makeHandler(app, HandleGetTorrentstreamCacheStatus))
 
 	//
 	// Extensions
@@ -409,6 +492,8 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 	v1Continuity.Patch("/item", makeHandler(app, HandleUpdateContinuityWatchHistoryItem))
 	v1Continuity.Get("/item/:id", makeHandler(app, HandleGetContinuityWatchHistoryItem))
 	v1Continuity.Get("/history", makeHandler(app, HandleGetContinuityWatchHistory))
+	v1Continuity.Post("/sync", makeHandler(app, HandleContinuityPeerSync))
+	v1Continuity.Post("/sync/now", makeHandler(app, HandleContinuitySyncWithPeer))
 
 	//
 	// Sync
@@ -440,6 +525,18 @@ func InitRoutes(app *core.App, fiberApp *fiber.App) {
 	v1.Post("/debrid/stream/start", makeHandler(app, HandleDebridStartStream))
 	v1.Post("/debrid/stream/cancel", makeHandler(app, HandleDebridCancelStream))
 
+	//
+	// Tracker sync
+	//
+
+	v1TrackerSync := v1.Group("/tracker-sync")
+	v1TrackerSync.Get("/settings", makeHandler(app, HandleGetTrackerSyncSettings))
+	v1TrackerSync.Patch("/settings", makeHandler(app, HandleSaveTrackerSyncSettings))
+	v1TrackerSync.Get("/queue", makeHandler(app, HandleGetTrackerSyncQueue))
+	v1TrackerSync.Delete("/queue", makeHandler(app, HandleClearTrackerSyncQueue))
+	v1TrackerSync.Post("/queue/retry", makeHandler(app, HandleRetryTrackerSyncQueue))
+	v1TrackerSync.Get("/ledger", makeHandler(app, HandleGetTrackerLedger))
+
 	//
 	// Websocket
 	//
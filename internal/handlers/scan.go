@@ -96,6 +96,8 @@ func HandleScanLocalFiles(c *RouteCtx) error {
 
 	go c.App.AutoDownloader.CleanUpDownloadedItems()
 
+	go c.App.NfoExporter.ExportAfterScan()
+
 	return c.RespondWithData(lfs)
 
 }
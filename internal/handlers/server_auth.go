@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"seanime/internal/auth"
+	"strconv"
+	"time"
+)
+
+type ServerAuthStatus struct {
+	// Enabled is true if a server password is set
+	Enabled bool `json:"enabled"`
+	// Authenticated is true if the request has a valid session or token
+	Authenticated bool `json:"authenticated"`
+	// Scope is the scope of the session or token, "full" or "read"
+	Scope string `json:"scope"`
+}
+
+// HandleGetServerAuthStatus
+//
+//	@summary returns whether authentication is enabled and whether the client is authenticated.
+//	@desc This route is public, the client uses it to decide whether to display the login page.
+//	@route /api/v1/server-auth/status [GET]
+//	@returns handlers.ServerAuthStatus
+func HandleGetServerAuthStatus(c *RouteCtx) error {
+	ret := ServerAuthStatus{
+		Enabled:       c.App.AuthManager.IsEnabled(),
+		Authenticated: true,
+		Scope:         auth.ScopeFull,
+	}
+
+	if ret.Enabled {
+		scope, ok := c.App.AuthManager.Validate(auth.TokenFromCtx(c.Fiber))
+		ret.Authenticated = ok
+		ret.Scope = scope
+	}
+
+	return c.RespondWithData(ret)
+}
+
+// HandleServerAuthLogin
+//
+//	@summary logs the client in with the server password.
+//	@desc This creates a session and sets the session cookie.
+//	@desc The session token is also returned so that it can be used as a bearer token.
+//	@desc Clients are locked out for an increasing duration after too many failed attempts, a 429 status is returned.
+//	@route /api/v1/server-auth/login [POST]
+//	@returns string
+func HandleServerAuthLogin(c *RouteCtx) error {
+
+	type body struct {
+		Password string `json:"password"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	token, expiresAt, err := c.App.AuthManager.Login(b.Password, c.Fiber.Get("User-Agent"), c.Fiber.IP())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidPassword) {
+			return c.Fiber.Status(fiber.StatusUnauthorized).JSON(NewErrorResponse(err))
+		}
+		var tooManyAttempts *auth.TooManyAttemptsError
+		if errors.As(err, &tooManyAttempts) {
+			c.Fiber.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(tooManyAttempts.RetryAfter.Seconds())+1))
+			return c.Fiber.Status(fiber.StatusTooManyRequests).JSON(NewErrorResponse(err))
+		}
+		return c.RespondWithError(err)
+	}
+
+	auth.SetSessionCookie(c.Fiber, token, expiresAt)
+
+	return c.RespondWithData(token)
+}
+
+// HandleServerAuthLogout
+//
+//	@summary logs the client out.
+//	@desc This revokes the current session and clears the session cookie.
+//	@route /api/v1/server-auth/logout [POST]
+//	@returns bool
+func HandleServerAuthLogout(c *RouteCtx) error {
+
+	err := c.App.AuthManager.Logout(auth.TokenFromCtx(c.Fiber))
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	c.Fiber.ClearCookie(auth.SessionCookieName)
+
+	return c.RespondWithData(true)
+}
+
+// HandleSetServerPassword
+//
+//	@summary sets, changes or removes the server password.
+//	@desc If a password is already set, the current password is required.
+//	@desc An empty new password disables authentication.
+//	@desc All sessions are revoked and websocket clients are disconnected, minted tokens are kept.
+//	@route /api/v1/server-auth/password [POST]
+//	@returns bool
+func HandleSetServerPassword(c *RouteCtx) error {
+
+	type body struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	err := c.App.AuthManager.SetPassword(b.CurrentPassword, b.NewPassword)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	// Force the clients to re-authenticate
+	c.App.WSEventManager.CloseAll()
+
+	return c.RespondWithData(true)
+}
+
+// HandleGetAuthTokens
+//
+//	@summary returns the tokens minted for other devices.
+//	@desc The tokens themselves are not returned, only their metadata.
+//	@route /api/v1/server-auth/tokens [GET]
+//	@returns []models.AuthToken
+func HandleGetAuthTokens(c *RouteCtx) error {
+	tokens, err := c.App.AuthManager.GetTokens()
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(tokens)
+}
+
+// HandleCreateAuthToken
+//
+//	@summary mints a token for another device.
+//	@desc The scope is either "full" or "read", read-only tokens can only be used for GET requests to the media-serving and library routes.
+//	@desc If expiresInDays is 0, the token never expires.
+//	@desc The token is only returned once.
+//	@route /api/v1/server-auth/tokens [POST]
+//	@returns string
+func HandleCreateAuthToken(c *RouteCtx) error {
+
+	type body struct {
+		Name          string `json:"name"`
+		Scope         string `json:"scope"`
+		ExpiresInDays int    `json:"expiresInDays"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	if b.Name == "" {
+		return c.RespondWithError(errors.New("name is required"))
+	}
+
+	token, _, err := c.App.AuthManager.CreateToken(b.Name, b.Scope, time.Duration(b.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(token)
+}
+
+// HandleRevokeAuthToken
+//
+//	@summary revokes a token.
+//	@route /api/v1/server-auth/tokens [DELETE]
+//	@returns bool
+func HandleRevokeAuthToken(c *RouteCtx) error {
+
+	type body struct {
+		ID uint `json:"id"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	err := c.App.AuthManager.RevokeToken(b.ID)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(true)
+}
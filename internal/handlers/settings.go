@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"seanime/internal/database/db"
 	"seanime/internal/database/models"
 	"seanime/internal/torrents/torrent"
 	"seanime/internal/util"
@@ -27,7 +28,7 @@ func HandleGetSettings(c *RouteCtx) error {
 		return c.RespondWithError(errors.New(runtime.GOOS))
 	}
 
-	return c.RespondWithData(settings)
+	return c.RespondWithData(c.App.GetProfileSettings(c.ProfileID(), settings))
 }
 
 // HandleGettingStarted
@@ -126,7 +127,7 @@ func HandleGettingStarted(c *RouteCtx) error {
 		}()
 	}
 
-	c.App.WSEventManager.SendEvent("settings", settings)
+	c.App.WSEventManager.SendEvent("settings", c.App.GetProfileSettings(c.ProfileID(), settings))
 
 	status := NewStatus(c)
 
@@ -206,6 +207,31 @@ func HandleSaveSettings(c *RouteCtx) error {
 		autoDownloaderSettings.Enabled = false
 	}
 
+	// The peer sync is bound to the profile that configured it, it stays bound when other profiles save their settings
+	b.Library.ContinuityPeerSyncProfileID = 0
+	if b.Library.ContinuityPeerSyncEnabled {
+		b.Library.ContinuityPeerSyncProfileID = c.ProfileID()
+		if prevSettings != nil && prevSettings.Library != nil &&
+			prevSettings.Library.ContinuityPeerSyncEnabled &&
+			prevSettings.Library.ContinuityPeerSyncProfileID != 0 &&
+			prevSettings.Library.ContinuityPeerSyncUrl == b.Library.ContinuityPeerSyncUrl &&
+			prevSettings.Library.ContinuityPeerSyncSecret == b.Library.ContinuityPeerSyncSecret {
+			b.Library.ContinuityPeerSyncProfileID = prevSettings.Library.ContinuityPeerSyncProfileID
+		}
+	}
+
+	// The Discord settings of other profiles are stored in the profile
+	discord := &b.Discord
+	if c.ProfileID() != db.DefaultProfileID {
+		if err := c.App.SaveProfileDiscordSettings(c.ProfileID(), &b.Discord); err != nil {
+			return c.RespondWithError(err)
+		}
+		discord = nil
+		if prevSettings != nil {
+			discord = prevSettings.Discord
+		}
+	}
+
 	settings, err := c.App.Database.UpsertSettings(&models.Settings{
 		BaseModel: models.BaseModel{
 			ID:        1,
@@ -216,7 +242,7 @@ func HandleSaveSettings(c *RouteCtx) error {
 		Torrent:        &b.Torrent,
 		Anilist:        &b.Anilist,
 		Manga:          &b.Manga,
-		Discord:        &b.Discord,
+		Discord:        discord,
 		Notifications:  &b.Notifications,
 		AutoDownloader: &autoDownloaderSettings,
 	})
@@ -225,7 +251,7 @@ func HandleSaveSettings(c *RouteCtx) error {
 		return c.RespondWithError(err)
 	}
 
-	c.App.WSEventManager.SendEvent("settings", settings)
+	c.App.WSEventManager.SendEvent("settings", c.App.GetProfileSettings(c.ProfileID(), settings))
 
 	status := NewStatus(c)
 
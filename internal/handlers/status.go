@@ -31,7 +31,10 @@ type Status struct {
 	TorrentstreamSettings *models.TorrentstreamSettings `json:"torrentstreamSettings"`
 	DebridSettings        *models.DebridSettings        `json:"debridSettings"`
 	AnilistClientID       string                        `json:"anilistClientId"`
-	Updating              bool                          `json:"updating"` // If true, a new screen will be displayed
+	TrackingPlatform      string                        `json:"trackingPlatform"` // Platform used to track progress, set on startup
+	ProfileID             uint                          `json:"profileId"`        // Profile selected by the client, 0 if the client has to select a profile
+	ServerProfileID       uint                          `json:"serverProfileId"`  // Profile the background modules act on behalf of
+	Updating              bool                          `json:"updating"`         // If true, a new screen will be displayed
 }
 
 var clientInfoCache = result.NewResultMap[string, util.ClientInfo]()
@@ -45,7 +48,9 @@ func NewStatus(c *RouteCtx) *Status {
 	var theme *models.Theme
 	//var mal *models.Mal
 
-	if dbAcc, _ = c.App.Database.GetAccount(); dbAcc != nil {
+	profileID := c.ProfileID()
+
+	if dbAcc, _ = c.App.Database.GetProfileAccount(profileID); dbAcc != nil {
 		user, _ = anime.NewUser(dbAcc)
 		if user != nil {
 			user.Token = "HIDDEN"
@@ -56,6 +61,7 @@ func NewStatus(c *RouteCtx) *Status {
 		if settings.ID == 0 || settings.Library == nil || settings.Torrent == nil || settings.MediaPlayer == nil {
 			settings = nil
 		}
+		settings = c.App.GetProfileSettings(c.ProfileID(), settings)
 	}
 
 	clientInfo, found := clientInfoCache.Get(c.Fiber.Get("User-Agent"))
@@ -64,7 +70,9 @@ func NewStatus(c *RouteCtx) *Status {
 		clientInfoCache.Set(c.Fiber.Get("User-Agent"), clientInfo)
 	}
 
-	theme, _ = c.App.Database.GetTheme()
+	if profileID != 0 {
+		theme, _ = c.App.Database.GetProfileTheme(profileID)
+	}
 	//mal, _ = c.App.Database.GetMalInfo()
 	return &Status{
 		OS:              runtime.GOOS,
@@ -81,6 +89,9 @@ func NewStatus(c *RouteCtx) *Status {
 		TorrentstreamSettings: c.App.SecondarySettings.Torrentstream,
 		DebridSettings:        c.App.SecondarySettings.Debrid,
 		AnilistClientID:       c.App.Config.Anilist.ClientID,
+		TrackingPlatform:      c.App.GetTrackingPlatform(),
+		ProfileID:             profileID,
+		ServerProfileID:       c.App.Database.GetProfileID(),
 		Updating:              false,
 		//FeatureFlags:          c.App.FeatureFlags,
 	}
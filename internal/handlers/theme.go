@@ -8,7 +8,7 @@ import "seanime/internal/database/models"
 //	@route /api/v1/theme [GET]
 //	@returns models.Theme
 func HandleGetTheme(c *RouteCtx) error {
-	theme, err := c.App.Database.GetTheme()
+	theme, err := c.App.Database.GetProfileTheme(c.ProfileID())
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -32,13 +32,13 @@ func HandleUpdateTheme(c *RouteCtx) error {
 		return c.RespondWithError(err)
 	}
 
-	// Set the theme ID to 1, so we overwrite the previous settings
+	// Set the theme ID to the profile ID, so we overwrite the previous settings
 	b.Theme.BaseModel = models.BaseModel{
-		ID: 1,
+		ID: c.ProfileID(),
 	}
 
 	// Update the theme settings
-	if _, err := c.App.Database.UpsertTheme(&b.Theme); err != nil {
+	if _, err := c.App.Database.UpsertProfileTheme(c.ProfileID(), &b.Theme); err != nil {
 		return c.RespondWithError(err)
 	}
 
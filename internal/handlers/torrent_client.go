@@ -124,7 +124,7 @@ func HandleTorrentClientDownload(c *RouteCtx) error {
 		return c.RespondWithError(errors.New("could not contact torrent client, verify your settings or make sure it's running"))
 	}
 
-	completeAnime, err := c.App.AnilistPlatform.GetAnimeWithRelations(b.Media.ID)
+	completeAnime, err := c.Profile().AnilistPlatform.GetAnimeWithRelations(b.Media.ID)
 	if err != nil {
 		return c.RespondWithError(err)
 	}
@@ -140,7 +140,7 @@ func HandleTorrentClientDownload(c *RouteCtx) error {
 			EpisodeNumbers:   b.SmartSelect.MissingEpisodeNumbers,
 			Media:            completeAnime,
 			Destination:      b.Destination,
-			Platform:         c.App.AnilistPlatform,
+			Platform:         c.Profile().AnilistPlatform,
 			ShouldAddTorrent: true,
 		})
 		if err != nil {
@@ -173,11 +173,12 @@ func HandleTorrentClientDownload(c *RouteCtx) error {
 	}
 
 	// Add the media to the collection (if it wasn't already)
+	profile := c.Profile()
 	go func() {
 		defer util.HandlePanicInModuleThen("handlers/HandleTorrentClientDownload", func() {})
 		if b.Media != nil {
 			// Check if the media is already in the collection
-			animeCollection, err := c.App.GetAnimeCollection(false)
+			animeCollection, err := profile.GetAnimeCollection(false)
 			if err != nil {
 				return
 			}
@@ -186,11 +187,11 @@ func HandleTorrentClientDownload(c *RouteCtx) error {
 				return
 			}
 			// Add the media to the collection
-			err = c.App.AnilistPlatform.AddMediaToCollection([]int{b.Media.ID})
+			err = profile.AnilistPlatform.AddMediaToCollection([]int{b.Media.ID})
 			if err != nil {
 				c.App.Logger.Error().Err(err).Msg("anilist: Failed to add media to collection")
 			}
-			ac, _ := c.App.RefreshAnimeCollection()
+			ac, _ := profile.RefreshAnimeCollection()
 			c.App.WSEventManager.SendEvent(events.RefreshedAnilistAnimeCollection, ac)
 		}
 	}()
@@ -205,6 +205,16 @@ func HandleTorrentstreamDropTorrent(c *RouteCtx) error {
 	return c.RespondWithData(true)
 }
 
+// HandleGetTorrentstreamCacheStatus
+//
+//	@summary returns the torrents stored in the download directory.
+//	@desc This returns the size of the cache, the seeding status of the torrents and whether they were added to the library.
+//	@returns torrentstream.CacheStatus
+//	@route /api/v1/torrentstream/cache [GET]
+func HandleGetTorrentstreamCacheStatus(c *RouteCtx) error {
+	return c.RespondWithData(c.App.TorrentstreamRepository.GetCacheStatus())
+}
+
 // HandleGetTorrentstreamBatchHistory
 //
 //	@summary returns the most recent batch selected.
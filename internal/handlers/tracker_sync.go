@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"seanime/internal/database/models"
+)
+
+// HandleGetTrackerSyncSettings
+//
+//	@summary get tracker sync settings.
+//	@desc This returns the settings of the trackers progress changes are mirrored to.
+//	@returns models.TrackerSyncSettings
+//	@route /api/v1/tracker-sync/settings [GET]
+func HandleGetTrackerSyncSettings(c *RouteCtx) error {
+	settings, found := c.App.Database.GetTrackerSyncSettings()
+	if !found {
+		return c.RespondWithError(errors.New("tracker sync settings not found"))
+	}
+
+	return c.RespondWithData(settings)
+}
+
+// HandleSaveTrackerSyncSettings
+//
+//	@summary save tracker sync settings.
+//	@desc This saves the tracker sync settings and restarts the retry loop.
+//	@returns models.TrackerSyncSettings
+//	@route /api/v1/tracker-sync/settings [PATCH]
+func HandleSaveTrackerSyncSettings(c *RouteCtx) error {
+
+	type body struct {
+		Settings models.TrackerSyncSettings `json:"settings"`
+	}
+
+	var b body
+	if err := c.Fiber.BodyParser(&b); err != nil {
+		return c.RespondWithError(err)
+	}
+
+	b.Settings.ID = 1
+
+	settings, err := c.App.Database.UpsertTrackerSyncSettings(&b.Settings)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	c.App.InitOrRefreshTrackerSyncSettings()
+
+	return c.RespondWithData(settings)
+}
+
+// HandleGetTrackerSyncQueue
+//
+//	@summary returns the changes waiting to be written to the trackers.
+//	@desc These are the writes that failed, they are retried periodically.
+//	@returns []models.TrackerSyncQueueItem
+//	@route /api/v1/tracker-sync/queue [GET]
+func HandleGetTrackerSyncQueue(c *RouteCtx) error {
+	items, err := c.App.Database.GetTrackerSyncQueueItems()
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(items)
+}
+
+// HandleRetryTrackerSyncQueue
+//
+//	@summary retries all the queued changes now.
+//	@returns bool
+//	@route /api/v1/tracker-sync/queue/retry [POST]
+func HandleRetryTrackerSyncQueue(c *RouteCtx) error {
+	go c.App.FanoutPlatform.RetryNow()
+
+	return c.RespondWithData(true)
+}
+
+// HandleClearTrackerSyncQueue
+//
+//	@summary deletes all the queued changes.
+//	@desc The changes will not be written to the trackers.
+//	@returns bool
+//	@route /api/v1/tracker-sync/queue [DELETE]
+func HandleClearTrackerSyncQueue(c *RouteCtx) error {
+	err := c.App.Database.ClearTrackerSyncQueue()
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(true)
+}
+
+// HandleGetTrackerLedger
+//
+//	@summary returns the entries of the local-only ledger.
+//	@desc If a media ID is provided, only the entries of that media are returned.
+//	@returns []models.TrackerLedgerEntry
+//	@route /api/v1/tracker-sync/ledger [GET]
+func HandleGetTrackerLedger(c *RouteCtx) error {
+	mediaId := c.Fiber.QueryInt("mediaId", 0)
+
+	entries, err := c.App.Database.GetTrackerLedgerEntries(mediaId, 500)
+	if err != nil {
+		return c.RespondWithError(err)
+	}
+
+	return c.RespondWithData(entries)
+}
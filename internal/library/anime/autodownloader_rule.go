@@ -31,5 +31,34 @@ type (
 		EpisodeNumbers      []int                                 `json:"episodeNumbers,omitempty"`
 		Destination         string                                `json:"destination"`
 		AdditionalTerms     []string                              `json:"additionalTerms"`
+		// QualityProfile ranks the releases matching the rule.
+		// When it is enabled, the order of Resolutions and ReleaseGroups matters, the first ones being the best.
+		QualityProfile *AutoDownloaderQualityProfile `json:"qualityProfile,omitempty"`
+	}
+
+	// AutoDownloaderQualityProfile defines how releases are ranked and when downloaded episodes are upgraded.
+	AutoDownloaderQualityProfile struct {
+		Enabled bool `json:"enabled"`
+		// PreferredTags are terms that make a release better, the first ones being the most valuable.
+		// e.g. "v2", "REPACK", "batch", "HEVC"
+		PreferredTags []string `json:"preferredTags"`
+		// Upgrade downloads a better release of episodes that are already downloaded or in the library.
+		Upgrade bool `json:"upgrade"`
+		// CutoffResolution and CutoffReleaseGroup define the quality at which episodes are no longer upgraded.
+		// Repacks of the same release are still downloaded. Leave both empty to always upgrade.
+		CutoffResolution   string `json:"cutoffResolution,omitempty"`
+		CutoffReleaseGroup string `json:"cutoffReleaseGroup,omitempty"`
+		// KeepOldFiles keeps the previous file once the upgrade is scanned, instead of deleting it.
+		KeepOldFiles bool `json:"keepOldFiles"`
 	}
 )
+
+// HasQualityProfile returns true if the rule has an enabled quality profile.
+func (r *AutoDownloaderRule) HasQualityProfile() bool {
+	return r != nil && r.QualityProfile != nil && r.QualityProfile.Enabled
+}
+
+// CanUpgrade returns true if the episodes already downloaded can be upgraded.
+func (r *AutoDownloaderRule) CanUpgrade() bool {
+	return r.HasQualityProfile() && r.QualityProfile.Upgrade
+}
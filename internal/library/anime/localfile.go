@@ -24,6 +24,9 @@ type (
 		Locked           bool                   `json:"locked"`
 		Ignored          bool                   `json:"ignored"` // Unused for now
 		MediaId          int                    `json:"mediaId"`
+		// Size and ModTime are used to detect the files that changed since the last scan
+		Size    int64 `json:"size,omitempty"`
+		ModTime int64 `json:"modTime,omitempty"` // Unix timestamp in seconds
 	}
 
 	// LocalFileMetadata holds metadata related to a media episode.
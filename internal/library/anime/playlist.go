@@ -9,6 +9,7 @@ type (
 	// Playlist holds the data from models.PlaylistEntry
 	Playlist struct {
 		DbId       uint         `json:"dbId"`       // DbId is the database ID of the models.PlaylistEntry
+		ProfileID  uint         `json:"-"`          // ProfileID is the profile that owns the playlist
 		Name       string       `json:"name"`       // Name is the name of the playlist
 		LocalFiles []*LocalFile `json:"localFiles"` // LocalFiles is a list of local files in the playlist, in order
 	}
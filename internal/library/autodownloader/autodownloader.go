@@ -8,6 +8,9 @@ import (
 	"github.com/samber/lo"
 	"github.com/samber/mo"
 	"github.com/sourcegraph/conc/pool"
+	"net/http"
+	"os"
+	"path/filepath"
 	"seanime/internal/api/anilist"
 	"seanime/internal/api/metadata"
 	"seanime/internal/database/db"
@@ -48,6 +51,12 @@ type (
 		stopCh                  chan struct{}
 		startCh                 chan struct{}
 		debugTrace              bool
+		feedClient              *http.Client // Used to fetch the RSS and Atom feeds
+		downloadClient          *http.Client // Used to download the files from the debrid service
+		debridStagingDir        string       // Folder where the debrid files are downloaded before being placed in the library
+		libraryScanner          LibraryScanner
+		debridCheckCh           chan struct{}
+		debridLoopOnce          sync.Once
 		mu                      sync.Mutex
 	}
 
@@ -59,6 +68,7 @@ type (
 		Database                *db.Database
 		MetadataProvider        metadata.Provider
 		DebridClientRepository  *debrid_client.Repository
+		CacheDir                string // Used to stage the debrid downloads, the system temp directory is used if empty
 	}
 
 	tmpTorrentToDownload struct {
@@ -68,6 +78,11 @@ type (
 )
 
 func New(opts *NewAutoDownloaderOptions) *AutoDownloader {
+	cacheDir := opts.CacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(os.TempDir(), "seanime")
+	}
+
 	return &AutoDownloader{
 		logger:                  opts.Logger,
 		torrentClientRepository: opts.TorrentClientRepository,
@@ -88,6 +103,10 @@ func New(opts *NewAutoDownloaderOptions) *AutoDownloader {
 		stopCh:            make(chan struct{}, 1),
 		startCh:           make(chan struct{}, 1),
 		debugTrace:        true,
+		feedClient:        &http.Client{Timeout: 30 * time.Second},
+		downloadClient:    newDownloadClient(),
+		debridStagingDir:  filepath.Join(cacheDir, "debrid-downloads"),
+		debridCheckCh:     make(chan struct{}, 1),
 		mu:                sync.Mutex{},
 	}
 }
@@ -138,6 +157,10 @@ func (ad *AutoDownloader) Start() {
 	if ad == nil {
 		return
 	}
+
+	// Resume the debrid downloads, they don't depend on the torrent client
+	ad.startDebridDownloadLoop()
+
 	go func() {
 		ad.mu.Lock()
 		if ad.settings.Enabled {
@@ -179,7 +202,8 @@ func (ad *AutoDownloader) CleanUpDownloadedItems() {
 	}
 	ad.mu.Lock()
 	defer ad.mu.Unlock()
-	err := ad.database.DeleteDownloadedAutoDownloaderItems()
+	ad.removeReplacedFiles()
+	err := ad.database.DeleteDownloadedAutoDownloaderItems(time.Now().Add(-upgradeItemExpiry))
 	if err != nil {
 		return
 	}
@@ -218,25 +242,41 @@ func (ad *AutoDownloader) start() {
 
 }
 
-func (ad *AutoDownloader) checkForNewEpisodes() {
-	defer util.HandlePanicInModuleThen("autodownloader/checkForNewEpisodes", func() {})
-
-	ad.mu.Lock()
-	if ad == nil || ad.torrentRepository == nil || !ad.settings.Enabled || ad.settings.Provider == "" || ad.settings.Provider == torrent.ProviderNone {
-		ad.logger.Warn().Msg("autodownloader: Could not check for new episodes. AutoDownloader is not enabled or provider is not set.")
-		ad.mu.Unlock()
-		return
+// canUseProvider returns true if the default torrent provider can be used for auto downloading.
+func (ad *AutoDownloader) canUseProvider(settings *models.AutoDownloaderSettings) bool {
+	if settings.Provider == "" || settings.Provider == torrent.ProviderNone {
+		return false
 	}
-
 	// DEVNOTE: [checkForNewEpisodes] is called on startup, when the default anime provider extension has not yet been loaded.
 	providerExt, found := ad.torrentRepository.GetDefaultAnimeProviderExtension()
 	if !found {
 		//ad.logger.Warn().Msg("autodownloader: Could not check for new episodes. Default provider not found.")
-		ad.mu.Unlock()
-		return
+		return false
 	}
 	if providerExt.GetProvider().GetSettings().Type != hibiketorrent.AnimeProviderTypeMain {
 		ad.logger.Warn().Msgf("autodownloader: Could not check for new episodes. Provider '%s' cannot be used for auto downloading.", providerExt.GetName())
+		return false
+	}
+	return true
+}
+
+func (ad *AutoDownloader) checkForNewEpisodes() {
+	defer util.HandlePanicInModuleThen("autodownloader/checkForNewEpisodes", func() {})
+
+	ad.mu.Lock()
+	if ad == nil || ad.torrentRepository == nil || !ad.settings.Enabled {
+		ad.logger.Warn().Msg("autodownloader: Could not check for new episodes. AutoDownloader is not enabled.")
+		ad.mu.Unlock()
+		return
+	}
+
+	settings := *ad.settings
+
+	// RSS and Atom feeds are used in addition to the provider
+	feeds := ad.getFeeds()
+
+	useProvider := ad.canUseProvider(&settings)
+	if !useProvider && len(feeds) == 0 {
 		ad.mu.Unlock()
 		return
 	}
@@ -261,20 +301,17 @@ func (ad *AutoDownloader) checkForNewEpisodes() {
 	lfWrapper := anime.NewLocalFileWrapper(lfs)
 
 	// Get the latest torrents
-	torrents, err = ad.getLatestTorrents(rules)
+	torrents, err = ad.getLatestTorrents(&settings, rules, useProvider, feeds)
 	if err != nil {
 		ad.logger.Error().Err(err).Msg("autodownloader: Failed to get latest torrents")
 		return
 	}
 
+	// Remove the torrents that have already been queued
+	torrents = ad.filterQueuedTorrents(torrents)
+
 	// Get existing torrents
-	existingTorrents := make([]*torrent_client.Torrent, 0)
-	if ad.torrentClientRepository != nil {
-		existingTorrents, err = ad.torrentClientRepository.GetList()
-		if err != nil {
-			existingTorrents = make([]*torrent_client.Torrent, 0)
-		}
-	}
+	existingHashes := ad.getExistingTorrentHashes()
 
 	downloaded := 0
 	mu := sync.Mutex{}
@@ -311,13 +348,10 @@ func (ad *AutoDownloader) checkForNewEpisodes() {
 
 			// Get all torrents that follow the rule
 			torrentsToDownload := make([]*tmpTorrentToDownload, 0)
-		outer:
 			for _, t := range torrents {
 				// If the torrent is already added, skip it
-				for _, et := range existingTorrents {
-					if et.Hash == t.InfoHash {
-						continue outer // Skip the torrent
-					}
+				if _, found := existingHashes[t.InfoHash]; found {
+					continue // Skip the torrent
 				}
 
 				episode, ok := ad.torrentFollowsRule(t, rule, listEntry, localEntry, items)
@@ -329,55 +363,14 @@ func (ad *AutoDownloader) checkForNewEpisodes() {
 				}
 			}
 
-			// Download the torrent if there's only one
-			if len(torrentsToDownload) == 1 {
-				t := torrentsToDownload[0]
-				ok := ad.downloadTorrent(t.torrent, rule, t.episode)
-				if ok {
-					downloaded++
-				}
-				return
-			}
-
-			// If there's more than one, we will group them by episode and sort them
-			// Make a map [episode]torrents
-			epMap := make(map[int][]*tmpTorrentToDownload)
-			for _, t := range torrentsToDownload {
-				if _, ok := epMap[t.episode]; !ok {
-					epMap[t.episode] = make([]*tmpTorrentToDownload, 0)
-					epMap[t.episode] = append(epMap[t.episode], t)
-				} else {
-					epMap[t.episode] = append(epMap[t.episode], t)
-				}
-			}
-
-			// Go through each episode group and download the best torrent (by resolution and seeders)
-			for ep, torrents := range epMap {
-
-				// If there's only one torrent for the episode, download it
-				if len(torrents) == 1 {
-					ok := ad.downloadTorrent(torrents[0].torrent, rule, ep)
-					if ok {
-						mu.Lock()
-						downloaded++
-						mu.Unlock()
-					}
-					continue
+			// Download the best torrent for each episode
+			for _, t := range selectTorrentsToDownload(rule, torrentsToDownload) {
+				// Releases replacing existing episodes are recorded so that the old files can be deleted
+				var target *upgradeTarget
+				if rule.HasQualityProfile() {
+					target = getUpgradeTarget(t.episode, rule, localEntry, items)
 				}
-
-				// If there are more than one
-				// Sort by resolution
-				sort.Slice(torrents, func(i, j int) bool {
-					qI := comparison.ExtractResolutionInt(torrents[i].torrent.ParsedData.VideoResolution)
-					qJ := comparison.ExtractResolutionInt(torrents[j].torrent.ParsedData.VideoResolution)
-					return qI > qJ
-				})
-				// Sort by seeds
-				sort.Slice(torrents, func(i, j int) bool {
-					return torrents[i].torrent.Seeders > torrents[j].torrent.Seeders
-				})
-
-				ok := ad.downloadTorrent(torrents[0].torrent, rule, ep)
+				ok := ad.downloadTorrent(t.torrent, rule, t.episode, target)
 				if ok {
 					mu.Lock()
 					downloaded++
@@ -404,6 +397,80 @@ func (ad *AutoDownloader) checkForNewEpisodes() {
 
 }
 
+// getExistingTorrentHashes returns the hashes of the torrents that are already in the torrent client.
+func (ad *AutoDownloader) getExistingTorrentHashes() map[string]struct{} {
+	ret := make(map[string]struct{})
+	if ad.torrentClientRepository == nil {
+		return ret
+	}
+	existingTorrents, err := ad.torrentClientRepository.GetList()
+	if err != nil {
+		return ret
+	}
+	for _, et := range existingTorrents {
+		if et.Hash != "" {
+			ret[et.Hash] = struct{}{}
+		}
+	}
+	return ret
+}
+
+// selectTorrentsToDownload groups the torrents by episode and returns the best torrent for each episode.
+func selectTorrentsToDownload(rule *anime.AutoDownloaderRule, torrentsToDownload []*tmpTorrentToDownload) []*tmpTorrentToDownload {
+	if len(torrentsToDownload) <= 1 {
+		return torrentsToDownload
+	}
+
+	// Make a map [episode]torrents
+	epMap := make(map[int][]*tmpTorrentToDownload)
+	episodes := make([]int, 0)
+	for _, t := range torrentsToDownload {
+		if _, ok := epMap[t.episode]; !ok {
+			epMap[t.episode] = make([]*tmpTorrentToDownload, 0)
+			episodes = append(episodes, t.episode)
+		}
+		epMap[t.episode] = append(epMap[t.episode], t)
+	}
+
+	// Go through each episode group and select the best torrent
+	ret := make([]*tmpTorrentToDownload, 0, len(episodes))
+	for _, ep := range episodes {
+		torrents := epMap[ep]
+
+		if rule.HasQualityProfile() {
+			// Rank each torrent once
+			qualities := make(map[*tmpTorrentToDownload]*releaseQuality, len(torrents))
+			for _, t := range torrents {
+				qualities[t] = getReleaseQuality(t.torrent.Name, t.torrent.ParsedData, rule)
+			}
+			// Sort by quality, then by seeds
+			sort.SliceStable(torrents, func(i, j int) bool {
+				qI := qualities[torrents[i]]
+				qJ := qualities[torrents[j]]
+				if qI.isBetterThan(qJ) || qJ.isBetterThan(qI) {
+					return qI.isBetterThan(qJ)
+				}
+				return torrents[i].torrent.Seeders > torrents[j].torrent.Seeders
+			})
+		} else {
+			// Sort by resolution
+			sort.Slice(torrents, func(i, j int) bool {
+				qI := comparison.ExtractResolutionInt(torrents[i].torrent.ParsedData.VideoResolution)
+				qJ := comparison.ExtractResolutionInt(torrents[j].torrent.ParsedData.VideoResolution)
+				return qI > qJ
+			})
+			// Sort by seeds
+			sort.Slice(torrents, func(i, j int) bool {
+				return torrents[i].torrent.Seeders > torrents[j].torrent.Seeders
+			})
+		}
+
+		ret = append(ret, torrents[0])
+	}
+
+	return ret
+}
+
 func (ad *AutoDownloader) torrentFollowsRule(
 	t *NormalizedTorrent,
 	rule *anime.AutoDownloaderRule,
@@ -434,21 +501,25 @@ func (ad *AutoDownloader) torrentFollowsRule(
 		return -1, false
 	}
 
+	// Episodes already downloaded are only downloaded again if the release is an upgrade
+	if rule.HasQualityProfile() {
+		target := getUpgradeTarget(episode, rule, localEntry, items)
+		if !isWorthUpgrading(getReleaseQuality(t.Name, t.ParsedData, rule), target, rule) {
+			return -1, false
+		}
+	}
+
 	return episode, true
 }
 
-func (ad *AutoDownloader) downloadTorrent(t *NormalizedTorrent, rule *anime.AutoDownloaderRule, episode int) bool {
+// downloadTorrent adds the torrent to the torrent client or debrid service and to the queue.
+// upgrade is set if the rule has a quality profile, it can be nil.
+func (ad *AutoDownloader) downloadTorrent(t *NormalizedTorrent, rule *anime.AutoDownloaderRule, episode int, upgrade *upgradeTarget) bool {
 	defer util.HandlePanicInModuleThen("autodownloader/downloadTorrent", func() {})
 
 	ad.mu.Lock()
 	defer ad.mu.Unlock()
 
-	providerExtension, found := ad.torrentRepository.GetDefaultAnimeProviderExtension()
-	if !found {
-		ad.logger.Warn().Msg("autodownloader: Could not download torrent. Default provider not found")
-		return false
-	}
-
 	if ad.torrentClientRepository == nil {
 		ad.logger.Error().Msg("autodownloader: torrent client not found")
 		return false
@@ -467,13 +538,36 @@ func (ad *AutoDownloader) downloadTorrent(t *NormalizedTorrent, rule *anime.Auto
 	}
 
 	// Get torrent magnet
-	magnet, err := t.GetMagnet(providerExtension.GetProvider())
-	if err != nil {
-		ad.logger.Error().Str("link", t.Link).Str("name", t.Name).Msg("autodownloader: Failed to get magnet link for torrent")
-		return false
+	var magnet string
+	if t.feed != nil {
+		// The torrent comes from a feed, the torrent file is downloaded if there is no magnet link
+		var err error
+		magnet, err = ad.resolveFeedTorrent(t)
+		if err != nil {
+			ad.logger.Error().Err(err).Str("link", t.Link).Str("name", t.Name).Msg("autodownloader: Failed to get magnet link for torrent")
+			return false
+		}
+		// The info hash is only known now if the feed did not provide it
+		if ad.isTorrentQueued(t.InfoHash) {
+			return false
+		}
+	} else {
+		providerExtension, found := ad.torrentRepository.GetDefaultAnimeProviderExtension()
+		if !found {
+			ad.logger.Warn().Msg("autodownloader: Could not download torrent. Default provider not found")
+			return false
+		}
+
+		var err error
+		magnet, err = t.GetMagnet(providerExtension.GetProvider())
+		if err != nil {
+			ad.logger.Error().Str("link", t.Link).Str("name", t.Name).Msg("autodownloader: Failed to get magnet link for torrent")
+			return false
+		}
 	}
 
 	downloaded := false
+	var debridDownload *models.AutoDownloaderDebridDownload
 
 	switch useDebrid {
 	case true:
@@ -481,30 +575,28 @@ func (ad *AutoDownloader) downloadTorrent(t *NormalizedTorrent, rule *anime.Auto
 		// Debrid
 		//
 
-		if ad.settings.DownloadAutomatically {
-			// Add the torrent to the debrid provider and queue it
-			_, err := ad.debridClientRepository.AddAndQueueTorrent(debrid.AddTorrentOptions{
-				MagnetLink: magnet,
-			}, rule.Destination, rule.MediaId)
-			if err != nil {
-				ad.logger.Error().Err(err).Str("link", t.Link).Str("name", t.Name).Msg("autodownloader: Failed to add torrent to debrid")
-				return false
-			}
-		} else {
-			debridProvider, err := ad.debridClientRepository.GetProvider()
-			if err != nil {
-				ad.logger.Error().Err(err).Msg("autodownloader: Failed to get debrid provider")
-				return false
-			}
+		debridProvider, err := ad.debridClientRepository.GetProvider()
+		if err != nil {
+			ad.logger.Error().Err(err).Msg("autodownloader: Failed to get debrid provider")
+			return false
+		}
 
-			// Add the torrent to the debrid provider
-			_, err = debridProvider.AddTorrent(debrid.AddTorrentOptions{
-				MagnetLink: magnet,
-			})
-			if err != nil {
-				ad.logger.Error().Err(err).Str("link", t.Link).Str("name", t.Name).Msg("autodownloader: Failed to add torrent to debrid")
-				return false
-			}
+		// Add the torrent to the debrid provider
+		torrentItemId, err := debridProvider.AddTorrent(debrid.AddTorrentOptions{
+			MagnetLink: magnet,
+			InfoHash:   t.InfoHash,
+		})
+		if err != nil {
+			ad.logger.Error().Err(err).Str("link", t.Link).Str("name", t.Name).Msg("autodownloader: Failed to add torrent to debrid")
+			return false
+		}
+
+		// The files are downloaded by the Auto Downloader once the torrent is ready.
+		// If "Download automatically" is disabled, the download is paused until the user starts it.
+		debridDownload = &models.AutoDownloaderDebridDownload{
+			Provider:      debridProvider.GetSettings().ID,
+			TorrentItemID: torrentItemId,
+			Destination:   rule.Destination,
 		}
 
 	case false:
@@ -554,8 +646,19 @@ func (ad *AutoDownloader) downloadTorrent(t *NormalizedTorrent, rule *anime.Auto
 		TorrentName: t.Name,
 		Downloaded:  downloaded,
 	}
+	if upgrade != nil && upgrade.exists {
+		item.IsUpgrade = true
+		if !rule.QualityProfile.KeepOldFiles {
+			item.ReplacesPath = upgrade.replacesPath
+		}
+		ad.logger.Info().Str("name", t.Name).Int("episode", episode).Msg("autodownloader: Upgrading episode")
+	}
 	_ = ad.database.InsertAutoDownloaderItem(item)
 
+	if debridDownload != nil {
+		ad.queueDebridDownload(item, debridDownload.Provider, debridDownload.TorrentItemID, debridDownload.Destination, !ad.settings.DownloadAutomatically)
+	}
+
 	return true
 }
 
@@ -738,13 +841,27 @@ func (ad *AutoDownloader) isSeasonAndEpisodeMatch(
 	listEntry *anilist.AnimeListEntry,
 	localEntry *anime.LocalFileWrapperEntry,
 	items []*models.AutoDownloaderItem,
-) (a int, b bool) {
+) (int, bool) {
+	episode, _, ok := ad.matchSeasonAndEpisode(ad.settings, parsedData, rule, listEntry, localEntry, items)
+	return episode, ok
+}
+
+// matchSeasonAndEpisode is isSeasonAndEpisodeMatch, it also returns the reason the torrent was rejected.
+func (ad *AutoDownloader) matchSeasonAndEpisode(
+	settings *models.AutoDownloaderSettings,
+	parsedData *seanime_parser.Metadata,
+	rule *anime.AutoDownloaderRule,
+	listEntry *anilist.AnimeListEntry,
+	localEntry *anime.LocalFileWrapperEntry,
+	items []*models.AutoDownloaderItem,
+) (a int, reason string, b bool) {
 	defer util.HandlePanicInModuleThen("autodownloader/isSeasonAndEpisodeMatch", func() {
+		reason = "Unexpected error"
 		b = false
 	})
 
 	if listEntry == nil {
-		return -1, false
+		return -1, "Media not found in the anime list", false
 	}
 
 	episodes := parsedData.EpisodeNumber
@@ -752,7 +869,7 @@ func (ad *AutoDownloader) isSeasonAndEpisodeMatch(
 	// Skip if we parsed more than one episode number (e.g. "01-02")
 	// We can't handle this case since it might be a batch release
 	if len(episodes) > 1 {
-		return -1, false
+		return -1, "Multiple episode numbers, the release might be a batch", false
 	}
 
 	var ok bool
@@ -774,20 +891,23 @@ func (ad *AutoDownloader) isSeasonAndEpisodeMatch(
 		// Return true if the media (has only one episode or is a movie) AND (is not in the library)
 		if listEntry.GetMedia().GetCurrentEpisodeCount() == 1 || *listEntry.GetMedia().GetFormat() == anilist.MediaFormatMovie {
 			// Make sure it wasn't already added
-			for _, item := range items {
-				if item.Episode == 1 {
-					return -1, false // Skip, file already downloaded
+			// If the rule has a quality profile, existing releases are compared in torrentFollowsRule
+			if !rule.HasQualityProfile() {
+				for _, item := range items {
+					if item.Episode == 1 {
+						return -1, "Episode 1 was already downloaded", false // Skip, file already downloaded
+					}
 				}
-			}
-			// Make sure it doesn't exist in the library
-			if localEntry != nil {
-				if _, found := localEntry.FindLocalFileWithEpisodeNumber(1); found {
-					return -1, false // Skip, file already exists
+				// Make sure it doesn't exist in the library
+				if localEntry != nil {
+					if _, found := localEntry.FindLocalFileWithEpisodeNumber(1); found {
+						return -1, "Episode 1 is already in the library", false // Skip, file already exists
+					}
 				}
 			}
-			return 1, true // Good to go
+			return 1, "", true // Good to go
 		}
-		return -1, false
+		return -1, "No episode number and the media has more than one episode", false
 	}
 
 	// +---------------------+
@@ -809,23 +929,26 @@ func (ad *AutoDownloader) isSeasonAndEpisodeMatch(
 		ad.mu.Unlock()
 	}
 
-	// Return false if the episode is already downloaded
-	for _, item := range items {
-		if item.Episode == episode {
-			return -1, false // Skip, file already downloaded
+	// If the rule has a quality profile, existing releases are compared in torrentFollowsRule
+	if !rule.HasQualityProfile() {
+		// Return false if the episode is already downloaded
+		for _, item := range items {
+			if item.Episode == episode {
+				return -1, fmt.Sprintf("Episode %d was already downloaded", episode), false // Skip, file already downloaded
+			}
 		}
-	}
 
-	// Return false if the episode is already in the library
-	if localEntry != nil {
-		if _, found := localEntry.FindLocalFileWithEpisodeNumber(episode); found {
-			return -1, false
+		// Return false if the episode is already in the library
+		if localEntry != nil {
+			if _, found := localEntry.FindLocalFileWithEpisodeNumber(episode); found {
+				return -1, fmt.Sprintf("Episode %d is already in the library", episode), false
+			}
 		}
 	}
 
 	// As a last check, make sure the seasons match ONLY if the episode number is not absolute
 	// We do this check only for "likely" title comparison type since the season numbers are not compared
-	if ad.settings.EnableSeasonCheck {
+	if settings.EnableSeasonCheck {
 		if !hasAbsoluteEpisode {
 			switch rule.TitleComparisonType {
 			case anime.AutoDownloaderRuleTitleComparisonLikely:
@@ -835,10 +958,10 @@ func (ad *AutoDownloader) isSeasonAndEpisodeMatch(
 					if ok && season > 1 {
 						parsedComparisonTitle := seanime_parser.Parse(rule.ComparisonTitle)
 						if len(parsedComparisonTitle.SeasonNumber) == 0 {
-							return -1, false
+							return -1, fmt.Sprintf("Season %d but the comparison title has no season number", season), false
 						}
 						if season != util.StringToIntMust(parsedComparisonTitle.SeasonNumber[0]) {
-							return -1, false
+							return -1, fmt.Sprintf("Season %d does not match the season of the comparison title", season), false
 						}
 					}
 				}
@@ -853,9 +976,9 @@ func (ad *AutoDownloader) isSeasonAndEpisodeMatch(
 		// +---------------------+
 		// Return false if the user has already watched the episode
 		if listEntry.Progress != nil && *listEntry.GetProgress() > episode {
-			return -1, false
+			return -1, fmt.Sprintf("Episode %d was already watched", episode), false
 		}
-		return episode, true // Good to go
+		return episode, "", true // Good to go
 	case anime.AutoDownloaderRuleEpisodeSelected:
 		// +---------------------+
 		// | Episode "Selected"  |
@@ -863,12 +986,12 @@ func (ad *AutoDownloader) isSeasonAndEpisodeMatch(
 		// Return true if the episode is in the list of selected episodes
 		for _, ep := range rule.EpisodeNumbers {
 			if ep == episode {
-				return episode, true // Good to go
+				return episode, "", true // Good to go
 			}
 		}
-		return -1, false
+		return -1, fmt.Sprintf("Episode %d is not one of the selected episodes", episode), false
 	}
-	return -1, false
+	return -1, "Unknown episode type", false
 }
 
 func (ad *AutoDownloader) getRuleListEntry(rule *anime.AutoDownloaderRule) (*anilist.AnimeListEntry, bool) {
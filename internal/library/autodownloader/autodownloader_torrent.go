@@ -4,8 +4,10 @@ import (
 	"errors"
 	hibiketorrent "github.com/5rahim/hibike/pkg/extension/torrent"
 	"github.com/samber/lo"
+	"seanime/internal/database/models"
 	"seanime/internal/library/anime"
 	"seanime/seanime-parser"
+	"strings"
 	"sync"
 )
 
@@ -15,13 +17,92 @@ type (
 	NormalizedTorrent struct {
 		hibiketorrent.AnimeTorrent
 		ParsedData *seanime_parser.Metadata
-		magnet     string // Access using GetMagnet()
+		magnet     string                     // Access using GetMagnet()
+		feed       *models.AutoDownloaderFeed // Set if the torrent comes from an RSS or Atom feed
 	}
 )
 
-func (ad *AutoDownloader) getLatestTorrents(rules []*anime.AutoDownloaderRule) (ret []*NormalizedTorrent, err error) {
+// getLatestTorrents returns the latest torrents from the provider and the feeds.
+// Torrents with the same info hash are only returned once.
+func (ad *AutoDownloader) getLatestTorrents(settings *models.AutoDownloaderSettings, rules []*anime.AutoDownloaderRule, useProvider bool, feeds []*models.AutoDownloaderFeed) (ret []*NormalizedTorrent, err error) {
 	ad.logger.Debug().Msg("autodownloader: Checking for new episodes")
 
+	ret = make([]*NormalizedTorrent, 0)
+	if useProvider {
+		ret, err = ad.getProviderTorrents(settings, rules)
+		if err != nil {
+			// Feeds can still be used
+			if len(feeds) == 0 {
+				return nil, err
+			}
+			ret = make([]*NormalizedTorrent, 0)
+		}
+	}
+
+	if len(feeds) > 0 {
+		ret = append(ret, ad.getFeedTorrents(feeds)...)
+	}
+
+	ret = lo.UniqBy(ret, func(t *NormalizedTorrent) string {
+		if t.InfoHash == "" {
+			return t.Provider + t.Link + t.Name
+		}
+		return t.InfoHash
+	})
+
+	return ret, nil
+}
+
+// filterQueuedTorrents removes the torrents that have already been added to the queue, based on their info hash or link.
+func (ad *AutoDownloader) filterQueuedTorrents(torrents []*NormalizedTorrent) []*NormalizedTorrent {
+	items, err := ad.database.GetAutoDownloaderItems()
+	if err != nil || len(items) == 0 {
+		return torrents
+	}
+
+	hashes := make(map[string]struct{}, len(items))
+	links := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		if item.Hash != "" {
+			hashes[strings.ToLower(item.Hash)] = struct{}{}
+		}
+		if item.Link != "" {
+			links[item.Link] = struct{}{}
+		}
+	}
+
+	return lo.Filter(torrents, func(t *NormalizedTorrent, _ int) bool {
+		if _, found := hashes[strings.ToLower(t.InfoHash)]; found && t.InfoHash != "" {
+			return false
+		}
+		// Only feed links are compared, they are stable
+		if _, found := links[t.Link]; found && t.feed != nil && t.Link != "" {
+			return false
+		}
+		return true
+	})
+}
+
+// isTorrentQueued returns true if a queued item has the info hash.
+func (ad *AutoDownloader) isTorrentQueued(infoHash string) bool {
+	if infoHash == "" {
+		return false
+	}
+	items, err := ad.database.GetAutoDownloaderItems()
+	if err != nil {
+		return false
+	}
+	for _, item := range items {
+		if strings.EqualFold(item.Hash, infoHash) {
+			return true
+		}
+	}
+	return false
+}
+
+// getProviderTorrents returns the latest torrents from the default provider.
+func (ad *AutoDownloader) getProviderTorrents(settings *models.AutoDownloaderSettings, rules []*anime.AutoDownloaderRule) (ret []*NormalizedTorrent, err error) {
+
 	providerExtension, ok := ad.torrentRepository.GetDefaultAnimeProviderExtension()
 	if !ok {
 		ad.logger.Warn().Msg("autodownloader: No default torrent provider found")
@@ -35,7 +116,7 @@ func (ad *AutoDownloader) getLatestTorrents(rules []*anime.AutoDownloaderRule) (
 		return nil, err
 	}
 
-	if ad.settings.EnableEnhancedQueries {
+	if settings.EnableEnhancedQueries {
 		// Get unique release groups
 		uniqueReleaseGroups := GetUniqueReleaseGroups(rules)
 		// Filter the torrents
@@ -0,0 +1,627 @@
+package autodownloader
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"github.com/samber/lo"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"seanime/internal/database/models"
+	"seanime/internal/debrid/client"
+	"seanime/internal/debrid/debrid"
+	"seanime/internal/events"
+	"seanime/internal/notifier"
+	"seanime/internal/util"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Debrid downloads
+//
+// When the Auto Downloader uses a debrid service, it adds the torrent to the service and tracks it as an AutoDownloaderDebridDownload.
+// Once the torrent is ready, the files are downloaded to a staging folder in the cache directory, unpacked if they are archives
+// and moved to the rule's destination. Partial downloads are resumed after a restart using HTTP range requests.
+// The staging folder is outside the library so that partial files are not picked up by the watcher.
+//
+// If "Download automatically" is disabled, the download is paused until the user starts it.
+
+const (
+	DebridDownloadStatusPaused      = "paused" // Waiting for the user to start the download
+	DebridDownloadStatusQueued      = "queued"
+	DebridDownloadStatusDownloading = "downloading"
+	DebridDownloadStatusCompleted   = "completed"
+	DebridDownloadStatusFailed      = "failed"
+)
+
+const (
+	// maxDebridDownloadAttempts is the number of failed attempts after which a download is marked as failed.
+	maxDebridDownloadAttempts   = 5
+	debridDownloadCheckInterval = time.Minute
+	// debridDownloadStallTimeout is the time without receiving data after which a download is interrupted.
+	// The download is resumed on the next attempt.
+	debridDownloadStallTimeout = 2 * time.Minute
+)
+
+var ErrDebridDownloadNotPaused = errors.New("autodownloader: The download is not paused")
+
+// LibraryScanner scans the files placed in the library by the Auto Downloader.
+type LibraryScanner interface {
+	ScanPaths(paths []string)
+}
+
+// SetLibraryScanner sets the scanner used once debrid downloads are placed in the library.
+func (ad *AutoDownloader) SetLibraryScanner(scanner LibraryScanner) {
+	if ad == nil {
+		return
+	}
+	ad.libraryScanner = scanner
+}
+
+// queueDebridDownload tracks a torrent added to the debrid service.
+// It should be called after the AutoDownloaderItem is inserted.
+// If paused is true, the files are only downloaded once the user starts the download, see StartDebridDownload.
+func (ad *AutoDownloader) queueDebridDownload(item *models.AutoDownloaderItem, provider string, torrentItemId string, destination string, paused bool) {
+	status := DebridDownloadStatusQueued
+	if paused {
+		status = DebridDownloadStatusPaused
+	}
+
+	err := ad.database.InsertAutoDownloaderDebridDownload(&models.AutoDownloaderDebridDownload{
+		ItemID:        item.ID,
+		MediaID:       item.MediaID,
+		Episode:       item.Episode,
+		Provider:      provider,
+		TorrentItemID: torrentItemId,
+		TorrentName:   item.TorrentName,
+		Destination:   destination,
+		Status:        status,
+	})
+	if err != nil {
+		ad.logger.Error().Err(err).Str("name", item.TorrentName).Msg("autodownloader: Failed to queue debrid download")
+		return
+	}
+
+	if paused {
+		return
+	}
+
+	ad.checkDebridDownloads()
+}
+
+// StartDebridDownload queues a paused download.
+// The files are downloaded once the torrent is ready on the debrid service.
+func (ad *AutoDownloader) StartDebridDownload(id uint) error {
+	d, err := ad.database.GetAutoDownloaderDebridDownload(id)
+	if err != nil {
+		return err
+	}
+	if d.Status != DebridDownloadStatusPaused {
+		return ErrDebridDownloadNotPaused
+	}
+
+	d.Status = DebridDownloadStatusQueued
+	if err := ad.database.SaveAutoDownloaderDebridDownload(d); err != nil {
+		return err
+	}
+
+	ad.logger.Debug().Str("name", d.TorrentName).Msg("autodownloader: Debrid download started by the user")
+
+	ad.startDebridDownloadLoop()
+	ad.checkDebridDownloads()
+	return nil
+}
+
+// checkDebridDownloads checks the queued downloads as soon as possible, the torrent might already be cached.
+func (ad *AutoDownloader) checkDebridDownloads() {
+	select {
+	case ad.debridCheckCh <- struct{}{}:
+	default:
+	}
+}
+
+// startDebridDownloadLoop checks the queued debrid downloads periodically.
+// Interrupted downloads are resumed on the first check.
+func (ad *AutoDownloader) startDebridDownloadLoop() {
+	ad.debridLoopOnce.Do(func() {
+		go func() {
+			defer util.HandlePanicInModuleThen("autodownloader/startDebridDownloadLoop", func() {})
+
+			for {
+				ad.processDebridDownloads()
+				select {
+				case <-time.After(debridDownloadCheckInterval):
+				case <-ad.debridCheckCh:
+				}
+			}
+		}()
+	})
+}
+
+// processDebridDownloads downloads the ready torrents and scans the placed files.
+func (ad *AutoDownloader) processDebridDownloads() {
+	defer util.HandlePanicInModuleThen("autodownloader/processDebridDownloads", func() {})
+
+	if ad.debridClientRepository == nil {
+		return
+	}
+
+	downloads, err := ad.database.GetPendingAutoDownloaderDebridDownloads()
+	if err != nil || len(downloads) == 0 {
+		return
+	}
+
+	provider, err := ad.debridClientRepository.GetProvider()
+	if err != nil {
+		return
+	}
+
+	placedPaths := make([]string, 0)
+
+	for _, d := range downloads {
+		// The torrent was added to another provider
+		if d.Provider != provider.GetSettings().ID {
+			continue
+		}
+
+		placedPaths = append(placedPaths, ad.processDebridDownload(provider, d)...)
+	}
+
+	// The scanner ignores the paths if the autoscanner is disabled
+	if len(placedPaths) > 0 && ad.libraryScanner != nil {
+		ad.libraryScanner.ScanPaths(placedPaths)
+	}
+}
+
+// processDebridDownload downloads the torrent if it is ready and returns the placed paths.
+// Only failed downloads count as attempts, the torrent is checked again later if the debrid service cannot be reached.
+func (ad *AutoDownloader) processDebridDownload(provider debrid.Provider, d *models.AutoDownloaderDebridDownload) []string {
+	torrent, err := provider.GetTorrent(d.TorrentItemID)
+	if err != nil {
+		ad.logger.Warn().Err(err).Str("name", d.TorrentName).Msg("autodownloader: Failed to get debrid torrent, checking again later")
+		d.Error = err.Error()
+		_ = ad.database.SaveAutoDownloaderDebridDownload(d)
+		return nil
+	}
+
+	if torrent.Status == debrid.TorrentItemStatusError {
+		d.Attempts = maxDebridDownloadAttempts - 1
+		ad.failDebridDownload(d, fmt.Errorf("torrent failed on the debrid service"))
+		return nil
+	}
+
+	if !torrent.IsReady {
+		return nil
+	}
+
+	ad.logger.Debug().Str("name", d.TorrentName).Msg("autodownloader: Debrid torrent is ready, downloading")
+
+	paths, err := ad.downloadDebridTorrent(provider, d)
+	if err != nil {
+		ad.failDebridDownload(d, err)
+		return nil
+	}
+
+	d.Status = DebridDownloadStatusCompleted
+	d.Error = ""
+	_ = ad.database.SaveAutoDownloaderDebridDownload(d)
+
+	// The item is now treated like a torrent downloaded by the torrent client
+	_ = ad.database.UpdateAutoDownloaderItem(d.ItemID, &models.AutoDownloaderItem{Downloaded: true})
+
+	ad.wsEventManager.SendEvent(events.DebridDownloadProgress, map[string]interface{}{
+		"status": "completed",
+		"itemID": d.TorrentItemID,
+	})
+	ad.logger.Info().Str("name", d.TorrentName).Strs("paths", paths).Msg("autodownloader: Debrid download completed")
+	notifier.GlobalNotifier.Notify(notifier.AutoDownloader, fmt.Sprintf("Downloaded %q", d.TorrentName))
+
+	return paths
+}
+
+// failDebridDownload records a failed attempt. The download is retried on the next check until it reaches the maximum number of attempts.
+func (ad *AutoDownloader) failDebridDownload(d *models.AutoDownloaderDebridDownload, err error) {
+	d.Attempts++
+	d.Error = err.Error()
+
+	if d.Attempts >= maxDebridDownloadAttempts {
+		d.Status = DebridDownloadStatusFailed
+		_ = os.RemoveAll(ad.getDebridStagingDir(d))
+		ad.logger.Error().Err(err).Str("name", d.TorrentName).Msg("autodownloader: Debrid download failed")
+		ad.wsEventManager.SendEvent(events.DebridDownloadProgress, map[string]interface{}{
+			"status": "cancelled",
+			"itemID": d.TorrentItemID,
+		})
+		notifier.GlobalNotifier.Notify(notifier.AutoDownloader, fmt.Sprintf("Failed to download %q", d.TorrentName))
+	} else {
+		ad.logger.Warn().Err(err).Str("name", d.TorrentName).Int("attempts", d.Attempts).Msg("autodownloader: Debrid download attempt failed, retrying later")
+	}
+
+	_ = ad.database.SaveAutoDownloaderDebridDownload(d)
+}
+
+// newDownloadClient returns the client used to download the files from the debrid service.
+// There is no overall timeout since the files can be large, stalled downloads are cancelled by downloadDebridFile.
+func newDownloadClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+			TLSHandshakeTimeout:   30 * time.Second,
+			ResponseHeaderTimeout: time.Minute,
+		},
+	}
+}
+
+// getDebridStagingDir returns the folder where the files are downloaded before being placed in the destination.
+func (ad *AutoDownloader) getDebridStagingDir(d *models.AutoDownloaderDebridDownload) string {
+	return filepath.Join(ad.debridStagingDir, strconv.Itoa(int(d.ID)))
+}
+
+// downloadDebridTorrent downloads the files of the torrent and places them in the destination.
+// It returns the placed files and folders.
+func (ad *AutoDownloader) downloadDebridTorrent(provider debrid.Provider, d *models.AutoDownloaderDebridDownload) ([]string, error) {
+	d.Status = DebridDownloadStatusDownloading
+	_ = ad.database.SaveAutoDownloaderDebridDownload(d)
+
+	// Providers that cannot bundle the files of a torrent return one URL per file
+	downloadUrls, err := provider.GetTorrentDownloadUrls(debrid.DownloadTorrentOptions{ID: d.TorrentItemID})
+	if err != nil {
+		return nil, err
+	}
+
+	stagingDir := ad.getDebridStagingDir(d)
+	if err := os.MkdirAll(stagingDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create staging folder: %w", err)
+	}
+
+	files := make([]string, 0, len(downloadUrls))
+	for idx, u := range downloadUrls {
+		fp, err := ad.downloadDebridFile(d, idx, u, stagingDir)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, fp)
+	}
+
+	placedPaths, err := placeDebridFiles(files, stagingDir, d.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = os.RemoveAll(stagingDir)
+
+	return placedPaths, nil
+}
+
+// downloadDebridFile downloads a file to the staging folder and returns its path.
+//
+// The file is written to "{index}.part" and renamed to "{index}-{filename}" once its size is verified.
+// If the part file exists, the download resumes where it stopped.
+func (ad *AutoDownloader) downloadDebridFile(d *models.AutoDownloaderDebridDownload, idx int, downloadUrl string, stagingDir string) (string, error) {
+
+	// Already downloaded before a restart
+	if matches, _ := filepath.Glob(filepath.Join(stagingDir, strconv.Itoa(idx)+"-*")); len(matches) > 0 {
+		return matches[0], nil
+	}
+
+	partPath := filepath.Join(stagingDir, strconv.Itoa(idx)+".part")
+
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	// The request is cancelled if no data is received for a while
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stallTimer := time.AfterFunc(debridDownloadStallTimeout, cancel)
+	defer stallTimer.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadUrl, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := ad.downloadClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute download request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	expectedSize := int64(-1)
+	flags := os.O_CREATE | os.O_WRONLY
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		ad.logger.Debug().Str("name", d.TorrentName).Int64("offset", offset).Msg("autodownloader: Resuming debrid download")
+		flags |= os.O_APPEND
+		expectedSize = getContentRangeSize(resp.Header.Get("Content-Range"))
+		if expectedSize < 0 && resp.ContentLength >= 0 {
+			expectedSize = offset + resp.ContentLength
+		}
+	case http.StatusOK:
+		// The server does not support range requests, start over
+		offset = 0
+		flags |= os.O_TRUNC
+		expectedSize = resp.ContentLength
+	case http.StatusRequestedRangeNotSatisfiable:
+		_ = os.Remove(partPath)
+		return "", fmt.Errorf("partial download is invalid, restarting")
+	default:
+		return "", fmt.Errorf("download request failed: %s", resp.Status)
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open part file: %w", err)
+	}
+
+	progress := &debridDownloadProgressWriter{
+		ad:         ad,
+		itemId:     d.TorrentItemID,
+		totalBytes: offset,
+		totalSize:  expectedSize,
+		lastSent:   time.Now(),
+		stallTimer: stallTimer,
+	}
+	_, err = io.Copy(io.MultiWriter(file, progress), resp.Body)
+	_ = file.Close()
+	if err != nil {
+		// The part file is kept, the download is resumed on the next attempt
+		return "", fmt.Errorf("download interrupted: %w", err)
+	}
+
+	// Verify the size of the file
+	info, err := os.Stat(partPath)
+	if err != nil {
+		return "", err
+	}
+	if expectedSize >= 0 && info.Size() != expectedSize {
+		if info.Size() > expectedSize {
+			_ = os.Remove(partPath)
+		}
+		return "", fmt.Errorf("size mismatch, expected %d bytes, got %d", expectedSize, info.Size())
+	}
+
+	filename := getDebridDownloadFilename(resp, downloadUrl)
+	if filename == "" {
+		filename = "file-" + strconv.Itoa(idx)
+	}
+
+	dest := filepath.Join(stagingDir, strconv.Itoa(idx)+"-"+filename)
+	if err := os.Rename(partPath, dest); err != nil {
+		return "", fmt.Errorf("failed to rename part file: %w", err)
+	}
+
+	return dest, nil
+}
+
+type debridDownloadProgressWriter struct {
+	ad         *AutoDownloader
+	itemId     string
+	totalBytes int64
+	totalSize  int64
+	lastBytes  int64
+	lastSent   time.Time
+	stallTimer *time.Timer // Reset each time data is received
+}
+
+func (w *debridDownloadProgressWriter) Write(p []byte) (int, error) {
+	w.totalBytes += int64(len(p))
+	w.stallTimer.Reset(debridDownloadStallTimeout)
+
+	if elapsed := time.Since(w.lastSent); elapsed > 2*time.Second {
+		totalSize := "-"
+		if w.totalSize > 0 {
+			totalSize = humanize.Bytes(uint64(w.totalSize))
+		}
+		w.ad.wsEventManager.SendEvent(events.DebridDownloadProgress, map[string]interface{}{
+			"status":     "downloading",
+			"itemID":     w.itemId,
+			"totalBytes": humanize.Bytes(uint64(w.totalBytes)),
+			"totalSize":  totalSize,
+			"speed":      int(float64(w.totalBytes-w.lastBytes) / 1024 / elapsed.Seconds()), // KB/s
+		})
+		w.lastBytes = w.totalBytes
+		w.lastSent = time.Now()
+	}
+
+	return len(p), nil
+}
+
+// getContentRangeSize returns the complete size from a Content-Range header, e.g. "bytes 100-199/200" -> 200.
+// Returns -1 if the size is unknown.
+func getContentRangeSize(contentRange string) int64 {
+	_, size, found := strings.Cut(contentRange, "/")
+	if !found || size == "*" {
+		return -1
+	}
+	ret, err := strconv.ParseInt(strings.TrimSpace(size), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return ret
+}
+
+// getDebridDownloadFilename returns the file name from the Content-Disposition header, or from the URL.
+// Archives without an extension get one from the Content-Type header so that they are unpacked.
+func getDebridDownloadFilename(resp *http.Response, downloadUrl string) string {
+	filename := ""
+
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		filename = params["filename"]
+	}
+
+	if filename == "" {
+		if u, err := url.Parse(downloadUrl); err == nil {
+			if base := path.Base(u.Path); path.Ext(base) != "" {
+				filename = base
+			}
+		}
+	}
+
+	// Do not allow paths
+	filename = filepath.Base(filepath.Clean("/" + filename))
+	if filename == "/" || filename == "." || filename == string(filepath.Separator) {
+		filename = ""
+	}
+
+	if filepath.Ext(filename) == "" {
+		if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+			switch mediaType {
+			case "application/zip":
+				filename = cmp.Or(filename, "download") + ".zip"
+			case "application/x-rar-compressed", "application/vnd.rar":
+				filename = cmp.Or(filename, "download") + ".rar"
+			}
+		}
+	}
+
+	return filename
+}
+
+// placeDebridFiles unpacks the archives and moves the files to the destination.
+// It returns the placed files and folders.
+func placeDebridFiles(files []string, stagingDir string, destination string) ([]string, error) {
+	placedPaths := make([]string, 0, len(files))
+
+	for _, fp := range files {
+		// Remove the "{index}-" prefix
+		_, name, _ := strings.Cut(filepath.Base(fp), "-")
+
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".zip", ".rar":
+			archivePath := filepath.Join(filepath.Dir(fp), name)
+			if err := os.Rename(fp, archivePath); err != nil {
+				return nil, err
+			}
+			extractedDir, err := debrid_client.ExtractArchive(archivePath, stagingDir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack %s: %w", name, err)
+			}
+			_ = os.Remove(archivePath)
+
+			entries, err := os.ReadDir(extractedDir)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				dest := filepath.Join(destination, entry.Name())
+				if err := movePath(filepath.Join(extractedDir, entry.Name()), dest); err != nil {
+					return nil, err
+				}
+				placedPaths = append(placedPaths, dest)
+			}
+		default:
+			dest := filepath.Join(destination, name)
+			if err := movePath(fp, dest); err != nil {
+				return nil, err
+			}
+			placedPaths = append(placedPaths, dest)
+		}
+	}
+
+	return placedPaths, nil
+}
+
+// movePath moves a file or folder. Folders are merged with existing folders and existing files are replaced.
+// It fails if the destination exists and is not of the same type, e.g. a file would replace a folder.
+func movePath(src string, dest string) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	destInfo, err := os.Stat(dest)
+	if err == nil {
+		if srcInfo.IsDir() != destInfo.IsDir() {
+			return fmt.Errorf("cannot replace %s, it already exists and is not a %s", dest, lo.Ternary(srcInfo.IsDir(), "folder", "file"))
+		}
+		if srcInfo.IsDir() {
+			entries, err := os.ReadDir(src)
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				if err := movePath(filepath.Join(src, entry.Name()), filepath.Join(dest, entry.Name())); err != nil {
+					return err
+				}
+			}
+			return os.Remove(src)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := os.Rename(src, dest); err == nil {
+		return nil
+	}
+
+	// The staging folder can be on another device, the files are copied instead
+	if srcInfo.IsDir() {
+		if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := movePath(filepath.Join(src, entry.Name()), filepath.Join(dest, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return os.Remove(src)
+	}
+
+	return copyFile(src, dest)
+}
+
+// copyFile copies the file to a temporary file next to the destination, renames it and removes the source.
+// The temporary file does not have a video extension so that it is ignored by the scanner until it is complete.
+func copyFile(src string, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmpPath := dest + ".part"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, dest); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	_ = in.Close()
+	return os.Remove(src)
+}
@@ -0,0 +1,331 @@
+package autodownloader
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/debrid/debrid"
+	"seanime/internal/events"
+	"seanime/internal/util"
+	"testing"
+	"time"
+)
+
+// fakeDebridProvider returns the download URLs of the test server.
+type fakeDebridProvider struct {
+	debrid.Provider
+	downloadUrls []string
+	torrentErr   error
+}
+
+func (f *fakeDebridProvider) GetTorrent(id string) (*debrid.TorrentItem, error) {
+	if f.torrentErr != nil {
+		return nil, f.torrentErr
+	}
+	return &debrid.TorrentItem{ID: id, IsReady: true}, nil
+}
+
+func (f *fakeDebridProvider) GetSettings() debrid.Settings {
+	return debrid.Settings{ID: "fake", Name: "Fake"}
+}
+
+func (f *fakeDebridProvider) GetTorrentDownloadUrls(opts debrid.DownloadTorrentOptions) ([]string, error) {
+	return f.downloadUrls, nil
+}
+
+// fileServer serves files with range requests support.
+// If truncateAt is set, the first response is cut after that many bytes, like an interrupted download.
+type fileServer struct {
+	files      map[string][]byte
+	truncateAt int
+	ranges     []string
+}
+
+func (f *fileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	content, ok := f.files[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	f.ranges = append(f.ranges, r.Header.Get("Range"))
+
+	if f.truncateAt > 0 {
+		truncateAt := f.truncateAt
+		f.truncateAt = 0
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(content[:truncateAt])
+		w.(http.Flusher).Flush()
+		// Close the connection before the end of the body
+		if hj, ok := w.(http.Hijacker); ok {
+			conn, _, _ := hj.Hijack()
+			_ = conn.Close()
+		}
+		return
+	}
+
+	http.ServeContent(w, r, filepath.Base(r.URL.Path), time.Time{}, bytes.NewReader(content))
+}
+
+func newTestZip(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func newTestDebridAutoDownloader(t *testing.T) *AutoDownloader {
+	t.Setenv("TEST_ENV", "true")
+
+	logger := util.NewLogger()
+	database, err := db.NewDatabase(t.TempDir(), "test", logger)
+	require.NoError(t, err)
+
+	return &AutoDownloader{
+		logger:           logger,
+		database:         database,
+		wsEventManager:   events.NewMockWSEventManager(logger),
+		downloadClient:   newDownloadClient(),
+		debridStagingDir: t.TempDir(),
+		debridCheckCh:    make(chan struct{}, 1),
+	}
+}
+
+func TestDownloadDebridTorrent(t *testing.T) {
+	episode := bytes.Repeat([]byte("frieren"), 10000)
+
+	files := &fileServer{
+		files: map[string][]byte{
+			"/dl/episode.mkv": episode,
+			"/dl/batch.zip": newTestZip(t, map[string]string{
+				"Frieren/Frieren - 02.mkv": "episode 2",
+				"Frieren/Frieren - 03.mkv": "episode 3",
+			}),
+		},
+		truncateAt: 1000,
+	}
+	server := httptest.NewServer(files)
+	t.Cleanup(server.Close)
+
+	ad := newTestDebridAutoDownloader(t)
+	destination := t.TempDir()
+
+	// An episode from a previous download is replaced
+	require.NoError(t, os.MkdirAll(filepath.Join(destination, "Frieren"), os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(destination, "Frieren", "Frieren - 01.mkv"), []byte("episode 1"), 0644))
+
+	d := &models.AutoDownloaderDebridDownload{
+		ItemID:        1,
+		Provider:      "fake",
+		TorrentItemID: "TORRENT1",
+		TorrentName:   "Frieren",
+		Destination:   destination,
+		Status:        DebridDownloadStatusQueued,
+	}
+	require.NoError(t, ad.database.InsertAutoDownloaderDebridDownload(d))
+
+	provider := &fakeDebridProvider{downloadUrls: []string{server.URL + "/dl/episode.mkv", server.URL + "/dl/batch.zip"}}
+
+	// The first download is interrupted
+	_, err := ad.downloadDebridTorrent(provider, d)
+	require.Error(t, err)
+	assert.Equal(t, DebridDownloadStatusDownloading, d.Status)
+
+	info, err := os.Stat(filepath.Join(ad.getDebridStagingDir(d), "0.part"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), info.Size())
+
+	// The download resumes where it stopped
+	placed, err := ad.downloadDebridTorrent(provider, d)
+	require.NoError(t, err)
+	assert.Contains(t, files.ranges, "bytes=1000-")
+
+	assert.ElementsMatch(t, []string{
+		filepath.Join(destination, "episode.mkv"),
+		filepath.Join(destination, "Frieren"),
+	}, placed)
+
+	content, err := os.ReadFile(filepath.Join(destination, "episode.mkv"))
+	require.NoError(t, err)
+	assert.Equal(t, episode, content)
+
+	// The extracted folder is merged with the existing one
+	for _, name := range []string{"Frieren - 01.mkv", "Frieren - 02.mkv", "Frieren - 03.mkv"} {
+		assert.FileExists(t, filepath.Join(destination, "Frieren", name))
+	}
+
+	// The staging folder is removed, nothing was staged in the destination
+	assert.NoDirExists(t, ad.getDebridStagingDir(d))
+	entries, err := os.ReadDir(destination)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"episode.mkv", "Frieren"}, lo.Map(entries, func(e os.DirEntry, _ int) string { return e.Name() }))
+}
+
+func TestDownloadDebridFile_SizeMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server returns fewer bytes than announced in the Content-Range header
+		w.Header().Set("Content-Range", "bytes 5-9/20")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write([]byte("12345"))
+	}))
+	t.Cleanup(server.Close)
+
+	ad := newTestDebridAutoDownloader(t)
+	stagingDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(stagingDir, "0.part"), []byte("abcde"), 0644))
+
+	_, err := ad.downloadDebridFile(&models.AutoDownloaderDebridDownload{}, 0, server.URL+"/episode.mkv", stagingDir)
+	require.ErrorContains(t, err, "size mismatch")
+
+	// The part file is kept so that the download can be resumed
+	content, err := os.ReadFile(filepath.Join(stagingDir, "0.part"))
+	require.NoError(t, err)
+	assert.Equal(t, "abcde12345", string(content))
+	assert.NoFileExists(t, filepath.Join(stagingDir, "0-episode.mkv"))
+}
+
+func TestGetDebridDownloadFilename(t *testing.T) {
+	tests := []struct {
+		name               string
+		contentDisposition string
+		contentType        string
+		url                string
+		expected           string
+	}{
+		{
+			name:               "Content-Disposition",
+			contentDisposition: `attachment; filename="[SubsPlease] Sousou no Frieren - 01 (1080p).mkv"`,
+			url:                "https://example.com/dl/ABC",
+			expected:           "[SubsPlease] Sousou no Frieren - 01 (1080p).mkv",
+		},
+		{
+			name:     "URL",
+			url:      "https://example.com/dl/ABC/Frieren%20-%2001.mkv",
+			expected: "Frieren - 01.mkv",
+		},
+		{
+			name:        "Archive without extension",
+			contentType: "application/zip",
+			url:         "https://example.com/zip/ABC",
+			expected:    "download.zip",
+		},
+		{
+			name:               "Path in Content-Disposition",
+			contentDisposition: `attachment; filename="../../episode.mkv"`,
+			url:                "https://example.com/dl/ABC",
+			expected:           "episode.mkv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			resp.Header.Set("Content-Disposition", tt.contentDisposition)
+			resp.Header.Set("Content-Type", tt.contentType)
+			assert.Equal(t, tt.expected, getDebridDownloadFilename(resp, tt.url))
+		})
+	}
+}
+
+func TestMovePath_DifferentTypes(t *testing.T) {
+	dir := t.TempDir()
+
+	// A file does not replace a folder
+	src := filepath.Join(dir, "staging", "Frieren")
+	require.NoError(t, os.MkdirAll(filepath.Dir(src), os.ModePerm))
+	require.NoError(t, os.WriteFile(src, []byte("episode"), 0644))
+	dest := filepath.Join(dir, "library", "Frieren")
+	require.NoError(t, os.MkdirAll(dest, os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(dest, "Frieren - 01.mkv"), []byte("episode 1"), 0644))
+
+	require.Error(t, movePath(src, dest))
+	assert.FileExists(t, filepath.Join(dest, "Frieren - 01.mkv"))
+	assert.FileExists(t, src)
+
+	// A folder does not replace a file
+	srcDir := filepath.Join(dir, "staging", "Season 1")
+	require.NoError(t, os.MkdirAll(srcDir, os.ModePerm))
+	destFile := filepath.Join(dir, "library", "Season 1")
+	require.NoError(t, os.WriteFile(destFile, []byte("file"), 0644))
+
+	require.Error(t, movePath(srcDir, destFile))
+	assert.FileExists(t, destFile)
+}
+
+func TestStartDebridDownload(t *testing.T) {
+	ad := newTestDebridAutoDownloader(t)
+
+	d := &models.AutoDownloaderDebridDownload{
+		ItemID:        1,
+		Provider:      "fake",
+		TorrentItemID: "TORRENT1",
+		TorrentName:   "Frieren",
+		Destination:   t.TempDir(),
+	}
+	ad.queueDebridDownload(&models.AutoDownloaderItem{TorrentName: "Frieren"}, d.Provider, d.TorrentItemID, d.Destination, true)
+
+	downloads, err := ad.database.GetAutoDownloaderDebridDownloads()
+	require.NoError(t, err)
+	require.Len(t, downloads, 1)
+	assert.Equal(t, DebridDownloadStatusPaused, downloads[0].Status)
+
+	// Paused downloads are not processed
+	pending, err := ad.database.GetPendingAutoDownloaderDebridDownloads()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	ad.debridLoopOnce.Do(func() {}) // Do not start the loop
+	require.NoError(t, ad.StartDebridDownload(downloads[0].ID))
+	assert.ErrorIs(t, ad.StartDebridDownload(downloads[0].ID), ErrDebridDownloadNotPaused)
+
+	pending, err = ad.database.GetPendingAutoDownloaderDebridDownloads()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, DebridDownloadStatusQueued, pending[0].Status)
+}
+
+func TestProcessDebridDownload_ServiceUnreachable(t *testing.T) {
+	ad := newTestDebridAutoDownloader(t)
+
+	d := &models.AutoDownloaderDebridDownload{
+		ItemID:        1,
+		Provider:      "fake",
+		TorrentItemID: "TORRENT1",
+		TorrentName:   "Frieren",
+		Destination:   t.TempDir(),
+		Status:        DebridDownloadStatusQueued,
+	}
+	require.NoError(t, ad.database.InsertAutoDownloaderDebridDownload(d))
+	require.NoError(t, os.MkdirAll(ad.getDebridStagingDir(d), os.ModePerm))
+
+	// The service is unreachable for longer than the maximum number of attempts
+	provider := &fakeDebridProvider{torrentErr: errors.New("connection refused")}
+	for i := 0; i < maxDebridDownloadAttempts*2; i++ {
+		assert.Empty(t, ad.processDebridDownload(provider, d))
+	}
+
+	downloads, err := ad.database.GetPendingAutoDownloaderDebridDownloads()
+	require.NoError(t, err)
+	require.Len(t, downloads, 1)
+	assert.Equal(t, DebridDownloadStatusQueued, downloads[0].Status)
+	assert.Zero(t, downloads[0].Attempts)
+	assert.Equal(t, "connection refused", downloads[0].Error)
+	// The partial data is kept
+	assert.DirExists(t, ad.getDebridStagingDir(d))
+}
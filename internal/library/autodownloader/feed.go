@@ -0,0 +1,327 @@
+package autodownloader
+
+import (
+	"errors"
+	"fmt"
+	hibiketorrent "github.com/5rahim/hibike/pkg/extension/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/extensions"
+	"io"
+	"net/http"
+	"net/url"
+	"seanime/internal/database/models"
+	"seanime/internal/util"
+	"seanime/seanime-parser"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// FeedProviderName is the provider of the torrents coming from RSS and Atom feeds.
+	FeedProviderName = "feed"
+
+	maxTorrentFileSize = 10 * 1024 * 1024
+)
+
+// getFeeds returns the enabled feeds.
+func (ad *AutoDownloader) getFeeds() []*models.AutoDownloaderFeed {
+	if ad.database == nil {
+		return []*models.AutoDownloaderFeed{}
+	}
+
+	feeds, err := ad.database.GetAutoDownloaderFeeds()
+	if err != nil {
+		ad.logger.Error().Err(err).Msg("autodownloader: Failed to fetch feeds from the database")
+		return []*models.AutoDownloaderFeed{}
+	}
+
+	ret := make([]*models.AutoDownloaderFeed, 0, len(feeds))
+	for _, feed := range feeds {
+		if feed.Enabled && feed.URL != "" {
+			ret = append(ret, feed)
+		}
+	}
+	return ret
+}
+
+// getFeedTorrents fetches the torrents from all the feeds.
+// Feeds that cannot be fetched are skipped.
+func (ad *AutoDownloader) getFeedTorrents(feeds []*models.AutoDownloaderFeed) []*NormalizedTorrent {
+	ret := make([]*NormalizedTorrent, 0)
+	wg := sync.WaitGroup{}
+	mu := sync.Mutex{}
+
+	for _, feed := range feeds {
+		wg.Add(1)
+		go func(feed *models.AutoDownloaderFeed) {
+			defer wg.Done()
+			torrents, err := FetchFeed(ad.feedClient, feed)
+			if err != nil {
+				ad.logger.Error().Err(err).Str("feed", feed.Name).Msg("autodownloader: Failed to fetch feed")
+				return
+			}
+			ad.logger.Debug().Str("feed", feed.Name).Int("count", len(torrents)).Msg("autodownloader: Fetched feed")
+			mu.Lock()
+			ret = append(ret, torrents...)
+			mu.Unlock()
+		}(feed)
+	}
+	wg.Wait()
+
+	return ret
+}
+
+// FetchFeed fetches and parses an RSS or Atom feed, sending the cookies and headers of the feed.
+func FetchFeed(client *http.Client, feed *models.AutoDownloaderFeed) ([]*NormalizedTorrent, error) {
+	req, err := newFeedRequest(feed, feed.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed returned status code %d", resp.StatusCode)
+	}
+
+	return ParseFeed(resp.Body, feed)
+}
+
+// ParseFeed parses the content of an RSS or Atom feed into torrents.
+// Items without a magnet link, info hash or torrent file link are skipped.
+func ParseFeed(r io.Reader, feed *models.AutoDownloaderFeed) ([]*NormalizedTorrent, error) {
+	parsed, err := gofeed.NewParser().Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*NormalizedTorrent, 0, len(parsed.Items))
+	for _, item := range parsed.Items {
+		t, ok := feedItemToTorrent(item)
+		if !ok {
+			continue
+		}
+		ret = append(ret, &NormalizedTorrent{
+			AnimeTorrent: *t,
+			ParsedData:   seanime_parser.Parse(t.Name),
+			magnet:       t.MagnetLink,
+			feed:         feed,
+		})
+	}
+
+	return ret, nil
+}
+
+func feedItemToTorrent(item *gofeed.Item) (*hibiketorrent.AnimeTorrent, bool) {
+	if item == nil || item.Title == "" {
+		return nil, false
+	}
+
+	t := &hibiketorrent.AnimeTorrent{
+		Provider:      FeedProviderName,
+		Name:          strings.TrimSpace(item.Title),
+		Link:          item.Link,
+		EpisodeNumber: -1,
+	}
+
+	if item.PublishedParsed != nil {
+		t.Date = item.PublishedParsed.Format(time.RFC3339)
+	} else if item.UpdatedParsed != nil {
+		t.Date = item.UpdatedParsed.Format(time.RFC3339)
+	}
+
+	// Links can be magnet links or torrent files
+	candidates := []string{item.Link}
+	candidates = append(candidates, item.Links...)
+	for _, enclosure := range item.Enclosures {
+		if enclosure == nil {
+			continue
+		}
+		if enclosure.Type == "application/x-bittorrent" {
+			t.DownloadUrl = enclosure.URL
+		}
+		if size, err := strconv.ParseInt(enclosure.Length, 10, 64); err == nil && size > 0 {
+			t.Size = size
+		}
+		candidates = append(candidates, enclosure.URL)
+	}
+	if magnet := getFeedExtension(item.Extensions, "magnetURI"); magnet != "" {
+		candidates = append(candidates, magnet)
+	}
+
+	for _, link := range candidates {
+		switch {
+		case strings.HasPrefix(link, "magnet:"):
+			if t.MagnetLink == "" {
+				t.MagnetLink = link
+			}
+		case t.DownloadUrl == "" && isTorrentFileURL(link):
+			t.DownloadUrl = link
+		}
+	}
+
+	// Info hash, e.g. <nyaa:infoHash> or <torrent:infoHash>
+	t.InfoHash = strings.ToLower(getFeedExtension(item.Extensions, "infoHash"))
+	if t.InfoHash == "" && t.MagnetLink != "" {
+		t.InfoHash = getMagnetInfoHash(t.MagnetLink)
+	}
+
+	if t.Size == 0 {
+		if size := getFeedExtension(item.Extensions, "size"); size != "" {
+			t.Size, _ = util.StringSizeToBytes(size)
+		} else if size := getFeedExtension(item.Extensions, "contentLength"); size != "" {
+			t.Size, _ = strconv.ParseInt(size, 10, 64)
+		}
+	}
+	t.Seeders, _ = strconv.Atoi(getFeedExtension(item.Extensions, "seeders"))
+	t.Leechers, _ = strconv.Atoi(getFeedExtension(item.Extensions, "leechers"))
+	t.DownloadCount, _ = strconv.Atoi(getFeedExtension(item.Extensions, "downloads"))
+
+	if t.MagnetLink == "" && t.InfoHash != "" {
+		t.MagnetLink = fmt.Sprintf("magnet:?xt=urn:btih:%s&dn=%s", t.InfoHash, url.QueryEscape(t.Name))
+	}
+
+	if t.MagnetLink == "" && t.DownloadUrl == "" {
+		return nil, false
+	}
+	if t.Link == "" {
+		t.Link = t.DownloadUrl
+	}
+
+	return t, true
+}
+
+// resolveFeedTorrent downloads the torrent file of a feed item that has no magnet link, using the cookies and headers of the feed.
+// The magnet link and info hash of the torrent are set.
+func (ad *AutoDownloader) resolveFeedTorrent(t *NormalizedTorrent) (string, error) {
+	if t.magnet != "" {
+		return t.magnet, nil
+	}
+	if t.feed == nil || t.DownloadUrl == "" {
+		return "", errors.New("no torrent file link")
+	}
+
+	req, err := newFeedRequest(t.feed, t.DownloadUrl)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := ad.feedClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("torrent file returned status code %d", resp.StatusCode)
+	}
+
+	mi, err := metainfo.Load(io.LimitReader(resp.Body, maxTorrentFileSize))
+	if err != nil {
+		return "", err
+	}
+
+	// The trackers of the torrent file are kept, private trackers need them
+	magnet, err := mi.MagnetV2()
+	if err != nil {
+		return "", err
+	}
+
+	t.magnet = magnet.String()
+	t.InfoHash = mi.HashInfoBytes().HexString()
+
+	return t.magnet, nil
+}
+
+func newFeedRequest(feed *models.AutoDownloaderFeed, link string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", "Seanime")
+
+	// Only send the credentials to the host of the feed
+	if feedURL, err := url.Parse(feed.URL); err != nil || !strings.EqualFold(feedURL.Host, req.URL.Host) {
+		return req, nil
+	}
+
+	for name, value := range parseFeedHeaders(feed.Headers) {
+		req.Header.Set(name, value)
+	}
+	if feed.Cookies != "" {
+		req.Header.Set("Cookie", feed.Cookies)
+	}
+
+	return req, nil
+}
+
+// parseFeedHeaders parses the headers of a feed, one "Name: Value" per line.
+func parseFeedHeaders(s string) map[string]string {
+	ret := make(map[string]string)
+	for _, line := range strings.Split(s, "\n") {
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		ret[name] = strings.TrimSpace(value)
+	}
+	return ret
+}
+
+// getFeedExtension returns the value of a namespaced element, whatever the namespace.
+func getFeedExtension(extensions ext.Extensions, name string) string {
+	for _, elements := range extensions {
+		for key, values := range elements {
+			if strings.EqualFold(key, name) && len(values) > 0 && values[0].Value != "" {
+				return strings.TrimSpace(values[0].Value)
+			}
+		}
+	}
+	return ""
+}
+
+func getMagnetInfoHash(magnet string) string {
+	m, err := metainfo.ParseMagnetUri(magnet)
+	if err != nil {
+		return ""
+	}
+	return m.InfoHash.HexString()
+}
+
+func isTorrentFileURL(link string) bool {
+	if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+		return false
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(u.Path), ".torrent") || strings.Contains(strings.ToLower(u.Path), "/download")
+}
+
+// PreviewFeed fetches the feed and returns its torrents.
+// This is used to check the URL, cookies and headers of a feed before saving it.
+func (ad *AutoDownloader) PreviewFeed(feed *models.AutoDownloaderFeed) ([]*hibiketorrent.AnimeTorrent, error) {
+	torrents, err := FetchFeed(ad.feedClient, feed)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*hibiketorrent.AnimeTorrent, 0, len(torrents))
+	for _, t := range torrents {
+		ret = append(ret, &t.AnimeTorrent)
+	}
+	return ret, nil
+}
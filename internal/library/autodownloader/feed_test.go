@@ -0,0 +1,195 @@
+package autodownloader
+
+import (
+	"bytes"
+	"fmt"
+	hibiketorrent "github.com/5rahim/hibike/pkg/extension/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/util"
+	"strings"
+	"testing"
+)
+
+const testRSSFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss xmlns:nyaa="https://nyaa.si/xmlns/nyaa" version="2.0">
+	<channel>
+		<title>Nyaa - Home - Torrent File RSS</title>
+		<item>
+			<title>[SubsPlease] Sousou no Frieren - 01 (1080p) [F02B9CEE].mkv</title>
+			<link>%s/download/1.torrent</link>
+			<guid isPermaLink="true">%s/view/1</guid>
+			<pubDate>Fri, 29 Sep 2023 16:00:00 -0000</pubDate>
+			<nyaa:seeders>1200</nyaa:seeders>
+			<nyaa:leechers>12</nyaa:leechers>
+			<nyaa:downloads>25000</nyaa:downloads>
+			<nyaa:infoHash>0A1B2C3D4E5F60718293A4B5C6D7E8F901234567</nyaa:infoHash>
+			<nyaa:size>1.4 GiB</nyaa:size>
+		</item>
+		<item>
+			<title>[Private] Sousou no Frieren - 02 (1080p)</title>
+			<link>%s/details/2</link>
+			<enclosure url="%s/download/2.torrent" length="1503238553" type="application/x-bittorrent"/>
+		</item>
+		<item>
+			<title>An item without torrent</title>
+			<link>%s/news/3</link>
+		</item>
+	</channel>
+</rss>`
+
+const testAtomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Release group</title>
+	<entry>
+		<title>[Group] Sousou no Frieren - 03 [1080p]</title>
+		<id>urn:uuid:1</id>
+		<updated>2023-10-06T16:00:00Z</updated>
+		<link rel="alternate" href="magnet:?xt=urn:btih:ABCDEF0123456789ABCDEF0123456789ABCDEF01&amp;dn=Frieren"/>
+	</entry>
+</feed>`
+
+func newTestTorrentFile(t *testing.T) ([]byte, string) {
+	info := metainfo.Info{
+		Name:        "[Private] Sousou no Frieren - 02 (1080p).mkv",
+		PieceLength: 16384,
+		Pieces:      make([]byte, 20),
+		Length:      16384,
+	}
+	infoBytes, err := bencode.Marshal(info)
+	require.NoError(t, err)
+
+	mi := metainfo.MetaInfo{
+		InfoBytes: infoBytes,
+		Announce:  "https://tracker.example.com/announce?passkey=secret",
+	}
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, mi.Write(buf))
+
+	return buf.Bytes(), mi.HashInfoBytes().HexString()
+}
+
+func TestFetchFeed_RSS(t *testing.T) {
+	torrentFile, infoHash := newTestTorrentFile(t)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The tracker requires the cookie and the API key
+		if r.Header.Get("Cookie") != "uid=1; pass=abc" || r.Header.Get("X-Api-Key") != "key" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/rss":
+			u := server.URL
+			_, _ = fmt.Fprintf(w, testRSSFeed, u, u, u, u, u)
+		case "/download/2.torrent":
+			_, _ = w.Write(torrentFile)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	feed := &models.AutoDownloaderFeed{
+		Name:    "Tracker",
+		URL:     server.URL + "/rss",
+		Enabled: true,
+		Cookies: "uid=1; pass=abc",
+		Headers: "X-Api-Key: key\n\ninvalid line",
+	}
+
+	// Without the credentials
+	_, err := FetchFeed(server.Client(), &models.AutoDownloaderFeed{URL: feed.URL})
+	require.Error(t, err)
+
+	torrents, err := FetchFeed(server.Client(), feed)
+	require.NoError(t, err)
+	require.Len(t, torrents, 2)
+
+	// Nyaa-style item, the info hash is known
+	t1 := torrents[0]
+	assert.Equal(t, FeedProviderName, t1.Provider)
+	assert.Equal(t, "0a1b2c3d4e5f60718293a4b5c6d7e8f901234567", t1.InfoHash)
+	assert.True(t, strings.HasPrefix(t1.magnet, "magnet:?xt=urn:btih:0a1b2c3d4e5f60718293a4b5c6d7e8f901234567"))
+	assert.Equal(t, server.URL+"/download/1.torrent", t1.DownloadUrl)
+	assert.Equal(t, 1200, t1.Seeders)
+	assert.Equal(t, "2023-09-29T16:00:00Z", t1.Date)
+	assert.Equal(t, "SubsPlease", t1.ParsedData.ReleaseGroup)
+	assert.Equal(t, "1080p", t1.ParsedData.VideoResolution)
+
+	// Private tracker item, the torrent file is downloaded with the credentials of the feed
+	t2 := torrents[1]
+	assert.Empty(t, t2.InfoHash)
+	assert.Equal(t, server.URL+"/download/2.torrent", t2.DownloadUrl)
+	assert.Equal(t, int64(1503238553), t2.Size)
+
+	ad := &AutoDownloader{logger: util.NewLogger(), feedClient: server.Client()}
+	magnet, err := ad.resolveFeedTorrent(t2)
+	require.NoError(t, err)
+	assert.Equal(t, infoHash, t2.InfoHash)
+	assert.Contains(t, magnet, "urn:btih:"+infoHash)
+	assert.Contains(t, magnet, "passkey%3Dsecret") // The tracker of the torrent file is kept
+}
+
+func TestParseFeed_Atom(t *testing.T) {
+	torrents, err := ParseFeed(strings.NewReader(testAtomFeed), &models.AutoDownloaderFeed{Name: "Group"})
+	require.NoError(t, err)
+	require.Len(t, torrents, 1)
+
+	assert.Equal(t, "[Group] Sousou no Frieren - 03 [1080p]", torrents[0].Name)
+	assert.Equal(t, "abcdef0123456789abcdef0123456789abcdef01", torrents[0].InfoHash)
+	assert.True(t, strings.HasPrefix(torrents[0].magnet, "magnet:"))
+	assert.Equal(t, "2023-10-06T16:00:00Z", torrents[0].Date)
+}
+
+func TestNewFeedRequest_OtherHost(t *testing.T) {
+	feed := &models.AutoDownloaderFeed{
+		URL:     "https://tracker.example.com/rss",
+		Cookies: "pass=abc",
+		Headers: "Authorization: Bearer token",
+	}
+
+	req, err := newFeedRequest(feed, "https://tracker.example.com/download/1.torrent")
+	require.NoError(t, err)
+	assert.Equal(t, "pass=abc", req.Header.Get("Cookie"))
+	assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+
+	// The credentials are not sent to other hosts
+	req, err = newFeedRequest(feed, "https://cdn.example.org/1.torrent")
+	require.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Cookie"))
+	assert.Empty(t, req.Header.Get("Authorization"))
+}
+
+func TestFilterQueuedTorrents(t *testing.T) {
+	t.Setenv("TEST_ENV", "true")
+
+	logger := util.NewLogger()
+	database, err := db.NewDatabase(t.TempDir(), "test", logger)
+	require.NoError(t, err)
+
+	require.NoError(t, database.InsertAutoDownloaderItem(&models.AutoDownloaderItem{Hash: "0A1B2C", TorrentName: "Queued by hash"}))
+	require.NoError(t, database.InsertAutoDownloaderItem(&models.AutoDownloaderItem{Link: "https://tracker.example.com/details/2", TorrentName: "Queued by link"}))
+
+	feed := &models.AutoDownloaderFeed{Name: "Tracker"}
+	torrents := []*NormalizedTorrent{
+		{AnimeTorrent: hibiketorrent.AnimeTorrent{Name: "Queued by hash", InfoHash: "0a1b2c"}},
+		{AnimeTorrent: hibiketorrent.AnimeTorrent{Name: "Queued by link", Link: "https://tracker.example.com/details/2"}, feed: feed},
+		{AnimeTorrent: hibiketorrent.AnimeTorrent{Name: "New", Link: "https://tracker.example.com/details/3"}, feed: feed},
+	}
+
+	ad := &AutoDownloader{logger: logger, database: database}
+	ret := ad.filterQueuedTorrents(torrents)
+	require.Len(t, ret, 1)
+	assert.Equal(t, "New", ret[0].Name)
+
+	assert.True(t, ad.isTorrentQueued("0a1b2c"))
+	assert.False(t, ad.isTorrentQueued(""))
+}
@@ -0,0 +1,276 @@
+package autodownloader
+
+import (
+	"github.com/samber/lo"
+	"os"
+	"path/filepath"
+	"seanime/internal/database/db_bridge"
+	"seanime/internal/database/models"
+	"seanime/internal/library/anime"
+	"seanime/internal/util"
+	"seanime/internal/util/comparison"
+	"seanime/seanime-parser"
+	"strings"
+	"time"
+)
+
+// upgradeItemExpiry is the time after which an upgrade is forgotten if its file never appeared in the library.
+// The replaced file is kept.
+const upgradeItemExpiry = 30 * 24 * time.Hour
+
+type (
+	// releaseQuality is the rank of a release according to a quality profile.
+	// Lower ranks are better, higher scores and versions are better.
+	releaseQuality struct {
+		resolutionRank int
+		groupRank      int
+		tagScore       int
+		version        int
+	}
+
+	// upgradeTarget is what an upgrade would replace.
+	upgradeTarget struct {
+		exists       bool            // An item or a local file exists for the episode
+		quality      *releaseQuality // Best quality among the existing releases
+		replacesPath string          // Local file of the episode
+	}
+)
+
+// getReleaseQuality ranks the release using the rule.
+//   - Resolutions are ranked in the order of rule.Resolutions, or by their value if the rule has none.
+//   - Release groups are ranked in the order of rule.ReleaseGroups.
+//   - Each preferred tag adds to the score, the first tags weighing more.
+//   - Versions (v2, REPACK, PROPER) are used as the last tie-breaker.
+func getReleaseQuality(name string, parsedData *seanime_parser.Metadata, rule *anime.AutoDownloaderRule) *releaseQuality {
+	if parsedData == nil {
+		parsedData = seanime_parser.Parse(name)
+	}
+
+	ret := &releaseQuality{
+		resolutionRank: getResolutionRank(parsedData.VideoResolution, rule.Resolutions),
+		groupRank:      getReleaseGroupRank(parsedData.ReleaseGroup, rule.ReleaseGroups),
+		version:        getReleaseVersion(name, parsedData),
+	}
+
+	if rule.QualityProfile != nil {
+		lowerName := strings.ToLower(name)
+		tags := rule.QualityProfile.PreferredTags
+		for i, tag := range tags {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if tag != "" && strings.Contains(lowerName, tag) {
+				ret.tagScore += len(tags) - i
+			}
+		}
+	}
+
+	return ret
+}
+
+// isBetterThan returns true if q is strictly better than other.
+func (q *releaseQuality) isBetterThan(other *releaseQuality) bool {
+	if q.resolutionRank != other.resolutionRank {
+		return q.resolutionRank < other.resolutionRank
+	}
+	if q.groupRank != other.groupRank {
+		return q.groupRank < other.groupRank
+	}
+	if q.tagScore != other.tagScore {
+		return q.tagScore > other.tagScore
+	}
+	return q.version > other.version
+}
+
+// isRepackOf returns true if q is a newer version of the same release.
+func (q *releaseQuality) isRepackOf(other *releaseQuality) bool {
+	return q.resolutionRank == other.resolutionRank && q.groupRank == other.groupRank && q.version > other.version
+}
+
+// isCutoffReached returns true if the release should no longer be upgraded.
+func isCutoffReached(q *releaseQuality, rule *anime.AutoDownloaderRule) bool {
+	profile := rule.QualityProfile
+	if profile.CutoffResolution == "" && profile.CutoffReleaseGroup == "" {
+		return false
+	}
+	if profile.CutoffResolution != "" && q.resolutionRank > getResolutionRank(profile.CutoffResolution, rule.Resolutions) {
+		return false
+	}
+	if profile.CutoffReleaseGroup != "" && q.groupRank > getReleaseGroupRank(profile.CutoffReleaseGroup, rule.ReleaseGroups) {
+		return false
+	}
+	return true
+}
+
+// getUpgradeTarget returns the best release already downloaded or in the library for the episode.
+func getUpgradeTarget(
+	episode int,
+	rule *anime.AutoDownloaderRule,
+	localEntry *anime.LocalFileWrapperEntry,
+	items []*models.AutoDownloaderItem,
+) *upgradeTarget {
+	ret := &upgradeTarget{}
+
+	consider := func(q *releaseQuality) {
+		ret.exists = true
+		if ret.quality == nil || q.isBetterThan(ret.quality) {
+			ret.quality = q
+		}
+	}
+
+	for _, item := range items {
+		if item.Episode == episode {
+			consider(getReleaseQuality(item.TorrentName, nil, rule))
+		}
+	}
+
+	if localEntry != nil {
+		if lf, found := localEntry.FindLocalFileWithEpisodeNumber(episode); found {
+			consider(getReleaseQuality(lf.Name, nil, rule))
+			ret.replacesPath = lf.GetPath()
+		}
+	}
+
+	return ret
+}
+
+// isWorthUpgrading returns true if the release should replace the existing one.
+func isWorthUpgrading(q *releaseQuality, target *upgradeTarget, rule *anime.AutoDownloaderRule) bool {
+	if !target.exists {
+		return true
+	}
+	if !rule.CanUpgrade() || target.quality == nil {
+		return false
+	}
+	if isCutoffReached(target.quality, rule) {
+		// Only fixed versions of the same release
+		return q.isRepackOf(target.quality)
+	}
+	return q.isBetterThan(target.quality)
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+
+func getResolutionRank(resolution string, ranked []string) int {
+	if len(ranked) == 0 {
+		// Higher resolutions are better
+		return -comparison.ExtractResolutionInt(resolution)
+	}
+	if resolution == "" {
+		return len(ranked)
+	}
+	resolutionWithoutP := strings.TrimSuffix(strings.ToLower(resolution), "p")
+	for i, r := range ranked {
+		rWithoutP := strings.TrimSuffix(strings.ToLower(r), "p")
+		if resolutionWithoutP == rWithoutP || strings.Contains(resolutionWithoutP, rWithoutP) {
+			return i
+		}
+	}
+	return len(ranked)
+}
+
+func getReleaseGroupRank(releaseGroup string, ranked []string) int {
+	for i, rg := range ranked {
+		if strings.EqualFold(rg, releaseGroup) {
+			return i
+		}
+	}
+	return len(ranked)
+}
+
+func getReleaseVersion(name string, parsedData *seanime_parser.Metadata) int {
+	version := 1
+	if len(parsedData.ReleaseVersion) > 0 {
+		if v, ok := util.StringToInt(parsedData.ReleaseVersion[0]); ok {
+			version = v
+		}
+	}
+	lowerName := strings.ToLower(name)
+	if strings.Contains(lowerName, "repack") || strings.Contains(lowerName, "proper") {
+		version++
+	}
+	return version
+}
+
+// removeReplacedFiles deletes the files replaced by downloaded upgrades.
+// The old file is only deleted once the file of the upgrade has been scanned, otherwise the item is kept until the next scan.
+func (ad *AutoDownloader) removeReplacedFiles() {
+	items, err := ad.database.GetAutoDownloaderItems()
+	if err != nil {
+		return
+	}
+
+	items = lo.Filter(items, func(item *models.AutoDownloaderItem, _ int) bool {
+		return item.Downloaded && item.ReplacesPath != ""
+	})
+	if len(items) == 0 {
+		return
+	}
+
+	lfs, lfsId, err := db_bridge.GetLocalFiles(ad.database)
+	if err != nil {
+		return
+	}
+
+	removed := make([]string, 0)
+	for _, item := range items {
+		_, replaced := lo.Find(lfs, func(lf *anime.LocalFile) bool {
+			return lf.MediaId == item.MediaID &&
+				lf.GetEpisodeNumber() == item.Episode &&
+				!lf.HasSamePath(item.ReplacesPath) &&
+				isUpgradeFile(lf, item)
+		})
+		if !replaced {
+			continue
+		}
+
+		if err := os.Remove(item.ReplacesPath); err != nil && !os.IsNotExist(err) {
+			ad.logger.Error().Err(err).Str("path", item.ReplacesPath).Msg("autodownloader: Failed to remove replaced file")
+			continue
+		}
+		ad.logger.Info().Str("path", item.ReplacesPath).Msg("autodownloader: Removed replaced file")
+		removed = append(removed, item.ReplacesPath)
+		_ = ad.database.DeleteAutoDownloaderItem(item.ID)
+	}
+
+	if len(removed) == 0 {
+		return
+	}
+
+	lfs = lo.Filter(lfs, func(lf *anime.LocalFile, _ int) bool {
+		return !lo.ContainsBy(removed, lf.HasSamePath)
+	})
+	if _, err := db_bridge.SaveLocalFiles(ad.database, lfsId, lfs); err != nil {
+		ad.logger.Error().Err(err).Msg("autodownloader: Failed to save local files")
+	}
+}
+
+// isUpgradeFile returns true if the local file was downloaded by the upgrade item.
+//   - The file or one of its folders is named after the torrent, or
+//   - the file appeared after the item was created and it is the same release (group and resolution) as the torrent.
+//
+// Files of the same episode that were already in the library are not matched.
+func isUpgradeFile(lf *anime.LocalFile, item *models.AutoDownloaderItem) bool {
+	torrentName := strings.ToLower(strings.TrimSpace(item.TorrentName))
+	if torrentName == "" {
+		return false
+	}
+
+	path := filepath.ToSlash(lf.GetNormalizedPath())
+	for _, part := range strings.Split(path, "/") {
+		if part == torrentName || strings.TrimSuffix(part, filepath.Ext(part)) == strings.TrimSuffix(torrentName, filepath.Ext(torrentName)) {
+			return true
+		}
+	}
+
+	info, err := os.Stat(lf.GetPath())
+	if err != nil || info.ModTime().Before(item.CreatedAt) {
+		return false
+	}
+
+	torrentData := seanime_parser.Parse(item.TorrentName)
+	fileData := seanime_parser.Parse(lf.Name)
+	if torrentData == nil || fileData == nil {
+		return false
+	}
+	return strings.EqualFold(torrentData.ReleaseGroup, fileData.ReleaseGroup) &&
+		comparison.ExtractResolutionInt(torrentData.VideoResolution) == comparison.ExtractResolutionInt(fileData.VideoResolution)
+}
@@ -0,0 +1,174 @@
+package autodownloader
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"seanime/internal/database/models"
+	"seanime/internal/library/anime"
+	"testing"
+	"time"
+)
+
+func TestReleaseQuality(t *testing.T) {
+	rule := &anime.AutoDownloaderRule{
+		ReleaseGroups: []string{"SubsPlease", "Erai-raws"},
+		Resolutions:   []string{"1080p", "720p"},
+		QualityProfile: &anime.AutoDownloaderQualityProfile{
+			Enabled:       true,
+			PreferredTags: []string{"HEVC", "Multiple Subtitle"},
+		},
+	}
+
+	tests := []struct {
+		better string
+		worse  string
+	}{
+		{
+			better: "[SubsPlease] Oshi no Ko - 03 (1080p) [A1B2C3D4].mkv",
+			worse:  "[SubsPlease] Oshi no Ko - 03 (720p) [A1B2C3D4].mkv",
+		},
+		{
+			better: "[SubsPlease] Oshi no Ko - 03 (1080p) [A1B2C3D4].mkv",
+			worse:  "[Erai-raws] Oshi no Ko - 03 [1080p][Multiple Subtitle].mkv",
+		},
+		{
+			better: "[Erai-raws] Oshi no Ko - 03 [1080p][HEVC].mkv",
+			worse:  "[Erai-raws] Oshi no Ko - 03 [1080p][Multiple Subtitle].mkv",
+		},
+		{
+			better: "[SubsPlease] Oshi no Ko - 03v2 (1080p) [A1B2C3D4].mkv",
+			worse:  "[SubsPlease] Oshi no Ko - 03 (1080p) [A1B2C3D4].mkv",
+		},
+		{
+			better: "[SubsPlease] Oshi no Ko - 03 (1080p) REPACK.mkv",
+			worse:  "[SubsPlease] Oshi no Ko - 03 (1080p).mkv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.better, func(t *testing.T) {
+			better := getReleaseQuality(tt.better, nil, rule)
+			worse := getReleaseQuality(tt.worse, nil, rule)
+			assert.True(t, better.isBetterThan(worse))
+			assert.False(t, worse.isBetterThan(better))
+		})
+	}
+}
+
+func TestIsWorthUpgrading(t *testing.T) {
+	newRule := func(cutoffResolution string) *anime.AutoDownloaderRule {
+		return &anime.AutoDownloaderRule{
+			ReleaseGroups: []string{"SubsPlease", "Erai-raws"},
+			Resolutions:   []string{"1080p", "720p"},
+			QualityProfile: &anime.AutoDownloaderQualityProfile{
+				Enabled:          true,
+				Upgrade:          true,
+				CutoffResolution: cutoffResolution,
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		existing string
+		release  string
+		cutoff   string
+		expected bool
+	}{
+		{
+			name:     "Better resolution",
+			existing: "[SubsPlease] Oshi no Ko - 03 (720p).mkv",
+			release:  "[SubsPlease] Oshi no Ko - 03 (1080p).mkv",
+			expected: true,
+		},
+		{
+			name:     "Same release",
+			existing: "[SubsPlease] Oshi no Ko - 03 (1080p).mkv",
+			release:  "[SubsPlease] Oshi no Ko - 03 (1080p).mkv",
+			expected: false,
+		},
+		{
+			name:     "Worse release group",
+			existing: "[SubsPlease] Oshi no Ko - 03 (720p).mkv",
+			release:  "[Erai-raws] Oshi no Ko - 03 [720p].mkv",
+			expected: false,
+		},
+		{
+			name:     "Cutoff reached",
+			existing: "[Erai-raws] Oshi no Ko - 03 [720p].mkv",
+			release:  "[SubsPlease] Oshi no Ko - 03 (1080p).mkv",
+			cutoff:   "720p",
+			expected: false,
+		},
+		{
+			name:     "Cutoff reached but repack",
+			existing: "[Erai-raws] Oshi no Ko - 03 [720p].mkv",
+			release:  "[Erai-raws] Oshi no Ko - 03v2 [720p].mkv",
+			cutoff:   "720p",
+			expected: true,
+		},
+		{
+			name:     "Cutoff not reached",
+			existing: "[Erai-raws] Oshi no Ko - 03 [720p].mkv",
+			release:  "[SubsPlease] Oshi no Ko - 03 (720p).mkv",
+			cutoff:   "1080p",
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := newRule(tt.cutoff)
+			items := []*models.AutoDownloaderItem{{Episode: 3, TorrentName: tt.existing}}
+			target := getUpgradeTarget(3, rule, nil, items)
+			assert.True(t, target.exists)
+			assert.Equal(t, tt.expected, isWorthUpgrading(getReleaseQuality(tt.release, nil, rule), target, rule))
+		})
+	}
+
+	// Episodes that are not downloaded are always downloaded
+	rule := newRule("")
+	target := getUpgradeTarget(4, rule, nil, []*models.AutoDownloaderItem{{Episode: 3, TorrentName: "[SubsPlease] Oshi no Ko - 03 (1080p).mkv"}})
+	assert.False(t, target.exists)
+	assert.True(t, isWorthUpgrading(getReleaseQuality("[Erai-raws] Oshi no Ko - 04 [720p].mkv", nil, rule), target, rule))
+
+	// Upgrades are disabled
+	rule.QualityProfile.Upgrade = false
+	target = getUpgradeTarget(3, rule, nil, []*models.AutoDownloaderItem{{Episode: 3, TorrentName: "[SubsPlease] Oshi no Ko - 03 (720p).mkv"}})
+	assert.False(t, isWorthUpgrading(getReleaseQuality("[SubsPlease] Oshi no Ko - 03 (1080p).mkv", nil, rule), target, rule))
+}
+
+func TestIsUpgradeFile(t *testing.T) {
+	dir := t.TempDir()
+	createdAt := time.Now().Add(-time.Hour)
+
+	newFile := func(name string, modTime time.Time) *anime.LocalFile {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("video"), 0644))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+		return anime.NewLocalFile(path, dir)
+	}
+
+	item := &models.AutoDownloaderItem{
+		BaseModel:   models.BaseModel{CreatedAt: createdAt},
+		TorrentName: "[SubsPlease] Sousou no Frieren - 01v2 (1080p) [ABCDEF12].mkv",
+	}
+
+	// Named after the torrent
+	assert.True(t, isUpgradeFile(newFile("[SubsPlease] Sousou no Frieren - 01v2 (1080p) [ABCDEF12].mkv", createdAt.Add(-time.Hour)), item))
+
+	// Inside a folder named after the torrent
+	batchItem := &models.AutoDownloaderItem{BaseModel: models.BaseModel{CreatedAt: createdAt}, TorrentName: "[Judas] Sousou no Frieren (Season 1) [1080p]"}
+	assert.True(t, isUpgradeFile(newFile("[Judas] Sousou no Frieren (Season 1) [1080p]/Frieren - 01.mkv", createdAt.Add(-time.Hour)), batchItem))
+
+	// Same release that appeared after the item was created
+	assert.True(t, isUpgradeFile(newFile("[SubsPlease] Frieren - 01 (1080p).mkv", createdAt.Add(time.Minute)), item))
+
+	// A duplicate that was already in the library
+	assert.False(t, isUpgradeFile(newFile("[Erai-raws] Sousou no Frieren - 01 [1080p].mkv", createdAt.Add(-time.Hour)), item))
+	// Another release that appeared after the item was created
+	assert.False(t, isUpgradeFile(newFile("[Erai-raws] Frieren - 01 [720p].mkv", createdAt.Add(time.Minute)), item))
+}
@@ -0,0 +1,235 @@
+package autodownloader
+
+import (
+	"errors"
+	"fmt"
+	"github.com/samber/lo"
+	"seanime/internal/database/db_bridge"
+	"seanime/internal/database/models"
+	"seanime/internal/library/anime"
+	"seanime/seanime-parser"
+	"strings"
+)
+
+const (
+	SimulationCheckReleaseGroup     = "releaseGroup"
+	SimulationCheckResolution       = "resolution"
+	SimulationCheckTitle            = "title"
+	SimulationCheckAdditionalTerms  = "additionalTerms"
+	SimulationCheckSeasonAndEpisode = "seasonAndEpisode"
+	SimulationCheckQuality          = "quality"
+	SimulationCheckQueue            = "queue"
+	SimulationCheckTorrentClient    = "torrentClient"
+)
+
+type (
+	SimulationOptions struct {
+		// Rule is a saved rule or a draft.
+		Rule *anime.AutoDownloaderRule
+		// TorrentNames are used instead of the provider and feed results if not empty.
+		TorrentNames []string
+	}
+
+	// SimulationResult is the outcome of running a rule against a list of torrents.
+	SimulationResult struct {
+		Rule     *anime.AutoDownloaderRule `json:"rule"`
+		Torrents []*SimulatedTorrent       `json:"torrents"`
+		// Downloads are the torrents that would be downloaded, the best one for each episode.
+		Downloads []*SimulatedTorrent `json:"downloads"`
+	}
+
+	SimulatedTorrent struct {
+		Name       string                   `json:"name"`
+		Provider   string                   `json:"provider,omitempty"`
+		InfoHash   string                   `json:"infoHash,omitempty"`
+		Seeders    int                      `json:"seeders"`
+		ParsedData *seanime_parser.Metadata `json:"parsedData"`
+		Checks     []*SimulationCheck       `json:"checks"`
+		// Matched is true if the torrent passed all the checks.
+		Matched bool `json:"matched"`
+		// Episode is the episode the torrent would be downloaded as, -1 if it was rejected.
+		Episode       int  `json:"episode"`
+		WouldDownload bool `json:"wouldDownload"`
+		IsUpgrade     bool `json:"isUpgrade"`
+	}
+
+	SimulationCheck struct {
+		Name   string `json:"name"`
+		Passed bool   `json:"passed"`
+		Reason string `json:"reason,omitempty"`
+	}
+)
+
+// Simulate runs the rule against the latest provider and feed results, or against the given torrent names.
+// It returns the verdict of each check for each torrent, and what would be downloaded.
+// Nothing is added to the queue or the torrent client.
+func (ad *AutoDownloader) Simulate(opts *SimulationOptions) (*SimulationResult, error) {
+	if ad == nil || opts.Rule == nil {
+		return nil, errors.New("no rule")
+	}
+	rule := opts.Rule
+
+	// The settings can be updated while the simulation is running
+	ad.mu.Lock()
+	settings := *ad.settings
+	ad.mu.Unlock()
+
+	listEntry, found := ad.getRuleListEntry(rule)
+	if !found {
+		return nil, errors.New("anime not found in the anime list")
+	}
+
+	var torrents []*NormalizedTorrent
+	if len(opts.TorrentNames) > 0 {
+		torrents = make([]*NormalizedTorrent, 0, len(opts.TorrentNames))
+		for _, name := range opts.TorrentNames {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			torrents = append(torrents, newSimulationTorrent(name))
+		}
+	} else {
+		if ad.torrentRepository == nil {
+			return nil, errors.New("torrent repository not initialized")
+		}
+		feeds := ad.getFeeds()
+		useProvider := ad.canUseProvider(&settings)
+		if !useProvider && len(feeds) == 0 {
+			return nil, errors.New("no torrent provider or feed to use")
+		}
+		var err error
+		torrents, err = ad.getLatestTorrents(&settings, []*anime.AutoDownloaderRule{rule}, useProvider, feeds)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var localEntry *anime.LocalFileWrapperEntry
+	if lfs, _, err := db_bridge.GetLocalFiles(ad.database); err == nil {
+		localEntry, _ = anime.NewLocalFileWrapper(lfs).GetLocalEntryById(rule.MediaId)
+	}
+
+	items, err := ad.database.GetAutoDownloaderItemByMediaId(rule.MediaId)
+	if err != nil {
+		items = make([]*models.AutoDownloaderItem, 0)
+	}
+	existingHashes := ad.getExistingTorrentHashes()
+	queued := make(map[*NormalizedTorrent]struct{})
+	notQueued := ad.filterQueuedTorrents(torrents)
+	for _, t := range torrents {
+		if !lo.Contains(notQueued, t) {
+			queued[t] = struct{}{}
+		}
+	}
+
+	ret := &SimulationResult{
+		Rule:      rule,
+		Torrents:  make([]*SimulatedTorrent, 0, len(torrents)),
+		Downloads: make([]*SimulatedTorrent, 0),
+	}
+
+	simulated := make(map[*NormalizedTorrent]*SimulatedTorrent, len(torrents))
+	torrentsToDownload := make([]*tmpTorrentToDownload, 0)
+
+	for _, t := range torrents {
+		st := &SimulatedTorrent{
+			Name:       t.Name,
+			Provider:   t.Provider,
+			InfoHash:   t.InfoHash,
+			Seeders:    t.Seeders,
+			ParsedData: t.ParsedData,
+			Checks:     make([]*SimulationCheck, 0),
+			Episode:    -1,
+		}
+		ret.Torrents = append(ret.Torrents, st)
+		simulated[t] = st
+
+		addCheck := func(name string, passed bool, reason string) {
+			st.Checks = append(st.Checks, &SimulationCheck{Name: name, Passed: passed, Reason: reason})
+		}
+
+		// Same checks as torrentFollowsRule, all of them are run to show every verdict
+		if ad.isReleaseGroupMatch(t.ParsedData.ReleaseGroup, rule) {
+			addCheck(SimulationCheckReleaseGroup, true, "")
+		} else {
+			addCheck(SimulationCheckReleaseGroup, false, fmt.Sprintf("Release group %q is not one of %s", t.ParsedData.ReleaseGroup, strings.Join(rule.ReleaseGroups, ", ")))
+		}
+
+		if ad.isResolutionMatch(t.ParsedData.VideoResolution, rule) {
+			addCheck(SimulationCheckResolution, true, "")
+		} else if t.ParsedData.VideoResolution == "" {
+			addCheck(SimulationCheckResolution, false, "No resolution found in the name")
+		} else {
+			addCheck(SimulationCheckResolution, false, fmt.Sprintf("Resolution %q is not one of %s", t.ParsedData.VideoResolution, strings.Join(rule.Resolutions, ", ")))
+		}
+
+		if ad.isTitleMatch(t.ParsedData, t.Name, rule, listEntry) {
+			addCheck(SimulationCheckTitle, true, "")
+		} else {
+			addCheck(SimulationCheckTitle, false, fmt.Sprintf("Title %q does not match %q using the %q comparison", t.ParsedData.Title, rule.ComparisonTitle, rule.TitleComparisonType))
+		}
+
+		if ad.isAdditionalTermsMatch(t.Name, rule) {
+			addCheck(SimulationCheckAdditionalTerms, true, "")
+		} else {
+			addCheck(SimulationCheckAdditionalTerms, false, "The name does not contain one of the options of each additional term")
+		}
+
+		episode, reason, ok := ad.matchSeasonAndEpisode(&settings, t.ParsedData, rule, listEntry, localEntry, items)
+		addCheck(SimulationCheckSeasonAndEpisode, ok, reason)
+
+		if ok && rule.HasQualityProfile() {
+			target := getUpgradeTarget(episode, rule, localEntry, items)
+			if isWorthUpgrading(getReleaseQuality(t.Name, t.ParsedData, rule), target, rule) {
+				st.IsUpgrade = target.exists
+				addCheck(SimulationCheckQuality, true, "")
+			} else {
+				ok = false
+				addCheck(SimulationCheckQuality, false, fmt.Sprintf("Episode %d already exists and the release is not an upgrade", episode))
+			}
+		}
+
+		if _, found := queued[t]; found {
+			ok = false
+			addCheck(SimulationCheckQueue, false, "Already in the queue")
+		}
+
+		if _, found := existingHashes[t.InfoHash]; found {
+			ok = false
+			addCheck(SimulationCheckTorrentClient, false, "Already in the torrent client")
+		}
+
+		st.Matched = ok
+		for _, check := range st.Checks {
+			if !check.Passed {
+				st.Matched = false
+			}
+		}
+
+		if st.Matched {
+			st.Episode = episode
+			torrentsToDownload = append(torrentsToDownload, &tmpTorrentToDownload{
+				torrent: t,
+				episode: episode,
+			})
+		}
+	}
+
+	for _, t := range selectTorrentsToDownload(rule, torrentsToDownload) {
+		st := simulated[t.torrent]
+		st.WouldDownload = true
+		ret.Downloads = append(ret.Downloads, st)
+	}
+
+	return ret, nil
+}
+
+func newSimulationTorrent(name string) *NormalizedTorrent {
+	t := &NormalizedTorrent{
+		ParsedData: seanime_parser.Parse(name),
+	}
+	t.Name = name
+	t.EpisodeNumber = -1
+	return t
+}
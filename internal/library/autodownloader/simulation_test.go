@@ -0,0 +1,101 @@
+package autodownloader
+
+import (
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seanime/internal/api/anilist"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/library/anime"
+	"seanime/internal/util"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	t.Setenv("TEST_ENV", "true")
+
+	logger := util.NewLogger()
+	database, err := db.NewDatabase(t.TempDir(), "test", logger)
+	require.NoError(t, err)
+
+	// Episode 2 is already queued
+	require.NoError(t, database.InsertAutoDownloaderItem(&models.AutoDownloaderItem{MediaID: 154587, Episode: 2, TorrentName: "[SubsPlease] Sousou no Frieren - 02 (1080p)"}))
+
+	ad := &AutoDownloader{
+		logger:   logger,
+		database: database,
+		settings: &models.AutoDownloaderSettings{},
+		animeCollection: mo.Some(&anilist.AnimeCollection{
+			MediaListCollection: &anilist.AnimeCollection_MediaListCollection{
+				Lists: []*anilist.AnimeCollection_MediaListCollection_Lists{{
+					Entries: []*anilist.AnimeCollection_MediaListCollection_Lists_Entries{{
+						Progress: lo.ToPtr(0),
+						Media: &anilist.BaseAnime{
+							ID:       154587,
+							Title:    &anilist.BaseAnime_Title{Romaji: lo.ToPtr("Sousou no Frieren"), English: lo.ToPtr("Frieren: Beyond Journey's End")},
+							Episodes: lo.ToPtr(28),
+							Format:   lo.ToPtr(anilist.MediaFormatTv),
+						},
+					}},
+				}},
+			},
+		}),
+	}
+
+	rule := &anime.AutoDownloaderRule{
+		MediaId:             154587,
+		ReleaseGroups:       []string{"SubsPlease", "Erai-raws"},
+		Resolutions:         []string{"1080p", "720p"},
+		ComparisonTitle:     "Sousou no Frieren",
+		TitleComparisonType: anime.AutoDownloaderRuleTitleComparisonLikely,
+		EpisodeType:         anime.AutoDownloaderRuleEpisodeRecent,
+	}
+
+	ret, err := ad.Simulate(&SimulationOptions{
+		Rule: rule,
+		TorrentNames: []string{
+			"[SubsPlease] Sousou no Frieren - 03 (1080p) [A1B2C3D4].mkv",
+			"[SubsPlease] Sousou no Frieren - 03 (720p) [A1B2C3D4].mkv",
+			"[Judas] Sousou no Frieren - 03 (1080p).mkv",
+			"[SubsPlease] Sousou no Frieren - 02 (1080p) [A1B2C3D4].mkv",
+			"[SubsPlease] Oshi no Ko - 03 (1080p) [A1B2C3D4].mkv",
+			"",
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, ret.Torrents, 5)
+
+	getCheck := func(st *SimulatedTorrent, name string) *SimulationCheck {
+		check, found := lo.Find(st.Checks, func(c *SimulationCheck) bool { return c.Name == name })
+		require.True(t, found, name)
+		return check
+	}
+
+	assert.True(t, ret.Torrents[0].Matched)
+	assert.Equal(t, 3, ret.Torrents[0].Episode)
+	assert.True(t, ret.Torrents[1].Matched)
+	assert.Equal(t, "1080p", ret.Torrents[0].ParsedData.VideoResolution)
+
+	assert.False(t, ret.Torrents[2].Matched)
+	assert.False(t, getCheck(ret.Torrents[2], SimulationCheckReleaseGroup).Passed)
+	assert.Contains(t, getCheck(ret.Torrents[2], SimulationCheckReleaseGroup).Reason, "Judas")
+
+	assert.False(t, ret.Torrents[3].Matched)
+	assert.Equal(t, "Episode 2 was already downloaded", getCheck(ret.Torrents[3], SimulationCheckSeasonAndEpisode).Reason)
+
+	assert.False(t, ret.Torrents[4].Matched)
+	assert.False(t, getCheck(ret.Torrents[4], SimulationCheckTitle).Passed)
+
+	// The best release of episode 3 would be downloaded
+	require.Len(t, ret.Downloads, 1)
+	assert.Equal(t, ret.Torrents[0].Name, ret.Downloads[0].Name)
+	assert.True(t, ret.Torrents[0].WouldDownload)
+	assert.False(t, ret.Torrents[1].WouldDownload)
+
+	// Nothing was queued
+	items, err := database.GetAutoDownloaderItems()
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+}
@@ -7,7 +7,9 @@ import (
 	"seanime/internal/database/db"
 	"seanime/internal/database/db_bridge"
 	"seanime/internal/events"
+	"seanime/internal/library/anime"
 	"seanime/internal/library/autodownloader"
+	"seanime/internal/library/nfo"
 	"seanime/internal/library/scanner"
 	"seanime/internal/library/summary"
 	"seanime/internal/notifier"
@@ -23,6 +25,7 @@ type (
 		waiting          bool          // Used to prevent multiple scans from occurring at the same time.
 		missedAction     bool          // Used to indicate that a file action was missed while scanning.
 		mu               sync.Mutex
+		scanMu           sync.Mutex // Held while a scan reads, merges and saves the local files.
 		scannedCh        chan struct{}
 		waitTime         time.Duration // Wait time to listen to additional changes before triggering a scan.
 		enabled          bool
@@ -31,8 +34,14 @@ type (
 		wsEventManager   events.WSEventManagerInterface
 		db               *db.Database                   // Database instance is required to update the local files.
 		autoDownloader   *autodownloader.AutoDownloader // AutoDownloader instance is required to refresh queue.
+		nfoExporter      *nfo.Exporter                  // Used to update the NFO files after each scan.
 		metadataProvider metadata.Provider
 		logsDir          string
+		// Incremental scanning
+		incremental       bool                // If true, only the paths reported by the watcher are scanned.
+		pendingAdded      map[string]struct{} // Paths added since the last scan.
+		pendingRemoved    map[string]struct{} // Paths removed since the last scan.
+		fullScanRequested bool                // Set when a file action occurred without a path.
 	}
 	NewAutoScannerOptions struct {
 		Database         *db.Database
@@ -40,7 +49,9 @@ type (
 		Logger           *zerolog.Logger
 		WSEventManager   events.WSEventManagerInterface
 		Enabled          bool
+		Incremental      bool
 		AutoDownloader   *autodownloader.AutoDownloader
+		NfoExporter      *nfo.Exporter
 		WaitTime         time.Duration
 		MetadataProvider metadata.Provider
 		LogsDir          string
@@ -66,12 +77,20 @@ func New(opts *NewAutoScannerOptions) *AutoScanner {
 		wsEventManager:   opts.WSEventManager,
 		db:               opts.Database,
 		autoDownloader:   opts.AutoDownloader,
+		nfoExporter:      opts.NfoExporter,
 		metadataProvider: opts.MetadataProvider,
 		logsDir:          opts.LogsDir,
+		incremental:      opts.Incremental,
+		pendingAdded:     make(map[string]struct{}),
+		pendingRemoved:   make(map[string]struct{}),
 	}
 }
 
+// maxIncrementalPaths is the number of pending paths above which a full scan is triggered instead.
+const maxIncrementalPaths = 500
+
 // Notify is used to notify the AutoScanner that a file action has occurred.
+// The next scan will be a full scan.
 func (as *AutoScanner) Notify() {
 	if as == nil {
 		return
@@ -84,6 +103,50 @@ func (as *AutoScanner) Notify() {
 	as.mu.Lock()
 	defer as.mu.Unlock()
 
+	as.fullScanRequested = true
+
+	as.notify()
+}
+
+// NotifyFileEvent is used to notify the AutoScanner that a file or directory was added or removed.
+// In incremental mode, only the reported paths are scanned.
+func (as *AutoScanner) NotifyFileEvent(event *scanner.FileEvent) {
+	if as == nil || event == nil {
+		return
+	}
+
+	defer util.HandlePanicInModuleThen("scanner/autoscanner/NotifyFileEvent", func() {
+		as.logger.Error().Msg("autoscanner: recovered from panic")
+	})
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if !as.enabled {
+		return
+	}
+
+	switch event.Kind {
+	case scanner.FileEventAdded:
+		as.pendingAdded[event.Path] = struct{}{}
+	case scanner.FileEventRemoved:
+		as.pendingRemoved[event.Path] = struct{}{}
+	default:
+		as.fullScanRequested = true
+	}
+
+	// Too many changes, a full scan will be faster
+	if len(as.pendingAdded)+len(as.pendingRemoved) > maxIncrementalPaths {
+		as.fullScanRequested = true
+		as.pendingAdded = make(map[string]struct{})
+		as.pendingRemoved = make(map[string]struct{})
+	}
+
+	as.notify()
+}
+
+// notify should be called with the lock held.
+func (as *AutoScanner) notify() {
 	// If we are currently scanning, we will set the missedAction flag to true.
 	if as.waiting {
 		as.missedAction = true
@@ -117,6 +180,14 @@ func (as *AutoScanner) SetEnabled(enabled bool) {
 	as.enabled = enabled
 }
 
+// SetIncremental enables or disables incremental scanning.
+func (as *AutoScanner) SetIncremental(incremental bool) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.incremental = incremental
+}
+
 // watch is used to watch for file actions and trigger a scan.
 // When a file action occurs, it will wait 30 seconds before triggering a scan.
 // If another file action occurs within that 30 seconds, it will reset the timer.
@@ -157,23 +228,121 @@ func (as *AutoScanner) waitAndScan() {
 	}
 
 	as.waiting = false
+	incremental := as.incremental && !as.fullScanRequested
+	added, removed := as.takePendingPaths()
 	as.mu.Unlock()
 
 	// Trigger a scan.
+	if incremental && len(added)+len(removed) > 0 {
+		as.scanPaths(added, removed)
+		return
+	}
 	as.scan()
 }
 
+// takePendingPaths returns and resets the paths reported since the last scan.
+// It should be called with the lock held.
+func (as *AutoScanner) takePendingPaths() (added []string, removed []string) {
+	added = make([]string, 0, len(as.pendingAdded))
+	for p := range as.pendingAdded {
+		added = append(added, p)
+	}
+	removed = make([]string, 0, len(as.pendingRemoved))
+	for p := range as.pendingRemoved {
+		removed = append(removed, p)
+	}
+	as.pendingAdded = make(map[string]struct{})
+	as.pendingRemoved = make(map[string]struct{})
+	as.fullScanRequested = false
+	return
+}
+
 // RunNow bypasses checks and triggers a scan immediately, even if the autoscanner is disabled.
 func (as *AutoScanner) RunNow() {
+	as.mu.Lock()
+	_, _ = as.takePendingPaths()
+	as.mu.Unlock()
+
 	as.scan()
 }
 
-// scan is used to trigger a scan.
+// ScanPaths triggers an incremental scan of the given files or directories immediately.
+// It is used by the Auto Downloader once it places downloaded files in the library.
+// Nothing is scanned if the autoscanner is disabled.
+func (as *AutoScanner) ScanPaths(paths []string) {
+	as.mu.Lock()
+	enabled := as.enabled
+	as.mu.Unlock()
+
+	if !enabled {
+		as.logger.Debug().Int("paths", len(paths)).Msg("autoscanner: Disabled, skipping scan of new files")
+		return
+	}
+
+	as.scanPaths(paths, nil)
+}
+
+// ScanChanges scans the files that were added, modified or removed while the app was not running.
+// Files are compared with the stored local files using their size and modification time.
+func (as *AutoScanner) ScanChanges() {
+	defer util.HandlePanicInModuleThen("scanner/autoscanner/ScanChanges", func() {
+		as.logger.Error().Msg("autoscanner: Recovered from panic")
+	})
+
+	settings, err := as.db.GetSettings()
+	if err != nil || settings == nil || settings.Library == nil {
+		return
+	}
+
+	existingLfs, _, err := db_bridge.GetLocalFiles(as.db)
+	if err != nil {
+		as.logger.Error().Err(err).Msg("autoscanner: Failed to get existing local files")
+		return
+	}
+
+	added, removed, err := scanner.GetLibraryChanges(settings.Library.GetLibraryPaths(), existingLfs)
+	if err != nil {
+		as.logger.Error().Err(err).Msg("autoscanner: Failed to get library changes")
+		return
+	}
+
+	as.logger.Debug().Int("added", len(added)).Int("removed", len(removed)).Msg("autoscanner: Library changes since last run")
+
+	if len(added)+len(removed) == 0 {
+		return
+	}
+
+	as.scanPaths(added, removed)
+}
+
+// scan is used to trigger a full scan.
 func (as *AutoScanner) scan() {
 	defer util.HandlePanicInModuleThen("scanner/autoscanner/scan", func() {
 		as.logger.Error().Msg("autoscanner: Recovered from panic")
 	})
 
+	as.runScanner(func(sc *scanner.Scanner) ([]*anime.LocalFile, error) {
+		return sc.Scan()
+	})
+}
+
+// scanPaths is used to trigger an incremental scan of the given paths.
+func (as *AutoScanner) scanPaths(added []string, removed []string) {
+	defer util.HandlePanicInModuleThen("scanner/autoscanner/scanPaths", func() {
+		as.logger.Error().Msg("autoscanner: Recovered from panic")
+	})
+
+	as.runScanner(func(sc *scanner.Scanner) ([]*anime.LocalFile, error) {
+		return sc.ScanPaths(added, removed)
+	})
+}
+
+// runScanner creates the scanner, runs it and saves the resulting local files.
+// Scans are serialized so that one does not overwrite the local files saved by another.
+func (as *AutoScanner) runScanner(run func(sc *scanner.Scanner) ([]*anime.LocalFile, error)) {
+	as.scanMu.Lock()
+	defer as.scanMu.Unlock()
+
 	// Create scan summary logger
 	scanSummaryLogger := summary.NewScanSummaryLogger()
 
@@ -225,7 +394,7 @@ func (as *AutoScanner) scan() {
 		MetadataProvider:   as.metadataProvider,
 	}
 
-	allLfs, err := sc.Scan()
+	allLfs, err := run(&sc)
 	if err != nil {
 		if errors.Is(err, scanner.ErrNoLocalFiles) {
 			return
@@ -235,7 +404,8 @@ func (as *AutoScanner) scan() {
 		}
 	}
 
-	if as.db != nil && len(allLfs) > 0 {
+	// Saved even if there are no local files, an incremental scan returns none when the last file is removed
+	if as.db != nil {
 		as.logger.Trace().Msg("autoscanner: Updating local files")
 
 		// Insert the local files
@@ -256,6 +426,9 @@ func (as *AutoScanner) scan() {
 	// Refresh the queue
 	go as.autoDownloader.CleanUpDownloadedItems()
 
+	// Update the NFO files
+	go as.nfoExporter.ExportAfterScan()
+
 	notifier.GlobalNotifier.Notify(notifier.AutoScanner, "Your library has been scanned.")
 
 	return
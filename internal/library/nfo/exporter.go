@@ -0,0 +1,732 @@
+package nfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/metadata"
+	"seanime/internal/database/db"
+	"seanime/internal/database/db_bridge"
+	"seanime/internal/database/models"
+	"seanime/internal/library/anime"
+	"seanime/internal/library/organizer"
+	"seanime/internal/platforms/platform"
+	"seanime/internal/util"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ModeAlongside = "alongside" // The files are written next to the media files
+	ModeMirror    = "mirror"    // The media files are linked into a directory with clean names and the files are written next to the links
+
+	// Paths of the links in the mirror directory
+	mirrorEpisodeTemplate = "{title} ({year})/Season {season:02}/{title} - S{season:02}E{episode:02}.{ext}"
+	mirrorMovieTemplate   = "{title} ({year})/{title} ({year}).{ext}"
+
+	// The manifest lists the files created in the mirror directory so that only those are removed when they are no longer needed
+	mirrorManifestName = ".seanime-nfo.json"
+
+	fileKindLink    = "link"
+	fileKindNfo     = "nfo"
+	fileKindArtwork = "artwork"
+)
+
+var (
+	ErrInvalidMode     = errors.New("nfo: Invalid mode")
+	ErrNoMirrorDir     = errors.New("nfo: No mirror directory")
+	ErrAlreadyRunning  = errors.New("nfo: Export already running")
+	ErrMirrorInLibrary = errors.New("nfo: The mirror directory cannot be inside a library directory or contain one")
+)
+
+type (
+	// Exporter writes the NFO files and artwork of the matched library so that it can be read by Kodi, Jellyfin and Plex.
+	Exporter struct {
+		db               *db.Database
+		logger           *zerolog.Logger
+		platform         platform.Platform
+		metadataProvider metadata.Provider
+		client           *http.Client
+		mu               sync.Mutex
+	}
+
+	NewExporterOptions struct {
+		Database         *db.Database
+		Logger           *zerolog.Logger
+		Platform         platform.Platform
+		MetadataProvider metadata.Provider
+	}
+
+	// ExportResult is the summary of an export.
+	ExportResult struct {
+		Mode              string   `json:"mode"`
+		NfoWritten        int      `json:"nfoWritten"`
+		ArtworkDownloaded int      `json:"artworkDownloaded"`
+		LinksCreated      int      `json:"linksCreated"`
+		Removed           int      `json:"removed"`
+		Errors            []string `json:"errors"`
+	}
+
+	// mediaGroup holds the local files of a media.
+	mediaGroup struct {
+		media         *anilist.BaseAnime
+		animeMetadata *metadata.AnimeMetadata
+		localFiles    []*anime.LocalFile
+	}
+
+	export struct {
+		settings *models.NfoSettings
+		result   *ExportResult
+		expected map[string]struct{} // Files written or kept by this export, used to remove stale files
+		owned    map[string]string   // Files created by the exporter, path -> kind (link, nfo, artwork)
+		previous map[string]string   // Files created by the previous mirror export, read from the manifest
+	}
+)
+
+func NewExporter(opts *NewExporterOptions) *Exporter {
+	return &Exporter{
+		db:               opts.Database,
+		logger:           opts.Logger,
+		platform:         opts.Platform,
+		metadataProvider: opts.MetadataProvider,
+		client:           &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ExportAfterScan runs the export if it is enabled in the settings.
+// It is called after each scan.
+func (e *Exporter) ExportAfterScan() {
+	if e == nil {
+		return
+	}
+
+	settings, found := e.db.GetNfoSettings()
+	if !found || !settings.Enabled {
+		return
+	}
+
+	res, err := e.Export(settings)
+	if err != nil {
+		e.logger.Error().Err(err).Msg("nfo: Failed to export library metadata")
+		return
+	}
+
+	e.logger.Info().
+		Int("nfoWritten", res.NfoWritten).
+		Int("artworkDownloaded", res.ArtworkDownloaded).
+		Int("linksCreated", res.LinksCreated).
+		Int("removed", res.Removed).
+		Int("errors", len(res.Errors)).
+		Msg("nfo: Library metadata exported")
+}
+
+// Export writes the NFO files and artwork of all matched local files.
+// Existing files are only rewritten if their content changed, and NFO files that were not created by the exporter are never modified.
+// Files created by a previous export that are no longer needed are removed.
+func (e *Exporter) Export(settings *models.NfoSettings) (*ExportResult, error) {
+	if settings == nil {
+		return nil, errors.New("nfo: No settings")
+	}
+
+	mode := settings.Mode
+	if mode == "" {
+		mode = ModeAlongside
+	}
+	if mode != ModeAlongside && mode != ModeMirror {
+		return nil, ErrInvalidMode
+	}
+	if mode == ModeMirror && settings.MirrorDir == "" {
+		return nil, ErrNoMirrorDir
+	}
+
+	if !e.mu.TryLock() {
+		return nil, ErrAlreadyRunning
+	}
+	defer e.mu.Unlock()
+
+	lfs, _, err := db_bridge.GetLocalFiles(e.db)
+	if err != nil {
+		return nil, err
+	}
+
+	ex := &export{
+		settings: settings,
+		result: &ExportResult{
+			Mode:   mode,
+			Errors: make([]string, 0),
+		},
+		expected: make(map[string]struct{}),
+		owned:    make(map[string]string),
+		previous: make(map[string]string),
+	}
+
+	groups := e.getMediaGroups(lfs)
+
+	switch mode {
+	case ModeAlongside:
+		libraryPaths := e.getLibraryPaths()
+		for _, group := range groups {
+			e.exportAlongside(ex, group, groups, libraryPaths)
+		}
+		for _, libraryPath := range libraryPaths {
+			e.removeStaleAlongside(ex, libraryPath)
+		}
+	case ModeMirror:
+		mirrorDir := filepath.Clean(settings.MirrorDir)
+		if err := e.ValidateMirrorDir(mirrorDir); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(mirrorDir, 0755); err != nil {
+			return nil, err
+		}
+		e.exportMirror(ex, mirrorDir, groups)
+		e.removeStaleMirror(ex, mirrorDir)
+	}
+
+	return ex.result, nil
+}
+
+// getMediaGroups groups the matched local files by media.
+// Openings, endings and ignored files are left out.
+func (e *Exporter) getMediaGroups(lfs []*anime.LocalFile) []*mediaGroup {
+	var collection *anilist.AnimeCollection
+	if e.platform != nil {
+		collection, _ = e.platform.GetAnimeCollection(false)
+	}
+
+	groupsMap := make(map[int]*mediaGroup)
+	ret := make([]*mediaGroup, 0)
+
+	for _, lf := range lfs {
+		if lf.MediaId == 0 || lf.IsIgnored() || lf.GetMetadata() == nil || lf.GetType() == anime.LocalFileTypeNC {
+			continue
+		}
+
+		group, found := groupsMap[lf.MediaId]
+		if !found {
+			group = &mediaGroup{}
+			groupsMap[lf.MediaId] = group
+
+			if collection != nil {
+				group.media, _ = collection.FindAnime(lf.MediaId)
+			}
+			if group.media == nil && e.platform != nil {
+				media, err := e.platform.GetAnime(lf.MediaId)
+				if err != nil {
+					e.logger.Warn().Err(err).Int("mediaId", lf.MediaId).Msg("nfo: Failed to fetch media")
+				}
+				group.media = media
+			}
+			if group.media == nil {
+				continue
+			}
+
+			if e.metadataProvider != nil {
+				group.animeMetadata, _ = e.metadataProvider.GetAnimeMetadata(metadata.AnilistPlatform, lf.MediaId)
+			}
+			ret = append(ret, group)
+		}
+		if group.media == nil {
+			continue
+		}
+
+		group.localFiles = append(group.localFiles, lf)
+	}
+
+	for _, group := range ret {
+		sort.Slice(group.localFiles, func(i, j int) bool {
+			return group.localFiles[i].GetPath() < group.localFiles[j].GetPath()
+		})
+	}
+
+	return ret
+}
+
+// ValidateMirrorDir returns an error if the mirror directory overlaps a library directory.
+// Files in the mirror directory can be removed by the exporter, so it must not contain the media files.
+func (e *Exporter) ValidateMirrorDir(mirrorDir string) error {
+	return validateMirrorDir(mirrorDir, e.getLibraryPaths())
+}
+
+func validateMirrorDir(mirrorDir string, libraryPaths []string) error {
+	if mirrorDir == "" {
+		return ErrNoMirrorDir
+	}
+	mirrorDir, err := filepath.Abs(mirrorDir)
+	if err != nil {
+		return err
+	}
+	for _, libraryPath := range libraryPaths {
+		libraryPath, err := filepath.Abs(libraryPath)
+		if err != nil {
+			continue
+		}
+		if isInDir(mirrorDir, libraryPath) || isInDir(libraryPath, mirrorDir) {
+			return ErrMirrorInLibrary
+		}
+	}
+	return nil
+}
+
+func (e *Exporter) getLibraryPaths() []string {
+	if e.db == nil {
+		return []string{}
+	}
+
+	settings, err := e.db.GetSettings()
+	if err != nil || settings.Library == nil {
+		return []string{}
+	}
+
+	ret := make([]string, 0)
+	if settings.Library.LibraryPath != "" {
+		ret = append(ret, settings.Library.LibraryPath)
+	}
+	for _, path := range settings.Library.LibraryPaths {
+		if path != "" {
+			ret = append(ret, path)
+		}
+	}
+	return ret
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+
+// exportAlongside writes the files next to the media files.
+//   - Episodes: "<name>.nfo" and "<name>-thumb.jpg"
+//   - Movies: "<name>.nfo", "<name>-poster.jpg" and "<name>-fanart.jpg"
+//   - Series: "tvshow.nfo", "poster.jpg" and "fanart.jpg" in the directory containing all the episodes,
+//     unless it is a library root or it contains files of other media.
+//
+// The library watcher ignores these files, see scanner.IsWatchedFile, so an export does not trigger another scan.
+// The exporter should never write video files or create directories in the library.
+func (e *Exporter) exportAlongside(ex *export, group *mediaGroup, groups []*mediaGroup, libraryPaths []string) {
+	media := group.media
+
+	if media.IsMovie() {
+		for _, lf := range group.localFiles {
+			base := strings.TrimSuffix(lf.GetPath(), filepath.Ext(lf.GetPath()))
+			e.writeNfo(ex, base+".nfo", NewMovie(media, group.animeMetadata))
+			e.downloadArtwork(ex, media.GetCoverImageSafe(), base+"-poster.jpg")
+			e.downloadArtwork(ex, getFanart(media), base+"-fanart.jpg")
+		}
+		return
+	}
+
+	for _, lf := range group.localFiles {
+		base := strings.TrimSuffix(lf.GetPath(), filepath.Ext(lf.GetPath()))
+		season, episode, episodeMetadata := getEpisodeInfo(lf, group)
+		e.writeNfo(ex, base+".nfo", NewEpisode(media, season, episode, episodeMetadata))
+		if episodeMetadata != nil {
+			e.downloadArtwork(ex, episodeMetadata.Image, base+"-thumb.jpg")
+		}
+	}
+
+	showDir, ok := getShowDir(group, groups, libraryPaths)
+	if !ok {
+		e.logger.Debug().Int("mediaId", media.ID).Msg("nfo: No dedicated directory, skipping tvshow.nfo")
+		return
+	}
+	e.writeNfo(ex, filepath.Join(showDir, "tvshow.nfo"), NewTVShow(media, group.animeMetadata))
+	e.downloadArtwork(ex, media.GetCoverImageSafe(), filepath.Join(showDir, "poster.jpg"))
+	e.downloadArtwork(ex, getFanart(media), filepath.Join(showDir, "fanart.jpg"))
+}
+
+// removeStaleAlongside removes the generated episode NFO files whose media file no longer exists.
+func (e *Exporter) removeStaleAlongside(ex *export, libraryPath string) {
+	_ = filepath.WalkDir(libraryPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".nfo") || strings.EqualFold(d.Name(), "tvshow.nfo") {
+			return nil
+		}
+		if _, ok := ex.expected[path]; ok {
+			return nil
+		}
+		if hasSiblingMediaFile(path) || !isGeneratedFile(path) {
+			return nil
+		}
+		if err := os.Remove(path); err == nil {
+			ex.result.Removed++
+		}
+		return nil
+	})
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+
+// exportMirror links the media files into the mirror directory using clean names and writes the files next to the links.
+func (e *Exporter) exportMirror(ex *export, mirrorDir string, groups []*mediaGroup) {
+	ex.previous = readMirrorManifest(mirrorDir)
+
+	episodeTmpl, _ := organizer.ParseTemplate(mirrorEpisodeTemplate)
+	movieTmpl, _ := organizer.ParseTemplate(mirrorMovieTemplate)
+
+	for _, group := range groups {
+		media := group.media
+		isMovie := media.IsMovie()
+
+		tmpl := episodeTmpl
+		if isMovie {
+			tmpl = movieTmpl
+		}
+
+		showDir := ""
+		for _, lf := range group.localFiles {
+			rel, err := tmpl.Render(organizer.NewTemplateData(lf, media))
+			if err != nil {
+				ex.addError(lf.GetPath(), err)
+				continue
+			}
+			linkPath := filepath.Join(mirrorDir, filepath.FromSlash(rel))
+			if _, ok := ex.expected[linkPath]; ok {
+				ex.addError(lf.GetPath(), fmt.Errorf("same destination as another file: %s", rel))
+				continue
+			}
+
+			if err := e.link(ex, lf.GetPath(), linkPath); err != nil {
+				ex.addError(lf.GetPath(), err)
+				continue
+			}
+
+			base := strings.TrimSuffix(linkPath, filepath.Ext(linkPath))
+			if isMovie {
+				showDir = filepath.Dir(linkPath)
+				e.writeNfo(ex, base+".nfo", NewMovie(media, group.animeMetadata))
+				continue
+			}
+
+			// The show directory is the parent of the season directory
+			showDir = filepath.Dir(filepath.Dir(linkPath))
+			season, episode, episodeMetadata := getEpisodeInfo(lf, group)
+			e.writeNfo(ex, base+".nfo", NewEpisode(media, season, episode, episodeMetadata))
+			if episodeMetadata != nil {
+				e.downloadArtwork(ex, episodeMetadata.Image, base+"-thumb.jpg")
+			}
+		}
+
+		if showDir == "" {
+			continue
+		}
+		if !isMovie {
+			e.writeNfo(ex, filepath.Join(showDir, "tvshow.nfo"), NewTVShow(media, group.animeMetadata))
+		}
+		e.downloadArtwork(ex, media.GetCoverImageSafe(), filepath.Join(showDir, "poster.jpg"))
+		e.downloadArtwork(ex, getFanart(media), filepath.Join(showDir, "fanart.jpg"))
+	}
+}
+
+// link creates a symbolic link to the media file.
+// An existing link is only replaced if it was created by a previous export.
+func (e *Exporter) link(ex *export, target string, linkPath string) error {
+	ex.expected[linkPath] = struct{}{}
+
+	if info, err := os.Lstat(linkPath); err == nil {
+		if info.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("%s already exists and is not a link", linkPath)
+		}
+		if current, err := os.Readlink(linkPath); err == nil && current == target {
+			ex.owned[linkPath] = fileKindLink
+			return nil
+		}
+		if ex.previous[linkPath] != fileKindLink {
+			return fmt.Errorf("%s already exists and was not created by Seanime", linkPath)
+		}
+		if err := os.Remove(linkPath); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
+		return err
+	}
+	if err := os.Symlink(target, linkPath); err != nil {
+		return err
+	}
+	ex.owned[linkPath] = fileKindLink
+	ex.result.LinksCreated++
+	return nil
+}
+
+// removeStaleMirror removes the files created by the previous export that are no longer needed, then their empty parent directories.
+// Only the files listed in the manifest are removed, and only if they are still of the same kind, so that files added by the user are kept.
+// The manifest is then updated with the files created by this export.
+func (e *Exporter) removeStaleMirror(ex *export, mirrorDir string) {
+	dirs := make(map[string]struct{})
+	for path, kind := range ex.previous {
+		if _, ok := ex.expected[path]; ok {
+			continue
+		}
+		if !isInDir(mirrorDir, path) || !isOwnedFile(path, kind) {
+			continue
+		}
+		if err := os.Remove(path); err == nil {
+			ex.result.Removed++
+			dirs[filepath.Dir(path)] = struct{}{}
+		}
+	}
+
+	// Remove the directories that became empty, up to the mirror directory
+	for dir := range dirs {
+		for dir != mirrorDir && isInDir(mirrorDir, dir) {
+			if entries, err := os.ReadDir(dir); err != nil || len(entries) > 0 {
+				break
+			}
+			_ = os.Remove(dir)
+			dir = filepath.Dir(dir)
+		}
+	}
+
+	if err := writeMirrorManifest(mirrorDir, ex.owned); err != nil {
+		ex.addError(filepath.Join(mirrorDir, mirrorManifestName), err)
+	}
+}
+
+// isOwnedFile returns true if the file is still of the kind it was created as.
+func isOwnedFile(path string, kind string) bool {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	switch kind {
+	case fileKindLink:
+		return info.Mode()&os.ModeSymlink != 0
+	case fileKindNfo:
+		return info.Mode().IsRegular() && isGeneratedFile(path)
+	case fileKindArtwork:
+		return info.Mode().IsRegular()
+	}
+	return false
+}
+
+type mirrorManifest struct {
+	Files map[string]string `json:"files"` // Path relative to the mirror directory -> kind
+}
+
+// readMirrorManifest returns the absolute paths of the files listed in the manifest.
+func readMirrorManifest(mirrorDir string) map[string]string {
+	ret := make(map[string]string)
+
+	data, err := os.ReadFile(filepath.Join(mirrorDir, mirrorManifestName))
+	if err != nil {
+		return ret
+	}
+	var manifest mirrorManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return ret
+	}
+	for rel, kind := range manifest.Files {
+		// Ignore paths outside the mirror directory
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			continue
+		}
+		ret[filepath.Join(mirrorDir, filepath.FromSlash(rel))] = kind
+	}
+	return ret
+}
+
+func writeMirrorManifest(mirrorDir string, owned map[string]string) error {
+	manifest := mirrorManifest{Files: make(map[string]string, len(owned))}
+	for path, kind := range owned {
+		rel, err := filepath.Rel(mirrorDir, path)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+		manifest.Files[filepath.ToSlash(rel)] = kind
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(mirrorDir, mirrorManifestName), data, 0644)
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+
+// writeNfo writes the NFO file if its content changed.
+// NFO files that were not created by the exporter are left untouched.
+func (e *Exporter) writeNfo(ex *export, path string, v interface{}) {
+	ex.expected[path] = struct{}{}
+
+	content, err := Marshal(v)
+	if err != nil {
+		ex.addError(path, err)
+		return
+	}
+
+	if existing, err := os.ReadFile(path); err == nil {
+		if !IsGenerated(existing) {
+			return
+		}
+		ex.owned[path] = fileKindNfo
+		if bytes.Equal(existing, content) {
+			return
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		ex.addError(path, err)
+		return
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		ex.addError(path, err)
+		return
+	}
+	ex.owned[path] = fileKindNfo
+	ex.result.NfoWritten++
+}
+
+// downloadArtwork downloads the image if artwork is enabled and the file does not exist.
+func (e *Exporter) downloadArtwork(ex *export, url string, path string) {
+	if !ex.settings.DownloadArtwork || url == "" {
+		return
+	}
+	ex.expected[path] = struct{}{}
+
+	if _, err := os.Stat(path); err == nil {
+		// Existing artwork is only owned by the exporter if it was downloaded by a previous export
+		if ex.previous[path] == fileKindArtwork {
+			ex.owned[path] = fileKindArtwork
+		}
+		return
+	}
+
+	if err := e.downloadFile(url, path); err != nil {
+		ex.addError(path, err)
+		return
+	}
+	ex.owned[path] = fileKindArtwork
+	ex.result.ArtworkDownloaded++
+}
+
+func (e *Exporter) downloadFile(url string, path string) (err error) {
+	defer util.HandlePanicInModuleWithError("nfo/downloadFile", &err)
+
+	resp, err := e.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file so that an interrupted download is not kept
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, resp.Body)
+	_ = f.Close()
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+func (ex *export) addError(path string, err error) {
+	ex.result.Errors = append(ex.result.Errors, fmt.Sprintf("%s: %s", path, err.Error()))
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+
+// getEpisodeInfo returns the season and episode numbers of the local file and its metadata.
+func getEpisodeInfo(lf *anime.LocalFile, group *mediaGroup) (season int, episode int, episodeMetadata *metadata.EpisodeMetadata) {
+	data := organizer.NewTemplateData(lf, group.media)
+	season, _ = strconv.Atoi(data["season"])
+	episode = lf.GetEpisodeNumber()
+
+	if group.animeMetadata != nil && lf.GetMetadata().AniDBEpisode != "" {
+		episodeMetadata, _ = group.animeMetadata.FindEpisode(lf.GetMetadata().AniDBEpisode)
+	}
+	return
+}
+
+// getShowDir returns the directory containing all the local files of the media.
+// Returns false if it is a library root or if it contains local files of other media.
+func getShowDir(group *mediaGroup, groups []*mediaGroup, libraryPaths []string) (string, bool) {
+	if len(group.localFiles) == 0 {
+		return "", false
+	}
+
+	dir := filepath.Dir(group.localFiles[0].GetPath())
+	for _, lf := range group.localFiles[1:] {
+		for !isInDir(dir, lf.GetPath()) {
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				return "", false
+			}
+			dir = parent
+		}
+	}
+
+	for _, libraryPath := range libraryPaths {
+		if isInDir(dir, libraryPath) {
+			return "", false
+		}
+	}
+
+	for _, other := range groups {
+		if other == group {
+			continue
+		}
+		for _, lf := range other.localFiles {
+			if isInDir(dir, lf.GetPath()) {
+				return "", false
+			}
+		}
+	}
+
+	return dir, true
+}
+
+// isInDir returns true if path is dir or is inside dir.
+func isInDir(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func hasSiblingMediaFile(nfoPath string) bool {
+	base := strings.TrimSuffix(filepath.Base(nfoPath), filepath.Ext(nfoPath))
+	entries, err := os.ReadDir(filepath.Dir(nfoPath))
+	if err != nil {
+		return true
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := filepath.Ext(name)
+		if strings.TrimSuffix(name, ext) == base && util.IsValidVideoExtension(strings.ToLower(ext)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isGeneratedFile(path string) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return IsGenerated(content)
+}
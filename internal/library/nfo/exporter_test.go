@@ -0,0 +1,249 @@
+package nfo
+
+import (
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/metadata"
+	"seanime/internal/database/models"
+	"seanime/internal/library/anime"
+	"seanime/internal/library/scanner"
+	"seanime/internal/util"
+	"testing"
+)
+
+func newTestGroup(t *testing.T, dir string, server *httptest.Server) *mediaGroup {
+	media := &anilist.BaseAnime{
+		ID:         154587,
+		Title:      &anilist.BaseAnime_Title{Romaji: lo.ToPtr("Sousou no Frieren"), English: lo.ToPtr("Frieren")},
+		Format:     lo.ToPtr(anilist.MediaFormatTv),
+		StartDate:  &anilist.BaseAnime_StartDate{Year: lo.ToPtr(2023)},
+		CoverImage: &anilist.BaseAnime_CoverImage{ExtraLarge: lo.ToPtr(server.URL + "/cover.jpg")},
+	}
+	group := &mediaGroup{
+		media: media,
+		animeMetadata: &metadata.AnimeMetadata{
+			Episodes: map[string]*metadata.EpisodeMetadata{
+				"1": {Title: "The Journey's End", Image: server.URL + "/1.jpg"},
+			},
+		},
+	}
+
+	for i, name := range []string{
+		"[SubsPlease] Sousou no Frieren - 01 (1080p) [F02B9CEE].mkv",
+		"[SubsPlease] Sousou no Frieren - 02 (1080p) [E5A85899].mkv",
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(path, []byte("video"), 0644))
+
+		lf := anime.NewLocalFile(path, filepath.Dir(dir))
+		lf.MediaId = media.ID
+		lf.Metadata = &anime.LocalFileMetadata{Episode: i + 1, AniDBEpisode: lo.Ternary(i == 0, "1", "2"), Type: anime.LocalFileTypeMain}
+		group.localFiles = append(group.localFiles, lf)
+	}
+
+	return group
+}
+
+func newTestExport(settings *models.NfoSettings) *export {
+	return &export{
+		settings: settings,
+		result:   &ExportResult{Mode: settings.Mode, Errors: make([]string, 0)},
+		expected: make(map[string]struct{}),
+		owned:    make(map[string]string),
+		previous: make(map[string]string),
+	}
+}
+
+func TestExporter_Alongside(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("image"))
+	}))
+	defer server.Close()
+
+	libraryDir := t.TempDir()
+	showDir := filepath.Join(libraryDir, "Frieren")
+	group := newTestGroup(t, showDir, server)
+
+	e := NewExporter(&NewExporterOptions{Logger: util.NewLogger()})
+
+	// A user-created episode NFO is left untouched
+	customNfo := filepath.Join(showDir, "[SubsPlease] Sousou no Frieren - 02 (1080p) [E5A85899].nfo")
+	require.NoError(t, os.WriteFile(customNfo, []byte("<episodedetails><title>Custom</title></episodedetails>"), 0644))
+
+	ex := newTestExport(&models.NfoSettings{Mode: ModeAlongside, DownloadArtwork: true})
+	e.exportAlongside(ex, group, []*mediaGroup{group}, []string{libraryDir})
+	require.Empty(t, ex.result.Errors)
+
+	assert.Equal(t, 2, ex.result.NfoWritten) // Episode 1 and tvshow.nfo
+	assert.Equal(t, 2, ex.result.ArtworkDownloaded)
+	assert.FileExists(t, filepath.Join(showDir, "tvshow.nfo"))
+	assert.FileExists(t, filepath.Join(showDir, "poster.jpg"))
+	assert.FileExists(t, filepath.Join(showDir, "[SubsPlease] Sousou no Frieren - 01 (1080p) [F02B9CEE].nfo"))
+	assert.FileExists(t, filepath.Join(showDir, "[SubsPlease] Sousou no Frieren - 01 (1080p) [F02B9CEE]-thumb.jpg"))
+
+	content, err := os.ReadFile(customNfo)
+	require.NoError(t, err)
+	assert.False(t, IsGenerated(content))
+
+	// The written files do not trigger a library scan
+	written := 0
+	require.NoError(t, filepath.WalkDir(showDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) == ".mkv" {
+			return err
+		}
+		written++
+		assert.False(t, scanner.IsWatchedFile(path), path)
+		return nil
+	}))
+	assert.Equal(t, 5, written) // Including the user-created NFO file
+
+	// Nothing changed, nothing is written
+	ex = newTestExport(&models.NfoSettings{Mode: ModeAlongside, DownloadArtwork: true})
+	e.exportAlongside(ex, group, []*mediaGroup{group}, []string{libraryDir})
+	assert.Equal(t, 0, ex.result.NfoWritten)
+	assert.Equal(t, 0, ex.result.ArtworkDownloaded)
+
+	// The NFO file of a removed episode is removed
+	require.NoError(t, os.Remove(group.localFiles[0].GetPath()))
+	group.localFiles = group.localFiles[1:]
+	ex = newTestExport(&models.NfoSettings{Mode: ModeAlongside})
+	e.exportAlongside(ex, group, []*mediaGroup{group}, []string{libraryDir})
+	e.removeStaleAlongside(ex, libraryDir)
+	assert.Equal(t, 1, ex.result.Removed)
+	assert.NoFileExists(t, filepath.Join(showDir, "[SubsPlease] Sousou no Frieren - 01 (1080p) [F02B9CEE].nfo"))
+	assert.FileExists(t, customNfo)
+}
+
+func TestExporter_AlongsideLibraryRoot(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	// Files directly in the library root do not get a tvshow.nfo
+	libraryDir := t.TempDir()
+	group := newTestGroup(t, libraryDir, server)
+
+	e := NewExporter(&NewExporterOptions{Logger: util.NewLogger()})
+	ex := newTestExport(&models.NfoSettings{Mode: ModeAlongside})
+	e.exportAlongside(ex, group, []*mediaGroup{group}, []string{libraryDir})
+
+	assert.Equal(t, 2, ex.result.NfoWritten)
+	assert.NoFileExists(t, filepath.Join(libraryDir, "tvshow.nfo"))
+}
+
+func TestExporter_Mirror(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("image"))
+	}))
+	defer server.Close()
+
+	libraryDir := t.TempDir()
+	mirrorDir := t.TempDir()
+	group := newTestGroup(t, filepath.Join(libraryDir, "[SubsPlease] Sousou no Frieren (01-28)"), server)
+
+	e := NewExporter(&NewExporterOptions{Logger: util.NewLogger()})
+
+	// Stale link from a previous export
+	staleDir := filepath.Join(mirrorDir, "Old Show (2020)", "Season 01")
+	require.NoError(t, os.MkdirAll(staleDir, 0755))
+	staleLink := filepath.Join(staleDir, "Old Show - S01E01.mkv")
+	require.NoError(t, os.Symlink(group.localFiles[0].GetPath(), staleLink))
+	require.NoError(t, writeMirrorManifest(mirrorDir, map[string]string{staleLink: fileKindLink}))
+
+	ex := newTestExport(&models.NfoSettings{Mode: ModeMirror, MirrorDir: mirrorDir, DownloadArtwork: true})
+	e.exportMirror(ex, mirrorDir, []*mediaGroup{group})
+	e.removeStaleMirror(ex, mirrorDir)
+	require.Empty(t, ex.result.Errors)
+
+	seasonDir := filepath.Join(mirrorDir, "Frieren (2023)", "Season 01")
+	link := filepath.Join(seasonDir, "Frieren - S01E01.mkv")
+	target, err := os.Readlink(link)
+	require.NoError(t, err)
+	assert.Equal(t, group.localFiles[0].GetPath(), target)
+
+	assert.Equal(t, 2, ex.result.LinksCreated)
+	assert.FileExists(t, filepath.Join(seasonDir, "Frieren - S01E02.nfo"))
+	assert.FileExists(t, filepath.Join(seasonDir, "Frieren - S01E01-thumb.jpg"))
+	assert.FileExists(t, filepath.Join(mirrorDir, "Frieren (2023)", "tvshow.nfo"))
+	assert.FileExists(t, filepath.Join(mirrorDir, "Frieren (2023)", "poster.jpg"))
+
+	assert.Equal(t, 1, ex.result.Removed)
+	assert.NoDirExists(t, filepath.Join(mirrorDir, "Old Show (2020)"))
+
+	// Running again does not recreate the links
+	ex = newTestExport(&models.NfoSettings{Mode: ModeMirror, MirrorDir: mirrorDir, DownloadArtwork: true})
+	e.exportMirror(ex, mirrorDir, []*mediaGroup{group})
+	e.removeStaleMirror(ex, mirrorDir)
+	assert.Equal(t, 0, ex.result.LinksCreated)
+	assert.Equal(t, 0, ex.result.NfoWritten)
+	assert.Equal(t, 0, ex.result.Removed)
+}
+
+func TestExporter_MirrorKeepsUserFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("image"))
+	}))
+	defer server.Close()
+
+	libraryDir := t.TempDir()
+	mirrorDir := t.TempDir()
+	group := newTestGroup(t, filepath.Join(libraryDir, "Frieren"), server)
+
+	e := NewExporter(&NewExporterOptions{Logger: util.NewLogger()})
+
+	// Files that were not created by the exporter
+	userDir := filepath.Join(mirrorDir, "Other Show")
+	require.NoError(t, os.MkdirAll(userDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(userDir, "poster.jpg"), []byte("user"), 0644))
+	require.NoError(t, os.Symlink(group.localFiles[0].GetPath(), filepath.Join(userDir, "episode.mkv")))
+	require.NoError(t, os.MkdirAll(filepath.Join(mirrorDir, "Empty"), 0755))
+
+	// A link that exists at the destination but was not created by the exporter is not replaced
+	seasonDir := filepath.Join(mirrorDir, "Frieren (2023)", "Season 01")
+	require.NoError(t, os.MkdirAll(seasonDir, 0755))
+	userLink := filepath.Join(seasonDir, "Frieren - S01E02.mkv")
+	require.NoError(t, os.Symlink(filepath.Join(libraryDir, "elsewhere.mkv"), userLink))
+
+	ex := newTestExport(&models.NfoSettings{Mode: ModeMirror, MirrorDir: mirrorDir, DownloadArtwork: true})
+	e.exportMirror(ex, mirrorDir, []*mediaGroup{group})
+	e.removeStaleMirror(ex, mirrorDir)
+
+	assert.Len(t, ex.result.Errors, 1)
+	assert.Equal(t, 0, ex.result.Removed)
+	assert.FileExists(t, filepath.Join(userDir, "poster.jpg"))
+	assert.FileExists(t, filepath.Join(userDir, "episode.mkv"))
+	assert.DirExists(t, filepath.Join(mirrorDir, "Empty"))
+	target, err := os.Readlink(userLink)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(libraryDir, "elsewhere.mkv"), target)
+
+	// The files created by the exporter are removed once they are no longer needed
+	ex = newTestExport(&models.NfoSettings{Mode: ModeMirror, MirrorDir: mirrorDir, DownloadArtwork: true})
+	e.exportMirror(ex, mirrorDir, []*mediaGroup{})
+	e.removeStaleMirror(ex, mirrorDir)
+
+	assert.NoFileExists(t, filepath.Join(seasonDir, "Frieren - S01E01.mkv"))
+	assert.NoFileExists(t, filepath.Join(mirrorDir, "Frieren (2023)", "tvshow.nfo"))
+	assert.FileExists(t, userLink)
+	assert.FileExists(t, filepath.Join(userDir, "poster.jpg"))
+	assert.FileExists(t, filepath.Join(userDir, "episode.mkv"))
+	assert.DirExists(t, filepath.Join(mirrorDir, "Empty"))
+}
+
+func TestValidateMirrorDir(t *testing.T) {
+	libraryDir := t.TempDir()
+
+	assert.ErrorIs(t, validateMirrorDir("", []string{libraryDir}), ErrNoMirrorDir)
+	assert.ErrorIs(t, validateMirrorDir(libraryDir, []string{libraryDir}), ErrMirrorInLibrary)
+	assert.ErrorIs(t, validateMirrorDir(filepath.Join(libraryDir, "Mirror"), []string{libraryDir}), ErrMirrorInLibrary)
+	assert.ErrorIs(t, validateMirrorDir(filepath.Dir(libraryDir), []string{libraryDir}), ErrMirrorInLibrary)
+	assert.NoError(t, validateMirrorDir(t.TempDir(), []string{libraryDir}))
+	assert.NoError(t, validateMirrorDir(libraryDir+"-mirror", []string{libraryDir}))
+}
@@ -0,0 +1,279 @@
+package nfo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"regexp"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/metadata"
+	"strconv"
+	"strings"
+)
+
+// generatedMarker is written in every NFO file created by the exporter.
+// Files without it were created by the user or another tool and are never modified.
+const generatedMarker = "<!-- Generated by Seanime -->"
+
+type (
+	UniqueID struct {
+		Type    string `xml:"type,attr"`
+		Default bool   `xml:"default,attr,omitempty"`
+		Value   string `xml:",chardata"`
+	}
+
+	Thumb struct {
+		Aspect string `xml:"aspect,attr,omitempty"`
+		Value  string `xml:",chardata"`
+	}
+
+	Fanart struct {
+		Thumbs []Thumb `xml:"thumb"`
+	}
+
+	// TVShow is the content of tvshow.nfo.
+	TVShow struct {
+		XMLName       xml.Name   `xml:"tvshow"`
+		Title         string     `xml:"title"`
+		OriginalTitle string     `xml:"originaltitle,omitempty"`
+		ShowTitle     string     `xml:"showtitle,omitempty"`
+		Plot          string     `xml:"plot,omitempty"`
+		Year          int        `xml:"year,omitempty"`
+		Premiered     string     `xml:"premiered,omitempty"`
+		Status        string     `xml:"status,omitempty"`
+		Rating        string     `xml:"rating,omitempty"`
+		Genres        []string   `xml:"genre"`
+		UniqueIDs     []UniqueID `xml:"uniqueid"`
+		Thumbs        []Thumb    `xml:"thumb"`
+		Fanart        *Fanart    `xml:"fanart,omitempty"`
+	}
+
+	// Episode is the content of the NFO file of an episode.
+	Episode struct {
+		XMLName   xml.Name   `xml:"episodedetails"`
+		Title     string     `xml:"title"`
+		ShowTitle string     `xml:"showtitle,omitempty"`
+		Season    int        `xml:"season"`
+		Episode   int        `xml:"episode"`
+		Plot      string     `xml:"plot,omitempty"`
+		Aired     string     `xml:"aired,omitempty"`
+		Runtime   int        `xml:"runtime,omitempty"`
+		UniqueIDs []UniqueID `xml:"uniqueid"`
+		Thumbs    []Thumb    `xml:"thumb"`
+	}
+
+	// Movie is the content of the NFO file of a movie.
+	Movie struct {
+		XMLName       xml.Name   `xml:"movie"`
+		Title         string     `xml:"title"`
+		OriginalTitle string     `xml:"originaltitle,omitempty"`
+		Plot          string     `xml:"plot,omitempty"`
+		Year          int        `xml:"year,omitempty"`
+		Premiered     string     `xml:"premiered,omitempty"`
+		Runtime       int        `xml:"runtime,omitempty"`
+		Rating        string     `xml:"rating,omitempty"`
+		Genres        []string   `xml:"genre"`
+		UniqueIDs     []UniqueID `xml:"uniqueid"`
+		Thumbs        []Thumb    `xml:"thumb"`
+		Fanart        *Fanart    `xml:"fanart,omitempty"`
+	}
+)
+
+// NewTVShow creates the tvshow.nfo content of the media.
+func NewTVShow(media *anilist.BaseAnime, animeMetadata *metadata.AnimeMetadata) *TVShow {
+	ret := &TVShow{
+		Title:         getTitle(media),
+		OriginalTitle: media.GetRomajiTitleSafe(),
+		ShowTitle:     getTitle(media),
+		Plot:          cleanDescription(media.GetDescription()),
+		Year:          media.GetStartYearSafe(),
+		Premiered:     formatDate(media.GetStartDate()),
+		Rating:        getRating(media),
+		Genres:        getGenres(media),
+		UniqueIDs:     getUniqueIDs(media, animeMetadata),
+	}
+
+	if media.IsFinished() {
+		ret.Status = "Ended"
+	} else {
+		ret.Status = "Continuing"
+	}
+	if cover := media.GetCoverImageSafe(); cover != "" {
+		ret.Thumbs = append(ret.Thumbs, Thumb{Aspect: "poster", Value: cover})
+	}
+	if banner := getFanart(media); banner != "" {
+		ret.Fanart = &Fanart{Thumbs: []Thumb{{Value: banner}}}
+	}
+
+	return ret
+}
+
+// NewEpisode creates the NFO content of an episode.
+// episodeMetadata can be nil.
+func NewEpisode(media *anilist.BaseAnime, season int, episode int, episodeMetadata *metadata.EpisodeMetadata) *Episode {
+	ret := &Episode{
+		Title:     fmt.Sprintf("Episode %d", episode),
+		ShowTitle: getTitle(media),
+		Season:    season,
+		Episode:   episode,
+	}
+
+	if episodeMetadata != nil {
+		if episodeMetadata.Title != "" {
+			ret.Title = episodeMetadata.Title
+		}
+		ret.Plot = episodeMetadata.Summary
+		if ret.Plot == "" {
+			ret.Plot = episodeMetadata.Overview
+		}
+		ret.Aired = episodeMetadata.AirDate
+		ret.Runtime = episodeMetadata.Length
+		if episodeMetadata.AnidbEid > 0 {
+			ret.UniqueIDs = append(ret.UniqueIDs, UniqueID{Type: "anidb", Value: strconv.Itoa(episodeMetadata.AnidbEid)})
+		}
+		if episodeMetadata.TvdbId > 0 {
+			ret.UniqueIDs = append(ret.UniqueIDs, UniqueID{Type: "tvdb", Value: strconv.Itoa(episodeMetadata.TvdbId)})
+		}
+		if episodeMetadata.Image != "" {
+			ret.Thumbs = append(ret.Thumbs, Thumb{Value: episodeMetadata.Image})
+		}
+	}
+	if ret.Runtime == 0 && media.GetDuration() != nil {
+		ret.Runtime = *media.GetDuration()
+	}
+
+	return ret
+}
+
+// NewMovie creates the NFO content of a movie.
+func NewMovie(media *anilist.BaseAnime, animeMetadata *metadata.AnimeMetadata) *Movie {
+	ret := &Movie{
+		Title:         getTitle(media),
+		OriginalTitle: media.GetRomajiTitleSafe(),
+		Plot:          cleanDescription(media.GetDescription()),
+		Year:          media.GetStartYearSafe(),
+		Premiered:     formatDate(media.GetStartDate()),
+		Rating:        getRating(media),
+		Genres:        getGenres(media),
+		UniqueIDs:     getUniqueIDs(media, animeMetadata),
+	}
+
+	if media.GetDuration() != nil {
+		ret.Runtime = *media.GetDuration()
+	}
+	if cover := media.GetCoverImageSafe(); cover != "" {
+		ret.Thumbs = append(ret.Thumbs, Thumb{Aspect: "poster", Value: cover})
+	}
+	if banner := getFanart(media); banner != "" {
+		ret.Fanart = &Fanart{Thumbs: []Thumb{{Value: banner}}}
+	}
+
+	return ret
+}
+
+// Marshal returns the content of the NFO file.
+func Marshal(v interface{}) ([]byte, error) {
+	buf := bytes.NewBufferString(xml.Header)
+	buf.WriteString(generatedMarker + "\n")
+
+	enc := xml.NewEncoder(buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+
+	return buf.Bytes(), nil
+}
+
+// IsGenerated returns true if the NFO file content was created by the exporter.
+func IsGenerated(content []byte) bool {
+	return bytes.Contains(content, []byte(generatedMarker))
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+
+var (
+	htmlTagRegex   = regexp.MustCompile(`<[^>]*>`)
+	lineBreakRegex = regexp.MustCompile(`(?i)<br\s*/?>`)
+)
+
+// cleanDescription removes the HTML tags from AniList descriptions.
+func cleanDescription(description *string) string {
+	if description == nil {
+		return ""
+	}
+	s := lineBreakRegex.ReplaceAllString(*description, "\n")
+	s = htmlTagRegex.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	for strings.Contains(s, "\n\n\n") {
+		s = strings.ReplaceAll(s, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(s)
+}
+
+func getTitle(media *anilist.BaseAnime) string {
+	if title := media.GetTitleSafe(); title != "" {
+		return title
+	}
+	return media.GetRomajiTitleSafe()
+}
+
+func getRating(media *anilist.BaseAnime) string {
+	if media.GetMeanScore() == nil || *media.GetMeanScore() == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(*media.GetMeanScore())/10, 'f', 1, 64)
+}
+
+func getGenres(media *anilist.BaseAnime) []string {
+	ret := make([]string, 0, len(media.GetGenres()))
+	for _, genre := range media.GetGenres() {
+		if genre != nil {
+			ret = append(ret, *genre)
+		}
+	}
+	return ret
+}
+
+func getUniqueIDs(media *anilist.BaseAnime, animeMetadata *metadata.AnimeMetadata) []UniqueID {
+	ret := []UniqueID{{Type: "anilist", Default: true, Value: strconv.Itoa(media.GetID())}}
+	if media.GetIDMal() != nil && *media.GetIDMal() > 0 {
+		ret = append(ret, UniqueID{Type: "mal", Value: strconv.Itoa(*media.GetIDMal())})
+	}
+
+	if animeMetadata == nil || animeMetadata.Mappings == nil {
+		return ret
+	}
+	mappings := animeMetadata.Mappings
+	if mappings.AnidbId > 0 {
+		ret = append(ret, UniqueID{Type: "anidb", Value: strconv.Itoa(mappings.AnidbId)})
+	}
+	if mappings.ThetvdbId > 0 {
+		ret = append(ret, UniqueID{Type: "tvdb", Value: strconv.Itoa(mappings.ThetvdbId)})
+	}
+	if mappings.ThemoviedbId != "" {
+		ret = append(ret, UniqueID{Type: "tmdb", Value: mappings.ThemoviedbId})
+	}
+	if mappings.ImdbId != "" {
+		ret = append(ret, UniqueID{Type: "imdb", Value: mappings.ImdbId})
+	}
+	return ret
+}
+
+func formatDate(date *anilist.BaseAnime_StartDate) string {
+	if date == nil || date.GetYear() == nil || date.GetMonth() == nil || date.GetDay() == nil {
+		return ""
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", *date.GetYear(), *date.GetMonth(), *date.GetDay())
+}
+
+// getFanart returns the banner image.
+// Unlike BaseAnime.GetBannerImageSafe, it does not fall back to the cover image, which does not fit as a background.
+func getFanart(media *anilist.BaseAnime) string {
+	if media.GetBannerImage() == nil {
+		return ""
+	}
+	return *media.GetBannerImage()
+}
@@ -0,0 +1,78 @@
+package nfo
+
+import (
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/metadata"
+	"strings"
+	"testing"
+)
+
+func TestMarshalTVShow(t *testing.T) {
+	media := &anilist.BaseAnime{
+		ID:          154587,
+		IDMal:       lo.ToPtr(52991),
+		Title:       &anilist.BaseAnime_Title{Romaji: lo.ToPtr("Sousou no Frieren"), English: lo.ToPtr("Frieren: Beyond Journey's End")},
+		Description: lo.ToPtr("The adventure is over<br><br>but life goes on for an elf mage. <i>(Source: Crunchyroll)</i> &amp; more"),
+		Genres:      []*string{lo.ToPtr("Adventure"), lo.ToPtr("Drama")},
+		MeanScore:   lo.ToPtr(91),
+		StartDate:   &anilist.BaseAnime_StartDate{Year: lo.ToPtr(2023), Month: lo.ToPtr(9), Day: lo.ToPtr(29)},
+		Status:      lo.ToPtr(anilist.MediaStatusFinished),
+	}
+	animeMetadata := &metadata.AnimeMetadata{
+		Mappings: &metadata.AnimeMappings{AnidbId: 17617, ThetvdbId: 424536},
+	}
+
+	content, err := Marshal(NewTVShow(media, animeMetadata))
+	require.NoError(t, err)
+
+	s := string(content)
+	assert.True(t, IsGenerated(content))
+	assert.True(t, strings.HasPrefix(s, "<?xml"))
+	assert.Contains(t, s, "<title>Frieren: Beyond Journey&#39;s End</title>")
+	assert.Contains(t, s, "<originaltitle>Sousou no Frieren</originaltitle>")
+	assert.Contains(t, s, "<plot>The adventure is over&#xA;&#xA;but life goes on for an elf mage. (Source: Crunchyroll) &amp; more</plot>")
+	assert.Contains(t, s, "<year>2023</year>")
+	assert.Contains(t, s, "<premiered>2023-09-29</premiered>")
+	assert.Contains(t, s, "<status>Ended</status>")
+	assert.Contains(t, s, "<rating>9.1</rating>")
+	assert.Contains(t, s, "<genre>Drama</genre>")
+	assert.Contains(t, s, `<uniqueid type="anilist" default="true">154587</uniqueid>`)
+	assert.Contains(t, s, `<uniqueid type="mal">52991</uniqueid>`)
+	assert.Contains(t, s, `<uniqueid type="tvdb">424536</uniqueid>`)
+}
+
+func TestMarshalEpisode(t *testing.T) {
+	media := &anilist.BaseAnime{
+		ID:       154587,
+		Title:    &anilist.BaseAnime_Title{Romaji: lo.ToPtr("Sousou no Frieren")},
+		Duration: lo.ToPtr(24),
+	}
+
+	// Without metadata
+	content, err := Marshal(NewEpisode(media, 1, 3, nil))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "<title>Episode 3</title>")
+	assert.Contains(t, string(content), "<season>1</season>")
+	assert.Contains(t, string(content), "<episode>3</episode>")
+	assert.Contains(t, string(content), "<runtime>24</runtime>")
+
+	content, err = Marshal(NewEpisode(media, 1, 3, &metadata.EpisodeMetadata{
+		Title:    "Killing Magic",
+		AirDate:  "2023-09-29",
+		Summary:  "Frieren and Fern head north.",
+		Image:    "https://example.com/3.jpg",
+		AnidbEid: 271234,
+	}))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "<title>Killing Magic</title>")
+	assert.Contains(t, string(content), "<aired>2023-09-29</aired>")
+	assert.Contains(t, string(content), "<thumb>https://example.com/3.jpg</thumb>")
+	assert.Contains(t, string(content), `<uniqueid type="anidb">271234</uniqueid>`)
+}
+
+func TestIsGenerated(t *testing.T) {
+	assert.False(t, IsGenerated([]byte("<?xml version=\"1.0\"?>\n<tvshow><title>Custom</title></tvshow>")))
+}
@@ -0,0 +1,155 @@
+package organizer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const opUnlink = "unlink"
+
+// fileOp is a single filesystem operation that can be reverted.
+type fileOp struct {
+	kind     string // ModeMove, ModeHardlink, ModeSymlink or opUnlink
+	src      string
+	dst      string
+	linkKind string // Kind of link removed by opUnlink, used to recreate it
+}
+
+func normalizePath(path string) string {
+	return filepath.ToSlash(strings.ToLower(path))
+}
+
+func (op *fileOp) apply() error {
+	switch op.kind {
+	case ModeMove:
+		return moveFile(op.src, op.dst)
+	case ModeHardlink:
+		return os.Link(op.src, op.dst)
+	case ModeSymlink:
+		return os.Symlink(op.src, op.dst)
+	case opUnlink:
+		// Only remove links that still point to the source
+		if op.linkKind == ModeSymlink {
+			target, err := os.Readlink(op.dst)
+			if err != nil {
+				return err
+			}
+			if filepath.Clean(target) != filepath.Clean(op.src) {
+				return fmt.Errorf("organizer: %s no longer points to %s", op.dst, op.src)
+			}
+		}
+		return os.Remove(op.dst)
+	}
+	return ErrInvalidMode
+}
+
+func (op *fileOp) revert() error {
+	switch op.kind {
+	case ModeMove:
+		return moveFile(op.dst, op.src)
+	case ModeHardlink, ModeSymlink:
+		return os.Remove(op.dst)
+	case opUnlink:
+		return (&fileOp{kind: op.linkKind, src: op.src, dst: op.dst}).apply()
+	}
+	return ErrInvalidMode
+}
+
+// applyFileOps applies the operations in order.
+// If an operation fails, the operations already applied are reverted and the error is returned.
+// Returns the directories that were created, parents first.
+func applyFileOps(ops []*fileOp) (createdDirs []string, err error) {
+	createdDirs = make([]string, 0)
+	for i, op := range ops {
+		if op.kind != opUnlink {
+			if _, statErr := os.Lstat(op.dst); statErr == nil {
+				revertFileOps(ops[:i], createdDirs)
+				return nil, fmt.Errorf("organizer: %s already exists", op.dst)
+			}
+
+			dirs, err := mkdirAll(filepath.Dir(op.dst))
+			createdDirs = append(createdDirs, dirs...)
+			if err != nil {
+				revertFileOps(ops[:i], createdDirs)
+				return nil, err
+			}
+		}
+
+		if err := op.apply(); err != nil {
+			revertFileOps(ops[:i], createdDirs)
+			return nil, fmt.Errorf("organizer: Failed to %s %s: %w", op.kind, op.src, err)
+		}
+	}
+	return createdDirs, nil
+}
+
+// revertFileOps reverts the operations in reverse order and removes the created directories if they are empty.
+func revertFileOps(ops []*fileOp, createdDirs []string) {
+	for i := len(ops) - 1; i >= 0; i-- {
+		_ = ops[i].revert()
+	}
+	for i := len(createdDirs) - 1; i >= 0; i-- {
+		_ = os.Remove(createdDirs[i])
+	}
+}
+
+// mkdirAll creates the directory and its parents and returns the directories that were created, parents first.
+func mkdirAll(dir string) ([]string, error) {
+	missing := make([]string, 0)
+	for d := dir; d != filepath.Dir(d); d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append([]string{d}, missing...)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return missing, nil
+}
+
+// moveFile renames the file, falling back to copying it when the destination is on another device.
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+
+	if copyErr := copyFile(src, dst); copyErr != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	return nil
+}
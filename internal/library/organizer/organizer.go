@@ -0,0 +1,478 @@
+package organizer
+
+import (
+	"errors"
+	"github.com/goccy/go-json"
+	"github.com/rs/zerolog"
+	"os"
+	"path/filepath"
+	"seanime/internal/api/anilist"
+	"seanime/internal/database/db"
+	"seanime/internal/database/db_bridge"
+	"seanime/internal/database/models"
+	"seanime/internal/library/anime"
+	"seanime/internal/platforms/platform"
+	"seanime/internal/util"
+	"seanime/seanime-parser"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	ModeMove     = "move"
+	ModeHardlink = "hardlink"
+	ModeSymlink  = "symlink"
+
+	OperationStatusReady    = "ready"    // The file will be organized
+	OperationStatusSkipped  = "skipped"  // The file is already organized or cannot be organized
+	OperationStatusConflict = "conflict" // The destination already exists or is shared with another file
+	OperationStatusDone     = "done"     // The file has been organized
+)
+
+var (
+	ErrNoDestination  = errors.New("organizer: No destination directory, set one or set a library path")
+	ErrInvalidMode    = errors.New("organizer: Invalid mode")
+	ErrAlreadyRunning = errors.New("organizer: Already running")
+	ErrAlreadyUndone  = errors.New("organizer: This run has already been undone")
+	// Links inside a library directory would be scanned as new local files, next to the original files
+	ErrLinksInLibrary = errors.New("organizer: Links cannot be created inside a library directory, set a destination directory outside the library")
+	// Files moved out of the library would be dropped from the local files on the next scan
+	ErrMoveOutsideLibrary = errors.New("organizer: Files can only be moved inside a library directory, set a destination directory inside the library")
+)
+
+type (
+	// Organizer renames, moves or links the matched local files according to a template.
+	// Runs are recorded in an undo log and the stored local files of moved files are updated so that no rescan is needed.
+	// Linked files stay in the library, the local files keep pointing to them and the links are created outside the library.
+	Organizer struct {
+		db       *db.Database
+		logger   *zerolog.Logger
+		platform platform.Platform
+		mu       sync.Mutex
+	}
+
+	NewOrganizerOptions struct {
+		Database *db.Database
+		Logger   *zerolog.Logger
+		Platform platform.Platform // Used to fetch the media that are not in the collection
+	}
+
+	PlanOptions struct {
+		Settings        *models.OrganizerSettings
+		LibraryPath     string                   // Used when the settings have no destination directory
+		LibraryPaths    []string                 // All the library directories, empty directories are only removed inside them
+		AnimeCollection *anilist.AnimeCollection // Optional, media not found in the collection are fetched
+	}
+
+	// Operation is the planned organization of a single file.
+	Operation struct {
+		Source      string `json:"source"`
+		Destination string `json:"destination"`
+		MediaId     int    `json:"mediaId"`
+		Episode     int    `json:"episode"`
+		Status      string `json:"status"`
+		Reason      string `json:"reason,omitempty"`
+	}
+
+	// Plan is the dry-run of an organizer run.
+	Plan struct {
+		Mode           string       `json:"mode"`
+		Template       string       `json:"template"`
+		DestinationDir string       `json:"destinationDir"`
+		Operations     []*Operation `json:"operations"`
+	}
+
+	// runLog is the content of models.OrganizerLog.Operations.
+	runLog struct {
+		Operations  []*Operation `json:"operations"`
+		CreatedDirs []string     `json:"createdDirs"`
+	}
+)
+
+func NewOrganizer(opts *NewOrganizerOptions) *Organizer {
+	return &Organizer{
+		db:       opts.Database,
+		logger:   opts.Logger,
+		platform: opts.Platform,
+	}
+}
+
+// Plan returns the operations that would be performed, nothing is modified.
+func (o *Organizer) Plan(opts *PlanOptions) (*Plan, error) {
+	if opts.Settings == nil {
+		return nil, errors.New("organizer: No settings")
+	}
+
+	mode := opts.Settings.Mode
+	if mode == "" {
+		mode = ModeMove
+	}
+	if mode != ModeMove && mode != ModeHardlink && mode != ModeSymlink {
+		return nil, ErrInvalidMode
+	}
+
+	rawTemplate := opts.Settings.Template
+	if rawTemplate == "" {
+		rawTemplate = DefaultTemplate
+	}
+	tmpl, err := ParseTemplate(rawTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	destDir := opts.Settings.DestinationDir
+	if destDir == "" {
+		destDir = opts.LibraryPath
+	}
+	if destDir == "" {
+		return nil, ErrNoDestination
+	}
+	destDir = filepath.Clean(destDir)
+
+	inLibrary := false
+	for _, libraryPath := range append([]string{opts.LibraryPath}, opts.LibraryPaths...) {
+		if libraryPath != "" && (util.IsSameDir(libraryPath, destDir) || util.IsSubdirectory(libraryPath, destDir)) {
+			inLibrary = true
+			break
+		}
+	}
+	if mode == ModeMove && !inLibrary {
+		return nil, ErrMoveOutsideLibrary
+	}
+	if mode != ModeMove && inLibrary {
+		return nil, ErrLinksInLibrary
+	}
+
+	lfs, _, err := db_bridge.GetLocalFiles(o.db)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Mode:           mode,
+		Template:       rawTemplate,
+		DestinationDir: destDir,
+		Operations:     make([]*Operation, 0),
+	}
+
+	mediaCache := make(map[int]*anilist.BaseAnime)
+	destinations := make(map[string]*Operation)
+
+	for _, lf := range lfs {
+		if lf.MediaId == 0 || lf.IsIgnored() || lf.GetMetadata() == nil {
+			continue
+		}
+
+		op := &Operation{
+			Source:  lf.GetPath(),
+			MediaId: lf.MediaId,
+			Episode: lf.GetEpisodeNumber(),
+			Status:  OperationStatusReady,
+		}
+		plan.Operations = append(plan.Operations, op)
+
+		if lf.GetType() == anime.LocalFileTypeNC {
+			op.Status = OperationStatusSkipped
+			op.Reason = "openings and endings are not organized"
+			continue
+		}
+
+		media, ok := o.getMedia(lf.MediaId, opts.AnimeCollection, mediaCache)
+		if !ok {
+			op.Status = OperationStatusSkipped
+			op.Reason = "media not found"
+			continue
+		}
+
+		rel, err := tmpl.Render(NewTemplateData(lf, media))
+		if err != nil {
+			op.Status = OperationStatusSkipped
+			op.Reason = err.Error()
+			continue
+		}
+		op.Destination = filepath.Join(destDir, filepath.FromSlash(rel))
+
+		if normalizePath(op.Destination) == normalizePath(op.Source) {
+			op.Status = OperationStatusSkipped
+			op.Reason = "already organized"
+			continue
+		}
+
+		key := normalizePath(op.Destination)
+		if other, found := destinations[key]; found {
+			op.Status = OperationStatusConflict
+			op.Reason = "same destination as " + filepath.Base(other.Source)
+			if other.Status == OperationStatusReady {
+				other.Status = OperationStatusConflict
+				other.Reason = "same destination as " + filepath.Base(op.Source)
+			}
+			continue
+		}
+		destinations[key] = op
+
+		if _, err := os.Lstat(op.Destination); err == nil {
+			op.Status = OperationStatusConflict
+			op.Reason = "destination already exists"
+			continue
+		}
+	}
+
+	return plan, nil
+}
+
+// Execute plans and performs the run.
+// If an operation fails, the operations already performed are reverted.
+// When files are moved, the local files are updated to point to the new paths.
+func (o *Organizer) Execute(opts *PlanOptions) (*Plan, *models.OrganizerLog, error) {
+	if !o.mu.TryLock() {
+		return nil, nil, ErrAlreadyRunning
+	}
+	defer o.mu.Unlock()
+
+	plan, err := o.Plan(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ready := make([]*Operation, 0)
+	fileOps := make([]*fileOp, 0)
+	for _, op := range plan.Operations {
+		if op.Status != OperationStatusReady {
+			continue
+		}
+		ready = append(ready, op)
+		fileOps = append(fileOps, &fileOp{kind: plan.Mode, src: op.Source, dst: op.Destination})
+	}
+
+	if len(ready) == 0 {
+		return plan, nil, errors.New("organizer: Nothing to organize")
+	}
+
+	o.logger.Info().Int("count", len(ready)).Str("mode", plan.Mode).Msg("organizer: Organizing files")
+
+	createdDirs, err := applyFileOps(fileOps)
+	if err != nil {
+		o.logger.Error().Err(err).Msg("organizer: Failed to organize files, changes reverted")
+		return nil, nil, err
+	}
+
+	// Update the local files of the moved files.
+	// Linked files are still in the library, their local files are left untouched.
+	if plan.Mode == ModeMove {
+		paths := make(map[string]string, len(ready))
+		for _, op := range ready {
+			paths[normalizePath(op.Source)] = op.Destination
+		}
+		if err := o.updateLocalFilePaths(paths); err != nil {
+			revertFileOps(fileOps, createdDirs)
+			o.logger.Error().Err(err).Msg("organizer: Failed to update local files, changes reverted")
+			return nil, nil, err
+		}
+	}
+
+	for _, op := range ready {
+		op.Status = OperationStatusDone
+	}
+
+	if plan.Mode == ModeMove && opts.Settings.DeleteEmptyDirs {
+		for _, op := range ready {
+			removeEmptyParents(filepath.Dir(op.Source), append([]string{opts.LibraryPath}, opts.LibraryPaths...))
+		}
+	}
+
+	data, _ := json.Marshal(&runLog{Operations: ready, CreatedDirs: createdDirs})
+	log := &models.OrganizerLog{
+		Mode:       plan.Mode,
+		Operations: data,
+	}
+	if err := o.db.InsertOrganizerLog(log); err != nil {
+		// The files have been organized, the run just cannot be undone
+		o.logger.Error().Err(err).Msg("organizer: Failed to save undo log")
+	}
+
+	o.logger.Info().Int("count", len(ready)).Msg("organizer: Files organized")
+
+	return plan, log, nil
+}
+
+// Undo reverts a run.
+// Moved files are moved back and links are removed.
+func (o *Organizer) Undo(logId uint) error {
+	if !o.mu.TryLock() {
+		return ErrAlreadyRunning
+	}
+	defer o.mu.Unlock()
+
+	log, err := o.db.GetOrganizerLog(logId)
+	if err != nil {
+		return err
+	}
+	if log.UndoneAt != nil {
+		return ErrAlreadyUndone
+	}
+
+	var rl runLog
+	if err := json.Unmarshal(log.Operations, &rl); err != nil {
+		return err
+	}
+
+	fileOps := make([]*fileOp, 0, len(rl.Operations))
+	for i := len(rl.Operations) - 1; i >= 0; i-- {
+		op := rl.Operations[i]
+		switch log.Mode {
+		case ModeMove:
+			fileOps = append(fileOps, &fileOp{kind: ModeMove, src: op.Destination, dst: op.Source})
+		default:
+			fileOps = append(fileOps, &fileOp{kind: opUnlink, src: op.Source, dst: op.Destination, linkKind: log.Mode})
+		}
+	}
+
+	createdDirs, err := applyFileOps(fileOps)
+	if err != nil {
+		o.logger.Error().Err(err).Msg("organizer: Failed to undo, changes reverted")
+		return err
+	}
+
+	paths := make(map[string]string, len(rl.Operations))
+	for _, op := range rl.Operations {
+		paths[normalizePath(op.Destination)] = op.Source
+	}
+	if err := o.updateLocalFilePaths(paths); err != nil {
+		revertFileOps(fileOps, createdDirs)
+		return err
+	}
+
+	// Remove the directories created by the run if they are empty
+	for i := len(rl.CreatedDirs) - 1; i >= 0; i-- {
+		_ = os.Remove(rl.CreatedDirs[i])
+	}
+
+	if err := o.db.SetOrganizerLogUndone(log.ID); err != nil {
+		return err
+	}
+
+	o.logger.Info().Uint("id", log.ID).Msg("organizer: Run undone")
+
+	return nil
+}
+
+// updateLocalFilePaths replaces the paths of the stored local files.
+// The keys of paths are normalized paths.
+func (o *Organizer) updateLocalFilePaths(paths map[string]string) error {
+	lfs, lfsId, err := db_bridge.GetLocalFiles(o.db)
+	if err != nil {
+		return err
+	}
+
+	// Copy the local files so that the cached ones are left untouched if saving fails
+	updated := make([]*anime.LocalFile, len(lfs))
+	for i, lf := range lfs {
+		updated[i] = lf
+		if newPath, ok := paths[lf.GetNormalizedPath()]; ok {
+			cp := *lf
+			cp.Path = newPath
+			cp.Name = filepath.Base(newPath)
+			updated[i] = &cp
+		}
+	}
+
+	_, err = db_bridge.SaveLocalFiles(o.db, lfsId, updated)
+	return err
+}
+
+func (o *Organizer) getMedia(mediaId int, collection *anilist.AnimeCollection, cache map[int]*anilist.BaseAnime) (*anilist.BaseAnime, bool) {
+	if media, ok := cache[mediaId]; ok {
+		return media, media != nil
+	}
+
+	var media *anilist.BaseAnime
+	if collection != nil {
+		media, _ = collection.FindAnime(mediaId)
+	}
+	if media == nil && o.platform != nil {
+		var err error
+		media, err = o.platform.GetAnime(mediaId)
+		if err != nil {
+			o.logger.Warn().Err(err).Int("mediaId", mediaId).Msg("organizer: Failed to fetch media")
+		}
+	}
+
+	cache[mediaId] = media
+	return media, media != nil
+}
+
+// NewTemplateData returns the values of the template variables for the local file.
+func NewTemplateData(lf *anime.LocalFile, media *anilist.BaseAnime) TemplateData {
+	data := TemplateData{
+		"title":   media.GetPreferredTitle(),
+		"romaji":  media.GetRomajiTitleSafe(),
+		"english": media.GetTitleSafe(),
+		"episode": strconv.Itoa(lf.GetEpisodeNumber()),
+		"ext":     strings.TrimPrefix(filepath.Ext(lf.GetPath()), "."),
+		"mediaId": strconv.Itoa(media.ID),
+	}
+
+	if year := media.GetStartYearSafe(); year > 0 {
+		data["year"] = strconv.Itoa(year)
+	}
+	if media.Format != nil {
+		data["format"] = string(*media.Format)
+	}
+
+	// Season
+	season := 1
+	if lf.GetType() == anime.LocalFileTypeSpecial {
+		season = 0
+	} else if s, ok := parsedSeason(lf); ok {
+		season = s
+	} else if s := media.GetPossibleSeasonNumber(); s > 0 {
+		season = s
+	}
+	data["season"] = strconv.Itoa(season)
+
+	if parsed := lf.GetParsedData(); parsed != nil {
+		data["group"] = parsed.ReleaseGroup
+		data["episodeTitle"] = parsed.EpisodeTitle
+	}
+
+	// The resolution is not stored in the local file
+	if elements := seanime_parser.Parse(lf.Name); elements != nil {
+		data["resolution"] = elements.VideoResolution
+	}
+
+	return data
+}
+
+func parsedSeason(lf *anime.LocalFile) (int, bool) {
+	if lf.GetParsedData() == nil || lf.GetParsedData().Season == "" {
+		return 0, false
+	}
+	s, err := strconv.Atoi(lf.GetParsedData().Season)
+	return s, err == nil
+}
+
+// removeEmptyParents removes dir and its parents while they are empty.
+// Only directories strictly inside a library path are removed, nothing is removed if the directory is outside the library.
+func removeEmptyParents(dir string, libraryPaths []string) {
+	for dir != "" && dir != filepath.Dir(dir) {
+		isInLibrary := false
+		for _, libraryPath := range libraryPaths {
+			if libraryPath != "" && util.IsSubdirectory(libraryPath, dir) {
+				isInLibrary = true
+				break
+			}
+		}
+		if !isInLibrary {
+			return
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,273 @@
+package organizer
+
+import (
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"seanime/internal/api/anilist"
+	"seanime/internal/database/db"
+	"seanime/internal/database/db_bridge"
+	"seanime/internal/database/models"
+	"seanime/internal/library/anime"
+	"seanime/internal/util"
+	"testing"
+)
+
+func TestOrganizer_ExecuteAndUndo(t *testing.T) {
+	t.Setenv("TEST_ENV", "true")
+
+	logger := util.NewLogger()
+	database, err := db.NewDatabase(t.TempDir(), "test", logger)
+	require.NoError(t, err)
+
+	libraryDir := t.TempDir()
+	downloadDir := filepath.Join(libraryDir, "Downloads")
+	require.NoError(t, os.MkdirAll(downloadDir, 0755))
+
+	filenames := []string{
+		"[SubsPlease] Sousou no Frieren - 01 (1080p) [F02B9CEE].mkv",
+		"[SubsPlease] Sousou no Frieren - 02 (1080p) [E5A85899].mkv",
+	}
+	lfs := make([]*anime.LocalFile, 0)
+	for i, name := range filenames {
+		path := filepath.Join(downloadDir, name)
+		require.NoError(t, os.WriteFile(path, []byte("video"), 0644))
+
+		lf := anime.NewLocalFile(path, libraryDir)
+		lf.MediaId = 154587
+		lf.Metadata = &anime.LocalFileMetadata{Episode: i + 1, AniDBEpisode: "1", Type: anime.LocalFileTypeMain}
+		lfs = append(lfs, lf)
+	}
+	_, err = db_bridge.InsertLocalFiles(database, lfs)
+	require.NoError(t, err)
+
+	collection := &anilist.AnimeCollection{
+		MediaListCollection: &anilist.AnimeCollection_MediaListCollection{
+			Lists: []*anilist.AnimeCollection_MediaListCollection_Lists{{
+				Entries: []*anilist.AnimeCollection_MediaListCollection_Lists_Entries{{
+					Media: &anilist.BaseAnime{
+						ID:    154587,
+						Title: &anilist.BaseAnime_Title{Romaji: lo.ToPtr("Sousou no Frieren")},
+					},
+				}},
+			}},
+		},
+	}
+
+	o := NewOrganizer(&NewOrganizerOptions{
+		Database: database,
+		Logger:   logger,
+	})
+
+	opts := &PlanOptions{
+		Settings: &models.OrganizerSettings{
+			Template:        DefaultTemplate,
+			Mode:            ModeMove,
+			DeleteEmptyDirs: true,
+		},
+		LibraryPath:     libraryDir,
+		AnimeCollection: collection,
+	}
+
+	expected := []string{
+		filepath.Join(libraryDir, "Sousou no Frieren", "Season 1", "Sousou no Frieren - S01E01 [SubsPlease][1080p].mkv"),
+		filepath.Join(libraryDir, "Sousou no Frieren", "Season 1", "Sousou no Frieren - S01E02 [SubsPlease][1080p].mkv"),
+	}
+
+	// Dry-run
+	plan, err := o.Plan(opts)
+	require.NoError(t, err)
+	require.Len(t, plan.Operations, 2)
+	for i, op := range plan.Operations {
+		assert.Equal(t, OperationStatusReady, op.Status)
+		assert.Equal(t, expected[i], op.Destination)
+		assert.FileExists(t, op.Source)
+	}
+
+	// Execute
+	_, log, err := o.Execute(opts)
+	require.NoError(t, err)
+	require.NotNil(t, log)
+
+	for _, path := range expected {
+		assert.FileExists(t, path)
+	}
+	assert.NoDirExists(t, downloadDir)
+
+	storedLfs, _, err := db_bridge.GetLocalFiles(database)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, expected, lo.Map(storedLfs, func(lf *anime.LocalFile, _ int) string { return lf.Path }))
+
+	// Running the plan again should not do anything
+	plan, err = o.Plan(opts)
+	require.NoError(t, err)
+	for _, op := range plan.Operations {
+		assert.Equal(t, OperationStatusSkipped, op.Status)
+	}
+
+	// Undo
+	require.NoError(t, o.Undo(log.ID))
+
+	for i, name := range filenames {
+		assert.FileExists(t, filepath.Join(downloadDir, name))
+		assert.NoFileExists(t, expected[i])
+	}
+	assert.NoDirExists(t, filepath.Join(libraryDir, "Sousou no Frieren"))
+
+	storedLfs, _, err = db_bridge.GetLocalFiles(database)
+	require.NoError(t, err)
+	for _, lf := range storedLfs {
+		assert.Equal(t, downloadDir, filepath.Dir(lf.Path))
+	}
+
+	assert.ErrorIs(t, o.Undo(log.ID), ErrAlreadyUndone)
+}
+
+func TestOrganizer_Conflicts(t *testing.T) {
+	t.Setenv("TEST_ENV", "true")
+
+	logger := util.NewLogger()
+	database, err := db.NewDatabase(t.TempDir(), "test", logger)
+	require.NoError(t, err)
+
+	libraryDir := t.TempDir()
+
+	// Two releases of the same episode
+	lfs := make([]*anime.LocalFile, 0)
+	for _, name := range []string{"[SubsPlease] Frieren - 01 (1080p).mkv", "[SubsPlease] Frieren - 01v2 (1080p).mkv"} {
+		path := filepath.Join(libraryDir, name)
+		require.NoError(t, os.WriteFile(path, []byte("video"), 0644))
+		lf := anime.NewLocalFile(path, libraryDir)
+		lf.MediaId = 1
+		lf.Metadata = &anime.LocalFileMetadata{Episode: 1, Type: anime.LocalFileTypeMain}
+		lfs = append(lfs, lf)
+	}
+	_, err = db_bridge.InsertLocalFiles(database, lfs)
+	require.NoError(t, err)
+
+	collection := &anilist.AnimeCollection{
+		MediaListCollection: &anilist.AnimeCollection_MediaListCollection{
+			Lists: []*anilist.AnimeCollection_MediaListCollection_Lists{{
+				Entries: []*anilist.AnimeCollection_MediaListCollection_Lists_Entries{{
+					Media: &anilist.BaseAnime{ID: 1, Title: &anilist.BaseAnime_Title{Romaji: lo.ToPtr("Frieren")}},
+				}},
+			}},
+		},
+	}
+
+	o := NewOrganizer(&NewOrganizerOptions{Database: database, Logger: logger})
+
+	plan, err := o.Plan(&PlanOptions{
+		Settings:        &models.OrganizerSettings{Template: "{romaji}/{romaji} - {episode:02}.{ext}", Mode: ModeMove},
+		LibraryPath:     libraryDir,
+		AnimeCollection: collection,
+	})
+	require.NoError(t, err)
+	require.Len(t, plan.Operations, 2)
+	for _, op := range plan.Operations {
+		assert.Equal(t, OperationStatusConflict, op.Status)
+	}
+}
+
+func TestOrganizer_MoveOutsideLibrary(t *testing.T) {
+	t.Setenv("TEST_ENV", "true")
+	logger := util.NewLogger()
+	database, err := db.NewDatabase(t.TempDir(), "test", logger)
+	require.NoError(t, err)
+
+	o := NewOrganizer(&NewOrganizerOptions{Database: database, Logger: logger})
+
+	libraryDir := t.TempDir()
+	_, err = o.Plan(&PlanOptions{
+		Settings:    &models.OrganizerSettings{Mode: ModeMove, DestinationDir: t.TempDir()},
+		LibraryPath: libraryDir,
+	})
+	assert.ErrorIs(t, err, ErrMoveOutsideLibrary)
+
+	_, err = o.Plan(&PlanOptions{
+		Settings:    &models.OrganizerSettings{Mode: ModeMove, DestinationDir: filepath.Join(libraryDir, "Organized")},
+		LibraryPath: libraryDir,
+	})
+	assert.NoError(t, err)
+}
+
+func TestOrganizer_Links(t *testing.T) {
+	t.Setenv("TEST_ENV", "true")
+
+	logger := util.NewLogger()
+	database, err := db.NewDatabase(t.TempDir(), "test", logger)
+	require.NoError(t, err)
+
+	libraryDir := t.TempDir()
+	path := filepath.Join(libraryDir, "[SubsPlease] Frieren - 01 (1080p).mkv")
+	require.NoError(t, os.WriteFile(path, []byte("video"), 0644))
+	lf := anime.NewLocalFile(path, libraryDir)
+	lf.MediaId = 1
+	lf.Metadata = &anime.LocalFileMetadata{Episode: 1, Type: anime.LocalFileTypeMain}
+	_, err = db_bridge.InsertLocalFiles(database, []*anime.LocalFile{lf})
+	require.NoError(t, err)
+
+	collection := &anilist.AnimeCollection{
+		MediaListCollection: &anilist.AnimeCollection_MediaListCollection{
+			Lists: []*anilist.AnimeCollection_MediaListCollection_Lists{{
+				Entries: []*anilist.AnimeCollection_MediaListCollection_Lists_Entries{{
+					Media: &anilist.BaseAnime{ID: 1, Title: &anilist.BaseAnime_Title{Romaji: lo.ToPtr("Frieren")}},
+				}},
+			}},
+		},
+	}
+
+	o := NewOrganizer(&NewOrganizerOptions{Database: database, Logger: logger})
+
+	// Links cannot be created inside the library
+	_, err = o.Plan(&PlanOptions{
+		Settings:        &models.OrganizerSettings{Template: "{romaji}/{romaji} - {episode:02}.{ext}", Mode: ModeHardlink},
+		LibraryPath:     libraryDir,
+		AnimeCollection: collection,
+	})
+	assert.ErrorIs(t, err, ErrLinksInLibrary)
+
+	// The local files keep pointing to the original files
+	destDir := t.TempDir()
+	_, log, err := o.Execute(&PlanOptions{
+		Settings:        &models.OrganizerSettings{Template: "{romaji}/{romaji} - {episode:02}.{ext}", Mode: ModeHardlink, DestinationDir: destDir},
+		LibraryPath:     libraryDir,
+		AnimeCollection: collection,
+	})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(destDir, "Frieren", "Frieren - 01.mkv"))
+	assert.FileExists(t, path)
+
+	storedLfs, _, err := db_bridge.GetLocalFiles(database)
+	require.NoError(t, err)
+	require.Len(t, storedLfs, 1)
+	assert.Equal(t, path, storedLfs[0].Path)
+
+	require.NoError(t, o.Undo(log.ID))
+	assert.NoFileExists(t, filepath.Join(destDir, "Frieren", "Frieren - 01.mkv"))
+	assert.FileExists(t, path)
+}
+
+func TestRemoveEmptyParents(t *testing.T) {
+	root := t.TempDir()
+	libraryDir := filepath.Join(root, "Library")
+	outsideDir := filepath.Join(root, "Downloads", "Frieren")
+	insideDir := filepath.Join(libraryDir, "Downloads", "Frieren")
+	require.NoError(t, os.MkdirAll(outsideDir, 0755))
+	require.NoError(t, os.MkdirAll(insideDir, 0755))
+
+	// Nothing is removed outside the library
+	removeEmptyParents(outsideDir, []string{libraryDir})
+	assert.DirExists(t, outsideDir)
+
+	// Nothing is removed without a library path
+	removeEmptyParents(outsideDir, []string{""})
+	assert.DirExists(t, outsideDir)
+
+	// Empty directories are removed up to the library path
+	removeEmptyParents(insideDir, []string{libraryDir})
+	assert.NoDirExists(t, filepath.Join(libraryDir, "Downloads"))
+	assert.DirExists(t, libraryDir)
+}
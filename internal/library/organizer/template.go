@@ -0,0 +1,113 @@
+package organizer
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const DefaultTemplate = "{romaji}/Season {season}/{romaji} - S{season:02}E{episode:02} [{group}][{resolution}].{ext}"
+
+// templateVariables are the variables that can be used in a template.
+var templateVariables = map[string]struct{}{
+	"title":        {}, // Preferred title
+	"romaji":       {},
+	"english":      {},
+	"year":         {},
+	"format":       {},
+	"season":       {},
+	"episode":      {},
+	"episodeTitle": {},
+	"group":        {},
+	"resolution":   {},
+	"ext":          {},
+	"mediaId":      {},
+}
+
+var (
+	templateVarRegex   = regexp.MustCompile(`\{([a-zA-Z]+)(?::(\d+))?}`)
+	emptyBracketsRegex = regexp.MustCompile(`\[\s*]|\(\s*\)`)
+	spacesRegex        = regexp.MustCompile(`\s{2,}`)
+	invalidCharsRegex  = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]`)
+)
+
+type (
+	// Template is a parsed path template.
+	// Variables are written as {name} and can be zero-padded with {name:02}.
+	Template struct {
+		raw string
+	}
+
+	// TemplateData holds the values of the template variables for a file.
+	TemplateData map[string]string
+)
+
+// ParseTemplate validates the template.
+func ParseTemplate(raw string) (*Template, error) {
+	raw = strings.TrimSpace(filepath.ToSlash(raw))
+	if raw == "" {
+		return nil, fmt.Errorf("organizer: Template is empty")
+	}
+	if strings.HasPrefix(raw, "/") || filepath.IsAbs(raw) {
+		return nil, fmt.Errorf("organizer: Template should be a relative path")
+	}
+	if strings.Count(raw, "{") != strings.Count(raw, "}") {
+		return nil, fmt.Errorf("organizer: Template has unbalanced braces")
+	}
+
+	for _, match := range templateVarRegex.FindAllStringSubmatch(raw, -1) {
+		if _, ok := templateVariables[match[1]]; !ok {
+			return nil, fmt.Errorf("organizer: Unknown template variable {%s}", match[1])
+		}
+	}
+
+	for _, part := range strings.Split(raw, "/") {
+		if part == ".." || part == "." {
+			return nil, fmt.Errorf("organizer: Template cannot contain relative path elements")
+		}
+	}
+
+	return &Template{raw: raw}, nil
+}
+
+// Render returns the relative path of the file.
+// Each path element is sanitized so that the values cannot create directories or contain invalid characters.
+func (t *Template) Render(data TemplateData) (string, error) {
+	parts := strings.Split(t.raw, "/")
+	for i, part := range parts {
+		rendered := templateVarRegex.ReplaceAllStringFunc(part, func(s string) string {
+			match := templateVarRegex.FindStringSubmatch(s)
+			value := data[match[1]]
+			if match[2] != "" && value != "" {
+				if width, err := strconv.Atoi(match[2]); err == nil {
+					if n, err := strconv.Atoi(value); err == nil {
+						value = fmt.Sprintf("%0*d", width, n)
+					}
+				}
+			}
+			return invalidCharsRegex.ReplaceAllString(value, "")
+		})
+		rendered = cleanPathElement(rendered)
+		if rendered == "" {
+			return "", fmt.Errorf("organizer: Template element %q is empty", part)
+		}
+		parts[i] = rendered
+	}
+
+	return strings.Join(parts, "/"), nil
+}
+
+// cleanPathElement removes the brackets left empty by missing values and the trailing dots and spaces.
+func cleanPathElement(s string) string {
+	s = emptyBracketsRegex.ReplaceAllString(s, "")
+	s = spacesRegex.ReplaceAllString(s, " ")
+	s = strings.ReplaceAll(s, " .", ".")
+	s = strings.TrimSpace(s)
+	s = strings.TrimRight(s, ". ")
+	if s == "" || s == "." || s == ".." {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,75 @@
+package organizer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+
+	data := TemplateData{
+		"romaji":     "Sousou no Frieren",
+		"season":     "1",
+		"episode":    "7",
+		"group":      "SubsPlease",
+		"resolution": "1080p",
+		"ext":        "mkv",
+	}
+
+	tests := []struct {
+		template string
+		data     TemplateData
+		expected string
+	}{
+		{
+			template: DefaultTemplate,
+			data:     data,
+			expected: "Sousou no Frieren/Season 1/Sousou no Frieren - S01E07 [SubsPlease][1080p].mkv",
+		},
+		{
+			template: "{romaji} - {episode:03}.{ext}",
+			data:     data,
+			expected: "Sousou no Frieren - 007.mkv",
+		},
+		{
+			// Missing values leave no empty brackets
+			template: "{romaji} - {episode} [{group}] ({year}).{ext}",
+			data:     TemplateData{"romaji": "Frieren", "episode": "1", "ext": "mkv"},
+			expected: "Frieren - 1.mkv",
+		},
+		{
+			// Values cannot create directories
+			template: "{romaji}/{episode}.{ext}",
+			data:     TemplateData{"romaji": "Fate/Zero: Part 2", "episode": "1", "ext": "mkv"},
+			expected: "FateZero Part 2/1.mkv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := ParseTemplate(tt.template)
+			require.NoError(t, err)
+
+			ret, err := tmpl.Render(tt.data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ret)
+		})
+	}
+}
+
+func TestParseTemplate_Invalid(t *testing.T) {
+
+	templates := []string{
+		"",
+		"/{romaji}/{episode}.{ext}",
+		"{romaji}/../{episode}.{ext}",
+		"{romaji}/{unknown}.{ext}",
+		"{romaji/{episode}.{ext}",
+	}
+
+	for _, tmpl := range templates {
+		_, err := ParseTemplate(tmpl)
+		assert.Error(t, err, tmpl)
+	}
+}
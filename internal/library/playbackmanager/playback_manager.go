@@ -467,7 +467,7 @@ func (pm *PlaybackManager) StartPlaylist(playlist *anime.Playlist) (err error) {
 
 	// Delete playlist in goroutine
 	go func() {
-		err := db_bridge.DeletePlaylist(pm.Database, playlist.DbId)
+		err := db_bridge.DeletePlaylist(pm.Database, playlist.ProfileID, playlist.DbId)
 		if err != nil {
 			pm.Logger.Error().Err(err).Str("name", playlist.Name).Msgf("playback manager: Failed to delete playlist")
 			return
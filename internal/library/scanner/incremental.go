@@ -0,0 +1,252 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"seanime/internal/api/anilist"
+	"seanime/internal/events"
+	"seanime/internal/library/anime"
+	"seanime/internal/library/filesystem"
+	"seanime/internal/library/summary"
+	"seanime/internal/util"
+	"seanime/internal/util/limiter"
+	"strings"
+	"sync"
+)
+
+// ScanPaths scans only the given paths and merges the result into Scanner.ExistingLocalFiles.
+//   - added: Files or directories that were created, renamed or modified. They are (re-)matched.
+//   - removed: Files or directories that were removed or renamed. Their local files are dropped if they no longer exist.
+//
+// Other local files are left untouched, and so are locked and ignored files if SkipLockedFiles and SkipIgnoredFiles are set.
+// Returns all the local files.
+func (scn *Scanner) ScanPaths(added []string, removed []string) (lfs []*anime.LocalFile, err error) {
+	defer util.HandlePanicWithError(&err)
+
+	if scn.ScanSummaryLogger == nil {
+		scn.ScanSummaryLogger = summary.NewScanSummaryLogger()
+	}
+
+	scn.Logger.Debug().Int("added", len(added)).Int("removed", len(removed)).Msg("scanner: Starting incremental scan")
+	scn.WSEventManager.SendEvent(events.EventScanProgress, 0)
+	scn.WSEventManager.SendEvent(events.EventScanStatus, "Retrieving changed files...")
+
+	allLibraries := []string{scn.DirPath}
+	allLibraries = append(allLibraries, scn.OtherDirPaths...)
+
+	// +---------------------+
+	// |     Added files     |
+	// +---------------------+
+
+	addedLfs := make(map[string]*anime.LocalFile)
+	for _, path := range added {
+		root, ok := getLibraryRoot(allLibraries, path)
+		if !ok {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			// Removed since the event
+			continue
+		}
+
+		if info.IsDir() {
+			paths, err := filesystem.GetMediaFilePathsFromDirS(path)
+			if err != nil {
+				scn.Logger.Warn().Err(err).Str("path", path).Msg("scanner: Failed to read directory")
+				continue
+			}
+			for _, p := range paths {
+				addedLfs[normalizePath(p)] = anime.NewLocalFile(p, root)
+			}
+			continue
+		}
+
+		if util.IsValidVideoExtension(strings.ToLower(filepath.Ext(path))) {
+			addedLfs[normalizePath(path)] = anime.NewLocalFile(path, root)
+		}
+	}
+
+	removedPaths := make([]string, 0, len(removed))
+	for _, path := range removed {
+		removedPaths = append(removedPaths, normalizePath(path))
+	}
+	isRemoved := func(path string) bool {
+		for _, r := range removedPaths {
+			if path == r || strings.HasPrefix(path, r+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	// +---------------------+
+	// |    Merge existing   |
+	// +---------------------+
+
+	ret := make([]*anime.LocalFile, 0, len(scn.ExistingLocalFiles)+len(addedLfs))
+	statsToRefresh := make([]*anime.LocalFile, 0)
+	for _, lf := range scn.ExistingLocalFiles {
+		path := normalizePath(lf.Path)
+
+		if _, ok := addedLfs[path]; ok {
+			if (scn.SkipLockedFiles && lf.IsLocked()) || (scn.SkipIgnoredFiles && lf.IsIgnored()) {
+				// Keep the existing file, only its stats are refreshed
+				delete(addedLfs, path)
+				ret = append(ret, lf)
+				statsToRefresh = append(statsToRefresh, lf)
+			}
+			// Otherwise, the file is matched again
+			continue
+		}
+
+		if isRemoved(path) && !filesystem.FileExists(lf.Path) {
+			continue
+		}
+
+		if lf.Size == 0 && lf.ModTime == 0 {
+			statsToRefresh = append(statsToRefresh, lf)
+		}
+
+		ret = append(ret, lf)
+	}
+
+	hydrateFileStats(statsToRefresh)
+
+	// +---------------------+
+	// |      Matching       |
+	// +---------------------+
+
+	toMatch := make([]*anime.LocalFile, 0, len(addedLfs))
+	for _, lf := range addedLfs {
+		toMatch = append(toMatch, lf)
+	}
+
+	if len(toMatch) > 0 {
+		mf, mc, err := scn.matchLocalFiles(toMatch, anilist.NewCompleteAnimeCache(), limiter.NewAnilistLimiter())
+		if err != nil {
+			return nil, err
+		}
+
+		scn.ScanSummaryLogger.HydrateData(toMatch, mc.NormalizedMedia, mf.AnimeCollectionWithRelations)
+
+		hydrateFileStats(toMatch)
+		ret = append(ret, toMatch...)
+	}
+
+	scn.Logger.Info().Int("matched", len(toMatch)).Int("total", len(ret)).Msg("scanner: Incremental scan completed")
+	scn.WSEventManager.SendEvent(events.EventScanProgress, 100)
+	scn.WSEventManager.SendEvent(events.EventScanStatus, "Scan completed")
+
+	if scn.ScanLogger != nil {
+		scn.ScanLogger.logger.Info().
+			Int("scannedFileCount", len(toMatch)).
+			Int("totalFileCount", len(ret)).
+			Msg("Incremental scan completed")
+	}
+
+	return ret, nil
+}
+
+// GetLibraryChanges compares the video files in the library with the existing local files.
+// Returns the paths of the files that were added or modified (size or modification time changed)
+// and the paths of the files that were removed since the last scan.
+// This is used to catch up with the changes that happened while the app was not running.
+func GetLibraryChanges(libraryPaths []string, existing []*anime.LocalFile) (added []string, removed []string, err error) {
+	existingByPath := make(map[string]*anime.LocalFile, len(existing))
+	for _, lf := range existing {
+		existingByPath[normalizePath(lf.Path)] = lf
+	}
+
+	added = make([]string, 0)
+	removed = make([]string, 0)
+	current := make(map[string]struct{})
+
+	for _, libraryPath := range libraryPaths {
+		if libraryPath == "" {
+			continue
+		}
+		paths, err := filesystem.GetMediaFilePathsFromDirS(libraryPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, path := range paths {
+			key := normalizePath(path)
+			if _, ok := current[key]; ok {
+				continue
+			}
+			current[key] = struct{}{}
+
+			lf, found := existingByPath[key]
+			if !found {
+				added = append(added, path)
+				continue
+			}
+
+			// Files scanned before the stats were stored are considered unchanged
+			if lf.Size == 0 && lf.ModTime == 0 {
+				continue
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if info.Size() != lf.Size || info.ModTime().Unix() != lf.ModTime {
+				added = append(added, path)
+			}
+		}
+	}
+
+	for key, lf := range existingByPath {
+		if _, ok := current[key]; !ok {
+			removed = append(removed, lf.Path)
+		}
+	}
+
+	return added, removed, nil
+}
+
+// hydrateFileStats stores the size and the modification time of the files.
+func hydrateFileStats(lfs []*anime.LocalFile) {
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, 16)
+	for _, lf := range lfs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(lf *anime.LocalFile) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			info, err := os.Stat(lf.Path)
+			if err != nil {
+				return
+			}
+			lf.Size = info.Size()
+			lf.ModTime = info.ModTime().Unix()
+		}(lf)
+	}
+	wg.Wait()
+}
+
+// getLibraryRoot returns the library path containing the path.
+// If library paths are nested, the deepest one is returned.
+func getLibraryRoot(libraryPaths []string, path string) (string, bool) {
+	ret := ""
+	for _, libraryPath := range libraryPaths {
+		if libraryPath == "" {
+			continue
+		}
+		if util.IsSubdirectory(libraryPath, path) && len(libraryPath) > len(ret) {
+			ret = libraryPath
+		}
+	}
+	return ret, ret != ""
+}
+
+func normalizePath(path string) string {
+	return filepath.ToSlash(strings.ToLower(filepath.Clean(path)))
+}
@@ -0,0 +1,201 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"seanime/internal/api/anilist"
+	"seanime/internal/events"
+	"seanime/internal/library/anime"
+	"seanime/internal/platforms/anilist_platform"
+	"seanime/internal/test_utils"
+	"seanime/internal/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLibraryChanges(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	unchanged := write("Show/Show - 01.mkv", "01")
+	modified := write("Show/Show - 02.mkv", "02")
+	legacy := write("Show/Show - 03.mkv", "03")
+	added := write("Show/Show - 04.mkv", "04")
+	_ = write("Show/notes.txt", "not a video")
+	removed := filepath.Join(dir, "Show", "Show - 00.mkv")
+
+	existing := []*anime.LocalFile{
+		anime.NewLocalFile(unchanged, dir),
+		anime.NewLocalFile(modified, dir),
+		anime.NewLocalFile(legacy, dir),
+		anime.NewLocalFile(removed, dir),
+	}
+	hydrateFileStats(existing[:2])
+
+	// Modify the second file after its stats were stored
+	require.NoError(t, os.WriteFile(modified, []byte("02 - v2"), 0644))
+	require.NoError(t, os.Chtimes(modified, time.Now().Add(time.Hour), time.Now().Add(time.Hour)))
+
+	addedPaths, removedPaths, err := GetLibraryChanges([]string{dir}, existing)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{modified, added}, addedPaths)
+	assert.ElementsMatch(t, []string{removed}, removedPaths)
+}
+
+func TestGetLibraryRoot(t *testing.T) {
+	libraries := []string{"/anime", "/anime/movies", "/other"}
+
+	root, ok := getLibraryRoot(libraries, "/anime/movies/Movie.mkv")
+	assert.True(t, ok)
+	assert.Equal(t, "/anime/movies", root)
+
+	root, ok = getLibraryRoot(libraries, "/anime/Show/Show - 01.mkv")
+	assert.True(t, ok)
+	assert.Equal(t, "/anime", root)
+
+	_, ok = getLibraryRoot(libraries, "/downloads/Show - 01.mkv")
+	assert.False(t, ok)
+}
+
+func newTestIncrementalScanner(dir string, existing []*anime.LocalFile) *Scanner {
+	logger := util.NewLogger()
+	return &Scanner{
+		DirPath:            dir,
+		Logger:             logger,
+		WSEventManager:     events.NewMockWSEventManager(logger),
+		ExistingLocalFiles: existing,
+		SkipLockedFiles:    true,
+		SkipIgnoredFiles:   true,
+	}
+}
+
+func writeTestFile(t *testing.T, path string, content string) string {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func localFilePaths(lfs []*anime.LocalFile) []string {
+	ret := make([]string, 0, len(lfs))
+	for _, lf := range lfs {
+		ret = append(ret, lf.Path)
+	}
+	return ret
+}
+
+// Removed files and the files of removed directories are dropped, the other local files are kept as is.
+func TestScanner_ScanPaths_Removed(t *testing.T) {
+	dir := t.TempDir()
+
+	kept := writeTestFile(t, filepath.Join(dir, "Show", "Show - 01.mkv"), "01")
+	// Removed then created again before the scan, e.g. replaced by the user
+	recreated := writeTestFile(t, filepath.Join(dir, "Show", "Show - 02.mkv"), "02")
+	removed := filepath.Join(dir, "Show", "Show - 03.mkv")
+	removedDir := filepath.Join(dir, "Movie")
+	inRemovedDir := filepath.Join(removedDir, "Movie.mkv")
+	// Same prefix as the removed directory but not inside it
+	similar := writeTestFile(t, filepath.Join(dir, "Movie 2", "Movie 2.mkv"), "movie 2")
+
+	existing := []*anime.LocalFile{
+		anime.NewLocalFile(kept, dir),
+		anime.NewLocalFile(recreated, dir),
+		anime.NewLocalFile(removed, dir),
+		anime.NewLocalFile(inRemovedDir, dir),
+		anime.NewLocalFile(similar, dir),
+	}
+	existing[0].MediaId = 21
+
+	scn := newTestIncrementalScanner(dir, existing)
+	lfs, err := scn.ScanPaths(nil, []string{removed, recreated, removedDir})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{kept, recreated, similar}, localFilePaths(lfs))
+	// Existing local files are not matched again
+	assert.Same(t, existing[0], lfs[0])
+	assert.Equal(t, 21, lfs[0].MediaId)
+}
+
+// Removing the last file returns no local files so that the removal is saved.
+func TestScanner_ScanPaths_RemovedLastFile(t *testing.T) {
+	dir := t.TempDir()
+	removed := filepath.Join(dir, "Show", "Show - 01.mkv")
+
+	scn := newTestIncrementalScanner(dir, []*anime.LocalFile{anime.NewLocalFile(removed, dir)})
+	lfs, err := scn.ScanPaths(nil, []string{removed})
+	require.NoError(t, err)
+	assert.Empty(t, lfs)
+}
+
+// Locked and ignored files that are modified are kept, only their stats are refreshed.
+func TestScanner_ScanPaths_ModifiedLockedFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	locked := writeTestFile(t, filepath.Join(dir, "Show", "Show - 01.mkv"), "01")
+	ignored := writeTestFile(t, filepath.Join(dir, "Show", "Show - 02.mkv"), "02")
+
+	existing := []*anime.LocalFile{
+		anime.NewLocalFile(locked, dir),
+		anime.NewLocalFile(ignored, dir),
+	}
+	existing[0].MediaId = 21
+	existing[0].Locked = true
+	existing[1].Ignored = true
+
+	require.NoError(t, os.WriteFile(locked, []byte("01 - v2"), 0644))
+
+	scn := newTestIncrementalScanner(dir, existing)
+	// Paths reported by the watcher can have trailing separators
+	lfs, err := scn.ScanPaths([]string{locked, ignored + string(filepath.Separator)}, nil)
+	require.NoError(t, err)
+
+	require.Len(t, lfs, 2)
+	assert.Same(t, existing[0], lfs[0])
+	assert.Equal(t, 21, lfs[0].MediaId)
+	assert.Equal(t, int64(len("01 - v2")), lfs[0].Size)
+	assert.Same(t, existing[1], lfs[1])
+}
+
+// Added files are matched and appended, a renamed directory drops the old paths and matches the new ones.
+func TestScanner_ScanPaths_AddedAndRenamed(t *testing.T) {
+	test_utils.InitTestProvider(t, test_utils.Anilist())
+
+	dir := t.TempDir()
+	anilistPlatform := anilist_platform.NewAnilistPlatform(anilist.TestGetMockAnilistClient(), util.NewLogger())
+
+	oldDir := filepath.Join(dir, "86 - Eighty Six")
+	newDir := filepath.Join(dir, "[SubsPlease] 86 - Eighty Six (01-23) (1080p) [Batch]")
+	existingPath := filepath.Join(oldDir, "[SubsPlease] 86 - Eighty Six - 20v2 (1080p) [30072859].mkv")
+	renamedPath := writeTestFile(t, filepath.Join(newDir, "[SubsPlease] 86 - Eighty Six - 20v2 (1080p) [30072859].mkv"), "20")
+	addedPath := writeTestFile(t, filepath.Join(newDir, "[SubsPlease] 86 - Eighty Six - 21v2 (1080p) [4B1616A5].mkv"), "21")
+	other := writeTestFile(t, filepath.Join(dir, "Other", "Other - 01.mkv"), "01")
+
+	existing := []*anime.LocalFile{
+		anime.NewLocalFile(existingPath, dir),
+		anime.NewLocalFile(other, dir),
+	}
+
+	scn := newTestIncrementalScanner(dir, existing)
+	scn.Platform = anilistPlatform
+
+	// The watcher reports a renamed directory as removed (old path) and added (new path)
+	lfs, err := scn.ScanPaths([]string{newDir}, []string{oldDir})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{other, renamedPath, addedPath}, localFilePaths(lfs))
+	for _, lf := range lfs {
+		if lf.Path != other {
+			assert.NotZero(t, lf.MediaId, lf.Path)
+			assert.NotZero(t, lf.Size, lf.Path)
+		}
+	}
+}
@@ -159,6 +159,7 @@ func (scn *Scanner) Scan() (lfs []*anime.LocalFile, err error) {
 				}
 			}
 		}
+		hydrateFileStats(localFiles)
 		scn.Logger.Debug().Msg("scanner: Scan completed")
 		scn.WSEventManager.SendEvent(events.EventScanProgress, 100)
 		scn.WSEventManager.SendEvent(events.EventScanStatus, "Scan completed")
@@ -166,6 +167,65 @@ func (scn *Scanner) Scan() (lfs []*anime.LocalFile, err error) {
 		return localFiles, nil
 	}
 
+	mf, mc, err := scn.matchLocalFiles(localFiles, completeAnimeCache, anilistRateLimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	scn.WSEventManager.SendEvent(events.EventScanProgress, 90)
+	scn.WSEventManager.SendEvent(events.EventScanStatus, "Verifying file integrity...")
+
+	// Hydrate the summary logger before merging files
+	scn.ScanSummaryLogger.HydrateData(localFiles, mc.NormalizedMedia, mf.AnimeCollectionWithRelations)
+
+	// +---------------------+
+	// |    Merge files      |
+	// +---------------------+
+
+	// Merge skipped files with scanned files
+	// Only files that exist (this removes deleted/moved files)
+	if len(skippedLfs) > 0 {
+		wg := sync.WaitGroup{}
+		mu := sync.Mutex{}
+		wg.Add(len(skippedLfs))
+		for _, skippedLf := range skippedLfs {
+			go func(skippedLf *anime.LocalFile) {
+				defer wg.Done()
+				if filesystem.FileExists(skippedLf.Path) {
+					mu.Lock()
+					localFiles = append(localFiles, skippedLf)
+					mu.Unlock()
+				}
+			}(skippedLf)
+		}
+		wg.Wait()
+	}
+
+	// Store the size and modification time of the files to detect changes
+	hydrateFileStats(localFiles)
+
+	scn.Logger.Info().Msg("scanner: Scan completed")
+	scn.WSEventManager.SendEvent(events.EventScanProgress, 100)
+	scn.WSEventManager.SendEvent(events.EventScanStatus, "Scan completed")
+
+	if scn.ScanLogger != nil {
+		scn.ScanLogger.logger.Info().
+			Int("scannedFileCount", len(localFiles)).
+			Int("skippedFileCount", len(skippedLfs)).
+			Int("unknownMediaCount", len(mf.UnknownMediaIds)).
+			Msg("Scan completed")
+	}
+
+	return localFiles, nil
+}
+
+// matchLocalFiles fetches the media, matches the local files with them and hydrates their metadata.
+func (scn *Scanner) matchLocalFiles(
+	localFiles []*anime.LocalFile,
+	completeAnimeCache *anilist.CompleteAnimeCache,
+	anilistRateLimiter *limiter.Limiter,
+) (*MediaFetcher, *MediaContainer, error) {
+
 	scn.WSEventManager.SendEvent(events.EventScanProgress, 20)
 	if scn.Enhanced {
 		scn.WSEventManager.SendEvent(events.EventScanStatus, "Fetching media detected from file titles...")
@@ -190,7 +250,7 @@ func (scn *Scanner) Scan() (lfs []*anime.LocalFile, err error) {
 		ScanLogger:             scn.ScanLogger,
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	scn.WSEventManager.SendEvent(events.EventScanProgress, 40)
@@ -234,7 +294,7 @@ func (scn *Scanner) Scan() (lfs []*anime.LocalFile, err error) {
 			scn.WSEventManager.SendEvent(events.EventScanProgress, 100)
 			scn.WSEventManager.SendEvent(events.EventScanStatus, "Scan completed")
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
 	scn.WSEventManager.SendEvent(events.EventScanProgress, 70)
@@ -274,46 +334,5 @@ func (scn *Scanner) Scan() (lfs []*anime.LocalFile, err error) {
 		}
 	}
 
-	scn.WSEventManager.SendEvent(events.EventScanProgress, 90)
-	scn.WSEventManager.SendEvent(events.EventScanStatus, "Verifying file integrity...")
-
-	// Hydrate the summary logger before merging files
-	scn.ScanSummaryLogger.HydrateData(localFiles, mc.NormalizedMedia, mf.AnimeCollectionWithRelations)
-
-	// +---------------------+
-	// |    Merge files      |
-	// +---------------------+
-
-	// Merge skipped files with scanned files
-	// Only files that exist (this removes deleted/moved files)
-	if len(skippedLfs) > 0 {
-		wg := sync.WaitGroup{}
-		mu := sync.Mutex{}
-		wg.Add(len(skippedLfs))
-		for _, skippedLf := range skippedLfs {
-			go func(skippedLf *anime.LocalFile) {
-				defer wg.Done()
-				if filesystem.FileExists(skippedLf.Path) {
-					mu.Lock()
-					localFiles = append(localFiles, skippedLf)
-					mu.Unlock()
-				}
-			}(skippedLf)
-		}
-		wg.Wait()
-	}
-
-	scn.Logger.Info().Msg("scanner: Scan completed")
-	scn.WSEventManager.SendEvent(events.EventScanProgress, 100)
-	scn.WSEventManager.SendEvent(events.EventScanStatus, "Scan completed")
-
-	if scn.ScanLogger != nil {
-		scn.ScanLogger.logger.Info().
-			Int("scannedFileCount", len(localFiles)).
-			Int("skippedFileCount", len(skippedLfs)).
-			Int("unknownMediaCount", len(mf.UnknownMediaIds)).
-			Msg("Scan completed")
-	}
-
-	return localFiles, nil
+	return mf, mc, nil
 }
@@ -6,6 +6,10 @@ import (
 	"os"
 	"path/filepath"
 	"seanime/internal/events"
+	"seanime/internal/util"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Watcher is a custom file system event watcher
@@ -14,8 +18,32 @@ type Watcher struct {
 	Logger         *zerolog.Logger
 	WSEventManager events.WSEventManagerInterface
 	TotalSize      string
+	writeTimers    map[string]*time.Timer // Pending write events, key: path
+	writeMu        sync.Mutex
+	dirs           map[string]struct{} // Watched directories, used to report removed directories
+	dirMu          sync.Mutex
 }
 
+// writeDebounce is the time without writes after which a written file is reported.
+// A file that is being copied or downloaded into the library is written many times.
+const writeDebounce = 2 * time.Second
+
+const (
+	FileEventAdded   FileEventKind = "added"
+	FileEventRemoved FileEventKind = "removed"
+)
+
+type (
+	FileEventKind string
+
+	// FileEvent is a file or directory that was added or removed.
+	// A renamed path is reported as removed, the new path is reported as added.
+	FileEvent struct {
+		Path string
+		Kind FileEventKind
+	}
+)
+
 type NewWatcherOptions struct {
 	Logger         *zerolog.Logger
 	WSEventManager events.WSEventManagerInterface
@@ -32,6 +60,8 @@ func NewWatcher(opts *NewWatcherOptions) (*Watcher, error) {
 		Watcher:        watcher,
 		Logger:         opts.Logger,
 		WSEventManager: opts.WSEventManager,
+		writeTimers:    make(map[string]*time.Timer),
+		dirs:           make(map[string]struct{}),
 	}, nil
 }
 
@@ -43,23 +73,9 @@ type WatchLibraryFilesOptions struct {
 
 // InitLibraryFileWatcher starts watching the specified directory and its subdirectories for file system events
 func (w *Watcher) InitLibraryFileWatcher(opts *WatchLibraryFilesOptions) error {
-	// Define a function to add directories and their subdirectories to the watcher
-	watchDir := func(dir string) error {
-		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return nil
-			}
-			if info.IsDir() {
-				return w.Watcher.Add(path)
-			}
-			return nil
-		})
-		return err
-	}
-
 	// Add the initial directory and its subdirectories to the watcher
 	for _, path := range opts.LibraryPaths {
-		if err := watchDir(path); err != nil {
+		if err := w.watchDir(path); err != nil {
 			return err
 		}
 	}
@@ -69,8 +85,52 @@ func (w *Watcher) InitLibraryFileWatcher(opts *WatchLibraryFilesOptions) error {
 	return nil
 }
 
+// watchDir adds the directory and its subdirectories to the watcher.
+func (w *Watcher) watchDir(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			w.dirMu.Lock()
+			w.dirs[path] = struct{}{}
+			w.dirMu.Unlock()
+			return w.Watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+// removeDir forgets the directory and its subdirectories.
+// Returns false if the path was not a watched directory.
+func (w *Watcher) removeDir(dir string) bool {
+	w.dirMu.Lock()
+	defer w.dirMu.Unlock()
+
+	if _, ok := w.dirs[dir]; !ok {
+		return false
+	}
+	for path := range w.dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			delete(w.dirs, path)
+		}
+	}
+	return true
+}
+
+// IsWatchedFile returns true if the changes to the file are reported.
+// Only video files can be matched, so the other files are ignored. This includes partially downloaded or copied files (e.g. ".part"),
+// and the files written next to the media by other modules (e.g. NFO files and artwork), which would otherwise trigger another scan.
+// Hidden files are ignored too, e.g. the "._" files created by macOS.
+func IsWatchedFile(path string) bool {
+	if strings.HasPrefix(filepath.Base(path), ".") {
+		return false
+	}
+	return util.IsValidVideoExtension(filepath.Ext(path))
+}
+
 func (w *Watcher) StartWatching(
-	onFileAction func(),
+	onFileAction func(event *FileEvent),
 ) {
 	// Start a goroutine to handle file system events
 	go func() {
@@ -81,16 +141,29 @@ func (w *Watcher) StartWatching(
 					return
 				}
 				if event.Op&fsnotify.Write == fsnotify.Write {
+					w.debounceWrite(event.Name, onFileAction)
 				}
 				if event.Op&fsnotify.Create == fsnotify.Create {
-					w.Logger.Debug().Msgf("watcher: File created: %s", event.Name)
-					w.WSEventManager.SendEvent(events.LibraryWatcherFileAdded, event.Name)
-					onFileAction()
+					// Watch new directories
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						w.Logger.Debug().Msgf("watcher: Directory created: %s", event.Name)
+						w.WSEventManager.SendEvent(events.LibraryWatcherFileAdded, event.Name)
+						w.watchDir(event.Name)
+						onFileAction(&FileEvent{Path: event.Name, Kind: FileEventAdded})
+					} else if IsWatchedFile(event.Name) {
+						w.Logger.Debug().Msgf("watcher: File created: %s", event.Name)
+						w.WSEventManager.SendEvent(events.LibraryWatcherFileAdded, event.Name)
+						// The file may still be being written, it is reported once the writes stop
+						w.debounceWrite(event.Name, onFileAction)
+					}
 				}
-				if event.Op&fsnotify.Remove == fsnotify.Remove {
-					w.Logger.Debug().Msgf("watcher: File removed: %s", event.Name)
-					w.WSEventManager.SendEvent(events.LibraryWatcherFileRemoved, event.Name)
-					onFileAction()
+				if event.Op&fsnotify.Remove == fsnotify.Remove || event.Op&fsnotify.Rename == fsnotify.Rename {
+					// The path no longer exists, it is reported if it was a watched directory or a video file
+					if w.removeDir(event.Name) || IsWatchedFile(event.Name) {
+						w.Logger.Debug().Msgf("watcher: File removed: %s", event.Name)
+						w.WSEventManager.SendEvent(events.LibraryWatcherFileRemoved, event.Name)
+						onFileAction(&FileEvent{Path: event.Name, Kind: FileEventRemoved})
+					}
 				}
 
 			case err, ok := <-w.Watcher.Errors:
@@ -103,7 +176,43 @@ func (w *Watcher) StartWatching(
 	}()
 }
 
+// debounceWrite reports a created or written file as added once it has not been written to for writeDebounce.
+// This way a file that is being copied is not scanned before it is complete.
+// Files that are not watched are ignored, see IsWatchedFile.
+func (w *Watcher) debounceWrite(path string, onFileAction func(event *FileEvent)) {
+	if !IsWatchedFile(path) {
+		return
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return
+	}
+
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+
+	if timer, ok := w.writeTimers[path]; ok {
+		timer.Reset(writeDebounce)
+		return
+	}
+
+	w.writeTimers[path] = time.AfterFunc(writeDebounce, func() {
+		w.writeMu.Lock()
+		delete(w.writeTimers, path)
+		w.writeMu.Unlock()
+
+		w.Logger.Trace().Msgf("watcher: File written: %s", path)
+		onFileAction(&FileEvent{Path: path, Kind: FileEventAdded})
+	})
+}
+
 func (w *Watcher) StopWatching() {
+	w.writeMu.Lock()
+	for path, timer := range w.writeTimers {
+		timer.Stop()
+		delete(w.writeTimers, path)
+	}
+	w.writeMu.Unlock()
+
 	err := w.Watcher.Close()
 	if err == nil {
 		w.Logger.Trace().Err(err).Msgf("watcher: Watcher stopped")
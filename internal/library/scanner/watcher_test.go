@@ -0,0 +1,102 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"seanime/internal/events"
+	"seanime/internal/util"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsWatchedFile(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/anime/Show/Show - 01.mkv", true},
+		{"/anime/Show/Show - 01.MP4", true},
+		{"/anime/Show/Show - 01.mkv.part", false},
+		{"/anime/Show/Show - 01.mkv.!qB", false},
+		{"/anime/Show/Show - 01.nfo", false},
+		{"/anime/Show/Show - 01-thumb.jpg", false},
+		{"/anime/Show/poster.jpg.tmp", false},
+		{"/anime/Show/Show - 01.ass", false},
+		{"/anime/Show/._Show - 01.mkv", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, IsWatchedFile(tt.path), tt.path)
+	}
+}
+
+func TestWatcher_IgnoresOtherFiles(t *testing.T) {
+	logger := util.NewLogger()
+	dir := t.TempDir()
+
+	w, err := NewWatcher(&NewWatcherOptions{
+		Logger:         logger,
+		WSEventManager: events.NewMockWSEventManager(logger),
+	})
+	require.NoError(t, err)
+	defer w.StopWatching()
+	require.NoError(t, w.InitLibraryFileWatcher(&WatchLibraryFilesOptions{LibraryPaths: []string{dir}}))
+
+	var mu sync.Mutex
+	received := make([]FileEvent, 0)
+	w.StartWatching(func(event *FileEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, *event)
+	})
+
+	showDir := filepath.Join(dir, "Show")
+	require.NoError(t, os.Mkdir(showDir, 0755))
+	time.Sleep(100 * time.Millisecond) // Let the watcher add the directory
+
+	episode := filepath.Join(showDir, "Show - 01.mkv")
+	require.NoError(t, os.WriteFile(episode, []byte("episode"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(showDir, "Show - 02.mkv.part"), []byte("partial"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(showDir, "Show - 01.nfo"), []byte("nfo"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(showDir, "poster.jpg"), []byte("jpg"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(showDir, "poster.jpg")))
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) >= 2
+	}, 5*time.Second, 50*time.Millisecond)
+	time.Sleep(writeDebounce + 500*time.Millisecond) // Let the ignored files be reported if they were not ignored
+
+	mu.Lock()
+	assert.ElementsMatch(t, []FileEvent{
+		{Path: showDir, Kind: FileEventAdded},
+		{Path: episode, Kind: FileEventAdded},
+	}, received)
+	received = received[:0]
+	mu.Unlock()
+
+	// Removed directories are reported even though they no longer exist
+	require.NoError(t, os.RemoveAll(showDir))
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, event := range received {
+			if event.Path == showDir && event.Kind == FileEventRemoved {
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 50*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, event := range received {
+		assert.Contains(t, []string{showDir, episode}, event.Path)
+	}
+}
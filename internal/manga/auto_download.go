@@ -0,0 +1,225 @@
+package manga
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"seanime/internal/api/anilist"
+	"seanime/internal/database/models"
+	"seanime/internal/events"
+	"seanime/internal/manga/providers"
+	"seanime/internal/notifier"
+	"seanime/internal/util"
+	"slices"
+	"strconv"
+	"strings"
+
+	hibikemanga "github.com/5rahim/hibike/pkg/extension/manga"
+	"github.com/samber/lo"
+)
+
+// The manga auto downloader queues new chapters of manga that have a models.MangaAutoDownloaderRule.
+// It is run periodically by the cron jobs and can be triggered by the client.
+
+var ErrMangaAutoDownloaderRunning = errors.New("manga auto downloader is already running")
+
+type AutoDownloaderQueuedChapters struct {
+	MediaId  int      `json:"mediaId"`
+	Provider string   `json:"provider"`
+	Chapters []string `json:"chapters"` // Chapter numbers, e.g. ["12", "12.5"]
+}
+
+// RunAutoDownloader checks the enabled rules and adds the new chapters to the download queue.
+// The download queue is started if chapters were queued.
+func (d *Downloader) RunAutoDownloader(mangaCollection *anilist.MangaCollection) (ret []*AutoDownloaderQueuedChapters, err error) {
+	defer util.HandlePanicInModuleWithError("manga/RunAutoDownloader", &err)
+
+	if !d.autoDownloaderMu.TryLock() {
+		return nil, ErrMangaAutoDownloaderRunning
+	}
+	defer d.autoDownloaderMu.Unlock()
+
+	rules, err := d.database.GetMangaAutoDownloaderRules()
+	if err != nil {
+		return nil, err
+	}
+
+	rules = lo.Filter(rules, func(rule *models.MangaAutoDownloaderRule, _ int) bool {
+		return rule.Enabled
+	})
+	if len(rules) == 0 {
+		return make([]*AutoDownloaderQueuedChapters, 0), nil
+	}
+
+	d.logger.Debug().Int("rules", len(rules)).Msg("manga downloader: Running auto downloader")
+
+	// Refresh the downloaded chapters
+	d.hydrateMediaMap()
+
+	ret = make([]*AutoDownloaderQueuedChapters, 0)
+	for _, rule := range rules {
+		queued, err := d.runAutoDownloaderRule(rule, mangaCollection)
+		if err != nil {
+			d.logger.Warn().Err(err).Int("mediaId", rule.MediaID).Msg("manga downloader: Auto downloader rule failed")
+			continue
+		}
+		if queued != nil {
+			ret = append(ret, queued)
+		}
+	}
+
+	if len(ret) == 0 {
+		d.logger.Debug().Msg("manga downloader: No new chapters found")
+		return ret, nil
+	}
+
+	d.RunChapterDownloadQueue()
+	d.wsEventManager.SendEvent(events.MangaAutoDownloaderQueued, ret)
+
+	return ret, nil
+}
+
+// runAutoDownloaderRule fetches the chapters from the provider and queues the new ones.
+// It returns nil if no chapters were queued.
+func (d *Downloader) runAutoDownloaderRule(rule *models.MangaAutoDownloaderRule, mangaCollection *anilist.MangaCollection) (*AutoDownloaderQueuedChapters, error) {
+	listEntry, ok := mangaCollection.GetListEntryFromMangaId(rule.MediaID)
+	if !ok || listEntry.GetMedia() == nil {
+		return nil, errors.New("manga is not in the collection")
+	}
+	media := listEntry.GetMedia()
+
+	// Always fetch the chapters from the provider so that new releases are found
+	chapterContainer, err := d.repository.GetMangaChapterContainer(&GetMangaChapterContainerOptions{
+		Provider:  rule.Provider,
+		MediaId:   rule.MediaID,
+		Titles:    media.GetAllTitles(),
+		Year:      media.GetStartYearSafe(),
+		SkipCache: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	after := rule.LastQueuedChapter
+	if rule.OnlyAfterProgress {
+		after = max(after, float64(lo.FromPtr(listEntry.GetProgress())))
+	}
+
+	// Chapters that are already downloaded or queued, from any provider
+	exclude := make(map[string]struct{})
+	downloadData, err := d.GetMediaDownloads(rule.MediaID, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range []ProviderDownloadMap{downloadData.Downloaded, downloadData.Queued} {
+		for _, chapters := range m {
+			for _, ch := range chapters {
+				exclude[manga_providers.GetNormalizedChapter(ch.ChapterNumber)] = struct{}{}
+			}
+		}
+	}
+
+	chapters := selectAutoDownloadChapters(chapterContainer.Chapters, after, ParseScanlators(rule.Scanlators), exclude)
+	if len(chapters) == 0 {
+		return nil, nil
+	}
+
+	ret := &AutoDownloaderQueuedChapters{
+		MediaId:  rule.MediaID,
+		Provider: rule.Provider,
+		Chapters: make([]string, 0, len(chapters)),
+	}
+
+	for _, ch := range chapters {
+		err := d.DownloadChapter(DownloadChapterOptions{
+			Provider:  rule.Provider,
+			MediaId:   rule.MediaID,
+			ChapterId: ch.ID,
+		})
+		if err != nil {
+			// Stop here so that the chapter is retried on the next run
+			d.logger.Warn().Err(err).Str("chapter", ch.Chapter).Msg("manga downloader: Failed to queue chapter")
+			break
+		}
+
+		number := manga_providers.GetNormalizedChapter(ch.Chapter)
+		ret.Chapters = append(ret.Chapters, number)
+		if f, err := strconv.ParseFloat(number, 64); err == nil {
+			rule.LastQueuedChapter = max(rule.LastQueuedChapter, f)
+		}
+	}
+
+	if len(ret.Chapters) == 0 {
+		return nil, nil
+	}
+
+	if err := d.database.UpdateMangaAutoDownloaderRule(rule); err != nil {
+		d.logger.Error().Err(err).Msg("manga downloader: Failed to update auto downloader rule")
+	}
+
+	d.logger.Info().Int("mediaId", rule.MediaID).Strs("chapters", ret.Chapters).Msg("manga downloader: Auto downloader queued chapters")
+
+	if len(ret.Chapters) == 1 {
+		notifier.GlobalNotifier.Notify(notifier.MangaAutoDownloader, fmt.Sprintf("Chapter %s of %s has been added to the download queue", ret.Chapters[0], media.GetPreferredTitle()))
+	} else {
+		notifier.GlobalNotifier.Notify(notifier.MangaAutoDownloader, fmt.Sprintf("%d chapters of %s have been added to the download queue", len(ret.Chapters), media.GetPreferredTitle()))
+	}
+
+	return ret, nil
+}
+
+// selectAutoDownloadChapters returns the chapters numbered after 'after' that are not excluded, sorted by chapter number.
+// When several chapters have the same number, the one from the preferred scanlator is selected.
+func selectAutoDownloadChapters(chapters []*hibikemanga.ChapterDetails, after float64, scanlators []string, exclude map[string]struct{}) []*hibikemanga.ChapterDetails {
+	type candidate struct {
+		chapter *hibikemanga.ChapterDetails
+		number  float64
+		rank    int
+	}
+
+	candidates := make(map[string]*candidate)
+	for _, ch := range chapters {
+		normalized := manga_providers.GetNormalizedChapter(ch.Chapter)
+		number, err := strconv.ParseFloat(normalized, 64)
+		if err != nil || number <= after {
+			continue
+		}
+		if _, ok := exclude[normalized]; ok {
+			continue
+		}
+
+		rank := scanlatorRank(ch.Scanlator, scanlators)
+		if c, ok := candidates[normalized]; ok && c.rank <= rank {
+			continue
+		}
+		candidates[normalized] = &candidate{chapter: ch, number: number, rank: rank}
+	}
+
+	sorted := lo.Values(candidates)
+	slices.SortFunc(sorted, func(a, b *candidate) int {
+		return cmp.Compare(a.number, b.number)
+	})
+
+	return lo.Map(sorted, func(c *candidate, _ int) *hibikemanga.ChapterDetails {
+		return c.chapter
+	})
+}
+
+// scanlatorRank returns the index of the scanlator in the preference list, or the length of the list if it isn't in it.
+func scanlatorRank(scanlator string, scanlators []string) int {
+	for i, s := range scanlators {
+		if strings.EqualFold(strings.TrimSpace(scanlator), s) {
+			return i
+		}
+	}
+	return len(scanlators)
+}
+
+// ParseScanlators returns the scanlators of a comma-separated list.
+//
+//	e.g. "Group A, Group B" -> ["Group A", "Group B"]
+func ParseScanlators(value string) []string {
+	return lo.Compact(lo.Map(strings.Split(value, ","), func(s string, _ int) string {
+		return strings.TrimSpace(s)
+	}))
+}
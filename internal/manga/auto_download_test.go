@@ -0,0 +1,61 @@
+package manga
+
+import (
+	hibikemanga "github.com/5rahim/hibike/pkg/extension/manga"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSelectAutoDownloadChapters(t *testing.T) {
+	chapters := []*hibikemanga.ChapterDetails{
+		{ID: "9", Chapter: "9"},
+		{ID: "10-a", Chapter: "10", Scanlator: "Group A"},
+		{ID: "10-b", Chapter: "10", Scanlator: "group b"},
+		{ID: "11-a", Chapter: "11", Scanlator: "Group A"},
+		{ID: "10.5", Chapter: "010.5"},
+		{ID: "12", Chapter: "12"},
+		{ID: "extra", Chapter: "Extra"},
+	}
+
+	tests := []struct {
+		name       string
+		after      float64
+		scanlators string
+		exclude    []string
+		expected   []string
+	}{
+		{
+			name:     "All chapters",
+			expected: []string{"9", "10-a", "10.5", "11-a", "12"},
+		},
+		{
+			name:       "Scanlator preference",
+			after:      9,
+			scanlators: "Group B, Group A",
+			expected:   []string{"10-b", "10.5", "11-a", "12"},
+		},
+		{
+			name:     "Excluded chapters",
+			after:    9,
+			exclude:  []string{"10", "12"},
+			expected: []string{"10.5", "11-a"},
+		},
+		{
+			name:     "No new chapters",
+			after:    12,
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exclude := make(map[string]struct{})
+			for _, e := range tt.exclude {
+				exclude[e] = struct{}{}
+			}
+			res := selectAutoDownloadChapters(chapters, tt.after, ParseScanlators(tt.scanlators), exclude)
+			assert.Equal(t, tt.expected, lo.Map(res, func(ch *hibikemanga.ChapterDetails, _ int) string { return ch.ID }))
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/samber/lo"
 	"seanime/internal/extension"
+	"seanime/internal/manga/providers"
 	"seanime/internal/util"
 	"seanime/internal/util/comparison"
 	"sync"
@@ -33,6 +34,8 @@ type GetMangaChapterContainerOptions struct {
 	MediaId  int
 	Titles   []*string
 	Year     int
+	// SkipCache fetches the chapters from the provider even if they are cached, the cache is then updated
+	SkipCache bool
 }
 
 // GetMangaChapterContainer returns the ChapterContainer for a manga entry based on the provider.
@@ -59,7 +62,8 @@ func (r *Repository) GetMangaChapterContainer(opts *GetMangaChapterContainerOpti
 	containerBucket := r.getFcProviderBucket(provider, mediaId, bucketTypeChapter)
 
 	// Check if the container is in the cache
-	if found, _ := r.fileCacher.Get(containerBucket, chapterContainerKey, &container); found {
+	// The local library is always scanned again so that new files are listed
+	if found, _ := r.fileCacher.Get(containerBucket, chapterContainerKey, &container); found && !opts.SkipCache && provider != manga_providers.LocalMangaProvider {
 		r.logger.Info().Str("bucket", containerBucket.Name()).Msg("manga: Chapter Container Cache HIT")
 		return container, nil
 	}
@@ -0,0 +1,262 @@
+package manga
+
+import (
+	"cmp"
+	"errors"
+	"seanime/internal/manga/providers"
+	"seanime/internal/util"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	hibikemanga "github.com/5rahim/hibike/pkg/extension/manga"
+	"github.com/samber/lo"
+)
+
+// A merged chapter container combines the chapter lists of several providers.
+// Chapters are deduplicated by chapter number, the chapter of the highest ranked provider is used and the chapters of the
+// other providers are kept as alternatives. When the pages of a chapter cannot be fetched, the alternatives are tried in order.
+// The same goes for a single page that cannot be fetched, the page with the same index is served from the alternatives.
+//
+//	e.g. Providers: ["mangadex", "comick"]
+//	     mangadex: 1, 2, 4
+//	     comick: 1, 2, 3, 4
+//	  -> 1 (mangadex), 2 (mangadex), 3 (comick), 4 (mangadex)
+//
+// Chapters without a number (e.g. "Oneshot", "Extra") cannot be matched, they are all kept and have no alternatives.
+
+var ErrNoProvidersSelected = errors.New("no providers selected")
+
+type (
+	MergedChapterContainer struct {
+		MediaId int `json:"mediaId"`
+		// Providers in order of preference
+		Providers []string `json:"providers"`
+		// Chapters contains one chapter per chapter number, the provider of each chapter is set
+		Chapters []*hibikemanga.ChapterDetails `json:"chapters"`
+		// Alternatives are the chapters of the other providers, in order of preference.
+		// The key is the normalized chapter number, e.g. "10.5"
+		Alternatives map[string][]*hibikemanga.ChapterDetails `json:"alternatives"`
+		// Errors contains the error message of each provider that could not be queried
+		Errors map[string]string `json:"errors,omitempty"`
+	}
+
+	GetMergedChapterContainerOptions struct {
+		MediaId   int
+		Providers []string
+		Titles    []*string
+		Year      int
+	}
+
+	GetMergedPageContainerOptions struct {
+		GetMergedChapterContainerOptions
+		// Provider and ChapterId of the chapter to read, it is tried first
+		Provider   string
+		ChapterId  string
+		DoublePage bool
+		IsOffline  bool
+	}
+)
+
+// GetMergedChapterContainer fetches the chapters of each provider and merges them.
+// An error is only returned if no provider returned chapters.
+func (r *Repository) GetMergedChapterContainer(opts *GetMergedChapterContainerOptions) (ret *MergedChapterContainer, err error) {
+	defer util.HandlePanicInModuleWithError("manga/GetMergedChapterContainer", &err)
+
+	providers := lo.Uniq(lo.Compact(opts.Providers))
+	if len(providers) == 0 {
+		return nil, ErrNoProvidersSelected
+	}
+
+	containers := make([]*ChapterContainer, len(providers))
+	errs := make([]error, len(providers))
+
+	wg := sync.WaitGroup{}
+	for i, provider := range providers {
+		wg.Add(1)
+		go func(i int, provider string) {
+			defer wg.Done()
+			containers[i], errs[i] = r.GetMangaChapterContainer(&GetMangaChapterContainerOptions{
+				Provider: provider,
+				MediaId:  opts.MediaId,
+				Titles:   opts.Titles,
+				Year:     opts.Year,
+			})
+		}(i, provider)
+	}
+	wg.Wait()
+
+	providerErrors := make(map[string]string)
+	for i, provider := range providers {
+		if errs[i] != nil {
+			r.logger.Warn().Err(errs[i]).Str("provider", provider).Msg("manga: Failed to get chapters for merged container")
+			providerErrors[provider] = errs[i].Error()
+			containers[i] = nil
+		}
+	}
+
+	if len(providerErrors) == len(providers) {
+		return nil, ErrNoChapters
+	}
+
+	ret = mergeChapterContainers(opts.MediaId, providers, containers)
+	if len(providerErrors) > 0 {
+		ret.Errors = providerErrors
+	}
+
+	r.logger.Debug().Int("mediaId", opts.MediaId).Int("chapters", len(ret.Chapters)).Strs("providers", providers).Msg("manga: Merged chapters")
+
+	return ret, nil
+}
+
+// GetMergedPageContainer returns the pages of a chapter of the merged container.
+// If the pages of the requested chapter cannot be fetched, the chapters of the other providers with the same number are tried.
+// The pages are registered with the merged chapter list so that a page that cannot be fetched is served from the other providers, see GetPageImageByKey.
+func (r *Repository) GetMergedPageContainer(opts *GetMergedPageContainerOptions) (ret *PageContainer, err error) {
+	defer util.HandlePanicInModuleWithError("manga/GetMergedPageContainer", &err)
+
+	defer func() {
+		if ret != nil && !opts.IsOffline {
+			mergedOpts := opts.GetMergedChapterContainerOptions
+			r.registerPageKeys(ret, &mergedOpts)
+		}
+	}()
+
+	// The requested chapter is tried first, even if it's not part of the merged container
+	ret, err = r.getMergedChapterPages(opts, opts.Provider, opts.ChapterId)
+	if err == nil {
+		return ret, nil
+	}
+
+	// Only downloaded chapters can be read when offline
+	if opts.IsOffline {
+		return nil, err
+	}
+
+	// The chapters of the other providers are only fetched if needed
+	merged, mergeErr := r.GetMergedChapterContainer(&opts.GetMergedChapterContainerOptions)
+	if mergeErr != nil {
+		return nil, err
+	}
+
+	for _, chapter := range merged.GetFallbackChapters(opts.Provider, opts.ChapterId) {
+		ret, err = r.getMergedChapterPages(opts, chapter.Provider, chapter.ID)
+		if err == nil {
+			return ret, nil
+		}
+	}
+
+	return nil, err
+}
+
+// getMergedChapterPages returns the pages of the chapter, ErrNoPages if it has none.
+func (r *Repository) getMergedChapterPages(opts *GetMergedPageContainerOptions, provider string, chapterId string) (*PageContainer, error) {
+	ret, err := r.GetMangaPageContainer(provider, opts.MediaId, chapterId, opts.DoublePage, opts.IsOffline)
+	if err == nil && len(ret.Pages) == 0 {
+		err = ErrNoPages
+	}
+	if err != nil {
+		r.logger.Warn().Err(err).Str("provider", provider).Str("chapterId", chapterId).Msg("manga: Failed to get pages, trying next provider")
+		return nil, err
+	}
+	return ret, nil
+}
+
+// GetFallbackChapters returns the chapters of the other providers that have the same number as the given chapter, in order of preference.
+func (c *MergedChapterContainer) GetFallbackChapters(provider string, chapterId string) []*hibikemanga.ChapterDetails {
+	for _, chapters := range c.allChaptersByNumber() {
+		isRequested := lo.ContainsBy(chapters, func(ch *hibikemanga.ChapterDetails) bool {
+			return ch.Provider == provider && ch.ID == chapterId
+		})
+		if !isRequested {
+			continue
+		}
+		return lo.Filter(chapters, func(ch *hibikemanga.ChapterDetails, _ int) bool {
+			return ch.Provider != provider
+		})
+	}
+	return nil
+}
+
+// allChaptersByNumber returns the selected chapter followed by its alternatives for each chapter number.
+// Chapters without a number are not included.
+func (c *MergedChapterContainer) allChaptersByNumber() map[string][]*hibikemanga.ChapterDetails {
+	ret := make(map[string][]*hibikemanga.ChapterDetails, len(c.Chapters))
+	for _, ch := range c.Chapters {
+		if _, ok := getChapterNumber(ch.Chapter); !ok {
+			continue
+		}
+		number := manga_providers.GetNormalizedChapter(ch.Chapter)
+		ret[number] = append([]*hibikemanga.ChapterDetails{ch}, c.Alternatives[number]...)
+	}
+	return ret
+}
+
+// mergeChapterContainers merges the containers in order of preference, nil containers are ignored.
+func mergeChapterContainers(mediaId int, providers []string, containers []*ChapterContainer) *MergedChapterContainer {
+	ret := &MergedChapterContainer{
+		MediaId:      mediaId,
+		Providers:    providers,
+		Chapters:     make([]*hibikemanga.ChapterDetails, 0),
+		Alternatives: make(map[string][]*hibikemanga.ChapterDetails),
+	}
+
+	selected := make(map[string]struct{})
+
+	for i, container := range containers {
+		if container == nil {
+			continue
+		}
+		// A provider can list the same chapter several times (e.g. different scanlators), only the first one is used
+		seen := make(map[string]struct{})
+		for _, chapter := range container.Chapters {
+			// Copy the chapter so that the cached containers are not modified
+			ch := *chapter
+			ch.Provider = providers[i]
+
+			if _, ok := getChapterNumber(chapter.Chapter); !ok {
+				ret.Chapters = append(ret.Chapters, &ch)
+				continue
+			}
+
+			number := manga_providers.GetNormalizedChapter(chapter.Chapter)
+			if _, ok := seen[number]; ok {
+				continue
+			}
+			seen[number] = struct{}{}
+
+			if _, ok := selected[number]; ok {
+				ret.Alternatives[number] = append(ret.Alternatives[number], &ch)
+				continue
+			}
+			selected[number] = struct{}{}
+			ret.Chapters = append(ret.Chapters, &ch)
+		}
+	}
+
+	slices.SortStableFunc(ret.Chapters, func(a, b *hibikemanga.ChapterDetails) int {
+		af, okA := getChapterNumber(a.Chapter)
+		bf, okB := getChapterNumber(b.Chapter)
+		if !okA || !okB {
+			// Chapters without a number are kept at the end
+			return cmp.Compare(lo.Ternary(!okA, 1, 0), lo.Ternary(!okB, 1, 0))
+		}
+		return cmp.Compare(af, bf)
+	})
+
+	for i, ch := range ret.Chapters {
+		ch.Index = uint(i)
+	}
+
+	return ret
+}
+
+// getChapterNumber returns the number of the chapter, false if the chapter has no number (e.g. "Oneshot" or an empty string).
+func getChapterNumber(chapter string) (float64, bool) {
+	if strings.TrimSpace(chapter) == "" {
+		return 0, false
+	}
+	ret, err := strconv.ParseFloat(manga_providers.GetNormalizedChapter(chapter), 64)
+	return ret, err == nil
+}
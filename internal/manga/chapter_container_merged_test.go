@@ -0,0 +1,65 @@
+package manga
+
+import (
+	hibikemanga "github.com/5rahim/hibike/pkg/extension/manga"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMergeChapterContainers(t *testing.T) {
+	mangadex := &ChapterContainer{
+		Provider: "mangadex",
+		Chapters: []*hibikemanga.ChapterDetails{
+			{ID: "md-4", Chapter: "4"},
+			{ID: "md-1", Chapter: "1"},
+			{ID: "md-2", Chapter: "2"},
+			{ID: "md-2-alt", Chapter: "2"},
+			{ID: "md-extra", Chapter: "Extra"},
+			{ID: "md-special", Chapter: "Special"},
+		},
+	}
+	comick := &ChapterContainer{
+		Provider: "comick",
+		Chapters: []*hibikemanga.ChapterDetails{
+			{ID: "ck-1", Chapter: "01"},
+			{ID: "ck-2", Chapter: "2"},
+			{ID: "ck-3", Chapter: "3"},
+			{ID: "ck-4", Chapter: "4"},
+			{ID: "ck-oneshot", Chapter: "Oneshot"},
+			{ID: "ck-untitled", Chapter: ""},
+			{ID: "ck-extra", Chapter: "Extra"},
+		},
+	}
+
+	merged := mergeChapterContainers(1, []string{"mangadex", "comick", "weebcentral"}, []*ChapterContainer{mangadex, comick, nil})
+
+	// Chapters without a number are all kept
+	assert.Equal(t, []string{"md-1", "md-2", "ck-3", "md-4", "md-extra", "md-special", "ck-oneshot", "ck-untitled", "ck-extra"}, lo.Map(merged.Chapters, func(ch *hibikemanga.ChapterDetails, _ int) string { return ch.ID }))
+	assert.Equal(t, []uint{0, 1, 2, 3, 4, 5, 6, 7, 8}, lo.Map(merged.Chapters, func(ch *hibikemanga.ChapterDetails, _ int) uint { return ch.Index }))
+	assert.Empty(t, merged.Alternatives["0"])
+	assert.Empty(t, merged.Alternatives["Extra"])
+	assert.Equal(t, "comick", merged.Chapters[2].Provider)
+
+	require.Len(t, merged.Alternatives["2"], 1)
+	assert.Equal(t, "ck-2", merged.Alternatives["2"][0].ID)
+	assert.Empty(t, merged.Alternatives["3"])
+
+	// The cached containers are not modified
+	assert.Equal(t, uint(0), comick.Chapters[2].Index)
+	assert.Equal(t, "", comick.Chapters[2].Provider)
+
+	// Fallback
+	fallback := merged.GetFallbackChapters("mangadex", "md-1")
+	require.Len(t, fallback, 1)
+	assert.Equal(t, "ck-1", fallback[0].ID)
+
+	fallback = merged.GetFallbackChapters("comick", "ck-4")
+	require.Len(t, fallback, 1)
+	assert.Equal(t, "md-4", fallback[0].ID)
+
+	assert.Empty(t, merged.GetFallbackChapters("comick", "ck-3"))
+	assert.Empty(t, merged.GetFallbackChapters("comick", "ck-extra"))
+	assert.Empty(t, merged.GetFallbackChapters("comick", "unknown"))
+}
@@ -19,8 +19,9 @@ type (
 		Provider       string                     `json:"provider"`
 		ChapterId      string                     `json:"chapterId"`
 		Pages          []*hibikemanga.ChapterPage `json:"pages"`
-		PageDimensions map[int]*PageDimension     `json:"pageDimensions"` // Indexed by page number
-		IsDownloaded   bool                       `json:"isDownloaded"`   // TODO remove
+		PageDimensions map[int]*PageDimension     `json:"pageDimensions"`     // Indexed by page number
+		PageKeys       map[int]string             `json:"pageKeys,omitempty"` // Keys of the pages served by the image route, indexed by page number
+		IsDownloaded   bool                       `json:"isDownloaded"`       // TODO remove
 	}
 
 	// PageDimension is used to store the dimensions of a page.
@@ -89,6 +90,7 @@ func (r *Repository) GetMangaPageContainer(
 		// Hydrate page dimensions
 		pageDimensions, _ := r.getPageDimensions(doublePage, provider, mediaId, chapterId, container.Pages)
 		container.PageDimensions = pageDimensions
+		r.registerPageKeys(container, nil)
 
 		r.logger.Debug().Str("key", pageContainerKey).Msg("manga: Page Container Cache HIT")
 		return container, nil
@@ -165,6 +167,8 @@ func (r *Repository) GetMangaPageContainer(
 		r.logger.Warn().Err(err).Msg("manga: Failed to populate cache")
 	}
 
+	r.registerPageKeys(container, nil)
+
 	r.logger.Debug().Str("key", pageContainerKey).Msg("manga: Retrieved pages")
 
 	return container, nil
@@ -200,7 +204,13 @@ func (r *Repository) getPageDimensions(enabled bool, provider string, mediaId in
 		wg.Add(1)
 		go func(page *hibikemanga.ChapterPage) {
 			defer wg.Done()
-			buf, err := manga_providers.GetImageByProxy(page.URL, page.Headers)
+			var buf []byte
+			var err error
+			if provider == manga_providers.LocalMangaProvider {
+				buf, _, err = r.localProvider.ReadPage(chapterId, page.Index)
+			} else {
+				buf, err = r.imagePipeline.GetImage(page.URL, page.Headers)
+			}
 			if err != nil {
 				return
 			}
@@ -31,6 +31,10 @@ type (
 
 		chapterDownloadedCh chan chapter_downloader.DownloadID
 		readingDownloadDir  bool
+
+		exportQueue *exportQueue
+
+		autoDownloaderMu sync.Mutex
 	}
 
 	// MediaMap is created after reading the download directory.
@@ -86,6 +90,7 @@ func NewDownloader(opts *NewDownloaderOptions) *Downloader {
 		repository:     opts.Repository,
 		mediaMap:       new(MediaMap),
 		filecacher:     filecacher,
+		exportQueue:    newExportQueue(),
 	}
 
 	d.chapterDownloader = chapter_downloader.NewDownloader(&chapter_downloader.NewDownloaderOptions{
@@ -93,9 +98,12 @@ func NewDownloader(opts *NewDownloaderOptions) *Downloader {
 		WSEventManager: opts.WSEventManager,
 		Database:       opts.Database,
 		DownloadDir:    opts.DownloadDir,
+		ImagePipeline:  opts.Repository.GetImagePipeline(),
+		LocalProvider:  opts.Repository.GetLocalProvider(),
 	})
 
 	go d.hydrateMediaMap()
+	d.startExportLoop()
 
 	return d
 }
@@ -2,6 +2,7 @@ package chapter_downloader
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	hibikemanga "github.com/5rahim/hibike/pkg/extension/manga"
 	"github.com/goccy/go-json"
@@ -13,11 +14,11 @@ import (
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
-	"io"
 	"os"
 	"path/filepath"
 	"seanime/internal/database/db"
 	"seanime/internal/events"
+	"seanime/internal/manga/imagecache"
 	"seanime/internal/manga/providers"
 	"seanime/internal/util"
 	"strconv"
@@ -40,6 +41,8 @@ type (
 		wsEventManager events.WSEventManagerInterface
 		database       *db.Database
 		downloadDir    string
+		imagePipeline  *manga_imagecache.Pipeline  // Optimizes and splits pages, can be nil
+		localProvider  *manga_providers.LocalManga // Reads the pages of local chapters
 		mu             sync.Mutex
 		downloadMu     sync.Mutex
 		// cancelChannel is used to cancel some or all downloads.
@@ -71,6 +74,14 @@ type (
 		Size        int64  `json:"size"`
 		Width       int    `json:"width"`
 		Height      int    `json:"height"`
+		// Tiles of a webtoon strip that was split, in order. Filename is the first tile.
+		Tiles []TileInfo `json:"tiles,omitempty"`
+	}
+
+	TileInfo struct {
+		Filename string `json:"filename"`
+		Width    int    `json:"width"`
+		Height   int    `json:"height"`
 	}
 )
 
@@ -80,6 +91,8 @@ type (
 		WSEventManager events.WSEventManagerInterface
 		DownloadDir    string
 		Database       *db.Database
+		ImagePipeline  *manga_imagecache.Pipeline
+		LocalProvider  *manga_providers.LocalManga
 	}
 
 	DownloadOptions struct {
@@ -96,6 +109,8 @@ func NewDownloader(opts *NewDownloaderOptions) *Downloader {
 		logger:              opts.Logger,
 		wsEventManager:      opts.WSEventManager,
 		downloadDir:         opts.DownloadDir,
+		imagePipeline:       opts.ImagePipeline,
+		localProvider:       opts.LocalProvider,
 		cancelChannels:      make(map[DownloadID]chan struct{}),
 		runCh:               runCh,
 		queue:               NewQueue(opts.Database, opts.Logger, opts.WSEventManager, runCh),
@@ -257,7 +272,7 @@ func (cd *Downloader) downloadChapterImages(queueInfo *QueueInfo) (err error) {
 				//cd.logger.Warn().Msg("chapter downloader: Download goroutine canceled")
 				return
 			default:
-				cd.downloadPage(page, destination, registry)
+				cd.downloadPage(page, queueInfo.DownloadID, destination, registry)
 			}
 		}(page, &registry)
 	}
@@ -279,9 +294,22 @@ func (cd *Downloader) downloadChapterImages(queueInfo *QueueInfo) (err error) {
 	return
 }
 
+// getPageImage returns the image of the page.
+// The pages of local chapters are served by Seanime, their URLs are relative so they are read from the library instead.
+func (cd *Downloader) getPageImage(page *hibikemanga.ChapterPage, downloadId DownloadID) ([]byte, error) {
+	if downloadId.Provider == manga_providers.LocalMangaProvider {
+		if cd.localProvider == nil {
+			return nil, errors.New("local provider not available")
+		}
+		buf, _, err := cd.localProvider.ReadPage(downloadId.ChapterId, page.Index)
+		return buf, err
+	}
+	return manga_providers.GetImageByProxy(page.URL, page.Headers)
+}
+
 // downloadPage downloads a single page from the URL and saves it to the destination directory.
 // It also updates the Registry with the page information.
-func (cd *Downloader) downloadPage(page *hibikemanga.ChapterPage, destination string, registry *Registry) {
+func (cd *Downloader) downloadPage(page *hibikemanga.ChapterPage, downloadId DownloadID, destination string, registry *Registry) {
 
 	defer util.HandlePanicInModuleThen("manga/downloader/downloadImage", func() {
 	})
@@ -290,47 +318,56 @@ func (cd *Downloader) downloadPage(page *hibikemanga.ChapterPage, destination st
 
 	imgID := fmt.Sprintf("%02d", page.Index+1)
 
-	buf, err := manga_providers.GetImageByProxy(page.URL, page.Headers)
+	buf, err := cd.getPageImage(page, downloadId)
 	if err != nil {
 		cd.logger.Error().Err(err).Msgf("chapter downloader: Failed to get image from URL %s", page.URL)
 		return
 	}
 
-	// Get the image format
-	config, format, err := image.DecodeConfig(bytes.NewReader(buf))
-	if err != nil {
-		cd.logger.Error().Err(err).Msgf("chapter downloader: Failed to decode image format from URL %s", page.URL)
-		return
+	// Optimize the image and split webtoon strips
+	images := [][]byte{buf}
+	if cd.imagePipeline != nil {
+		images = cd.imagePipeline.ProcessDownloadedImage(buf)
 	}
 
-	filename := imgID + "." + format
-
-	// Create the file
-	filePath := filepath.Join(destination, filename)
-	file, err := os.Create(filePath)
-	if err != nil {
-		cd.logger.Error().Err(err).Msgf("chapter downloader: Failed to create file for image %s", imgID)
-		return
+	pageInfo := PageInfo{
+		Index:       page.Index,
+		OriginalURL: page.URL,
 	}
-	defer file.Close()
 
-	// Copy the image data to the file
-	_, err = io.Copy(file, bytes.NewReader(buf))
-	if err != nil {
-		cd.logger.Error().Err(err).Msgf("image downloader: Failed to write image data to file for image from %s", page.URL)
-		return
+	for i, data := range images {
+		// Get the image format
+		config, format, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			cd.logger.Error().Err(err).Msgf("chapter downloader: Failed to decode image format from URL %s", page.URL)
+			return
+		}
+
+		// e.g. 01.jpeg, or 01_01.jpeg, 01_02.jpeg for tiles
+		filename := imgID + "." + format
+		if len(images) > 1 {
+			filename = fmt.Sprintf("%s_%02d.%s", imgID, i+1, format)
+		}
+
+		if err := os.WriteFile(filepath.Join(destination, filename), data, 0644); err != nil {
+			cd.logger.Error().Err(err).Msgf("image downloader: Failed to write image data to file for image from %s", page.URL)
+			return
+		}
+
+		pageInfo.Size += int64(len(data))
+		if i == 0 {
+			pageInfo.Filename = filename
+			pageInfo.Width = config.Width
+			pageInfo.Height = config.Height
+		}
+		if len(images) > 1 {
+			pageInfo.Tiles = append(pageInfo.Tiles, TileInfo{Filename: filename, Width: config.Width, Height: config.Height})
+		}
 	}
 
 	// Update registry
 	cd.downloadMu.Lock()
-	(*registry)[page.Index] = PageInfo{
-		Index:       page.Index,
-		Width:       config.Width,
-		Height:      config.Height,
-		Filename:    filename,
-		OriginalURL: page.URL,
-		Size:        int64(len(buf)),
-	}
+	(*registry)[page.Index] = pageInfo
 	cd.downloadMu.Unlock()
 
 	return
@@ -1,7 +1,6 @@
 package manga
 
 import (
-	"cmp"
 	hibikemanga "github.com/5rahim/hibike/pkg/extension/manga"
 	"github.com/goccy/go-json"
 	"os"
@@ -206,25 +205,7 @@ func (r *Repository) getDownloadedMangaPageContainer(
 		return nil, err
 	}
 
-	pageList := make([]*hibikemanga.ChapterPage, 0)
-	pageDimensions := make(map[int]*PageDimension)
-
-	// Get the downloaded pages
-	for pageIndex, pageInfo := range *pageRegistry {
-		pageList = append(pageList, &hibikemanga.ChapterPage{
-			Index:    pageIndex,
-			URL:      filepath.Join(chapterDir, pageInfo.Filename),
-			Provider: provider,
-		})
-		pageDimensions[pageIndex] = &PageDimension{
-			Width:  pageInfo.Width,
-			Height: pageInfo.Height,
-		}
-	}
-
-	slices.SortStableFunc(pageList, func(i, j *hibikemanga.ChapterPage) int {
-		return cmp.Compare(i.Index, j.Index)
-	})
+	pageList, pageDimensions := getDownloadedPages(chapterDir, provider, *pageRegistry)
 
 	container := &PageContainer{
 		MediaId:        mediaId,
@@ -239,3 +220,52 @@ func (r *Repository) getDownloadedMangaPageContainer(
 
 	return container, nil
 }
+
+// getDownloadedPages returns the pages of a downloaded chapter sorted by index.
+// The tiles of a split webtoon strip are returned as separate pages, in that case the pages are re-indexed.
+func getDownloadedPages(chapterDir string, provider string, registry chapter_downloader.Registry) ([]*hibikemanga.ChapterPage, map[int]*PageDimension) {
+	indices := make([]int, 0, len(registry))
+	for index := range registry {
+		indices = append(indices, index)
+	}
+	slices.Sort(indices)
+
+	hasTiles := false
+	for _, pageInfo := range registry {
+		if len(pageInfo.Tiles) > 0 {
+			hasTiles = true
+			break
+		}
+	}
+
+	pageList := make([]*hibikemanga.ChapterPage, 0, len(registry))
+	pageDimensions := make(map[int]*PageDimension, len(registry))
+
+	addPage := func(index int, filename string, width, height int) {
+		if hasTiles {
+			index = len(pageList)
+		}
+		pageList = append(pageList, &hibikemanga.ChapterPage{
+			Index:    index,
+			URL:      filepath.Join(chapterDir, filename),
+			Provider: provider,
+		})
+		pageDimensions[index] = &PageDimension{
+			Width:  width,
+			Height: height,
+		}
+	}
+
+	for _, index := range indices {
+		pageInfo := registry[index]
+		if len(pageInfo.Tiles) == 0 {
+			addPage(index, pageInfo.Filename, pageInfo.Width, pageInfo.Height)
+			continue
+		}
+		for _, tile := range pageInfo.Tiles {
+			addPage(index, tile.Filename, tile.Width, tile.Height)
+		}
+	}
+
+	return pageList, pageDimensions
+}
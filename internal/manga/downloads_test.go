@@ -0,0 +1,32 @@
+package manga
+
+import (
+	hibikemanga "github.com/5rahim/hibike/pkg/extension/manga"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"seanime/internal/manga/downloader"
+	"testing"
+)
+
+func TestGetDownloadedPages(t *testing.T) {
+	registry := chapter_downloader.Registry{
+		1: {Index: 1, Filename: "02.jpeg", Width: 100, Height: 400, Tiles: []chapter_downloader.TileInfo{
+			{Filename: "02_01.jpeg", Width: 100, Height: 150},
+			{Filename: "02_02.jpeg", Width: 100, Height: 150},
+			{Filename: "02_03.jpeg", Width: 100, Height: 100},
+		}},
+		0: {Index: 0, Filename: "01.jpeg", Width: 100, Height: 150},
+		2: {Index: 2, Filename: "03.png", Width: 100, Height: 150},
+	}
+
+	pages, dimensions := getDownloadedPages("comick_1_abc_1", "comick", registry)
+	assert.Equal(t, []string{"01.jpeg", "02_01.jpeg", "02_02.jpeg", "02_03.jpeg", "03.png"}, lo.Map(pages, func(p *hibikemanga.ChapterPage, _ int) string { return filepath.Base(p.URL) }))
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, lo.Map(pages, func(p *hibikemanga.ChapterPage, _ int) int { return p.Index }))
+	assert.Equal(t, 100, dimensions[3].Height)
+
+	// Pages without tiles keep their index
+	delete(registry, 1)
+	pages, _ = getDownloadedPages("comick_1_abc_1", "comick", registry)
+	assert.Equal(t, []int{0, 2}, lo.Map(pages, func(p *hibikemanga.ChapterPage, _ int) int { return p.Index }))
+}
@@ -0,0 +1,398 @@
+package manga
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"seanime/internal/api/anilist"
+	"seanime/internal/events"
+	"seanime/internal/manga/downloader"
+	"seanime/internal/manga/exporter"
+	"seanime/internal/manga/providers"
+	"seanime/internal/util"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+// Exports downloaded chapters as CBZ or EPUB files.
+// Export jobs are processed one at a time in the background and the resulting files are written to
+// the "exports" directory of the download directory, which is served under /manga-downloads/exports.
+//
+//	e.g. downloadDir/exports/Berserk - Vol. 1.cbz
+//	     downloadDir/exports/Berserk - Chapter 364.epub
+
+const (
+	ExportJobStatusQueued    ExportJobStatus = "queued"
+	ExportJobStatusRunning   ExportJobStatus = "running"
+	ExportJobStatusCompleted ExportJobStatus = "completed"
+	ExportJobStatusFailed    ExportJobStatus = "failed"
+
+	exportDirName = "exports"
+)
+
+var ErrNoChaptersToExport = errors.New("no downloaded chapters to export")
+
+type (
+	ExportJobStatus string
+
+	ExportJob struct {
+		ID            string                   `json:"id"`
+		MediaId       int                      `json:"mediaId"`
+		Provider      string                   `json:"provider"`
+		Title         string                   `json:"title"`
+		Format        manga_exporter.Format    `json:"format"`
+		GroupByVolume bool                     `json:"groupByVolume"`
+		Status        ExportJobStatus          `json:"status"`
+		Progress      int                      `json:"progress"` // Number of files written
+		Total         int                      `json:"total"`    // Number of files to write
+		Files         []string                 `json:"files"`    // Paths relative to the download directory, e.g. "exports/Berserk - Vol. 1.cbz"
+		Error         string                   `json:"error,omitempty"`
+		CreatedAt     time.Time                `json:"createdAt"`
+		chapters      []*exportChapter         `json:"-"`
+		metadata      *manga_exporter.Metadata `json:"-"`
+	}
+
+	ExportChaptersOptions struct {
+		MediaId  int
+		Provider string
+		// ChapterIds of the chapters to export, all downloaded chapters of the provider are exported if empty
+		ChapterIds    []string
+		Format        manga_exporter.Format
+		GroupByVolume bool
+		Media         *anilist.BaseManga
+	}
+
+	exportChapter struct {
+		id     string
+		number string
+		title  string
+		volume string
+		pages  []*manga_exporter.Page
+	}
+
+	exportQueue struct {
+		mu   sync.Mutex
+		jobs []*ExportJob
+		ch   chan *ExportJob
+	}
+)
+
+func newExportQueue() *exportQueue {
+	return &exportQueue{
+		jobs: make([]*ExportJob, 0),
+		ch:   make(chan *ExportJob, 100),
+	}
+}
+
+// ExportChapters adds an export job to the queue.
+func (d *Downloader) ExportChapters(opts *ExportChaptersOptions) (*ExportJob, error) {
+	if !manga_exporter.IsValidFormat(opts.Format) {
+		return nil, fmt.Errorf("unsupported format: %s", opts.Format)
+	}
+	if opts.Media == nil {
+		return nil, errors.New("media not found")
+	}
+
+	chapters, err := d.getExportChapters(opts.MediaId, opts.Provider, opts.ChapterIds)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &ExportJob{
+		ID:            uuid.NewString(),
+		MediaId:       opts.MediaId,
+		Provider:      opts.Provider,
+		Title:         opts.Media.GetPreferredTitle(),
+		Format:        opts.Format,
+		GroupByVolume: opts.GroupByVolume,
+		Status:        ExportJobStatusQueued,
+		Files:         make([]string, 0),
+		CreatedAt:     time.Now(),
+		chapters:      chapters,
+		metadata:      newExportMetadata(opts.Media),
+	}
+
+	d.exportQueue.mu.Lock()
+	d.exportQueue.jobs = append(d.exportQueue.jobs, job)
+	d.exportQueue.mu.Unlock()
+
+	select {
+	case d.exportQueue.ch <- job:
+	default:
+		d.setExportJobStatus(job, ExportJobStatusFailed, errors.New("too many export jobs"))
+		return nil, errors.New("too many export jobs")
+	}
+
+	d.logger.Debug().Str("id", job.ID).Int("mediaId", job.MediaId).Int("chapters", len(chapters)).Msg("manga downloader: Export job queued")
+	d.sendExportJobsEvent()
+
+	return job.copy(), nil
+}
+
+// GetExportJobs returns all the export jobs of the session, most recent first.
+func (d *Downloader) GetExportJobs() []*ExportJob {
+	d.exportQueue.mu.Lock()
+	defer d.exportQueue.mu.Unlock()
+
+	ret := make([]*ExportJob, 0, len(d.exportQueue.jobs))
+	for i := len(d.exportQueue.jobs) - 1; i >= 0; i-- {
+		ret = append(ret, d.exportQueue.jobs[i].copy())
+	}
+	return ret
+}
+
+// ClearFinishedExportJobs removes completed and failed jobs from the list.
+// The exported files are not deleted.
+func (d *Downloader) ClearFinishedExportJobs() {
+	d.exportQueue.mu.Lock()
+	d.exportQueue.jobs = lo.Filter(d.exportQueue.jobs, func(job *ExportJob, _ int) bool {
+		return job.Status == ExportJobStatusQueued || job.Status == ExportJobStatusRunning
+	})
+	d.exportQueue.mu.Unlock()
+
+	d.sendExportJobsEvent()
+}
+
+func (d *Downloader) startExportLoop() {
+	go func() {
+		for job := range d.exportQueue.ch {
+			d.runExportJob(job)
+		}
+	}()
+}
+
+func (d *Downloader) runExportJob(job *ExportJob) {
+	defer util.HandlePanicInModuleThen("manga/runExportJob", func() {
+		d.setExportJobStatus(job, ExportJobStatusFailed, errors.New("unexpected error"))
+	})
+
+	for _, ch := range job.chapters {
+		if err := d.hydrateExportChapterPages(job.Provider, job.MediaId, ch); err != nil {
+			d.logger.Error().Err(err).Str("chapterId", ch.id).Msg("manga downloader: Failed to read downloaded chapter")
+			d.setExportJobStatus(job, ExportJobStatusFailed, err)
+			return
+		}
+	}
+
+	books := groupExportChapters(job.metadata.SeriesTitle, job.chapters, job.GroupByVolume)
+
+	d.exportQueue.mu.Lock()
+	job.Total = len(books)
+	d.exportQueue.mu.Unlock()
+	d.setExportJobStatus(job, ExportJobStatusRunning, nil)
+
+	exportDir := filepath.Join(d.downloadDir, exportDirName)
+	if err := os.MkdirAll(exportDir, os.ModePerm); err != nil {
+		d.setExportJobStatus(job, ExportJobStatusFailed, err)
+		return
+	}
+
+	for _, book := range books {
+		filename := util.SanitizeFilename(book.Title) + "." + string(job.Format)
+		if err := writeExportFile(filepath.Join(exportDir, filename), job.Format, job.metadata, book); err != nil {
+			d.logger.Error().Err(err).Str("book", book.Title).Msg("manga downloader: Failed to write export file")
+			d.setExportJobStatus(job, ExportJobStatusFailed, err)
+			return
+		}
+
+		d.exportQueue.mu.Lock()
+		job.Progress++
+		job.Files = append(job.Files, exportDirName+"/"+filename)
+		d.exportQueue.mu.Unlock()
+		d.sendExportJobsEvent()
+	}
+
+	d.logger.Info().Str("id", job.ID).Int("files", len(books)).Msg("manga downloader: Export job completed")
+	d.setExportJobStatus(job, ExportJobStatusCompleted, nil)
+}
+
+// writeExportFile writes the book to a temporary file and renames it once it is complete.
+func writeExportFile(path string, format manga_exporter.Format, metadata *manga_exporter.Metadata, book *manga_exporter.Book) error {
+	tmpPath := path + ".part"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	err = manga_exporter.Write(f, format, metadata, book)
+	_ = f.Close()
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+func (d *Downloader) setExportJobStatus(job *ExportJob, status ExportJobStatus, err error) {
+	d.exportQueue.mu.Lock()
+	job.Status = status
+	if err != nil {
+		job.Error = err.Error()
+	}
+	if status == ExportJobStatusCompleted || status == ExportJobStatusFailed {
+		// Release the chapter list
+		job.chapters = nil
+	}
+	d.exportQueue.mu.Unlock()
+
+	d.sendExportJobsEvent()
+}
+
+func (d *Downloader) sendExportJobsEvent() {
+	d.wsEventManager.SendEvent(events.MangaExportUpdated, d.GetExportJobs())
+}
+
+func (j *ExportJob) copy() *ExportJob {
+	ret := *j
+	ret.Files = slices.Clone(j.Files)
+	ret.chapters = nil
+	return &ret
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// getExportChapters returns the downloaded chapters to export, sorted by chapter number.
+func (d *Downloader) getExportChapters(mediaId int, provider string, chapterIds []string) ([]*exportChapter, error) {
+	files, err := os.ReadDir(d.downloadDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Chapter details are stored in the permanent bucket when a chapter is downloaded
+	chapterContainer, found := d.repository.getChapterContainerFromPermanentFilecache(provider, mediaId)
+	if !found {
+		chapterContainer, _ = d.repository.getChapterContainerFromFilecache(provider, mediaId)
+	}
+
+	ret := make([]*exportChapter, 0)
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		id, ok := chapter_downloader.ParseChapterDirName(file.Name())
+		if !ok || id.MediaId != mediaId || id.Provider != provider {
+			continue
+		}
+		if len(chapterIds) > 0 && !slices.Contains(chapterIds, id.ChapterId) {
+			continue
+		}
+
+		ch := &exportChapter{
+			id:     id.ChapterId,
+			number: id.ChapterNumber,
+		}
+		if chapterContainer != nil {
+			if details, ok := chapterContainer.GetChapter(id.ChapterId); ok {
+				ch.title = details.Title
+				_, ch.volume = manga_providers.ParseLocalChapterName(details.Title)
+			}
+		}
+		ret = append(ret, ch)
+	}
+
+	if len(ret) == 0 {
+		return nil, ErrNoChaptersToExport
+	}
+
+	slices.SortStableFunc(ret, func(a, b *exportChapter) int {
+		return compareChapterNumbers(a.number, b.number)
+	})
+
+	return ret, nil
+}
+
+// groupExportChapters returns the books to write.
+// Chapters with a known volume are grouped together when groupByVolume is true, other chapters are exported individually.
+func groupExportChapters(seriesTitle string, chapters []*exportChapter, groupByVolume bool) []*manga_exporter.Book {
+	ret := make([]*manga_exporter.Book, 0)
+	volumes := make(map[string]*manga_exporter.Book)
+
+	for _, ch := range chapters {
+		if groupByVolume && ch.volume != "" {
+			book, ok := volumes[ch.volume]
+			if !ok {
+				book = &manga_exporter.Book{
+					Title:  fmt.Sprintf("%s - Vol. %s", seriesTitle, ch.volume),
+					Volume: ch.volume,
+				}
+				volumes[ch.volume] = book
+				ret = append(ret, book)
+			}
+			book.Chapters = append(book.Chapters, &manga_exporter.Chapter{Number: ch.number, Title: ch.title, Pages: ch.pages})
+			continue
+		}
+
+		ret = append(ret, &manga_exporter.Book{
+			Title:    fmt.Sprintf("%s - Chapter %s", seriesTitle, ch.number),
+			Volume:   ch.volume,
+			Number:   ch.number,
+			Chapters: []*manga_exporter.Chapter{{Number: ch.number, Title: ch.title, Pages: ch.pages}},
+		})
+	}
+
+	slices.SortStableFunc(ret, func(a, b *manga_exporter.Book) int {
+		return compareChapterNumbers(a.Chapters[0].Number, b.Chapters[0].Number)
+	})
+
+	return ret
+}
+
+// hydrateExportChapterPages reads the registry of the downloaded chapter and sets its pages.
+func (d *Downloader) hydrateExportChapterPages(provider string, mediaId int, ch *exportChapter) error {
+	pageContainer, err := d.repository.getDownloadedMangaPageContainer(provider, mediaId, ch.id)
+	if err != nil {
+		return err
+	}
+
+	ch.pages = make([]*manga_exporter.Page, 0, len(pageContainer.Pages))
+	for _, page := range pageContainer.Pages {
+		p := &manga_exporter.Page{
+			// e.g. downloadDir/comick_1234_abc_13/01.jpg
+			Path: filepath.Join(d.downloadDir, page.URL),
+		}
+		if dim, ok := pageContainer.PageDimensions[page.Index]; ok && dim != nil {
+			p.Width, p.Height = dim.Width, dim.Height
+		}
+		ch.pages = append(ch.pages, p)
+	}
+	return nil
+}
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+func newExportMetadata(media *anilist.BaseManga) *manga_exporter.Metadata {
+	ret := &manga_exporter.Metadata{
+		AnilistId:   media.ID,
+		SeriesTitle: media.GetPreferredTitle(),
+		Year:        media.GetStartYearSafe(),
+		Genres:      lo.FilterMap(media.Genres, func(g *string, _ int) (string, bool) { return lo.FromPtr(g), g != nil }),
+		Web:         lo.FromPtr(media.SiteURL),
+		RightToLeft: lo.FromPtr(media.CountryOfOrigin) == "JP",
+	}
+	if media.Description != nil {
+		// AniList descriptions contain HTML
+		desc := strings.ReplaceAll(*media.Description, "<br>", "\n")
+		ret.Summary = strings.TrimSpace(htmlTagRegex.ReplaceAllString(desc, ""))
+	}
+	return ret
+}
+
+// compareChapterNumbers compares chapter numbers numerically, e.g. "2" < "10.5".
+func compareChapterNumbers(a, b string) int {
+	af, errA := strconv.ParseFloat(a, 64)
+	bf, errB := strconv.ParseFloat(b, 64)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return cmp.Compare(af, bf)
+}
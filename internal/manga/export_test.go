@@ -0,0 +1,29 @@
+package manga
+
+import (
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"seanime/internal/manga/exporter"
+	"testing"
+)
+
+func TestGroupExportChapters(t *testing.T) {
+	chapters := []*exportChapter{
+		{id: "a", number: "1", volume: "1"},
+		{id: "b", number: "2", volume: "1"},
+		{id: "c", number: "3", volume: "2"},
+		{id: "d", number: "10", volume: ""},
+		{id: "e", number: "9.5", volume: ""},
+	}
+
+	books := groupExportChapters("Berserk", chapters, true)
+	assert.Equal(t, []string{"Berserk - Vol. 1", "Berserk - Vol. 2", "Berserk - Chapter 9.5", "Berserk - Chapter 10"}, lo.Map(books, func(b *manga_exporter.Book, _ int) string { return b.Title }))
+	assert.Len(t, books[0].Chapters, 2)
+	assert.Equal(t, "", books[0].Number)
+	assert.Equal(t, "10", books[3].Number)
+
+	books = groupExportChapters("Berserk", chapters, false)
+	assert.Len(t, books, 5)
+	assert.Equal(t, "Berserk - Chapter 1", books[0].Title)
+	assert.Equal(t, "1", books[0].Volume)
+}
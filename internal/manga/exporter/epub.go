@@ -0,0 +1,281 @@
+package manga_exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Default page size used in the viewport when the dimensions of a page are unknown
+const (
+	defaultPageWidth  = 800
+	defaultPageHeight = 1200
+)
+
+type epubPage struct {
+	ID        string // e.g. "p0001"
+	ImageHref string // e.g. "images/p0001.jpg"
+	PageHref  string // e.g. "pages/p0001.xhtml"
+	MediaType string
+	Width     int
+	Height    int
+}
+
+type epubNavPoint struct {
+	Title    string
+	PageHref string
+}
+
+// WriteEPUB writes the book as a fixed-layout EPUB 3 file.
+func WriteEPUB(w io.Writer, metadata *Metadata, book *Book) error {
+	zw := zip.NewWriter(w)
+
+	// The mimetype must be the first entry and must not be compressed
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(fw, "application/epub+zip"); err != nil {
+		return err
+	}
+
+	if err := writeZipString(zw, "META-INF/container.xml", epubContainer); err != nil {
+		return err
+	}
+
+	pages := make([]*epubPage, 0, book.PageCount())
+	navPoints := make([]*epubNavPoint, 0, len(book.Chapters))
+
+	for _, ch := range book.Chapters {
+		for pageIdx, page := range ch.Pages {
+			ext := strings.ToLower(filepath.Ext(page.Path))
+			id := fmt.Sprintf("p%04d", len(pages)+1)
+			p := &epubPage{
+				ID:        id,
+				ImageHref: "images/" + id + ext,
+				PageHref:  "pages/" + id + ".xhtml",
+				MediaType: imageMediaType(ext),
+				Width:     page.Width,
+				Height:    page.Height,
+			}
+			if p.Width <= 0 || p.Height <= 0 {
+				p.Width, p.Height = defaultPageWidth, defaultPageHeight
+			}
+
+			fw, err := zw.CreateHeader(&zip.FileHeader{Name: "OEBPS/" + p.ImageHref, Method: zip.Store})
+			if err != nil {
+				return err
+			}
+			if err := copyFile(fw, page.Path); err != nil {
+				return err
+			}
+
+			if err := writeZipTemplate(zw, "OEBPS/"+p.PageHref, epubPageTmpl, p); err != nil {
+				return err
+			}
+
+			if pageIdx == 0 {
+				navPoints = append(navPoints, &epubNavPoint{Title: chapterNavTitle(ch), PageHref: p.PageHref})
+			}
+			pages = append(pages, p)
+		}
+	}
+
+	if len(pages) == 0 {
+		return fmt.Errorf("no pages")
+	}
+
+	title := book.Title
+	if title == "" {
+		title = metadata.SeriesTitle
+	}
+
+	data := map[string]interface{}{
+		"Identifier":  fmt.Sprintf("urn:seanime:anilist:%d:%s:%s", metadata.AnilistId, book.Volume, book.Number),
+		"Title":       title,
+		"Series":      metadata.SeriesTitle,
+		"Volume":      book.Volume,
+		"Language":    epubLanguage(metadata),
+		"Description": metadata.Summary,
+		"Genres":      metadata.Genres,
+		"Modified":    time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"Direction":   "ltr",
+		"Pages":       pages,
+		"NavPoints":   navPoints,
+		"Width":       pages[0].Width,
+		"Height":      pages[0].Height,
+	}
+	if metadata.RightToLeft {
+		data["Direction"] = "rtl"
+	}
+
+	if err := writeZipTemplate(zw, "OEBPS/content.opf", epubContentTmpl, data); err != nil {
+		return err
+	}
+	if err := writeZipTemplate(zw, "OEBPS/nav.xhtml", epubNavTmpl, data); err != nil {
+		return err
+	}
+	if err := writeZipTemplate(zw, "OEBPS/toc.ncx", epubNcxTmpl, data); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func chapterNavTitle(ch *Chapter) string {
+	if ch.Title != "" {
+		return ch.Title
+	}
+	return "Chapter " + ch.Number
+}
+
+func epubLanguage(metadata *Metadata) string {
+	if metadata.LanguageISO != "" {
+		return metadata.LanguageISO
+	}
+	return "en"
+}
+
+func imageMediaType(ext string) string {
+	switch ext {
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	default:
+		return "image/jpeg"
+	}
+}
+
+func writeZipString(zw *zip.Writer, name string, content string) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(fw, content)
+	return err
+}
+
+func writeZipTemplate(zw *zip.Writer, name string, tmpl *template.Template, data interface{}) error {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(buf.Bytes())
+	return err
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// text/template is used with an explicit escape function since the output is XML
+var epubFuncs = template.FuncMap{"esc": html.EscapeString}
+
+var epubPageTmpl = template.Must(template.New("page").Funcs(epubFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head>
+  <title>{{.ID}}</title>
+  <meta name="viewport" content="width={{.Width}}, height={{.Height}}"/>
+  <style>html, body { margin: 0; padding: 0; } img { width: 100%; height: 100%; object-fit: contain; }</style>
+</head>
+<body>
+  <img src="../{{esc .ImageHref}}" alt=""/>
+</body>
+</html>
+`))
+
+var epubContentTmpl = template.Must(template.New("content").Funcs(epubFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="{{esc .Language}}">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">{{esc .Identifier}}</dc:identifier>
+    <dc:title>{{esc .Title}}</dc:title>
+    <dc:language>{{esc .Language}}</dc:language>
+    {{- if .Description}}
+    <dc:description>{{esc .Description}}</dc:description>
+    {{- end}}
+    {{- range .Genres}}
+    <dc:subject>{{esc .}}</dc:subject>
+    {{- end}}
+    <meta property="dcterms:modified">{{.Modified}}</meta>
+    {{- if .Series}}
+    <meta property="belongs-to-collection" id="series">{{esc .Series}}</meta>
+    <meta refines="#series" property="collection-type">series</meta>
+    {{- if .Volume}}
+    <meta refines="#series" property="group-position">{{esc .Volume}}</meta>
+    {{- end}}
+    {{- end}}
+    <meta property="rendition:layout">pre-paginated</meta>
+    <meta property="rendition:spread">none</meta>
+    <meta name="cover" content="img-{{(index .Pages 0).ID}}"/>
+    <meta name="original-resolution" content="{{.Width}}x{{.Height}}"/>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    {{- range $i, $p := .Pages}}
+    <item id="img-{{$p.ID}}" href="{{esc $p.ImageHref}}" media-type="{{$p.MediaType}}"{{if eq $i 0}} properties="cover-image"{{end}}/>
+    <item id="{{$p.ID}}" href="{{esc $p.PageHref}}" media-type="application/xhtml+xml"/>
+    {{- end}}
+  </manifest>
+  <spine toc="ncx" page-progression-direction="{{.Direction}}">
+    {{- range .Pages}}
+    <itemref idref="{{.ID}}"/>
+    {{- end}}
+  </spine>
+</package>
+`))
+
+var epubNavTmpl = template.Must(template.New("nav").Funcs(epubFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head>
+  <title>{{esc .Title}}</title>
+</head>
+<body>
+  <nav epub:type="toc" id="toc">
+    <ol>
+      {{- range .NavPoints}}
+      <li><a href="{{esc .PageHref}}">{{esc .Title}}</a></li>
+      {{- end}}
+    </ol>
+  </nav>
+</body>
+</html>
+`))
+
+var epubNcxTmpl = template.Must(template.New("ncx").Funcs(epubFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head>
+    <meta name="dtb:uid" content="{{esc .Identifier}}"/>
+  </head>
+  <docTitle><text>{{esc .Title}}</text></docTitle>
+  <navMap>
+    {{- range $i, $n := .NavPoints}}
+    <navPoint id="nav{{$i}}" playOrder="{{$i}}">
+      <navLabel><text>{{esc $n.Title}}</text></navLabel>
+      <content src="{{esc $n.PageHref}}"/>
+    </navPoint>
+    {{- end}}
+  </navMap>
+</ncx>
+`))
@@ -0,0 +1,204 @@
+package manga_exporter
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Packages downloaded chapters into files that can be read by comic readers and e-readers.
+//
+//	CBZ: The pages of each chapter are stored in order, with a ComicInfo.xml file describing the book.
+//	EPUB: Fixed-layout EPUB 3 with one page per image and a navigation entry per chapter.
+
+const (
+	FormatCBZ  Format = "cbz"
+	FormatEPUB Format = "epub"
+)
+
+type (
+	Format string
+
+	// Metadata describes the series, it is filled from AniList.
+	Metadata struct {
+		AnilistId   int
+		SeriesTitle string
+		Summary     string
+		Year        int
+		Genres      []string
+		Web         string
+		LanguageISO string
+		RightToLeft bool // Japanese manga are read from right to left
+	}
+
+	// Book is an exported file, either a single chapter or a volume.
+	Book struct {
+		Title    string
+		Volume   string // Empty if unknown
+		Number   string // Chapter number, empty for a volume
+		Chapters []*Chapter
+	}
+
+	Chapter struct {
+		Number string
+		Title  string
+		Pages  []*Page
+	}
+
+	Page struct {
+		Path   string // Path of the image on disk
+		Width  int
+		Height int
+	}
+)
+
+func IsValidFormat(format Format) bool {
+	return format == FormatCBZ || format == FormatEPUB
+}
+
+// Write writes the book in the given format.
+func Write(w io.Writer, format Format, metadata *Metadata, book *Book) error {
+	switch format {
+	case FormatCBZ:
+		return WriteCBZ(w, metadata, book)
+	case FormatEPUB:
+		return WriteEPUB(w, metadata, book)
+	}
+	return fmt.Errorf("unsupported format: %s", format)
+}
+
+// PageCount returns the number of pages of the book.
+func (b *Book) PageCount() int {
+	ret := 0
+	for _, ch := range b.Chapters {
+		ret += len(ch.Pages)
+	}
+	return ret
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// CBZ
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ComicInfo follows the ComicInfo.xml schema (v2.0) used by comic readers.
+// See https://anansi-project.github.io/docs/comicinfo/schemas/v2.0
+type ComicInfo struct {
+	XMLName     xml.Name        `xml:"ComicInfo"`
+	XmlnsXsi    string          `xml:"xmlns:xsi,attr"`
+	XmlnsXsd    string          `xml:"xmlns:xsd,attr"`
+	Title       string          `xml:"Title,omitempty"`
+	Series      string          `xml:"Series,omitempty"`
+	Number      string          `xml:"Number,omitempty"`
+	Volume      string          `xml:"Volume,omitempty"`
+	Summary     string          `xml:"Summary,omitempty"`
+	Year        int             `xml:"Year,omitempty"`
+	Genre       string          `xml:"Genre,omitempty"`
+	Web         string          `xml:"Web,omitempty"`
+	PageCount   int             `xml:"PageCount"`
+	LanguageISO string          `xml:"LanguageISO,omitempty"`
+	Manga       string          `xml:"Manga,omitempty"`
+	Pages       *ComicInfoPages `xml:"Pages,omitempty"`
+}
+
+type ComicInfoPages struct {
+	Pages []*ComicInfoPage `xml:"Page"`
+}
+
+type ComicInfoPage struct {
+	Image       int    `xml:"Image,attr"`
+	Type        string `xml:"Type,attr,omitempty"`
+	ImageWidth  int    `xml:"ImageWidth,attr,omitempty"`
+	ImageHeight int    `xml:"ImageHeight,attr,omitempty"`
+}
+
+// NewComicInfo returns the ComicInfo of the book.
+func NewComicInfo(metadata *Metadata, book *Book) *ComicInfo {
+	ret := &ComicInfo{
+		XmlnsXsi:    "http://www.w3.org/2001/XMLSchema-instance",
+		XmlnsXsd:    "http://www.w3.org/2001/XMLSchema",
+		Title:       book.Title,
+		Series:      metadata.SeriesTitle,
+		Number:      book.Number,
+		Volume:      book.Volume,
+		Summary:     metadata.Summary,
+		Year:        metadata.Year,
+		Genre:       strings.Join(metadata.Genres, ", "),
+		Web:         metadata.Web,
+		PageCount:   book.PageCount(),
+		LanguageISO: metadata.LanguageISO,
+		Manga:       "Yes",
+		Pages:       &ComicInfoPages{Pages: make([]*ComicInfoPage, 0, book.PageCount())},
+	}
+	if metadata.RightToLeft {
+		ret.Manga = "YesAndRightToLeft"
+	}
+
+	idx := 0
+	for _, ch := range book.Chapters {
+		for _, page := range ch.Pages {
+			p := &ComicInfoPage{
+				Image:       idx,
+				ImageWidth:  page.Width,
+				ImageHeight: page.Height,
+			}
+			if idx == 0 {
+				p.Type = "FrontCover"
+			}
+			ret.Pages.Pages = append(ret.Pages.Pages, p)
+			idx++
+		}
+	}
+
+	return ret
+}
+
+// WriteCBZ writes the pages and the ComicInfo.xml file to a zip archive.
+// The pages are renamed so that they are sorted by chapter and page.
+//
+//	e.g. "0001_0001.jpg", "0001_0002.jpg", "0002_0001.jpg"
+func WriteCBZ(w io.Writer, metadata *Metadata, book *Book) error {
+	zw := zip.NewWriter(w)
+
+	for chIdx, ch := range book.Chapters {
+		for pageIdx, page := range ch.Pages {
+			name := fmt.Sprintf("%04d_%04d%s", chIdx+1, pageIdx+1, strings.ToLower(filepath.Ext(page.Path)))
+			// Images are already compressed
+			fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+			if err != nil {
+				return err
+			}
+			if err := copyFile(fw, page.Path); err != nil {
+				return err
+			}
+		}
+	}
+
+	fw, err := zw.Create("ComicInfo.xml")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(fw, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(fw)
+	enc.Indent("", "  ")
+	if err := enc.Encode(NewComicInfo(metadata, book)); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
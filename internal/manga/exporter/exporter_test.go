@@ -0,0 +1,110 @@
+package manga_exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteCBZ(t *testing.T) {
+	book := newTestBook(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCBZ(&buf, testMetadata, book))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	names := make([]string, 0)
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"0001_0001.jpg", "0001_0002.png", "0002_0001.jpg", "ComicInfo.xml"}, names)
+	assert.Equal(t, "c1p2", readZipFile(t, zr, "0001_0002.png"))
+
+	var info ComicInfo
+	require.NoError(t, xml.Unmarshal([]byte(readZipFile(t, zr, "ComicInfo.xml")), &info))
+	assert.Equal(t, "Berserk", info.Series)
+	assert.Equal(t, "Berserk - Vol. 1", info.Title)
+	assert.Equal(t, "1", info.Volume)
+	assert.Equal(t, "Action, Drama", info.Genre)
+	assert.Equal(t, 1989, info.Year)
+	assert.Equal(t, "YesAndRightToLeft", info.Manga)
+	assert.Equal(t, 3, info.PageCount)
+	require.Len(t, info.Pages.Pages, 3)
+	assert.Equal(t, "FrontCover", info.Pages.Pages[0].Type)
+	assert.Equal(t, 800, info.Pages.Pages[0].ImageWidth)
+}
+
+func TestWriteEPUB(t *testing.T) {
+	book := newTestBook(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteEPUB(&buf, testMetadata, book))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	// The mimetype must be the first entry, uncompressed
+	require.NotEmpty(t, zr.File)
+	assert.Equal(t, "mimetype", zr.File[0].Name)
+	assert.Equal(t, zip.Store, zr.File[0].Method)
+	assert.Equal(t, "application/epub+zip", readZipFile(t, zr, "mimetype"))
+
+	assert.Equal(t, "c2p1", readZipFile(t, zr, "OEBPS/images/p0003.jpg"))
+
+	opf := readZipFile(t, zr, "OEBPS/content.opf")
+	assert.Contains(t, opf, `page-progression-direction="rtl"`)
+	assert.Contains(t, opf, `<dc:title>Berserk - Vol. 1</dc:title>`)
+	assert.Contains(t, opf, `properties="cover-image"`)
+	assert.Contains(t, opf, `media-type="image/png"`)
+	// Metadata is escaped
+	assert.Contains(t, opf, `Guts &amp; Griffith`)
+
+	nav := readZipFile(t, zr, "OEBPS/nav.xhtml")
+	assert.Contains(t, nav, `<a href="pages/p0001.xhtml">Chapter 1</a>`)
+	assert.Contains(t, nav, `<a href="pages/p0003.xhtml">Chapter 2 - The Guardians of Desire</a>`)
+}
+
+var testMetadata = &Metadata{
+	AnilistId:   30002,
+	SeriesTitle: "Berserk",
+	Summary:     "Guts & Griffith",
+	Year:        1989,
+	Genres:      []string{"Action", "Drama"},
+	RightToLeft: true,
+}
+
+// newTestBook creates a volume with two chapters, each page contains its name.
+func newTestBook(t *testing.T) *Book {
+	dir := t.TempDir()
+	page := func(name string, width, height int) *Page {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(name[:len(name)-len(filepath.Ext(name))]), 0644))
+		return &Page{Path: path, Width: width, Height: height}
+	}
+
+	return &Book{
+		Title:  "Berserk - Vol. 1",
+		Volume: "1",
+		Chapters: []*Chapter{
+			{Number: "1", Pages: []*Page{page("c1p1.jpg", 800, 1200), page("c1p2.png", 800, 1200)}},
+			{Number: "2", Title: "Chapter 2 - The Guardians of Desire", Pages: []*Page{page("c2p1.jpg", 0, 0)}},
+		},
+	}
+}
+
+func readZipFile(t *testing.T, zr *zip.Reader, name string) string {
+	f, err := zr.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return string(data)
+}
@@ -0,0 +1,299 @@
+package manga_imagecache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Cache is a disk cache of images with a size limit.
+// The least recently used images are removed when the limit is exceeded.
+// The modification time of the files is used as the access time so that the order is kept across restarts.
+//
+//	e.g. cacheDir/manga-images/3b5d...e1 (sha256 of the key)
+type Cache struct {
+	logger  *zerolog.Logger
+	dir     string
+	maxSize int64
+
+	mu      sync.Mutex
+	size    int64
+	entries map[string]*list.Element // key: filename
+	lru     *list.List               // Front is the most recently used
+	hits    int64
+	misses  int64
+}
+
+type (
+	cacheEntry struct {
+		name string
+		size int64
+	}
+
+	Stats struct {
+		Size    int64 `json:"size"`    // Total size in bytes
+		MaxSize int64 `json:"maxSize"` // Size limit in bytes
+		Count   int   `json:"count"`   // Number of images
+		Hits    int64 `json:"hits"`    // Since the server started
+		Misses  int64 `json:"misses"`  // Since the server started
+	}
+
+	NewCacheOptions struct {
+		Logger  *zerolog.Logger
+		Dir     string
+		MaxSize int64
+	}
+)
+
+func NewCache(opts *NewCacheOptions) *Cache {
+	c := &Cache{
+		logger:  opts.Logger,
+		dir:     opts.Dir,
+		maxSize: opts.MaxSize,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+
+	_ = os.MkdirAll(c.dir, os.ModePerm)
+	c.load()
+
+	return c
+}
+
+// load reads the cache directory, files are ordered by modification time.
+func (c *Cache) load() {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("manga image cache: Failed to read cache directory")
+		return
+	}
+
+	type fileInfo struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	infos := make([]*fileInfo, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(file.Name(), ".tmp") {
+			// Interrupted write
+			_ = os.Remove(filepath.Join(c.dir, file.Name()))
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, &fileInfo{name: file.Name(), size: info.Size(), modTime: info.ModTime()})
+	}
+
+	// Most recent first
+	slices.SortFunc(infos, func(a, b *fileInfo) int {
+		return b.modTime.Compare(a.modTime)
+	})
+
+	c.mu.Lock()
+	for _, info := range infos {
+		c.entries[info.name] = c.lru.PushBack(&cacheEntry{name: info.name, size: info.size})
+		c.size += info.size
+	}
+	evicted := c.evict()
+	c.mu.Unlock()
+
+	c.removeFiles(evicted)
+}
+
+// Get returns the cached image.
+// The lock is only held to update the index, files are read and written without it.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	name := cacheFilename(key)
+	path := filepath.Join(c.dir, name)
+
+	c.mu.Lock()
+	_, ok := c.entries[name]
+	if !ok {
+		c.misses++
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.mu.Unlock()
+
+	data, err := os.ReadFile(path)
+
+	c.mu.Lock()
+	el, ok := c.entries[name]
+	if err != nil {
+		// The file was removed
+		if ok {
+			c.remove(el)
+		}
+		c.misses++
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.hits++
+	// The entry can have been evicted while the file was read
+	if ok {
+		c.lru.MoveToFront(el)
+	}
+	c.mu.Unlock()
+
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	return data, true
+}
+
+// Set adds the image to the cache and removes the least recently used images if the size limit is exceeded.
+func (c *Cache) Set(key string, data []byte) error {
+	name := cacheFilename(key)
+
+	c.mu.Lock()
+	maxSize := c.maxSize
+	c.mu.Unlock()
+
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil
+	}
+
+	// The temporary file is unique so that concurrent writes of the same image do not conflict
+	tmpFile, err := os.CreateTemp(c.dir, name+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	// The file is renamed while the lock is held so that removeFiles cannot delete it before it is indexed
+	c.mu.Lock()
+	if err := os.Rename(tmpPath, filepath.Join(c.dir, name)); err != nil {
+		c.mu.Unlock()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if el, ok := c.entries[name]; ok {
+		c.size -= el.Value.(*cacheEntry).size
+		c.lru.Remove(el)
+	}
+	c.entries[name] = c.lru.PushFront(&cacheEntry{name: name, size: int64(len(data))})
+	c.size += int64(len(data))
+	evicted := c.evict()
+	c.mu.Unlock()
+
+	c.removeFiles(evicted)
+
+	return nil
+}
+
+// SetMaxSize changes the size limit, 0 means no limit.
+func (c *Cache) SetMaxSize(maxSize int64) {
+	c.mu.Lock()
+	c.maxSize = maxSize
+	evicted := c.evict()
+	c.mu.Unlock()
+
+	c.removeFiles(evicted)
+}
+
+func (c *Cache) Stats() *Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return &Stats{
+		Size:    c.size,
+		MaxSize: c.maxSize,
+		Count:   len(c.entries),
+		Hits:    c.hits,
+		Misses:  c.misses,
+	}
+}
+
+// Purge removes all the cached images.
+// The directory is kept so that the temporary files of the images being written are not removed.
+func (c *Cache) Purge() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() || strings.HasSuffix(file.Name(), ".tmp") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, file.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.size = 0
+
+	c.logger.Info().Msg("manga image cache: Purged")
+
+	return nil
+}
+
+// evict removes the least recently used images from the index until the size is under the limit.
+// It returns the names of the files to remove with removeFiles once the mutex is released.
+// The mutex must be held.
+func (c *Cache) evict() []string {
+	if c.maxSize <= 0 {
+		return nil
+	}
+	var ret []string
+	for c.size > c.maxSize && c.lru.Len() > 0 {
+		ret = append(ret, c.remove(c.lru.Back()))
+	}
+	if len(ret) > 0 {
+		c.logger.Debug().Int("count", len(ret)).Msg("manga image cache: Evicted images")
+	}
+	return ret
+}
+
+// remove removes the entry from the index and returns its filename.
+// The mutex must be held.
+func (c *Cache) remove(el *list.Element) string {
+	entry := el.Value.(*cacheEntry)
+	c.lru.Remove(el)
+	delete(c.entries, entry.name)
+	c.size -= entry.size
+	return entry.name
+}
+
+// removeFiles deletes the files of removed entries.
+// A file is skipped if its image was added again since it was removed from the index.
+// The mutex should not be held.
+func (c *Cache) removeFiles(names []string) {
+	for _, name := range names {
+		c.mu.Lock()
+		if _, ok := c.entries[name]; !ok {
+			_ = os.Remove(filepath.Join(c.dir, name))
+		}
+		c.mu.Unlock()
+	}
+}
+
+func cacheFilename(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
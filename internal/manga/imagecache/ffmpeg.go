@@ -0,0 +1,116 @@
+package manga_imagecache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"seanime/internal/util"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebP and AVIF images are encoded with FFmpeg, it needs to be built with libwebp and libaom.
+// The output is written to a temporary file since the AVIF muxer cannot write to a pipe.
+
+const ffmpegTimeout = 30 * time.Second
+
+var (
+	ErrAVIFDecodeUnsupported = errors.New("avif: decoding is not supported")
+	ErrFfmpegEncode          = errors.New("ffmpeg: failed to encode image")
+)
+
+func init() {
+	// Only the dimensions of AVIF images can be read, e.g. to get the size of a downloaded page
+	image.RegisterFormat(FormatAVIF, "????ftypavif", decodeAVIF, decodeAVIFConfig)
+}
+
+// encodeWebP encodes the image as lossy WebP, the alpha channel is kept.
+func encodeWebP(img image.Image, quality int, ffmpegPath string) ([]byte, error) {
+	return encodeWithFfmpeg(img, ffmpegPath, FormatWebP,
+		"-c:v", "libwebp",
+		"-quality", strconv.Itoa(quality),
+	)
+}
+
+// encodeAVIF encodes the image as a still AVIF image.
+// The quality is mapped to the CRF of libaom, from 63 (quality 1) to 0 (quality 100).
+func encodeAVIF(img image.Image, quality int, ffmpegPath string) ([]byte, error) {
+	crf := (100 - quality) * 63 / 99
+	return encodeWithFfmpeg(img, ffmpegPath, FormatAVIF,
+		"-c:v", "libaom-av1",
+		"-still-picture", "1",
+		"-crf", strconv.Itoa(crf),
+		"-cpu-used", "6",
+		"-row-mt", "1",
+		"-pix_fmt", "yuv420p",
+	)
+}
+
+// encodeWithFfmpeg pipes the image to FFmpeg as PNG and returns the encoded image.
+func encodeWithFfmpeg(img image.Image, ffmpegPath string, format string, codecArgs ...string) ([]byte, error) {
+	if ffmpegPath == "" {
+		ffmpegPath = "ffmpeg"
+	}
+
+	var input bytes.Buffer
+	if err := png.Encode(&input, img); err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "seanime-manga-image-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	outputPath := filepath.Join(tmpDir, "page."+format)
+
+	args := []string{"-hide_banner", "-loglevel", "error", "-f", "png_pipe", "-i", "pipe:0", "-frames:v", "1"}
+	args = append(args, codecArgs...)
+	args = append(args, "-f", format, "-y", outputPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), ffmpegTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := util.NewCmdCtx(ctx, ffmpegPath, args...)
+	cmd.Stdin = &input
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w as %s: %w: %s", ErrFfmpegEncode, format, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return os.ReadFile(outputPath)
+}
+
+func decodeAVIF(io.Reader) (image.Image, error) {
+	return nil, ErrAVIFDecodeUnsupported
+}
+
+// decodeAVIFConfig reads the dimensions of an AVIF image from its image spatial extents ('ispe') property.
+func decodeAVIFConfig(r io.Reader) (image.Config, error) {
+	// The properties are stored in the meta box, before the image data
+	header, err := io.ReadAll(io.LimitReader(r, 64*1024))
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	// Box type, then version and flags, width and height
+	i := bytes.Index(header, []byte("ispe"))
+	if i < 0 || len(header) < i+16 {
+		return image.Config{}, errors.New("avif: missing image dimensions")
+	}
+
+	return image.Config{
+		Width:  int(binary.BigEndian.Uint32(header[i+8:])),
+		Height: int(binary.BigEndian.Uint32(header[i+12:])),
+	}, nil
+}
@@ -0,0 +1,213 @@
+package manga_imagecache
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"seanime/internal/util"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(&NewCacheOptions{Logger: util.NewLogger(), Dir: dir, MaxSize: 10})
+
+	require.NoError(t, cache.Set("a", []byte("aaaa")))
+	require.NoError(t, cache.Set("b", []byte("bbbb")))
+
+	// "a" becomes the most recently used
+	data, ok := cache.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, "aaaa", string(data))
+
+	// "b" is evicted
+	require.NoError(t, cache.Set("c", []byte("cccc")))
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	_, ok = cache.Get("c")
+	assert.True(t, ok)
+
+	// Larger than the limit
+	require.NoError(t, cache.Set("d", []byte(strings.Repeat("d", 11))))
+	_, ok = cache.Get("d")
+	assert.False(t, ok)
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(8), stats.Size)
+	assert.Equal(t, 2, stats.Count)
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(2), stats.Misses)
+
+	// The cache is restored from the directory
+	time.Sleep(10 * time.Millisecond)
+	_, _ = cache.Get("a")
+	cache = NewCache(&NewCacheOptions{Logger: util.NewLogger(), Dir: dir, MaxSize: 4})
+	assert.Equal(t, 1, cache.Stats().Count)
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+
+	require.NoError(t, cache.Purge())
+	assert.Equal(t, 0, cache.Stats().Count)
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+}
+
+// Concurrent reads and writes of the same images, run with -race.
+func TestCache_Concurrent(t *testing.T) {
+	cache := NewCache(&NewCacheOptions{Logger: util.NewLogger(), Dir: t.TempDir(), MaxSize: 40})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				key := strconv.Itoa((i + j) % 20)
+				if data, ok := cache.Get(key); ok {
+					assert.Equal(t, "image "+key, string(data))
+					continue
+				}
+				assert.NoError(t, cache.Set(key, []byte("image "+key)))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats := cache.Stats()
+	assert.LessOrEqual(t, stats.Size, int64(40))
+	assert.Equal(t, int64(8*50), stats.Hits+stats.Misses)
+
+	// Files of images added again after being evicted are not removed
+	for name := range cache.entries {
+		_, err := os.Stat(filepath.Join(cache.dir, name))
+		assert.NoError(t, err)
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	data := newTestImage(t, 2000, 1000)
+
+	res, err := Optimize(data, &OptimizeOptions{MaxWidth: 1000})
+	require.NoError(t, err)
+	config, format, err := image.DecodeConfig(bytes.NewReader(res))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 1000, config.Width)
+	assert.Equal(t, 500, config.Height)
+
+	_, err = Optimize([]byte("not an image"), &OptimizeOptions{})
+	assert.Error(t, err)
+}
+
+func TestOptimize_Formats(t *testing.T) {
+	data := newTestImage(t, 200, 100)
+
+	// WebP and AVIF are never silently encoded as JPEG
+	_, err := Optimize(data, &OptimizeOptions{Format: FormatWebP, FfmpegPath: filepath.Join(t.TempDir(), "ffmpeg")})
+	assert.ErrorIs(t, err, ErrFfmpegEncode)
+
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not found")
+	}
+
+	for _, format := range []string{FormatWebP, FormatAVIF} {
+		res, err := Optimize(data, &OptimizeOptions{MaxWidth: 100, Format: format})
+		require.NoError(t, err, format)
+		config, decodedFormat, err := image.DecodeConfig(bytes.NewReader(res))
+		require.NoError(t, err, format)
+		assert.Equal(t, format, decodedFormat)
+		assert.Equal(t, 100, config.Width)
+		assert.Equal(t, 50, config.Height)
+	}
+}
+
+func TestDecodeAVIFConfig(t *testing.T) {
+	// ftyp box followed by an ispe property
+	data := []byte("\x00\x00\x00\x14ftypavif\x00\x00\x00\x00mif1" +
+		"\x00\x00\x00\x14ispe\x00\x00\x00\x00\x00\x00\x02\x58\x00\x00\x03\x84")
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "avif", format)
+	assert.Equal(t, 600, config.Width)
+	assert.Equal(t, 900, config.Height)
+
+	_, _, err = image.Decode(bytes.NewReader(data))
+	assert.ErrorIs(t, err, ErrAVIFDecodeUnsupported)
+}
+
+func TestSplitTiles(t *testing.T) {
+	// Not tall enough
+	tiles, err := SplitTiles(newTestImage(t, 100, 300), &OptimizeOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, tiles)
+
+	tiles, err = SplitTiles(newTestImage(t, 100, 400), &OptimizeOptions{})
+	require.NoError(t, err)
+	require.Len(t, tiles, 3)
+
+	heights := make([]int, 0)
+	for _, tile := range tiles {
+		config, _, err := image.DecodeConfig(bytes.NewReader(tile))
+		require.NoError(t, err)
+		assert.Equal(t, 100, config.Width)
+		heights = append(heights, config.Height)
+	}
+	assert.Equal(t, []int{150, 150, 100}, heights)
+}
+
+func TestPipeline(t *testing.T) {
+	fetched := 0
+	p := NewPipeline(&NewPipelineOptions{Logger: util.NewLogger(), CacheDir: t.TempDir()})
+	p.fetchImage = func(url string, headers map[string]string) ([]byte, error) {
+		fetched++
+		return newTestImage(t, 2000, 1000), nil
+	}
+
+	// Original image
+	data, err := p.GetImage("https://example.com/1.png", nil)
+	require.NoError(t, err)
+	_, format, _ := image.DecodeConfig(bytes.NewReader(data))
+	assert.Equal(t, "png", format)
+
+	_, err = p.GetImage("https://example.com/1.png", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, fetched)
+
+	// Optimized images are cached separately
+	p.SetSettings(&Settings{OptimizeImages: true, MaxWidth: 500})
+	data, err = p.GetImage("https://example.com/1.png", nil)
+	require.NoError(t, err)
+	config, format, _ := image.DecodeConfig(bytes.NewReader(data))
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 500, config.Width)
+	assert.Equal(t, 2, fetched)
+	assert.Equal(t, 2, p.GetCacheStats().Count)
+
+	// Downloaded webtoon strip
+	p.SetSettings(&Settings{SplitTallImages: true})
+	assert.Len(t, p.ProcessDownloadedImage(newTestImage(t, 100, 400)), 3)
+	assert.Len(t, p.ProcessDownloadedImage(newTestImage(t, 100, 100)), 1)
+}
+
+func newTestImage(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
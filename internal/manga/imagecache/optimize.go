@@ -0,0 +1,142 @@
+package manga_imagecache
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif" // Register GIF format
+	"image/jpeg"
+	_ "image/png" // Register PNG format
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Register WebP format
+)
+
+// Images are re-encoded as JPEG by default, which every reader supports.
+// The standard library and golang.org/x/image cannot encode WebP or AVIF, FFmpeg is used for these formats (see ffmpeg.go).
+
+const (
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+	FormatAVIF = "avif"
+
+	DefaultQuality = 80
+
+	// An image is a webtoon strip if its height is more than tallImageRatio times its width.
+	tallImageRatio = 3
+	// Tiles of a strip have a height of tileRatio times the width, the last tile can be shorter.
+	tileRatio = 1.5
+)
+
+type OptimizeOptions struct {
+	MaxWidth   int    // Wider images are downscaled, 0 means no limit
+	Quality    int    // Quality from 1 to 100, DefaultQuality if 0
+	Format     string // FormatJPEG, FormatWebP or FormatAVIF, FormatJPEG if empty
+	FfmpegPath string // Used to encode WebP and AVIF, "ffmpeg" if empty
+}
+
+// Optimize downscales and re-encodes the image.
+// The original data is returned if it is smaller than the re-encoded image and the image was not downscaled.
+func Optimize(data []byte, opts *OptimizeOptions) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	resized := resize(img, opts.MaxWidth)
+
+	ret, err := encode(resized, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if resized == img && len(ret) >= len(data) {
+		return data, nil
+	}
+	return ret, nil
+}
+
+// IsTallImage returns true if the image is a webtoon strip that should be split into tiles.
+func IsTallImage(width, height int) bool {
+	return width > 0 && height > width*tallImageRatio
+}
+
+// SplitTiles splits a webtoon strip into page-sized tiles from top to bottom.
+// It returns nil if the image is not tall enough to be split.
+func SplitTiles(data []byte, opts *OptimizeOptions) ([][]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if !IsTallImage(config.Width, config.Height) {
+		return nil, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	img = resize(img, opts.MaxWidth)
+
+	bounds := img.Bounds()
+	tileHeight := int(float64(bounds.Dx()) * tileRatio)
+
+	ret := make([][]byte, 0, bounds.Dy()/tileHeight+1)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += tileHeight {
+		rect := image.Rect(bounds.Min.X, y, bounds.Max.X, min(y+tileHeight, bounds.Max.Y))
+		tile := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+		draw.Draw(tile, tile.Bounds(), img, rect.Min, draw.Src)
+
+		buf, err := encode(tile, opts)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, buf)
+	}
+
+	return ret, nil
+}
+
+// resize downscales the image to maxWidth, keeping the aspect ratio.
+// The same image is returned if it is not wider than maxWidth.
+func resize(img image.Image, maxWidth int) image.Image {
+	bounds := img.Bounds()
+	if maxWidth <= 0 || bounds.Dx() <= maxWidth {
+		return img
+	}
+
+	height := bounds.Dy() * maxWidth / bounds.Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, maxWidth, max(height, 1)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// encode encodes the image in the format of the options.
+func encode(img image.Image, opts *OptimizeOptions) ([]byte, error) {
+	quality := opts.Quality
+	if quality <= 0 || quality > 100 {
+		quality = DefaultQuality
+	}
+
+	switch opts.Format {
+	case FormatWebP:
+		return encodeWebP(img, quality, opts.FfmpegPath)
+	case FormatAVIF:
+		return encodeAVIF(flatten(img), quality, opts.FfmpegPath)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// flatten draws transparent areas on a white background, for formats without an alpha channel.
+func flatten(img image.Image) image.Image {
+	bounds := img.Bounds()
+	ret := image.NewRGBA(bounds)
+	draw.Draw(ret, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(ret, bounds, img, bounds.Min, draw.Over)
+	return ret
+}
@@ -0,0 +1,181 @@
+package manga_imagecache
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"seanime/internal/manga/providers"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// Pipeline fetches page images through the disk cache and optimizes them.
+//
+//	Reading: GetImage returns the cached page, or fetches, optimizes (if enabled) and caches it.
+//	Downloading: ProcessDownloadedImage optimizes a downloaded page and splits webtoon strips into tiles (if enabled).
+
+const DefaultCacheSizeLimit int64 = 500 * 1024 * 1024
+
+type (
+	Pipeline struct {
+		logger   *zerolog.Logger
+		cache    *Cache
+		mu       sync.RWMutex
+		settings *Settings
+		// ffmpegPath is the FFmpeg binary of the media streaming settings, used to encode WebP and AVIF
+		ffmpegPath string
+		// fetchImage is replaced in tests
+		fetchImage func(url string, headers map[string]string) ([]byte, error)
+	}
+
+	Settings struct {
+		CacheSizeLimit  int64 // In bytes, DefaultCacheSizeLimit if 0
+		OptimizeImages  bool  // Downscale and re-encode pages
+		MaxWidth        int
+		Quality         int
+		Format          string // Output format of optimized images, FormatJPEG if empty
+		SplitTallImages bool   // Split webtoon strips into tiles when downloading
+	}
+
+	NewPipelineOptions struct {
+		Logger   *zerolog.Logger
+		CacheDir string
+	}
+)
+
+func NewPipeline(opts *NewPipelineOptions) *Pipeline {
+	return &Pipeline{
+		logger: opts.Logger,
+		cache: NewCache(&NewCacheOptions{
+			Logger:  opts.Logger,
+			Dir:     filepath.Join(opts.CacheDir, "manga-images"),
+			MaxSize: DefaultCacheSizeLimit,
+		}),
+		settings:   &Settings{},
+		fetchImage: manga_providers.GetImageByProxy,
+	}
+}
+
+func (p *Pipeline) SetSettings(settings *Settings) {
+	if settings == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.settings = settings
+	p.mu.Unlock()
+
+	if settings.CacheSizeLimit > 0 {
+		p.cache.SetMaxSize(settings.CacheSizeLimit)
+	} else {
+		p.cache.SetMaxSize(DefaultCacheSizeLimit)
+	}
+}
+
+// SetFfmpegPath sets the FFmpeg binary used to encode WebP and AVIF images.
+func (p *Pipeline) SetFfmpegPath(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ffmpegPath = path
+}
+
+func (p *Pipeline) getSettings() *Settings {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.settings
+}
+
+func (p *Pipeline) getOptimizeOptions(settings *Settings) *OptimizeOptions {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return &OptimizeOptions{
+		MaxWidth:   settings.MaxWidth,
+		Quality:    settings.Quality,
+		Format:     settings.Format,
+		FfmpegPath: p.ffmpegPath,
+	}
+}
+
+// GetImage returns the image from the cache or fetches it.
+// Optimized and original images are cached under different keys so that changing the settings doesn't return stale images.
+func (p *Pipeline) GetImage(url string, headers map[string]string) ([]byte, error) {
+	settings := p.getSettings()
+
+	key := url
+	if settings.OptimizeImages {
+		key = fmt.Sprintf("%s$w%d$q%d", url, settings.MaxWidth, settings.Quality)
+		if settings.Format != "" && settings.Format != FormatJPEG {
+			key += "$" + settings.Format
+		}
+	}
+
+	if data, ok := p.cache.Get(key); ok {
+		return data, nil
+	}
+
+	data, err := p.fetchImage(url, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.OptimizeImages {
+		optimized, err := Optimize(data, p.getOptimizeOptions(settings))
+		if err != nil {
+			// Not an image the decoder supports or FFmpeg failed, keep the original
+			p.logOptimizeError(err).Str("url", url).Msg("manga image cache: Failed to optimize image")
+		} else {
+			data = optimized
+		}
+	}
+
+	if err := p.cache.Set(key, data); err != nil {
+		p.logger.Warn().Err(err).Msg("manga image cache: Failed to cache image")
+	}
+
+	return data, nil
+}
+
+// ProcessDownloadedImage returns the images to write for a downloaded page.
+// A webtoon strip is split into several tiles if SplitTallImages is enabled, otherwise a single image is returned.
+func (p *Pipeline) ProcessDownloadedImage(data []byte) [][]byte {
+	settings := p.getSettings()
+	opts := p.getOptimizeOptions(settings)
+
+	if settings.SplitTallImages {
+		tiles, err := SplitTiles(data, opts)
+		if err != nil {
+			p.logOptimizeError(err).Msg("manga image cache: Failed to split image")
+		} else if len(tiles) > 0 {
+			return tiles
+		}
+	}
+
+	if settings.OptimizeImages {
+		optimized, err := Optimize(data, opts)
+		if err != nil {
+			p.logOptimizeError(err).Msg("manga image cache: Failed to optimize image")
+		} else {
+			return [][]byte{optimized}
+		}
+	}
+
+	return [][]byte{data}
+}
+
+// logOptimizeError returns a warning event if FFmpeg failed, e.g. if it is not installed, since the output format is then ignored.
+// Images the decoder does not support are expected and only logged for debugging.
+func (p *Pipeline) logOptimizeError(err error) *zerolog.Event {
+	if errors.Is(err, ErrFfmpegEncode) {
+		return p.logger.Warn().Err(err)
+	}
+	return p.logger.Debug().Err(err)
+}
+
+func (p *Pipeline) GetCacheStats() *Stats {
+	return p.cache.Stats()
+}
+
+func (p *Pipeline) PurgeCache() error {
+	return p.cache.Purge()
+}
@@ -0,0 +1,173 @@
+package manga
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"seanime/internal/manga/imagecache"
+	"seanime/internal/manga/providers"
+	"strings"
+	"sync"
+
+	hibikemanga "github.com/5rahim/hibike/pkg/extension/manga"
+	"github.com/samber/lo"
+)
+
+// maxPageKeys is the number of page keys kept in memory, all the keys are dropped when it is reached.
+// The keys of a chapter are registered again when its page container is requested.
+const maxPageKeys = 20000
+
+var ErrPageKeyNotFound = errors.New("page not found, reload the chapter")
+
+type (
+	// pageKeys maps the opaque keys of the page images served by the image route to the pages returned by the providers.
+	// Only the pages of the page containers are registered so that the route cannot be used to fetch arbitrary URLs.
+	pageKeys struct {
+		mu    sync.RWMutex
+		pages map[string]*keyedPage
+	}
+
+	keyedPage struct {
+		provider  string
+		mediaId   int
+		chapterId string
+		page      *hibikemanga.ChapterPage
+		// merged is set if the page is part of a merged chapter list,
+		// the same page of the other providers' chapters is then served if the page cannot be fetched.
+		merged *GetMergedChapterContainerOptions
+	}
+)
+
+func newPageKeys() *pageKeys {
+	return &pageKeys{pages: make(map[string]*keyedPage)}
+}
+
+// GetImagePipeline returns the pipeline used to cache and optimize page images.
+func (r *Repository) GetImagePipeline() *manga_imagecache.Pipeline {
+	return r.imagePipeline
+}
+
+// SetImageSettings sets the cache size limit and the optimization settings of page images.
+func (r *Repository) SetImageSettings(settings *manga_imagecache.Settings) {
+	r.imagePipeline.SetSettings(settings)
+}
+
+// GetPageImage returns a page image from the cache, or fetches and caches it.
+func (r *Repository) GetPageImage(url string, headers map[string]string) ([]byte, error) {
+	return r.imagePipeline.GetImage(url, headers)
+}
+
+// GetPageImageByKey returns the image of a page of a page container from the cache, or fetches and caches it.
+// The key is one of the page container's 'pageKeys'.
+// If the page is part of a merged chapter list and cannot be fetched, the page with the same index is served from
+// the chapters of the other providers with the same number, in order of preference.
+func (r *Repository) GetPageImageByKey(key string) ([]byte, error) {
+	r.pageKeys.mu.RLock()
+	p, ok := r.pageKeys.pages[key]
+	r.pageKeys.mu.RUnlock()
+	if !ok {
+		return nil, ErrPageKeyNotFound
+	}
+
+	data, err := r.imagePipeline.GetImage(p.page.URL, p.page.Headers)
+	if err == nil || p.merged == nil {
+		return data, err
+	}
+
+	r.logger.Warn().Err(err).Str("provider", p.provider).Str("chapterId", p.chapterId).Int("index", p.page.Index).Msg("manga: Failed to get page, trying next provider")
+
+	if fallback, fallbackErr := r.getFallbackPageImage(p); fallbackErr == nil {
+		return fallback, nil
+	}
+
+	return nil, err
+}
+
+// getFallbackPageImage returns the page with the same index from the first fallback chapter that has it.
+func (r *Repository) getFallbackPageImage(p *keyedPage) ([]byte, error) {
+	merged, err := r.GetMergedChapterContainer(p.merged)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, chapter := range merged.GetFallbackChapters(p.provider, p.chapterId) {
+		container, err := r.GetMangaPageContainer(chapter.Provider, p.mediaId, chapter.ID, false, false)
+		if err != nil || container.IsDownloaded {
+			continue
+		}
+		page, ok := lo.Find(container.Pages, func(page *hibikemanga.ChapterPage) bool {
+			return page.Index == p.page.Index
+		})
+		if !ok {
+			continue
+		}
+
+		var data []byte
+		if chapter.Provider == manga_providers.LocalMangaProvider {
+			data, _, err = r.localProvider.ReadPage(chapter.ID, page.Index)
+		} else {
+			data, err = r.imagePipeline.GetImage(page.URL, page.Headers)
+		}
+		if err != nil {
+			continue
+		}
+
+		r.logger.Debug().Str("provider", chapter.Provider).Str("chapterId", chapter.ID).Int("index", page.Index).Msg("manga: Served page from fallback provider")
+		return data, nil
+	}
+
+	return nil, ErrNoPages
+}
+
+// registerPageKeys registers the pages of the container and sets its page keys.
+// 'merged' is the merged chapter list the container is part of, if any.
+// Downloaded and local pages are not served by the image route and are not registered.
+func (r *Repository) registerPageKeys(container *PageContainer, merged *GetMergedChapterContainerOptions) {
+	container.PageKeys = nil
+	if container.IsDownloaded {
+		return
+	}
+
+	keys := make(map[int]string, len(container.Pages))
+
+	r.pageKeys.mu.Lock()
+	defer r.pageKeys.mu.Unlock()
+
+	if len(r.pageKeys.pages)+len(container.Pages) > maxPageKeys {
+		clear(r.pageKeys.pages)
+	}
+
+	for _, page := range container.Pages {
+		if !strings.HasPrefix(page.URL, "http://") && !strings.HasPrefix(page.URL, "https://") {
+			continue
+		}
+		key := getPageKey(container.Provider, page.URL)
+		r.pageKeys.pages[key] = &keyedPage{
+			provider:  container.Provider,
+			mediaId:   container.MediaId,
+			chapterId: container.ChapterId,
+			page:      page,
+			merged:    merged,
+		}
+		keys[page.Index] = key
+	}
+
+	if len(keys) > 0 {
+		container.PageKeys = keys
+	}
+}
+
+// getPageKey returns the key of a page, it is the same for every request of the page so that browsers can cache the image.
+func getPageKey(provider string, url string) string {
+	sum := sha256.Sum256([]byte(provider + "$" + url))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *Repository) GetImageCacheStats() *manga_imagecache.Stats {
+	return r.imagePipeline.GetCacheStats()
+}
+
+// PurgeImageCache removes all the cached page images.
+func (r *Repository) PurgeImageCache() error {
+	return r.imagePipeline.PurgeCache()
+}
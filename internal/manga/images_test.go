@@ -0,0 +1,113 @@
+package manga
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"seanime/internal/util"
+	"seanime/internal/util/filecache"
+	"strings"
+	"testing"
+
+	hibikemanga "github.com/5rahim/hibike/pkg/extension/manga"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPageImageByKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("page " + r.URL.Path))
+	}))
+	defer srv.Close()
+
+	repo := NewRepository(&NewRepositoryOptions{
+		Logger:   util.NewLogger(),
+		CacheDir: t.TempDir(),
+	})
+
+	container := &PageContainer{
+		MediaId:   1,
+		Provider:  "mangadex",
+		ChapterId: "1",
+		Pages: []*hibikemanga.ChapterPage{
+			{Provider: "mangadex", URL: srv.URL + "/1.jpg", Index: 0},
+			{Provider: "mangadex", URL: srv.URL + "/2.jpg", Index: 1},
+			{Provider: "mangadex", URL: "/api/v1/manga/local-page/1/2", Index: 2},
+		},
+	}
+	repo.registerPageKeys(container, nil)
+
+	// Only remote pages get a key
+	require.Len(t, container.PageKeys, 2)
+	assert.NotContains(t, container.PageKeys, 2)
+
+	data, err := repo.GetPageImageByKey(container.PageKeys[1])
+	require.NoError(t, err)
+	assert.Equal(t, "page /2.jpg", string(data))
+
+	// The same page always has the same key
+	assert.Equal(t, getPageKey("mangadex", srv.URL+"/1.jpg"), container.PageKeys[0])
+
+	// URLs that were not returned by a provider cannot be requested
+	_, err = repo.GetPageImageByKey(getPageKey("mangadex", srv.URL+"/3.jpg"))
+	assert.ErrorIs(t, err, ErrPageKeyNotFound)
+
+	// Downloaded pages are not registered
+	downloaded := &PageContainer{Provider: "mangadex", IsDownloaded: true, Pages: []*hibikemanga.ChapterPage{{URL: srv.URL + "/4.jpg"}}}
+	repo.registerPageKeys(downloaded, nil)
+	assert.Nil(t, downloaded.PageKeys)
+}
+
+func TestGetPageImageByKey_MergedFallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/mangadex/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("page " + r.URL.Path))
+	}))
+	defer srv.Close()
+
+	fileCacher, err := filecache.NewCacher(t.TempDir())
+	require.NoError(t, err)
+
+	repo := NewRepository(&NewRepositoryOptions{
+		Logger:     util.NewLogger(),
+		FileCacher: fileCacher,
+		CacheDir:   t.TempDir(),
+	})
+
+	// Cache the chapters and pages of both providers
+	containers := map[string]*PageContainer{}
+	for _, provider := range []string{"mangadex", "comick"} {
+		chapterId := provider + "-1"
+		require.NoError(t, fileCacher.Set(repo.getFcProviderBucket(provider, 1, bucketTypeChapter), getMangaChapterContainerCacheKey(provider, 1), &ChapterContainer{
+			MediaId:  1,
+			Provider: provider,
+			Chapters: []*hibikemanga.ChapterDetails{{ID: chapterId, Chapter: "1"}},
+		}))
+		containers[provider] = &PageContainer{
+			MediaId:   1,
+			Provider:  provider,
+			ChapterId: chapterId,
+			Pages: []*hibikemanga.ChapterPage{
+				{Provider: provider, URL: srv.URL + "/" + provider + "/0.jpg", Index: 0},
+				{Provider: provider, URL: srv.URL + "/" + provider + "/1.jpg", Index: 1},
+			},
+		}
+		require.NoError(t, fileCacher.Set(repo.getFcProviderBucket(provider, 1, bucketTypePage), fmt.Sprintf("%s$%d$%s", provider, 1, chapterId), containers[provider]))
+	}
+
+	mangadex := containers["mangadex"]
+
+	// Without a merged chapter list, the error is returned
+	repo.registerPageKeys(mangadex, nil)
+	_, err = repo.GetPageImageByKey(mangadex.PageKeys[1])
+	assert.Error(t, err)
+
+	// The same page of the next provider is served
+	repo.registerPageKeys(mangadex, &GetMergedChapterContainerOptions{MediaId: 1, Providers: []string{"mangadex", "comick"}})
+	data, err := repo.GetPageImageByKey(mangadex.PageKeys[1])
+	require.NoError(t, err)
+	assert.Equal(t, "page /comick/1.jpg", string(data))
+}
@@ -0,0 +1,87 @@
+package manga
+
+import (
+	"seanime/internal/api/anilist"
+	"seanime/internal/manga/providers"
+	"seanime/internal/util/comparison"
+)
+
+type (
+	// LocalMangaSeries is a series of the local library matched to an entry of the user's manga collection.
+	LocalMangaSeries struct {
+		*manga_providers.LocalMangaSeries
+		MediaId int `json:"mediaId"` // 0 if the series was not matched
+	}
+)
+
+// GetLocalProvider returns the built-in provider that reads chapters from the local library.
+func (r *Repository) GetLocalProvider() *manga_providers.LocalManga {
+	return r.localProvider
+}
+
+// SetLocalLibraryDirs sets the directories of the local manga library.
+func (r *Repository) SetLocalLibraryDirs(dirs []string) {
+	r.localProvider.SetLibraryDirs(dirs)
+}
+
+// ReadLocalPage returns the content and the MIME type of a page of the local library.
+func (r *Repository) ReadLocalPage(chapterId string, index int) ([]byte, string, error) {
+	return r.localProvider.ReadPage(chapterId, index)
+}
+
+// GetLocalMangaLibrary returns the series of the local library and the collection entries they match.
+// Manual mappings take precedence over the title matching.
+func (r *Repository) GetLocalMangaLibrary(mangaCollection *anilist.MangaCollection) []*LocalMangaSeries {
+	series := r.localProvider.GetSeries()
+
+	entries := make([]*anilist.MangaListEntry, 0)
+	if mangaCollection != nil && mangaCollection.MediaListCollection != nil {
+		for _, list := range mangaCollection.MediaListCollection.GetLists() {
+			entries = append(entries, list.GetEntries()...)
+		}
+	}
+
+	// Manually mapped series, only the mappings of entries in the collection are used
+	mapped := make(map[string]int)
+	mappings, err := r.db.GetMangaMappings(manga_providers.LocalMangaProvider)
+	if err != nil {
+		r.logger.Warn().Err(err).Msg("manga: Failed to get local manga mappings")
+	}
+	inCollection := make(map[int]struct{}, len(entries))
+	for _, entry := range entries {
+		inCollection[entry.GetMedia().GetID()] = struct{}{}
+	}
+	for _, mapping := range mappings {
+		if _, ok := inCollection[mapping.MediaID]; ok {
+			mapped[mapping.MangaID] = mapping.MediaID
+		}
+	}
+
+	ret := make([]*LocalMangaSeries, 0, len(series))
+	for _, s := range series {
+		item := &LocalMangaSeries{LocalMangaSeries: s}
+		if mediaId, ok := mapped[s.ID]; ok {
+			item.MediaId = mediaId
+		} else {
+			item.MediaId = matchLocalSeries(s.Title, entries)
+		}
+		ret = append(ret, item)
+	}
+
+	return ret
+}
+
+// matchLocalSeries returns the media ID of the entry whose titles are the most similar to the title.
+func matchLocalSeries(title string, entries []*anilist.MangaListEntry) int {
+	bestRating := 0.0
+	mediaId := 0
+	for _, entry := range entries {
+		res, ok := comparison.FindBestMatchWithSorensenDice(&title, entry.GetMedia().GetAllTitles())
+		if !ok || res.Rating < manga_providers.LocalMangaMinSearchRating || res.Rating <= bestRating {
+			continue
+		}
+		bestRating = res.Rating
+		mediaId = entry.GetMedia().GetID()
+	}
+	return mediaId
+}
@@ -35,6 +35,7 @@ func TestGetDownloadedChapterContainers(t *testing.T) {
 		ServerURI:      "",
 		WsEventManager: events.NewMockWSEventManager(logger),
 		DownloadDir:    filepath.Join(test_utils.ConfigData.Path.DataDir, "manga"),
+		CacheDir:       filepath.Join(test_utils.ConfigData.Path.DataDir, "cache"),
 		Database:       nil, // FIX
 	})
 
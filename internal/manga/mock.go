@@ -23,6 +23,7 @@ func GetMockRepository(t *testing.T, db *db.Database) *Repository {
 		ServerURI:      "",
 		WsEventManager: events.NewMockWSEventManager(logger),
 		DownloadDir:    filepath.Join(test_utils.ConfigData.Path.DataDir, "manga"),
+		CacheDir:       filepath.Join(test_utils.ConfigData.Path.DataDir, "cache"),
 		Database:       db,
 	})
 
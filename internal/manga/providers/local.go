@@ -0,0 +1,601 @@
+package manga_providers
+
+import (
+	"archive/zip"
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	hibikemanga "github.com/5rahim/hibike/pkg/extension/manga"
+	"github.com/nwaples/rardecode/v2"
+	"github.com/rs/zerolog"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"seanime/internal/util"
+	"seanime/internal/util/comparison"
+	"seanime/seanime-parser"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Local manga library
+//
+// Each directory of a library directory is a series, e.g. "{libraryDir}/Berserk".
+// A chapter is either a CBZ/CBR archive or a folder of images, optionally grouped in volume folders.
+//	e.g. "Berserk/Berserk v01 c001.cbz", "Berserk/Vol. 02/Chapter 12/001.jpg"
+//
+// DEVNOTE: IDs are the base64 encoded paths of the series and chapters, so that they do not contain slashes.
+
+const (
+	// LocalMangaMinSearchRating is the minimum similarity between a title and the name of a series for it to match.
+	LocalMangaMinSearchRating = 0.6
+	localMangaMaxDepth        = 2   // Series folder -> volume folder -> chapter folder
+	localMangaMaxPageLists    = 256 // Number of archive page lists kept in memory
+)
+
+var (
+	ErrNotInLocalLibrary = errors.New("path is not in the local manga library")
+
+	localChapterRegex = regexp.MustCompile(`(?i)(?:^|[^a-z])(?:chapter|chap|ch|c)[\s._-]*(\d+(?:\.\d+)?)`)
+	localVolumeRegex  = regexp.MustCompile(`(?i)(?:^|[^a-z])(?:volume|vol|v)[\s._-]*(\d+(?:\.\d+)?)`)
+	digitsRegex       = regexp.MustCompile(`\d+`)
+)
+
+type (
+	LocalManga struct {
+		logger      *zerolog.Logger
+		dirs        []string
+		mu          sync.RWMutex
+		pageLists   map[string]*localPageList // Page lists of the archives, keyed by path
+		pageListsMu sync.Mutex
+	}
+
+	// localPageList is the cached page list of an archive.
+	// It is valid as long as the archive's size and modification time do not change.
+	localPageList struct {
+		size    int64
+		modTime time.Time
+		pages   []string
+	}
+
+	// LocalMangaSeries is a series folder of the local library.
+	LocalMangaSeries struct {
+		ID    string `json:"id"`
+		Title string `json:"title"` // Parsed from the folder name
+		Path  string `json:"path"`
+	}
+
+	localChapter struct {
+		path    string
+		name    string
+		chapter string
+		volume  string
+	}
+)
+
+func NewLocalManga(logger *zerolog.Logger) *LocalManga {
+	return &LocalManga{
+		logger:    logger,
+		dirs:      make([]string, 0),
+		pageLists: make(map[string]*localPageList),
+	}
+}
+
+// SetLibraryDirs sets the directories that are scanned.
+func (lm *LocalManga) SetLibraryDirs(dirs []string) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	lm.dirs = make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			lm.dirs = append(lm.dirs, filepath.Clean(dir))
+		}
+	}
+}
+
+func (lm *LocalManga) getLibraryDirs() []string {
+	lm.mu.RLock()
+	defer lm.mu.RUnlock()
+	return slices.Clone(lm.dirs)
+}
+
+func (lm *LocalManga) GetSettings() hibikemanga.Settings {
+	return hibikemanga.Settings{
+		SupportsMultiScanlator: false,
+		SupportsMultiLanguage:  false,
+	}
+}
+
+// GetSeries returns the series folders of the library directories.
+func (lm *LocalManga) GetSeries() []*LocalMangaSeries {
+	ret := make([]*LocalMangaSeries, 0)
+	for _, dir := range lm.getLibraryDirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			lm.logger.Warn().Err(err).Str("dir", dir).Msg("local manga: Failed to read library directory")
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			ret = append(ret, &LocalMangaSeries{
+				ID:    encodeLocalMangaID(path),
+				Title: ParseLocalSeriesTitle(entry.Name()),
+				Path:  path,
+			})
+		}
+	}
+	return ret
+}
+
+func (lm *LocalManga) Search(opts hibikemanga.SearchOptions) ([]*hibikemanga.SearchResult, error) {
+	ret := make([]*hibikemanga.SearchResult, 0)
+
+	lm.logger.Debug().Str("query", opts.Query).Msg("local manga: Searching manga")
+
+	for _, series := range lm.GetSeries() {
+		compRes, ok := comparison.FindBestMatchWithSorensenDice(&opts.Query, []*string{&series.Title})
+		if !ok || compRes.Rating < LocalMangaMinSearchRating {
+			continue
+		}
+		ret = append(ret, &hibikemanga.SearchResult{
+			Provider:     LocalMangaProvider,
+			ID:           series.ID,
+			Title:        series.Title,
+			SearchRating: compRes.Rating,
+		})
+	}
+
+	if len(ret) == 0 {
+		return nil, ErrNoResults
+	}
+
+	return ret, nil
+}
+
+func (lm *LocalManga) FindChapters(id string) ([]*hibikemanga.ChapterDetails, error) {
+	seriesPath, err := lm.decodeID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	lm.logger.Debug().Str("path", seriesPath).Msg("local manga: Finding chapters")
+
+	chapters := make([]*localChapter, 0)
+	lm.scanChapters(seriesPath, "", 0, &chapters)
+
+	if len(chapters) == 0 {
+		return nil, ErrNoChapters
+	}
+
+	sortLocalChapters(chapters)
+
+	ret := make([]*hibikemanga.ChapterDetails, 0, len(chapters))
+	for i, ch := range chapters {
+		// Chapters without a number are numbered by their order
+		chapter := ch.chapter
+		if chapter == "" {
+			chapter = strconv.Itoa(i + 1)
+		}
+		title := "Chapter " + chapter
+		if ch.volume != "" {
+			title = fmt.Sprintf("Vol. %s %s", ch.volume, title)
+		}
+		ret = append(ret, &hibikemanga.ChapterDetails{
+			Provider: LocalMangaProvider,
+			ID:       encodeLocalMangaID(ch.path),
+			URL:      ch.path,
+			Title:    title,
+			Chapter:  chapter,
+			Index:    uint(i),
+		})
+	}
+
+	return ret, nil
+}
+
+// scanChapters adds the archives and image folders of the directory to the list.
+// Folders that do not contain images are scanned as volume folders.
+func (lm *LocalManga) scanChapters(dir string, volume string, depth int, chapters *[]*localChapter) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || name == "__MACOSX" {
+			continue
+		}
+		path := filepath.Join(dir, name)
+
+		if !entry.IsDir() {
+			if isMangaArchive(name) {
+				*chapters = append(*chapters, newLocalChapter(path, strings.TrimSuffix(name, filepath.Ext(name)), volume))
+			}
+			continue
+		}
+
+		if depth >= localMangaMaxDepth {
+			continue
+		}
+
+		if images, _ := listImagesInDir(path); len(images) > 0 {
+			*chapters = append(*chapters, newLocalChapter(path, name, volume))
+			continue
+		}
+
+		// Volume folder
+		_, folderVolume := ParseLocalChapterName(name)
+		lm.scanChapters(path, cmp.Or(folderVolume, volume), depth+1, chapters)
+	}
+}
+
+func newLocalChapter(path string, name string, volume string) *localChapter {
+	chapter, vol := ParseLocalChapterName(name)
+	return &localChapter{
+		path:    path,
+		name:    name,
+		chapter: chapter,
+		volume:  cmp.Or(vol, volume),
+	}
+}
+
+func (lm *LocalManga) FindChapterPages(id string) ([]*hibikemanga.ChapterPage, error) {
+	chapterPath, err := lm.decodeID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := lm.getChapterPages(chapterPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, ErrNoPages
+	}
+
+	ret := make([]*hibikemanga.ChapterPage, 0, len(pages))
+	for i := range pages {
+		ret = append(ret, &hibikemanga.ChapterPage{
+			Provider: LocalMangaProvider,
+			URL:      GetLocalMangaPageURL(id, i),
+			Index:    i,
+			Headers:  map[string]string{},
+		})
+	}
+
+	return ret, nil
+}
+
+// GetLocalMangaPageURL returns the URL of the endpoint that serves the page.
+func GetLocalMangaPageURL(chapterId string, index int) string {
+	return fmt.Sprintf("/api/v1/manga/local-page/%s/%d", chapterId, index)
+}
+
+// ReadPage returns the content and the MIME type of the page.
+func (lm *LocalManga) ReadPage(chapterId string, index int) ([]byte, string, error) {
+	chapterPath, err := lm.decodeID(chapterId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	pages, err := lm.getChapterPages(chapterPath)
+	if err != nil {
+		return nil, "", err
+	}
+	if index < 0 || index >= len(pages) {
+		return nil, "", ErrNoPages
+	}
+	page := pages[index]
+
+	contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(page)))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	var data []byte
+	switch strings.ToLower(filepath.Ext(chapterPath)) {
+	case ".cbz", ".zip":
+		data, err = readZipEntry(chapterPath, page)
+	case ".cbr", ".rar":
+		data, err = readRarEntry(chapterPath, page)
+	default:
+		data, err = os.ReadFile(filepath.Join(chapterPath, page))
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, contentType, nil
+}
+
+// getChapterPages returns the sorted names of the images of a chapter.
+// The page lists of archives are cached so that reading a chapter does not list the archive for every page.
+func (lm *LocalManga) getChapterPages(chapterPath string) ([]string, error) {
+	if !isMangaArchive(chapterPath) {
+		return listChapterPages(chapterPath)
+	}
+
+	info, err := os.Stat(chapterPath)
+	if err != nil {
+		return nil, err
+	}
+
+	lm.pageListsMu.Lock()
+	cached, ok := lm.pageLists[chapterPath]
+	lm.pageListsMu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.pages, nil
+	}
+
+	pages, err := listChapterPages(chapterPath)
+	if err != nil {
+		return nil, err
+	}
+
+	lm.pageListsMu.Lock()
+	if len(lm.pageLists) >= localMangaMaxPageLists {
+		clear(lm.pageLists)
+	}
+	lm.pageLists[chapterPath] = &localPageList{size: info.Size(), modTime: info.ModTime(), pages: pages}
+	lm.pageListsMu.Unlock()
+
+	return pages, nil
+}
+
+// decodeID returns the path encoded in the ID.
+// The path must be in one of the library directories.
+func (lm *LocalManga) decodeID(id string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return "", fmt.Errorf("invalid local manga id: %w", err)
+	}
+	path := filepath.Clean(string(b))
+	if !util.IsSubdirectoryOfAny(lm.getLibraryDirs(), path) {
+		return "", ErrNotInLocalLibrary
+	}
+	return path, nil
+}
+
+func encodeLocalMangaID(path string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(path))
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ParseLocalSeriesTitle returns the title of a series from its folder name.
+//
+//	e.g. "Berserk [Dark Horse]" -> "Berserk"
+func ParseLocalSeriesTitle(name string) string {
+	if elements := seanime_parser.Parse(name); elements != nil && elements.Title != "" {
+		return elements.Title
+	}
+	return name
+}
+
+// ParseLocalChapterName returns the chapter and volume numbers of a chapter archive or folder.
+// Explicit markers are used first, the numbers found by the filename parser are used otherwise.
+//
+//	e.g. "Berserk v03 c012" -> "12", "3"
+//	e.g. "Vinland Saga 045" -> "45", ""
+func ParseLocalChapterName(name string) (chapter string, volume string) {
+	if m := localChapterRegex.FindStringSubmatch(name); m != nil {
+		chapter = normalizeLocalNumber(m[1])
+	}
+	if m := localVolumeRegex.FindStringSubmatch(name); m != nil {
+		volume = normalizeLocalNumber(m[1])
+	}
+
+	if chapter == "" || volume == "" {
+		if elements := seanime_parser.Parse(name); elements != nil {
+			if chapter == "" && volume == "" && len(elements.EpisodeNumber) > 0 {
+				chapter = normalizeLocalNumber(elements.EpisodeNumber[0])
+			}
+			if volume == "" && len(elements.VolumeNumber) > 0 {
+				volume = normalizeLocalNumber(elements.VolumeNumber[0])
+			}
+		}
+	}
+
+	return
+}
+
+// normalizeLocalNumber removes the padding zeros.
+//
+//	e.g. "012" -> "12", "10.50" -> "10.5"
+func normalizeLocalNumber(s string) string {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// sortLocalChapters sorts the chapters by chapter number, volume number and name.
+func sortLocalChapters(chapters []*localChapter) {
+	number := func(s string) float64 {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return -1
+		}
+		return f
+	}
+	slices.SortStableFunc(chapters, func(a, b *localChapter) int {
+		if a.chapter != "" && b.chapter != "" {
+			if c := cmp.Compare(number(a.chapter), number(b.chapter)); c != 0 {
+				return c
+			}
+		}
+		if c := cmp.Compare(number(a.volume), number(b.volume)); c != 0 {
+			return c
+		}
+		return NaturalCompare(a.name, b.name)
+	})
+}
+
+// NaturalCompare compares two strings, the numbers are compared by value.
+//
+//	e.g. "page2" < "page10"
+func NaturalCompare(a, b string) int {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	for a != "" && b != "" {
+		locA := digitsRegex.FindStringIndex(a)
+		locB := digitsRegex.FindStringIndex(b)
+		if locA == nil || locB == nil || locA[0] != locB[0] || a[:locA[0]] != b[:locB[0]] {
+			return strings.Compare(a, b)
+		}
+		numA, _ := strconv.ParseUint(a[locA[0]:locA[1]], 10, 64)
+		numB, _ := strconv.ParseUint(b[locB[0]:locB[1]], 10, 64)
+		if c := cmp.Compare(numA, numB); c != 0 {
+			return c
+		}
+		a, b = a[locA[1]:], b[locB[1]:]
+	}
+	return strings.Compare(a, b)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func isMangaArchive(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".cbz", ".zip", ".cbr", ".rar":
+		return true
+	}
+	return false
+}
+
+func isMangaImage(name string) bool {
+	base := filepath.Base(name)
+	if strings.HasPrefix(base, ".") || strings.Contains(filepath.ToSlash(name), "__MACOSX/") {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".webp", ".gif", ".bmp", ".avif":
+		return true
+	}
+	return false
+}
+
+// listChapterPages returns the sorted names of the images of a chapter archive or folder.
+func listChapterPages(chapterPath string) ([]string, error) {
+	switch strings.ToLower(filepath.Ext(chapterPath)) {
+	case ".cbz", ".zip":
+		return listImagesInZip(chapterPath)
+	case ".cbr", ".rar":
+		return listImagesInRar(chapterPath)
+	default:
+		return listImagesInDir(chapterPath)
+	}
+}
+
+func sortPages(pages []string) []string {
+	slices.SortFunc(pages, NaturalCompare)
+	return pages
+}
+
+func listImagesInDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() && isMangaImage(entry.Name()) {
+			ret = append(ret, entry.Name())
+		}
+	}
+	return sortPages(ret), nil
+}
+
+func listImagesInZip(path string) ([]string, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	ret := make([]string, 0)
+	for _, f := range r.File {
+		if !f.FileInfo().IsDir() && isMangaImage(f.Name) {
+			ret = append(ret, f.Name)
+		}
+	}
+	return sortPages(ret), nil
+}
+
+func readZipEntry(path string, name string) ([]byte, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// The entries are compared by name since zip.Reader.Open rejects names that are not valid fs.FS paths,
+	// e.g. names starting with "./" or containing backslashes
+	for _, f := range r.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, os.ErrNotExist
+}
+
+func listImagesInRar(path string) ([]string, error) {
+	r, err := rardecode.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	ret := make([]string, 0)
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !header.IsDir && isMangaImage(header.Name) {
+			ret = append(ret, header.Name)
+		}
+	}
+	return sortPages(ret), nil
+}
+
+func readRarEntry(path string, name string) ([]byte, error) {
+	r, err := rardecode.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name == name {
+			return io.ReadAll(r)
+		}
+	}
+	return nil, os.ErrNotExist
+}
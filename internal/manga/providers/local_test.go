@@ -0,0 +1,150 @@
+package manga_providers
+
+import (
+	"archive/zip"
+	"encoding/base64"
+	hibikemanga "github.com/5rahim/hibike/pkg/extension/manga"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"seanime/internal/util"
+	"slices"
+	"testing"
+)
+
+func TestParseLocalChapterName(t *testing.T) {
+	tests := []struct {
+		name            string
+		expectedChapter string
+		expectedVolume  string
+	}{
+		{"Berserk v03 c012", "12", "3"},
+		{"Berserk - Vol. 03 Ch. 12", "12", "3"},
+		{"Chainsaw Man - c097 (v11) [Group]", "97", "11"},
+		{"Chapter 10.5", "10.5", ""},
+		{"Ch.005", "5", ""},
+		{"Vagabond v01", "", "1"},
+		{"Vinland Saga 045", "45", ""},
+		{"Extras", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chapter, volume := ParseLocalChapterName(tt.name)
+			assert.Equal(t, tt.expectedChapter, chapter)
+			assert.Equal(t, tt.expectedVolume, volume)
+		})
+	}
+}
+
+func TestNaturalCompare(t *testing.T) {
+	pages := []string{"page10.jpg", "page2.jpg", "Page1.jpg", "cover.jpg"}
+	slices.SortFunc(pages, NaturalCompare)
+	assert.Equal(t, []string{"cover.jpg", "Page1.jpg", "page2.jpg", "page10.jpg"}, pages)
+}
+
+func TestLocalManga(t *testing.T) {
+	libraryDir := t.TempDir()
+	seriesDir := filepath.Join(libraryDir, "Berserk [Dark Horse]")
+
+	// Archive chapters
+	writeTestCbz(t, filepath.Join(seriesDir, "Berserk v01 c002.cbz"), []string{"10.jpg", "2.jpg", "1.jpg", "__MACOSX/._1.jpg", "info.txt"})
+	writeTestCbz(t, filepath.Join(seriesDir, "Berserk v01 c001.cbz"), []string{"1.png"})
+	// Folder chapter in a volume folder
+	writeTestFile(t, filepath.Join(seriesDir, "Volume 2", "Chapter 10", "001.jpg"))
+	writeTestFile(t, filepath.Join(seriesDir, "Volume 2", "Chapter 10", "002.jpg"))
+	// Not a chapter
+	writeTestFile(t, filepath.Join(seriesDir, "notes.txt"))
+	// Another series
+	writeTestFile(t, filepath.Join(libraryDir, "Vinland Saga", "Vinland Saga 001", "001.jpg"))
+
+	provider := NewLocalManga(util.NewLogger())
+	provider.SetLibraryDirs([]string{libraryDir})
+
+	// Search
+	res, err := provider.Search(hibikemanga.SearchOptions{Query: "Berserk"})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, "Berserk", res[0].Title)
+
+	_, err = provider.Search(hibikemanga.SearchOptions{Query: "One Piece"})
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	// Chapters
+	chapters, err := provider.FindChapters(res[0].ID)
+	require.NoError(t, err)
+	require.Len(t, chapters, 3)
+	assert.Equal(t, []string{"1", "2", "10"}, lo.Map(chapters, func(c *hibikemanga.ChapterDetails, _ int) string { return c.Chapter }))
+	assert.Equal(t, "Vol. 2 Chapter 10", chapters[2].Title)
+
+	// Pages of an archive
+	pages, err := provider.FindChapterPages(chapters[1].ID)
+	require.NoError(t, err)
+	require.Len(t, pages, 3)
+	assert.Equal(t, GetLocalMangaPageURL(chapters[1].ID, 0), pages[0].URL)
+
+	data, contentType, err := provider.ReadPage(chapters[1].ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "10.jpg", string(data))
+	assert.Equal(t, "image/jpeg", contentType)
+
+	// Pages of a folder
+	data, _, err = provider.ReadPage(chapters[2].ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "002.jpg", string(data))
+
+	_, _, err = provider.ReadPage(chapters[2].ID, 2)
+	assert.Error(t, err)
+
+	// Files outside the library cannot be read
+	outside := base64.RawURLEncoding.EncodeToString([]byte(filepath.Join(libraryDir, "..", "secret")))
+	_, err = provider.FindChapterPages(outside)
+	assert.ErrorIs(t, err, ErrNotInLocalLibrary)
+}
+
+func TestLocalManga_ArchiveEntryNames(t *testing.T) {
+	libraryDir := t.TempDir()
+	// Entry names that are not valid fs.FS paths
+	writeTestCbz(t, filepath.Join(libraryDir, "Berserk", "Berserk c001.cbz"), []string{"./01.jpg", `pages\02.jpg`})
+
+	provider := NewLocalManga(util.NewLogger())
+	provider.SetLibraryDirs([]string{libraryDir})
+
+	res, err := provider.Search(hibikemanga.SearchOptions{Query: "Berserk"})
+	require.NoError(t, err)
+	chapters, err := provider.FindChapters(res[0].ID)
+	require.NoError(t, err)
+	require.Len(t, chapters, 1)
+
+	data, _, err := provider.ReadPage(chapters[0].ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "./01.jpg", string(data))
+
+	data, _, err = provider.ReadPage(chapters[0].ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, `pages\02.jpg`, string(data))
+}
+
+func writeTestFile(t *testing.T, path string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(filepath.Base(path)), 0644))
+}
+
+// writeTestCbz creates an archive in which each file contains its name.
+func writeTestCbz(t *testing.T, path string, files []string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for _, name := range files {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(name))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+}
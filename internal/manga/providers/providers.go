@@ -3,12 +3,13 @@ package manga_providers
 import "errors"
 
 const (
-	MangaseeProvider  string = "mangasee"
-	MangadexProvider  string = "mangadex"
-	ComickProvider    string = "comick"
-	MangapillProvider string = "mangapill"
-	ManganatoProvider string = "manganato"
-	MangafireProvider string = "mangafire"
+	MangaseeProvider   string = "mangasee"
+	MangadexProvider   string = "mangadex"
+	ComickProvider     string = "comick"
+	MangapillProvider  string = "mangapill"
+	ManganatoProvider  string = "manganato"
+	MangafireProvider  string = "mangafire"
+	LocalMangaProvider string = "local-manga"
 )
 
 var (
@@ -14,6 +14,8 @@ import (
 	"seanime/internal/database/db"
 	"seanime/internal/events"
 	"seanime/internal/extension"
+	"seanime/internal/manga/imagecache"
+	"seanime/internal/manga/providers"
 	"seanime/internal/util/filecache"
 	"strconv"
 	"strings"
@@ -24,6 +26,7 @@ import (
 var (
 	ErrNoResults            = errors.New("no results found for this media")
 	ErrNoChapters           = errors.New("no manga chapters found")
+	ErrNoPages              = errors.New("no pages found")
 	ErrChapterNotFound      = errors.New("chapter not found")
 	ErrChapterNotDownloaded = errors.New("chapter not downloaded")
 	ErrNoTitlesProvided     = errors.New("no titles provided")
@@ -39,6 +42,9 @@ type (
 		mu                    sync.Mutex
 		downloadDir           string
 		db                    *db.Database
+		localProvider         *manga_providers.LocalManga
+		imagePipeline         *manga_imagecache.Pipeline
+		pageKeys              *pageKeys
 	}
 
 	NewRepositoryOptions struct {
@@ -47,6 +53,7 @@ type (
 		ServerURI      string
 		WsEventManager events.WSEventManagerInterface
 		DownloadDir    string
+		CacheDir       string
 		Database       *db.Database
 	}
 )
@@ -60,6 +67,12 @@ func NewRepository(opts *NewRepositoryOptions) *Repository {
 		downloadDir:           opts.DownloadDir,
 		providerExtensionBank: extension.NewUnifiedBank(),
 		db:                    opts.Database,
+		localProvider:         manga_providers.NewLocalManga(opts.Logger),
+		imagePipeline: manga_imagecache.NewPipeline(&manga_imagecache.NewPipelineOptions{
+			Logger:   opts.Logger,
+			CacheDir: opts.CacheDir,
+		}),
+		pageKeys: newPageKeys(),
 	}
 	return r
 }
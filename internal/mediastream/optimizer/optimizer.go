@@ -1,12 +1,15 @@
 package optimizer
 
 import (
+	"context"
 	"fmt"
 	"github.com/rs/zerolog"
 	"github.com/samber/mo"
+	"seanime/internal/database/db"
 	"seanime/internal/events"
 	"seanime/internal/mediastream/videofile"
 	"seanime/internal/util"
+	"sync"
 )
 
 const (
@@ -20,30 +23,67 @@ type (
 	Quality string
 
 	Optimizer struct {
-		wsEventManager  events.WSEventManagerInterface
-		logger          *zerolog.Logger
-		libraryDir      mo.Option[string]
-		concurrentTasks int
+		wsEventManager     events.WSEventManagerInterface
+		logger             *zerolog.Logger
+		db                 *db.Database
+		mediaInfoExtractor *videofile.MediaInfoExtractor
+		settings           mo.Option[*Settings]
+		concurrentTasks    int
+
+		mu         sync.Mutex
+		running    bool               // The job loop is running
+		jobCancel  context.CancelFunc // Cancels the current job
+		currentJob uint
+		wakeCh     chan struct{}
+	}
+
+	// Settings of the pre-transcode jobs, see models.MediastreamSettings.
+	Settings struct {
+		Enabled     bool
+		LibraryDir  string // Where the optimized files are stored
+		FfmpegPath  string
+		FfprobePath string
+		Quality     Quality // Default quality
+		WindowStart string  // "HH:MM", jobs run at any time if empty
+		WindowEnd   string  // "HH:MM"
+		MaxThreads  int     // 0 means no limit
 	}
 
 	NewOptimizerOptions struct {
-		Logger         *zerolog.Logger
-		WSEventManager events.WSEventManagerInterface
+		Logger             *zerolog.Logger
+		WSEventManager     events.WSEventManagerInterface
+		Database           *db.Database
+		MediaInfoExtractor *videofile.MediaInfoExtractor
 	}
 )
 
 func NewOptimizer(opts *NewOptimizerOptions) *Optimizer {
 	ret := &Optimizer{
-		logger:          opts.Logger,
-		wsEventManager:  opts.WSEventManager,
-		libraryDir:      mo.None[string](),
-		concurrentTasks: 2,
+		logger:             opts.Logger,
+		wsEventManager:     opts.WSEventManager,
+		db:                 opts.Database,
+		mediaInfoExtractor: opts.MediaInfoExtractor,
+		settings:           mo.None[*Settings](),
+		concurrentTasks:    2,
+		wakeCh:             make(chan struct{}, 1),
 	}
 	return ret
 }
 
-func (o *Optimizer) SetLibraryDir(libraryDir string) {
-	o.libraryDir = mo.Some[string](libraryDir)
+// SetSettings updates the settings and starts the job loop if pre-transcoding is enabled.
+// Jobs interrupted by a restart are resumed.
+func (o *Optimizer) SetSettings(settings *Settings) {
+	if settings.Quality == "" {
+		settings.Quality = QualityMedium
+	}
+	o.settings = mo.Some(settings)
+
+	if settings.Enabled && settings.LibraryDir != "" {
+		o.startJobLoop()
+	} else {
+		o.cancelCurrentJob()
+	}
+	o.wake()
 }
 
 /////////////
@@ -55,12 +95,13 @@ type StartMediaOptimizationOptions struct {
 	MediaInfo         *videofile.MediaInfo
 }
 
+// StartMediaOptimization queues a single file to be pre-transcoded.
 func (o *Optimizer) StartMediaOptimization(opts *StartMediaOptimizationOptions) (err error) {
 	defer util.HandlePanicInModuleWithError("mediastream/optimizer/StartMediaOptimization", &err)
 
 	o.logger.Debug().Any("opts", opts).Msg("mediastream: Starting media optimization")
 
-	if !o.libraryDir.IsPresent() {
+	if o.settings.IsAbsent() || o.settings.MustGet().LibraryDir == "" {
 		return fmt.Errorf("library directory not set")
 	}
 
@@ -68,6 +109,7 @@ func (o *Optimizer) StartMediaOptimization(opts *StartMediaOptimizationOptions)
 		return fmt.Errorf("no filepath")
 	}
 
+	_, err = o.QueueJobs([]*QueueJobOptions{{Path: opts.Filepath}}, opts.Quality)
 	return
 }
 
@@ -85,3 +127,41 @@ func qualityToPreset(quality Quality) string {
 		return "veryfast"
 	}
 }
+
+// qualityToCrf returns the constant rate factor of the quality, lower is better.
+func qualityToCrf(quality Quality) int {
+	switch quality {
+	case QualityLow:
+		return 28
+	case QualityMedium:
+		return 24
+	case QualityHigh:
+		return 21
+	case QualityMax:
+		return 18
+	default:
+		return 24
+	}
+}
+
+// qualityToMaxHeight returns the maximum height of the optimized file, 0 means the source height.
+func qualityToMaxHeight(quality Quality) int {
+	switch quality {
+	case QualityLow:
+		return 480
+	case QualityMedium:
+		return 720
+	case QualityHigh:
+		return 1080
+	default:
+		return 0
+	}
+}
+
+func IsValidQuality(quality Quality) bool {
+	switch quality {
+	case QualityLow, QualityMedium, QualityHigh, QualityMax:
+		return true
+	}
+	return false
+}
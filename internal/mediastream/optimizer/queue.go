@@ -0,0 +1,457 @@
+package optimizer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"seanime/internal/database/models"
+	"seanime/internal/events"
+	"seanime/internal/mediastream/transcoder"
+	"seanime/internal/mediastream/videofile"
+	"seanime/internal/util"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pre-transcode job queue
+//
+// Jobs are stored in the database so that the queue survives restarts, interrupted jobs are started over.
+// A single job runs at a time and only within the configured time window.
+// The optimized files are stored in the library directory, keyed by the hash of the source file.
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+	JobStatusCanceled  = "canceled"
+)
+
+var (
+	errJobCanceled = errors.New("job canceled")
+	// errJobPaused is used when the time window ends or pre-transcoding is disabled, the job is queued again
+	errJobPaused = errors.New("job paused")
+)
+
+type QueueJobOptions struct {
+	Path    string
+	MediaID int
+}
+
+// GetOptimizedFilePath returns the path of the optimized file.
+//
+//	e.g. {libraryDir}/{hash}/medium.mp4
+func GetOptimizedFilePath(libraryDir string, hash string, quality Quality) string {
+	return filepath.Join(libraryDir, hash, string(quality)+".mp4")
+}
+
+// QueueJobs adds the files to the queue and returns the number of jobs added.
+// Files that are already queued or optimized in the same quality are skipped.
+func (o *Optimizer) QueueJobs(opts []*QueueJobOptions, quality Quality) (int, error) {
+	if o.settings.IsAbsent() || o.settings.MustGet().LibraryDir == "" {
+		return 0, errors.New("library directory not set")
+	}
+	settings := o.settings.MustGet()
+
+	if quality == "" {
+		quality = settings.Quality
+	}
+	if !IsValidQuality(quality) {
+		return 0, fmt.Errorf("invalid quality: %s", quality)
+	}
+
+	pending, err := o.db.GetPendingMediastreamPreTranscodeJobs()
+	if err != nil {
+		return 0, err
+	}
+	queued := make(map[string]struct{}, len(pending))
+	for _, job := range pending {
+		queued[job.Path+"|"+job.Quality] = struct{}{}
+	}
+
+	added := 0
+	for _, opt := range opts {
+		if _, ok := queued[opt.Path+"|"+string(quality)]; ok {
+			continue
+		}
+		// The hash is computed from the file, skip files that do not exist
+		hash, err := videofile.GetHashFromPath(opt.Path)
+		if err != nil {
+			o.logger.Warn().Err(err).Str("path", opt.Path).Msg("mediastream: Cannot queue file for pre-transcoding")
+			continue
+		}
+		if _, err := os.Stat(GetOptimizedFilePath(settings.LibraryDir, hash, quality)); err == nil {
+			continue
+		}
+
+		err = o.db.InsertMediastreamPreTranscodeJob(&models.MediastreamPreTranscodeJob{
+			Path:    opt.Path,
+			Hash:    hash,
+			MediaID: opt.MediaID,
+			Quality: string(quality),
+			Status:  JobStatusQueued,
+		})
+		if err != nil {
+			return added, err
+		}
+		queued[opt.Path+"|"+string(quality)] = struct{}{}
+		added++
+	}
+
+	if added > 0 {
+		o.logger.Info().Int("count", added).Str("quality", string(quality)).Msg("mediastream: Queued files for pre-transcoding")
+		o.wsEventManager.SendEvent(events.MediastreamPreTranscodeQueueUpdated, nil)
+		o.wake()
+	}
+
+	return added, nil
+}
+
+// GetJobs returns all jobs, including the finished ones.
+func (o *Optimizer) GetJobs() ([]*models.MediastreamPreTranscodeJob, error) {
+	return o.db.GetMediastreamPreTranscodeJobs()
+}
+
+// CancelJob cancels a queued or running job.
+// The job loop starts jobs under the same lock, so a job cannot start between the check and the cancel.
+func (o *Optimizer) CancelJob(id uint) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	job, err := o.db.GetMediastreamPreTranscodeJob(id)
+	if err != nil {
+		return err
+	}
+	if job.Status != JobStatusQueued && job.Status != JobStatusRunning {
+		return errors.New("job is not pending")
+	}
+
+	if o.currentJob == id && o.jobCancel != nil {
+		// The job loop updates the status
+		o.jobCancel()
+		return nil
+	}
+
+	job.Status = JobStatusCanceled
+	if err := o.db.SaveMediastreamPreTranscodeJob(job); err != nil {
+		return err
+	}
+	o.wsEventManager.SendEvent(events.MediastreamPreTranscodeQueueUpdated, nil)
+	return nil
+}
+
+// ClearFinishedJobs removes the completed, failed and canceled jobs.
+func (o *Optimizer) ClearFinishedJobs() error {
+	err := o.db.DeleteFinishedMediastreamPreTranscodeJobs()
+	if err != nil {
+		return err
+	}
+	o.wsEventManager.SendEvent(events.MediastreamPreTranscodeQueueUpdated, nil)
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (o *Optimizer) wake() {
+	select {
+	case o.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+func (o *Optimizer) isEnabled() bool {
+	settings, ok := o.settings.Get()
+	return ok && settings.Enabled && settings.LibraryDir != ""
+}
+
+// cancelCurrentJob pauses the running job, it is queued again.
+func (o *Optimizer) cancelCurrentJob() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.jobCancel != nil {
+		o.jobCancel()
+	}
+}
+
+func (o *Optimizer) startJobLoop() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.running {
+		return
+	}
+	o.running = true
+	go o.runJobLoop()
+}
+
+func (o *Optimizer) runJobLoop() {
+	defer util.HandlePanicInModuleThen("mediastream/optimizer/runJobLoop", func() {})
+	defer func() {
+		o.mu.Lock()
+		o.running = false
+		o.mu.Unlock()
+	}()
+
+	o.logger.Debug().Msg("mediastream: Pre-transcode job loop started")
+
+	for {
+		if !o.isEnabled() {
+			o.logger.Debug().Msg("mediastream: Pre-transcode job loop stopped")
+			return
+		}
+		settings := o.settings.MustGet()
+
+		// Wait for the time window
+		if !isWithinWindow(settings.WindowStart, settings.WindowEnd, time.Now()) {
+			o.wait(time.Minute)
+			continue
+		}
+
+		jobs, err := o.db.GetPendingMediastreamPreTranscodeJobs()
+		if err != nil || len(jobs) == 0 {
+			o.wait(10 * time.Minute)
+			continue
+		}
+
+		o.runJob(jobs[0], settings)
+	}
+}
+
+func (o *Optimizer) wait(d time.Duration) {
+	select {
+	case <-o.wakeCh:
+	case <-time.After(d):
+	}
+}
+
+func (o *Optimizer) runJob(job *models.MediastreamPreTranscodeJob, settings *Settings) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	o.mu.Lock()
+	// The job may have been canceled since it was fetched
+	job, err := o.db.GetMediastreamPreTranscodeJob(job.ID)
+	if err != nil || (job.Status != JobStatusQueued && job.Status != JobStatusRunning) {
+		o.mu.Unlock()
+		return
+	}
+	o.currentJob = job.ID
+	o.jobCancel = func() { cancel(errJobCanceled) }
+	job.Status = JobStatusRunning
+	job.Progress = 0
+	job.Error = ""
+	_ = o.db.SaveMediastreamPreTranscodeJob(job)
+	o.mu.Unlock()
+	defer func() {
+		o.mu.Lock()
+		o.currentJob = 0
+		o.jobCancel = nil
+		o.mu.Unlock()
+	}()
+
+	// Pause the job when the time window ends or pre-transcoding is disabled
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !o.isEnabled() || !isWithinWindow(settings.WindowStart, settings.WindowEnd, time.Now()) {
+					cancel(errJobPaused)
+					return
+				}
+			}
+		}
+	}()
+
+	o.wsEventManager.SendEvent(events.MediastreamPreTranscodeQueueUpdated, nil)
+
+	o.logger.Info().Str("path", job.Path).Str("quality", job.Quality).Msg("mediastream: Pre-transcoding file")
+
+	err = o.transcodeFile(ctx, job, settings)
+
+	switch {
+	case err == nil:
+		job.Status = JobStatusCompleted
+		job.Progress = 1
+		o.logger.Info().Str("path", job.OutputPath).Msg("mediastream: Pre-transcoded file")
+	case errors.Is(context.Cause(ctx), errJobCanceled):
+		job.Status = JobStatusCanceled
+		o.logger.Debug().Str("path", job.Path).Msg("mediastream: Pre-transcode job canceled")
+	case errors.Is(context.Cause(ctx), errJobPaused):
+		// Started over in the next time window
+		job.Status = JobStatusQueued
+		job.Progress = 0
+		o.logger.Debug().Str("path", job.Path).Msg("mediastream: Pre-transcode job paused")
+	default:
+		job.Status = JobStatusFailed
+		job.Error = err.Error()
+		o.logger.Error().Err(err).Str("path", job.Path).Msg("mediastream: Failed to pre-transcode file")
+	}
+
+	_ = o.db.SaveMediastreamPreTranscodeJob(job)
+	o.wsEventManager.SendEvent(events.MediastreamPreTranscodeQueueUpdated, nil)
+}
+
+// transcodeFile transcodes the file of the job to a H.264/AAC mp4 file.
+// The file is written to a temporary file first so that an interrupted job does not leave a partial file.
+func (o *Optimizer) transcodeFile(ctx context.Context, job *models.MediastreamPreTranscodeJob, settings *Settings) error {
+	mediaInfo, err := o.mediaInfoExtractor.GetInfo(settings.FfprobePath, job.Path)
+	if err != nil {
+		return err
+	}
+	if mediaInfo.Video == nil {
+		return errors.New("no video stream")
+	}
+
+	outputPath := GetOptimizedFilePath(settings.LibraryDir, mediaInfo.Sha, Quality(job.Quality))
+	job.OutputPath = outputPath
+
+	// Skip files that were optimized in the meantime
+	if _, err := os.Stat(outputPath); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return err
+	}
+	tmpPath := outputPath + ".part"
+	_ = os.Remove(tmpPath)
+
+	cmd := util.NewCmdCtx(ctx, settings.FfmpegPath, getTranscodeArgs(job.Path, tmpPath, mediaInfo, Quality(job.Quality), settings.MaxThreads)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// Report the progress
+	scanner := bufio.NewScanner(stdout)
+	lastUpdate := time.Time{}
+	for scanner.Scan() {
+		seconds, ok := parseProgressLine(scanner.Text())
+		if !ok || mediaInfo.Duration <= 0 || time.Since(lastUpdate) < 2*time.Second {
+			continue
+		}
+		lastUpdate = time.Now()
+		job.Progress = min(seconds/float64(mediaInfo.Duration), 1)
+		_ = o.db.SaveMediastreamPreTranscodeJob(job)
+		o.wsEventManager.SendEvent(events.MediastreamPreTranscodeProgress, job)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		_ = os.Remove(tmpPath)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return os.Rename(tmpPath, outputPath)
+}
+
+// getTranscodeArgs returns the ffmpeg arguments that transcode the file to a H.264/AAC mp4 file.
+func getTranscodeArgs(path string, outputPath string, mediaInfo *videofile.MediaInfo, quality Quality, maxThreads int) []string {
+	args := []string{
+		"-nostdin", "-y",
+		"-hide_banner", "-loglevel", "error",
+		// Report the progress to stdout
+		"-nostats", "-progress", "pipe:1",
+	}
+	if maxThreads > 0 {
+		// Limit the decoder threads
+		args = append(args, "-threads", strconv.Itoa(maxThreads))
+	}
+	args = append(args,
+		"-i", path,
+		"-map", "0:V:0",
+		"-map", "0:a?",
+		"-c:v", "libx264",
+		"-preset", qualityToPreset(quality),
+		"-crf", strconv.Itoa(qualityToCrf(quality)),
+		// force 8bits output, see transcoder.GetHardwareAccelSettings
+		"-pix_fmt", "yuv420p",
+	)
+
+	filters := make([]string, 0, 2)
+	if mediaInfo.Video != nil && mediaInfo.Video.IsHDR() {
+		filters = append(filters, transcoder.SoftwareToneMapFilter)
+	}
+	if maxHeight := qualityToMaxHeight(quality); maxHeight > 0 {
+		// Only downscale, the width is a multiple of 2
+		filters = append(filters, fmt.Sprintf("scale=-2:'min(%d,ih)'", maxHeight))
+	}
+	if len(filters) > 0 {
+		args = append(args, "-vf", strings.Join(filters, ","))
+	}
+
+	args = append(args,
+		"-c:a", "aac",
+		"-ac", "2",
+		"-b:a", "192k",
+	)
+	if maxThreads > 0 {
+		// Limit the encoder threads
+		args = append(args, "-threads", strconv.Itoa(maxThreads))
+	}
+	args = append(args,
+		// Move the index to the beginning of the file so that playback can start before the file is downloaded
+		"-movflags", "+faststart",
+		"-f", "mp4",
+		outputPath,
+	)
+	return args
+}
+
+// parseProgressLine returns the current position in seconds from a line of ffmpeg's progress output.
+//
+//	e.g. "out_time_us=12345678" -> 12.345678
+func parseProgressLine(line string) (float64, bool) {
+	key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+	if !found || (key != "out_time_us" && key != "out_time_ms") {
+		return 0, false
+	}
+	// DEVNOTE: "out_time_ms" is also in microseconds
+	us, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || us < 0 {
+		return 0, false
+	}
+	return float64(us) / 1_000_000, true
+}
+
+// isWithinWindow returns true if the time is within the daily window.
+// The window can span midnight (e.g. "23:00" to "06:00"), jobs run at any time if the window is not set.
+func isWithinWindow(start string, end string, now time.Time) bool {
+	startMin, ok1 := parseClock(start)
+	endMin, ok2 := parseClock(end)
+	if !ok1 || !ok2 || startMin == endMin {
+		return true
+	}
+
+	nowMin := now.Hour()*60 + now.Minute()
+	if startMin < endMin {
+		return nowMin >= startMin && nowMin < endMin
+	}
+	return nowMin >= startMin || nowMin < endMin
+}
+
+// parseClock returns the number of minutes since midnight.
+//
+//	e.g. "01:30" -> 90
+func parseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
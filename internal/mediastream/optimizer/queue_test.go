@@ -0,0 +1,161 @@
+package optimizer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"seanime/internal/database/db"
+	"seanime/internal/events"
+	"seanime/internal/mediastream/videofile"
+	"seanime/internal/util"
+	"testing"
+	"time"
+)
+
+func TestIsWithinWindow(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 1, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name     string
+		start    string
+		end      string
+		now      time.Time
+		expected bool
+	}{
+		{"no window", "", "", at(12, 0), true},
+		{"invalid window", "25:00", "06:00", at(12, 0), true},
+		{"same day, inside", "09:00", "17:00", at(12, 0), true},
+		{"same day, before", "09:00", "17:00", at(8, 59), false},
+		{"same day, end is excluded", "09:00", "17:00", at(17, 0), false},
+		{"overnight, before midnight", "23:00", "06:00", at(23, 30), true},
+		{"overnight, after midnight", "23:00", "06:00", at(2, 0), true},
+		{"overnight, outside", "23:00", "06:00", at(12, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isWithinWindow(tt.start, tt.end, tt.now))
+		})
+	}
+}
+
+func TestParseProgressLine(t *testing.T) {
+	seconds, ok := parseProgressLine("out_time_us=12500000")
+	assert.True(t, ok)
+	assert.Equal(t, 12.5, seconds)
+
+	seconds, ok = parseProgressLine("out_time_ms=1000000")
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, seconds)
+
+	_, ok = parseProgressLine("out_time=00:00:12.500000")
+	assert.False(t, ok)
+
+	_, ok = parseProgressLine("out_time_us=N/A")
+	assert.False(t, ok)
+}
+
+func TestGetTranscodeArgs(t *testing.T) {
+	mediaInfo := &videofile.MediaInfo{Video: &videofile.Video{}}
+
+	args := getTranscodeArgs("in.mkv", "out.mp4.part", mediaInfo, QualityMedium, 2)
+	assert.Contains(t, args, "scale=-2:'min(720,ih)'")
+	assert.Equal(t, "out.mp4.part", args[len(args)-1])
+	assert.Equal(t, 2, countArg(args, "-threads"))
+
+	// No downscaling and no thread limit
+	args = getTranscodeArgs("in.mkv", "out.mp4.part", mediaInfo, QualityMax, 0)
+	assert.NotContains(t, args, "-vf")
+	assert.Equal(t, 0, countArg(args, "-threads"))
+}
+
+func countArg(args []string, arg string) int {
+	ret := 0
+	for _, a := range args {
+		if a == arg {
+			ret++
+		}
+	}
+	return ret
+}
+
+func TestQueueJobs(t *testing.T) {
+	t.Setenv("TEST_ENV", "true")
+	logger := util.NewLogger()
+
+	database, err := db.NewDatabase(t.TempDir(), "test", logger)
+	require.NoError(t, err)
+
+	o := NewOptimizer(&NewOptimizerOptions{
+		Logger:         logger,
+		WSEventManager: events.NewMockWSEventManager(logger),
+		Database:       database,
+	})
+
+	// Not configured
+	_, err = o.QueueJobs([]*QueueJobOptions{{Path: "a.mkv"}}, QualityMedium)
+	assert.Error(t, err)
+
+	// Disabled so that the job loop does not start
+	libraryDir := t.TempDir()
+	o.SetSettings(&Settings{LibraryDir: libraryDir})
+
+	srcDir := t.TempDir()
+	paths := make([]string, 0)
+	for _, name := range []string{"ep1.mkv", "ep2.mkv", "ep3.mkv"} {
+		p := filepath.Join(srcDir, name)
+		require.NoError(t, os.WriteFile(p, []byte(name), 0644))
+		paths = append(paths, p)
+	}
+
+	// ep3 is already optimized
+	hash, err := videofile.GetHashFromPath(paths[2])
+	require.NoError(t, err)
+	optimizedPath := GetOptimizedFilePath(libraryDir, hash, QualityMedium)
+	require.NoError(t, os.MkdirAll(filepath.Dir(optimizedPath), 0755))
+	require.NoError(t, os.WriteFile(optimizedPath, []byte{}, 0644))
+
+	opts := []*QueueJobOptions{
+		{Path: paths[0], MediaID: 1},
+		{Path: paths[1], MediaID: 1},
+		{Path: paths[2], MediaID: 1},
+		{Path: filepath.Join(srcDir, "missing.mkv"), MediaID: 1},
+	}
+
+	// The default quality is used
+	added, err := o.QueueJobs(opts, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+
+	// Already queued
+	added, err = o.QueueJobs(opts, QualityMedium)
+	require.NoError(t, err)
+	assert.Equal(t, 0, added)
+
+	// Another quality
+	added, err = o.QueueJobs(opts[:1], QualityHigh)
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	_, err = o.QueueJobs(opts, "ultra")
+	assert.Error(t, err)
+
+	jobs, err := o.GetJobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+
+	// Canceled jobs are removed when clearing the finished jobs
+	require.NoError(t, o.CancelJob(jobs[0].ID))
+	assert.Error(t, o.CancelJob(jobs[0].ID))
+	require.NoError(t, o.ClearFinishedJobs())
+
+	jobs, err = o.GetJobs()
+	require.NoError(t, err)
+	assert.Len(t, jobs, 2)
+	for _, job := range jobs {
+		assert.Equal(t, JobStatusQueued, job.Status)
+	}
+}
@@ -35,6 +35,9 @@ type (
 		StreamType StreamType           `json:"streamType"` // Tells the frontend how to play the media.
 		StreamUrl  string               `json:"streamUrl"`  // The relative endpoint to stream the media.
 		MediaInfo  *videofile.MediaInfo `json:"mediaInfo"`
+		// The relative endpoint to the WebVTT thumbnails track, empty if the trickplay files are not generated yet.
+		// An events.MediastreamTrickplayReady event is sent once they are.
+		TrickplayUrl string `json:"trickplayUrl,omitempty"`
 		//Metadata  *Metadata       `json:"metadata"`
 		// todo: add more fields (e.g. metadata)
 	}
@@ -144,6 +147,15 @@ func (p *PlaybackManager) newMediaContainer(filepath string, streamType StreamTy
 
 	p.logger.Debug().Msg("mediastream: Extracted attachments")
 
+	// Serve the seek previews if they were generated, otherwise generate them in the background.
+	if p.repository.isTrickplayEnabled() {
+		if videofile.TrickplayExists(p.repository.cacheDir, hash) {
+			ret.TrickplayUrl = getTrickplayUrl(hash)
+		} else {
+			_ = p.repository.QueueTrickplay([]string{filepath}, true)
+		}
+	}
+
 	streamUrl := ""
 	switch streamType {
 	case StreamTypeDirect:
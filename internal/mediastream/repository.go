@@ -6,6 +6,7 @@ import (
 	"github.com/samber/mo"
 	"os"
 	"path/filepath"
+	"seanime/internal/database/db"
 	"seanime/internal/database/models"
 	"seanime/internal/events"
 	"seanime/internal/mediastream/optimizer"
@@ -28,6 +29,7 @@ type (
 		logger                     *zerolog.Logger
 		wsEventManager             events.WSEventManagerInterface
 		fileCacher                 *filecache.Cacher
+		trickplay                  *trickplayGenerator
 		reqMu                      sync.Mutex
 		cacheDir                   string // where attachments are stored
 		transcodeDir               string // where stream segments are stored
@@ -37,22 +39,27 @@ type (
 		Logger         *zerolog.Logger
 		WSEventManager events.WSEventManagerInterface
 		FileCacher     *filecache.Cacher
+		Database       *db.Database
 	}
 )
 
 func NewRepository(opts *NewRepositoryOptions) *Repository {
+	mediaInfoExtractor := videofile.NewMediaInfoExtractor(opts.FileCacher, opts.Logger)
 	ret := &Repository{
 		logger: opts.Logger,
 		optimizer: optimizer.NewOptimizer(&optimizer.NewOptimizerOptions{
-			Logger:         opts.Logger,
-			WSEventManager: opts.WSEventManager,
+			Logger:             opts.Logger,
+			WSEventManager:     opts.WSEventManager,
+			Database:           opts.Database,
+			MediaInfoExtractor: mediaInfoExtractor,
 		}),
 		directPlayVideoStreamCache: result.NewResultMap[string, *VideoStream](),
 		settings:                   mo.None[*models.MediastreamSettings](),
 		transcoder:                 mo.None[*transcoder.Transcoder](),
 		wsEventManager:             opts.WSEventManager,
 		fileCacher:                 opts.FileCacher,
-		mediaInfoExtractor:         videofile.NewMediaInfoExtractor(opts.FileCacher, opts.Logger),
+		mediaInfoExtractor:         mediaInfoExtractor,
+		trickplay:                  newTrickplayGenerator(),
 	}
 	ret.playbackManager = NewPlaybackManager(ret)
 
@@ -107,7 +114,16 @@ func (r *Repository) InitializeModules(settings *models.MediastreamSettings, cac
 	r.transcodeDir = transcodeDir
 
 	// Set the optimizer settings
-	r.optimizer.SetLibraryDir(settings.PreTranscodeLibraryDir)
+	r.optimizer.SetSettings(&optimizer.Settings{
+		Enabled:     settings.PreTranscodeEnabled,
+		LibraryDir:  settings.PreTranscodeLibraryDir,
+		FfmpegPath:  settings.FfmpegPath,
+		FfprobePath: settings.FfprobePath,
+		Quality:     optimizer.Quality(settings.PreTranscodeQuality),
+		WindowStart: settings.PreTranscodeWindowStart,
+		WindowEnd:   settings.PreTranscodeWindowEnd,
+		MaxThreads:  settings.PreTranscodeMaxThreads,
+	})
 
 	// Initialize the transcoder
 	if ok := r.initializeTranscoder(r.settings); ok {
@@ -163,7 +179,7 @@ func (r *Repository) StartMediaOptimization(opts *StartMediaOptimizationOptions)
 		return errors.New("module not initialized")
 	}
 
-	mediaInfo, err := r.mediaInfoExtractor.GetInfo(r.settings.MustGet().FfmpegPath, opts.Filepath)
+	mediaInfo, err := r.mediaInfoExtractor.GetInfo(r.settings.MustGet().FfprobePath, opts.Filepath)
 	if err != nil {
 		return
 	}
@@ -176,6 +192,35 @@ func (r *Repository) StartMediaOptimization(opts *StartMediaOptimizationOptions)
 	return
 }
 
+type QueuePreTranscodeJobsOptions struct {
+	Files   []*optimizer.QueueJobOptions
+	Quality optimizer.Quality // Default quality if empty
+}
+
+// QueuePreTranscodeJobs adds the files to the pre-transcode queue and returns the number of jobs added.
+func (r *Repository) QueuePreTranscodeJobs(opts *QueuePreTranscodeJobsOptions) (int, error) {
+	if !r.IsInitialized() {
+		return 0, errors.New("module not initialized")
+	}
+	if !r.settings.MustGet().PreTranscodeEnabled {
+		return 0, errors.New("pre-transcoding is not enabled")
+	}
+
+	return r.optimizer.QueueJobs(opts.Files, opts.Quality)
+}
+
+func (r *Repository) GetPreTranscodeJobs() ([]*models.MediastreamPreTranscodeJob, error) {
+	return r.optimizer.GetJobs()
+}
+
+func (r *Repository) CancelPreTranscodeJob(id uint) error {
+	return r.optimizer.CancelJob(id)
+}
+
+func (r *Repository) ClearFinishedPreTranscodeJobs() error {
+	return r.optimizer.ClearFinishedJobs()
+}
+
 func (r *Repository) RequestOptimizedStream(filepath string) (ret *MediaContainer, err error) {
 	if !r.IsInitialized() {
 		return nil, errors.New("module not initialized")
@@ -286,6 +331,7 @@ func (r *Repository) initializeTranscoder(settings mo.Option[*models.Mediastream
 		FfmpegPath:  settings.MustGet().FfmpegPath,
 		FfprobePath: settings.MustGet().FfprobePath,
 		TempOutDir:  r.transcodeDir,
+		CacheDir:    r.cacheDir,
 	}
 
 	tc, err := transcoder.NewTranscoder(opts)
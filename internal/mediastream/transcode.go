@@ -49,8 +49,21 @@ func (r *Repository) ServeFiberTranscodeStream(fiberCtx *fiber.Ctx, clientId str
 	}
 
 	// /master.m3u8
+	// /master.m3u8?burnSubtitle=:subtitle&hdr=true
 	if path == "master.m3u8" {
-		ret, err := r.transcoder.MustGet().GetMaster(mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, clientId)
+		// Clients that cannot render subtitles can request a subtitle track to be burned into the video
+		subtitle := transcoder.NoSubtitle
+		if burnSubtitle := fiberCtx.Query("burnSubtitle"); burnSubtitle != "" {
+			idx, err := strconv.ParseInt(burnSubtitle, 10, 32)
+			if err != nil {
+				return errors.New("invalid burnSubtitle query")
+			}
+			subtitle = int32(idx)
+		}
+		// Clients are assumed to be SDR, HDR and 10-bit sources are converted unless the client can display them
+		hdrClient := fiberCtx.QueryBool("hdr", false)
+
+		ret, err := r.transcoder.MustGet().GetMaster(mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, subtitle, hdrClient, clientId)
 		if err != nil {
 			return err
 		}
@@ -60,6 +73,17 @@ func (r *Repository) ServeFiberTranscodeStream(fiberCtx *fiber.Ctx, clientId str
 		return fiberCtx.SendString(ret)
 	}
 
+	// Original video stream converted to SDR
+	// /sdr/...
+	forceSdr, path := transcoder.ParseSdrPath(path)
+
+	// Video streams with a subtitle burned in
+	// /burn/:subtitle/:quality/...
+	subtitle, path, err := transcoder.ParseBurnInPath(path)
+	if err != nil {
+		return err
+	}
+
 	// Video stream
 	// /:quality/index.m3u8
 	if strings.HasSuffix(path, "index.m3u8") && !strings.Contains(path, "audio") {
@@ -73,7 +97,7 @@ func (r *Repository) ServeFiberTranscodeStream(fiberCtx *fiber.Ctx, clientId str
 			return err
 		}
 
-		ret, err := r.transcoder.MustGet().GetVideoIndex(mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, quality, clientId)
+		ret, err := r.transcoder.MustGet().GetVideoIndex(mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, transcoder.VideoKey{Quality: quality, Subtitle: subtitle, ForceSdr: forceSdr}, clientId)
 		if err != nil {
 			return err
 		}
@@ -120,7 +144,7 @@ func (r *Repository) ServeFiberTranscodeStream(fiberCtx *fiber.Ctx, clientId str
 			return err
 		}
 
-		ret, err := r.transcoder.MustGet().GetVideoSegment(mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, quality, segment, clientId)
+		ret, err := r.transcoder.MustGet().GetVideoSegment(mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, transcoder.VideoKey{Quality: quality, Subtitle: subtitle, ForceSdr: forceSdr}, segment, clientId)
 		if err != nil {
 			return err
 		}
@@ -32,6 +32,10 @@ func (as *AudioStream) getOutPath(encoderId int) string {
 	return filepath.Join(as.file.Out, fmt.Sprintf("segment-a%d-%d-%%d.ts", as.index, encoderId))
 }
 
+func (as *AudioStream) getDecodeFlags() []string {
+	return as.settings.HwAccel.DecodeFlags
+}
+
 func (as *AudioStream) getFlags() Flags {
 	return AudioF
 }
@@ -0,0 +1,131 @@
+package transcoder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"seanime/internal/mediastream/videofile"
+	"strconv"
+	"strings"
+)
+
+// Subtitle burn-in
+//
+// Clients that cannot render subtitles (e.g. TVs, Chromecast) can request a master playlist with a subtitle track burned into the video.
+// The burned variants are separate video streams, served under "burn/{subtitle}/{quality}/".
+// Since "copy" cannot be used, the original quality is transcoded at the source resolution.
+
+// NoSubtitle means that no subtitle track is burned into the video.
+const NoSubtitle int32 = -1
+
+// VideoKey identifies a video stream of a file.
+type VideoKey struct {
+	Quality  Quality
+	Subtitle int32 // Index of the subtitle track burned into the video, NoSubtitle if none
+	ForceSdr bool  // The original quality is converted to 8-bit SDR, see tonemap.go
+}
+
+func (k VideoKey) String() string {
+	ret := string(k.Quality)
+	if k.ForceSdr {
+		ret += ", sdr"
+	}
+	if k.Subtitle != NoSubtitle {
+		ret += fmt.Sprintf(", subtitle %d", k.Subtitle)
+	}
+	return ret
+}
+
+// normalize returns the key of the video stream.
+// Only the original quality can be copied, other qualities are always converted to SDR.
+func (k VideoKey) normalize() VideoKey {
+	k.ForceSdr = k.ForceSdr && k.Quality == Original
+	return k
+}
+
+// ParseBurnInPath parses the subtitle index of a burned video stream path and returns the rest of the path.
+//
+//	e.g. "burn/2/720p/index.m3u8" -> 2, "720p/index.m3u8"
+//	e.g. "720p/index.m3u8" -> NoSubtitle, "720p/index.m3u8"
+func ParseBurnInPath(path string) (int32, string, error) {
+	rest, found := strings.CutPrefix(path, "burn/")
+	if !found {
+		return NoSubtitle, path, nil
+	}
+	idxStr, rest, found := strings.Cut(rest, "/")
+	if !found {
+		return NoSubtitle, path, fmt.Errorf("invalid burn-in path: %s", path)
+	}
+	idx, err := strconv.ParseInt(idxStr, 10, 32)
+	if err != nil || idx < 0 {
+		return NoSubtitle, path, fmt.Errorf("invalid burn-in subtitle index: %s", idxStr)
+	}
+	return int32(idx), rest, nil
+}
+
+// getBurnInPrefix returns the path prefix of the video streams with the subtitle burned in.
+func getBurnInPrefix(subtitle int32) string {
+	if subtitle == NoSubtitle {
+		return ""
+	}
+	return fmt.Sprintf("burn/%d/", subtitle)
+}
+
+// getSubtitle returns the subtitle track with the given index.
+func (fs *FileStream) getSubtitle(subtitle int32) (*videofile.Subtitle, error) {
+	for _, sub := range fs.Info.Subtitles {
+		if int32(sub.Index) == subtitle {
+			return &sub, nil
+		}
+	}
+	return nil, fmt.Errorf("subtitle track %d not found", subtitle)
+}
+
+// getSubtitleFilter returns the filter that renders the subtitle track onto the video.
+// The subtitle files and fonts are the ones extracted when the media container was created, see videofile.ExtractAttachment.
+func (fs *FileStream) getSubtitleFilter(subtitle int32) (string, error) {
+	sub, err := fs.getSubtitle(subtitle)
+	if err != nil {
+		return "", err
+	}
+	if sub.Extension == nil || *sub.Extension == "" {
+		return "", fmt.Errorf("subtitle track %d cannot be burned in", subtitle)
+	}
+
+	subPath := filepath.Join(videofile.GetFileSubsCacheDir(fs.settings.CacheDir, fs.Sha), fmt.Sprintf("%d.%s", sub.Index, *sub.Extension))
+	if _, err := os.Stat(subPath); err != nil {
+		return "", fmt.Errorf("subtitle track %d was not extracted", subtitle)
+	}
+
+	filter := fmt.Sprintf("subtitles=filename=%s", escapeFilterValue(subPath))
+	// Fonts embedded in the file are used by ASS subtitles
+	fontsDir := videofile.GetFileAttCacheDir(fs.settings.CacheDir, fs.Sha)
+	if entries, err := os.ReadDir(fontsDir); err == nil && len(entries) > 0 {
+		filter += fmt.Sprintf(":fontsdir=%s", escapeFilterValue(fontsDir))
+	}
+
+	return filter, nil
+}
+
+// escapeFilterValue escapes a value for the filter's options and for the filtergraph.
+// See https://ffmpeg.org/ffmpeg-filters.html#Notes-on-filtergraph-escaping
+func escapeFilterValue(value string) string {
+	// Filter option level
+	value = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`).Replace(value)
+	// Filtergraph level
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`, `[`, `\[`, `]`, `\]`, `,`, `\,`, `;`, `\;`).Replace(value)
+}
+
+// removeHwOutputFormat removes the flag that keeps decoded frames in GPU memory.
+// Subtitles are rendered on the CPU, the frames are uploaded back to the GPU by the scale filter.
+func removeHwOutputFormat(flags []string) []string {
+	ret := make([]string, 0, len(flags))
+	for i := 0; i < len(flags); i++ {
+		if flags[i] == "-hwaccel_output_format" {
+			i++
+			continue
+		}
+		ret = append(ret, flags[i])
+	}
+	return ret
+}
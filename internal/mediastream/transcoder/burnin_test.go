@@ -0,0 +1,37 @@
+package transcoder
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseBurnInPath(t *testing.T) {
+	subtitle, rest, err := ParseBurnInPath("burn/2/720p/index.m3u8")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), subtitle)
+	assert.Equal(t, "720p/index.m3u8", rest)
+
+	subtitle, rest, err = ParseBurnInPath("720p/segments-1.ts")
+	require.NoError(t, err)
+	assert.Equal(t, NoSubtitle, subtitle)
+	assert.Equal(t, "720p/segments-1.ts", rest)
+
+	_, _, err = ParseBurnInPath("burn/abc/720p/index.m3u8")
+	assert.Error(t, err)
+
+	_, _, err = ParseBurnInPath("burn/2")
+	assert.Error(t, err)
+}
+
+func TestEscapeFilterValue(t *testing.T) {
+	assert.Equal(t, `/cache/videofiles/abc/subs/0.ass`, escapeFilterValue("/cache/videofiles/abc/subs/0.ass"))
+	assert.Equal(t, `C\\:\\\\cache\\\\0.ass`, escapeFilterValue(`C:\cache\0.ass`))
+	assert.Equal(t, `/subs/it\\\'s\,\[1\].ass`, escapeFilterValue("/subs/it's,[1].ass"))
+}
+
+func TestRemoveHwOutputFormat(t *testing.T) {
+	flags := GetHardwareAccelSettings(HwAccelOptions{Kind: "nvidia", Preset: "fast"}).DecodeFlags
+	assert.Equal(t, []string{"-hwaccel", "cuda"}, removeHwOutputFormat(flags))
+	assert.Empty(t, removeHwOutputFormat([]string{}))
+}
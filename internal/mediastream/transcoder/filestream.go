@@ -21,14 +21,15 @@ import (
 // FileStream represents a stream of file data.
 // It holds the keyframes, media information, video streams, and audio streams.
 type FileStream struct {
-	ready     sync.WaitGroup                     // A WaitGroup to synchronize go routines.
-	err       error                              // An error that might occur during processing.
-	Path      string                             // The path of the file.
-	Out       string                             // The output path.
-	Keyframes *Keyframe                          // The keyframes of the video.
-	Info      *videofile.MediaInfo               // The media information of the file.
-	videos    *result.Map[Quality, *VideoStream] // A map of video streams.
-	audios    *result.Map[int32, *AudioStream]   // A map of audio streams.
+	ready     sync.WaitGroup                      // A WaitGroup to synchronize go routines.
+	err       error                               // An error that might occur during processing.
+	Path      string                              // The path of the file.
+	Sha       string                              // The hash of the file.
+	Out       string                              // The output path.
+	Keyframes *Keyframe                           // The keyframes of the video.
+	Info      *videofile.MediaInfo                // The media information of the file.
+	videos    *result.Map[VideoKey, *VideoStream] // A map of video streams.
+	audios    *result.Map[int32, *AudioStream]    // A map of audio streams.
 	logger    *zerolog.Logger
 	settings  *Settings
 }
@@ -43,8 +44,9 @@ func NewFileStream(
 ) *FileStream {
 	ret := &FileStream{
 		Path:     path,
+		Sha:      sha,
 		Out:      filepath.Join(settings.StreamDir, sha),
-		videos:   result.NewResultMap[Quality, *VideoStream](),
+		videos:   result.NewResultMap[VideoKey, *VideoStream](),
 		audios:   result.NewResultMap[int32, *AudioStream](),
 		logger:   logger,
 		settings: settings,
@@ -62,7 +64,7 @@ func NewFileStream(
 
 // Kill stops all streams.
 func (fs *FileStream) Kill() {
-	fs.videos.Range(func(_ Quality, s *VideoStream) bool {
+	fs.videos.Range(func(_ VideoKey, s *VideoStream) bool {
 		s.Kill()
 		return true
 	})
@@ -80,7 +82,21 @@ func (fs *FileStream) Destroy() {
 }
 
 // GetMaster generates the master playlist.
-func (fs *FileStream) GetMaster() string {
+// If subtitle is not NoSubtitle, the video streams point to the variants with the subtitle track burned in.
+// If hdrClient is false, the original quality of HDR and 10-bit sources points to the variant converted to SDR.
+func (fs *FileStream) GetMaster(subtitle int32, hdrClient bool) (string, error) {
+	if subtitle != NoSubtitle {
+		if _, err := fs.getSubtitle(subtitle); err != nil {
+			return "", err
+		}
+	}
+	prefix := getBurnInPrefix(subtitle)
+	forceSdr := fs.needsSdrOriginal(hdrClient)
+	originalPrefix := prefix
+	if forceSdr {
+		originalPrefix = sdrPathPrefix + prefix
+	}
+
 	master := "#EXTM3U\n"
 	if fs.Info.Video != nil {
 		var transmuxQuality Quality
@@ -90,23 +106,26 @@ func (fs *FileStream) GetMaster() string {
 				break
 			}
 		}
+		// codec is the prefix + the level, the level is not part of the codec we want to compare for the same_codec check bellow
+		transmuxPrefix := "avc1.6400"
+		transmuxCodec := transmuxPrefix + "28"
 		{
 			bitrate := float64(fs.Info.Video.Bitrate)
 			master += "#EXT-X-STREAM-INF:"
 			master += fmt.Sprintf("AVERAGE-BANDWIDTH=%d,", int(math.Min(bitrate*0.8, float64(transmuxQuality.AverageBitrate()))))
 			master += fmt.Sprintf("BANDWIDTH=%d,", int(math.Min(bitrate, float64(transmuxQuality.MaxBitrate()))))
 			master += fmt.Sprintf("RESOLUTION=%dx%d,", fs.Info.Video.Width, fs.Info.Video.Height)
-			if fs.Info.Video.MimeCodec != nil {
+			if subtitle != NoSubtitle || forceSdr {
+				// The original quality is transcoded when the subtitle is burned in or when converted to SDR
+				master += fmt.Sprintf("CODECS=\"%s\",", transmuxCodec)
+			} else if fs.Info.Video.MimeCodec != nil {
 				master += fmt.Sprintf("CODECS=\"%s\",", *fs.Info.Video.MimeCodec)
 			}
 			master += "AUDIO=\"audio\","
 			master += "CLOSED-CAPTIONS=NONE\n"
-			master += fmt.Sprintf("./%s/index.m3u8\n", Original)
+			master += fmt.Sprintf("./%s%s/index.m3u8\n", originalPrefix, Original)
 		}
 		aspectRatio := float32(fs.Info.Video.Width) / float32(fs.Info.Video.Height)
-		// codec is the prefix + the level, the level is not part of the codec we want to compare for the same_codec check bellow
-		transmuxPrefix := "avc1.6400"
-		transmuxCodec := transmuxPrefix + "28"
 
 		for _, quality := range Qualities {
 			sameCodec := fs.Info.Video.MimeCodec != nil && strings.HasPrefix(*fs.Info.Video.MimeCodec, transmuxPrefix)
@@ -120,7 +139,7 @@ func (fs *FileStream) GetMaster() string {
 				master += fmt.Sprintf("CODECS=\"%s\",", transmuxCodec)
 				master += "AUDIO=\"audio\","
 				master += "CLOSED-CAPTIONS=NONE\n"
-				master += fmt.Sprintf("./%s/index.m3u8\n", quality)
+				master += fmt.Sprintf("./%s%s/index.m3u8\n", prefix, quality)
 			}
 		}
 
@@ -156,20 +175,21 @@ func (fs *FileStream) GetMaster() string {
 		master += "CHANNELS=\"2\","
 		master += fmt.Sprintf("URI=\"./audio/%d/index.m3u8\"\n", audio.Index)
 	}
-	return master
+	return master, nil
 }
 
 // GetVideoIndex gets the index of a video stream of a specific quality.
-func (fs *FileStream) GetVideoIndex(quality Quality) (string, error) {
-	stream := fs.getVideoStream(quality)
+func (fs *FileStream) GetVideoIndex(key VideoKey) (string, error) {
+	stream := fs.getVideoStream(key)
 	return stream.GetIndex()
 }
 
-// getVideoStream gets a video stream of a specific quality.
+// getVideoStream gets a video stream of a specific quality, with the subtitle track burned in if subtitle is not NoSubtitle.
 // It creates a new stream if it does not exist.
-func (fs *FileStream) getVideoStream(quality Quality) *VideoStream {
-	stream, _ := fs.videos.GetOrSet(quality, func() (*VideoStream, error) {
-		return NewVideoStream(fs, quality, fs.logger, fs.settings), nil
+func (fs *FileStream) getVideoStream(key VideoKey) *VideoStream {
+	key = key.normalize()
+	stream, _ := fs.videos.GetOrSet(key, func() (*VideoStream, error) {
+		return NewVideoStream(fs, key, fs.logger, fs.settings), nil
 	})
 	return stream
 }
@@ -181,12 +201,12 @@ func (fs *FileStream) getVideoStream(quality Quality) *VideoStream {
 //}
 
 // GetVideoSegment gets a segment of a video stream of a specific quality.
-func (fs *FileStream) GetVideoSegment(quality Quality, segment int32) (string, error) {
-	streamLogger.Debug().Msgf("filestream: Retrieving video segment %d (%s)", segment, quality)
+func (fs *FileStream) GetVideoSegment(key VideoKey, segment int32) (string, error) {
+	streamLogger.Debug().Msgf("filestream: Retrieving video segment %d (%s)", segment, key)
 	// Debug
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
 	defer cancel()
-	debugStreamRequest(fmt.Sprintf("video %s, segment %d", quality, segment), ctx)
+	debugStreamRequest(fmt.Sprintf("video %s, segment %d", key, segment), ctx)
 
 	//stream := fs.getVideoStream(quality)
 	//return stream.GetSegment(segment)
@@ -200,7 +220,7 @@ func (fs *FileStream) GetVideoSegment(quality Quality, segment int32) (string, e
 	// Execute the retrieval operation in a goroutine
 	go func() {
 		defer close(done)
-		stream := fs.getVideoStream(quality)
+		stream := fs.getVideoStream(key)
 		ret, err = stream.GetSegment(segment)
 	}()
 
@@ -209,7 +229,7 @@ func (fs *FileStream) GetVideoSegment(quality Quality, segment int32) (string, e
 	case <-done:
 		return ret, err
 	case <-ctx.Done():
-		return "", fmt.Errorf("filestream: timeout while retrieving video segment %d (%s)", segment, quality)
+		return "", fmt.Errorf("filestream: timeout while retrieving video segment %d (%s)", segment, key)
 	}
 }
 
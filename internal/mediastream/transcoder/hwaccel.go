@@ -76,6 +76,8 @@ func GetHardwareAccelSettings(opts HwAccelOptions) HwAccelSettings {
 			// }
 			// See https://www.reddit.com/r/ffmpeg/comments/1bqn60w/hardware_accelerated_decoding_without_hwdownload/ for more info
 			ScaleFilter: "format=nv12|vaapi,hwupload,scale_vaapi=%d:%d:format=nv12",
+			// tonemap_vaapi needs 10bits frames (p010) and outputs nv12 in bt709
+			ToneMapFilter: "format=p010|vaapi,hwupload,tonemap_vaapi=format=nv12:t=bt709:m=bt709:p=bt709,scale_vaapi=%d:%d:format=nv12",
 		}
 	case "qsv", "intel":
 		return HwAccelSettings{
@@ -91,6 +93,8 @@ func GetHardwareAccelSettings(opts HwAccelOptions) HwAccelSettings {
 			},
 			// see note on ScaleFilter of the vaapi HwAccel, this is the same filter but adapted to qsv
 			ScaleFilter: "format=nv12|qsv,hwupload,scale_qsv=%d:%d:format=nv12",
+			// vpp_qsv can tone-map and scale in a single pass
+			ToneMapFilter: "format=p010|qsv,hwupload,vpp_qsv=w=%d:h=%d:format=nv12:tonemap=1",
 		}
 	case "nvidia":
 		return HwAccelSettings{
@@ -15,4 +15,6 @@ type HwAccelSettings struct {
 	DecodeFlags []string
 	EncodeFlags []string
 	ScaleFilter string
+	// Filter that tone-maps HDR frames to SDR and scales them on the GPU, empty if not supported by the accelerator
+	ToneMapFilter string
 }
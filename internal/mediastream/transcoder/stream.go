@@ -34,6 +34,7 @@ const (
 
 type StreamHandle interface {
 	getTranscodeArgs(segments string) []string
+	getDecodeFlags() []string
 	getOutPath(encoderId int) string
 	getFlags() Flags
 }
@@ -424,7 +425,7 @@ func (ts *Stream) run(start int32) error {
 		"-nostats", "-hide_banner", "-loglevel", "warning",
 	}
 
-	args = append(args, ts.settings.HwAccel.DecodeFlags...)
+	args = append(args, ts.handle.getDecodeFlags()...)
 
 	if startRef != 0 {
 		if ts.handle.getFlags()&VideoF != 0 {
@@ -0,0 +1,43 @@
+package transcoder
+
+import (
+	"strings"
+)
+
+// HDR and 10-bit sources
+//
+// Transcoded video streams are always encoded in 8-bit SDR since most clients cannot decode anything else,
+// HDR sources are tone-mapped so that colors are not washed out.
+// The original quality is copied as is, unless the client cannot display HDR or 10-bit videos,
+// in which case it is transcoded at the source resolution and served under "sdr/".
+
+const (
+	// SoftwareToneMapFilter converts HDR (PQ/HLG) frames to 8-bit BT.709 SDR on the CPU.
+	// The frames are linearized and converted to float so that the tone-mapping is not affected by the transfer function.
+	SoftwareToneMapFilter = "zscale=t=linear:npl=100,format=gbrpf32le,zscale=p=bt709,tonemap=tonemap=hable:desat=0,zscale=t=bt709:m=bt709:r=tv,format=yuv420p"
+	sdrPathPrefix         = "sdr/"
+)
+
+// ParseSdrPath returns true if the path is the one of a video stream converted to SDR and returns the rest of the path.
+//
+//	e.g. "sdr/original/index.m3u8" -> true, "original/index.m3u8"
+func ParseSdrPath(path string) (bool, string) {
+	rest, found := strings.CutPrefix(path, sdrPathPrefix)
+	return found, rest
+}
+
+// needsSdrOriginal returns true if the original quality cannot be copied for a client that cannot display HDR or 10-bit videos.
+func (fs *FileStream) needsSdrOriginal(hdrClient bool) bool {
+	return !hdrClient && fs.Info.Video != nil && !fs.Info.Video.Is8BitSDR()
+}
+
+// isHDR returns true if the source should be tone-mapped when transcoded.
+func (fs *FileStream) isHDR() bool {
+	return fs.Info.Video != nil && fs.Info.Video.IsHDR()
+}
+
+// useHwToneMap returns true if the hardware accelerator can tone-map and scale the video on the GPU.
+// Subtitles are rendered on the CPU after tone-mapping, so the software tone-mapping is used when they are burned in.
+func useHwToneMap(hwAccel HwAccelSettings, subtitle int32) bool {
+	return hwAccel.ToneMapFilter != "" && subtitle == NoSubtitle
+}
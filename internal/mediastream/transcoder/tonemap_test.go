@@ -0,0 +1,107 @@
+package transcoder
+
+import (
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seanime/internal/mediastream/videofile"
+	"seanime/internal/util"
+	"strings"
+	"testing"
+)
+
+func newTestFileStream(video *videofile.Video, hwAccel string) *FileStream {
+	return &FileStream{
+		Info: &videofile.MediaInfo{
+			Video:    video,
+			Duration: 1420,
+		},
+		settings: &Settings{
+			HwAccel: GetHardwareAccelSettings(HwAccelOptions{Kind: hwAccel, Preset: "fast"}),
+		},
+		logger: util.NewLogger(),
+	}
+}
+
+func newTestVideoStream(fs *FileStream, key VideoKey) *VideoStream {
+	vs := &VideoStream{
+		quality:  key.Quality,
+		subtitle: key.Subtitle,
+		forceSdr: key.ForceSdr,
+		logger:   fs.logger,
+		settings: fs.settings,
+	}
+	vs.file = fs
+	return vs
+}
+
+func getFilterArg(args []string) string {
+	idx := lo.IndexOf(args, "-vf")
+	if idx == -1 {
+		return ""
+	}
+	return args[idx+1]
+}
+
+var hdrVideo = &videofile.Video{
+	Codec:         "hevc",
+	MimeCodec:     lo.ToPtr("hvc1.2.4.L120.90"),
+	Quality:       videofile.P1080,
+	Width:         1920,
+	Height:        1080,
+	Bitrate:       8_000_000,
+	BitDepth:      10,
+	ColorTransfer: videofile.ColorTransferPQ,
+}
+
+func TestGetMaster_Sdr(t *testing.T) {
+	fs := newTestFileStream(hdrVideo, "disabled")
+
+	// SDR clients get the original quality converted to SDR
+	master, err := fs.GetMaster(NoSubtitle, false)
+	require.NoError(t, err)
+	assert.Contains(t, master, "./sdr/original/index.m3u8")
+	assert.NotContains(t, master, "hvc1")
+
+	// HDR clients get the original quality as is
+	master, err = fs.GetMaster(NoSubtitle, true)
+	require.NoError(t, err)
+	assert.Contains(t, master, "./original/index.m3u8")
+	assert.NotContains(t, master, "sdr/")
+
+	// 8-bit SDR sources are not converted
+	fs = newTestFileStream(&videofile.Video{Quality: videofile.P1080, Width: 1920, Height: 1080, Bitrate: 8_000_000, BitDepth: 8}, "disabled")
+	master, err = fs.GetMaster(NoSubtitle, false)
+	require.NoError(t, err)
+	assert.NotContains(t, master, "sdr/")
+}
+
+func TestVideoStream_ToneMap(t *testing.T) {
+	// Software
+	fs := newTestFileStream(hdrVideo, "disabled")
+	args := newTestVideoStream(fs, VideoKey{Quality: P720, Subtitle: NoSubtitle}).getTranscodeArgs("")
+	assert.Equal(t, SoftwareToneMapFilter+",scale=1280:720", getFilterArg(args))
+
+	// The original quality is copied unless converted to SDR
+	args = newTestVideoStream(fs, VideoKey{Quality: Original, Subtitle: NoSubtitle}).getTranscodeArgs("")
+	assert.Contains(t, args, "copy")
+	args = newTestVideoStream(fs, VideoKey{Quality: Original, Subtitle: NoSubtitle, ForceSdr: true}).getTranscodeArgs("")
+	assert.Equal(t, SoftwareToneMapFilter+",scale=1920:1080", getFilterArg(args))
+
+	// VAAPI tone-maps on the GPU
+	fs = newTestFileStream(hdrVideo, "vaapi")
+	vs := newTestVideoStream(fs, VideoKey{Quality: P720, Subtitle: NoSubtitle})
+	assert.True(t, strings.Contains(getFilterArg(vs.getTranscodeArgs("")), "tonemap_vaapi"))
+	assert.Contains(t, vs.getDecodeFlags(), "-hwaccel_output_format")
+
+	// NVIDIA tone-maps on the CPU, frames are not kept in GPU memory
+	fs = newTestFileStream(hdrVideo, "nvidia")
+	vs = newTestVideoStream(fs, VideoKey{Quality: P720, Subtitle: NoSubtitle})
+	assert.True(t, strings.HasPrefix(getFilterArg(vs.getTranscodeArgs("")), SoftwareToneMapFilter+",format=nv12|cuda"))
+	assert.NotContains(t, vs.getDecodeFlags(), "-hwaccel_output_format")
+
+	// SDR sources are not tone-mapped
+	fs = newTestFileStream(&videofile.Video{Quality: videofile.P1080, Width: 1920, Height: 1080, BitDepth: 10}, "disabled")
+	args = newTestVideoStream(fs, VideoKey{Quality: P720, Subtitle: NoSubtitle}).getTranscodeArgs("")
+	assert.Equal(t, "scale=1280:720", getFilterArg(args))
+}
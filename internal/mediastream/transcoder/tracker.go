@@ -10,11 +10,11 @@ import (
 )
 
 type ClientInfo struct {
-	client  string
-	path    string
-	quality *Quality
-	audio   int32
-	head    int32
+	client string
+	path   string
+	video  *VideoKey
+	audio  int32
+	head   int32
 }
 
 type Tracker struct {
@@ -71,8 +71,8 @@ func (t *Tracker) start() {
 			old, ok := t.clients[info.client]
 			// First fixup the info. Most routes return partial infos
 			if ok && old.path == info.path {
-				if info.quality == nil {
-					info.quality = old.quality
+				if info.video == nil {
+					info.video = old.video
 				}
 				if info.audio == -1 {
 					info.audio = old.audio
@@ -91,11 +91,11 @@ func (t *Tracker) start() {
 				if old.audio != info.audio && old.audio != -1 {
 					t.KillAudioIfDead(old.path, old.audio)
 				}
-				if old.quality != info.quality && old.quality != nil {
-					t.KillQualityIfDead(old.path, *old.quality)
+				if old.video != nil && (info.video == nil || *old.video != *info.video) {
+					t.KillQualityIfDead(old.path, *old.video)
 				}
 				if old.head != -1 && Abs(info.head-old.head) > 100 {
-					t.KillOrphanedHeads(old.path, old.quality, old.audio)
+					t.KillOrphanedHeads(old.path, old.video, old.audio)
 				}
 			} else if ok {
 				t.KillStreamIfDead(old.path)
@@ -112,9 +112,9 @@ func (t *Tracker) start() {
 
 				if !t.KillStreamIfDead(info.path) {
 					audioCleanup := info.audio != -1 && t.KillAudioIfDead(info.path, info.audio)
-					videoCleanup := info.quality != nil && t.KillQualityIfDead(info.path, *info.quality)
+					videoCleanup := info.video != nil && t.KillQualityIfDead(info.path, *info.video)
 					if !audioCleanup || !videoCleanup {
-						t.KillOrphanedHeads(info.path, info.quality, info.audio)
+						t.KillOrphanedHeads(info.path, info.video, info.audio)
 					}
 				}
 
@@ -185,20 +185,20 @@ func (t *Tracker) KillAudioIfDead(path string, audio int32) bool {
 	return true
 }
 
-func (t *Tracker) KillQualityIfDead(path string, quality Quality) bool {
+func (t *Tracker) KillQualityIfDead(path string, video VideoKey) bool {
 	for _, stream := range t.clients {
-		if stream.path == path && stream.quality != nil && *stream.quality == quality {
+		if stream.path == path && stream.video != nil && *stream.video == video {
 			return false
 		}
 	}
 	//start := time.Now()
-	t.logger.Trace().Msgf("transcoder: Killing %s video stream ", quality)
+	t.logger.Trace().Msgf("transcoder: Killing %s video stream ", video)
 
 	stream, ok := t.transcoder.streams.Get(path)
 	if !ok {
 		return false
 	}
-	vstream, vok := stream.videos.Get(quality)
+	vstream, vok := stream.videos.Get(video)
 	if !vok {
 		return false
 	}
@@ -208,14 +208,14 @@ func (t *Tracker) KillQualityIfDead(path string, quality Quality) bool {
 	return true
 }
 
-func (t *Tracker) KillOrphanedHeads(path string, quality *Quality, audio int32) {
+func (t *Tracker) KillOrphanedHeads(path string, video *VideoKey, audio int32) {
 	stream, ok := t.transcoder.streams.Get(path)
 	if !ok {
 		return
 	}
 
-	if quality != nil {
-		vstream, vok := stream.videos.Get(*quality)
+	if video != nil {
+		vstream, vok := stream.videos.Get(*video)
 		if vok {
 			t.killOrphanedHeads(&vstream.Stream)
 		}
@@ -7,6 +7,7 @@ package transcoder
 import (
 	"fmt"
 	"github.com/rs/zerolog"
+	"github.com/samber/lo"
 	"os"
 	"path"
 	"path/filepath"
@@ -30,6 +31,7 @@ type (
 		HwAccel     HwAccelSettings
 		FfmpegPath  string
 		FfprobePath string
+		CacheDir    string // Where the subtitles and attachments are extracted
 	}
 
 	NewTranscoderOptions struct {
@@ -39,6 +41,7 @@ type (
 		TempOutDir  string
 		FfmpegPath  string
 		FfprobePath string
+		CacheDir    string
 	}
 )
 
@@ -69,6 +72,7 @@ func NewTranscoder(opts *NewTranscoderOptions) (*Transcoder, error) {
 			}),
 			FfmpegPath:  opts.FfmpegPath,
 			FfprobePath: opts.FfprobePath,
+			CacheDir:    opts.CacheDir,
 		},
 	}
 	ret.tracker = NewTracker(ret)
@@ -122,7 +126,10 @@ func (t *Transcoder) getFileStream(path string, hash string, mediaInfo *videofil
 	return ret, nil
 }
 
-func (t *Transcoder) GetMaster(path string, hash string, mediaInfo *videofile.MediaInfo, client string) (string, error) {
+// GetMaster returns the master playlist.
+// If subtitle is not NoSubtitle, the video streams of the playlist have the subtitle track burned in.
+// If hdrClient is false, HDR and 10-bit sources are converted to 8-bit SDR.
+func (t *Transcoder) GetMaster(path string, hash string, mediaInfo *videofile.MediaInfo, subtitle int32, hdrClient bool, client string) (string, error) {
 	if debugStream {
 		start := time.Now()
 		t.logger.Trace().Msgf("transcoder: Retrieving master file")
@@ -133,25 +140,25 @@ func (t *Transcoder) GetMaster(path string, hash string, mediaInfo *videofile.Me
 		return "", err
 	}
 	t.clientChan <- ClientInfo{
-		client:  client,
-		path:    path,
-		quality: nil,
-		audio:   -1,
-		head:    -1,
+		client: client,
+		path:   path,
+		video:  nil,
+		audio:  -1,
+		head:   -1,
 	}
-	return stream.GetMaster(), nil
+	return stream.GetMaster(subtitle, hdrClient)
 }
 
 func (t *Transcoder) GetVideoIndex(
 	path string,
 	hash string,
 	mediaInfo *videofile.MediaInfo,
-	quality Quality,
+	key VideoKey,
 	client string,
 ) (string, error) {
 	if debugStream {
 		start := time.Now()
-		t.logger.Trace().Msgf("transcoder: Retrieving video index file (%s)", quality)
+		t.logger.Trace().Msgf("transcoder: Retrieving video index file (%s)", key)
 		defer t.logger.Trace().Msgf("transcoder: Video index file retrieved in %.2fs", time.Since(start).Seconds())
 	}
 	stream, err := t.getFileStream(path, hash, mediaInfo)
@@ -159,13 +166,13 @@ func (t *Transcoder) GetVideoIndex(
 		return "", err
 	}
 	t.clientChan <- ClientInfo{
-		client:  client,
-		path:    path,
-		quality: &quality,
-		audio:   -1,
-		head:    -1,
+		client: client,
+		path:   path,
+		video:  lo.ToPtr(key.normalize()),
+		audio:  -1,
+		head:   -1,
 	}
-	return stream.GetVideoIndex(quality)
+	return stream.GetVideoIndex(key)
 }
 
 func (t *Transcoder) GetAudioIndex(
@@ -197,13 +204,13 @@ func (t *Transcoder) GetVideoSegment(
 	path string,
 	hash string,
 	mediaInfo *videofile.MediaInfo,
-	quality Quality,
+	key VideoKey,
 	segment int32,
 	client string,
 ) (string, error) {
 	if debugStream {
 		start := time.Now()
-		t.logger.Trace().Msgf("transcoder: Retrieving video segment %d (%s) [GetVideoSegment]", segment, quality)
+		t.logger.Trace().Msgf("transcoder: Retrieving video segment %d (%s) [GetVideoSegment]", segment, key)
 		defer t.logger.Trace().Msgf("transcoder: Video segment retrieved in %.2fs", time.Since(start).Seconds())
 	}
 	stream, err := t.getFileStream(path, hash, mediaInfo)
@@ -212,14 +219,14 @@ func (t *Transcoder) GetVideoSegment(
 	}
 	//t.logger.Trace().Msgf("transcoder: Sending client info, segment %d (%s) [GetVideoSegment]", segment, quality)
 	t.clientChan <- ClientInfo{
-		client:  client,
-		path:    path,
-		quality: &quality,
-		audio:   -1,
-		head:    segment,
+		client: client,
+		path:   path,
+		video:  lo.ToPtr(key.normalize()),
+		audio:  -1,
+		head:   segment,
 	}
 	//t.logger.Trace().Msgf("transcoder: Getting video segment %d (%s) [GetVideoSegment]", segment, quality)
-	return stream.GetVideoSegment(quality, segment)
+	return stream.GetVideoSegment(key, segment)
 }
 
 func (t *Transcoder) GetAudioSegment(
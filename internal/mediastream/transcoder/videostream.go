@@ -8,34 +8,65 @@ import (
 	"fmt"
 	"github.com/rs/zerolog"
 	"path/filepath"
+	"strings"
 )
 
 type VideoStream struct {
 	Stream
 	quality  Quality
+	subtitle int32 // Index of the subtitle track burned into the video, NoSubtitle if none
+	forceSdr bool  // The original quality is converted to 8-bit SDR
 	logger   *zerolog.Logger
 	settings *Settings
 }
 
-func NewVideoStream(file *FileStream, quality Quality, logger *zerolog.Logger, settings *Settings) *VideoStream {
-	logger.Trace().Str("file", filepath.Base(file.Path)).Any("quality", quality).Msgf("transcoder: Creating video stream")
+func NewVideoStream(file *FileStream, key VideoKey, logger *zerolog.Logger, settings *Settings) *VideoStream {
+	logger.Trace().Str("file", filepath.Base(file.Path)).Any("quality", key.Quality).Int32("subtitle", key.Subtitle).Msgf("transcoder: Creating video stream")
 	ret := new(VideoStream)
-	ret.quality = quality
+	ret.quality = key.Quality
+	ret.subtitle = key.Subtitle
+	ret.forceSdr = key.ForceSdr
 	ret.logger = logger
 	ret.settings = settings
-	NewStream(fmt.Sprintf("video (%s)", quality), file, ret, &ret.Stream, settings, logger)
+	NewStream(fmt.Sprintf("video (%s)", key), file, ret, &ret.Stream, settings, logger)
 	return ret
 }
 
+func (vs *VideoStream) isTransmux() bool {
+	return vs.quality == Original && vs.subtitle == NoSubtitle && !vs.forceSdr
+}
+
 func (vs *VideoStream) getFlags() Flags {
-	if vs.quality == Original {
+	if vs.isTransmux() {
 		return VideoF | Transmux
 	}
 	return VideoF
 }
 
 func (vs *VideoStream) getOutPath(encoderId int) string {
-	return filepath.Join(vs.file.Out, fmt.Sprintf("segment-%s-%d-%%d.ts", vs.quality, encoderId))
+	variant := string(vs.quality)
+	if vs.forceSdr {
+		variant += "-sdr"
+	}
+	if vs.subtitle != NoSubtitle {
+		variant += fmt.Sprintf("-s%d", vs.subtitle)
+	}
+	return filepath.Join(vs.file.Out, fmt.Sprintf("segment-%s-%d-%%d.ts", variant, encoderId))
+}
+
+// needsCpuFilters returns true if the frames are processed on the CPU before being scaled.
+func (vs *VideoStream) needsCpuFilters() bool {
+	if vs.isTransmux() {
+		return false
+	}
+	return vs.subtitle != NoSubtitle || (vs.file.isHDR() && !useHwToneMap(vs.settings.HwAccel, vs.subtitle))
+}
+
+func (vs *VideoStream) getDecodeFlags() []string {
+	if vs.needsCpuFilters() {
+		return removeHwOutputFormat(vs.settings.HwAccel.DecodeFlags)
+	}
+	return vs.settings.HwAccel.DecodeFlags
 }
 
 func closestMultiple(n int32, x int32) int32 {
@@ -53,24 +84,53 @@ func (vs *VideoStream) getTranscodeArgs(segments string) []string {
 		"-map", "0:V:0",
 	}
 
-	if vs.quality == Original {
+	if vs.isTransmux() {
 		args = append(args,
 			"-c:v", "copy",
 		)
 		return args
 	}
 
+	// The original quality is only transcoded when a subtitle is burned in or when converted to SDR, keep the source resolution
+	quality := vs.quality
+	height := vs.file.Info.Video.Height
+	if quality == Original {
+		quality = QualityFromHeight(height)
+	} else {
+		height = quality.Height()
+	}
+
 	args = append(args, vs.settings.HwAccel.EncodeFlags...)
-	width := int32(float64(vs.quality.Height()) / float64(vs.file.Info.Video.Height) * float64(vs.file.Info.Video.Width))
+	width := int32(float64(height) / float64(vs.file.Info.Video.Height) * float64(vs.file.Info.Video.Width))
 	// force a width that is a multiple of two else some apps behave badly.
 	width = closestMultiple(width, 2)
+	filters := make([]string, 0, 3)
+	scaleFilter := vs.settings.HwAccel.ScaleFilter
+	if vs.file.isHDR() {
+		// The output is 8-bit SDR, tone-map the frames so that colors are not washed out
+		if useHwToneMap(vs.settings.HwAccel, vs.subtitle) {
+			scaleFilter = vs.settings.HwAccel.ToneMapFilter
+		} else {
+			filters = append(filters, SoftwareToneMapFilter)
+		}
+	}
+	if vs.subtitle != NoSubtitle {
+		// Render the subtitles before scaling so that they are positioned relative to the source resolution
+		subtitleFilter, err := vs.file.getSubtitleFilter(vs.subtitle)
+		if err != nil {
+			vs.logger.Error().Err(err).Msg("transcoder: Cannot burn in subtitles")
+		} else {
+			filters = append(filters, subtitleFilter)
+		}
+	}
+	filters = append(filters, fmt.Sprintf(scaleFilter, width, height))
 	args = append(args,
-		"-vf", fmt.Sprintf(vs.settings.HwAccel.ScaleFilter, width, vs.quality.Height()),
+		"-vf", strings.Join(filters, ","),
 		// Even less sure but buf size are 5x the average bitrate since the average bitrate is only
 		// useful for hls segments.
-		"-bufsize", fmt.Sprint(vs.quality.MaxBitrate()*5),
-		"-b:v", fmt.Sprint(vs.quality.AverageBitrate()),
-		"-maxrate", fmt.Sprint(vs.quality.MaxBitrate()),
+		"-bufsize", fmt.Sprint(quality.MaxBitrate()*5),
+		"-b:v", fmt.Sprint(quality.AverageBitrate()),
+		"-maxrate", fmt.Sprint(quality.MaxBitrate()),
 		// Force segments to be split exactly on keyframes (only works when transcoding)
 		// forced-idr is needed to force keyframes to be an idr-frame (by default it can be any i frames)
 		// without this option, some hardware encoders uses others i-frames and the -f segment can't cut at them.
@@ -0,0 +1,321 @@
+package mediastream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"os"
+	"path/filepath"
+	"seanime/internal/events"
+	"seanime/internal/mediastream/videofile"
+	"seanime/internal/util"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Trickplay
+//
+// Seek previews are generated in the background by a single worker, one file at a time.
+// The sprite sheets and the WebVTT thumbnails track are stored in the cache directory, next to the attachments.
+
+const (
+	defaultTrickplayInterval = 10 // Seconds
+)
+
+var defaultTrickplayOptions = videofile.TrickplayOptions{
+	Width:   160,
+	Columns: 10,
+	Rows:    10,
+}
+
+type (
+	trickplayGenerator struct {
+		mu      sync.Mutex
+		queue   []string            // Paths of the files to process
+		queued  map[string]struct{} // Paths in the queue
+		current string              // Path of the file being processed
+		running bool
+		cancel  context.CancelFunc
+		// runID identifies the running worker, it changes when the worker is cancelled
+		// so that a cancelled worker that is still finishing its file does not update the state of the next one.
+		runID uint64
+	}
+
+	TrickplayStatus struct {
+		Enabled   bool   `json:"enabled"`
+		Current   string `json:"current"` // Path of the file being processed
+		Pending   int    `json:"pending"`
+		TotalSize int64  `json:"totalSize"` // Bytes
+		MaxSize   int64  `json:"maxSize"`   // Bytes, 0 means no limit
+	}
+)
+
+func newTrickplayGenerator() *trickplayGenerator {
+	return &trickplayGenerator{
+		queue:  make([]string, 0),
+		queued: make(map[string]struct{}),
+	}
+}
+
+func (r *Repository) isTrickplayEnabled() bool {
+	return r.IsInitialized() && r.settings.MustGet().TrickplayEnabled
+}
+
+func (r *Repository) getTrickplayOptions() videofile.TrickplayOptions {
+	opts := defaultTrickplayOptions
+	opts.Interval = defaultTrickplayInterval
+	if interval := r.settings.MustGet().TrickplayInterval; interval > 0 {
+		opts.Interval = interval
+	}
+	return opts
+}
+
+func getTrickplayUrl(hash string) string {
+	return fmt.Sprintf("/api/v1/mediastream/trickplay/%s/%s", hash, videofile.TrickplayVttFilename)
+}
+
+// QueueTrickplay adds the files to the trickplay generation queue.
+// If priority is true, the files are processed before the others (e.g. the file being played).
+func (r *Repository) QueueTrickplay(paths []string, priority bool) error {
+	if !r.isTrickplayEnabled() {
+		return errors.New("trickplay is not enabled")
+	}
+
+	g := r.trickplay
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	toAdd := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if _, ok := g.queued[path]; ok || path == g.current {
+			continue
+		}
+		g.queued[path] = struct{}{}
+		toAdd = append(toAdd, path)
+	}
+
+	if priority {
+		g.queue = append(toAdd, g.queue...)
+	} else {
+		g.queue = append(g.queue, toAdd...)
+	}
+
+	if !g.running && len(g.queue) > 0 {
+		g.running = true
+		g.runID++
+		var ctx context.Context
+		ctx, g.cancel = context.WithCancel(context.Background())
+		go r.runTrickplayWorker(ctx, g.runID)
+	}
+
+	return nil
+}
+
+// CancelTrickplay empties the queue and stops the current generation.
+func (r *Repository) CancelTrickplay() {
+	g := r.trickplay
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.queue = make([]string, 0)
+	g.queued = make(map[string]struct{})
+	if g.cancel != nil {
+		g.cancel()
+		g.cancel = nil
+	}
+	// The cancelled worker can still be finishing its file, the next files are processed by a new worker
+	g.runID++
+	g.running = false
+	g.current = ""
+}
+
+// runTrickplayWorker processes the queue until it is empty or the worker is cancelled.
+// runID is the ID of the run, the worker stops without updating the state once it is stale.
+func (r *Repository) runTrickplayWorker(ctx context.Context, runID uint64) {
+	defer util.HandlePanicInModuleThen("mediastream/runTrickplayWorker", func() {})
+
+	g := r.trickplay
+	defer func() {
+		g.mu.Lock()
+		if g.runID == runID {
+			g.running = false
+			g.current = ""
+		}
+		g.mu.Unlock()
+	}()
+
+	for {
+		g.mu.Lock()
+		if g.runID != runID || len(g.queue) == 0 || ctx.Err() != nil || !r.isTrickplayEnabled() {
+			g.mu.Unlock()
+			return
+		}
+		path := g.queue[0]
+		g.queue = g.queue[1:]
+		delete(g.queued, path)
+		g.current = path
+		g.mu.Unlock()
+
+		if err := r.generateTrickplay(ctx, path); err != nil {
+			r.logger.Warn().Err(err).Str("path", path).Msg("mediastream: Failed to generate trickplay")
+		}
+	}
+}
+
+func (r *Repository) generateTrickplay(ctx context.Context, path string) error {
+	settings := r.settings.MustGet()
+
+	mediaInfo, err := r.mediaInfoExtractor.GetInfo(settings.FfprobePath, path)
+	if err != nil {
+		return err
+	}
+
+	if videofile.TrickplayExists(r.cacheDir, mediaInfo.Sha) {
+		return nil
+	}
+
+	r.logger.Debug().Str("path", path).Msg("mediastream: Generating trickplay")
+
+	err = videofile.GenerateTrickplay(ctx, settings.FfmpegPath, path, mediaInfo.Sha, mediaInfo, r.cacheDir, r.getTrickplayOptions(), r.logger)
+	if err != nil {
+		return err
+	}
+
+	r.wsEventManager.SendEvent(events.MediastreamTrickplayReady, map[string]string{
+		"hash": mediaInfo.Sha,
+		"url":  getTrickplayUrl(mediaInfo.Sha),
+	})
+
+	r.evictTrickplayCache(mediaInfo.Sha)
+
+	return nil
+}
+
+type trickplayDir struct {
+	hash    string
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// evictTrickplayCache removes the least recently generated trickplay files until the total size is under the limit.
+// The trickplay files of the given hash are kept.
+func (r *Repository) evictTrickplayCache(keepHash string) {
+	maxSize := int64(r.settings.MustGet().TrickplayMaxCacheSize) * 1024 * 1024
+	if maxSize <= 0 {
+		return
+	}
+
+	dirs, total := getTrickplayDirs(r.cacheDir)
+	for _, dir := range selectTrickplayEvictions(dirs, total, maxSize, keepHash) {
+		_ = os.RemoveAll(dir.path)
+		r.logger.Trace().Str("hash", dir.hash).Msg("mediastream: Removed trickplay files")
+	}
+}
+
+// selectTrickplayEvictions returns the oldest directories to remove so that the total size is under maxSize.
+func selectTrickplayEvictions(dirs []*trickplayDir, total int64, maxSize int64, keepHash string) []*trickplayDir {
+	sort.Slice(dirs, func(i, j int) bool {
+		return dirs[i].modTime.Before(dirs[j].modTime)
+	})
+
+	ret := make([]*trickplayDir, 0)
+	for _, dir := range dirs {
+		if total <= maxSize {
+			break
+		}
+		if dir.hash == keepHash {
+			continue
+		}
+		ret = append(ret, dir)
+		total -= dir.size
+	}
+	return ret
+}
+
+// getTrickplayDirs returns the generated trickplay directories and their total size.
+func getTrickplayDirs(cacheDir string) ([]*trickplayDir, int64) {
+	ret := make([]*trickplayDir, 0)
+	var total int64
+
+	entries, err := os.ReadDir(filepath.Join(cacheDir, "videofiles"))
+	if err != nil {
+		return ret, 0
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := videofile.GetFileTrickplayCacheDir(cacheDir, entry.Name())
+		info, err := os.Stat(filepath.Join(dir, videofile.TrickplayVttFilename))
+		if err != nil {
+			continue
+		}
+		size, err := util.DirSize(dir)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, &trickplayDir{
+			hash:    entry.Name(),
+			path:    dir,
+			size:    int64(size),
+			modTime: info.ModTime(),
+		})
+		total += int64(size)
+	}
+
+	return ret, total
+}
+
+// GetTrickplayStatus returns the state of the trickplay generation queue.
+func (r *Repository) GetTrickplayStatus() *TrickplayStatus {
+	ret := &TrickplayStatus{
+		Enabled: r.isTrickplayEnabled(),
+	}
+	if r.IsInitialized() {
+		ret.MaxSize = int64(r.settings.MustGet().TrickplayMaxCacheSize) * 1024 * 1024
+		_, ret.TotalSize = getTrickplayDirs(r.cacheDir)
+	}
+
+	r.trickplay.mu.Lock()
+	ret.Current = r.trickplay.current
+	ret.Pending = len(r.trickplay.queue)
+	r.trickplay.mu.Unlock()
+
+	return ret
+}
+
+// ServeFiberTrickplay serves the WebVTT thumbnails track and the sprite sheets.
+//
+//	e.g. /api/v1/mediastream/trickplay/{hash}/thumbnails.vtt, /api/v1/mediastream/trickplay/{hash}/sprite_001.jpg
+func (r *Repository) ServeFiberTrickplay(fiberCtx *fiber.Ctx) error {
+	if !r.IsInitialized() {
+		return errors.New("module not initialized")
+	}
+
+	hash := fiberCtx.Params("hash")
+	filename := fiberCtx.Params("*1")
+
+	// Only serve the generated files
+	if hash == "" || strings.ContainsAny(hash, `/\.`) || filename != filepath.Base(filename) {
+		return fiber.ErrBadRequest
+	}
+	if filename != videofile.TrickplayVttFilename && filepath.Ext(filename) != ".jpg" {
+		return fiber.ErrBadRequest
+	}
+
+	fp := filepath.Join(videofile.GetFileTrickplayCacheDir(r.cacheDir, hash), filename)
+	if _, err := os.Stat(fp); err != nil {
+		return fiber.ErrNotFound
+	}
+
+	if filename == videofile.TrickplayVttFilename {
+		fiberCtx.Set("Content-Type", "text/vtt")
+	}
+
+	return fiberCtx.SendFile(fp)
+}
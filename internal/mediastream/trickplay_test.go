@@ -0,0 +1,32 @@
+package mediastream
+
+import (
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSelectTrickplayEvictions(t *testing.T) {
+	now := time.Now()
+
+	newDirs := func() []*trickplayDir {
+		return []*trickplayDir{
+			{hash: "recent", size: 100, modTime: now},
+			{hash: "old", size: 100, modTime: now.Add(-time.Hour)},
+			{hash: "oldest", size: 100, modTime: now.Add(-2 * time.Hour)},
+		}
+	}
+	hashes := func(dirs []*trickplayDir) []string {
+		return lo.Map(dirs, func(d *trickplayDir, _ int) string { return d.hash })
+	}
+
+	// Under the limit
+	assert.Empty(t, selectTrickplayEvictions(newDirs(), 300, 300, ""))
+
+	// The oldest are removed first
+	assert.Equal(t, []string{"oldest"}, hashes(selectTrickplayEvictions(newDirs(), 300, 250, "")))
+
+	// The files of the current media are kept
+	assert.Equal(t, []string{"old", "recent"}, hashes(selectTrickplayEvictions(newDirs(), 300, 50, "oldest")))
+}
@@ -0,0 +1,87 @@
+package videofile
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"seanime/internal/util"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ColorTransferPQ is the transfer function of HDR10 and Dolby Vision
+	ColorTransferPQ = "smpte2084"
+	// ColorTransferHLG is the transfer function of Hybrid Log-Gamma
+	ColorTransferHLG = "arib-std-b67"
+)
+
+type videoColorInfo struct {
+	Index          int    `json:"index"`
+	ColorTransfer  string `json:"color_transfer"`
+	ColorPrimaries string `json:"color_primaries"`
+}
+
+// IsHDR returns true if the video uses an HDR transfer function.
+func (v *Video) IsHDR() bool {
+	return v.ColorTransfer == ColorTransferPQ || v.ColorTransfer == ColorTransferHLG
+}
+
+// Is8BitSDR returns true if the video can be displayed by SDR clients as is.
+func (v *Video) Is8BitSDR() bool {
+	return !v.IsHDR() && v.BitDepth <= 8
+}
+
+// ffprobeGetColorInfo returns the color transfer and primaries of the video streams, indexed by stream index.
+// DEVNOTE: The ffprobe package does not expose these fields.
+func ffprobeGetColorInfo(ffprobePath string, path string) (map[int]videoColorInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	cmd := util.NewCmdCtx(
+		ctx,
+		cmp.Or(ffprobePath, "ffprobe"),
+		"-v", "error",
+		"-select_streams", "v",
+		"-show_entries", "stream=index,color_transfer,color_primaries",
+		"-of", "json",
+		path,
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Streams []videoColorInfo `json:"streams"`
+	}
+	if err := json.Unmarshal(out, &data); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[int]videoColorInfo, len(data.Streams))
+	for _, s := range data.Streams {
+		ret[s.Index] = s
+	}
+	return ret, nil
+}
+
+// getBitDepth returns the bit depth of the video from the raw sample size or the pixel format.
+//
+//	e.g. "yuv420p10le" -> 10, "yuv420p" -> 8
+func getBitDepth(bitsPerRawSample string, pixFmt string) uint32 {
+	if bits, err := strconv.ParseUint(bitsPerRawSample, 10, 32); err == nil && bits > 0 {
+		return uint32(bits)
+	}
+	if pixFmt == "" {
+		return 0
+	}
+	for _, depth := range []uint32{16, 14, 12, 10, 9} {
+		s := strconv.Itoa(int(depth))
+		if strings.HasSuffix(pixFmt, s+"le") || strings.HasSuffix(pixFmt, s+"be") || strings.HasSuffix(pixFmt, s) {
+			return depth
+		}
+	}
+	return 8
+}
@@ -0,0 +1,26 @@
+package videofile
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetBitDepth(t *testing.T) {
+	assert.Equal(t, uint32(10), getBitDepth("10", "yuv420p10le"))
+	assert.Equal(t, uint32(10), getBitDepth("", "yuv420p10le"))
+	assert.Equal(t, uint32(10), getBitDepth("N/A", "p010le"))
+	assert.Equal(t, uint32(12), getBitDepth("", "yuv444p12be"))
+	assert.Equal(t, uint32(8), getBitDepth("", "yuv420p"))
+	assert.Equal(t, uint32(0), getBitDepth("", ""))
+}
+
+func TestVideo_IsHDR(t *testing.T) {
+	assert.True(t, (&Video{ColorTransfer: ColorTransferPQ, BitDepth: 10}).IsHDR())
+	assert.True(t, (&Video{ColorTransfer: ColorTransferHLG, BitDepth: 10}).IsHDR())
+	assert.False(t, (&Video{ColorTransfer: "bt709", BitDepth: 8}).IsHDR())
+
+	assert.True(t, (&Video{ColorTransfer: "bt709", BitDepth: 8}).Is8BitSDR())
+	assert.True(t, (&Video{}).Is8BitSDR())
+	assert.False(t, (&Video{ColorTransfer: "bt709", BitDepth: 10}).Is8BitSDR())
+	assert.False(t, (&Video{ColorTransfer: ColorTransferPQ, BitDepth: 10}).Is8BitSDR())
+}
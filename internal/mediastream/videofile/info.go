@@ -61,6 +61,16 @@ type Video struct {
 	Height uint32 `json:"height"`
 	// The average bitrate of the video in bytes/s
 	Bitrate uint32 `json:"bitrate"`
+	// The pixel format of the video stream, e.g., "yuv420p10le"
+	PixFmt string `json:"pixFmt"`
+	// The number of bits per color component, 0 if unknown
+	BitDepth uint32 `json:"bitDepth"`
+	// The transfer characteristics, e.g., "smpte2084" for HDR10, "arib-std-b67" for HLG
+	ColorTransfer string `json:"colorTransfer"`
+	// The color primaries, e.g., "bt2020"
+	ColorPrimaries string `json:"colorPrimaries"`
+	// The color space (matrix coefficients), e.g., "bt2020nc"
+	ColorSpace string `json:"colorSpace"`
 }
 
 type Audio struct {
@@ -131,7 +141,8 @@ func (e *MediaInfoExtractor) GetInfo(ffprobePath, path string) (mi *MediaInfo, e
 
 	e.logger.Debug().Str("path", path).Str("hash", hash).Msg("mediastream: Getting media information [MediaInfoExtractor]")
 
-	bucketName := fmt.Sprintf("mediastream_mediainfo_%s", hash)
+	// DEVNOTE: Bump the version when fields are added to MediaInfo so that they are extracted again
+	bucketName := fmt.Sprintf("mediastream_mediainfo_v2_%s", hash)
 	bucket := filecache.NewBucket(bucketName, 24*7*52*time.Hour)
 	e.logger.Trace().Str("bucketName", bucketName).Msg("mediastream: Using cache bucket [MediaInfoExtractor]")
 
@@ -176,6 +187,9 @@ func FfprobeGetInfo(ffprobePath, path, hash string) (*MediaInfo, error) {
 		return nil, err
 	}
 
+	// Not fatal, the video is considered SDR
+	colorInfo, _ := ffprobeGetColorInfo(ffprobePath, path)
+
 	ext := filepath.Ext(path)[1:]
 
 	sizeUint64, _ := strconv.ParseUint(data.Format.Size, 10, 64)
@@ -202,7 +216,12 @@ func FfprobeGetInfo(ffprobePath, path, hash string) (*MediaInfo, error) {
 			Height:    uint32(stream.Height),
 			// ffmpeg does not report bitrate in mkv files, fallback to bitrate of the whole container
 			// (bigger than the result since it contains audio and other videos but better than nothing).
-			Bitrate: uint32(bitrate),
+			Bitrate:        uint32(bitrate),
+			PixFmt:         stream.PixFmt,
+			BitDepth:       getBitDepth(stream.BitsPerRawSample, stream.PixFmt),
+			ColorTransfer:  colorInfo[stream.Index].ColorTransfer,
+			ColorPrimaries: colorInfo[stream.Index].ColorPrimaries,
+			ColorSpace:     stream.ColorSpace,
 		}
 	})
 
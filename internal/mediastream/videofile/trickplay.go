@@ -0,0 +1,148 @@
+package videofile
+
+import (
+	"context"
+	"fmt"
+	"github.com/rs/zerolog"
+	"math"
+	"os"
+	"path/filepath"
+	"seanime/internal/util"
+	"seanime/internal/util/crashlog"
+	"strings"
+)
+
+const (
+	// TrickplayVttFilename is the name of the WebVTT file, it is written once all sprite sheets are generated.
+	TrickplayVttFilename = "thumbnails.vtt"
+)
+
+type TrickplayOptions struct {
+	Interval int // Seconds between two thumbnails
+	Width    int // Width of a thumbnail
+	Columns  int // Thumbnails per row in a sprite sheet
+	Rows     int // Rows in a sprite sheet
+}
+
+func GetFileTrickplayCacheDir(outDir string, hash string) string {
+	return filepath.Join(outDir, "videofiles", hash, "/trickplay")
+}
+
+// TrickplayExists returns true if the sprite sheets and the WebVTT file were generated.
+func TrickplayExists(cacheDir string, hash string) bool {
+	_, err := os.Stat(filepath.Join(GetFileTrickplayCacheDir(cacheDir, hash), TrickplayVttFilename))
+	return err == nil
+}
+
+// GenerateTrickplay generates the sprite sheets and the WebVTT thumbnails track of the file.
+//
+//	e.g. {cacheDir}/videofiles/{hash}/trickplay/sprite_001.jpg, {cacheDir}/videofiles/{hash}/trickplay/thumbnails.vtt
+func GenerateTrickplay(ctx context.Context, ffmpegPath string, path string, hash string, mediaInfo *MediaInfo, cacheDir string, opts TrickplayOptions, logger *zerolog.Logger) (err error) {
+	logger.Debug().Str("hash", hash).Msgf("videofile: Starting trickplay generation")
+
+	if mediaInfo.Video == nil || mediaInfo.Video.Width == 0 || mediaInfo.Video.Height == 0 {
+		return fmt.Errorf("videofile: No video stream")
+	}
+	if mediaInfo.Duration <= 0 {
+		return fmt.Errorf("videofile: Unknown duration")
+	}
+
+	outDir := GetFileTrickplayCacheDir(cacheDir, hash)
+	if TrickplayExists(cacheDir, hash) {
+		logger.Debug().Str("hash", hash).Msgf("videofile: Trickplay already generated")
+		return nil
+	}
+
+	// Start over if a previous generation was interrupted
+	_ = os.RemoveAll(outDir)
+	if err = os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	height := getTrickplayHeight(opts.Width, mediaInfo.Video.Width, mediaInfo.Video.Height)
+
+	// Instantiate a new crash logger
+	crashLogger := crashlog.GlobalCrashLogger.InitArea("ffmpeg")
+	defer crashLogger.Close()
+
+	crashLogger.LogInfof("Generating trickplay for %s", path)
+
+	// DEVNOTE: All paths fed into this command should be absolute
+	cmd := util.NewCmdCtx(
+		ctx,
+		ffmpegPath,
+		"-nostdin",
+		"-y",
+		// Only decode keyframes, this is much faster and precise enough for previews
+		"-skip_frame", "nokey",
+		"-i", path,
+		"-an", "-sn",
+		"-vf", fmt.Sprintf("fps=1/%d,scale=%d:%d,tile=%dx%d", opts.Interval, opts.Width, height, opts.Columns, opts.Rows),
+		"-vsync", "vfr",
+		"-q:v", "5",
+		filepath.Join(outDir, "sprite_%03d.jpg"),
+	)
+	cmd.Stdout = crashLogger.Stdout()
+	cmd.Stderr = crashLogger.Stdout()
+	err = cmd.Run()
+	if err != nil {
+		logger.Error().Err(err).Msgf("videofile: Error generating trickplay")
+		crashlog.GlobalCrashLogger.WriteAreaLogToFile(crashLogger)
+		_ = os.RemoveAll(outDir)
+		return err
+	}
+
+	vtt := BuildTrickplayVtt(float64(mediaInfo.Duration), height, opts)
+
+	err = os.WriteFile(filepath.Join(outDir, TrickplayVttFilename), []byte(vtt), 0644)
+	if err != nil {
+		_ = os.RemoveAll(outDir)
+		return err
+	}
+
+	logger.Debug().Str("hash", hash).Msgf("videofile: Trickplay generated")
+
+	return nil
+}
+
+// getTrickplayHeight returns the height of a thumbnail, keeping the aspect ratio of the video.
+// The height is rounded to an even number since some encoders require it.
+func getTrickplayHeight(width int, videoWidth uint32, videoHeight uint32) int {
+	height := int(math.Round(float64(width) * float64(videoHeight) / float64(videoWidth)))
+	if height%2 != 0 {
+		height++
+	}
+	return max(height, 2)
+}
+
+// BuildTrickplayVtt returns the WebVTT thumbnails track pointing to the tiles of the sprite sheets.
+func BuildTrickplayVtt(duration float64, height int, opts TrickplayOptions) string {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n")
+
+	perSprite := opts.Columns * opts.Rows
+	count := int(math.Ceil(duration / float64(opts.Interval)))
+
+	for i := 0; i < count; i++ {
+		start := float64(i * opts.Interval)
+		end := math.Min(float64((i+1)*opts.Interval), duration)
+
+		sprite := i/perSprite + 1
+		tile := i % perSprite
+		x := (tile % opts.Columns) * opts.Width
+		y := (tile / opts.Columns) * height
+
+		sb.WriteString(fmt.Sprintf("\n%s --> %s\nsprite_%03d.jpg#xywh=%d,%d,%d,%d\n",
+			formatVttTimestamp(start), formatVttTimestamp(end), sprite, x, y, opts.Width, height))
+	}
+
+	return sb.String()
+}
+
+func formatVttTimestamp(seconds float64) string {
+	ms := int64(math.Round(seconds * 1000))
+	h := ms / 3600000
+	m := (ms % 3600000) / 60000
+	s := (ms % 60000) / 1000
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", h, m, s, ms%1000)
+}
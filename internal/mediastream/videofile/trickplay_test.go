@@ -0,0 +1,38 @@
+package videofile
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestGetTrickplayHeight(t *testing.T) {
+	assert.Equal(t, 90, getTrickplayHeight(160, 1920, 1080))
+	assert.Equal(t, 120, getTrickplayHeight(160, 640, 480))
+	// Rounded to an even number
+	assert.Equal(t, 68, getTrickplayHeight(160, 1920, 800))
+}
+
+func TestFormatVttTimestamp(t *testing.T) {
+	assert.Equal(t, "00:00:00.000", formatVttTimestamp(0))
+	assert.Equal(t, "00:01:05.500", formatVttTimestamp(65.5))
+	assert.Equal(t, "01:02:03.000", formatVttTimestamp(3723))
+}
+
+func TestBuildTrickplayVtt(t *testing.T) {
+	opts := TrickplayOptions{
+		Interval: 10,
+		Width:    160,
+		Columns:  2,
+		Rows:     2,
+	}
+
+	vtt := BuildTrickplayVtt(45, 90, opts)
+
+	assert.True(t, strings.HasPrefix(vtt, "WEBVTT\n"))
+	assert.Equal(t, 5, strings.Count(vtt, " --> "))
+	assert.Contains(t, vtt, "00:00:00.000 --> 00:00:10.000\nsprite_001.jpg#xywh=0,0,160,90\n")
+	assert.Contains(t, vtt, "00:00:30.000 --> 00:00:40.000\nsprite_001.jpg#xywh=160,90,160,90\n")
+	// The last cue ends at the end of the video and is on the next sprite sheet
+	assert.Contains(t, vtt, "00:00:40.000 --> 00:00:45.000\nsprite_002.jpg#xywh=0,0,160,90\n")
+}
@@ -21,9 +21,10 @@ type (
 )
 
 const (
-	AutoDownloader Notification = "Auto Downloader"
-	AutoScanner    Notification = "Auto Scanner"
-	Debrid         Notification = "Debrid"
+	AutoDownloader      Notification = "Auto Downloader"
+	MangaAutoDownloader Notification = "Manga Auto Downloader"
+	AutoScanner         Notification = "Auto Scanner"
+	Debrid              Notification = "Debrid"
 )
 
 var GlobalNotifier = NewNotifier()
@@ -64,7 +65,7 @@ func (n *Notifier) canProceed(id Notification) bool {
 	}
 
 	switch id {
-	case AutoDownloader:
+	case AutoDownloader, MangaAutoDownloader:
 		return !n.settings.MustGet().DisableAutoDownloaderNotifications
 	case AutoScanner:
 		return !n.settings.MustGet().DisableAutoScannerNotifications
@@ -42,6 +42,14 @@ func NewAnilistPlatform(anilistClient anilist.AnilistClient, logger *zerolog.Log
 }
 
 func (ap *AnilistPlatform) SetUsername(username string) {
+	// Clear the cached collections if the user changed, e.g. when switching profiles
+	if ap.username.IsPresent() && ap.username.MustGet() != username {
+		ap.animeCollection = mo.None[*anilist.AnimeCollection]()
+		ap.rawAnimeCollection = mo.None[*anilist.AnimeCollection]()
+		ap.mangaCollection = mo.None[*anilist.MangaCollection]()
+		ap.rawMangaCollection = mo.None[*anilist.MangaCollection]()
+	}
+
 	// Set the username for the AnilistPlatform
 	if username == "" {
 		ap.username = mo.Some[string]("")
@@ -114,13 +122,43 @@ func (ap *AnilistPlatform) UpdateEntryProgress(mediaID int, progress int, totalE
 
 func (ap *AnilistPlatform) DeleteEntry(mediaID int) error {
 	ap.logger.Trace().Msg("anilist platform: Deleting entry")
-	_, err := ap.anilistClient.DeleteEntry(context.Background(), &mediaID)
+
+	listEntryID, err := ap.getListEntryID(mediaID)
+	if err != nil {
+		return err
+	}
+
+	_, err = ap.anilistClient.DeleteEntry(context.Background(), &listEntryID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// getListEntryID returns the ID of the list entry of the anime or manga.
+// The cached collections are checked first, then refreshed if the entry isn't found.
+func (ap *AnilistPlatform) getListEntryID(mediaID int) (int, error) {
+	for _, bypassCache := range []bool{false, true} {
+		animeCollection, err := ap.GetRawAnimeCollection(bypassCache)
+		if err != nil {
+			return 0, err
+		}
+		if entry, found := animeCollection.GetListEntryFromAnimeId(mediaID); found {
+			return entry.ID, nil
+		}
+
+		mangaCollection, err := ap.GetRawMangaCollection(bypassCache)
+		if err != nil {
+			return 0, err
+		}
+		if entry, found := mangaCollection.GetListEntryFromMangaId(mediaID); found {
+			return entry.ID, nil
+		}
+	}
+
+	return 0, errors.New("list entry not found")
+}
+
 func (ap *AnilistPlatform) GetAnime(mediaID int) (*anilist.BaseAnime, error) {
 	ap.logger.Trace().Msg("anilist platform: Fetching anime")
 	ret, err := ap.anilistClient.BaseAnimeByID(context.Background(), &mediaID)
@@ -0,0 +1,298 @@
+package fanout_platform
+
+import (
+	"github.com/rs/zerolog"
+	"seanime/internal/api/anilist"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/platforms/platform"
+	"sync"
+)
+
+const (
+	TrackerAnilist   = "anilist"
+	TrackerMal       = "mal"
+	TrackerKitsu     = "kitsu"
+	TrackerShikimori = "shikimori"
+	TrackerLedger    = "ledger"
+)
+
+type (
+	// FanoutPlatform wraps the platform used to track progress and mirrors
+	// every progress, status and score change to additional trackers.
+	// Writes that fail are persisted and retried later.
+	FanoutPlatform struct {
+		platform.Platform // Tracking platform
+		primaryName       string
+		logger            *zerolog.Logger
+		db                *db.Database
+		trackers          map[string]Tracker
+		settings          *models.TrackerSyncSettings
+		queue             *retryQueue
+		pending           []*Change     // Changes waiting to be mirrored, consumed by a single worker
+		pendingCh         chan struct{} // Wakes up the worker
+		pendingMu         sync.Mutex
+		mu                sync.RWMutex
+	}
+
+	NewFanoutPlatformOptions struct {
+		Platform    platform.Platform // Tracking platform
+		PrimaryName string            // Name of the tracking platform, it will never be mirrored to
+		// Platforms that can be mirrored to, the tracking platform can be included
+		AnilistPlatform platform.Platform
+		MalPlatform     platform.Platform
+		KitsuPlatform   platform.Platform
+		AnilistClient   anilist.AnilistClient
+		Database        *db.Database
+		Logger          *zerolog.Logger
+	}
+)
+
+func NewFanoutPlatform(opts *NewFanoutPlatformOptions) *FanoutPlatform {
+	fp := &FanoutPlatform{
+		Platform:    opts.Platform,
+		primaryName: opts.PrimaryName,
+		logger:      opts.Logger,
+		db:          opts.Database,
+		trackers:    make(map[string]Tracker),
+		pendingCh:   make(chan struct{}, 1),
+	}
+
+	if opts.AnilistPlatform != nil {
+		fp.trackers[TrackerAnilist] = newPlatformTracker(TrackerAnilist, opts.AnilistPlatform, func() bool {
+			return opts.Database.GetAnilistToken() != ""
+		})
+	}
+	if opts.MalPlatform != nil {
+		fp.trackers[TrackerMal] = newPlatformTracker(TrackerMal, opts.MalPlatform, func() bool {
+			_, err := opts.Database.GetMalInfo()
+			return err == nil
+		})
+	}
+	if opts.KitsuPlatform != nil {
+		fp.trackers[TrackerKitsu] = newPlatformTracker(TrackerKitsu, opts.KitsuPlatform, func() bool {
+			_, err := opts.Database.GetKitsuInfo()
+			return err == nil
+		})
+	}
+	fp.trackers[TrackerShikimori] = newShikimoriTracker(opts.AnilistClient, opts.Logger)
+	fp.trackers[TrackerLedger] = newLedgerTracker(opts.Database)
+
+	fp.queue = newRetryQueue(fp)
+
+	go fp.mirrorLoop()
+
+	return fp
+}
+
+// SetSettings updates the settings and starts or stops the retry loop.
+func (fp *FanoutPlatform) SetSettings(settings *models.TrackerSyncSettings) {
+	fp.mu.Lock()
+	fp.settings = settings
+	if t, ok := fp.trackers[TrackerShikimori].(*shikimoriTracker); ok && settings != nil {
+		t.SetAccessToken(settings.ShikimoriAccessToken)
+	}
+	fp.mu.Unlock()
+
+	if settings != nil && settings.Enabled {
+		fp.queue.start(settings.RetryInterval)
+	} else {
+		fp.queue.stop()
+	}
+}
+
+// SetAnilistClient updates the client of the tracking platform and of the platforms that are mirrored to.
+func (fp *FanoutPlatform) SetAnilistClient(client anilist.AnilistClient) {
+	fp.Platform.SetAnilistClient(client)
+
+	fp.mu.RLock()
+	defer fp.mu.RUnlock()
+	for _, t := range fp.trackers {
+		switch t := t.(type) {
+		case *platformTracker:
+			t.platform.SetAnilistClient(client)
+		case *shikimoriTracker:
+			t.SetAnilistClient(client)
+		}
+	}
+}
+
+// SetUsername updates the AniList username of the tracking platform and of the platforms that are mirrored to.
+// The AniList platform needs it to fetch the collections, e.g. to find the list entry of a deleted media.
+func (fp *FanoutPlatform) SetUsername(username string) {
+	fp.Platform.SetUsername(username)
+
+	fp.mu.RLock()
+	defer fp.mu.RUnlock()
+	for _, t := range fp.trackers {
+		if t, ok := t.(*platformTracker); ok {
+			t.platform.SetUsername(username)
+		}
+	}
+}
+
+func (fp *FanoutPlatform) UpdateEntry(mediaID int, status *anilist.MediaListStatus, scoreRaw *int, progress *int, startedAt *anilist.FuzzyDateInput, completedAt *anilist.FuzzyDateInput) error {
+	return fp.write(&Change{
+		Action:      ActionUpdate,
+		MediaID:     mediaID,
+		Status:      status,
+		ScoreRaw:    scoreRaw,
+		Progress:    progress,
+		StartedAt:   startedAt,
+		CompletedAt: completedAt,
+	}, func() error {
+		return fp.Platform.UpdateEntry(mediaID, status, scoreRaw, progress, startedAt, completedAt)
+	})
+}
+
+func (fp *FanoutPlatform) UpdateEntryProgress(mediaID int, progress int, totalEpisodes *int) error {
+	return fp.write(&Change{
+		Action:        ActionProgress,
+		MediaID:       mediaID,
+		Progress:      &progress,
+		TotalEpisodes: totalEpisodes,
+	}, func() error {
+		return fp.Platform.UpdateEntryProgress(mediaID, progress, totalEpisodes)
+	})
+}
+
+func (fp *FanoutPlatform) DeleteEntry(mediaID int) error {
+	return fp.write(&Change{
+		Action:  ActionDelete,
+		MediaID: mediaID,
+	}, func() error {
+		return fp.Platform.DeleteEntry(mediaID)
+	})
+}
+
+// write applies the change to the tracking platform and hands it to the mirror worker, even if the write failed.
+// While the changes are mirrored, a failed write is also queued for the tracking platform so that it is replayed
+// when connectivity returns, and the following changes for the same media are queued after it so that they are applied in order.
+// The error of the write is returned, nil if the change was queued behind older changes.
+func (fp *FanoutPlatform) write(change *Change, apply func() error) error {
+	defer fp.send(change)
+
+	primary, queued := fp.primaryTracker()
+	if queued && fp.db.HasTrackerSyncQueueItems(primary.Name(), change.MediaID) {
+		fp.queue.enqueue(primary.Name(), change, nil)
+		return nil
+	}
+
+	err := apply()
+	if err != nil && queued {
+		fp.logger.Warn().Err(err).Str("tracker", primary.Name()).Int("mediaId", change.MediaID).Msg("fanout: Failed to write change, queuing")
+		fp.queue.enqueue(primary.Name(), change, err)
+	}
+	return err
+}
+
+// primaryTracker returns the tracker of the tracking platform and whether its failed writes are queued.
+// They are only queued while the changes are mirrored since the retry loop does not run otherwise,
+// and not in offline mode since the tracking platform is then the local platform.
+func (fp *FanoutPlatform) primaryTracker() (Tracker, bool) {
+	fp.mu.RLock()
+	defer fp.mu.RUnlock()
+
+	if fp.settings == nil || !fp.settings.Enabled {
+		return nil, false
+	}
+	t, ok := fp.trackers[fp.primaryName].(*platformTracker)
+	if !ok || t.platform != fp.Platform || !t.IsConnected() {
+		return nil, false
+	}
+	return t, true
+}
+
+// send hands the change to the mirror worker without blocking.
+func (fp *FanoutPlatform) send(change *Change) {
+	fp.pendingMu.Lock()
+	fp.pending = append(fp.pending, change)
+	fp.pendingMu.Unlock()
+
+	select {
+	case fp.pendingCh <- struct{}{}:
+	default:
+	}
+}
+
+// enabledTrackers returns the trackers the changes should be mirrored to.
+func (fp *FanoutPlatform) enabledTrackers() []Tracker {
+	fp.mu.RLock()
+	defer fp.mu.RUnlock()
+
+	s := fp.settings
+	if s == nil || !s.Enabled {
+		return nil
+	}
+
+	enabled := map[string]bool{
+		TrackerAnilist:   s.MirrorAnilist,
+		TrackerMal:       s.MirrorMal,
+		TrackerKitsu:     s.MirrorKitsu,
+		TrackerShikimori: s.MirrorShikimori,
+		TrackerLedger:    s.EnableLedger,
+	}
+
+	ret := make([]Tracker, 0)
+	for _, name := range []string{TrackerLedger, TrackerAnilist, TrackerMal, TrackerKitsu, TrackerShikimori} {
+		if !enabled[name] || name == fp.primaryName {
+			continue
+		}
+		if t, ok := fp.trackers[name]; ok {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+func (fp *FanoutPlatform) getTracker(name string) (Tracker, bool) {
+	fp.mu.RLock()
+	defer fp.mu.RUnlock()
+	t, ok := fp.trackers[name]
+	return t, ok
+}
+
+// mirrorLoop mirrors the changes one at a time, in the order they were made,
+// so that an older change is never written after a newer one.
+func (fp *FanoutPlatform) mirrorLoop() {
+	for range fp.pendingCh {
+		fp.pendingMu.Lock()
+		changes := fp.pending
+		fp.pending = nil
+		fp.pendingMu.Unlock()
+
+		for _, change := range changes {
+			fp.mirror(change)
+		}
+	}
+}
+
+// mirror writes the change to the enabled trackers.
+// If a write fails, or if older changes for the same media are still queued, the change is queued.
+func (fp *FanoutPlatform) mirror(change *Change) {
+	for _, t := range fp.enabledTrackers() {
+		if !t.IsConnected() {
+			fp.logger.Trace().Str("tracker", t.Name()).Msg("fanout: Tracker not connected, skipping")
+			continue
+		}
+
+		// Keep the changes in order
+		if fp.db.HasTrackerSyncQueueItems(t.Name(), change.MediaID) {
+			fp.queue.enqueue(t.Name(), change, nil)
+			continue
+		}
+
+		if err := t.Apply(change); err != nil {
+			fp.logger.Warn().Err(err).Str("tracker", t.Name()).Int("mediaId", change.MediaID).Msg("fanout: Failed to mirror change, queuing")
+			fp.queue.enqueue(t.Name(), change, err)
+			continue
+		}
+
+		fp.logger.Debug().Str("tracker", t.Name()).Int("mediaId", change.MediaID).Str("action", string(change.Action)).Msg("fanout: Mirrored change")
+	}
+}
+
+// RetryNow retries all the queued changes, regardless of their next attempt time.
+func (fp *FanoutPlatform) RetryNow() {
+	fp.queue.process(true)
+}
@@ -0,0 +1,182 @@
+package fanout_platform
+
+import (
+	"errors"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/platforms/platform"
+	"seanime/internal/util"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeTracker struct {
+	name    string
+	fail    bool
+	applied []*Change
+	mu      sync.Mutex
+}
+
+func (t *fakeTracker) Name() string      { return t.name }
+func (t *fakeTracker) IsConnected() bool { return true }
+func (t *fakeTracker) Apply(change *Change) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.fail {
+		return errors.New("offline")
+	}
+	t.applied = append(t.applied, change)
+	return nil
+}
+
+func newTestFanoutPlatform(t *testing.T, tracker *fakeTracker) *FanoutPlatform {
+	t.Setenv("TEST_ENV", "true")
+	logger := util.NewLogger()
+	database, err := db.NewDatabase(t.TempDir(), "test", logger)
+	require.NoError(t, err)
+
+	fp := NewFanoutPlatform(&NewFanoutPlatformOptions{
+		PrimaryName: TrackerAnilist,
+		Database:    database,
+		Logger:      logger,
+	})
+	fp.trackers[TrackerMal] = tracker
+	fp.settings = &models.TrackerSyncSettings{
+		Enabled:   true,
+		MirrorMal: true,
+	}
+	return fp
+}
+
+func TestMirrorQueueAndReplay(t *testing.T) {
+	tracker := &fakeTracker{name: TrackerMal, fail: true}
+	fp := newTestFanoutPlatform(t, tracker)
+
+	fp.mirror(&Change{Action: ActionProgress, MediaID: 1, Progress: lo.ToPtr(1)})
+	fp.mirror(&Change{Action: ActionProgress, MediaID: 1, Progress: lo.ToPtr(2)})
+	fp.mirror(&Change{Action: ActionDelete, MediaID: 2})
+
+	items, err := fp.db.GetTrackerSyncQueueItems()
+	require.NoError(t, err)
+	// The first progress change is superseded by the second one
+	require.Len(t, items, 2)
+	deleteItem, found := lo.Find(items, func(i *models.TrackerSyncQueueItem) bool { return i.MediaID == 2 })
+	require.True(t, found)
+	assert.Equal(t, 1, deleteItem.Attempts)
+
+	// Not due yet
+	fp.queue.process(false)
+	assert.Empty(t, tracker.applied)
+
+	// Connectivity returns
+	tracker.fail = false
+	fp.RetryNow()
+
+	require.Len(t, tracker.applied, 2)
+	progressChange, found := lo.Find(tracker.applied, func(c *Change) bool { return c.MediaID == 1 })
+	require.True(t, found)
+	assert.Equal(t, 2, *progressChange.Progress)
+
+	items, err = fp.db.GetTrackerSyncQueueItems()
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestMirrorKeepsOrder(t *testing.T) {
+	tracker := &fakeTracker{name: TrackerMal, fail: true}
+	fp := newTestFanoutPlatform(t, tracker)
+
+	fp.mirror(&Change{Action: ActionUpdate, MediaID: 1, Progress: lo.ToPtr(3)})
+
+	// The tracker is reachable again but the older change is still queued
+	tracker.fail = false
+	fp.mirror(&Change{Action: ActionProgress, MediaID: 1, Progress: lo.ToPtr(4)})
+	assert.Empty(t, tracker.applied)
+
+	fp.RetryNow()
+	require.Len(t, tracker.applied, 2)
+	assert.Equal(t, ActionUpdate, tracker.applied[0].Action)
+	assert.Equal(t, 4, *tracker.applied[1].Progress)
+}
+
+func TestMirrorWorkerKeepsCallOrder(t *testing.T) {
+	tracker := &fakeTracker{name: TrackerMal}
+	fp := newTestFanoutPlatform(t, tracker)
+
+	for i := 1; i <= 20; i++ {
+		fp.send(&Change{Action: ActionProgress, MediaID: 1, Progress: lo.ToPtr(i)})
+	}
+
+	require.Eventually(t, func() bool {
+		tracker.mu.Lock()
+		defer tracker.mu.Unlock()
+		return len(tracker.applied) == 20
+	}, 5*time.Second, 10*time.Millisecond)
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	for i, change := range tracker.applied {
+		assert.Equal(t, i+1, *change.Progress)
+	}
+}
+
+// fakePlatform is a tracking platform whose progress writes fail while it is offline.
+type fakePlatform struct {
+	platform.Platform
+	offline  bool
+	progress []int
+	mu       sync.Mutex
+}
+
+func (p *fakePlatform) UpdateEntryProgress(mediaID int, progress int, totalEpisodes *int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.offline {
+		return errors.New("offline")
+	}
+	p.progress = append(p.progress, progress)
+	return nil
+}
+
+func TestPrimaryWriteQueuedWhenOffline(t *testing.T) {
+	tracker := &fakeTracker{name: TrackerMal}
+	fp := newTestFanoutPlatform(t, tracker)
+	primary := &fakePlatform{offline: true}
+	fp.Platform = primary
+	fp.trackers[TrackerAnilist] = newPlatformTracker(TrackerAnilist, primary, func() bool { return true })
+
+	assert.Error(t, fp.UpdateEntryProgress(1, 1, nil))
+	// Queued behind the failed write
+	assert.NoError(t, fp.UpdateEntryProgress(1, 2, nil))
+	assert.Empty(t, primary.progress)
+
+	// The change is still mirrored
+	require.Eventually(t, func() bool {
+		tracker.mu.Lock()
+		defer tracker.mu.Unlock()
+		return len(tracker.applied) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Connectivity returns, the latest progress is replayed
+	primary.offline = false
+	fp.RetryNow()
+	assert.Equal(t, []int{2}, primary.progress)
+
+	items, err := fp.db.GetTrackerSyncQueueItems()
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestPrimaryIsNotMirrored(t *testing.T) {
+	fp := newTestFanoutPlatform(t, &fakeTracker{name: TrackerMal})
+	fp.trackers[TrackerAnilist] = &fakeTracker{name: TrackerAnilist}
+	fp.settings.MirrorAnilist = true
+
+	trackers := fp.enabledTrackers()
+	require.Len(t, trackers, 1)
+	assert.Equal(t, TrackerMal, trackers[0].Name())
+}
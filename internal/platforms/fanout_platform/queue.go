@@ -0,0 +1,177 @@
+package fanout_platform
+
+import (
+	"context"
+	"github.com/goccy/go-json"
+	"seanime/internal/database/models"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRetryInterval = 5 * time.Minute
+	maxRetryInterval     = 6 * time.Hour
+	retryTickInterval    = time.Minute
+)
+
+// retryQueue persists the changes that could not be written to a tracker and retries them with exponential backoff.
+type retryQueue struct {
+	fp            *FanoutPlatform
+	retryInterval time.Duration
+	cancel        context.CancelFunc
+	mu            sync.Mutex
+	processMu     sync.Mutex
+}
+
+func newRetryQueue(fp *FanoutPlatform) *retryQueue {
+	return &retryQueue{
+		fp:            fp,
+		retryInterval: defaultRetryInterval,
+	}
+}
+
+// start starts the retry loop, restarting it if it is already running.
+// intervalMinutes is the base interval between two attempts.
+func (q *retryQueue) start(intervalMinutes int) {
+	q.stop()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.retryInterval = defaultRetryInterval
+	if intervalMinutes > 0 {
+		q.retryInterval = time.Duration(intervalMinutes) * time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+
+	go func() {
+		// Replay the changes that were queued while the app was closed or offline
+		q.process(false)
+
+		ticker := time.NewTicker(retryTickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				q.process(false)
+			}
+		}
+	}()
+}
+
+func (q *retryQueue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.cancel != nil {
+		q.cancel()
+		q.cancel = nil
+	}
+}
+
+// backoff returns the delay before the next attempt.
+func (q *retryQueue) backoff(attempts int) time.Duration {
+	q.mu.Lock()
+	d := q.retryInterval
+	q.mu.Unlock()
+
+	for i := 1; i < attempts && d < maxRetryInterval; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryInterval)
+}
+
+// enqueue persists the change.
+// Queued progress changes for the same media are dropped since the new one supersedes them.
+func (q *retryQueue) enqueue(tracker string, change *Change, err error) {
+	data, mErr := json.Marshal(change)
+	if mErr != nil {
+		q.fp.logger.Error().Err(mErr).Msg("fanout: Failed to marshal change")
+		return
+	}
+
+	if change.Action == ActionProgress {
+		_ = q.fp.db.DeleteTrackerSyncQueueItemsByMedia(tracker, change.MediaID, string(ActionProgress))
+	}
+
+	item := &models.TrackerSyncQueueItem{
+		Tracker:       tracker,
+		Action:        string(change.Action),
+		MediaID:       change.MediaID,
+		Change:        data,
+		NextAttemptAt: time.Now(),
+	}
+	if err != nil {
+		item.Attempts = 1
+		item.LastError = err.Error()
+		item.NextAttemptAt = time.Now().Add(q.backoff(1))
+	}
+
+	if dbErr := q.fp.db.InsertTrackerSyncQueueItem(item); dbErr != nil {
+		q.fp.logger.Error().Err(dbErr).Msg("fanout: Failed to queue change")
+	}
+}
+
+// process retries the queued changes in order.
+// If a change fails, the following changes for the same tracker and media are skipped so that they are not applied out of order.
+// If force is true, the next attempt time is ignored.
+func (q *retryQueue) process(force bool) {
+	q.processMu.Lock()
+	defer q.processMu.Unlock()
+
+	items, err := q.fp.db.GetTrackerSyncQueueItems()
+	if err != nil {
+		q.fp.logger.Error().Err(err).Msg("fanout: Failed to get queued changes")
+		return
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	type key struct {
+		tracker string
+		mediaId int
+	}
+	blocked := make(map[key]bool)
+	now := time.Now()
+
+	for _, item := range items {
+		k := key{item.Tracker, item.MediaID}
+		if blocked[k] {
+			continue
+		}
+
+		if !force && item.NextAttemptAt.After(now) {
+			blocked[k] = true
+			continue
+		}
+
+		t, ok := q.fp.getTracker(item.Tracker)
+		if !ok || !t.IsConnected() {
+			continue
+		}
+
+		var change Change
+		if err := json.Unmarshal(item.Change, &change); err != nil {
+			q.fp.logger.Error().Err(err).Uint("id", item.ID).Msg("fanout: Dropping invalid queued change")
+			_ = q.fp.db.DeleteTrackerSyncQueueItem(item.ID)
+			continue
+		}
+
+		if err := t.Apply(&change); err != nil {
+			blocked[k] = true
+			item.Attempts++
+			item.LastError = err.Error()
+			item.NextAttemptAt = time.Now().Add(q.backoff(item.Attempts))
+			_ = q.fp.db.UpdateTrackerSyncQueueItem(item)
+			q.fp.logger.Debug().Err(err).Str("tracker", item.Tracker).Int("mediaId", item.MediaID).Int("attempts", item.Attempts).Msg("fanout: Retry failed")
+			continue
+		}
+
+		_ = q.fp.db.DeleteTrackerSyncQueueItem(item.ID)
+		q.fp.logger.Info().Str("tracker", item.Tracker).Int("mediaId", item.MediaID).Msg("fanout: Replayed queued change")
+	}
+}
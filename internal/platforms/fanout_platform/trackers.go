@@ -0,0 +1,263 @@
+package fanout_platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/shikimori"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/platforms/platform"
+	"sync"
+)
+
+type (
+	Action string
+
+	// Change is a progress, status or score change that is mirrored to the trackers.
+	Change struct {
+		Action        Action                   `json:"action"`
+		MediaID       int                      `json:"mediaId"`
+		Status        *anilist.MediaListStatus `json:"status,omitempty"`
+		ScoreRaw      *int                     `json:"scoreRaw,omitempty"`
+		Progress      *int                     `json:"progress,omitempty"`
+		TotalEpisodes *int                     `json:"totalEpisodes,omitempty"`
+		StartedAt     *anilist.FuzzyDateInput  `json:"startedAt,omitempty"`
+		CompletedAt   *anilist.FuzzyDateInput  `json:"completedAt,omitempty"`
+	}
+
+	// Tracker is a service changes can be mirrored to.
+	Tracker interface {
+		Name() string
+		// IsConnected returns false if the user has not connected the tracker.
+		// Changes are not mirrored nor queued for trackers that are not connected.
+		IsConnected() bool
+		Apply(change *Change) error
+	}
+)
+
+const (
+	ActionUpdate   Action = "update"
+	ActionProgress Action = "progress"
+	ActionDelete   Action = "delete"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// platformTracker mirrors changes to a platform.Platform (AniList, MAL, Kitsu).
+type platformTracker struct {
+	name        string
+	platform    platform.Platform
+	isConnected func() bool
+}
+
+func newPlatformTracker(name string, p platform.Platform, isConnected func() bool) *platformTracker {
+	return &platformTracker{
+		name:        name,
+		platform:    p,
+		isConnected: isConnected,
+	}
+}
+
+func (t *platformTracker) Name() string {
+	return t.name
+}
+
+func (t *platformTracker) IsConnected() bool {
+	return t.isConnected()
+}
+
+func (t *platformTracker) Apply(change *Change) error {
+	switch change.Action {
+	case ActionUpdate:
+		return t.platform.UpdateEntry(change.MediaID, change.Status, change.ScoreRaw, change.Progress, change.StartedAt, change.CompletedAt)
+	case ActionProgress:
+		if change.Progress == nil {
+			return nil
+		}
+		return t.platform.UpdateEntryProgress(change.MediaID, *change.Progress, change.TotalEpisodes)
+	case ActionDelete:
+		return t.platform.DeleteEntry(change.MediaID)
+	}
+	return fmt.Errorf("unknown action %q", change.Action)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// shikimoriTracker mirrors changes to Shikimori.
+// Shikimori uses MAL IDs, the AniList metadata is used to translate the IDs.
+type shikimoriTracker struct {
+	accessToken   string
+	anilistClient anilist.AnilistClient
+	logger        *zerolog.Logger
+	media         map[int]*shikimoriMedia // AniList ID -> media
+	mu            sync.Mutex
+}
+
+type shikimoriMedia struct {
+	MalID      int
+	TargetType shikimori.TargetType
+	Total      int // Total episodes or chapters, 0 if unknown
+}
+
+func newShikimoriTracker(anilistClient anilist.AnilistClient, logger *zerolog.Logger) *shikimoriTracker {
+	return &shikimoriTracker{
+		anilistClient: anilistClient,
+		logger:        logger,
+		media:         make(map[int]*shikimoriMedia),
+	}
+}
+
+func (t *shikimoriTracker) SetAccessToken(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.accessToken = token
+}
+
+func (t *shikimoriTracker) SetAnilistClient(client anilist.AnilistClient) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.anilistClient = client
+}
+
+func (t *shikimoriTracker) Name() string {
+	return TrackerShikimori
+}
+
+func (t *shikimoriTracker) IsConnected() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.accessToken != ""
+}
+
+func (t *shikimoriTracker) resolveMedia(mediaID int) (*shikimoriMedia, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if m, ok := t.media[mediaID]; ok {
+		return m, nil
+	}
+
+	// AniList IDs are unique across anime and manga
+	if res, err := t.anilistClient.BaseAnimeByID(context.Background(), &mediaID); err == nil && res.GetMedia() != nil {
+		if res.GetMedia().GetIDMal() == nil {
+			return nil, errors.New("shikimori: No MAL ID found")
+		}
+		m := &shikimoriMedia{
+			MalID:      *res.GetMedia().GetIDMal(),
+			TargetType: shikimori.TargetTypeAnime,
+			Total:      res.GetMedia().GetTotalEpisodeCount(),
+		}
+		t.media[mediaID] = m
+		return m, nil
+	}
+
+	res, err := t.anilistClient.BaseMangaByID(context.Background(), &mediaID)
+	if err != nil {
+		return nil, err
+	}
+	if res.GetMedia() == nil || res.GetMedia().GetIDMal() == nil {
+		return nil, errors.New("shikimori: No MAL ID found")
+	}
+	m := &shikimoriMedia{
+		MalID:      *res.GetMedia().GetIDMal(),
+		TargetType: shikimori.TargetTypeManga,
+	}
+	if res.GetMedia().GetChapters() != nil {
+		m.Total = *res.GetMedia().GetChapters()
+	}
+	t.media[mediaID] = m
+	return m, nil
+}
+
+func (t *shikimoriTracker) Apply(change *Change) error {
+	t.mu.Lock()
+	w := shikimori.NewWrapper(t.accessToken, t.logger)
+	t.mu.Unlock()
+
+	m, err := t.resolveMedia(change.MediaID)
+	if err != nil {
+		return err
+	}
+
+	switch change.Action {
+	case ActionUpdate, ActionProgress:
+		rate := &shikimori.UserRate{}
+		if change.Status != nil {
+			rate.Status = toShikimoriStatus(*change.Status)
+		}
+		if change.ScoreRaw != nil {
+			score := max(0, min((*change.ScoreRaw+5)/10, 10))
+			rate.Score = &score
+		}
+		if change.Progress != nil {
+			if m.TargetType == shikimori.TargetTypeManga {
+				rate.Chapters = change.Progress
+			} else {
+				rate.Episodes = change.Progress
+			}
+			total := m.Total
+			if change.TotalEpisodes != nil && *change.TotalEpisodes > 0 {
+				total = *change.TotalEpisodes
+			}
+			if change.Action == ActionProgress && total > 0 && *change.Progress >= total {
+				rate.Status = shikimori.UserRateStatusCompleted
+			}
+		}
+		return w.UpsertUserRate(m.TargetType, m.MalID, rate)
+	case ActionDelete:
+		return w.DeleteUserRate(m.TargetType, m.MalID)
+	}
+	return fmt.Errorf("unknown action %q", change.Action)
+}
+
+func toShikimoriStatus(status anilist.MediaListStatus) shikimori.UserRateStatus {
+	switch status {
+	case anilist.MediaListStatusCurrent:
+		return shikimori.UserRateStatusWatching
+	case anilist.MediaListStatusRepeating:
+		return shikimori.UserRateStatusRewatching
+	case anilist.MediaListStatusCompleted:
+		return shikimori.UserRateStatusCompleted
+	case anilist.MediaListStatusPaused:
+		return shikimori.UserRateStatusOnHold
+	case anilist.MediaListStatusDropped:
+		return shikimori.UserRateStatusDropped
+	default:
+		return shikimori.UserRateStatusPlanned
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ledgerTracker records every change in the database.
+type ledgerTracker struct {
+	db *db.Database
+}
+
+func newLedgerTracker(db *db.Database) *ledgerTracker {
+	return &ledgerTracker{db: db}
+}
+
+func (t *ledgerTracker) Name() string {
+	return TrackerLedger
+}
+
+func (t *ledgerTracker) IsConnected() bool {
+	return true
+}
+
+func (t *ledgerTracker) Apply(change *Change) error {
+	entry := &models.TrackerLedgerEntry{
+		Action:   string(change.Action),
+		MediaID:  change.MediaID,
+		ScoreRaw: change.ScoreRaw,
+		Progress: change.Progress,
+	}
+	if change.Status != nil {
+		entry.Status = string(*change.Status)
+	}
+	return t.db.InsertTrackerLedgerEntry(entry)
+}
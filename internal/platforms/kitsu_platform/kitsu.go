@@ -0,0 +1,563 @@
+package kitsu_platform
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/kitsu"
+	"seanime/internal/database/db"
+	"seanime/internal/platforms/platform"
+	"seanime/internal/util/limiter"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoKitsuID means the AniList media could not be mapped to a Kitsu media
+	ErrNoKitsuID = errors.New("kitsu platform: could not find media on Kitsu")
+)
+
+type (
+	// kitsuMedia identifies a Kitsu anime or manga
+	kitsuMedia struct {
+		ID   string
+		Kind kitsu.MediaKind
+	}
+
+	// KitsuPlatform uses Kitsu to track progress.
+	// Library entries are fetched from Kitsu and translated to AniList-shaped types using Kitsu's mappings,
+	// metadata is still fetched from AniList.
+	KitsuPlatform struct {
+		logger          *zerolog.Logger
+		db              *db.Database
+		anilistClient   anilist.AnilistClient
+		animeCollection mo.Option[*anilist.AnimeCollection]
+		mangaCollection mo.Option[*anilist.MangaCollection]
+		kitsuIDs        map[int]*kitsuMedia // AniList ID -> Kitsu media
+		mu              sync.RWMutex
+	}
+)
+
+func NewKitsuPlatform(anilistClient anilist.AnilistClient, db *db.Database, logger *zerolog.Logger) platform.Platform {
+	return &KitsuPlatform{
+		logger:          logger,
+		db:              db,
+		anilistClient:   anilistClient,
+		animeCollection: mo.None[*anilist.AnimeCollection](),
+		mangaCollection: mo.None[*anilist.MangaCollection](),
+		kitsuIDs:        make(map[int]*kitsuMedia),
+	}
+}
+
+// getWrapper returns a Kitsu wrapper with a valid access token.
+func (kp *KitsuPlatform) getWrapper() (*kitsu.Wrapper, error) {
+	kitsuInfo, err := kp.db.GetKitsuInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	kitsuInfo, err = kitsu.VerifyKitsuAuth(kitsuInfo, kp.db, kp.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return kitsu.NewWrapper(kitsuInfo.AccessToken, kitsuInfo.UserID, kp.logger), nil
+}
+
+func (kp *KitsuPlatform) isAuthenticated() bool {
+	kitsuInfo, err := kp.db.GetKitsuInfo()
+	return err == nil && kitsuInfo.AccessToken != "" && kitsuInfo.UserID != ""
+}
+
+// SetUsername is a no-op, the Kitsu account is read from the database.
+func (kp *KitsuPlatform) SetUsername(username string) {
+	// no-op
+}
+
+func (kp *KitsuPlatform) SetAnilistClient(client anilist.AnilistClient) {
+	kp.anilistClient = client
+}
+
+// resolveKitsuMedia returns the Kitsu media of the given AniList media.
+// It first looks in the collections, then uses Kitsu's AniList mappings and finally its MyAnimeList mappings.
+func (kp *KitsuPlatform) resolveKitsuMedia(w *kitsu.Wrapper, mediaID int) (*kitsuMedia, error) {
+	kp.mu.RLock()
+	if m, ok := kp.kitsuIDs[mediaID]; ok {
+		kp.mu.RUnlock()
+		return m, nil
+	}
+	kp.mu.RUnlock()
+
+	var ret *kitsuMedia
+	var idMal *int
+	if anime, err := kp.GetAnime(mediaID); err == nil && anime != nil {
+		ret = &kitsuMedia{Kind: kitsu.MediaKindAnime}
+		idMal = anime.GetIDMal()
+	} else if manga, err := kp.GetManga(mediaID); err == nil && manga != nil {
+		ret = &kitsuMedia{Kind: kitsu.MediaKindManga}
+		idMal = manga.GetIDMal()
+	} else {
+		return nil, ErrNoKitsuID
+	}
+
+	anilistSite, malSite := kitsu.ExternalSiteAnilistAnime, kitsu.ExternalSiteMyAnimeListAnime
+	if ret.Kind == kitsu.MediaKindManga {
+		anilistSite, malSite = kitsu.ExternalSiteAnilistManga, kitsu.ExternalSiteMyAnimeListManga
+	}
+
+	id, err := w.GetMediaIDByExternalID(anilistSite, mediaID)
+	if err != nil && idMal != nil {
+		id, err = w.GetMediaIDByExternalID(malSite, *idMal)
+	}
+	if err != nil || id == "" {
+		return nil, ErrNoKitsuID
+	}
+	ret.ID = id
+
+	kp.mu.Lock()
+	kp.kitsuIDs[mediaID] = ret
+	kp.mu.Unlock()
+
+	return ret, nil
+}
+
+func (kp *KitsuPlatform) getAnimeCollection() mo.Option[*anilist.AnimeCollection] {
+	kp.mu.RLock()
+	defer kp.mu.RUnlock()
+	return kp.animeCollection
+}
+
+func (kp *KitsuPlatform) getMangaCollection() mo.Option[*anilist.MangaCollection] {
+	kp.mu.RLock()
+	defer kp.mu.RUnlock()
+	return kp.mangaCollection
+}
+
+// isRepeating returns true if the media is in the user's rewatching/rereading list.
+func (kp *KitsuPlatform) isRepeating(mediaID int, kind kitsu.MediaKind) bool {
+	if kind == kitsu.MediaKindManga {
+		if collection, ok := kp.getMangaCollection().Get(); ok {
+			if entry, found := collection.GetListEntryFromMangaId(mediaID); found {
+				return entry.GetStatus() != nil && *entry.GetStatus() == anilist.MediaListStatusRepeating
+			}
+		}
+		return false
+	}
+	if collection, ok := kp.getAnimeCollection().Get(); ok {
+		if entry, found := collection.GetListEntryFromAnimeId(mediaID); found {
+			return entry.GetStatus() != nil && *entry.GetStatus() == anilist.MediaListStatusRepeating
+		}
+	}
+	return false
+}
+
+func (kp *KitsuPlatform) UpdateEntry(mediaID int, status *anilist.MediaListStatus, scoreRaw *int, progress *int, startedAt *anilist.FuzzyDateInput, completedAt *anilist.FuzzyDateInput) error {
+	kp.logger.Trace().Msg("kitsu platform: Updating entry")
+
+	w, err := kp.getWrapper()
+	if err != nil {
+		return err
+	}
+
+	media, err := kp.resolveKitsuMedia(w, mediaID)
+	if err != nil {
+		return err
+	}
+
+	attributes := &kitsu.LibraryEntryAttributes{
+		Progress:     progress,
+		RatingTwenty: toKitsuRating(scoreRaw),
+		StartedAt:    lo.EmptyableToPtr(platform.FormatFuzzyDate(startedAt)),
+		FinishedAt:   lo.EmptyableToPtr(platform.FormatFuzzyDate(completedAt)),
+	}
+	if status != nil {
+		s, reconsuming := toKitsuStatus(*status)
+		attributes.Status = s
+		attributes.Reconsuming = &reconsuming
+	}
+
+	return w.UpsertLibraryEntry(media.Kind, media.ID, attributes)
+}
+
+func (kp *KitsuPlatform) UpdateEntryProgress(mediaID int, progress int, totalEpisodes *int) error {
+	kp.logger.Trace().Msg("kitsu platform: Updating entry progress")
+
+	w, err := kp.getWrapper()
+	if err != nil {
+		return err
+	}
+
+	media, err := kp.resolveKitsuMedia(w, mediaID)
+	if err != nil {
+		return err
+	}
+
+	totalEp := 0
+	if totalEpisodes != nil && *totalEpisodes > 0 {
+		totalEp = *totalEpisodes
+	}
+
+	status := anilist.MediaListStatusCurrent
+	if kp.isRepeating(mediaID, media.Kind) {
+		status = anilist.MediaListStatusRepeating
+	}
+	if totalEp > 0 && progress >= totalEp {
+		status = anilist.MediaListStatusCompleted
+	}
+	if totalEp > 0 && progress > totalEp {
+		progress = totalEp
+	}
+
+	kitsuStatus, reconsuming := toKitsuStatus(status)
+
+	return w.UpsertLibraryEntry(media.Kind, media.ID, &kitsu.LibraryEntryAttributes{
+		Status:      kitsuStatus,
+		Progress:    &progress,
+		Reconsuming: &reconsuming,
+	})
+}
+
+func (kp *KitsuPlatform) DeleteEntry(mediaID int) error {
+	kp.logger.Trace().Msg("kitsu platform: Deleting entry")
+
+	w, err := kp.getWrapper()
+	if err != nil {
+		return err
+	}
+
+	media, err := kp.resolveKitsuMedia(w, mediaID)
+	if err != nil {
+		return err
+	}
+
+	return w.DeleteLibraryEntry(media.Kind, media.ID)
+}
+
+func (kp *KitsuPlatform) GetAnime(mediaID int) (*anilist.BaseAnime, error) {
+	kp.logger.Trace().Msg("kitsu platform: Fetching anime")
+	ret, err := kp.anilistClient.BaseAnimeByID(context.Background(), &mediaID)
+	if err != nil {
+		return nil, err
+	}
+	return ret.GetMedia(), nil
+}
+
+func (kp *KitsuPlatform) GetAnimeByMalID(malID int) (*anilist.BaseAnime, error) {
+	kp.logger.Trace().Msg("kitsu platform: Fetching anime by MAL ID")
+	ret, err := kp.anilistClient.BaseAnimeByMalID(context.Background(), &malID)
+	if err != nil {
+		return nil, err
+	}
+	return ret.GetMedia(), nil
+}
+
+func (kp *KitsuPlatform) GetAnimeWithRelations(mediaID int) (*anilist.CompleteAnime, error) {
+	kp.logger.Trace().Msg("kitsu platform: Fetching anime with relations")
+	ret, err := kp.anilistClient.CompleteAnimeByID(context.Background(), &mediaID)
+	if err != nil {
+		return nil, err
+	}
+	return ret.GetMedia(), nil
+}
+
+func (kp *KitsuPlatform) GetAnimeDetails(mediaID int) (*anilist.AnimeDetailsById_Media, error) {
+	kp.logger.Trace().Msg("kitsu platform: Fetching anime details")
+	ret, err := kp.anilistClient.AnimeDetailsByID(context.Background(), &mediaID)
+	if err != nil {
+		return nil, err
+	}
+	return ret.GetMedia(), nil
+}
+
+func (kp *KitsuPlatform) GetManga(mediaID int) (*anilist.BaseManga, error) {
+	kp.logger.Trace().Msg("kitsu platform: Fetching manga")
+	ret, err := kp.anilistClient.BaseMangaByID(context.Background(), &mediaID)
+	if err != nil {
+		return nil, err
+	}
+	return ret.GetMedia(), nil
+}
+
+func (kp *KitsuPlatform) GetMangaDetails(mediaID int) (*anilist.MangaDetailsById_Media, error) {
+	kp.logger.Trace().Msg("kitsu platform: Fetching manga details")
+	ret, err := kp.anilistClient.MangaDetailsByID(context.Background(), &mediaID)
+	if err != nil {
+		return nil, err
+	}
+	return ret.GetMedia(), nil
+}
+
+func (kp *KitsuPlatform) GetAnimeCollection(bypassCache bool) (*anilist.AnimeCollection, error) {
+	if collection, ok := kp.getAnimeCollection().Get(); ok && !bypassCache {
+		return collection, nil
+	}
+
+	if !kp.isAuthenticated() {
+		return nil, nil
+	}
+
+	err := kp.refreshAnimeCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	return kp.getAnimeCollection().MustGet(), nil
+}
+
+// GetRawAnimeCollection returns the same collection as GetAnimeCollection since Kitsu has no custom lists.
+func (kp *KitsuPlatform) GetRawAnimeCollection(bypassCache bool) (*anilist.AnimeCollection, error) {
+	return kp.GetAnimeCollection(bypassCache)
+}
+
+func (kp *KitsuPlatform) RefreshAnimeCollection() (*anilist.AnimeCollection, error) {
+	if !kp.isAuthenticated() {
+		return nil, nil
+	}
+
+	err := kp.refreshAnimeCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	return kp.getAnimeCollection().MustGet(), nil
+}
+
+func (kp *KitsuPlatform) refreshAnimeCollection() error {
+	w, err := kp.getWrapper()
+	if err != nil {
+		return err
+	}
+
+	kitsuEntries, err := w.GetLibraryEntries(kitsu.MediaKindAnime)
+	if err != nil {
+		return err
+	}
+
+	anilistIDs, malIDs := splitEntryIDs(kitsuEntries)
+
+	byAnilistID, err := anilist.FetchBaseAnimeMap(anilistIDs)
+	if err != nil {
+		return err
+	}
+	byMalID, err := anilist.FetchBaseAnimeMapByMalIDs(malIDs)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]*anilist.AnimeListEntry, 0, len(kitsuEntries))
+	for _, e := range kitsuEntries {
+		m, ok := byAnilistID[e.AnilistID]
+		if !ok {
+			m, ok = byMalID[e.MalID]
+		}
+		if !ok || e.Attributes == nil {
+			kp.logger.Debug().Str("kitsuId", e.MediaID).Msg("kitsu platform: Could not find media on AniList")
+			continue
+		}
+		kp.setKitsuMedia(m.ID, e.MediaID, kitsu.MediaKindAnime)
+
+		entry := &anilist.AnimeListEntry{
+			ID:       m.ID,
+			Score:    fromKitsuRating(e.Attributes.RatingTwenty),
+			Progress: lo.ToPtr(lo.FromPtr(e.Attributes.Progress)),
+			Status:   lo.ToPtr(fromKitsuStatus(e.Attributes.Status, lo.FromPtr(e.Attributes.Reconsuming))),
+			Notes:    e.Attributes.Notes,
+			Repeat:   e.Attributes.ReconsumeCount,
+			Private:  e.Attributes.Private,
+			Media:    m,
+		}
+		if date := platform.ParseFuzzyDate(lo.FromPtr(e.Attributes.StartedAt)); date != nil {
+			entry.StartedAt = &anilist.AnimeCollection_MediaListCollection_Lists_Entries_StartedAt{Year: date.Year, Month: date.Month, Day: date.Day}
+		}
+		if date := platform.ParseFuzzyDate(lo.FromPtr(e.Attributes.FinishedAt)); date != nil {
+			entry.CompletedAt = &anilist.AnimeCollection_MediaListCollection_Lists_Entries_CompletedAt{Year: date.Year, Month: date.Month, Day: date.Day}
+		}
+		entries = append(entries, entry)
+	}
+
+	kp.mu.Lock()
+	kp.animeCollection = mo.Some(platform.NewAnimeCollection(entries))
+	kp.mu.Unlock()
+
+	return nil
+}
+
+func (kp *KitsuPlatform) GetAnimeCollectionWithRelations() (*anilist.AnimeCollectionWithRelations, error) {
+	kp.logger.Trace().Msg("kitsu platform: Fetching anime collection with relations")
+
+	collection, err := kp.GetAnimeCollection(false)
+	if err != nil || collection == nil {
+		return nil, err
+	}
+
+	ids := lo.Map(collection.GetAllAnime(), func(m *anilist.BaseAnime, _ int) int { return m.ID })
+	media, err := anilist.FetchCompleteAnimeMap(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return platform.NewAnimeCollectionWithRelations(collection, media), nil
+}
+
+func (kp *KitsuPlatform) GetMangaCollection(bypassCache bool) (*anilist.MangaCollection, error) {
+	if collection, ok := kp.getMangaCollection().Get(); ok && !bypassCache {
+		return collection, nil
+	}
+
+	if !kp.isAuthenticated() {
+		return nil, nil
+	}
+
+	err := kp.refreshMangaCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	return kp.getMangaCollection().MustGet(), nil
+}
+
+// GetRawMangaCollection returns the same collection as GetMangaCollection since Kitsu has no custom lists.
+func (kp *KitsuPlatform) GetRawMangaCollection(bypassCache bool) (*anilist.MangaCollection, error) {
+	return kp.GetMangaCollection(bypassCache)
+}
+
+func (kp *KitsuPlatform) RefreshMangaCollection() (*anilist.MangaCollection, error) {
+	if !kp.isAuthenticated() {
+		return nil, nil
+	}
+
+	err := kp.refreshMangaCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	return kp.getMangaCollection().MustGet(), nil
+}
+
+func (kp *KitsuPlatform) refreshMangaCollection() error {
+	w, err := kp.getWrapper()
+	if err != nil {
+		return err
+	}
+
+	kitsuEntries, err := w.GetLibraryEntries(kitsu.MediaKindManga)
+	if err != nil {
+		return err
+	}
+
+	anilistIDs, malIDs := splitEntryIDs(kitsuEntries)
+
+	byAnilistID, err := anilist.FetchBaseMangaMap(anilistIDs)
+	if err != nil {
+		return err
+	}
+	byMalID, err := anilist.FetchBaseMangaMapByMalIDs(malIDs)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]*anilist.MangaCollection_MediaListCollection_Lists_Entries, 0, len(kitsuEntries))
+	for _, e := range kitsuEntries {
+		m, ok := byAnilistID[e.AnilistID]
+		if !ok {
+			m, ok = byMalID[e.MalID]
+		}
+		if !ok || e.Attributes == nil {
+			kp.logger.Debug().Str("kitsuId", e.MediaID).Msg("kitsu platform: Could not find media on AniList")
+			continue
+		}
+		kp.setKitsuMedia(m.ID, e.MediaID, kitsu.MediaKindManga)
+
+		entry := &anilist.MangaCollection_MediaListCollection_Lists_Entries{
+			ID:       m.ID,
+			Score:    fromKitsuRating(e.Attributes.RatingTwenty),
+			Progress: lo.ToPtr(lo.FromPtr(e.Attributes.Progress)),
+			Status:   lo.ToPtr(fromKitsuStatus(e.Attributes.Status, lo.FromPtr(e.Attributes.Reconsuming))),
+			Notes:    e.Attributes.Notes,
+			Repeat:   e.Attributes.ReconsumeCount,
+			Private:  e.Attributes.Private,
+			Media:    m,
+		}
+		if date := platform.ParseFuzzyDate(lo.FromPtr(e.Attributes.StartedAt)); date != nil {
+			entry.StartedAt = &anilist.MangaCollection_MediaListCollection_Lists_Entries_StartedAt{Year: date.Year, Month: date.Month, Day: date.Day}
+		}
+		if date := platform.ParseFuzzyDate(lo.FromPtr(e.Attributes.FinishedAt)); date != nil {
+			entry.CompletedAt = &anilist.MangaCollection_MediaListCollection_Lists_Entries_CompletedAt{Year: date.Year, Month: date.Month, Day: date.Day}
+		}
+		entries = append(entries, entry)
+	}
+
+	kp.mu.Lock()
+	kp.mangaCollection = mo.Some(platform.NewMangaCollection(entries))
+	kp.mu.Unlock()
+
+	return nil
+}
+
+func (kp *KitsuPlatform) setKitsuMedia(mediaID int, kitsuID string, kind kitsu.MediaKind) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	kp.kitsuIDs[mediaID] = &kitsuMedia{ID: kitsuID, Kind: kind}
+}
+
+// splitEntryIDs returns the AniList IDs of the entries that have an AniList mapping
+// and the MAL IDs of the entries that only have a MyAnimeList mapping.
+func splitEntryIDs(entries []*kitsu.LibraryEntry) (anilistIDs []int, malIDs []int) {
+	for _, e := range entries {
+		if e.AnilistID > 0 {
+			anilistIDs = append(anilistIDs, e.AnilistID)
+		} else if e.MalID > 0 {
+			malIDs = append(malIDs, e.MalID)
+		}
+	}
+	return
+}
+
+func (kp *KitsuPlatform) AddMediaToCollection(mIds []int) error {
+	kp.logger.Trace().Msg("kitsu platform: Adding media to collection")
+	if len(mIds) == 0 {
+		kp.logger.Debug().Msg("kitsu platform: No media added to planning list")
+		return nil
+	}
+
+	w, err := kp.getWrapper()
+	if err != nil {
+		return err
+	}
+
+	rateLimiter := limiter.NewLimiter(1*time.Second, 1) // 1 request per second
+
+	for _, id := range mIds {
+		rateLimiter.Wait()
+		media, err := kp.resolveKitsuMedia(w, id)
+		if err != nil {
+			kp.logger.Error().Err(err).Int("mediaId", id).Msg("kitsu platform: An error occurred while adding media to planning list")
+			continue
+		}
+		err = w.UpsertLibraryEntry(media.Kind, media.ID, &kitsu.LibraryEntryAttributes{
+			Status: kitsu.LibraryEntryStatusPlanned,
+		})
+		if err != nil {
+			kp.logger.Error().Err(err).Int("mediaId", id).Msg("kitsu platform: An error occurred while adding media to planning list")
+		}
+	}
+
+	kp.logger.Debug().Any("count", len(mIds)).Msg("kitsu platform: Media added to planning list")
+	return nil
+}
+
+func (kp *KitsuPlatform) GetStudioDetails(studioID int) (*anilist.StudioDetails, error) {
+	kp.logger.Trace().Msg("kitsu platform: Fetching studio details")
+	ret, err := kp.anilistClient.StudioDetails(context.Background(), &studioID)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (kp *KitsuPlatform) GetAnilistClient() anilist.AnilistClient {
+	return kp.anilistClient
+}
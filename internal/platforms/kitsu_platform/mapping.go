@@ -0,0 +1,64 @@
+package kitsu_platform
+
+import (
+	"github.com/samber/lo"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/kitsu"
+)
+
+// toKitsuStatus converts an AniList status to a Kitsu status.
+// Kitsu has no "repeating" status, it is represented by the "current" status with the reconsuming flag.
+func toKitsuStatus(status anilist.MediaListStatus) (ret kitsu.LibraryEntryStatus, reconsuming bool) {
+	switch status {
+	case anilist.MediaListStatusCurrent:
+		return kitsu.LibraryEntryStatusCurrent, false
+	case anilist.MediaListStatusRepeating:
+		return kitsu.LibraryEntryStatusCurrent, true
+	case anilist.MediaListStatusCompleted:
+		return kitsu.LibraryEntryStatusCompleted, false
+	case anilist.MediaListStatusPaused:
+		return kitsu.LibraryEntryStatusOnHold, false
+	case anilist.MediaListStatusDropped:
+		return kitsu.LibraryEntryStatusDropped, false
+	default:
+		return kitsu.LibraryEntryStatusPlanned, false
+	}
+}
+
+// fromKitsuStatus converts a Kitsu status to an AniList status.
+func fromKitsuStatus(status kitsu.LibraryEntryStatus, reconsuming bool) anilist.MediaListStatus {
+	switch status {
+	case kitsu.LibraryEntryStatusCurrent:
+		if reconsuming {
+			return anilist.MediaListStatusRepeating
+		}
+		return anilist.MediaListStatusCurrent
+	case kitsu.LibraryEntryStatusCompleted:
+		return anilist.MediaListStatusCompleted
+	case kitsu.LibraryEntryStatusOnHold:
+		return anilist.MediaListStatusPaused
+	case kitsu.LibraryEntryStatusDropped:
+		return anilist.MediaListStatusDropped
+	default:
+		return anilist.MediaListStatusPlanning
+	}
+}
+
+// toKitsuRating converts a raw AniList score (0-100) to a Kitsu rating (2-20).
+// A score of 0 means the entry is not rated, nil is returned so that the rating is left untouched.
+func toKitsuRating(scoreRaw *int) *int {
+	if scoreRaw == nil || *scoreRaw <= 0 {
+		return nil
+	}
+	rating := (*scoreRaw + 2) / 5
+	rating = max(2, min(rating, 20))
+	return &rating
+}
+
+// fromKitsuRating converts a Kitsu rating (2-20) to a raw AniList score (0-100).
+func fromKitsuRating(rating *int) *float64 {
+	if rating == nil {
+		return lo.ToPtr(0.0)
+	}
+	return lo.ToPtr(float64(*rating * 5))
+}
@@ -0,0 +1,58 @@
+package kitsu_platform
+
+import (
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/kitsu"
+	"testing"
+)
+
+func TestStatusConversion(t *testing.T) {
+
+	tests := []struct {
+		status      anilist.MediaListStatus
+		expected    kitsu.LibraryEntryStatus
+		reconsuming bool
+	}{
+		{anilist.MediaListStatusCurrent, kitsu.LibraryEntryStatusCurrent, false},
+		{anilist.MediaListStatusRepeating, kitsu.LibraryEntryStatusCurrent, true},
+		{anilist.MediaListStatusCompleted, kitsu.LibraryEntryStatusCompleted, false},
+		{anilist.MediaListStatusPaused, kitsu.LibraryEntryStatusOnHold, false},
+		{anilist.MediaListStatusDropped, kitsu.LibraryEntryStatusDropped, false},
+		{anilist.MediaListStatusPlanning, kitsu.LibraryEntryStatusPlanned, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			ret, reconsuming := toKitsuStatus(tt.status)
+			assert.Equal(t, tt.expected, ret)
+			assert.Equal(t, tt.reconsuming, reconsuming)
+
+			// Round trip
+			assert.Equal(t, tt.status, fromKitsuStatus(ret, reconsuming))
+		})
+	}
+}
+
+func TestKitsuRating(t *testing.T) {
+
+	tests := []struct {
+		scoreRaw *int
+		expected *int
+	}{
+		{nil, nil},
+		{lo.ToPtr(0), nil},
+		{lo.ToPtr(3), lo.ToPtr(2)},
+		{lo.ToPtr(50), lo.ToPtr(10)},
+		{lo.ToPtr(88), lo.ToPtr(18)},
+		{lo.ToPtr(100), lo.ToPtr(20)},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, toKitsuRating(tt.scoreRaw))
+	}
+
+	assert.Equal(t, 90.0, *fromKitsuRating(lo.ToPtr(18)))
+	assert.Equal(t, 0.0, *fromKitsuRating(nil))
+}
@@ -0,0 +1,544 @@
+package mal_platform
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/mal"
+	"seanime/internal/database/db"
+	"seanime/internal/platforms/platform"
+	"seanime/internal/util/limiter"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoMalID means the AniList media has no MyAnimeList counterpart
+	ErrNoMalID = errors.New("mal platform: media has no MyAnimeList ID")
+)
+
+type (
+	// MalPlatform uses MyAnimeList to track progress.
+	// Lists are fetched from MyAnimeList and translated to AniList-shaped types using the MAL IDs of the media,
+	// metadata is still fetched from AniList.
+	MalPlatform struct {
+		logger          *zerolog.Logger
+		db              *db.Database
+		anilistClient   anilist.AnilistClient
+		animeCollection mo.Option[*anilist.AnimeCollection]
+		mangaCollection mo.Option[*anilist.MangaCollection]
+		animeMalIDs     map[int]int // AniList ID -> MAL ID
+		mangaMalIDs     map[int]int // AniList ID -> MAL ID
+		mu              sync.RWMutex
+	}
+)
+
+func NewMalPlatform(anilistClient anilist.AnilistClient, db *db.Database, logger *zerolog.Logger) platform.Platform {
+	return &MalPlatform{
+		logger:          logger,
+		db:              db,
+		anilistClient:   anilistClient,
+		animeCollection: mo.None[*anilist.AnimeCollection](),
+		mangaCollection: mo.None[*anilist.MangaCollection](),
+		animeMalIDs:     make(map[int]int),
+		mangaMalIDs:     make(map[int]int),
+	}
+}
+
+// getWrapper returns a MAL wrapper with a valid access token.
+func (mp *MalPlatform) getWrapper() (*mal.Wrapper, error) {
+	malInfo, err := mp.db.GetMalInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	malInfo, err = mal.VerifyMALAuth(malInfo, mp.db, mp.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return mal.NewWrapper(malInfo.AccessToken, mp.logger), nil
+}
+
+func (mp *MalPlatform) isAuthenticated() bool {
+	malInfo, err := mp.db.GetMalInfo()
+	return err == nil && malInfo.AccessToken != ""
+}
+
+// SetUsername is a no-op, the MAL account is read from the database.
+func (mp *MalPlatform) SetUsername(username string) {
+	// no-op
+}
+
+func (mp *MalPlatform) SetAnilistClient(client anilist.AnilistClient) {
+	mp.anilistClient = client
+}
+
+// resolveMalID returns the MAL ID of the given AniList media and whether it is a manga.
+// It first looks in the collections and falls back to AniList.
+func (mp *MalPlatform) resolveMalID(mediaID int) (malID int, isManga bool, err error) {
+	mp.mu.RLock()
+	if id, ok := mp.animeMalIDs[mediaID]; ok {
+		mp.mu.RUnlock()
+		return id, false, nil
+	}
+	if id, ok := mp.mangaMalIDs[mediaID]; ok {
+		mp.mu.RUnlock()
+		return id, true, nil
+	}
+	mp.mu.RUnlock()
+
+	if anime, err := mp.GetAnime(mediaID); err == nil && anime != nil {
+		if anime.GetIDMal() == nil {
+			return 0, false, ErrNoMalID
+		}
+		mp.mu.Lock()
+		mp.animeMalIDs[mediaID] = *anime.GetIDMal()
+		mp.mu.Unlock()
+		return *anime.GetIDMal(), false, nil
+	}
+
+	manga, err := mp.GetManga(mediaID)
+	if err != nil {
+		return 0, false, err
+	}
+	if manga.GetIDMal() == nil {
+		return 0, true, ErrNoMalID
+	}
+	mp.mu.Lock()
+	mp.mangaMalIDs[mediaID] = *manga.GetIDMal()
+	mp.mu.Unlock()
+	return *manga.GetIDMal(), true, nil
+}
+
+func (mp *MalPlatform) getAnimeCollection() mo.Option[*anilist.AnimeCollection] {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+	return mp.animeCollection
+}
+
+func (mp *MalPlatform) getMangaCollection() mo.Option[*anilist.MangaCollection] {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+	return mp.mangaCollection
+}
+
+// isRepeating returns true if the media is in the user's rewatching/rereading list.
+func (mp *MalPlatform) isRepeating(mediaID int, isManga bool) bool {
+	if isManga {
+		if collection, ok := mp.getMangaCollection().Get(); ok {
+			if entry, found := collection.GetListEntryFromMangaId(mediaID); found {
+				return entry.GetStatus() != nil && *entry.GetStatus() == anilist.MediaListStatusRepeating
+			}
+		}
+		return false
+	}
+	if collection, ok := mp.getAnimeCollection().Get(); ok {
+		if entry, found := collection.GetListEntryFromAnimeId(mediaID); found {
+			return entry.GetStatus() != nil && *entry.GetStatus() == anilist.MediaListStatusRepeating
+		}
+	}
+	return false
+}
+
+func (mp *MalPlatform) UpdateEntry(mediaID int, status *anilist.MediaListStatus, scoreRaw *int, progress *int, startedAt *anilist.FuzzyDateInput, completedAt *anilist.FuzzyDateInput) error {
+	mp.logger.Trace().Msg("mal platform: Updating entry")
+
+	malID, isManga, err := mp.resolveMalID(mediaID)
+	if err != nil {
+		return err
+	}
+
+	w, err := mp.getWrapper()
+	if err != nil {
+		return err
+	}
+
+	var malStatus *mal.MediaListStatus
+	var repeating *bool
+	if status != nil {
+		s, r := toMalStatus(*status, isManga)
+		malStatus, repeating = &s, &r
+	}
+	score := toMalScore(scoreRaw)
+	startDate := lo.EmptyableToPtr(platform.FormatFuzzyDate(startedAt))
+	finishDate := lo.EmptyableToPtr(platform.FormatFuzzyDate(completedAt))
+
+	if isManga {
+		return w.UpdateMangaListStatus(&mal.MangaListStatusParams{
+			Status:          malStatus,
+			IsRereading:     repeating,
+			NumChaptersRead: progress,
+			Score:           score,
+			StartDate:       startDate,
+			FinishDate:      finishDate,
+		}, malID)
+	}
+
+	return w.UpdateAnimeListStatus(&mal.AnimeListStatusParams{
+		Status:             malStatus,
+		IsRewatching:       repeating,
+		NumEpisodesWatched: progress,
+		Score:              score,
+		StartDate:          startDate,
+		FinishDate:         finishDate,
+	}, malID)
+}
+
+func (mp *MalPlatform) UpdateEntryProgress(mediaID int, progress int, totalEpisodes *int) error {
+	mp.logger.Trace().Msg("mal platform: Updating entry progress")
+
+	malID, isManga, err := mp.resolveMalID(mediaID)
+	if err != nil {
+		return err
+	}
+
+	w, err := mp.getWrapper()
+	if err != nil {
+		return err
+	}
+
+	totalEp := 0
+	if totalEpisodes != nil && *totalEpisodes > 0 {
+		totalEp = *totalEpisodes
+	}
+
+	status := anilist.MediaListStatusCurrent
+	if mp.isRepeating(mediaID, isManga) {
+		status = anilist.MediaListStatusRepeating
+	}
+	if totalEp > 0 && progress >= totalEp {
+		status = anilist.MediaListStatusCompleted
+	}
+	if totalEp > 0 && progress > totalEp {
+		progress = totalEp
+	}
+
+	malStatus, repeating := toMalStatus(status, isManga)
+
+	if isManga {
+		return w.UpdateMangaListStatus(&mal.MangaListStatusParams{
+			Status:          &malStatus,
+			IsRereading:     &repeating,
+			NumChaptersRead: &progress,
+		}, malID)
+	}
+
+	return w.UpdateAnimeListStatus(&mal.AnimeListStatusParams{
+		Status:             &malStatus,
+		IsRewatching:       &repeating,
+		NumEpisodesWatched: &progress,
+	}, malID)
+}
+
+func (mp *MalPlatform) DeleteEntry(mediaID int) error {
+	mp.logger.Trace().Msg("mal platform: Deleting entry")
+
+	malID, isManga, err := mp.resolveMalID(mediaID)
+	if err != nil {
+		return err
+	}
+
+	w, err := mp.getWrapper()
+	if err != nil {
+		return err
+	}
+
+	if isManga {
+		return w.DeleteMangaListItem(malID)
+	}
+	return w.DeleteAnimeListItem(malID)
+}
+
+func (mp *MalPlatform) GetAnime(mediaID int) (*anilist.BaseAnime, error) {
+	mp.logger.Trace().Msg("mal platform: Fetching anime")
+	ret, err := mp.anilistClient.BaseAnimeByID(context.Background(), &mediaID)
+	if err != nil {
+		return nil, err
+	}
+	return ret.GetMedia(), nil
+}
+
+func (mp *MalPlatform) GetAnimeByMalID(malID int) (*anilist.BaseAnime, error) {
+	mp.logger.Trace().Msg("mal platform: Fetching anime by MAL ID")
+	ret, err := mp.anilistClient.BaseAnimeByMalID(context.Background(), &malID)
+	if err != nil {
+		return nil, err
+	}
+	return ret.GetMedia(), nil
+}
+
+func (mp *MalPlatform) GetAnimeWithRelations(mediaID int) (*anilist.CompleteAnime, error) {
+	mp.logger.Trace().Msg("mal platform: Fetching anime with relations")
+	ret, err := mp.anilistClient.CompleteAnimeByID(context.Background(), &mediaID)
+	if err != nil {
+		return nil, err
+	}
+	return ret.GetMedia(), nil
+}
+
+func (mp *MalPlatform) GetAnimeDetails(mediaID int) (*anilist.AnimeDetailsById_Media, error) {
+	mp.logger.Trace().Msg("mal platform: Fetching anime details")
+	ret, err := mp.anilistClient.AnimeDetailsByID(context.Background(), &mediaID)
+	if err != nil {
+		return nil, err
+	}
+	return ret.GetMedia(), nil
+}
+
+func (mp *MalPlatform) GetManga(mediaID int) (*anilist.BaseManga, error) {
+	mp.logger.Trace().Msg("mal platform: Fetching manga")
+	ret, err := mp.anilistClient.BaseMangaByID(context.Background(), &mediaID)
+	if err != nil {
+		return nil, err
+	}
+	return ret.GetMedia(), nil
+}
+
+func (mp *MalPlatform) GetMangaDetails(mediaID int) (*anilist.MangaDetailsById_Media, error) {
+	mp.logger.Trace().Msg("mal platform: Fetching manga details")
+	ret, err := mp.anilistClient.MangaDetailsByID(context.Background(), &mediaID)
+	if err != nil {
+		return nil, err
+	}
+	return ret.GetMedia(), nil
+}
+
+func (mp *MalPlatform) GetAnimeCollection(bypassCache bool) (*anilist.AnimeCollection, error) {
+	if collection, ok := mp.getAnimeCollection().Get(); ok && !bypassCache {
+		return collection, nil
+	}
+
+	if !mp.isAuthenticated() {
+		return nil, nil
+	}
+
+	err := mp.refreshAnimeCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	return mp.getAnimeCollection().MustGet(), nil
+}
+
+// GetRawAnimeCollection returns the same collection as GetAnimeCollection since MyAnimeList has no custom lists.
+func (mp *MalPlatform) GetRawAnimeCollection(bypassCache bool) (*anilist.AnimeCollection, error) {
+	return mp.GetAnimeCollection(bypassCache)
+}
+
+func (mp *MalPlatform) RefreshAnimeCollection() (*anilist.AnimeCollection, error) {
+	if !mp.isAuthenticated() {
+		return nil, nil
+	}
+
+	err := mp.refreshAnimeCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	return mp.getAnimeCollection().MustGet(), nil
+}
+
+func (mp *MalPlatform) refreshAnimeCollection() error {
+	w, err := mp.getWrapper()
+	if err != nil {
+		return err
+	}
+
+	malEntries, err := w.GetAnimeCollection()
+	if err != nil {
+		return err
+	}
+
+	malIDs := lo.Map(malEntries, func(e *mal.AnimeListEntry, _ int) int { return e.Node.ID })
+	media, err := anilist.FetchBaseAnimeMapByMalIDs(malIDs)
+	if err != nil {
+		return err
+	}
+
+	animeMalIDs := make(map[int]int, len(malEntries))
+	entries := make([]*anilist.AnimeListEntry, 0, len(malEntries))
+	for _, e := range malEntries {
+		m, ok := media[e.Node.ID]
+		if !ok {
+			mp.logger.Debug().Int("malId", e.Node.ID).Str("title", e.Node.Title).Msg("mal platform: Could not find media on AniList")
+			continue
+		}
+		animeMalIDs[m.ID] = e.Node.ID
+
+		entry := &anilist.AnimeListEntry{
+			ID:       m.ID,
+			Score:    lo.ToPtr(float64(e.ListStatus.Score * 10)),
+			Progress: lo.ToPtr(e.ListStatus.NumEpisodesWatched),
+			Status:   lo.ToPtr(fromMalStatus(e.ListStatus.Status, e.ListStatus.IsRewatching)),
+			Media:    m,
+		}
+		if date := platform.ParseFuzzyDate(e.ListStatus.StartDate); date != nil {
+			entry.StartedAt = &anilist.AnimeCollection_MediaListCollection_Lists_Entries_StartedAt{Year: date.Year, Month: date.Month, Day: date.Day}
+		}
+		if date := platform.ParseFuzzyDate(e.ListStatus.FinishDate); date != nil {
+			entry.CompletedAt = &anilist.AnimeCollection_MediaListCollection_Lists_Entries_CompletedAt{Year: date.Year, Month: date.Month, Day: date.Day}
+		}
+		entries = append(entries, entry)
+	}
+
+	mp.mu.Lock()
+	mp.animeMalIDs = animeMalIDs
+	mp.animeCollection = mo.Some(platform.NewAnimeCollection(entries))
+	mp.mu.Unlock()
+
+	return nil
+}
+
+func (mp *MalPlatform) GetAnimeCollectionWithRelations() (*anilist.AnimeCollectionWithRelations, error) {
+	mp.logger.Trace().Msg("mal platform: Fetching anime collection with relations")
+
+	collection, err := mp.GetAnimeCollection(false)
+	if err != nil || collection == nil {
+		return nil, err
+	}
+
+	ids := lo.Map(collection.GetAllAnime(), func(m *anilist.BaseAnime, _ int) int { return m.ID })
+	media, err := anilist.FetchCompleteAnimeMap(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return platform.NewAnimeCollectionWithRelations(collection, media), nil
+}
+
+func (mp *MalPlatform) GetMangaCollection(bypassCache bool) (*anilist.MangaCollection, error) {
+	if collection, ok := mp.getMangaCollection().Get(); ok && !bypassCache {
+		return collection, nil
+	}
+
+	if !mp.isAuthenticated() {
+		return nil, nil
+	}
+
+	err := mp.refreshMangaCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	return mp.getMangaCollection().MustGet(), nil
+}
+
+// GetRawMangaCollection returns the same collection as GetMangaCollection since MyAnimeList has no custom lists.
+func (mp *MalPlatform) GetRawMangaCollection(bypassCache bool) (*anilist.MangaCollection, error) {
+	return mp.GetMangaCollection(bypassCache)
+}
+
+func (mp *MalPlatform) RefreshMangaCollection() (*anilist.MangaCollection, error) {
+	if !mp.isAuthenticated() {
+		return nil, nil
+	}
+
+	err := mp.refreshMangaCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	return mp.getMangaCollection().MustGet(), nil
+}
+
+func (mp *MalPlatform) refreshMangaCollection() error {
+	w, err := mp.getWrapper()
+	if err != nil {
+		return err
+	}
+
+	malEntries, err := w.GetMangaCollection()
+	if err != nil {
+		return err
+	}
+
+	malIDs := lo.Map(malEntries, func(e *mal.MangaListEntry, _ int) int { return e.Node.ID })
+	media, err := anilist.FetchBaseMangaMapByMalIDs(malIDs)
+	if err != nil {
+		return err
+	}
+
+	mangaMalIDs := make(map[int]int, len(malEntries))
+	entries := make([]*anilist.MangaCollection_MediaListCollection_Lists_Entries, 0, len(malEntries))
+	for _, e := range malEntries {
+		m, ok := media[e.Node.ID]
+		if !ok {
+			mp.logger.Debug().Int("malId", e.Node.ID).Str("title", e.Node.Title).Msg("mal platform: Could not find media on AniList")
+			continue
+		}
+		mangaMalIDs[m.ID] = e.Node.ID
+
+		entry := &anilist.MangaCollection_MediaListCollection_Lists_Entries{
+			ID:       m.ID,
+			Score:    lo.ToPtr(float64(e.ListStatus.Score * 10)),
+			Progress: lo.ToPtr(e.ListStatus.NumChaptersRead),
+			Status:   lo.ToPtr(fromMalStatus(e.ListStatus.Status, e.ListStatus.IsRereading)),
+			Media:    m,
+		}
+		if date := platform.ParseFuzzyDate(e.ListStatus.StartDate); date != nil {
+			entry.StartedAt = &anilist.MangaCollection_MediaListCollection_Lists_Entries_StartedAt{Year: date.Year, Month: date.Month, Day: date.Day}
+		}
+		if date := platform.ParseFuzzyDate(e.ListStatus.FinishDate); date != nil {
+			entry.CompletedAt = &anilist.MangaCollection_MediaListCollection_Lists_Entries_CompletedAt{Year: date.Year, Month: date.Month, Day: date.Day}
+		}
+		entries = append(entries, entry)
+	}
+
+	mp.mu.Lock()
+	mp.mangaMalIDs = mangaMalIDs
+	mp.mangaCollection = mo.Some(platform.NewMangaCollection(entries))
+	mp.mu.Unlock()
+
+	return nil
+}
+
+func (mp *MalPlatform) AddMediaToCollection(mIds []int) error {
+	mp.logger.Trace().Msg("mal platform: Adding media to collection")
+	if len(mIds) == 0 {
+		mp.logger.Debug().Msg("mal platform: No media added to planning list")
+		return nil
+	}
+
+	w, err := mp.getWrapper()
+	if err != nil {
+		return err
+	}
+
+	rateLimiter := limiter.NewLimiter(1*time.Second, 1) // 1 request per second
+
+	status := mal.MediaListStatusPlanToWatch
+	for _, id := range mIds {
+		rateLimiter.Wait()
+		malID, _, err := mp.resolveMalID(id)
+		if err != nil {
+			mp.logger.Error().Err(err).Int("mediaId", id).Msg("mal platform: An error occurred while adding media to planning list")
+			continue
+		}
+		err = w.UpdateAnimeListStatus(&mal.AnimeListStatusParams{
+			Status: &status,
+		}, malID)
+		if err != nil {
+			mp.logger.Error().Err(err).Int("mediaId", id).Msg("mal platform: An error occurred while adding media to planning list")
+		}
+	}
+
+	mp.logger.Debug().Any("count", len(mIds)).Msg("mal platform: Media added to planning list")
+	return nil
+}
+
+func (mp *MalPlatform) GetStudioDetails(studioID int) (*anilist.StudioDetails, error) {
+	mp.logger.Trace().Msg("mal platform: Fetching studio details")
+	ret, err := mp.anilistClient.StudioDetails(context.Background(), &studioID)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (mp *MalPlatform) GetAnilistClient() anilist.AnilistClient {
+	return mp.anilistClient
+}
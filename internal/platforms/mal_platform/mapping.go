@@ -0,0 +1,71 @@
+package mal_platform
+
+import (
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/mal"
+)
+
+// toMalStatus converts an AniList status to a MAL status.
+// MAL has no "repeating" status, it is represented by the "watching"/"reading" status with the rewatching flag.
+func toMalStatus(status anilist.MediaListStatus, isManga bool) (ret mal.MediaListStatus, repeating bool) {
+	switch status {
+	case anilist.MediaListStatusCurrent:
+		if isManga {
+			return mal.MediaListStatusReading, false
+		}
+		return mal.MediaListStatusWatching, false
+	case anilist.MediaListStatusRepeating:
+		if isManga {
+			return mal.MediaListStatusReading, true
+		}
+		return mal.MediaListStatusWatching, true
+	case anilist.MediaListStatusCompleted:
+		return mal.MediaListStatusCompleted, false
+	case anilist.MediaListStatusPaused:
+		return mal.MediaListStatusOnHold, false
+	case anilist.MediaListStatusDropped:
+		return mal.MediaListStatusDropped, false
+	default:
+		if isManga {
+			return mal.MediaListStatusPlanToRead, false
+		}
+		return mal.MediaListStatusPlanToWatch, false
+	}
+}
+
+// fromMalStatus converts a MAL status to an AniList status.
+func fromMalStatus(status mal.MediaListStatus, repeating bool) anilist.MediaListStatus {
+	switch status {
+	case mal.MediaListStatusWatching, mal.MediaListStatusReading:
+		if repeating {
+			return anilist.MediaListStatusRepeating
+		}
+		return anilist.MediaListStatusCurrent
+	case mal.MediaListStatusCompleted:
+		if repeating {
+			return anilist.MediaListStatusRepeating
+		}
+		return anilist.MediaListStatusCompleted
+	case mal.MediaListStatusOnHold:
+		return anilist.MediaListStatusPaused
+	case mal.MediaListStatusDropped:
+		return anilist.MediaListStatusDropped
+	default:
+		return anilist.MediaListStatusPlanning
+	}
+}
+
+// toMalScore converts a raw AniList score (0-100) to a MAL score (0-10).
+func toMalScore(scoreRaw *int) *int {
+	if scoreRaw == nil {
+		return nil
+	}
+	score := (*scoreRaw + 5) / 10
+	if score > 10 {
+		score = 10
+	}
+	if score < 0 {
+		score = 0
+	}
+	return &score
+}
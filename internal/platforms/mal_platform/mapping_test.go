@@ -0,0 +1,59 @@
+package mal_platform
+
+import (
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/mal"
+	"testing"
+)
+
+func TestStatusConversion(t *testing.T) {
+
+	tests := []struct {
+		status    anilist.MediaListStatus
+		isManga   bool
+		expected  mal.MediaListStatus
+		repeating bool
+	}{
+		{anilist.MediaListStatusCurrent, false, mal.MediaListStatusWatching, false},
+		{anilist.MediaListStatusCurrent, true, mal.MediaListStatusReading, false},
+		{anilist.MediaListStatusRepeating, false, mal.MediaListStatusWatching, true},
+		{anilist.MediaListStatusRepeating, true, mal.MediaListStatusReading, true},
+		{anilist.MediaListStatusCompleted, false, mal.MediaListStatusCompleted, false},
+		{anilist.MediaListStatusPaused, false, mal.MediaListStatusOnHold, false},
+		{anilist.MediaListStatusDropped, true, mal.MediaListStatusDropped, false},
+		{anilist.MediaListStatusPlanning, false, mal.MediaListStatusPlanToWatch, false},
+		{anilist.MediaListStatusPlanning, true, mal.MediaListStatusPlanToRead, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			ret, repeating := toMalStatus(tt.status, tt.isManga)
+			assert.Equal(t, tt.expected, ret)
+			assert.Equal(t, tt.repeating, repeating)
+
+			// Round trip
+			assert.Equal(t, tt.status, fromMalStatus(ret, repeating))
+		})
+	}
+}
+
+func TestToMalScore(t *testing.T) {
+
+	tests := []struct {
+		scoreRaw *int
+		expected *int
+	}{
+		{nil, nil},
+		{lo.ToPtr(0), lo.ToPtr(0)},
+		{lo.ToPtr(84), lo.ToPtr(8)},
+		{lo.ToPtr(85), lo.ToPtr(9)},
+		{lo.ToPtr(100), lo.ToPtr(10)},
+		{lo.ToPtr(120), lo.ToPtr(10)},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, toMalScore(tt.scoreRaw))
+	}
+}
@@ -0,0 +1,183 @@
+package platform
+
+import (
+	"fmt"
+	"github.com/samber/lo"
+	"seanime/internal/api/anilist"
+	"strconv"
+	"strings"
+)
+
+// listOrder is the order in which AniList returns the lists of a collection.
+var listOrder = []anilist.MediaListStatus{
+	anilist.MediaListStatusCurrent,
+	anilist.MediaListStatusRepeating,
+	anilist.MediaListStatusCompleted,
+	anilist.MediaListStatusPaused,
+	anilist.MediaListStatusDropped,
+	anilist.MediaListStatusPlanning,
+}
+
+func listName(status anilist.MediaListStatus, isManga bool) string {
+	switch status {
+	case anilist.MediaListStatusCurrent:
+		if isManga {
+			return "Reading"
+		}
+		return "Watching"
+	case anilist.MediaListStatusRepeating:
+		if isManga {
+			return "Rereading"
+		}
+		return "Rewatching"
+	case anilist.MediaListStatusCompleted:
+		return "Completed"
+	case anilist.MediaListStatusPaused:
+		return "Paused"
+	case anilist.MediaListStatusDropped:
+		return "Dropped"
+	default:
+		return "Planning"
+	}
+}
+
+// NewAnimeCollection groups the entries by status into an AniList-shaped collection.
+// This is used by platforms that do not use AniList to track progress.
+// Entries with no status are ignored.
+func NewAnimeCollection(entries []*anilist.AnimeListEntry) *anilist.AnimeCollection {
+	lists := make([]*anilist.AnimeCollection_MediaListCollection_Lists, 0, len(listOrder))
+	for _, status := range listOrder {
+		listEntries := lo.Filter(entries, func(e *anilist.AnimeListEntry, _ int) bool {
+			return e.Status != nil && *e.Status == status && e.Media != nil
+		})
+		lists = append(lists, &anilist.AnimeCollection_MediaListCollection_Lists{
+			Status:       lo.ToPtr(status),
+			Name:         lo.ToPtr(listName(status, false)),
+			IsCustomList: lo.ToPtr(false),
+			Entries:      listEntries,
+		})
+	}
+	return &anilist.AnimeCollection{
+		MediaListCollection: &anilist.AnimeCollection_MediaListCollection{
+			Lists: lists,
+		},
+	}
+}
+
+// NewMangaCollection is the same as NewAnimeCollection but for manga.
+func NewMangaCollection(entries []*anilist.MangaCollection_MediaListCollection_Lists_Entries) *anilist.MangaCollection {
+	lists := make([]*anilist.MangaCollection_MediaListCollection_Lists, 0, len(listOrder))
+	for _, status := range listOrder {
+		listEntries := lo.Filter(entries, func(e *anilist.MangaCollection_MediaListCollection_Lists_Entries, _ int) bool {
+			return e.Status != nil && *e.Status == status && e.Media != nil
+		})
+		lists = append(lists, &anilist.MangaCollection_MediaListCollection_Lists{
+			Status:       lo.ToPtr(status),
+			Name:         lo.ToPtr(listName(status, true)),
+			IsCustomList: lo.ToPtr(false),
+			Entries:      listEntries,
+		})
+	}
+	return &anilist.MangaCollection{
+		MediaListCollection: &anilist.MangaCollection_MediaListCollection{
+			Lists: lists,
+		},
+	}
+}
+
+// NewAnimeCollectionWithRelations converts the collection using the given media.
+// Entries whose media is not in the map are skipped.
+func NewAnimeCollectionWithRelations(collection *anilist.AnimeCollection, media map[int]*anilist.CompleteAnime) *anilist.AnimeCollectionWithRelations {
+	ret := &anilist.AnimeCollectionWithRelations{
+		MediaListCollection: &anilist.AnimeCollectionWithRelations_MediaListCollection{
+			Lists: make([]*anilist.AnimeCollectionWithRelations_MediaListCollection_Lists, 0),
+		},
+	}
+	for _, list := range collection.GetMediaListCollection().GetLists() {
+		entries := make([]*anilist.AnimeCollectionWithRelations_MediaListCollection_Lists_Entries, 0, len(list.GetEntries()))
+		for _, entry := range list.GetEntries() {
+			m, ok := media[entry.GetMedia().GetID()]
+			if !ok {
+				continue
+			}
+			e := &anilist.AnimeCollectionWithRelations_MediaListCollection_Lists_Entries{
+				ID:       entry.ID,
+				Score:    entry.Score,
+				Progress: entry.Progress,
+				Status:   entry.Status,
+				Notes:    entry.Notes,
+				Repeat:   entry.Repeat,
+				Private:  entry.Private,
+				Media:    m,
+			}
+			if entry.StartedAt != nil {
+				e.StartedAt = &anilist.AnimeCollectionWithRelations_MediaListCollection_Lists_Entries_StartedAt{
+					Year:  entry.StartedAt.Year,
+					Month: entry.StartedAt.Month,
+					Day:   entry.StartedAt.Day,
+				}
+			}
+			if entry.CompletedAt != nil {
+				e.CompletedAt = &anilist.AnimeCollectionWithRelations_MediaListCollection_Lists_Entries_CompletedAt{
+					Year:  entry.CompletedAt.Year,
+					Month: entry.CompletedAt.Month,
+					Day:   entry.CompletedAt.Day,
+				}
+			}
+			entries = append(entries, e)
+		}
+		ret.MediaListCollection.Lists = append(ret.MediaListCollection.Lists, &anilist.AnimeCollectionWithRelations_MediaListCollection_Lists{
+			Status:       list.Status,
+			Name:         list.Name,
+			IsCustomList: list.IsCustomList,
+			Entries:      entries,
+		})
+	}
+	return ret
+}
+
+// ParseFuzzyDate parses dates such as "2024-01-05", "2024-01" or "2024-01-05T00:00:00.000Z".
+// Returns nil if the date is empty or invalid.
+func ParseFuzzyDate(s string) *anilist.FuzzyDateInput {
+	s = strings.TrimSpace(s)
+	if len(s) > 10 {
+		s = s[:10]
+	}
+	if s == "" {
+		return nil
+	}
+
+	parts := strings.Split(s, "-")
+	ret := &anilist.FuzzyDateInput{}
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		switch i {
+		case 0:
+			ret.Year = &v
+		case 1:
+			ret.Month = &v
+		case 2:
+			ret.Day = &v
+		}
+	}
+	return ret
+}
+
+// FormatFuzzyDate formats the date as "YYYY-MM-DD", "YYYY-MM" or "YYYY" depending on which fields are set.
+// Returns an empty string if the year is not set.
+func FormatFuzzyDate(date *anilist.FuzzyDateInput) string {
+	if date == nil || date.Year == nil {
+		return ""
+	}
+	ret := fmt.Sprintf("%04d", *date.Year)
+	if date.Month != nil {
+		ret += fmt.Sprintf("-%02d", *date.Month)
+		if date.Day != nil {
+			ret += fmt.Sprintf("-%02d", *date.Day)
+		}
+	}
+	return ret
+}
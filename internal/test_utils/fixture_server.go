@@ -0,0 +1,102 @@
+package test_utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type (
+	// FixtureServer replays the JSON responses recorded in a testdata directory.
+	// The route function returns the status code and the fixture file of each request,
+	// an empty fixture name sends the status code without a body. Missing fixtures are served as 404.
+	FixtureServer struct {
+		URL      string
+		dir      string
+		route    func(r *http.Request) (status int, fixture string)
+		mu       sync.Mutex
+		requests []*FixtureRequest
+	}
+
+	FixtureRequest struct {
+		Method string
+		Path   string
+		Form   url.Values // Query and body parameters
+	}
+)
+
+// NewFixtureServer starts a FixtureServer that is closed at the end of the test.
+func NewFixtureServer(t *testing.T, dir string, route func(r *http.Request) (status int, fixture string)) *FixtureServer {
+	f := &FixtureServer{dir: dir, route: route}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	f.URL = server.URL
+	return f
+}
+
+func (f *FixtureServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+
+	f.mu.Lock()
+	f.requests = append(f.requests, &FixtureRequest{Method: r.Method, Path: r.URL.Path, Form: r.Form})
+	f.mu.Unlock()
+
+	status, fixture := f.route(r)
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if fixture == "" {
+		w.WriteHeader(status)
+		return
+	}
+
+	data, err := os.ReadFile(filepath.Join(f.dir, fixture))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+// Called returns true if a request was made to the path with the given form values.
+// The method is ignored if empty, form is a list of key and value pairs.
+func (f *FixtureServer) Called(method string, path string, form ...string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, req := range f.requests {
+		if (method != "" && req.Method != method) || req.Path != path {
+			continue
+		}
+		matches := true
+		for i := 0; i+1 < len(form); i += 2 {
+			if req.Form.Get(form[i]) != form[i+1] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// Form returns a form value of the last request made to the path.
+func (f *FixtureServer) Form(path string, key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := len(f.requests) - 1; i >= 0; i-- {
+		if f.requests[i].Path == path {
+			return f.requests[i].Form.Get(key)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,206 @@
+package deluge
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/goccy/go-json"
+	"github.com/rs/zerolog"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errCodeNotAuthenticated is returned by the Web UI when the session cookie is missing or expired.
+const errCodeNotAuthenticated = 1
+
+type (
+	// Deluge is a client for the JSON-RPC API of the Deluge Web UI.
+	Deluge struct {
+		Path     string
+		Logger   *zerolog.Logger
+		url      string
+		password string
+		client   *http.Client
+		mu       sync.Mutex
+		id       int
+	}
+
+	NewDelugeOptions struct {
+		Path     string
+		Logger   *zerolog.Logger
+		Password string
+		Host     string // Default: 127.0.0.1
+		Port     int    // Default: 8112
+	}
+
+	rpcRequest struct {
+		Method string        `json:"method"`
+		Params []interface{} `json:"params"`
+		ID     int           `json:"id"`
+	}
+
+	rpcResponse struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+		ID     int             `json:"id"`
+	}
+
+	rpcError struct {
+		Message string `json:"message"`
+		Code    int    `json:"code"`
+	}
+)
+
+func New(options *NewDelugeOptions) *Deluge {
+	if options.Host == "" {
+		options.Host = "127.0.0.1"
+	}
+	if options.Port == 0 {
+		options.Port = 8112
+	}
+
+	// The Web UI keeps the session in a cookie
+	jar, _ := cookiejar.New(nil)
+
+	return &Deluge{
+		Path:     options.Path,
+		Logger:   options.Logger,
+		url:      fmt.Sprintf("http://%s:%d/json", options.Host, options.Port),
+		password: options.Password,
+		client:   &http.Client{Jar: jar, Timeout: 30 * time.Second},
+	}
+}
+
+// Login authenticates to the Web UI and connects it to a daemon if it is not already connected.
+func (c *Deluge) Login() error {
+	var ok bool
+	if err := c.rawCall("auth.login", []interface{}{c.password}, &ok); err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("deluge: invalid password")
+	}
+
+	_, err := c.connect()
+	return err
+}
+
+// connect connects the Web UI to the first daemon if it is not connected.
+// Returns true if the Web UI was not connected.
+func (c *Deluge) connect() (bool, error) {
+	var connected bool
+	if err := c.rawCall("web.connected", []interface{}{}, &connected); err != nil {
+		return false, err
+	}
+	if connected {
+		return false, nil
+	}
+
+	// Connect to the first daemon, e.g. [["id", "127.0.0.1", 58846, "localclient"]]
+	var hosts [][]interface{}
+	if err := c.rawCall("web.get_hosts", []interface{}{}, &hosts); err != nil {
+		return true, err
+	}
+	if len(hosts) == 0 || len(hosts[0]) == 0 {
+		return true, errors.New("deluge: no daemon found")
+	}
+	hostId, _ := hosts[0][0].(string)
+
+	if err := c.rawCall("web.connect", []interface{}{hostId}, nil); err != nil {
+		return true, err
+	}
+
+	c.Logger.Debug().Str("host", hostId).Msg("deluge: Connected the Web UI to the daemon")
+
+	return true, nil
+}
+
+// Ping returns nil if the Web UI can be reached with the current credentials and is connected to a daemon.
+func (c *Deluge) Ping() error {
+	var connected bool
+	if err := c.call("web.connected", []interface{}{}, &connected); err != nil {
+		return err
+	}
+	if connected {
+		return nil
+	}
+
+	_, err := c.connect()
+	return err
+}
+
+// call sends the request, logging in again if the session has expired
+// and reconnecting the Web UI if it was disconnected from the daemon (e.g. the daemon restarted).
+func (c *Deluge) call(method string, params []interface{}, result interface{}) error {
+	err := c.rawCall(method, params, result)
+	var rpcErr *rpcError
+	if err == nil || !errors.As(err, &rpcErr) {
+		return err
+	}
+
+	if rpcErr.Code == errCodeNotAuthenticated {
+		if err := c.Login(); err != nil {
+			return err
+		}
+		return c.rawCall(method, params, result)
+	}
+
+	// The core methods are unknown to the Web UI while it is not connected
+	if !strings.HasPrefix(method, "core.") {
+		return err
+	}
+	reconnected, connectErr := c.connect()
+	if connectErr != nil || !reconnected {
+		return err
+	}
+	return c.rawCall(method, params, result)
+}
+
+func (c *Deluge) rawCall(method string, params []interface{}, result interface{}) error {
+	c.mu.Lock()
+	c.id++
+	id := c.id
+	c.mu.Unlock()
+
+	body, err := json.Marshal(&rpcRequest{Method: method, Params: params, ID: id})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("deluge: %s returned status code %d", method, resp.StatusCode)
+	}
+
+	var rpcResp rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return err
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if result == nil || len(rpcResp.Result) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(rpcResp.Result, result)
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("deluge: %s (code %d)", e.Message, e.Code)
+}
@@ -0,0 +1,236 @@
+package deluge
+
+import (
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"seanime/internal/util"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDeluge is a fake Deluge Web UI JSON-RPC server.
+type fakeDeluge struct {
+	mu         sync.Mutex
+	password   string
+	connected  bool
+	torrents   map[string]map[string]interface{}
+	priorities map[string][]int
+	calls      []string
+}
+
+func newFakeDeluge() *fakeDeluge {
+	return &fakeDeluge{
+		password: "deluge",
+		torrents: map[string]map[string]interface{}{
+			"0a1b2c": {
+				"name":                  "[SubsPlease] Sousou no Frieren (01-28) (1080p) [Batch]",
+				"num_seeds":             12,
+				"upload_payload_rate":   0,
+				"download_payload_rate": 1048576,
+				"progress":              42.5,
+				"total_size":            34359738368,
+				"eta":                   3600,
+				"state":                 "Downloading",
+				"save_path":             "/downloads",
+				"is_finished":           false,
+			},
+		},
+		priorities: map[string][]int{"0a1b2c": {1, 1, 1, 1}},
+	}
+}
+
+func (f *fakeDeluge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var req struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+		ID     int               `json:"id"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	f.calls = append(f.calls, req.Method)
+
+	respond := func(result interface{}) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "error": nil, "id": req.ID})
+	}
+
+	if req.Method == "auth.login" {
+		var password string
+		_ = json.Unmarshal(req.Params[0], &password)
+		if password == f.password {
+			http.SetCookie(w, &http.Cookie{Name: "_session_id", Value: "session", Path: "/"})
+		}
+		respond(password == f.password)
+		return
+	}
+
+	if c, err := r.Cookie("_session_id"); err != nil || c.Value != "session" {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": nil, "error": map[string]interface{}{"message": "Not authenticated", "code": 1}, "id": req.ID})
+		return
+	}
+
+	// The core methods are only registered while the Web UI is connected to a daemon
+	if strings.HasPrefix(req.Method, "core.") && !f.connected {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": nil, "error": map[string]interface{}{"message": "Unknown method", "code": 2}, "id": req.ID})
+		return
+	}
+
+	switch req.Method {
+	case "web.connected":
+		respond(f.connected)
+	case "web.get_hosts":
+		respond([][]interface{}{{"host1", "127.0.0.1", 58846, "localclient"}})
+	case "web.connect":
+		f.connected = true
+		respond([]string{})
+	case "core.get_torrents_status":
+		respond(f.torrents)
+	case "core.get_torrent_status":
+		var hash string
+		var keys []string
+		_ = json.Unmarshal(req.Params[0], &hash)
+		_ = json.Unmarshal(req.Params[1], &keys)
+		if _, ok := f.torrents[hash]; !ok {
+			respond(map[string]interface{}{})
+			return
+		}
+		ret := map[string]interface{}{}
+		for _, key := range keys {
+			switch key {
+			case "files":
+				files := make([]map[string]interface{}, 0)
+				for i := range f.priorities[hash] {
+					files = append(files, map[string]interface{}{"index": i, "path": "Frieren/" + strconv.Itoa(i+1) + ".mkv", "size": 100})
+				}
+				ret["files"] = files
+			case "file_priorities":
+				ret["file_priorities"] = f.priorities[hash]
+			default:
+				ret[key] = f.torrents[hash][key]
+			}
+		}
+		respond(ret)
+	case "core.set_torrent_options":
+		var hashes []string
+		var options struct {
+			FilePriorities []int `json:"file_priorities"`
+		}
+		_ = json.Unmarshal(req.Params[0], &hashes)
+		_ = json.Unmarshal(req.Params[1], &options)
+		f.priorities[hashes[0]] = options.FilePriorities
+		respond(nil)
+	case "core.add_torrent_magnet":
+		f.torrents["3d4e5f"] = map[string]interface{}{"name": "New", "state": "Downloading"}
+		respond("3d4e5f")
+	case "core.pause_torrents":
+		var hashes []string
+		_ = json.Unmarshal(req.Params[0], &hashes)
+		for _, h := range hashes {
+			f.torrents[h]["state"] = "Paused"
+		}
+		respond(nil)
+	case "core.remove_torrent":
+		var hash string
+		_ = json.Unmarshal(req.Params[0], &hash)
+		delete(f.torrents, hash)
+		respond(true)
+	default:
+		respond(nil)
+	}
+}
+
+func newTestDeluge(t *testing.T, fake *fakeDeluge) *Deluge {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, _ := strconv.Atoi(u.Port())
+
+	return New(&NewDelugeOptions{
+		Logger:   util.NewLogger(),
+		Password: "deluge",
+		Host:     u.Hostname(),
+		Port:     port,
+	})
+}
+
+func TestDeluge_Login(t *testing.T) {
+	fake := newFakeDeluge()
+	client := newTestDeluge(t, fake)
+
+	// The first call logs in and connects to the daemon
+	torrents, err := client.GetTorrents(nil)
+	require.NoError(t, err)
+	assert.True(t, fake.connected)
+	assert.Equal(t, []string{"core.get_torrents_status", "auth.login", "web.connected", "web.get_hosts", "web.connect", "core.get_torrents_status"}, fake.calls)
+
+	require.Len(t, torrents, 1)
+	assert.Equal(t, "0a1b2c", torrents[0].Hash)
+	assert.Equal(t, StateDownloading, torrents[0].State)
+	assert.Equal(t, 42.5, torrents[0].Progress)
+	assert.Equal(t, int64(34359738368), torrents[0].TotalSize)
+
+	// Wrong password
+	client.password = "wrong"
+	client.client.Jar = nil
+	_, err = client.GetTorrents(nil)
+	assert.Error(t, err)
+}
+
+func TestDeluge_Reconnect(t *testing.T) {
+	fake := newFakeDeluge()
+	client := newTestDeluge(t, fake)
+
+	require.True(t, client.CheckStart())
+	assert.True(t, fake.connected)
+
+	// The daemon restarted, the Web UI is reconnected
+	fake.connected = false
+	fake.calls = nil
+	torrents, err := client.GetTorrents(nil)
+	require.NoError(t, err)
+	assert.Len(t, torrents, 1)
+	assert.Equal(t, []string{"core.get_torrents_status", "web.connected", "web.get_hosts", "web.connect", "core.get_torrents_status"}, fake.calls)
+
+	fake.connected = false
+	require.NoError(t, client.Ping())
+	assert.True(t, fake.connected)
+
+	// Deluge is not started by Seanime but the Web UI is still checked
+	client.password = "wrong"
+	client.client.Jar = nil
+	assert.False(t, client.CheckStart())
+}
+
+func TestDeluge_Torrents(t *testing.T) {
+	fake := newFakeDeluge()
+	client := newTestDeluge(t, fake)
+
+	hash, err := client.AddMagnet("magnet:?xt=urn:btih:3d4e5f", "/downloads")
+	require.NoError(t, err)
+	assert.Equal(t, "3d4e5f", hash)
+	assert.True(t, client.TorrentExists("3D4E5F"))
+	assert.False(t, client.TorrentExists("ffffff"))
+
+	files, err := client.GetFiles("0a1b2c")
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+	assert.Equal(t, "Frieren/2.mkv", files[1].Path)
+
+	require.NoError(t, client.SetFilesUnwanted("0a1b2c", []int{0, 2}))
+	assert.Equal(t, []int{0, 1, 0, 1}, fake.priorities["0a1b2c"])
+
+	require.NoError(t, client.PauseTorrents([]string{"0A1B2C"}))
+	assert.Equal(t, StatePaused, fake.torrents["0a1b2c"]["state"])
+
+	require.NoError(t, client.RemoveTorrents([]string{"3d4e5f"}, true))
+	assert.False(t, client.TorrentExists("3d4e5f"))
+}
@@ -0,0 +1,91 @@
+package deluge
+
+import (
+	"errors"
+	"runtime"
+	"seanime/internal/util"
+	"time"
+)
+
+func (c *Deluge) getExecutableName() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "deluge.exe"
+	default:
+		return "deluge"
+	}
+}
+
+func (c *Deluge) getExecutablePath() string {
+
+	if len(c.Path) > 0 {
+		return c.Path
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		return "C:/Program Files/Deluge/deluge.exe"
+	case "linux":
+		return "/usr/bin/deluge" // Default path for Deluge on most Linux distributions
+	case "darwin":
+		return "/Applications/Deluge.app/Contents/MacOS/Deluge"
+	default:
+		return "C:/Program Files/Deluge/deluge.exe"
+	}
+}
+
+func (c *Deluge) Start() error {
+
+	// If the path is empty, do not check if Deluge is running
+	if c.Path == "" {
+		return nil
+	}
+
+	name := c.getExecutableName()
+	if util.ProgramIsRunning(name) {
+		return nil
+	}
+
+	exe := c.getExecutablePath()
+	cmd := util.NewCmd(exe)
+	err := cmd.Start()
+	if err != nil {
+		return errors.New("failed to start Deluge")
+	}
+
+	time.Sleep(1 * time.Second)
+
+	return nil
+}
+
+func (c *Deluge) CheckStart() bool {
+	if c == nil {
+		return false
+	}
+
+	err := c.Ping()
+	if err == nil {
+		return true
+	}
+
+	// If the path is empty, Deluge is not started by Seanime (e.g. it runs on another machine)
+	if c.Path == "" {
+		c.Logger.Debug().Err(err).Msg("deluge: Could not reach the Web UI")
+		return false
+	}
+
+	err = c.Start()
+	timeout := time.After(30 * time.Second)
+	ticker := time.Tick(1 * time.Second)
+	for {
+		select {
+		case <-ticker:
+			err := c.Ping()
+			if err == nil {
+				return true
+			}
+		case <-timeout:
+			return false
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package deluge
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+const (
+	StateDownloading = "Downloading"
+	StateSeeding     = "Seeding"
+	StatePaused      = "Paused"
+	StateChecking    = "Checking"
+	StateQueued      = "Queued"
+	StateAllocating  = "Allocating"
+	StateMoving      = "Moving"
+	StateError       = "Error"
+)
+
+type (
+	Torrent struct {
+		Hash                string  `json:"hash"`
+		Name                string  `json:"name"`
+		NumSeeds            int     `json:"num_seeds"`
+		UploadPayloadRate   int64   `json:"upload_payload_rate"`
+		DownloadPayloadRate int64   `json:"download_payload_rate"`
+		Progress            float64 `json:"progress"` // 0-100
+		TotalSize           int64   `json:"total_size"`
+		Eta                 float64 `json:"eta"`
+		State               string  `json:"state"`
+		SavePath            string  `json:"save_path"`
+		IsFinished          bool    `json:"is_finished"`
+	}
+
+	File struct {
+		Index int    `json:"index"`
+		Path  string `json:"path"`
+		Size  int64  `json:"size"`
+	}
+)
+
+var torrentKeys = []string{
+	"hash",
+	"name",
+	"num_seeds",
+	"upload_payload_rate",
+	"download_payload_rate",
+	"progress",
+	"total_size",
+	"eta",
+	"state",
+	"save_path",
+	"is_finished",
+}
+
+// GetTorrents returns the torrents with the given hashes, or all the torrents if hashes is empty.
+func (c *Deluge) GetTorrents(hashes []string) ([]*Torrent, error) {
+	filter := map[string]interface{}{}
+	if len(hashes) > 0 {
+		filter["id"] = toLower(hashes)
+	}
+
+	var res map[string]*Torrent
+	if err := c.call("core.get_torrents_status", []interface{}{filter, torrentKeys}, &res); err != nil {
+		return nil, err
+	}
+
+	ret := make([]*Torrent, 0, len(res))
+	for hash, t := range res {
+		if t == nil {
+			continue
+		}
+		if t.Hash == "" {
+			t.Hash = hash
+		}
+		ret = append(ret, t)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+
+	return ret, nil
+}
+
+// AddMagnet adds the magnet link and returns the hash of the torrent.
+func (c *Deluge) AddMagnet(magnet string, dest string) (string, error) {
+	options := map[string]interface{}{}
+	if dest != "" {
+		options["download_location"] = dest
+	}
+
+	var hash *string
+	if err := c.call("core.add_torrent_magnet", []interface{}{magnet, options}, &hash); err != nil {
+		return "", err
+	}
+	if hash == nil {
+		// The torrent is already added
+		return "", nil
+	}
+	return *hash, nil
+}
+
+func (c *Deluge) RemoveTorrents(hashes []string, removeData bool) error {
+	for _, hash := range toLower(hashes) {
+		if err := c.call("core.remove_torrent", []interface{}{hash, removeData}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Deluge) PauseTorrents(hashes []string) error {
+	return c.call("core.pause_torrents", []interface{}{toLower(hashes)}, nil)
+}
+
+func (c *Deluge) ResumeTorrents(hashes []string) error {
+	return c.call("core.resume_torrents", []interface{}{toLower(hashes)}, nil)
+}
+
+// GetFiles returns the files of the torrent.
+// The list is empty while the metadata of a magnet link is being downloaded.
+func (c *Deluge) GetFiles(hash string) ([]*File, error) {
+	var res struct {
+		Files []*File `json:"files"`
+	}
+	if err := c.call("core.get_torrent_status", []interface{}{strings.ToLower(hash), []string{"files"}}, &res); err != nil {
+		return nil, err
+	}
+	if res.Files == nil {
+		return []*File{}, nil
+	}
+	return res.Files, nil
+}
+
+// SetFilesUnwanted sets the priority of the files at the given indices to 0, the other priorities are kept.
+func (c *Deluge) SetFilesUnwanted(hash string, indices []int) error {
+	hash = strings.ToLower(hash)
+
+	var res struct {
+		FilePriorities []int `json:"file_priorities"`
+	}
+	if err := c.call("core.get_torrent_status", []interface{}{hash, []string{"file_priorities"}}, &res); err != nil {
+		return err
+	}
+	if len(res.FilePriorities) == 0 {
+		return errors.New("deluge: torrent has no files")
+	}
+
+	priorities := res.FilePriorities
+	for _, idx := range indices {
+		if idx >= 0 && idx < len(priorities) {
+			priorities[idx] = 0
+		}
+	}
+
+	return c.call("core.set_torrent_options", []interface{}{[]string{hash}, map[string]interface{}{"file_priorities": priorities}}, nil)
+}
+
+// TorrentExists returns true if the torrent is in the session.
+func (c *Deluge) TorrentExists(hash string) bool {
+	var res map[string]interface{}
+	err := c.call("core.get_torrent_status", []interface{}{strings.ToLower(hash), []string{"hash"}}, &res)
+	return err == nil && len(res) > 0
+}
+
+func toLower(hashes []string) []string {
+	ret := make([]string, len(hashes))
+	for i, h := range hashes {
+		ret[i] = strings.ToLower(h)
+	}
+	return ret
+}
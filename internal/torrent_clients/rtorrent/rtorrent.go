@@ -0,0 +1,92 @@
+package rtorrent
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/rs/zerolog"
+	"io"
+	"net/http"
+	"time"
+)
+
+const maxResponseSize = 32 * 1024 * 1024
+
+type (
+	// RTorrent is a client for the XML-RPC API of rTorrent.
+	// The API is usually exposed by a web server (e.g. /RPC2) or by the httprpc plugin of ruTorrent.
+	RTorrent struct {
+		Logger   *zerolog.Logger
+		url      string
+		username string
+		password string
+		client   *http.Client
+	}
+
+	NewRTorrentOptions struct {
+		Logger   *zerolog.Logger
+		URL      string // Default: http://127.0.0.1/RPC2
+		Username string
+		Password string
+	}
+)
+
+func New(options *NewRTorrentOptions) *RTorrent {
+	if options.URL == "" {
+		options.URL = "http://127.0.0.1/RPC2"
+	}
+	return &RTorrent{
+		Logger:   options.Logger,
+		url:      options.URL,
+		username: options.Username,
+		password: options.Password,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Ping returns nil if rTorrent can be reached with the current credentials.
+func (c *RTorrent) Ping() error {
+	_, err := c.call("system.client_version")
+	return err
+}
+
+// CheckStart returns true if rTorrent can be reached.
+// rTorrent runs as a daemon, it is never started by Seanime.
+func (c *RTorrent) CheckStart() bool {
+	if c == nil {
+		return false
+	}
+	return c.Ping() == nil
+}
+
+func (c *RTorrent) call(method string, params ...interface{}) (interface{}, error) {
+	body, err := encodeMethodCall(method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rtorrent: %s returned status code %d", method, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeMethodResponse(data)
+}
@@ -0,0 +1,196 @@
+package rtorrent
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"seanime/internal/util"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRTorrent is a fake rTorrent XML-RPC server.
+type fakeRTorrent struct {
+	mu       sync.Mutex
+	calls    []string
+	params   [][]string
+	torrents map[string]bool
+	basePath string
+}
+
+func (f *fakeRTorrent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		MethodName string   `xml:"methodName"`
+		Params     []string `xml:"params>param>value>string"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.calls = append(f.calls, req.MethodName)
+	f.params = append(f.params, req.Params)
+
+	respond := func(value string) {
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><methodResponse><params><param><value>%s</value></param></params></methodResponse>`, value)
+	}
+
+	switch req.MethodName {
+	case "system.client_version":
+		respond("<string>0.9.8</string>")
+	case "d.multicall2":
+		row := func(hash, name string, size, completed, state, active, complete int) string {
+			return fmt.Sprintf(`<value><array><data>
+				<value><string>%s</string></value>
+				<value><string>%s</string></value>
+				<value><i8>%d</i8></value>
+				<value><i8>%d</i8></value>
+				<value><i8>0</i8></value>
+				<value><i8>2048</i8></value>
+				<value><i8>%d</i8></value>
+				<value><i8>%d</i8></value>
+				<value><i8>%d</i8></value>
+				<value><i8>0</i8></value>
+				<value><string>/downloads</string></value>
+				<value><i8>5</i8></value>
+			</data></array></value>`, hash, name, size, completed, state, active, complete)
+		}
+		respond("<array><data>" +
+			row("0A1B2C", "Downloading", 4096, 1024, 1, 1, 0) +
+			row("3D4E5F", "Seeding", 4096, 4096, 1, 1, 1) +
+			row("6A7B8C", "Paused", 4096, 1024, 1, 0, 0) +
+			"</data></array>")
+	case "f.multicall":
+		respond(`<array><data>
+			<value><array><data><value><string>Frieren/01.mkv</string></value></data></array></value>
+			<value><array><data><value><string>Frieren/02.mkv</string></value></data></array></value>
+		</data></array>`)
+	case "d.name":
+		if len(req.Params) == 0 || !f.torrents[req.Params[0]] {
+			_, _ = fmt.Fprint(w, `<?xml version="1.0"?><methodResponse><fault><value><struct>
+				<member><name>faultCode</name><value><i4>-501</i4></value></member>
+				<member><name>faultString</name><value><string>Could not find info-hash.</string></value></member>
+			</struct></value></fault></methodResponse>`)
+			return
+		}
+		respond("<string>Frieren</string>")
+	case "d.base_path":
+		respond("<string>" + f.basePath + "</string>")
+	default:
+		respond("<i8>0</i8>")
+	}
+}
+
+func newTestRTorrent(t *testing.T, fake *fakeRTorrent) *RTorrent {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return New(&NewRTorrentOptions{
+		Logger:   util.NewLogger(),
+		URL:      server.URL + "/RPC2",
+		Username: "user",
+		Password: "pass",
+	})
+}
+
+func TestRTorrent_GetTorrents(t *testing.T) {
+	fake := &fakeRTorrent{}
+	client := newTestRTorrent(t, fake)
+
+	require.True(t, client.CheckStart())
+
+	torrents, err := client.GetTorrents()
+	require.NoError(t, err)
+	require.Len(t, torrents, 3)
+
+	assert.Equal(t, "0a1b2c", torrents[0].Hash)
+	assert.Equal(t, int64(4096), torrents[0].SizeBytes)
+	assert.Equal(t, int64(1024), torrents[0].CompletedBytes)
+	assert.Equal(t, int64(2048), torrents[0].DownRate)
+	assert.True(t, torrents[0].IsActive)
+	assert.False(t, torrents[0].IsComplete)
+	assert.Equal(t, "/downloads", torrents[0].Directory)
+	assert.True(t, torrents[1].IsComplete)
+	assert.False(t, torrents[2].IsActive)
+
+	// Wrong credentials
+	client.password = "wrong"
+	assert.False(t, client.CheckStart())
+}
+
+func TestRTorrent_Torrents(t *testing.T) {
+	fake := &fakeRTorrent{torrents: map[string]bool{"0A1B2C": true}}
+	client := newTestRTorrent(t, fake)
+
+	assert.True(t, client.TorrentExists("0a1b2c"))
+	assert.False(t, client.TorrentExists("ffffff"))
+
+	require.NoError(t, client.AddMagnet("magnet:?xt=urn:btih:0a1b2c&dn=Frieren", `/downloads/Sousou no "Frieren"`))
+	assert.Equal(t, []string{"", "magnet:?xt=urn:btih:0a1b2c&dn=Frieren", `d.directory.set="/downloads/Sousou no \"Frieren\""`}, fake.params[len(fake.params)-1])
+
+	files, err := client.GetFiles("0a1b2c")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Frieren/01.mkv", "Frieren/02.mkv"}, files)
+
+	fake.calls = nil
+	fake.params = nil
+	require.NoError(t, client.SetFilesUnwanted("0a1b2c", []int{1}))
+	assert.Equal(t, []string{"f.priority.set", "d.update_priorities"}, fake.calls)
+	assert.Equal(t, "0A1B2C:f1", fake.params[0][0])
+
+	// The data is removed
+	fake.basePath = filepath.Join(t.TempDir(), "Frieren")
+	require.NoError(t, os.MkdirAll(filepath.Join(fake.basePath, "Season 1"), 0755))
+	fake.calls = nil
+	require.NoError(t, client.RemoveTorrents([]string{"0a1b2c"}, true))
+	assert.Equal(t, []string{"d.base_path", "d.custom5.set", "d.erase"}, fake.calls)
+	assert.NoDirExists(t, fake.basePath)
+
+	// The data is kept
+	require.NoError(t, os.MkdirAll(fake.basePath, 0755))
+	fake.calls = nil
+	require.NoError(t, client.RemoveTorrents([]string{"0a1b2c"}, false))
+	assert.Equal(t, []string{"d.erase"}, fake.calls)
+	assert.DirExists(t, fake.basePath)
+
+	// Stopped torrent, the path is built from the directory of the torrent
+	fake.basePath = ""
+	fake.calls = nil
+	require.NoError(t, client.RemoveTorrents([]string{"0a1b2c"}, true))
+	assert.Equal(t, []string{"d.base_path", "d.directory", "d.is_multi_file", "d.name", "d.custom5.set", "d.erase"}, fake.calls)
+}
+
+func TestDecodeMethodResponse(t *testing.T) {
+	v, err := decodeMethodResponse([]byte(`<methodResponse><params><param><value>untyped</value></param></params></methodResponse>`))
+	require.NoError(t, err)
+	assert.Equal(t, "untyped", v)
+
+	v, err = decodeMethodResponse([]byte(`<methodResponse><params><param><value><struct>
+		<member><name>a</name><value><boolean>1</boolean></value></member>
+		<member><name>b</name><value><double>1.5</double></value></member>
+	</struct></value></param></params></methodResponse>`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": true, "b": 1.5}, v)
+
+	_, err = decodeMethodResponse([]byte(`<methodResponse><fault><value><struct>
+		<member><name>faultCode</name><value><int>-506</int></value></member>
+		<member><name>faultString</name><value><string>Method not defined</string></value></member>
+	</struct></value></fault></methodResponse>`))
+	var fault *Fault
+	require.ErrorAs(t, err, &fault)
+	assert.Equal(t, -506, fault.Code)
+	assert.True(t, strings.Contains(err.Error(), "Method not defined"))
+}
@@ -0,0 +1,215 @@
+package rtorrent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type (
+	Torrent struct {
+		Hash           string
+		Name           string
+		SizeBytes      int64
+		CompletedBytes int64
+		UpRate         int64
+		DownRate       int64
+		State          int64 // 1 if started, 0 if stopped
+		IsActive       bool  // false if paused
+		IsComplete     bool
+		IsHashing      bool
+		Directory      string
+		PeersComplete  int64
+	}
+)
+
+var torrentFields = []string{
+	"d.hash=",
+	"d.name=",
+	"d.size_bytes=",
+	"d.completed_bytes=",
+	"d.up.rate=",
+	"d.down.rate=",
+	"d.state=",
+	"d.is_active=",
+	"d.complete=",
+	"d.hashing=",
+	"d.directory=",
+	"d.peers_complete=",
+}
+
+// GetTorrents returns all the torrents of the main view.
+func (c *RTorrent) GetTorrents() ([]*Torrent, error) {
+	params := []interface{}{"", "main"}
+	for _, f := range torrentFields {
+		params = append(params, f)
+	}
+
+	res, err := c.call("d.multicall2", params...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, ok := res.([]interface{})
+	if !ok {
+		return nil, errors.New("rtorrent: unexpected response")
+	}
+
+	ret := make([]*Torrent, 0, len(rows))
+	for _, row := range rows {
+		values, ok := row.([]interface{})
+		if !ok || len(values) < len(torrentFields) {
+			continue
+		}
+		ret = append(ret, &Torrent{
+			Hash:           strings.ToLower(toString(values[0])),
+			Name:           toString(values[1]),
+			SizeBytes:      toInt64(values[2]),
+			CompletedBytes: toInt64(values[3]),
+			UpRate:         toInt64(values[4]),
+			DownRate:       toInt64(values[5]),
+			State:          toInt64(values[6]),
+			IsActive:       toInt64(values[7]) == 1,
+			IsComplete:     toInt64(values[8]) == 1,
+			IsHashing:      toInt64(values[9]) != 0,
+			Directory:      toString(values[10]),
+			PeersComplete:  toInt64(values[11]),
+		})
+	}
+
+	return ret, nil
+}
+
+// AddMagnet adds and starts the magnet link.
+func (c *RTorrent) AddMagnet(magnet string, dest string) error {
+	params := []interface{}{"", magnet}
+	if dest != "" {
+		params = append(params, `d.directory.set="`+strings.ReplaceAll(dest, `"`, `\"`)+`"`)
+	}
+	_, err := c.call("load.start", params...)
+	return err
+}
+
+// RemoveTorrents removes the torrents from rTorrent.
+// rTorrent does not delete the data itself. If removeData is true, the torrents are flagged for the erasedata plugin of ruTorrent
+// and the data is deleted using the path reported by rTorrent.
+// The data can only be deleted if that path is accessible from Seanime, i.e. rTorrent runs on the same machine or the paths are mounted the same way.
+func (c *RTorrent) RemoveTorrents(hashes []string, removeData bool) error {
+	for _, hash := range hashes {
+		hash = strings.ToUpper(hash)
+
+		dataPath := ""
+		if removeData {
+			dataPath = c.getDataPath(hash)
+			_, _ = c.call("d.custom5.set", hash, "1")
+		}
+
+		if _, err := c.call("d.erase", hash); err != nil {
+			return err
+		}
+
+		if dataPath != "" {
+			if err := os.RemoveAll(dataPath); err != nil {
+				c.Logger.Warn().Err(err).Str("path", dataPath).Msg("rtorrent: Failed to remove the data of the torrent")
+			}
+		}
+	}
+	return nil
+}
+
+// getDataPath returns the path of the file or folder of the torrent if it is accessible, or an empty string.
+// d.base_path is empty while the torrent is stopped, the path is then built from d.directory.
+func (c *RTorrent) getDataPath(hash string) string {
+	ret := ""
+	if res, err := c.call("d.base_path", hash); err == nil {
+		ret = toString(res)
+	}
+	if ret == "" {
+		// d.directory is the folder of the torrent for multi-file torrents, the parent folder for single-file torrents
+		dir, err := c.call("d.directory", hash)
+		if err != nil || toString(dir) == "" {
+			return ""
+		}
+		ret = toString(dir)
+		if isMultiFile, err := c.call("d.is_multi_file", hash); err != nil || toInt64(isMultiFile) != 1 {
+			name, err := c.call("d.name", hash)
+			if err != nil || toString(name) == "" {
+				return ""
+			}
+			ret = filepath.Join(ret, toString(name))
+		}
+	}
+
+	ret = filepath.Clean(ret)
+	// Never remove a relative path or a root directory
+	if !filepath.IsAbs(ret) || filepath.Dir(ret) == ret {
+		return ""
+	}
+	if _, err := os.Stat(ret); err != nil {
+		c.Logger.Warn().Str("path", ret).Msg("rtorrent: The data of the torrent is not accessible, it will not be removed")
+		return ""
+	}
+	return ret
+}
+
+func (c *RTorrent) PauseTorrents(hashes []string) error {
+	for _, hash := range hashes {
+		if _, err := c.call("d.stop", strings.ToUpper(hash)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RTorrent) ResumeTorrents(hashes []string) error {
+	for _, hash := range hashes {
+		if _, err := c.call("d.start", strings.ToUpper(hash)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetFiles returns the paths of the files of the torrent, in order.
+// The list is empty while the metadata of a magnet link is being downloaded.
+func (c *RTorrent) GetFiles(hash string) ([]string, error) {
+	res, err := c.call("f.multicall", strings.ToUpper(hash), "", "f.path=")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, ok := res.([]interface{})
+	if !ok {
+		return nil, errors.New("rtorrent: unexpected response")
+	}
+
+	ret := make([]string, 0, len(rows))
+	for _, row := range rows {
+		values, ok := row.([]interface{})
+		if !ok || len(values) == 0 {
+			continue
+		}
+		ret = append(ret, toString(values[0]))
+	}
+	return ret, nil
+}
+
+// SetFilesUnwanted sets the priority of the files at the given indices to 0 ("off").
+func (c *RTorrent) SetFilesUnwanted(hash string, indices []int) error {
+	hash = strings.ToUpper(hash)
+	for _, idx := range indices {
+		if _, err := c.call("f.priority.set", hash+":f"+strconv.Itoa(idx), 0); err != nil {
+			return err
+		}
+	}
+	_, err := c.call("d.update_priorities", hash)
+	return err
+}
+
+// TorrentExists returns true if the torrent is loaded.
+func (c *RTorrent) TorrentExists(hash string) bool {
+	_, err := c.call("d.name", strings.ToUpper(hash))
+	return err == nil
+}
@@ -0,0 +1,204 @@
+package rtorrent
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Minimal XML-RPC encoding, enough for the rTorrent API.
+
+type (
+	xmlValue struct {
+		Text    string     `xml:",chardata"`
+		String  *string    `xml:"string"`
+		Int     *string    `xml:"int"`
+		I4      *string    `xml:"i4"`
+		I8      *string    `xml:"i8"`
+		Boolean *string    `xml:"boolean"`
+		Double  *string    `xml:"double"`
+		Array   *xmlArray  `xml:"array"`
+		Struct  *xmlStruct `xml:"struct"`
+		Nil     *struct{}  `xml:"nil"`
+	}
+
+	xmlArray struct {
+		Values []xmlValue `xml:"data>value"`
+	}
+
+	xmlStruct struct {
+		Members []xmlMember `xml:"member"`
+	}
+
+	xmlMember struct {
+		Name  string   `xml:"name"`
+		Value xmlValue `xml:"value"`
+	}
+
+	xmlMethodResponse struct {
+		Params []xmlValue `xml:"params>param>value"`
+		Fault  *xmlValue  `xml:"fault>value"`
+	}
+
+	// Fault is an error returned by rTorrent.
+	Fault struct {
+		Code    int
+		Message string
+	}
+)
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("rtorrent: %s (code %d)", f.Message, f.Code)
+}
+
+func encodeMethodCall(method string, params []interface{}) ([]byte, error) {
+	buf := bytes.NewBufferString(xml.Header)
+	buf.WriteString("<methodCall><methodName>")
+	if err := xml.EscapeText(buf, []byte(method)); err != nil {
+		return nil, err
+	}
+	buf.WriteString("</methodName><params>")
+	for _, p := range params {
+		buf.WriteString("<param>")
+		if err := encodeValue(buf, p); err != nil {
+			return nil, err
+		}
+		buf.WriteString("</param>")
+	}
+	buf.WriteString("</params></methodCall>")
+	return buf.Bytes(), nil
+}
+
+func encodeValue(buf *bytes.Buffer, v interface{}) error {
+	buf.WriteString("<value>")
+	switch v := v.(type) {
+	case string:
+		buf.WriteString("<string>")
+		if err := xml.EscapeText(buf, []byte(v)); err != nil {
+			return err
+		}
+		buf.WriteString("</string>")
+	case int:
+		buf.WriteString("<i8>" + strconv.Itoa(v) + "</i8>")
+	case int64:
+		buf.WriteString("<i8>" + strconv.FormatInt(v, 10) + "</i8>")
+	case bool:
+		buf.WriteString("<boolean>" + map[bool]string{true: "1", false: "0"}[v] + "</boolean>")
+	case float64:
+		buf.WriteString("<double>" + strconv.FormatFloat(v, 'f', -1, 64) + "</double>")
+	case []string:
+		buf.WriteString("<array><data>")
+		for _, s := range v {
+			if err := encodeValue(buf, s); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("</data></array>")
+	case []interface{}:
+		buf.WriteString("<array><data>")
+		for _, e := range v {
+			if err := encodeValue(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("</data></array>")
+	default:
+		return fmt.Errorf("rtorrent: unsupported parameter type %T", v)
+	}
+	buf.WriteString("</value>")
+	return nil
+}
+
+// decodeMethodResponse returns the first parameter of the response.
+// Arrays are decoded as []interface{}, structs as map[string]interface{}, integers as int64.
+func decodeMethodResponse(data []byte) (interface{}, error) {
+	var resp xmlMethodResponse
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Fault != nil {
+		fault := &Fault{}
+		if m, ok := resp.Fault.toInterface().(map[string]interface{}); ok {
+			fault.Code = int(toInt64(m["faultCode"]))
+			fault.Message = toString(m["faultString"])
+		}
+		return nil, fault
+	}
+
+	if len(resp.Params) == 0 {
+		return nil, nil
+	}
+	return resp.Params[0].toInterface(), nil
+}
+
+func (v *xmlValue) toInterface() interface{} {
+	switch {
+	case v.String != nil:
+		return *v.String
+	case v.Int != nil:
+		return parseInt(*v.Int)
+	case v.I4 != nil:
+		return parseInt(*v.I4)
+	case v.I8 != nil:
+		return parseInt(*v.I8)
+	case v.Boolean != nil:
+		return strings.TrimSpace(*v.Boolean) == "1"
+	case v.Double != nil:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(*v.Double), 64)
+		return f
+	case v.Array != nil:
+		ret := make([]interface{}, 0, len(v.Array.Values))
+		for i := range v.Array.Values {
+			ret = append(ret, v.Array.Values[i].toInterface())
+		}
+		return ret
+	case v.Struct != nil:
+		ret := make(map[string]interface{}, len(v.Struct.Members))
+		for i := range v.Struct.Members {
+			ret[v.Struct.Members[i].Name] = v.Struct.Members[i].Value.toInterface()
+		}
+		return ret
+	case v.Nil != nil:
+		return nil
+	default:
+		// A value without a type is a string
+		return v.Text
+	}
+}
+
+func parseInt(s string) int64 {
+	i, _ := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	return i
+}
+
+func toInt64(v interface{}) int64 {
+	switch v := v.(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case string:
+		return parseInt(v)
+	default:
+		return 0
+	}
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"github.com/rs/zerolog"
 	"seanime/internal/api/metadata"
 	"seanime/internal/events"
+	"seanime/internal/torrent_clients/deluge"
 	"seanime/internal/torrent_clients/qbittorrent"
 	"seanime/internal/torrent_clients/qbittorrent/model"
+	"seanime/internal/torrent_clients/rtorrent"
 	"seanime/internal/torrent_clients/transmission"
 	"seanime/internal/torrents/torrent"
 	"strconv"
@@ -18,6 +20,8 @@ import (
 const (
 	QbittorrentClient  = "qbittorrent"
 	TransmissionClient = "transmission"
+	DelugeClient       = "deluge"
+	RTorrentClient     = "rtorrent"
 	NoneClient         = "none"
 )
 
@@ -26,6 +30,8 @@ type (
 		logger                      *zerolog.Logger
 		qBittorrentClient           *qbittorrent.Client
 		transmission                *transmission.Transmission
+		deluge                      *deluge.Deluge
+		rtorrent                    *rtorrent.RTorrent
 		torrentRepository           *torrent.Repository
 		provider                    string
 		metadataProvider            metadata.Provider
@@ -37,6 +43,8 @@ type (
 		Logger            *zerolog.Logger
 		QbittorrentClient *qbittorrent.Client
 		Transmission      *transmission.Transmission
+		Deluge            *deluge.Deluge
+		RTorrent          *rtorrent.RTorrent
 		TorrentRepository *torrent.Repository
 		Provider          string
 		MetadataProvider  metadata.Provider
@@ -57,6 +65,8 @@ func NewRepository(opts *NewRepositoryOptions) *Repository {
 		logger:             opts.Logger,
 		qBittorrentClient:  opts.QbittorrentClient,
 		transmission:       opts.Transmission,
+		deluge:             opts.Deluge,
+		rtorrent:           opts.RTorrent,
 		torrentRepository:  opts.TorrentRepository,
 		provider:           opts.Provider,
 		metadataProvider:   opts.MetadataProvider,
@@ -109,6 +119,10 @@ func (r *Repository) Start() bool {
 		return r.qBittorrentClient.CheckStart()
 	case TransmissionClient:
 		return r.transmission.CheckStart()
+	case DelugeClient:
+		return r.deluge.CheckStart()
+	case RTorrentClient:
+		return r.rtorrent.CheckStart()
 	case NoneClient:
 		return true
 	default:
@@ -123,6 +137,10 @@ func (r *Repository) TorrentExists(hash string) bool {
 	case TransmissionClient:
 		torrents, err := r.transmission.Client.TorrentGetAllForHashes(context.Background(), []string{hash})
 		return err == nil && len(torrents) > 0
+	case DelugeClient:
+		return r.deluge.TorrentExists(hash)
+	case RTorrentClient:
+		return r.rtorrent.TorrentExists(hash)
 	default:
 		return false
 	}
@@ -145,6 +163,20 @@ func (r *Repository) GetList() ([]*Torrent, error) {
 			return nil, err
 		}
 		return r.FromTransmissionTorrents(torrents), nil
+	case DelugeClient:
+		torrents, err := r.deluge.GetTorrents(nil)
+		if err != nil {
+			r.logger.Err(err).Msg("torrent client: Error while getting torrent list (Deluge)")
+			return nil, err
+		}
+		return r.FromDelugeTorrents(torrents), nil
+	case RTorrentClient:
+		torrents, err := r.rtorrent.GetTorrents()
+		if err != nil {
+			r.logger.Err(err).Msg("torrent client: Error while getting torrent list (rTorrent)")
+			return nil, err
+		}
+		return r.FromRTorrentTorrents(torrents), nil
 	default:
 		return nil, errors.New("torrent client: No torrent client provider found")
 	}
@@ -195,6 +227,22 @@ func (r *Repository) GetActiveCount(ret *ActiveCount) {
 			}
 		}
 		return
+	case DelugeClient, RTorrentClient:
+		torrents, err := r.GetList()
+		if err != nil {
+			return
+		}
+		for _, t := range torrents {
+			switch t.Status {
+			case TorrentStatusDownloading:
+				ret.Downloading++
+			case TorrentStatusSeeding:
+				ret.Seeding++
+			case TorrentStatusPaused:
+				ret.Paused++
+			}
+		}
+		return
 	default:
 		return
 	}
@@ -240,6 +288,22 @@ func (r *Repository) AddMagnets(magnets []string, dest string) error {
 				break
 			}
 		}
+	case DelugeClient:
+		for _, magnet := range magnets {
+			_, err = r.deluge.AddMagnet(magnet, dest)
+			if err != nil {
+				r.logger.Err(err).Msg("torrent client: Error while adding magnets (Deluge)")
+				break
+			}
+		}
+	case RTorrentClient:
+		for _, magnet := range magnets {
+			err = r.rtorrent.AddMagnet(magnet, dest)
+			if err != nil {
+				r.logger.Err(err).Msg("torrent client: Error while adding magnets (rTorrent)")
+				break
+			}
+		}
 	case NoneClient:
 		return errors.New("torrent client: No torrent client selected")
 	}
@@ -279,6 +343,10 @@ func (r *Repository) RemoveTorrents(hashes []string) error {
 			r.logger.Err(err).Msg("torrent client: Error while removing torrents (Transmission)")
 			return err
 		}
+	case DelugeClient:
+		err = r.deluge.RemoveTorrents(hashes, true)
+	case RTorrentClient:
+		err = r.rtorrent.RemoveTorrents(hashes, true)
 	}
 	if err != nil {
 		r.logger.Err(err).Msg("torrent client: Error while removing torrents")
@@ -298,6 +366,10 @@ func (r *Repository) PauseTorrents(hashes []string) error {
 		err = r.qBittorrentClient.Torrent.StopTorrents(hashes)
 	case TransmissionClient:
 		err = r.transmission.Client.TorrentStopHashes(context.Background(), hashes)
+	case DelugeClient:
+		err = r.deluge.PauseTorrents(hashes)
+	case RTorrentClient:
+		err = r.rtorrent.PauseTorrents(hashes)
 	}
 
 	if err != nil {
@@ -319,6 +391,10 @@ func (r *Repository) ResumeTorrents(hashes []string) error {
 		err = r.qBittorrentClient.Torrent.ResumeTorrents(hashes)
 	case TransmissionClient:
 		err = r.transmission.Client.TorrentStartHashes(context.Background(), hashes)
+	case DelugeClient:
+		err = r.deluge.ResumeTorrents(hashes)
+	case RTorrentClient:
+		err = r.rtorrent.ResumeTorrents(hashes)
 	}
 
 	if err != nil {
@@ -356,6 +432,10 @@ func (r *Repository) DeselectFiles(hash string, indices []int) error {
 			FilesUnwanted: ind,
 			IDs:           []int64{id},
 		})
+	case DelugeClient:
+		err = r.deluge.SetFilesUnwanted(hash, indices)
+	case RTorrentClient:
+		err = r.rtorrent.SetFilesUnwanted(hash, indices)
 	}
 
 	if err != nil {
@@ -408,6 +488,22 @@ func (r *Repository) GetFiles(hash string) (filenames []string, err error) {
 						}
 						return
 					}
+				case DelugeClient:
+					delugeFiles, err := r.deluge.GetFiles(hash)
+					if err == nil && len(delugeFiles) > 0 {
+						r.logger.Debug().Str("hash", hash).Int("count", len(delugeFiles)).Msg("torrent client: Retrieved torrent files")
+						for _, f := range delugeFiles {
+							filenames = append(filenames, f.Path)
+						}
+						return
+					}
+				case RTorrentClient:
+					rtorrentFiles, err := r.rtorrent.GetFiles(hash)
+					if err == nil && len(rtorrentFiles) > 0 {
+						r.logger.Debug().Str("hash", hash).Int("count", len(rtorrentFiles)).Msg("torrent client: Retrieved torrent files")
+						filenames = append(filenames, rtorrentFiles...)
+						return
+					}
 				}
 			}
 		}
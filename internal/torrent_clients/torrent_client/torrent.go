@@ -3,7 +3,9 @@ package torrent_client
 import (
 	"github.com/dustin/go-humanize"
 	"github.com/hekmon/transmissionrpc/v3"
+	"seanime/internal/torrent_clients/deluge"
 	"seanime/internal/torrent_clients/qbittorrent/model"
+	"seanime/internal/torrent_clients/rtorrent"
 	"seanime/internal/util"
 )
 
@@ -162,3 +164,104 @@ func fromQbitTorrentStatus(st qbittorrent_model.TorrentState) TorrentStatus {
 		return TorrentStatusOther
 	}
 }
+
+func (r *Repository) FromDelugeTorrents(t []*deluge.Torrent) []*Torrent {
+	ret := make([]*Torrent, 0, len(t))
+	for _, t := range t {
+		ret = append(ret, r.FromDelugeTorrent(t))
+	}
+	return ret
+}
+
+func (r *Repository) FromDelugeTorrent(t *deluge.Torrent) *Torrent {
+	torrent := &Torrent{}
+
+	torrent.Name = t.Name
+	torrent.Hash = t.Hash
+	torrent.Seeds = t.NumSeeds
+	torrent.UpSpeed = util.ToHumanReadableSpeed(int(t.UploadPayloadRate))
+	torrent.DownSpeed = util.ToHumanReadableSpeed(int(t.DownloadPayloadRate))
+	torrent.Progress = t.Progress / 100
+	torrent.Size = humanize.Bytes(uint64(t.TotalSize))
+	torrent.Eta = util.FormatETA(int(t.Eta))
+	torrent.ContentPath = t.SavePath
+	torrent.Status = fromDelugeTorrentStatus(t.State, t.IsFinished)
+
+	return torrent
+}
+
+// fromDelugeTorrentStatus returns a normalized status for the torrent.
+func fromDelugeTorrentStatus(st string, isFinished bool) TorrentStatus {
+	switch st {
+	case deluge.StateSeeding:
+		return TorrentStatusSeeding
+	case deluge.StatePaused:
+		if isFinished {
+			return TorrentStatusStopped
+		}
+		return TorrentStatusPaused
+	case deluge.StateDownloading, deluge.StateChecking, deluge.StateAllocating, deluge.StateMoving:
+		return TorrentStatusDownloading
+	case deluge.StateQueued:
+		if isFinished {
+			return TorrentStatusSeeding
+		}
+		return TorrentStatusDownloading
+	default:
+		return TorrentStatusOther
+	}
+}
+
+func (r *Repository) FromRTorrentTorrents(t []*rtorrent.Torrent) []*Torrent {
+	ret := make([]*Torrent, 0, len(t))
+	for _, t := range t {
+		ret = append(ret, r.FromRTorrentTorrent(t))
+	}
+	return ret
+}
+
+func (r *Repository) FromRTorrentTorrent(t *rtorrent.Torrent) *Torrent {
+	torrent := &Torrent{}
+
+	torrent.Name = t.Name
+	torrent.Hash = t.Hash
+	torrent.Seeds = int(t.PeersComplete)
+	torrent.UpSpeed = util.ToHumanReadableSpeed(int(t.UpRate))
+	torrent.DownSpeed = util.ToHumanReadableSpeed(int(t.DownRate))
+	torrent.Progress = 0.0
+	if t.SizeBytes > 0 {
+		torrent.Progress = float64(t.CompletedBytes) / float64(t.SizeBytes)
+	}
+	torrent.Size = humanize.Bytes(uint64(t.SizeBytes))
+	torrent.Eta = "???"
+	if t.IsComplete {
+		torrent.Eta = util.FormatETA(0)
+	} else if t.DownRate > 0 {
+		torrent.Eta = util.FormatETA(int((t.SizeBytes - t.CompletedBytes) / t.DownRate))
+	}
+	torrent.ContentPath = t.Directory
+	torrent.Status = fromRTorrentTorrentStatus(t)
+
+	return torrent
+}
+
+// fromRTorrentTorrentStatus returns a normalized status for the torrent.
+// rTorrent has no status, it is derived from the state (started or stopped) and the activity (paused or not) of the torrent.
+func fromRTorrentTorrentStatus(t *rtorrent.Torrent) TorrentStatus {
+	if t.IsHashing {
+		return TorrentStatusDownloading
+	}
+	if t.State == 0 {
+		if t.IsComplete {
+			return TorrentStatusStopped
+		}
+		return TorrentStatusPaused
+	}
+	if !t.IsActive {
+		return TorrentStatusPaused
+	}
+	if t.IsComplete {
+		return TorrentStatusSeeding
+	}
+	return TorrentStatusDownloading
+}
@@ -0,0 +1,48 @@
+package torrent_client
+
+import (
+	"github.com/stretchr/testify/assert"
+	"seanime/internal/torrent_clients/deluge"
+	"seanime/internal/torrent_clients/rtorrent"
+	"testing"
+)
+
+func TestFromDelugeTorrentStatus(t *testing.T) {
+	tests := []struct {
+		state      string
+		isFinished bool
+		expected   TorrentStatus
+	}{
+		{deluge.StateDownloading, false, TorrentStatusDownloading},
+		{deluge.StateChecking, false, TorrentStatusDownloading},
+		{deluge.StateQueued, false, TorrentStatusDownloading},
+		{deluge.StateQueued, true, TorrentStatusSeeding},
+		{deluge.StateSeeding, true, TorrentStatusSeeding},
+		{deluge.StatePaused, false, TorrentStatusPaused},
+		{deluge.StatePaused, true, TorrentStatusStopped},
+		{deluge.StateError, false, TorrentStatusOther},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, fromDelugeTorrentStatus(tt.state, tt.isFinished), tt.state)
+	}
+}
+
+func TestFromRTorrentTorrentStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		torrent  *rtorrent.Torrent
+		expected TorrentStatus
+	}{
+		{"downloading", &rtorrent.Torrent{State: 1, IsActive: true}, TorrentStatusDownloading},
+		{"seeding", &rtorrent.Torrent{State: 1, IsActive: true, IsComplete: true}, TorrentStatusSeeding},
+		{"paused", &rtorrent.Torrent{State: 1, IsActive: false}, TorrentStatusPaused},
+		{"stopped incomplete", &rtorrent.Torrent{State: 0}, TorrentStatusPaused},
+		{"stopped complete", &rtorrent.Torrent{State: 0, IsComplete: true}, TorrentStatusStopped},
+		{"hashing", &rtorrent.Torrent{State: 1, IsHashing: true}, TorrentStatusDownloading},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, fromRTorrentTorrentStatus(tt.torrent), tt.name)
+	}
+}
@@ -0,0 +1,313 @@
+package torrentstream
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"seanime/internal/util"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Cache
+//
+// Torrents are stored in the download directory, in a folder named after their info hash.
+// When the cache is enabled (MaxCacheSize > 0), torrents whose streamed file is fully downloaded are kept on disk
+// so that they can be streamed again without being downloaded.
+// When the download directory exceeds the maximum size, the least recently used torrents are evicted.
+
+const cacheIndexFilename = ".seanime-cache.json"
+
+type (
+	// CacheEntry is a torrent stored in the download directory.
+	CacheEntry struct {
+		InfoHash      string `json:"infoHash"`
+		Name          string `json:"name"`
+		MediaId       int    `json:"mediaId"`
+		MediaTitle    string `json:"mediaTitle"`
+		EpisodeNumber int    `json:"episodeNumber"`
+		// FilePath is the path of the streamed file, relative to the torrent folder
+		FilePath   string    `json:"filePath"`
+		Completed  bool      `json:"completed"` // The streamed file is fully downloaded
+		LastUsedAt time.Time `json:"lastUsedAt"`
+		// CompletedAt is used to enforce the seeding time limit
+		CompletedAt    time.Time `json:"completedAt,omitempty"`
+		SeedingStopped bool      `json:"seedingStopped"`
+		// PromotedPath is the path of the file copied to the library, if any
+		PromotedPath string `json:"promotedPath,omitempty"`
+	}
+
+	// cache keeps track of the torrents stored in the download directory.
+	// The index is persisted in the download directory so that it is consistent with the files.
+	cache struct {
+		mu      sync.Mutex
+		dir     string
+		entries map[string]*CacheEntry // Key: info hash
+	}
+
+	CacheStatus struct {
+		Enabled   bool                `json:"enabled"`
+		TotalSize int64               `json:"totalSize"` // Bytes
+		MaxSize   int64               `json:"maxSize"`   // Bytes, 0 if the cache is disabled
+		Entries   []*CacheEntryStatus `json:"entries"`
+	}
+
+	CacheEntryStatus struct {
+		*CacheEntry
+		Size      int64   `json:"size"`
+		IsCurrent bool    `json:"isCurrent"` // The torrent is being streamed
+		IsSeeding bool    `json:"isSeeding"`
+		Ratio     float64 `json:"ratio"`
+	}
+)
+
+func newCache() *cache {
+	return &cache{
+		entries: make(map[string]*CacheEntry),
+	}
+}
+
+// load reads the index from the download directory.
+// Entries whose folder no longer exists are removed.
+func (c *cache) load(dir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dir = dir
+	c.entries = make(map[string]*CacheEntry)
+
+	data, err := os.ReadFile(filepath.Join(dir, cacheIndexFilename))
+	if err != nil {
+		return
+	}
+
+	var entries []*CacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		if _, err := os.Stat(filepath.Join(dir, e.InfoHash)); err != nil {
+			continue
+		}
+		c.entries[e.InfoHash] = e
+	}
+}
+
+// save writes the index to the download directory.
+// The lock should be held by the caller.
+func (c *cache) save() {
+	if c.dir == "" {
+		return
+	}
+
+	entries := make([]*CacheEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].InfoHash < entries[j].InfoHash
+	})
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(c.dir, cacheIndexFilename), data, 0644)
+}
+
+// update applies fn to the entry, creating it if it does not exist, and saves the index.
+func (c *cache) update(infoHash string, fn func(e *CacheEntry)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[infoHash]
+	if !ok {
+		e = &CacheEntry{InfoHash: infoHash}
+		c.entries[infoHash] = e
+	}
+	fn(e)
+	c.save()
+}
+
+func (c *cache) get(infoHash string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[infoHash]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	return *e, true
+}
+
+func (c *cache) remove(infoHash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, infoHash)
+	c.save()
+}
+
+// isKept returns true if the folder should be kept when the torrents are dropped.
+func (c *cache) isKept(infoHash string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[infoHash]
+	return ok && e.Completed
+}
+
+// selectEvictions returns the info hashes of the folders to remove so that the total size is under maxSize.
+// Incomplete torrents are removed first, then completed torrents from the least recently used.
+// The current torrent is never removed.
+func (c *cache) selectEvictions(sizes map[string]int64, maxSize int64, currentInfoHash string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+	if total <= maxSize {
+		return nil
+	}
+
+	candidates := make([]string, 0, len(sizes))
+	for infoHash := range sizes {
+		if infoHash == currentInfoHash {
+			continue
+		}
+		candidates = append(candidates, infoHash)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		ei, oki := c.entries[candidates[i]]
+		ej, okj := c.entries[candidates[j]]
+		completedI := oki && ei.Completed
+		completedJ := okj && ej.Completed
+		if completedI != completedJ {
+			return !completedI
+		}
+		if !completedI {
+			return candidates[i] < candidates[j]
+		}
+		return ei.LastUsedAt.Before(ej.LastUsedAt)
+	})
+
+	ret := make([]string, 0)
+	for _, infoHash := range candidates {
+		if total <= maxSize {
+			break
+		}
+		ret = append(ret, infoHash)
+		total -= sizes[infoHash]
+	}
+
+	return ret
+}
+
+// getFolderSizes returns the size of each torrent folder in the download directory.
+func getFolderSizes(dir string) map[string]int64 {
+	ret := make(map[string]int64)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ret
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		size, err := util.DirSize(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		ret[entry.Name()] = int64(size)
+	}
+
+	return ret
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (r *Repository) getMaxCacheSize() int64 {
+	if r.settings.IsAbsent() {
+		return 0
+	}
+	return int64(r.settings.MustGet().MaxCacheSize) * 1024 * 1024
+}
+
+func (r *Repository) isCacheEnabled() bool {
+	return r.getMaxCacheSize() > 0
+}
+
+// evictCache removes the torrents that exceed the maximum cache size.
+func (r *Repository) evictCache() {
+	if !r.isCacheEnabled() {
+		return
+	}
+
+	currentInfoHash := ""
+	if r.client.currentTorrent.IsPresent() {
+		currentInfoHash = r.client.currentTorrent.MustGet().InfoHash().HexString()
+	}
+
+	downloadDir := r.GetDownloadDir()
+	evictions := r.cache.selectEvictions(getFolderSizes(downloadDir), r.getMaxCacheSize(), currentInfoHash)
+
+	for _, infoHash := range evictions {
+		// Drop the torrent if it's still seeding
+		if r.client.torrentClient.IsPresent() {
+			for _, t := range r.client.torrentClient.MustGet().Torrents() {
+				if t.InfoHash().HexString() == infoHash {
+					t.Drop()
+				}
+			}
+		}
+		_ = os.RemoveAll(filepath.Join(downloadDir, infoHash))
+		r.cache.remove(infoHash)
+		r.logger.Debug().Str("infoHash", infoHash).Msg("torrentstream: Evicted torrent from cache")
+	}
+}
+
+// GetCacheStatus returns the torrents stored in the download directory.
+func (r *Repository) GetCacheStatus() *CacheStatus {
+	ret := &CacheStatus{
+		Enabled: r.isCacheEnabled(),
+		MaxSize: r.getMaxCacheSize(),
+		Entries: make([]*CacheEntryStatus, 0),
+	}
+
+	currentInfoHash := ""
+	if r.client.currentTorrent.IsPresent() {
+		currentInfoHash = r.client.currentTorrent.MustGet().InfoHash().HexString()
+	}
+
+	for infoHash, size := range getFolderSizes(r.GetDownloadDir()) {
+		ret.TotalSize += size
+
+		entry, ok := r.cache.get(infoHash)
+		if !ok {
+			entry = CacheEntry{InfoHash: infoHash}
+		}
+		status := &CacheEntryStatus{
+			CacheEntry: &entry,
+			Size:       size,
+			IsCurrent:  infoHash == currentInfoHash,
+		}
+		if t, ok := r.client.findTorrentByInfoHash(infoHash); ok {
+			status.IsSeeding = t.Seeding()
+			status.Ratio = getSeedRatio(t)
+		}
+		ret.Entries = append(ret.Entries, status)
+	}
+
+	sort.Slice(ret.Entries, func(i, j int) bool {
+		return ret.Entries[i].LastUsedAt.After(ret.Entries[j].LastUsedAt)
+	})
+
+	return ret
+}
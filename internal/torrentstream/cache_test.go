@@ -0,0 +1,111 @@
+package torrentstream
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"seanime/internal/database/models"
+	"testing"
+	"time"
+)
+
+func TestCache_SelectEvictions(t *testing.T) {
+	now := time.Now()
+
+	c := newCache()
+	c.entries = map[string]*CacheEntry{
+		"recent":  {InfoHash: "recent", Completed: true, LastUsedAt: now},
+		"old":     {InfoHash: "old", Completed: true, LastUsedAt: now.Add(-48 * time.Hour)},
+		"older":   {InfoHash: "older", Completed: true, LastUsedAt: now.Add(-72 * time.Hour)},
+		"current": {InfoHash: "current", Completed: false, LastUsedAt: now.Add(-96 * time.Hour)},
+	}
+
+	sizes := map[string]int64{
+		"recent":     100,
+		"old":        100,
+		"older":      100,
+		"current":    100,
+		"incomplete": 100, // Not in the index
+	}
+
+	// Under the limit
+	assert.Empty(t, c.selectEvictions(sizes, 500, "current"))
+
+	// Incomplete torrents are evicted first, then the least recently used
+	assert.Equal(t, []string{"incomplete", "older"}, c.selectEvictions(sizes, 300, "current"))
+
+	// The current torrent is never evicted
+	assert.Equal(t, []string{"incomplete", "older", "old", "recent"}, c.selectEvictions(sizes, 0, "current"))
+}
+
+func TestCache_Load(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "kept"), os.ModePerm))
+
+	c := newCache()
+	c.load(dir)
+	c.update("kept", func(e *CacheEntry) {
+		e.Completed = true
+	})
+	c.update("deleted", func(e *CacheEntry) {
+		e.Completed = true
+	})
+
+	// Entries whose folder was deleted are ignored
+	c2 := newCache()
+	c2.load(dir)
+	assert.True(t, c2.isKept("kept"))
+	assert.False(t, c2.isKept("deleted"))
+}
+
+func TestIsSeedingLimitReached(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		ratioLimit  float64
+		timeLimit   int
+		ratio       float64
+		completedAt time.Time
+		expected    bool
+	}{
+		{name: "No limit", ratio: 10, completedAt: now.Add(-24 * time.Hour), expected: false},
+		{name: "Ratio reached", ratioLimit: 1.5, ratio: 1.5, expected: true},
+		{name: "Ratio not reached", ratioLimit: 1.5, ratio: 1.2, expected: false},
+		{name: "Time reached", timeLimit: 60, completedAt: now.Add(-61 * time.Minute), expected: true},
+		{name: "Time not reached", timeLimit: 60, completedAt: now.Add(-10 * time.Minute), expected: false},
+		{name: "Not completed", timeLimit: 60, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := Settings{TorrentstreamSettings: models.TorrentstreamSettings{
+				SeedRatioLimit: tt.ratioLimit,
+				SeedTimeLimit:  tt.timeLimit,
+			}}
+			assert.Equal(t, tt.expected, isSeedingLimitReached(settings, tt.ratio, tt.completedAt, now))
+		})
+	}
+}
+
+func TestLinkOrCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "torrent", "episode.mkv")
+	require.NoError(t, os.MkdirAll(filepath.Dir(src), os.ModePerm))
+	require.NoError(t, os.WriteFile(src, []byte("episode"), 0644))
+
+	dest := filepath.Join(dir, "library", "Sousou no Frieren", "episode.mkv")
+	require.NoError(t, linkOrCopyFile(src, dest))
+
+	content, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, "episode", string(content))
+
+	// The file is still available after the torrent is evicted
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "torrent")))
+	assert.FileExists(t, dest)
+
+	// Existing files are not replaced
+	assert.Error(t, linkOrCopyFile(src, dest))
+}
@@ -15,6 +15,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"seanime/internal/auth"
 	"seanime/internal/mediaplayers/mediaplayer"
 	"strings"
 	"time"
@@ -34,6 +35,7 @@ type (
 		stopCh                      chan struct{}                    // Closed when the media player stops
 		mediaPlayerPlaybackStatusCh chan *mediaplayer.PlaybackStatus // Continuously receives playback status
 		timeSinceLoggedSeeding      time.Time
+		timeSinceManagedTorrents    time.Time
 	}
 
 	TorrentStatus struct {
@@ -101,6 +103,7 @@ func (c *Client) initializeClient() error {
 		settings.TorrentClientPort = 43213
 	}
 	cfg.ListenPort = settings.TorrentClientPort
+	cfg.UploadRateLimiter = newUploadRateLimiter(settings.UploadRateLimit)
 	// Set the download directory
 	// e.g. /path/to/temp/seanime/torrentstream/{infohash}
 	cfg.DefaultStorage = storage.NewFileByInfoHash(settings.DownloadDir)
@@ -114,6 +117,8 @@ func (c *Client) initializeClient() error {
 	}
 	c.repository.logger.Info().Msgf("torrentstream: Initialized torrent client on port %d", settings.TorrentClientPort)
 	c.torrentClient = mo.Some(client)
+	// Load the cached torrents before dropping the others
+	c.repository.cache.load(settings.DownloadDir)
 	c.dropTorrents()
 	c.mu.Unlock()
 
@@ -146,6 +151,7 @@ func (c *Client) initializeClient() error {
 			//	c.mu.Unlock()
 
 			case status := <-c.mediaPlayerPlaybackStatusCh:
+				c.repository.onPlaybackStatus(status)
 				// DEVNOTE: When this is received, "default" case is executed right after
 				if status != nil && c.currentFile.IsPresent() && c.repository.playback.currentVideoDuration == 0 {
 					// If the stored video duration is 0 but the media player status shows a duration that is not 0
@@ -202,6 +208,10 @@ func (c *Client) initializeClient() error {
 						Seeders:            t.Stats().ConnectedSeeders,
 					}
 					c.repository.wsEventManager.SendEvent(eventTorrentStatus, c.currentTorrentStatus)
+					// Prefetch the next episode once the current one is downloaded, since the bandwidth is available
+					if c.currentTorrentStatus.ProgressPercentage >= 100 {
+						go c.repository.prefetchNextEpisode()
+					}
 					// Always log the progress so the user knows what's happening
 					c.repository.logger.Trace().Msgf("torrentstream: Progress: %.2f%%, Download speed: %s, Upload speed: %s, Size: %s",
 						c.currentTorrentStatus.ProgressPercentage,
@@ -221,6 +231,11 @@ func (c *Client) initializeClient() error {
 						}
 					}
 				}
+				// Enforce the seeding policy and the cache size
+				if time.Since(c.timeSinceManagedTorrents) > 30*time.Second {
+					c.timeSinceManagedTorrents = time.Now()
+					c.repository.manageTorrents()
+				}
 				time.Sleep(3 * time.Second)
 			}
 		}
@@ -238,21 +253,35 @@ func (c *Client) GetStreamingUrl() string {
 		return ""
 	}
 
+	// External media players cannot send cookies, the stream token is passed in the URL
+	query := ""
+	if c.repository.authManager != nil && c.repository.authManager.IsEnabled() {
+		query = "?" + auth.TokenQueryParam + "=" + c.repository.authManager.StreamToken()
+	}
+
 	settings := c.repository.settings.MustGet()
 	if settings.StreamingServerHost == "0.0.0.0" {
-		return fmt.Sprintf("http://127.0.0.1:%d/stream/%s", settings.StreamingServerPort, url.PathEscape(c.currentFile.MustGet().DisplayPath()))
+		return fmt.Sprintf("http://127.0.0.1:%d/stream/%s%s", settings.StreamingServerPort, url.PathEscape(c.currentFile.MustGet().DisplayPath()), query)
 	}
-	return fmt.Sprintf("http://%s:%d/stream/%s", settings.StreamingServerHost, settings.StreamingServerPort, url.PathEscape(c.currentFile.MustGet().DisplayPath()))
+	return fmt.Sprintf("http://%s:%d/stream/%s%s", settings.StreamingServerHost, settings.StreamingServerPort, url.PathEscape(c.currentFile.MustGet().DisplayPath()), query)
 }
 
 func (c *Client) AddTorrent(id string) (*torrent.Torrent, error) {
+	return c.addTorrent(id, true)
+}
+
+// addTorrent adds the torrent, dropping the other torrents if dropOthers is true.
+// The other torrents are kept when the next episode is prefetched.
+func (c *Client) addTorrent(id string, dropOthers bool) (*torrent.Torrent, error) {
 	if c.torrentClient.IsAbsent() {
 		return nil, errors.New("torrent client is not initialized")
 	}
 
 	// Drop all torrents
-	for _, t := range c.torrentClient.MustGet().Torrents() {
-		t.Drop()
+	if dropOthers {
+		for _, t := range c.torrentClient.MustGet().Torrents() {
+			t.Drop()
+		}
 	}
 
 	if strings.HasPrefix(id, "magnet") {
@@ -394,6 +423,14 @@ func (c *Client) RemoveTorrent(infoHash string) error {
 	return fmt.Errorf("no torrent found")
 }
 
+// isCurrentTorrent returns true if the info hash is the one of the torrent being streamed.
+func (c *Client) isCurrentTorrent(infoHash string) bool {
+	if c.currentTorrent.IsAbsent() || infoHash == "" {
+		return false
+	}
+	return strings.EqualFold(c.currentTorrent.MustGet().InfoHash().HexString(), infoHash)
+}
+
 func (c *Client) dropTorrents() {
 	if c.torrentClient.IsAbsent() {
 		return
@@ -405,12 +442,17 @@ func (c *Client) dropTorrents() {
 	}
 
 	if c.repository.settings.IsPresent() {
-		// Delete all torrents
+		// Delete all torrents, except the completed ones if the cache is enabled
+		cacheEnabled := c.repository.isCacheEnabled()
 		fe, err := os.ReadDir(c.repository.settings.MustGet().DownloadDir)
 		if err == nil {
 			for _, f := range fe {
 				if f.IsDir() {
+					if cacheEnabled && c.repository.cache.isKept(f.Name()) {
+						continue
+					}
 					_ = os.RemoveAll(path.Join(c.repository.settings.MustGet().DownloadDir, f.Name()))
+					c.repository.cache.remove(f.Name())
 				}
 			}
 		}
@@ -26,11 +26,21 @@ type (
 	}
 )
 
-func (r *Repository) findBestTorrent(media *anilist.CompleteAnime, aniDbEpisode string, episodeNumber int) (ret *playbackTorrent, err error) {
+// findBestTorrent searches the torrents for the episode, adds the best one and selects the episode's file.
+//
+// If prefetch is true, the torrent is added in the background for the next episode:
+// the other torrents are not dropped, no loading status is sent and only the first pieces of the file are prioritized.
+func (r *Repository) findBestTorrent(media *anilist.CompleteAnime, aniDbEpisode string, episodeNumber int, prefetch bool) (ret *playbackTorrent, err error) {
 	defer util.HandlePanicInModuleWithError("torrentstream/findBestTorrent", &err)
 
 	r.logger.Debug().Msgf("torrentstream: Finding best torrent for %s, Episode %d", media.GetTitleSafe(), episodeNumber)
 
+	sendLoadingStatus := func(state TorrentLoadingStatusState, checking string) {
+		if !prefetch {
+			r.sendTorrentLoadingStatus(state, checking)
+		}
+	}
+
 	providerId := itorrent.ProviderAnimeTosho // todo: get provider from settings
 
 	// Get AnimeTosho provider extension
@@ -50,7 +60,7 @@ func (r *Repository) findBestTorrent(media *anilist.CompleteAnime, aniDbEpisode
 		searchBatch = true
 	}
 
-	r.sendTorrentLoadingStatus(TLSStateSearchingTorrents, "")
+	sendLoadingStatus(TLSStateSearchingTorrents, "")
 
 	var data *itorrent.SearchData
 searchLoop:
@@ -121,7 +131,7 @@ searchLoop:
 		if tries >= 2 {
 			break
 		}
-		r.sendTorrentLoadingStatus(TLSStateAddingTorrent, searchT.Name)
+		sendLoadingStatus(TLSStateAddingTorrent, searchT.Name)
 		r.logger.Trace().Msgf("torrentstream: Getting torrent magnet")
 		magnet, err := providerExtension.GetProvider().GetTorrentMagnetLink(searchT)
 		if err != nil {
@@ -131,17 +141,26 @@ searchLoop:
 		}
 		r.logger.Debug().Msgf("torrentstream: Adding torrent %s from magnet", searchT.Link)
 
-		t, err := r.client.AddTorrent(magnet)
+		// The current torrent was already checked for the next episode
+		if prefetch && r.client.isCurrentTorrent(searchT.InfoHash) {
+			continue
+		}
+
+		t, err := r.client.addTorrent(magnet, !prefetch)
 		if err != nil {
 			r.logger.Warn().Err(err).Msgf("torrentstream: Error adding torrent %s", searchT.Link)
 			tries++
 			continue
 		}
 
-		r.sendTorrentLoadingStatus(TLSStateCheckingTorrent, searchT.Name)
+		sendLoadingStatus(TLSStateCheckingTorrent, searchT.Name)
 
 		// If the torrent has only one file, return it
 		if len(t.Files()) == 1 {
+			if prefetch {
+				prioritizeFirstPieces(t, t.Files()[0], torrent.PiecePriorityHigh)
+				return &playbackTorrent{Torrent: t, File: t.Files()[0]}, nil
+			}
 			t.DownloadAll()
 			firstPieceIdx := t.Files()[0].Offset() * int64(t.NumPieces()) / t.Length()
 			endPieceIdx := (t.Files()[0].Offset() + t.Length()) * int64(t.NumPieces()) / t.Length()
@@ -156,7 +175,7 @@ searchLoop:
 			}, nil
 		}
 
-		r.sendTorrentLoadingStatus(TLSStateSelectingFile, searchT.Name)
+		sendLoadingStatus(TLSStateSelectingFile, searchT.Name)
 
 		// DEVNOTE: The gap between adding the torrent and file analysis causes some pieces to be downloaded
 		// We currently can't Pause/Resume torrents so :shrug:
@@ -214,6 +233,12 @@ searchLoop:
 			}
 		}
 		tFile := t.Files()[analysisFile.GetIndex()]
+		if prefetch {
+			prioritizeFirstPieces(t, tFile, torrent.PiecePriorityHigh)
+			selectedTorrent = t
+			selectedFile = tFile
+			break
+		}
 		// Select the first 5% of the pieces
 		firstPieceIdx := tFile.Offset() * int64(t.NumPieces()) / t.Length()
 		endPieceIdx := (tFile.Offset() + tFile.Length()) * int64(t.NumPieces()) / t.Length()
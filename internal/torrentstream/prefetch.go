@@ -0,0 +1,226 @@
+package torrentstream
+
+import (
+	"github.com/anacrolix/torrent"
+	"github.com/samber/lo"
+	"seanime/internal/api/anilist"
+	"seanime/internal/mediaplayers/mediaplayer"
+	torrentanalyzer "seanime/internal/torrents/analyzer"
+	"seanime/internal/util"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Next episode prefetch
+//
+// Once the current episode passes a threshold, the next episode is resolved in the background,
+// either from the same batch torrent or by searching for the best torrent, and its first pieces are prioritized.
+// When the user starts the next episode, the prefetched torrent is used instead of searching again.
+
+const (
+	// prefetchPlaybackThreshold is the playback completion after which the next episode is prefetched
+	prefetchPlaybackThreshold = 0.5
+	// prefetchPieces is the percentage of the file's pieces that are prioritized
+	prefetchPieces = 5
+)
+
+type (
+	prefetcher struct {
+		mu sync.Mutex
+		// Current stream
+		media         *anilist.CompleteAnime
+		episodeNumber int
+		triggered     bool // The next episode was already prefetched for the current stream
+		next          *prefetchedEpisode
+	}
+
+	prefetchedEpisode struct {
+		mediaId       int
+		episodeNumber int
+		torrent       *torrent.Torrent
+		file          *torrent.File
+	}
+)
+
+func newPrefetcher() *prefetcher {
+	return &prefetcher{}
+}
+
+// matches returns true if the prefetched episode can be used for the stream.
+// A manually selected torrent is only used if it is the prefetched torrent and no file was selected.
+func (p *prefetchedEpisode) matches(opts *StartStreamOptions) bool {
+	if p.mediaId != opts.MediaId || p.episodeNumber != opts.EpisodeNumber {
+		return false
+	}
+	if opts.AutoSelect {
+		return true
+	}
+	return opts.Torrent != nil && opts.FileIndex == nil && strings.EqualFold(opts.Torrent.InfoHash, p.torrent.InfoHash().HexString())
+}
+
+// setCurrentStream resets the prefetcher when a stream starts.
+func (r *Repository) setCurrentStream(media *anilist.CompleteAnime, episodeNumber int) {
+	r.prefetcher.mu.Lock()
+	defer r.prefetcher.mu.Unlock()
+
+	r.prefetcher.media = media
+	r.prefetcher.episodeNumber = episodeNumber
+	r.prefetcher.triggered = false
+	r.prefetcher.next = nil
+}
+
+// usePrefetchedEpisode returns the prefetched episode if it matches the stream and starts downloading it.
+func (r *Repository) usePrefetchedEpisode(opts *StartStreamOptions) (*playbackTorrent, bool) {
+	r.prefetcher.mu.Lock()
+	next := r.prefetcher.next
+	r.prefetcher.next = nil
+	r.prefetcher.mu.Unlock()
+
+	if next == nil || !next.matches(opts) {
+		return nil, false
+	}
+
+	// The torrent might have been dropped when the previous stream was stopped
+	t, found := r.client.findTorrentByInfoHash(next.torrent.InfoHash().HexString())
+	if !found {
+		return nil, false
+	}
+
+	// Drop the torrent of the previous episode if it's not the same
+	for _, other := range r.client.torrentClient.MustGet().Torrents() {
+		if other != t {
+			other.Drop()
+		}
+	}
+
+	// Download the file and unselect the rest
+	for _, f := range t.Files() {
+		if f != next.file {
+			f.SetPriority(torrent.PiecePriorityNone)
+		}
+	}
+	next.file.Download()
+	prioritizeFirstPieces(t, next.file, torrent.PiecePriorityNow)
+
+	r.logger.Debug().Str("name", next.file.DisplayPath()).Msg("torrentstream: Using prefetched episode")
+
+	return &playbackTorrent{
+		Torrent: t,
+		File:    next.file,
+	}, true
+}
+
+// onPlaybackStatus prefetches the next episode once the current episode passes the threshold.
+func (r *Repository) onPlaybackStatus(status *mediaplayer.PlaybackStatus) {
+	if status == nil || status.CompletionPercentage < prefetchPlaybackThreshold {
+		return
+	}
+	go r.prefetchNextEpisode()
+}
+
+// prefetchNextEpisode resolves the next episode and prioritizes its first pieces.
+// It only runs once per stream.
+func (r *Repository) prefetchNextEpisode() {
+	defer util.HandlePanicInModuleThen("torrentstream/prefetchNextEpisode", func() {})
+
+	r.prefetcher.mu.Lock()
+	if r.prefetcher.triggered || r.prefetcher.media == nil {
+		r.prefetcher.mu.Unlock()
+		return
+	}
+	r.prefetcher.triggered = true
+	media := r.prefetcher.media
+	episodeNumber := r.prefetcher.episodeNumber
+	r.prefetcher.mu.Unlock()
+
+	if r.client.currentTorrent.IsAbsent() || media.IsMovie() {
+		return
+	}
+
+	nextEpisodeNumber := episodeNumber + 1
+	if count := media.GetCurrentEpisodeCount(); count > 0 && nextEpisodeNumber > count {
+		return
+	}
+	nextAniDbEpisode := strconv.Itoa(nextEpisodeNumber)
+
+	r.logger.Debug().Int("episode", nextEpisodeNumber).Msg("torrentstream: Prefetching next episode")
+
+	// Look for the next episode in the current torrent first
+	next, found := r.findEpisodeInTorrent(r.client.currentTorrent.MustGet(), media, nextAniDbEpisode)
+	if !found {
+		ret, err := r.findBestTorrent(media, nextAniDbEpisode, nextEpisodeNumber, true)
+		if err != nil {
+			r.logger.Warn().Err(err).Int("episode", nextEpisodeNumber).Msg("torrentstream: Could not prefetch next episode")
+			return
+		}
+		next = ret
+	}
+
+	r.prefetcher.mu.Lock()
+	defer r.prefetcher.mu.Unlock()
+
+	// Another stream started in the meantime
+	if r.prefetcher.media != media || r.prefetcher.episodeNumber != episodeNumber {
+		return
+	}
+
+	r.prefetcher.next = &prefetchedEpisode{
+		mediaId:       media.GetID(),
+		episodeNumber: nextEpisodeNumber,
+		torrent:       next.Torrent,
+		file:          next.File,
+	}
+
+	r.logger.Info().Str("name", next.File.DisplayPath()).Msg("torrentstream: Prefetched next episode")
+}
+
+// findEpisodeInTorrent analyzes the files of a batch torrent and prioritizes the first pieces of the episode's file.
+func (r *Repository) findEpisodeInTorrent(t *torrent.Torrent, media *anilist.CompleteAnime, aniDbEpisode string) (*playbackTorrent, bool) {
+	if t.Info() == nil || len(t.Files()) <= 1 {
+		return nil, false
+	}
+
+	analyzer := torrentanalyzer.NewAnalyzer(&torrentanalyzer.NewAnalyzerOptions{
+		Logger: r.logger,
+		Filepaths: lo.Map(t.Files(), func(f *torrent.File, _ int) string {
+			return f.DisplayPath()
+		}),
+		Media:            media,
+		Platform:         r.platform,
+		MetadataProvider: r.metadataProvider,
+	})
+
+	analysis, err := analyzer.AnalyzeTorrentFiles()
+	if err != nil {
+		return nil, false
+	}
+
+	analysisFile, found := analysis.GetFileByAniDBEpisode(aniDbEpisode)
+	if !found {
+		return nil, false
+	}
+
+	f := t.Files()[analysisFile.GetIndex()]
+	prioritizeFirstPieces(t, f, torrent.PiecePriorityHigh)
+
+	return &playbackTorrent{
+		Torrent: t,
+		File:    f,
+	}, true
+}
+
+// prioritizeFirstPieces sets the priority of the first pieces of the file so that playback can start.
+func prioritizeFirstPieces(t *torrent.Torrent, f *torrent.File, priority torrent.PiecePriority) {
+	if t.Info() == nil || f.Length() == 0 {
+		return
+	}
+
+	firstPieceIdx := f.BeginPieceIndex()
+	endPieceIdx := f.EndPieceIndex()
+	count := max((endPieceIdx-firstPieceIdx)*prefetchPieces/100, 1)
+
+	for idx := firstPieceIdx; idx < firstPieceIdx+count && idx < endPieceIdx; idx++ {
+		t.Piece(idx).SetPriority(priority)
+	}
+}
@@ -0,0 +1,52 @@
+package torrentstream
+
+import (
+	hibiketorrent "github.com/5rahim/hibike/pkg/extension/torrent"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrefetchedEpisode_Matches(t *testing.T) {
+	next := &prefetchedEpisode{
+		mediaId:       154587,
+		episodeNumber: 2,
+	}
+
+	tests := []struct {
+		name     string
+		opts     *StartStreamOptions
+		expected bool
+	}{
+		{
+			name:     "Auto select",
+			opts:     &StartStreamOptions{MediaId: 154587, EpisodeNumber: 2, AutoSelect: true},
+			expected: true,
+		},
+		{
+			name:     "Other episode",
+			opts:     &StartStreamOptions{MediaId: 154587, EpisodeNumber: 3, AutoSelect: true},
+			expected: false,
+		},
+		{
+			name:     "Other media",
+			opts:     &StartStreamOptions{MediaId: 1, EpisodeNumber: 2, AutoSelect: true},
+			expected: false,
+		},
+		{
+			name:     "Manual selection with file index",
+			opts:     &StartStreamOptions{MediaId: 154587, EpisodeNumber: 2, Torrent: &hibiketorrent.AnimeTorrent{}, FileIndex: new(int)},
+			expected: false,
+		},
+		{
+			name:     "Manual selection without torrent",
+			opts:     &StartStreamOptions{MediaId: 154587, EpisodeNumber: 2},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, next.matches(tt.opts))
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"seanime/internal/api/anilist"
 	"seanime/internal/api/metadata"
+	"seanime/internal/auth"
 	"seanime/internal/database/db"
 	"seanime/internal/database/models"
 	"seanime/internal/events"
@@ -29,6 +30,9 @@ type (
 		currentEpisodeCollection mo.Option[*EpisodeCollection] // Refreshed in [list.go] when the user opens the streaming page for a media
 
 		selectionHistoryMap *result.Map[int, *hibiketorrent.AnimeTorrent] // Key: AniList media ID
+		cache               *cache                                        // Torrents stored in the download directory
+		prefetcher          *prefetcher                                   // Next episode of the current stream
+		libraryScanner      LibraryScanner                                // Set by [SetLibraryScanner], used to scan promoted episodes
 
 		// Injected dependencies
 		torrentRepository               *torrent.Repository
@@ -42,6 +46,7 @@ type (
 		mediaPlayerRepositorySubscriber *mediaplayer.RepositorySubscriber
 		logger                          *zerolog.Logger
 		db                              *db.Database
+		authManager                     *auth.Manager
 	}
 
 	Settings struct {
@@ -58,6 +63,7 @@ type (
 		PlaybackManager    *playbackmanager.PlaybackManager
 		WSEventManager     events.WSEventManagerInterface
 		Database           *db.Database
+		AuthManager        *auth.Manager // Optional
 	}
 )
 
@@ -69,6 +75,8 @@ func NewRepository(opts *NewRepositoryOptions) *Repository {
 		settings:                        mo.Option[Settings]{},
 		currentEpisodeCollection:        mo.Option[*EpisodeCollection]{},
 		selectionHistoryMap:             result.NewResultMap[int, *hibiketorrent.AnimeTorrent](),
+		cache:                           newCache(),
+		prefetcher:                      newPrefetcher(),
 		torrentRepository:               opts.TorrentRepository,
 		baseAnimeCache:                  opts.BaseAnimeCache,
 		completeAnimeCache:              opts.CompleteAnimeCache,
@@ -80,6 +88,7 @@ func NewRepository(opts *NewRepositoryOptions) *Repository {
 		mediaPlayerRepositorySubscriber: nil,
 		logger:                          opts.Logger,
 		db:                              opts.Database,
+		authManager:                     opts.AuthManager,
 	}
 	ret.client = NewClient(ret)
 	ret.serverManager = newServerManager(ret)
@@ -0,0 +1,223 @@
+package torrentstream
+
+import (
+	"errors"
+	"fmt"
+	"github.com/anacrolix/torrent"
+	"golang.org/x/time/rate"
+	"io"
+	"os"
+	"path/filepath"
+	"seanime/internal/util"
+	"time"
+)
+
+// LibraryScanner scans the episodes promoted to the library.
+type LibraryScanner interface {
+	ScanPaths(paths []string)
+}
+
+// SetLibraryScanner sets the scanner used once a streamed episode is promoted to the library.
+func (r *Repository) SetLibraryScanner(scanner LibraryScanner) {
+	r.libraryScanner = scanner
+}
+
+// newUploadRateLimiter returns a limiter for the given rate in KB/s, 0 means no limit.
+func newUploadRateLimiter(kbps int) *rate.Limiter {
+	if kbps <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	limit := kbps * 1024
+	// The burst should be at least the size of a chunk, otherwise uploads are blocked
+	return rate.NewLimiter(rate.Limit(limit), max(limit, 256*1024))
+}
+
+// getSeedRatio returns the ratio of uploaded bytes to downloaded bytes.
+func getSeedRatio(t *torrent.Torrent) float64 {
+	if t.Info() == nil || t.BytesCompleted() == 0 {
+		return 0
+	}
+	stats := t.Stats()
+	return float64(stats.BytesWrittenData.Int64()) / float64(t.BytesCompleted())
+}
+
+// isSeedingLimitReached returns true if the torrent has reached the seeding ratio or time limit.
+func isSeedingLimitReached(settings Settings, ratio float64, completedAt time.Time, now time.Time) bool {
+	if settings.SeedRatioLimit > 0 && ratio >= settings.SeedRatioLimit {
+		return true
+	}
+	if settings.SeedTimeLimit > 0 && !completedAt.IsZero() && now.Sub(completedAt) >= time.Duration(settings.SeedTimeLimit)*time.Minute {
+		return true
+	}
+	return false
+}
+
+func (c *Client) findTorrentByInfoHash(infoHash string) (*torrent.Torrent, bool) {
+	if c.torrentClient.IsAbsent() {
+		return nil, false
+	}
+	for _, t := range c.torrentClient.MustGet().Torrents() {
+		if t.InfoHash().HexString() == infoHash {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// trackStreamedTorrent records the torrent in the cache when a stream starts.
+func (r *Repository) trackStreamedTorrent(t *torrent.Torrent, f *torrent.File, mediaId int, mediaTitle string, episodeNumber int) {
+	r.cache.update(t.InfoHash().HexString(), func(e *CacheEntry) {
+		// Another file of a batch is streamed
+		if e.FilePath != f.Path() {
+			e.Completed = false
+			e.CompletedAt = time.Time{}
+			e.PromotedPath = ""
+		}
+		e.Name = t.Name()
+		e.MediaId = mediaId
+		e.MediaTitle = mediaTitle
+		e.EpisodeNumber = episodeNumber
+		e.FilePath = f.Path()
+		e.LastUsedAt = time.Now()
+		e.SeedingStopped = false
+	})
+}
+
+// manageTorrents is called periodically by the client.
+// It marks the streamed file as completed, promotes it to the library, enforces the seeding policy and evicts the cache.
+func (r *Repository) manageTorrents() {
+	defer util.HandlePanicInModuleThen("torrentstream/manageTorrents", func() {})
+
+	if r.settings.IsAbsent() || r.client.torrentClient.IsAbsent() {
+		return
+	}
+	settings := r.settings.MustGet()
+
+	// Check if the streamed files are fully downloaded, the torrent keeps downloading after the stream is stopped
+	for _, t := range r.client.torrentClient.MustGet().Torrents() {
+		if t.Info() == nil {
+			continue
+		}
+		infoHash := t.InfoHash().HexString()
+		entry, ok := r.cache.get(infoHash)
+		if !ok || entry.Completed {
+			continue
+		}
+		for _, f := range t.Files() {
+			if f.Path() != entry.FilePath || f.Length() == 0 || f.BytesCompleted() != f.Length() {
+				continue
+			}
+			r.logger.Debug().Str("name", f.DisplayPath()).Msg("torrentstream: Streamed file is fully downloaded")
+			r.cache.update(infoHash, func(e *CacheEntry) {
+				e.Completed = true
+				e.CompletedAt = time.Now()
+			})
+			if settings.AddToLibrary {
+				go r.promoteToLibrary(infoHash)
+			}
+		}
+	}
+
+	// Enforce the seeding policy
+	now := time.Now()
+	for _, t := range r.client.torrentClient.MustGet().Torrents() {
+		if t.Info() == nil || !t.Seeding() {
+			continue
+		}
+		infoHash := t.InfoHash().HexString()
+		entry, _ := r.cache.get(infoHash)
+		if entry.SeedingStopped {
+			continue
+		}
+		if isSeedingLimitReached(settings, getSeedRatio(t), entry.CompletedAt, now) {
+			t.DisallowDataUpload()
+			r.cache.update(infoHash, func(e *CacheEntry) {
+				e.SeedingStopped = true
+			})
+			r.logger.Info().Str("name", t.Name()).Msg("torrentstream: Seeding limit reached, stopped seeding")
+		}
+	}
+
+	r.evictCache()
+}
+
+// promoteToLibrary copies the streamed file to the library and scans it.
+// The file is hard-linked when possible so that it does not use additional space.
+func (r *Repository) promoteToLibrary(infoHash string) {
+	defer util.HandlePanicInModuleThen("torrentstream/promoteToLibrary", func() {})
+
+	entry, ok := r.cache.get(infoHash)
+	if !ok || !entry.Completed || entry.PromotedPath != "" {
+		return
+	}
+
+	libraryPath, err := r.db.GetLibraryPathFromSettings()
+	if err != nil || libraryPath == "" {
+		r.logger.Warn().Msg("torrentstream: Cannot add episode to the library, no library path set")
+		return
+	}
+
+	src := filepath.Join(r.GetDownloadDir(), infoHash, filepath.FromSlash(entry.FilePath))
+	dest := filepath.Join(libraryPath, util.SanitizeFilename(entry.MediaTitle), filepath.Base(filepath.FromSlash(entry.FilePath)))
+
+	if err := linkOrCopyFile(src, dest); err != nil {
+		r.logger.Error().Err(err).Str("name", entry.Name).Msg("torrentstream: Failed to add episode to the library")
+		return
+	}
+
+	r.cache.update(infoHash, func(e *CacheEntry) {
+		e.PromotedPath = dest
+	})
+	r.logger.Info().Str("path", dest).Msg("torrentstream: Added episode to the library")
+
+	if r.libraryScanner != nil {
+		r.libraryScanner.ScanPaths([]string{dest})
+	}
+}
+
+// linkOrCopyFile hard-links the file to the destination, or copies it if linking is not possible (e.g. different drives).
+func linkOrCopyFile(src string, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("file already exists: %s", dest)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := os.Link(src, dest); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// Copy to a temporary file so that an interrupted copy is not mistaken for a complete file
+	tmpPath := dest + ".part"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, dest); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
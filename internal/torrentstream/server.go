@@ -187,6 +187,11 @@ func (s *serverManager) serve(w http.ResponseWriter, r *http.Request) {
 	s.lastUsed = time.Now()
 	s.repository.logger.Trace().Msg("torrentstream: Stream endpoint hit [server]")
 
+	if s.repository.authManager != nil && !s.repository.authManager.ValidateStreamRequest(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	if s.repository.client.currentFile.IsAbsent() || s.repository.client.currentTorrent.IsAbsent() {
 		s.repository.logger.Error().Msg("torrentstream: No torrent to stream [server]")
 		http.Error(w, "No torrent to stream", http.StatusNotFound)
@@ -58,22 +58,25 @@ func (r *Repository) StartStream(opts *StartStreamOptions) error {
 	//
 	// Find the best torrent / Select the torrent
 	//
-	var torrentToStream *playbackTorrent
-	switch opts.AutoSelect {
-	case true:
-		torrentToStream, err = r.findBestTorrent(media, aniDbEpisode, episodeNumber)
-		if err != nil {
-			r.wsEventManager.SendEvent(eventTorrentLoadingFailed, nil)
-			return err
-		}
-	case false:
-		if opts.Torrent == nil {
-			return fmt.Errorf("torrentstream: No torrent provided")
-		}
-		torrentToStream, err = r.findBestTorrentFromManualSelection(opts.Torrent, media, aniDbEpisode, opts.FileIndex)
-		if err != nil {
-			r.wsEventManager.SendEvent(eventTorrentLoadingFailed, nil)
-			return err
+	// Use the next episode if it was prefetched during the previous stream
+	torrentToStream, found := r.usePrefetchedEpisode(opts)
+	if !found {
+		switch opts.AutoSelect {
+		case true:
+			torrentToStream, err = r.findBestTorrent(media, aniDbEpisode, episodeNumber, false)
+			if err != nil {
+				r.wsEventManager.SendEvent(eventTorrentLoadingFailed, nil)
+				return err
+			}
+		case false:
+			if opts.Torrent == nil {
+				return fmt.Errorf("torrentstream: No torrent provided")
+			}
+			torrentToStream, err = r.findBestTorrentFromManualSelection(opts.Torrent, media, aniDbEpisode, opts.FileIndex)
+			if err != nil {
+				r.wsEventManager.SendEvent(eventTorrentLoadingFailed, nil)
+				return err
+			}
 		}
 	}
 
@@ -82,6 +85,8 @@ func (r *Repository) StartStream(opts *StartStreamOptions) error {
 	//
 	r.client.currentFile = mo.Some(torrentToStream.File)
 	r.client.currentTorrent = mo.Some(torrentToStream.Torrent)
+	r.trackStreamedTorrent(torrentToStream.Torrent, torrentToStream.File, opts.MediaId, media.GetRomajiTitleSafe(), episodeNumber)
+	r.setCurrentStream(media, episodeNumber)
 
 	r.sendTorrentLoadingStatus(TLSStateStartingServer, "")
 
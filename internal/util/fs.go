@@ -40,7 +40,7 @@ func IsSubdirectory(parent, child string) bool {
 	if err != nil {
 		return false
 	}
-	return rel != "." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
 }
 
 func IsSubdirectoryOfAny(dirs []string, child string) bool {
@@ -68,3 +68,19 @@ func IsSameDir(dir1, dir2 string) bool {
 	}
 	return absDir1 == absDir2
 }
+
+// SanitizeFilename replaces the characters that are not allowed in file and directory names.
+func SanitizeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '<', '>', ':', '"', '/', '\\', '|', '?', '*':
+			return '_'
+		}
+		if r < 32 {
+			return -1
+		}
+		return r
+	}, name)
+	// Windows does not allow trailing dots and spaces
+	return strings.TrimRight(strings.TrimSpace(name), ".")
+}
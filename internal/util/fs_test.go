@@ -2,6 +2,7 @@ package util
 
 import (
 	"github.com/stretchr/testify/require"
+	"path/filepath"
 	"testing"
 )
 
@@ -49,6 +50,36 @@ func TestSubdirectory(t *testing.T) {
 	}
 }
 
+// Same as TestSubdirectory with paths of the current platform.
+func TestIsSubdirectory(t *testing.T) {
+	tests := []struct {
+		name     string
+		parent   string
+		child    string
+		expected bool
+	}{
+		{name: "file", parent: "/library", child: "/library/Manga.cbz", expected: true},
+		{name: "nested", parent: "/library", child: "/library/Series/Chapter 1/01.png", expected: true},
+		{name: "trailing separator", parent: "/library/", child: "/library/Series", expected: true},
+		{name: "name starting with dots", parent: "/library", child: "/library/..Series", expected: true},
+		{name: "same directory", parent: "/library", child: "/library", expected: false},
+		{name: "same directory with trailing separator", parent: "/library", child: "/library/", expected: false},
+		{name: "parent directory", parent: "/library/Series", child: "/library", expected: false},
+		{name: "escape", parent: "/library", child: "/library/../etc/passwd", expected: false},
+		{name: "escape to parent", parent: "/library/Series", child: "/library/Series/..", expected: false},
+		{name: "sibling with same prefix", parent: "/library", child: "/library2/Manga.cbz", expected: false},
+		{name: "unrelated", parent: "/library", child: "/downloads/Manga.cbz", expected: false},
+		{name: "relative and absolute", parent: "library", child: "/library/Manga.cbz", expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := IsSubdirectory(filepath.FromSlash(test.parent), filepath.FromSlash(test.child))
+			require.Equal(t, test.expected, result)
+		})
+	}
+}
+
 func TestSameDir(t *testing.T) {
 	tests := []struct {
 		dir1     string
@@ -67,3 +98,22 @@ func TestSameDir(t *testing.T) {
 		})
 	}
 }
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "Sousou no Frieren", expected: "Sousou no Frieren"},
+		{name: "Re:Zero kara Hajimeru Isekai Seikatsu", expected: "Re_Zero kara Hajimeru Isekai Seikatsu"},
+		{name: "Fate/Zero", expected: "Fate_Zero"},
+		{name: "Why? ", expected: "Why_"},
+		{name: "Mahou Shoujo Madoka★Magica...", expected: "Mahou Shoujo Madoka★Magica"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, SanitizeFilename(test.name))
+		})
+	}
+}
@@ -2,12 +2,17 @@ package util
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"io"
 	"net/http"
+	"strings"
 )
 
-type ImageProxy struct{}
+type ImageProxy struct {
+	// GetImageFunc is used by ProxyImage to get the image instead of fetching it, e.g. to go through a cache.
+	GetImageFunc func(url string, headers map[string]string) ([]byte, error)
+}
 
 func (ip *ImageProxy) GetImage(url string, headers map[string]string) ([]byte, error) {
 	client := &http.Client{}
@@ -26,6 +31,11 @@ func (ip *ImageProxy) GetImage(url string, headers map[string]string) ([]byte, e
 	}
 	defer resp.Body.Close()
 
+	// Do not return error pages, they would be cached
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("image proxy: %s", resp.Status)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
@@ -35,7 +45,6 @@ func (ip *ImageProxy) GetImage(url string, headers map[string]string) ([]byte, e
 }
 
 func (ip *ImageProxy) setHeaders(c *fiber.Ctx) {
-	c.Set("Content-Type", "image/jpeg")
 	c.Set("Cache-Control", "public, max-age=31536000")
 	c.Set("Access-Control-Allow-Origin", "*")
 	c.Set("Access-Control-Allow-Methods", "GET")
@@ -58,10 +67,30 @@ func (ip *ImageProxy) ProxyImage(c *fiber.Ctx) error {
 	}
 
 	ip.setHeaders(c)
-	imageBuffer, err := ip.GetImage(url, headers)
+	getImage := ip.GetImage
+	if ip.GetImageFunc != nil {
+		getImage = ip.GetImageFunc
+	}
+	imageBuffer, err := getImage(url, headers)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString("Error fetching image")
 	}
 
+	// Cached images can be re-encoded
+	contentType := DetectImageContentType(imageBuffer)
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = "image/jpeg"
+	}
+	c.Set("Content-Type", contentType)
+
 	return c.Send(imageBuffer)
 }
+
+// DetectImageContentType returns the content type of the data.
+// Unlike http.DetectContentType, it recognizes AVIF images, which optimized manga pages can be encoded as.
+func DetectImageContentType(data []byte) string {
+	if len(data) >= 12 && string(data[4:12]) == "ftypavif" {
+		return "image/avif"
+	}
+	return http.DetectContentType(data)
+}
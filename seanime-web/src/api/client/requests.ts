@@ -7,6 +7,58 @@ import { toast } from "sonner"
 
 type SeaError = AxiosError<{ error: string }>
 
+//----------------------------------------------------------------------------------------------------------------------
+
+const serverAuthCheckListeners = new Set<() => void>()
+
+/**
+ * Subscribe to the requests that were rejected because the client is not authenticated to the server.
+ * The server auth gate uses it to display the login page.
+ */
+export function subscribeToServerAuthCheck(listener: () => void) {
+    serverAuthCheckListeners.add(listener)
+    return () => {
+        serverAuthCheckListeners.delete(listener)
+    }
+}
+
+/**
+ * Ask the server auth gate to check whether the client is still authenticated, e.g. when the websocket connection is closed.
+ */
+export function requestServerAuthCheck() {
+    serverAuthCheckListeners.forEach(listener => listener())
+}
+
+const profileCheckListeners = new Set<() => void>()
+
+/**
+ * Subscribe to the requests that were rejected because the client has to select its profile.
+ * The server data wrapper uses it to display the profile picker.
+ */
+export function subscribeToProfileCheck(listener: () => void) {
+    profileCheckListeners.add(listener)
+    return () => {
+        profileCheckListeners.delete(listener)
+    }
+}
+
+// A 401 status means that a server password is set and that the session is missing or expired
+// A 409 status means that the client has to select its profile, e.g. the server profile has a PIN or the profile session was revoked
+axios.interceptors.response.use(undefined, (error) => {
+    if (axios.isAxiosError(error) && error.response?.status === 401 && !error.config?.url?.endsWith("/server-auth/login")) {
+        requestServerAuthCheck()
+    }
+    if (axios.isAxiosError(error) && error.response?.status === 409) {
+        profileCheckListeners.forEach(listener => listener())
+    }
+    return Promise.reject(error)
+})
+
+// The login page or the profile picker is displayed instead of the error
+function _isUnauthorized(error: SeaError | null) {
+    return error?.response?.status === 401 || error?.response?.status === 409
+}
+
 type SeaQuery<D> = {
     endpoint: string
     method: "POST" | "GET" | "PATCH" | "DELETE" | "PUT"
@@ -54,6 +106,8 @@ export function useServerMutation<R = void, V = void>(
     }: ServerMutationProps<R, V>) {
     return useMutation<R | undefined, SeaError, V>({
         onError: error => {
+            // The login page or the profile picker is displayed instead
+            if (_isUnauthorized(error)) return
             toast.error(_handleSeaError(error.response?.data))
         },
         mutationFn: async (variables) => {
@@ -103,7 +157,7 @@ export function useServerQuery<R, V = any>(
     })
 
     useEffect(() => {
-        if (!muteError && props.isError) {
+        if (!muteError && props.isError && !_isUnauthorized(props.error)) {
             console.log("Server error", props.error)
             toast.error(_handleSeaError(props.error?.response?.data))
         }
@@ -1265,6 +1265,74 @@ export type GetPlaylistEpisodes_Variables = {
     progress: number
 }
 
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// profile
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * - Filepath: internal/handlers/profile.go
+ * - Filename: profile.go
+ * - Endpoint: /api/v1/profiles
+ * @description
+ * Route creates a new profile.
+ */
+export type CreateProfile_Variables = {
+    name: string
+    avatar: string
+    pin: string
+}
+
+/**
+ * - Filepath: internal/handlers/profile.go
+ * - Filename: profile.go
+ * - Endpoint: /api/v1/profiles
+ * @description
+ * Route updates the name, avatar or PIN of a profile.
+ */
+export type UpdateProfile_Variables = {
+    id: number
+    name?: string
+    avatar?: string
+    currentPin: string
+    pin?: string
+}
+
+/**
+ * - Filepath: internal/handlers/profile.go
+ * - Filename: profile.go
+ * - Endpoint: /api/v1/profiles
+ * @description
+ * Route deletes a profile along with its account, theme, playlists and watch history.
+ */
+export type DeleteProfile_Variables = {
+    id: number
+    pin: string
+}
+
+/**
+ * - Filepath: internal/handlers/profile.go
+ * - Filename: profile.go
+ * - Endpoint: /api/v1/profiles/select
+ * @description
+ * Route selects the profile used by the client.
+ */
+export type SelectProfile_Variables = {
+    id: number
+    pin: string
+}
+
+/**
+ * - Filepath: internal/handlers/profile.go
+ * - Filename: profile.go
+ * - Endpoint: /api/v1/profiles/server
+ * @description
+ * Route makes a profile the server profile.
+ */
+export type SetServerProfile_Variables = {
+    id: number
+    pin: string
+}
+
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 // releases
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -1305,6 +1373,57 @@ export type ScanLocalFiles_Variables = {
 // scan_summary
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// server_auth
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * - Filepath: internal/handlers/server_auth.go
+ * - Filename: server_auth.go
+ * - Endpoint: /api/v1/server-auth/login
+ * @description
+ * Route logs the client in with the server password.
+ */
+export type ServerAuthLogin_Variables = {
+    password: string
+}
+
+/**
+ * - Filepath: internal/handlers/server_auth.go
+ * - Filename: server_auth.go
+ * - Endpoint: /api/v1/server-auth/password
+ * @description
+ * Route sets, changes or removes the server password.
+ */
+export type SetServerPassword_Variables = {
+    currentPassword: string
+    newPassword: string
+}
+
+/**
+ * - Filepath: internal/handlers/server_auth.go
+ * - Filename: server_auth.go
+ * - Endpoint: /api/v1/server-auth/tokens
+ * @description
+ * Route mints a token for another device.
+ */
+export type CreateAuthToken_Variables = {
+    name: string
+    scope: string
+    expiresInDays: number
+}
+
+/**
+ * - Filepath: internal/handlers/server_auth.go
+ * - Filename: server_auth.go
+ * - Endpoint: /api/v1/server-auth/tokens
+ * @description
+ * Route revokes a token.
+ */
+export type RevokeAuthToken_Variables = {
+    id: number
+}
+
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 // settings
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -243,7 +243,8 @@ export const API_ENDPOINTS = {
          *  Route logs in the user by saving the JWT token in the database.
          *  This is called when the JWT token is obtained from AniList after logging in with redirection on the client.
          *  It also fetches the Viewer data from AniList and saves it in the database.
-         *  It creates a new handlers.Status and refreshes App modules.
+         *  The account is the one of the profile selected by the client.
+         *  It creates a new handlers.Status and refreshes App modules if the profile is the server profile.
          */
         Login: {
             key: "AUTH-login",
@@ -254,7 +255,8 @@ export const API_ENDPOINTS = {
          *  @description
          *  Route logs out the user by removing JWT token from the database.
          *  It removes JWT token and Viewer data from the database.
-         *  It creates a new handlers.Status and refreshes App modules.
+         *  The account is the one of the profile selected by the client.
+         *  It creates a new handlers.Status and refreshes App modules if the profile is the server profile.
          */
         Logout: {
             key: "AUTH-logout",
@@ -1338,6 +1340,71 @@ export const API_ENDPOINTS = {
             endpoint: "/api/v1/playlist/episodes/{id}/{progress}",
         },
     },
+    PROFILE: {
+        GetProfiles: {
+            key: "PROFILE-get-profiles",
+            methods: ["GET"],
+            endpoint: "/api/v1/profiles",
+        },
+        /**
+         *  @description
+         *  Route creates a new profile.
+         *  The profile starts without an AniList account, watch history, playlists or theme.
+         *  The scanned library is shared by all profiles.
+         */
+        CreateProfile: {
+            key: "PROFILE-create-profile",
+            methods: ["POST"],
+            endpoint: "/api/v1/profiles",
+        },
+        /**
+         *  @description
+         *  Route updates the name, avatar or PIN of a profile.
+         *  The current PIN is required to change the PIN, an empty PIN removes it.
+         */
+        UpdateProfile: {
+            key: "PROFILE-update-profile",
+            methods: ["PATCH"],
+            endpoint: "/api/v1/profiles",
+        },
+        /**
+         *  @description
+         *  Route deletes a profile along with its account, theme, playlists and watch history.
+         *  The default profile and the server profile cannot be deleted.
+         */
+        DeleteProfile: {
+            key: "PROFILE-delete-profile",
+            methods: ["DELETE"],
+            endpoint: "/api/v1/profiles",
+        },
+        /**
+         *  @description
+         *  Route selects the profile used by the client.
+         *  The PIN is required if the profile has one.
+         *  Clients are locked out for an increasing duration after too many invalid PINs, a 429 status is returned.
+         *  The profile session cookie is set, the requests of the client are then served with the account, watch history, playlists, theme and Discord settings of the profile.
+         *  The session token is also returned in the X-Seanime-Profile header for clients that do not keep cookies, they should send it back in the same header.
+         *  Other clients are not affected. A 409 status is returned to clients that have to select their profile.
+         *  The client should re-fetch the server status and its data after this.
+         */
+        SelectProfile: {
+            key: "PROFILE-select-profile",
+            methods: ["POST"],
+            endpoint: "/api/v1/profiles/select",
+        },
+        /**
+         *  @description
+         *  Route makes a profile the server profile.
+         *  The background modules (auto downloader, playback manager, sync, Discord RPC) act on behalf of the server profile.
+         *  Clients that did not select a profile use the server profile, unless it has a PIN.
+         *  The PIN is required if the profile has one.
+         */
+        SetServerProfile: {
+            key: "PROFILE-set-server-profile",
+            methods: ["POST"],
+            endpoint: "/api/v1/profiles/server",
+        },
+    },
     RELEASES: {
         /**
          *  @description
@@ -1381,6 +1448,79 @@ export const API_ENDPOINTS = {
             endpoint: "/api/v1/library/scan-summaries",
         },
     },
+    SERVER_AUTH: {
+        /**
+         *  @description
+         *  Route returns whether authentication is enabled and whether the client is authenticated.
+         *  This route is public, the client uses it to decide whether to display the login page.
+         */
+        GetServerAuthStatus: {
+            key: "SERVER-AUTH-get-server-auth-status",
+            methods: ["GET"],
+            endpoint: "/api/v1/server-auth/status",
+        },
+        /**
+         *  @description
+         *  Route logs the client in with the server password.
+         *  This creates a session and sets the session cookie.
+         *  The session token is also returned so that it can be used as a bearer token.
+         *  Clients are locked out for an increasing duration after too many failed attempts, a 429 status is returned.
+         */
+        ServerAuthLogin: {
+            key: "SERVER-AUTH-server-auth-login",
+            methods: ["POST"],
+            endpoint: "/api/v1/server-auth/login",
+        },
+        /**
+         *  @description
+         *  Route logs the client out.
+         *  This revokes the current session and clears the session cookie.
+         */
+        ServerAuthLogout: {
+            key: "SERVER-AUTH-server-auth-logout",
+            methods: ["POST"],
+            endpoint: "/api/v1/server-auth/logout",
+        },
+        /**
+         *  @description
+         *  Route sets, changes or removes the server password.
+         *  If a password is already set, the current password is required.
+         *  An empty new password disables authentication.
+         *  All sessions are revoked and websocket clients are disconnected, minted tokens are kept.
+         */
+        SetServerPassword: {
+            key: "SERVER-AUTH-set-server-password",
+            methods: ["POST"],
+            endpoint: "/api/v1/server-auth/password",
+        },
+        /**
+         *  @description
+         *  Route returns the tokens minted for other devices.
+         *  The tokens themselves are not returned, only their metadata.
+         */
+        GetAuthTokens: {
+            key: "SERVER-AUTH-get-auth-tokens",
+            methods: ["GET"],
+            endpoint: "/api/v1/server-auth/tokens",
+        },
+        /**
+         *  @description
+         *  Route mints a token for another device.
+         *  The scope is either "full" or "read", read-only tokens can only be used for GET requests to the media-serving and library routes.
+         *  If expiresInDays is 0, the token never expires.
+         *  The token is only returned once.
+         */
+        CreateAuthToken: {
+            key: "SERVER-AUTH-create-auth-token",
+            methods: ["POST"],
+            endpoint: "/api/v1/server-auth/tokens",
+        },
+        RevokeAuthToken: {
+            key: "SERVER-AUTH-revoke-auth-token",
+            methods: ["DELETE"],
+            endpoint: "/api/v1/server-auth/tokens",
+        },
+    },
     SETTINGS: {
         GetSettings: {
             key: "SETTINGS-get-settings",
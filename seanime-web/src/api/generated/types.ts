@@ -2246,6 +2246,25 @@ export type MalAuthResponse = {
     token_type: string
 }
 
+/**
+ * - Filepath: internal/handlers/profile.go
+ * - Filename: profile.go
+ * - Package: handlers
+ */
+export type Profile = {
+    hasPin: boolean
+    name: string
+    avatar: string
+    /**
+     * Server profile, see db.Database.GetProfileID
+     */
+    isActive: boolean
+    discord?: Models_DiscordSettings
+    id: number
+    createdAt?: string
+    updatedAt?: string
+}
+
 /**
  * - Filepath: internal/handlers/docs.go
  * - Filename: docs.go
@@ -2301,6 +2320,17 @@ export type RouteHandlerParam = {
     descriptions?: Array<string>
 }
 
+/**
+ * - Filepath: internal/handlers/server_auth.go
+ * - Filename: server_auth.go
+ * - Package: handlers
+ */
+export type ServerAuthStatus = {
+    enabled: boolean
+    authenticated: boolean
+    scope: string
+}
+
 /**
  * - Filepath: internal/handlers/status.go
  * - Filename: status.go
@@ -2324,6 +2354,18 @@ export type Status = {
     torrentstreamSettings?: Models_TorrentstreamSettings
     debridSettings?: Models_DebridSettings
     anilistClientId: string
+    /**
+     * Platform used to track progress, set on startup
+     */
+    trackingPlatform: string
+    /**
+     * Profile selected by the client, 0 if the client has to select a profile
+     */
+    profileId: number
+    /**
+     * Profile the background modules act on behalf of
+     */
+    serverProfileId: number
     /**
      * If true, a new screen will be displayed
      */
@@ -2447,6 +2489,10 @@ export type Manga_PageContainer = {
      * Indexed by page number
      */
     pageDimensions?: Record<number, Manga_PageDimension>
+    /**
+     * Keys of the pages served by the image route, indexed by page number
+     */
+    pageKeys?: Record<number, string>
     /**
      * TODO remove
      */
@@ -2674,6 +2720,28 @@ export type Models_Mal = {
  */
 export type Models_MangaSettings = {
     defaultMangaProvider: string
+    /**
+     * Directories of CBZ/CBR archives and image folders, each sub-directory is a series
+     */
+    localLibraryPaths: Models_LibraryPaths
+    /**
+     * ImageCacheSizeLimit is the size limit of the page image cache in MB, the default limit is used if 0
+     */
+    imageCacheSizeLimit: number
+    /**
+     * OptimizeImages downscales pages wider than ImageMaxWidth and re-encodes them with ImageQuality
+     */
+    optimizeImages: boolean
+    imageMaxWidth: number
+    imageQuality: number
+    /**
+     * "jpeg" (default), "webp" or "avif", WebP and AVIF are encoded with FFmpeg
+     */
+    imageFormat: string
+    /**
+     * Split webtoon strips into tiles when downloading
+     */
+    splitTallImages: boolean
 }
 
 /**
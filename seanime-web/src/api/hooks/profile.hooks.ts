@@ -0,0 +1,65 @@
+import { useServerMutation, useServerQuery } from "@/api/client/requests"
+import { CreateProfile_Variables, SelectProfile_Variables, SetServerProfile_Variables } from "@/api/generated/endpoint.types"
+import { API_ENDPOINTS } from "@/api/generated/endpoints"
+import { Profile, Status } from "@/api/generated/types"
+import { useQueryClient } from "@tanstack/react-query"
+import { toast } from "sonner"
+
+export function useGetProfiles(enabled: boolean = true) {
+    return useServerQuery<Array<Profile>>({
+        endpoint: API_ENDPOINTS.PROFILE.GetProfiles.endpoint,
+        method: API_ENDPOINTS.PROFILE.GetProfiles.methods[0],
+        queryKey: [API_ENDPOINTS.PROFILE.GetProfiles.key],
+        enabled: enabled,
+    })
+}
+
+export function useCreateProfile() {
+    const qc = useQueryClient()
+
+    return useServerMutation<Profile, CreateProfile_Variables>({
+        endpoint: API_ENDPOINTS.PROFILE.CreateProfile.endpoint,
+        method: API_ENDPOINTS.PROFILE.CreateProfile.methods[0],
+        mutationKey: [API_ENDPOINTS.PROFILE.CreateProfile.key],
+        onSuccess: async () => {
+            await qc.invalidateQueries({ queryKey: [API_ENDPOINTS.PROFILE.GetProfiles.key] })
+            toast.success("Profile created")
+        },
+    })
+}
+
+export function useSelectProfile() {
+    const qc = useQueryClient()
+
+    return useServerMutation<Status, SelectProfile_Variables>({
+        endpoint: API_ENDPOINTS.PROFILE.SelectProfile.endpoint,
+        method: API_ENDPOINTS.PROFILE.SelectProfile.methods[0],
+        mutationKey: [API_ENDPOINTS.PROFILE.SelectProfile.key],
+        onSuccess: async () => {
+            // The profile session cookie is set, the data of the previous profile is stale
+            await qc.invalidateQueries()
+        },
+        // Invalid PINs return a 401 status, which is not displayed by default
+        onError: error => {
+            toast.error(error.response?.data?.error || "Failed to select the profile")
+        },
+    })
+}
+
+export function useSetServerProfile() {
+    const qc = useQueryClient()
+
+    return useServerMutation<Status, SetServerProfile_Variables>({
+        endpoint: API_ENDPOINTS.PROFILE.SetServerProfile.endpoint,
+        method: API_ENDPOINTS.PROFILE.SetServerProfile.methods[0],
+        mutationKey: [API_ENDPOINTS.PROFILE.SetServerProfile.key],
+        onSuccess: async () => {
+            await qc.invalidateQueries({ queryKey: [API_ENDPOINTS.PROFILE.GetProfiles.key] })
+            await qc.invalidateQueries({ queryKey: [API_ENDPOINTS.STATUS.GetStatus.key] })
+            toast.success("Server profile changed")
+        },
+        onError: error => {
+            toast.error(error.response?.data?.error || "Failed to change the server profile")
+        },
+    })
+}
@@ -0,0 +1,35 @@
+import { useServerMutation, useServerQuery } from "@/api/client/requests"
+import { ServerAuthLogin_Variables } from "@/api/generated/endpoint.types"
+import { API_ENDPOINTS } from "@/api/generated/endpoints"
+import { ServerAuthStatus } from "@/api/generated/types"
+import { useQueryClient } from "@tanstack/react-query"
+import { toast } from "sonner"
+
+export function useGetServerAuthStatus() {
+    return useServerQuery<ServerAuthStatus>({
+        endpoint: API_ENDPOINTS.SERVER_AUTH.GetServerAuthStatus.endpoint,
+        method: API_ENDPOINTS.SERVER_AUTH.GetServerAuthStatus.methods[0],
+        queryKey: [API_ENDPOINTS.SERVER_AUTH.GetServerAuthStatus.key],
+        enabled: true,
+        // The server status query displays the connection errors
+        muteError: true,
+    })
+}
+
+export function useServerAuthLogin() {
+    const qc = useQueryClient()
+
+    return useServerMutation<string, ServerAuthLogin_Variables>({
+        endpoint: API_ENDPOINTS.SERVER_AUTH.ServerAuthLogin.endpoint,
+        method: API_ENDPOINTS.SERVER_AUTH.ServerAuthLogin.methods[0],
+        mutationKey: [API_ENDPOINTS.SERVER_AUTH.ServerAuthLogin.key],
+        onSuccess: async () => {
+            // The session cookie is set, refetch everything that failed while logged out
+            await qc.invalidateQueries()
+        },
+        // Invalid passwords also return a 401 status, which is not displayed by default
+        onError: error => {
+            toast.error(error.response?.data?.error || "Failed to log in")
+        },
+    })
+}
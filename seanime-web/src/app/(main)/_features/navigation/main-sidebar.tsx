@@ -3,6 +3,7 @@ import { useLogout } from "@/api/hooks/auth.hooks"
 import { useSyncIsActive } from "@/app/(main)/_atoms/sync.atoms"
 import { __globalSearch_isOpenAtom } from "@/app/(main)/_features/global-search/global-search"
 import { SidebarNavbar } from "@/app/(main)/_features/layout/top-navbar"
+import { __profiles_pickerIsOpenAtom } from "@/app/(main)/_features/profiles/profile-picker"
 import { UpdateModal } from "@/app/(main)/_features/update/update-modal"
 import { useAutoDownloaderQueueCount } from "@/app/(main)/_hooks/autodownloader-queue-count"
 import { useWebsocketMessageListener } from "@/app/(main)/_hooks/handle-websockets"
@@ -26,7 +27,7 @@ import { useThemeSettings } from "@/lib/theme/hooks"
 import { useSetAtom } from "jotai"
 import { usePathname } from "next/navigation"
 import React from "react"
-import { BiCalendarAlt, BiDownload, BiExtension, BiLogOut, BiNews } from "react-icons/bi"
+import { BiCalendarAlt, BiDownload, BiExtension, BiLogOut, BiNews, BiUserCircle } from "react-icons/bi"
 import { FaBookReader } from "react-icons/fa"
 import { FiLogIn, FiSearch, FiSettings } from "react-icons/fi"
 import { HiOutlineServerStack } from "react-icons/hi2"
@@ -69,6 +70,7 @@ export function MainSidebar() {
     }, [isPending, data])
 
     const setGlobalSearchIsOpen = useSetAtom(__globalSearch_isOpenAtom)
+    const setProfilePickerIsOpen = useSetAtom(__profiles_pickerIsOpenAtom)
 
     const loginModal = useDisclosure(false)
 
@@ -261,6 +263,11 @@ export function MainSidebar() {
                                     isCurrent: pathname === ("/settings"),
                                 },
                                 ...(ctx.isBelowBreakpoint ? [
+                                    {
+                                        iconType: BiUserCircle,
+                                        name: "Switch profile",
+                                        onClick: () => setProfilePickerIsOpen(true),
+                                    },
                                     {
                                         iconType: BiLogOut,
                                         name: "Sign out",
@@ -279,6 +286,11 @@ export function MainSidebar() {
                                 onMouseLeave={handleUnexpandedSidebar}
                                 onLinkItemClick={() => ctx.setOpen(false)}
                                 items={[
+                                    {
+                                        iconType: BiUserCircle,
+                                        name: "Switch profile",
+                                        onClick: () => setProfilePickerIsOpen(true),
+                                    },
                                     {
                                         iconType: FiLogIn,
                                         name: "Login",
@@ -302,6 +314,9 @@ export function MainSidebar() {
                             open={dropdownOpen}
                             onOpenChange={setDropdownOpen}
                         >
+                            <DropdownMenuItem onClick={() => setProfilePickerIsOpen(true)}>
+                                <BiUserCircle /> Switch profile
+                            </DropdownMenuItem>
                             <DropdownMenuItem onClick={confirmSignOut.open}>
                                 <BiLogOut /> Sign out
                             </DropdownMenuItem>
@@ -0,0 +1,186 @@
+import { Profile } from "@/api/generated/types"
+import { useCreateProfile, useGetProfiles, useSelectProfile, useSetServerProfile } from "@/api/hooks/profile.hooks"
+import { useServerStatus, useSetServerStatus } from "@/app/(main)/_hooks/use-server-status"
+import { LoadingOverlayWithLogo } from "@/components/shared/loading-overlay-with-logo"
+import { AppLayoutStack } from "@/components/ui/app-layout"
+import { Avatar } from "@/components/ui/avatar"
+import { Badge } from "@/components/ui/badge"
+import { Button } from "@/components/ui/button"
+import { Card } from "@/components/ui/card"
+import { cn } from "@/components/ui/core/styling"
+import { defineSchema, Field, Form } from "@/components/ui/form"
+import { atom } from "jotai"
+import { useAtom } from "jotai/react"
+import React from "react"
+import { BiLock, BiPlus } from "react-icons/bi"
+
+/**
+ * Set to true to display the profile picker, e.g. to switch profile.
+ * The picker is also displayed when the client has to select its profile.
+ */
+export const __profiles_pickerIsOpenAtom = atom(false)
+
+/**
+ * Lets the client select the profile its requests are served with.
+ * Selecting a profile does not affect the other clients.
+ * The profile can also be made the server profile, the one the background modules (auto downloader, playback manager, sync, Discord RPC) act on
+ * behalf of.
+ */
+export function ProfilePickerPage() {
+
+    const serverStatus = useServerStatus()
+    const setServerStatus = useSetServerStatus()
+    const [pickerIsOpen, setPickerIsOpen] = useAtom(__profiles_pickerIsOpenAtom)
+
+    const { data: profiles, isLoading } = useGetProfiles()
+    const { mutate: selectProfile, isPending: isSelecting } = useSelectProfile()
+    const { mutate: setServerProfile, isPending: isSettingServerProfile } = useSetServerProfile()
+
+    const [selected, setSelected] = React.useState<Profile | null>(null)
+    const [creating, setCreating] = React.useState(false)
+
+    function handleSelect(profile: Profile, pin: string, useAsServerProfile: boolean) {
+        selectProfile({ id: profile.id, pin }, {
+            onSuccess: data => {
+                if (useAsServerProfile) {
+                    setServerProfile({ id: profile.id, pin })
+                }
+                if (data) setServerStatus(data)
+                setSelected(null)
+                setPickerIsOpen(false)
+            },
+        })
+    }
+
+    if (isLoading) return <LoadingOverlayWithLogo />
+
+    return <div className="container max-w-3xl py-10">
+        <Card className="md:py-10">
+            <AppLayoutStack>
+                <div className="text-center space-y-4">
+                    <div className="mb-4 flex justify-center w-full">
+                        <img src="/logo.png" alt="logo" className="w-24 h-auto" />
+                    </div>
+                    <h3>Who's watching?</h3>
+
+                    {!selected && !creating && <>
+                        <div className="flex flex-wrap justify-center gap-4 px-4">
+                            {profiles?.map(profile => (
+                                <button
+                                    key={profile.id}
+                                    className={cn(
+                                        "w-32 p-4 rounded-[--radius-md] border flex flex-col items-center gap-2 hover:bg-[--subtle] transition",
+                                        profile.id === serverStatus?.profileId && "border-[--brand]",
+                                    )}
+                                    onClick={() => {
+                                        if (profile.hasPin || profile.id !== serverStatus?.serverProfileId) {
+                                            setSelected(profile)
+                                        } else {
+                                            handleSelect(profile, "", false)
+                                        }
+                                    }}
+                                >
+                                    <Avatar
+                                        size="lg"
+                                        src={profile.avatar || undefined}
+                                        fallback={profile.name.charAt(0).toUpperCase()}
+                                    />
+                                    <p className="truncate w-full">{profile.name}</p>
+                                    <div className="flex gap-1 items-center h-5">
+                                        {profile.hasPin && <BiLock className="text-[--muted]" />}
+                                        {profile.id === serverStatus?.serverProfileId && <Badge size="sm">Server</Badge>}
+                                    </div>
+                                </button>
+                            ))}
+                            <button
+                                className="w-32 p-4 rounded-[--radius-md] border border-dashed flex flex-col items-center justify-center gap-2 hover:bg-[--subtle] transition"
+                                onClick={() => setCreating(true)}
+                            >
+                                <BiPlus className="text-3xl text-[--muted]" />
+                                <p className="text-[--muted]">New profile</p>
+                            </button>
+                        </div>
+
+                        {pickerIsOpen && !!serverStatus?.profileId && <Button intent="gray-basic" onClick={() => setPickerIsOpen(false)}>
+                            Cancel
+                        </Button>}
+                    </>}
+
+                    {!!selected && <Form
+                        schema={defineSchema(({ z }) => z.object({
+                            pin: selected.hasPin ? z.string().min(1, "PIN is required") : z.string().optional().default(""),
+                            useAsServerProfile: z.boolean().optional().default(false),
+                        }))}
+                        onSubmit={data => handleSelect(selected, data.pin ?? "", !!data.useAsServerProfile)}
+                        defaultValues={{ pin: "", useAsServerProfile: false }}
+                    >
+                        <p className="text-lg font-semibold">{selected.name}</p>
+                        {selected.hasPin && <Field.Text
+                            name="pin"
+                            label="PIN"
+                            type="password"
+                            autoComplete="off"
+                            fieldClass="px-4"
+                        />}
+                        {selected.id !== serverStatus?.serverProfileId && <Field.Checkbox
+                            name="useAsServerProfile"
+                            label="Use as the server profile"
+                            help="The auto downloader, the media player progress tracking and the Discord rich presence will use this profile. Other clients keep their profile."
+                            fieldClass="px-4"
+                        />}
+                        <div className="flex gap-2 justify-center">
+                            <Button type="button" intent="gray-basic" onClick={() => setSelected(null)}>Back</Button>
+                            <Field.Submit loading={isSelecting || isSettingServerProfile}>Continue</Field.Submit>
+                        </div>
+                    </Form>}
+
+                    {creating && <CreateProfileForm onClose={() => setCreating(false)} />}
+                </div>
+            </AppLayoutStack>
+        </Card>
+    </div>
+}
+
+type CreateProfileFormProps = {
+    onClose: () => void
+}
+
+function CreateProfileForm(props: CreateProfileFormProps) {
+
+    const { onClose } = props
+
+    const { mutate: createProfile, isPending } = useCreateProfile()
+
+    return <Form
+        schema={defineSchema(({ z }) => z.object({
+            name: z.string().min(1, "Name is required"),
+            avatar: z.string().optional().default(""),
+            pin: z.string().refine(pin => pin === "" || pin.length >= 4, "PIN should be at least 4 characters long").optional().default(""),
+        }))}
+        onSubmit={data => {
+            createProfile({ name: data.name, avatar: data.avatar ?? "", pin: data.pin ?? "" }, {
+                onSuccess: () => onClose(),
+            })
+        }}
+        defaultValues={{ name: "", avatar: "", pin: "" }}
+    >
+        <p className="text-lg font-semibold">New profile</p>
+        <p className="text-[--muted]">
+            The profile has its own AniList account, watch history, playlists and theme. The library is shared by all profiles.
+        </p>
+        <Field.Text name="name" label="Name" fieldClass="px-4" />
+        <Field.Text name="avatar" label="Avatar URL" fieldClass="px-4" />
+        <Field.Text
+            name="pin"
+            label="PIN"
+            help="Optional. Required to select the profile."
+            type="password"
+            autoComplete="new-password"
+            fieldClass="px-4"
+        />
+        <div className="flex gap-2 justify-center">
+            <Button type="button" intent="gray-basic" onClick={onClose}>Cancel</Button>
+            <Field.Submit loading={isPending}>Create</Field.Submit>
+        </div>
+    </Form>
+}
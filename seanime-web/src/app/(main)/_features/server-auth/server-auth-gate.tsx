@@ -0,0 +1,85 @@
+import { subscribeToServerAuthCheck } from "@/api/client/requests"
+import { useGetServerAuthStatus, useServerAuthLogin } from "@/api/hooks/server_auth.hooks"
+import { LoadingOverlayWithLogo } from "@/components/shared/loading-overlay-with-logo"
+import { AppLayoutStack } from "@/components/ui/app-layout"
+import { Card } from "@/components/ui/card"
+import { defineSchema, Field, Form } from "@/components/ui/form"
+import React from "react"
+
+type ServerAuthGateProps = {
+    children?: React.ReactNode
+}
+
+/**
+ * Displays the login page instead of the app when a server password is set and the client is not logged in.
+ * The authentication status is checked again whenever a request returns a 401 status, e.g. when the session expired
+ * or the password was changed from another client.
+ */
+export function ServerAuthGate(props: ServerAuthGateProps) {
+
+    const {
+        children,
+        ...rest
+    } = props
+
+    const { data: authStatus, isLoading, refetch } = useGetServerAuthStatus()
+
+    React.useEffect(() => {
+        return subscribeToServerAuthCheck(() => {
+            refetch()
+        })
+    }, [])
+
+    if (isLoading) return <LoadingOverlayWithLogo />
+
+    if (authStatus?.enabled && !authStatus?.authenticated) {
+        return <ServerLoginPage onLoggedIn={() => refetch()} />
+    }
+
+    return children
+}
+
+type ServerLoginPageProps = {
+    onLoggedIn: () => void
+}
+
+function ServerLoginPage(props: ServerLoginPageProps) {
+
+    const { onLoggedIn } = props
+
+    const { mutate: login, isPending } = useServerAuthLogin()
+
+    return <div className="container max-w-xl py-10">
+        <Card className="md:py-10">
+            <AppLayoutStack>
+                <div className="text-center space-y-4">
+                    <div className="mb-4 flex justify-center w-full">
+                        <img src="/logo.png" alt="logo" className="w-24 h-auto" />
+                    </div>
+                    <h3>Log in</h3>
+                    <p className="text-[--muted]">This server is protected by a password.</p>
+
+                    <Form
+                        schema={defineSchema(({ z }) => z.object({
+                            password: z.string().min(1, "Password is required"),
+                        }))}
+                        onSubmit={data => {
+                            login({ password: data.password }, {
+                                onSuccess: () => onLoggedIn(),
+                            })
+                        }}
+                    >
+                        <Field.Text
+                            name="password"
+                            label="Password"
+                            type="password"
+                            autoComplete="current-password"
+                            fieldClass="px-4"
+                        />
+                        <Field.Submit loading={isPending}>Log in</Field.Submit>
+                    </Form>
+                </div>
+            </AppLayoutStack>
+        </Card>
+    </div>
+}
@@ -65,7 +65,7 @@ export function ChapterPage(props: ChapterPageProps) {
                         <IconButton intent="white" icon={<FaRedo id="retry-icon" />} onClick={retry} id="retry-button" tabIndex={-1} />
                     </div>}
                 <img
-                    src={getChapterPageUrl(page.url, pageContainer?.isDownloaded, page.headers)}
+                    src={getChapterPageUrl(page.url, pageContainer?.isDownloaded, page.headers, pageContainer?.pageKeys?.[page.index])}
                     alt={`Page ${index}`}
                     className={imageClass}
                     style={{ width: imageWidth, maxWidth: imageMaxWidth }}
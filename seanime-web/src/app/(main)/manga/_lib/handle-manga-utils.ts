@@ -27,8 +27,12 @@ export function isChapterAfter(a: string, b: string): boolean {
 
 export function useMangaReaderUtils() {
 
-    const getChapterPageUrl = React.useCallback((url: string, isDownloaded: boolean | undefined, headers?: Record<string, string>) => {
+    const getChapterPageUrl = React.useCallback((url: string, isDownloaded: boolean | undefined, headers?: Record<string, string>, key?: string) => {
         if (!isDownloaded) {
+            // Served through the image cache
+            if (key) {
+                return `${getServerBaseUrl()}/api/v1/manga/image?key=${key}`
+            }
             if (headers && Object.keys(headers).length > 0) {
                 return `${getServerBaseUrl()}/api/v1/image-proxy?url=${encodeURIComponent(url)}&headers=${encodeURIComponent(
                     JSON.stringify(headers))}`
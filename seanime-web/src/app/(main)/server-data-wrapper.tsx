@@ -1,5 +1,8 @@
+import { subscribeToProfileCheck } from "@/api/client/requests"
 import { useGetStatus } from "@/api/hooks/status.hooks"
 import { GettingStartedPage } from "@/app/(main)/_features/getting-started/getting-started-page"
+import { __profiles_pickerIsOpenAtom, ProfilePickerPage } from "@/app/(main)/_features/profiles/profile-picker"
+import { useWebsocketMessageListener } from "@/app/(main)/_hooks/handle-websockets"
 import { useServerStatus, useSetServerStatus } from "@/app/(main)/_hooks/use-server-status"
 import { LoadingOverlayWithLogo } from "@/components/shared/loading-overlay-with-logo"
 import { LuffyError } from "@/components/shared/luffy-error"
@@ -9,6 +12,9 @@ import { Card } from "@/components/ui/card"
 import { defineSchema, Field, Form } from "@/components/ui/form"
 import { logger } from "@/lib/helpers/debug"
 import { ANILIST_OAUTH_URL, ANILIST_PIN_URL } from "@/lib/server/config"
+import { WSEvents } from "@/lib/server/ws-events"
+import { useQueryClient } from "@tanstack/react-query"
+import { useAtomValue } from "jotai/react"
 import { usePathname, useRouter } from "next/navigation"
 import React from "react"
 
@@ -29,7 +35,9 @@ export function ServerDataWrapper(props: ServerDataWrapperProps) {
     const router = useRouter()
     const serverStatus = useServerStatus()
     const setServerStatus = useSetServerStatus()
-    const { data: _serverStatus, isLoading } = useGetStatus()
+    const { data: _serverStatus, isLoading, refetch } = useGetStatus()
+    const profilePickerIsOpen = useAtomValue(__profiles_pickerIsOpenAtom)
+    const qc = useQueryClient()
 
     React.useEffect(() => {
         if (_serverStatus) {
@@ -38,12 +46,34 @@ export function ServerDataWrapper(props: ServerDataWrapperProps) {
         }
     }, [_serverStatus])
 
+    // A request was rejected because the client has to select its profile
+    React.useEffect(() => {
+        return subscribeToProfileCheck(() => {
+            refetch()
+        })
+    }, [])
+
+    // Clients that did not select a profile use the server profile
+    useWebsocketMessageListener({
+        type: WSEvents.SERVER_PROFILE_CHANGED,
+        onMessage: () => {
+            qc.invalidateQueries()
+        },
+    })
+
 
     /**
      * If the server status is loading or doesn't exist, show the loading overlay
      */
     if (isLoading || !serverStatus) return <LoadingOverlayWithLogo />
 
+    /**
+     * If the client has to select its profile or wants to switch profile, show the profile picker
+     */
+    if (!serverStatus?.profileId || profilePickerIsOpen) {
+        return <ProfilePickerPage />
+    }
+
     /**
      * If the pathname is /auth/callback, show the callback page
      */
@@ -3,6 +3,12 @@ import { SettingsSubmitButton } from "@/app/(main)/settings/_components/settings
 import { Field } from "@/components/ui/form"
 import React from "react"
 
+const MANGA_IMAGE_FORMAT_OPTIONS = [
+    { label: "JPEG", value: "jpeg" },
+    { label: "WebP", value: "webp" },
+    { label: "AVIF", value: "avif" },
+]
+
 type MangaSettingsProps = {
     isPending: boolean
 }
@@ -43,6 +49,58 @@ export function MangaSettings(props: MangaSettingsProps) {
                 options={options}
             />
 
+            <h3>Page images</h3>
+
+            <Field.Number
+                name="mangaImageCacheSizeLimit"
+                label="Image cache size limit (MB)"
+                help="The least recently read pages are removed when the limit is exceeded. 0 uses the default limit."
+                min={0}
+                formatOptions={{
+                    useGrouping: false,
+                }}
+            />
+
+            <Field.Switch
+                name="mangaOptimizeImages"
+                label="Optimize images"
+                help="Downscale wide pages and re-encode them before caching them."
+            />
+
+            <Field.Select
+                name="mangaImageFormat"
+                label="Output format"
+                help="WebP and AVIF pages are encoded with FFmpeg (see the media streaming settings), pages are kept as they are if it fails."
+                options={MANGA_IMAGE_FORMAT_OPTIONS}
+            />
+
+            <Field.Number
+                name="mangaImageMaxWidth"
+                label="Maximum width"
+                help="Wider pages are downscaled. 0 means no limit."
+                min={0}
+                formatOptions={{
+                    useGrouping: false,
+                }}
+            />
+
+            <Field.Number
+                name="mangaImageQuality"
+                label="Quality"
+                help="From 1 to 100. 0 uses the default quality (80)."
+                min={0}
+                max={100}
+                formatOptions={{
+                    useGrouping: false,
+                }}
+            />
+
+            <Field.Switch
+                name="mangaSplitTallImages"
+                label="Split webtoon strips"
+                help="Split tall strips into page-sized tiles when downloading chapters."
+            />
+
             <SettingsSubmitButton isPending={isPending} />
         </>
     )
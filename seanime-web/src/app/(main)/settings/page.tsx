@@ -158,7 +158,15 @@ export default function Page() {
                                         autoSyncOfflineLocalData: data.autoSyncOfflineLocalData ?? false,
                                     },
                                     manga: {
+                                        ...status?.settings?.manga,
                                         defaultMangaProvider: data.defaultMangaProvider === "-" ? "" : data.defaultMangaProvider,
+                                        localLibraryPaths: status?.settings?.manga?.localLibraryPaths ?? [],
+                                        imageCacheSizeLimit: data.mangaImageCacheSizeLimit ?? 0,
+                                        optimizeImages: data.mangaOptimizeImages ?? false,
+                                        imageMaxWidth: data.mangaImageMaxWidth ?? 0,
+                                        imageQuality: data.mangaImageQuality ?? 0,
+                                        imageFormat: data.mangaImageFormat || "jpeg",
+                                        splitTallImages: data.mangaSplitTallImages ?? false,
                                     },
                                     mediaPlayer: {
                                         host: data.mediaPlayerHost,
@@ -257,6 +265,12 @@ export default function Page() {
                                 disableAutoDownloaderNotifications: status?.settings?.notifications?.disableAutoDownloaderNotifications ?? false,
                                 disableAutoScannerNotifications: status?.settings?.notifications?.disableAutoScannerNotifications ?? false,
                                 defaultMangaProvider: status?.settings?.manga?.defaultMangaProvider || "-",
+                                mangaImageCacheSizeLimit: status?.settings?.manga?.imageCacheSizeLimit ?? 0,
+                                mangaOptimizeImages: status?.settings?.manga?.optimizeImages ?? false,
+                                mangaImageMaxWidth: status?.settings?.manga?.imageMaxWidth ?? 0,
+                                mangaImageQuality: status?.settings?.manga?.imageQuality ?? 0,
+                                mangaImageFormat: status?.settings?.manga?.imageFormat || "jpeg",
+                                mangaSplitTallImages: status?.settings?.manga?.splitTallImages ?? false,
                                 showActiveTorrentCount: status?.settings?.torrent?.showActiveTorrentCount ?? false,
                                 autoPlayNextEpisode: status?.settings?.library?.autoPlayNextEpisode ?? false,
                                 enableWatchContinuity: status?.settings?.library?.enableWatchContinuity ?? false,
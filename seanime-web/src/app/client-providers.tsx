@@ -1,4 +1,5 @@
 "use client"
+import { ServerAuthGate } from "@/app/(main)/_features/server-auth/server-auth-gate"
 import { WebsocketProvider } from "@/app/websocket-provider"
 import { CustomThemeProvider } from "@/components/shared/custom-theme-provider"
 import { Toaster } from "@/components/ui/toaster"
@@ -32,7 +33,9 @@ export const ClientProviders: React.FC<ClientProvidersProps> = ({ children }) =>
             <JotaiProvider store={store}>
                 <QueryClientProvider client={queryClient}>
                     <WebsocketProvider>
-                        {children}
+                        <ServerAuthGate>
+                            {children}
+                        </ServerAuthGate>
                         <CustomThemeProvider />
                         <Toaster />
                     </WebsocketProvider>
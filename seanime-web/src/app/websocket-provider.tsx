@@ -1,3 +1,4 @@
+import { requestServerAuthCheck } from "@/api/client/requests"
 import { getServerBaseUrl } from "@/api/client/server-url"
 import { websocketAtom, WebSocketContext } from "@/app/(main)/_atoms/websocket.atoms"
 import { __openDrawersAtom } from "@/components/ui/drawer"
@@ -50,6 +51,8 @@ export function WebsocketProvider({ children }: { children: React.ReactNode }) {
             newSocket.addEventListener("close", () => {
                 console.log("WebSocket connection closed")
                 setIsConnected(false)
+                // The connection is refused and closed when the client is not logged in to the server
+                requestServerAuthCheck()
                 // Reconnect after a delay
                 setTimeout(connectWebSocket, 3000)
             })
@@ -76,6 +76,12 @@ export const settingsSchema = z.object({
     disableAutoDownloaderNotifications: z.boolean().optional().default(false),
     disableAutoScannerNotifications: z.boolean().optional().default(false),
     defaultMangaProvider: z.string().optional().default(""),
+    mangaImageCacheSizeLimit: z.number().optional().default(0),
+    mangaOptimizeImages: z.boolean().optional().default(false),
+    mangaImageMaxWidth: z.number().optional().default(0),
+    mangaImageQuality: z.number().optional().default(0),
+    mangaImageFormat: z.string().optional().default("jpeg"),
+    mangaSplitTallImages: z.boolean().optional().default(false),
     autoPlayNextEpisode: z.boolean().optional().default(false),
     showActiveTorrentCount: z.boolean().optional().default(false),
     enableWatchContinuity: z.boolean().optional().default(false),
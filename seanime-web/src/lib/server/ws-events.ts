@@ -31,5 +31,6 @@ export const enum WSEvents {
     SYNC_LOCAL_FINISHED = "sync-local-finished",
     SYNC_ANILIST_FINISHED = "sync-anilist-finished",
     DEBRID_DOWNLOAD_PROGRESS = "debrid-download-progress",
-    DEBRID_STREAM_STATE = "debrid-stream-state"
+    DEBRID_STREAM_STATE = "debrid-stream-state",
+    SERVER_PROFILE_CHANGED = "server-profile-changed",
 }